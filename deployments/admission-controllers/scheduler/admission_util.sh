@@ -58,6 +58,42 @@ fi
 if [ -z "$ENABLE_CONFIG_HOT_REFRESH" ]; then
   ENABLE_CONFIG_HOT_REFRESH=`cat ${CONF_FILE} | grep ^enableConfigHotRefresh | cut -d "=" -f 2`
 fi
+if [ -z "$ENABLE_SCHEDULING_GATE" ]; then
+  ENABLE_SCHEDULING_GATE=`cat ${CONF_FILE} | grep ^enableSchedulingGate | cut -d "=" -f 2`
+fi
+if [ -z "$ENABLE_USER_INFO" ]; then
+  ENABLE_USER_INFO=`cat ${CONF_FILE} | grep ^enableUserInfo | cut -d "=" -f 2`
+fi
+if [ -z "$DEFAULT_QUEUE" ]; then
+  DEFAULT_QUEUE=`cat ${CONF_FILE} | grep ^defaultQueue | cut -d "=" -f 2`
+fi
+if [ -z "$NAMESPACE_DEFAULT_QUEUES" ]; then
+  NAMESPACE_DEFAULT_QUEUES=`cat ${CONF_FILE} | grep ^namespaceDefaultQueues | cut -d "=" -f 2-`
+fi
+if [ -z "$REJECT_UNMAPPED_PODS" ]; then
+  REJECT_UNMAPPED_PODS=`cat ${CONF_FILE} | grep ^rejectUnmappedPods | cut -d "=" -f 2`
+fi
+if [ -z "$REJECT_UNMAPPED_NAMESPACES" ]; then
+  REJECT_UNMAPPED_NAMESPACES=`cat ${CONF_FILE} | grep ^rejectUnmappedNamespaces | cut -d "=" -f 2`
+fi
+if [ -z "$PROCESS_NAMESPACES" ]; then
+  PROCESS_NAMESPACES=`cat ${CONF_FILE} | grep ^processNamespaces | cut -d "=" -f 2`
+fi
+if [ -z "$BYPASS_NAMESPACES" ]; then
+  BYPASS_NAMESPACES=`cat ${CONF_FILE} | grep ^bypassNamespaces | cut -d "=" -f 2`
+fi
+if [ -z "$LABEL_SELECTOR" ]; then
+  LABEL_SELECTOR=`cat ${CONF_FILE} | grep ^labelSelector | cut -d "=" -f 2-`
+fi
+if [ -z "$PROCESS_WORKLOAD_KINDS" ]; then
+  PROCESS_WORKLOAD_KINDS=`cat ${CONF_FILE} | grep ^processWorkloadKinds | cut -d "=" -f 2`
+fi
+if [ -z "$DEFAULT_CONTAINER_REQUESTS" ]; then
+  DEFAULT_CONTAINER_REQUESTS=`cat ${CONF_FILE} | grep ^defaultContainerRequests | cut -d "=" -f 2-`
+fi
+if [ -z "$REJECT_PODS_WITHOUT_REQUESTS" ]; then
+  REJECT_PODS_WITHOUT_REQUESTS=`cat ${CONF_FILE} | grep ^rejectPodsWithoutRequests | cut -d "=" -f 2`
+fi
 delete_resources() {
   kubectl delete -f server.yaml
   # cleanup admissions
@@ -142,6 +178,18 @@ create_resources() {
     -e 's@${ADMISSION_CONTROLLER_IMAGE_TAG}@'"$ADMISSION_CONTROLLER_IMAGE_TAG"'@g' \
     -e 's@${ADMISSION_CONTROLLER_IMAGE_PULL_POLICY}@'"$ADMISSION_CONTROLLER_IMAGE_PULL_POLICY"'@g' \
     -e 's@${ENABLE_CONFIG_HOT_REFRESH}@'"$ENABLE_CONFIG_HOT_REFRESH"'@g' \
+    -e 's@${ENABLE_SCHEDULING_GATE}@'"$ENABLE_SCHEDULING_GATE"'@g' \
+    -e 's@${ENABLE_USER_INFO}@'"$ENABLE_USER_INFO"'@g' \
+    -e 's@${DEFAULT_QUEUE}@'"$DEFAULT_QUEUE"'@g' \
+    -e 's@${NAMESPACE_DEFAULT_QUEUES}@'"$NAMESPACE_DEFAULT_QUEUES"'@g' \
+    -e 's@${REJECT_UNMAPPED_PODS}@'"$REJECT_UNMAPPED_PODS"'@g' \
+    -e 's@${REJECT_UNMAPPED_NAMESPACES}@'"$REJECT_UNMAPPED_NAMESPACES"'@g' \
+    -e 's@${PROCESS_NAMESPACES}@'"$PROCESS_NAMESPACES"'@g' \
+    -e 's@${BYPASS_NAMESPACES}@'"$BYPASS_NAMESPACES"'@g' \
+    -e 's@${LABEL_SELECTOR}@'"$LABEL_SELECTOR"'@g' \
+    -e 's@${PROCESS_WORKLOAD_KINDS}@'"$PROCESS_WORKLOAD_KINDS"'@g' \
+    -e 's@${DEFAULT_CONTAINER_REQUESTS}@'"$DEFAULT_CONTAINER_REQUESTS"'@g' \
+    -e 's@${REJECT_PODS_WITHOUT_REQUESTS}@'"$REJECT_PODS_WITHOUT_REQUESTS"'@g' \
     <"${basedir}/templates/server.yaml.template" > server.yaml
 
 if [ -n "$ADMISSION_CONTROLLER_IMAGE_PULL_SECRETS" ]; then
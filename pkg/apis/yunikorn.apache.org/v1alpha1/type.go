@@ -41,6 +41,9 @@ type ApplicationSpec struct {
 	SchedulingPolicy SchedulingPolicy `json:"schedulingPolicy"`
 	Queue            string           `json:"queue"`
 	TaskGroups       []TaskGroup      `json:"taskGroups"`
+	// the running pods of other schedulers matching the selector in the namespace of the app are adopted by
+	// the app when it is added, e.g. the pods created before yunikorn was rolled out
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
 }
 
 type SchedulingPolicy struct {
@@ -58,13 +61,25 @@ const (
 )
 
 type TaskGroup struct {
-	Name         string                       `json:"name"`
+	Name string `json:"name"`
+	// the number of members of the gang, each reserving the min resource, a task group without min member
+	// reserves the min resource in total instead, e.g. for members of different sizes
 	MinMember    int32                        `json:"minMember"`
 	Labels       map[string]string            `json:"labels,omitempty"`
 	Annotations  map[string]string            `json:"annotations,omitempty"`
 	MinResource  map[string]resource.Quantity `json:"minResource"`
 	NodeSelector map[string]string            `json:"nodeSelector,omitempty"`
 	Tolerations  []v1.Toleration              `json:"tolerations,omitempty"`
+	// the largest placeholder of a task group without min member, the min resource is then reserved by
+	// several placeholders of the same size, a single placeholder reserves it when not set
+	MaxPlaceholderResource map[string]resource.Quantity `json:"maxPlaceholderResource,omitempty"`
+	// the task groups whose members must all be bound before the placeholders of this task group are created
+	DependsOn []string `json:"dependsOn,omitempty"`
+	// the priority class of the placeholders, placeholders can preempt lower priority pods
+	// or be made non-preempting with a priority class that never preempts
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+	// the maximum number of placeholders of the task group placed on the same node, 0 means no limit
+	MaxPerNode int32 `json:"maxPerNode,omitempty"`
 }
 
 // Status part
@@ -87,6 +102,20 @@ type ApplicationStatus struct {
 	AppStatus  ApplicationStateType `json:"applicationState,omitempty"`
 	Message    string               `json:"message,omitempty"`
 	LastUpdate metav1.Time          `json:"lastUpdate,omitempty"`
+	// the placeholder statistics of the task groups of a gang
+	TaskGroups []TaskGroupStatus `json:"taskGroups,omitempty"`
+}
+
+type TaskGroupStatus struct {
+	Name string `json:"name"`
+	// the number of placeholders requested for the task group
+	Desired int32 `json:"desired"`
+	// the placeholders currently bound to a node
+	Bound int32 `json:"bound"`
+	// the placeholders replaced by the real members of the task group
+	Replaced int32 `json:"replaced"`
+	// the placeholders released by the scheduler when the reservation timed out
+	TimedOut int32 `json:"timedOut"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
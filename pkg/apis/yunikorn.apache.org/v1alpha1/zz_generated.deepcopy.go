@@ -24,6 +24,7 @@ package v1alpha1
 import (
 	v1 "k8s.io/api/core/v1"
 	resource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -99,6 +100,11 @@ func (in *ApplicationSpec) DeepCopyInto(out *ApplicationSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -116,6 +122,11 @@ func (in *ApplicationSpec) DeepCopy() *ApplicationSpec {
 func (in *ApplicationStatus) DeepCopyInto(out *ApplicationStatus) {
 	*out = *in
 	in.LastUpdate.DeepCopyInto(&out.LastUpdate)
+	if in.TaskGroups != nil {
+		in, out := &in.TaskGroups, &out.TaskGroups
+		*out = make([]TaskGroupStatus, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -190,6 +201,18 @@ func (in *TaskGroup) DeepCopyInto(out *TaskGroup) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.MaxPlaceholderResource != nil {
+		in, out := &in.MaxPlaceholderResource, &out.MaxPlaceholderResource
+		*out = make(map[string]resource.Quantity, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.DependsOn != nil {
+		in, out := &in.DependsOn, &out.DependsOn
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -202,3 +225,19 @@ func (in *TaskGroup) DeepCopy() *TaskGroup {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TaskGroupStatus) DeepCopyInto(out *TaskGroupStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskGroupStatus.
+func (in *TaskGroupStatus) DeepCopy() *TaskGroupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TaskGroupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
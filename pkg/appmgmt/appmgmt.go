@@ -23,6 +23,7 @@ import (
 
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/appmgmt/general"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/appmgmt/interfaces"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/appmgmt/owner"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/appmgmt/sparkoperator"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
@@ -58,7 +59,9 @@ func NewAMService(amProtocol interfaces.ApplicationManagementProtocol,
 			// for spark operator - SparkApplication
 			sparkoperator.NewManager(amProtocol, apiProvider),
 			// for application crds
-			application.NewAppManager(amProtocol, apiProvider))
+			application.NewAppManager(amProtocol, apiProvider),
+			// for pods without application ID, grouped by their controller
+			owner.NewManager(amProtocol, apiProvider))
 	}
 
 	return appManager
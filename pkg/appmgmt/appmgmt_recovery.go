@@ -20,6 +20,7 @@ package appmgmt
 
 import (
 	"fmt"
+	"sort"
 	"time"
 
 	"go.uber.org/zap"
@@ -30,6 +31,9 @@ import (
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
 )
 
+// maximum number of the apps still recovering listed when the recovery times out
+const maxRecoveryDiagnostics = 10
+
 func (svc *AppManagementService) WaitForRecovery(maxTimeout time.Duration) error {
 	if !svc.apiProvider.IsTestingMode() {
 		apps, err := svc.recoverApps()
@@ -55,6 +59,11 @@ func (svc *AppManagementService) recoverApps() (map[string]interfaces.ManagedApp
 			// trigger recovery of the apps
 			// this is simply submit the app again
 			for _, appMeta := range appMetas {
+				if !svc.amProtocol.IsRecoveryRequired(appMeta.ApplicationID) {
+					log.Logger().Info("skip recovering app finished before the restart",
+						zap.String("appID", appMeta.ApplicationID))
+					continue
+				}
 				if app := svc.amProtocol.AddApplication(
 					&interfaces.AddApplicationRequest{
 						Metadata: appMeta,
@@ -95,8 +104,14 @@ func (svc *AppManagementService) waitForAppRecovery(
 
 			return false
 		}, 1*time.Second, maxTimeout); err != nil {
-			return fmt.Errorf("timeout waiting for app recovery in %s",
-				maxTimeout.String())
+			pendingApps := make([]string, 0, len(recoveringApps))
+			for _, app := range recoveringApps {
+				pendingApps = append(pendingApps,
+					fmt.Sprintf("%s (%s)", app.GetApplicationID(), app.GetApplicationState()))
+			}
+			sort.Strings(pendingApps)
+			return fmt.Errorf("timeout waiting for app recovery in %s, %d apps not recovered: %s",
+				maxTimeout.String(), len(pendingApps), utils.JoinWithLimit(pendingApps, maxRecoveryDiagnostics))
 		}
 	}
 
@@ -24,11 +24,13 @@ import (
 
 	"gotest.tools/assert"
 	v1 "k8s.io/api/core/v1"
+	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/appmgmt/interfaces"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/cache"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/callback"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/dispatcher"
@@ -65,6 +67,11 @@ func TestAppManagerRecoveryTimeout(t *testing.T) {
 
 	err = amService.waitForAppRecovery(apps, 3*time.Second)
 	assert.ErrorContains(t, err, "timeout waiting for app recovery")
+	// the apps still recovering are listed
+	assert.ErrorContains(t, err, "2 apps not recovered")
+	for appID := range apps {
+		assert.ErrorContains(t, err, appID+" (Recovering)")
+	}
 }
 
 func TestAppManagerRecoveryExitCondition(t *testing.T) {
@@ -88,6 +95,35 @@ func TestAppManagerRecoveryExitCondition(t *testing.T) {
 	assert.NilError(t, err)
 }
 
+func TestAppManagerRecoverySkipsFinishedApps(t *testing.T) {
+	conf.GetSchedulerConf().OperatorPlugins = "mocked-app-manager"
+	apiProvider := client.NewMockedAPIProvider()
+	apiProvider.GetAPIs().Conf.EnableAppCheckpoint = true
+	apiProvider.GetAPIs().Conf.AppCheckpointNamespace = "yunikorn"
+	// app01 was completed before the restart
+	_, err := apiProvider.GetAPIs().KubeClient.GetClientSet().CoreV1().ConfigMaps("yunikorn").Create(&v1.ConfigMap{
+		ObjectMeta: apis.ObjectMeta{
+			Name:      constants.AppCheckpointConfigMapName,
+			Namespace: "yunikorn",
+		},
+		Data: map[string]string{
+			"app01": `{"state":"Completed"}`,
+			"app02": `{"state":"Running"}`,
+		},
+	})
+	assert.NilError(t, err)
+	ctx := cache.NewContext(apiProvider)
+	amService := NewAMService(ctx, apiProvider)
+	amService.register(&mockedAppManager{})
+
+	apps, err := amService.recoverApps()
+	assert.NilError(t, err)
+	assert.Equal(t, len(apps), 1)
+	_, ok := apps["app02"]
+	assert.Assert(t, ok)
+	assert.Assert(t, ctx.GetApplication("app01") == nil)
+}
+
 // test app state transition during recovery
 func TestAppStatesDuringRecovery(t *testing.T) {
 	conf.GetSchedulerConf().OperatorPlugins = "mocked-app-manager"
@@ -113,7 +149,7 @@ func TestAppStatesDuringRecovery(t *testing.T) {
 	// waitForAppRecovery call should be blocked
 	// because the scheduler is still doing recovery
 	err = amService.waitForAppRecovery(apps, 3*time.Second)
-	assert.Error(t, err, "timeout waiting for app recovery in 3s")
+	assert.Error(t, err, "timeout waiting for app recovery in 3s, 2 apps not recovered: app01 (Recovering), app02 (Recovering)")
 	assert.Equal(t, app01.GetApplicationState(), events.States().Application.Recovering)
 	assert.Equal(t, app02.GetApplicationState(), events.States().Application.Recovering)
 
@@ -130,7 +166,7 @@ func TestAppStatesDuringRecovery(t *testing.T) {
 	// since app02 is still under recovery
 	// waitForRecovery should timeout because the scheduler is still under recovery
 	err = amService.waitForAppRecovery(apps, 3*time.Second)
-	assert.Error(t, err, "timeout waiting for app recovery in 3s")
+	assert.Error(t, err, "timeout waiting for app recovery in 3s, 1 apps not recovered: app02 (Recovering)")
 	assert.Equal(t, app01.GetApplicationState(), events.States().Application.Accepted)
 	assert.Equal(t, app02.GetApplicationState(), events.States().Application.Recovering)
 
@@ -30,6 +30,7 @@ import (
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
@@ -45,13 +46,18 @@ import (
 type Manager struct {
 	apiProvider client.APIProvider
 	amProtocol  interfaces.ApplicationManagementProtocol
+	batcher     *podEventBatcher // nil when the pod events are handled immediately
 }
 
 func NewManager(amProtocol interfaces.ApplicationManagementProtocol, apiProvider client.APIProvider) *Manager {
-	return &Manager{
+	manager := &Manager{
 		apiProvider: apiProvider,
 		amProtocol:  amProtocol,
 	}
+	if period := apiProvider.GetAPIs().Conf.GetPodEventCoalescePeriod(); period > 0 {
+		manager.batcher = newPodEventBatcher(period, manager.handlePodEvents)
+	}
+	return manager
 }
 
 // this implements AppManagementService interface
@@ -81,7 +87,10 @@ func (os *Manager) Start() error {
 
 // this implements AppManagementService interface
 func (os *Manager) Stop() {
-	// noop
+	// the pod events waiting for the coalesce period are not dropped
+	if os.batcher != nil {
+		os.batcher.flushAll()
+	}
 }
 
 func (os *Manager) getTaskMetadata(pod *v1.Pod) (interfaces.TaskMetadata, bool) {
@@ -122,8 +131,8 @@ func (os *Manager) getAppMetadata(pod *v1.Pod) (interfaces.ApplicationMetadata,
 	} else {
 		tags[constants.AppTagNamespace] = pod.Namespace
 	}
-	// get the user from Pod Labels
-	user := utils.GetUserFromPod(pod)
+	// get the user and the groups with the configured user resolver
+	userGroup := utils.GetUserGroupFromPod(pod)
 
 
 	taskGroups, err := utils.GetTaskGroupsFromAnnotation(pod)
@@ -143,13 +152,23 @@ func (os *Manager) getAppMetadata(pod *v1.Pod) (interfaces.ApplicationMetadata,
 			zap.Error(err))
 	}
 	return interfaces.ApplicationMetadata{
-		ApplicationID:           appID,
-		QueueName:               utils.GetQueueNameFromPod(pod),
-		User:                    user,
-		Tags:                    tags,
-		TaskGroups:              taskGroups,
-		PlaceholderTimeoutInSec: placeholderTimeout,
-		OwnerReferences:         ownerReferences,
+		ApplicationID:                   appID,
+		QueueName:                       utils.GetQueueNameFromPod(pod),
+		User:                            userGroup.User,
+		Groups:                          userGroup.Groups,
+		Tags:                            tags,
+		TaskGroups:                      taskGroups,
+		PlaceholderTimeoutInSec:         placeholderTimeout,
+		OwnerReferences:                 ownerReferences,
+		TaskOrderingPolicy:              utils.GetTaskOrderingPolicyParam(pod),
+		ClusterID:                       utils.GetClusterIDFromPod(pod),
+		Partition:                       utils.GetPartitionFromPod(pod),
+		CompletionPolicy:                os.apiProvider.GetAPIs().Conf.GetAppCompletionPolicy(os.Name()),
+		PlaceholderProgressTimeoutInSec: utils.GetPlaceholderProgressTimeoutParam(pod),
+		GangSchedulingStyle:             utils.GetGangSchedulingStyleParam(pod),
+		MaxParallelTasks:                utils.GetMaxParallelTasks(pod),
+		RuntimeClassName:                utils.GetRuntimeClassName(pod),
+		MaxReservingApps:                utils.GetMaxReservingApps(pod),
 	}, true
 }
 
@@ -207,26 +226,69 @@ func (os *Manager) addPod(obj interface{}) {
 		zap.String("Namespace", pod.Namespace),
 		zap.Bool("NeedsRecovery", recovery))
 
+	appID, err := utils.GetApplicationIDFromPod(pod)
+	if err != nil {
+		log.Logger().Debug("unable to get the application of the pod", zap.Error(err))
+		return
+	}
+	if os.batcher != nil {
+		os.batcher.add(appID, pod, recovery)
+		return
+	}
+	os.handlePodEvents(appID, []*podEvent{{pod: pod, added: true, recovery: recovery}}, 0)
+}
+
+// isCompleted returns true if the app is completed but still known to the scheduler
+func isCompleted(app interfaces.ManagedApp) bool {
+	return app.GetApplicationState() == events.States().Application.Completed
+}
+
+// handlePodEvents adds the app of the pods if it does not exist yet, then adds the tasks of the added pods
+// and completes the tasks of the terminated pods. The events are either coalesced or a single pod event.
+func (os *Manager) handlePodEvents(appID string, events []*podEvent, merged int) {
+	if len(events) > 1 || merged > 0 {
+		log.Logger().Debug("handling coalesced pod events",
+			zap.String("appID", appID),
+			zap.Int("pods", len(events)),
+			zap.Int("mergedEvents", merged))
+	}
 	// add app
-	if appMeta, ok := os.getAppMetadata(pod); ok {
-		// check if app already exist
-		if app := os.amProtocol.GetApplication(appMeta.ApplicationID); app == nil {
-			os.amProtocol.AddApplication(&interfaces.AddApplicationRequest{
-				Metadata: appMeta,
-			})
+	for _, event := range events {
+		if !event.added {
+			continue
+		}
+		if appMeta, ok := os.getAppMetadata(event.pod); ok {
+			// check if app already exist, a completed app is added again for a retry to reclaim its placeholders
+			if app := os.amProtocol.GetApplication(appMeta.ApplicationID); app == nil || isCompleted(app) {
+				os.amProtocol.AddApplication(&interfaces.AddApplicationRequest{
+					Metadata: appMeta,
+				})
+			}
 		}
+		break
 	}
 
-	// add task
-	if taskMeta, ok := os.getTaskMetadata(pod); ok {
-		if app := os.amProtocol.GetApplication(taskMeta.ApplicationID); app != nil {
-			if _, taskErr := app.GetTask(string(pod.UID)); taskErr != nil {
+	for _, event := range events {
+		taskMeta, ok := os.getTaskMetadata(event.pod)
+		if !ok {
+			continue
+		}
+		app := os.amProtocol.GetApplication(taskMeta.ApplicationID)
+		if app == nil {
+			continue
+		}
+		// add task
+		if event.added {
+			if _, taskErr := app.GetTask(string(event.pod.UID)); taskErr != nil {
 				os.amProtocol.AddTask(&interfaces.AddTaskRequest{
 					Metadata: taskMeta,
-					Recovery: recovery,
+					Recovery: event.recovery,
 				})
 			}
 		}
+		if event.completed {
+			os.amProtocol.NotifyTaskPhase(taskMeta.ApplicationID, taskMeta.TaskID, event.pod.Status.Phase)
+		}
 	}
 }
 
@@ -245,6 +307,11 @@ func (os *Manager) updatePod(old, new interface{}) {
 		return
 	}
 
+	appID, err := utils.GetApplicationIDFromPod(newPod)
+	if err != nil {
+		log.Logger().Debug("unable to get the application of the pod", zap.Error(err))
+		return
+	}
 	// triggered when pod status' phase changes
 	if oldPod.Status.Phase != newPod.Status.Phase {
 		// pod succeed or failed means all containers in the pod have been terminated,
@@ -257,13 +324,24 @@ func (os *Manager) updatePod(old, new interface{}) {
 				zap.String("podName", newPod.Name),
 				zap.String("podUID", string(newPod.UID)),
 				zap.String("podStatus", string(newPod.Status.Phase)))
+			if os.batcher != nil {
+				os.batcher.complete(appID, newPod)
+				return
+			}
+			os.handlePodEvents(appID, []*podEvent{{pod: newPod, completed: true}}, 0)
+			return
+		}
+		// the task of a pod waiting to be added is not bound, it cannot be running
+		if newPod.Status.Phase == v1.PodRunning {
 			if taskMeta, ok := os.getTaskMetadata(newPod); ok {
-				if app := os.amProtocol.GetApplication(taskMeta.ApplicationID); app != nil {
-					os.amProtocol.NotifyTaskComplete(taskMeta.ApplicationID, taskMeta.TaskID)
-				}
+				os.amProtocol.NotifyTaskPhase(taskMeta.ApplicationID, taskMeta.TaskID, v1.PodRunning)
 			}
 		}
 	}
+	// the task of a pod waiting to be added is created from the latest version of the pod
+	if os.batcher != nil {
+		os.batcher.refresh(appID, newPod)
+	}
 }
 
 // this function is called when a pod is deleted from api-server.
@@ -295,6 +373,13 @@ func (os *Manager) deletePod(obj interface{}) {
 		zap.String("podName", pod.Name),
 		zap.String("podUID", string(pod.UID)))
 
+	// the pending events of the app are handled first, the task of the pod must exist to be completed
+	if os.batcher != nil {
+		if appID, err := utils.GetApplicationIDFromPod(pod); err == nil {
+			os.batcher.flush(appID)
+		}
+	}
+
 	if taskMeta, ok := os.getTaskMetadata(pod); ok {
 		if app := os.amProtocol.GetApplication(taskMeta.ApplicationID); app != nil {
 			os.amProtocol.NotifyTaskComplete(taskMeta.ApplicationID, taskMeta.TaskID)
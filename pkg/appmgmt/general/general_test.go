@@ -20,11 +20,13 @@ package general
 
 import (
 	"testing"
+	"time"
 
 	"gotest.tools/assert"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/cache"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
@@ -457,6 +459,72 @@ func TestDeletePod(t *testing.T) {
 	assert.Equal(t, task.GetTaskState(), events.States().Task.Completed)
 }
 
+func TestCoalescePodEvents(t *testing.T) {
+	am := NewManager(cache.NewMockedAMProtocol(), client.NewMockedAPIProvider())
+	assert.Assert(t, am.batcher == nil)
+	// the window never ends during the test, the events are handed over explicitly
+	am.batcher = newPodEventBatcher(time.Hour, am.handlePodEvents)
+
+	newPod := func(name string, phase v1.PodPhase) *v1.Pod {
+		return &v1.Pod{
+			TypeMeta: apis.TypeMeta{
+				Kind:       "Pod",
+				APIVersion: "v1",
+			},
+			ObjectMeta: apis.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				UID:       types.UID("UID-" + name),
+				Labels: map[string]string{
+					"applicationId": "app00001",
+					"queue":         "root.a",
+				},
+			},
+			Spec: v1.PodSpec{SchedulerName: constants.SchedulerName},
+			Status: v1.PodStatus{
+				Phase: phase,
+			},
+		}
+	}
+
+	pod1 := newPod("pod00001", v1.PodPending)
+	pod2 := newPod("pod00002", v1.PodPending)
+	pod3 := newPod("pod00003", v1.PodPending)
+	am.addPod(pod1)
+	am.addPod(pod2)
+	am.addPod(pod3)
+	am.updatePod(pod2, newPod("pod00002", v1.PodFailed))
+	// nothing is added before the batch is handed over
+	assert.Assert(t, am.amProtocol.GetApplication("app00001") == nil)
+	assert.Equal(t, am.batcher.getPendingCount(), 3)
+
+	am.batcher.flush("app00001")
+	managedApp := am.amProtocol.GetApplication("app00001")
+	assert.Assert(t, managedApp != nil)
+	app, valid := toApplication(managedApp)
+	assert.Equal(t, valid, true)
+	assert.Equal(t, app.GetQueue(), "root.a")
+	assert.Equal(t, len(app.GetNewTasks()), 2)
+	task, err := app.GetTask("UID-pod00002")
+	assert.NilError(t, err)
+	assert.Equal(t, task.GetTaskState(), events.States().Task.Completed)
+
+	// a delete hands over the pending events first, the task of the deleted pod exists when it is completed
+	pod4 := newPod("pod00004", v1.PodPending)
+	am.addPod(pod4)
+	am.deletePod(pod4)
+	assert.Equal(t, am.batcher.getPendingCount(), 0)
+	task, err = app.GetTask("UID-pod00004")
+	assert.NilError(t, err)
+	assert.Equal(t, task.GetTaskState(), events.States().Task.Completed)
+
+	// stopping the manager does not drop the pending events
+	am.addPod(newPod("pod00005", v1.PodPending))
+	am.Stop()
+	_, err = app.GetTask("UID-pod00005")
+	assert.NilError(t, err)
+}
+
 func toApplication(something interface{}) (*cache.Application, bool) {
 	if app, valid := something.(*cache.Application); valid {
 		return app, true
@@ -0,0 +1,152 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package general
+
+import (
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// podEvent is the coalesced outcome of the adds and updates of a pod, the latest version of the pod is kept
+type podEvent struct {
+	pod       *v1.Pod
+	added     bool
+	recovery  bool
+	completed bool
+}
+
+// podBatch is the pending pod events of an app, in the order the pods were first seen
+type podBatch struct {
+	order  []types.UID
+	events map[types.UID]*podEvent
+	merged int
+}
+
+// podEventBatcher coalesces the pod adds and updates of an app within a short window, the pending events of
+// an app are handed over in one batch once the window of its first event is over. During mass pod creation
+// this turns the informer callbacks of an app into a single pass over the app instead of one per callback.
+type podEventBatcher struct {
+	window  time.Duration
+	flushFn func(appID string, events []*podEvent, merged int)
+	pending map[string]*podBatch
+	sync.Mutex
+}
+
+func newPodEventBatcher(window time.Duration, flushFn func(appID string, events []*podEvent, merged int)) *podEventBatcher {
+	return &podEventBatcher{
+		window:  window,
+		flushFn: flushFn,
+		pending: make(map[string]*podBatch),
+	}
+}
+
+// add queues a pod added to the informer
+func (b *podEventBatcher) add(appID string, pod *v1.Pod, recovery bool) {
+	b.merge(appID, pod, func(event *podEvent) {
+		event.added = true
+		event.recovery = event.recovery || recovery
+	})
+}
+
+// complete queues a pod that reached a terminated phase
+func (b *podEventBatcher) complete(appID string, pod *v1.Pod) {
+	b.merge(appID, pod, func(event *podEvent) {
+		event.completed = true
+	})
+}
+
+// refresh replaces the pod of a pending event with its latest version, a pod that is not pending is ignored
+func (b *podEventBatcher) refresh(appID string, pod *v1.Pod) {
+	b.Lock()
+	defer b.Unlock()
+	if batch, ok := b.pending[appID]; ok {
+		if event, ok := batch.events[pod.UID]; ok {
+			event.pod = pod
+			batch.merged++
+		}
+	}
+}
+
+func (b *podEventBatcher) merge(appID string, pod *v1.Pod, update func(event *podEvent)) {
+	b.Lock()
+	defer b.Unlock()
+	batch, ok := b.pending[appID]
+	if !ok {
+		batch = &podBatch{
+			events: make(map[types.UID]*podEvent),
+		}
+		b.pending[appID] = batch
+		time.AfterFunc(b.window, func() {
+			b.flush(appID)
+		})
+	}
+	event, ok := batch.events[pod.UID]
+	if !ok {
+		event = &podEvent{}
+		batch.events[pod.UID] = event
+		batch.order = append(batch.order, pod.UID)
+	} else {
+		batch.merged++
+	}
+	event.pod = pod
+	update(event)
+}
+
+// flush hands over the pending events of the app, e.g. before a pod of the app is deleted.
+// The lock is not held while the events are handled.
+func (b *podEventBatcher) flush(appID string) {
+	b.Lock()
+	batch, ok := b.pending[appID]
+	delete(b.pending, appID)
+	b.Unlock()
+	if !ok {
+		return
+	}
+	events := make([]*podEvent, 0, len(batch.order))
+	for _, uid := range batch.order {
+		events = append(events, batch.events[uid])
+	}
+	b.flushFn(appID, events, batch.merged)
+}
+
+// flushAll hands over the pending events of all the apps
+func (b *podEventBatcher) flushAll() {
+	b.Lock()
+	appIDs := make([]string, 0, len(b.pending))
+	for appID := range b.pending {
+		appIDs = append(appIDs, appID)
+	}
+	b.Unlock()
+	for _, appID := range appIDs {
+		b.flush(appID)
+	}
+}
+
+func (b *podEventBatcher) getPendingCount() int {
+	b.Lock()
+	defer b.Unlock()
+	count := 0
+	for _, batch := range b.pending {
+		count += len(batch.events)
+	}
+	return count
+}
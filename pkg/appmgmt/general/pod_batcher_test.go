@@ -0,0 +1,134 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package general
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type batchRecorder struct {
+	batches map[string][]*podEvent
+	merged  map[string]int
+	sync.Mutex
+}
+
+func newBatchRecorder() *batchRecorder {
+	return &batchRecorder{
+		batches: make(map[string][]*podEvent),
+		merged:  make(map[string]int),
+	}
+}
+
+func (r *batchRecorder) handle(appID string, events []*podEvent, merged int) {
+	r.Lock()
+	defer r.Unlock()
+	r.batches[appID] = events
+	r.merged[appID] = merged
+}
+
+func (r *batchRecorder) get(appID string) ([]*podEvent, int) {
+	r.Lock()
+	defer r.Unlock()
+	return r.batches[appID], r.merged[appID]
+}
+
+func newBatcherTestPod(name string, phase v1.PodPhase) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: apis.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			UID:       types.UID("UID-" + name),
+		},
+		Status: v1.PodStatus{
+			Phase: phase,
+		},
+	}
+}
+
+func TestPodEventBatcherMerge(t *testing.T) {
+	recorder := newBatchRecorder()
+	batcher := newPodEventBatcher(time.Hour, recorder.handle)
+
+	pod1 := newBatcherTestPod("pod-1", v1.PodPending)
+	pod2 := newBatcherTestPod("pod-2", v1.PodPending)
+	batcher.add("app-1", pod1, false)
+	batcher.add("app-1", pod2, true)
+	batcher.add("app-2", newBatcherTestPod("pod-3", v1.PodPending), false)
+	assert.Equal(t, batcher.getPendingCount(), 3)
+
+	// the updates of a pending pod only replace the pod
+	pod1Running := newBatcherTestPod("pod-1", v1.PodRunning)
+	batcher.refresh("app-1", pod1Running)
+	batcher.complete("app-1", newBatcherTestPod("pod-2", v1.PodSucceeded))
+	// a pod that is not pending is not queued by a refresh
+	batcher.refresh("app-1", newBatcherTestPod("pod-4", v1.PodRunning))
+	assert.Equal(t, batcher.getPendingCount(), 3)
+
+	batcher.flush("app-1")
+	assert.Equal(t, batcher.getPendingCount(), 1)
+	events, merged := recorder.get("app-1")
+	assert.Equal(t, len(events), 2)
+	assert.Equal(t, merged, 2)
+	// the order of the pods is the order they were first seen
+	assert.Equal(t, events[0].pod, pod1Running)
+	assert.Assert(t, events[0].added)
+	assert.Assert(t, !events[0].recovery)
+	assert.Assert(t, !events[0].completed)
+	assert.Equal(t, events[1].pod.Status.Phase, v1.PodSucceeded)
+	assert.Assert(t, events[1].added)
+	assert.Assert(t, events[1].recovery)
+	assert.Assert(t, events[1].completed)
+
+	// flushing an app without pending events is a noop
+	recorder.batches = make(map[string][]*podEvent)
+	batcher.flush("app-1")
+	events, _ = recorder.get("app-1")
+	assert.Assert(t, events == nil)
+
+	batcher.flushAll()
+	assert.Equal(t, batcher.getPendingCount(), 0)
+	events, merged = recorder.get("app-2")
+	assert.Equal(t, len(events), 1)
+	assert.Equal(t, merged, 0)
+}
+
+func TestPodEventBatcherWindow(t *testing.T) {
+	recorder := newBatchRecorder()
+	batcher := newPodEventBatcher(50*time.Millisecond, recorder.handle)
+
+	batcher.add("app-1", newBatcherTestPod("pod-1", v1.PodPending), false)
+	batcher.add("app-1", newBatcherTestPod("pod-2", v1.PodPending), false)
+	events, _ := recorder.get("app-1")
+	assert.Assert(t, events == nil)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for events == nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		events, _ = recorder.get("app-1")
+	}
+	assert.Equal(t, len(events), 2)
+	assert.Equal(t, batcher.getPendingCount(), 0)
+}
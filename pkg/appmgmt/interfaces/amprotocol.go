@@ -58,10 +58,34 @@ type ApplicationManagementProtocol interface {
 	// this will trigger some consequent operations for the given app
 	NotifyApplicationFail(appID string)
 
+	// resume a failed app, the app is resubmitted to the scheduler,
+	// returns an error if the app is not found or the app cannot be resumed.
+	ResumeApplication(appID string) error
+
+	// kill a running app, the pods of the app are deleted and its allocations are released,
+	// returns an error if the app is not found or the app cannot be killed.
+	KillApplication(appID string) error
+
+	// release the reservation of one task group of an app, the placeholders of the task group are deleted,
+	// returns an error if the app or the task group is not found or the app is not reserving.
+	ReleaseTaskGroup(appID, taskGroupName string) error
+
+	// returns false if the app does not need to be recovered after a restart,
+	// e.g. the app was already completed before the restart.
+	IsRecoveryRequired(appID string) bool
+
 	// notify the context that an task is completed,
 	// this will trigger some consequent operations for a given task,
 	// e.g release the allocations that assigned for this task.
 	NotifyTaskComplete(appID, taskID string)
+
+	// notify the context that the phase of the pod of a task changed,
+	// a bound task moves to Running, Succeeded or Failed along with its pod.
+	NotifyTaskPhase(appID, taskID string, phase v1.PodPhase)
+
+	// adopt a pod bound to a node by another scheduler, the pod becomes a bound task of the app.
+	// returns false if the pod is not adopted, e.g. it is scheduled by yunikorn or adopted already.
+	AdoptPod(appID string, pod *v1.Pod) bool
 }
 
 type AddApplicationRequest struct {
@@ -77,10 +101,21 @@ type ApplicationMetadata struct {
 	ApplicationID           string
 	QueueName               string
 	User                    string
+	Groups                  []string
 	Tags                    map[string]string
 	TaskGroups              []v1alpha1.TaskGroup
 	PlaceholderTimeoutInSec int64
 	OwnerReferences         []metav1.OwnerReference
+	TaskOrderingPolicy      string
+	ClusterID               string // target cluster in federation mode, empty for the local cluster
+	Partition               string // target partition, empty for the default partition
+	CompletionPolicy        string // how the app is completed, empty for the manual completion
+	// maximum time the reservation may go without a new placeholder bound, 0 disables the check
+	PlaceholderProgressTimeoutInSec int64
+	GangSchedulingStyle             string // what happens when the reservation stalls: Soft falls back, Hard fails
+	MaxParallelTasks                int    // maximum number of tasks being scheduled at a time, 0 for no limit
+	RuntimeClassName                string // runtime class of the pods, the placeholders run with it
+	MaxReservingApps                int    // maximum number of apps of the queue reserving at the same time, 0 for no limit
 }
 
 type TaskMetadata struct {
@@ -0,0 +1,364 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package owner
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	k8sCache "k8s.io/client-go/tools/cache"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/appmgmt/interfaces"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+const (
+	kindReplicaSet = "ReplicaSet"
+	kindDeployment = "Deployment"
+)
+
+// Manager implements interfaces#Recoverable, interfaces#AppManager
+// owner reference app management service groups the pods without any application ID
+// into one app per top-level controller, e.g a Job or a Deployment (through its ReplicaSets),
+// so the stock workloads get queue fairness without any yunikorn specific labels.
+// The app ID is generated from the kind, name and UID of the controller.
+type Manager struct {
+	apiProvider      client.APIProvider
+	amProtocol       interfaces.ApplicationManagementProtocol
+	informerFactory  informers.SharedInformerFactory
+	replicaSetLister appslisters.ReplicaSetLister
+	stopCh           chan struct{}
+}
+
+func NewManager(amProtocol interfaces.ApplicationManagementProtocol, apiProvider client.APIProvider) *Manager {
+	return &Manager{
+		apiProvider: apiProvider,
+		amProtocol:  amProtocol,
+		stopCh:      make(chan struct{}),
+	}
+}
+
+// this implements AppManagementService interface
+func (os *Manager) Name() string {
+	return "owner-reference"
+}
+
+// this implements AppManagementService interface
+func (os *Manager) ServiceInit() error {
+	// the replica sets are only used to find the deployment of a pod
+	os.informerFactory = informers.NewSharedInformerFactory(
+		os.apiProvider.GetAPIs().KubeClient.GetClientSet(), 0)
+	os.replicaSetLister = os.informerFactory.Apps().V1().ReplicaSets().Lister()
+	os.apiProvider.AddEventHandler(
+		&client.ResourceEventHandlers{
+			Type:     client.PodInformerHandlers,
+			FilterFn: os.filterPods,
+			AddFn:    os.addPod,
+			UpdateFn: os.updatePod,
+			DeleteFn: os.deletePod,
+		})
+	return nil
+}
+
+// this implements AppManagementService interface
+func (os *Manager) Start() error {
+	if os.informerFactory != nil {
+		log.Logger().Info("starting", zap.String("Name", os.Name()))
+		os.informerFactory.Start(os.stopCh)
+	}
+	return nil
+}
+
+// this implements AppManagementService interface
+func (os *Manager) Stop() {
+	log.Logger().Info("stopping", zap.String("Name", os.Name()))
+	close(os.stopCh)
+}
+
+// getAppOwner returns the top-level controller of the pod, the pod belongs to no app without a controller.
+// The pods of a ReplicaSet owned by a Deployment belong to the Deployment, the other controllers, e.g a Job
+// or a StatefulSet, own their pods directly. A Job created by a CronJob is not grouped with the other runs.
+func (os *Manager) getAppOwner(pod *v1.Pod) *metav1.OwnerReference {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return nil
+	}
+	if owner.Kind == kindReplicaSet {
+		if rs := os.getReplicaSet(pod.Namespace, owner); rs != nil {
+			if rsOwner := metav1.GetControllerOf(rs); rsOwner != nil && rsOwner.Kind == kindDeployment {
+				return rsOwner
+			}
+		}
+	}
+	return owner
+}
+
+// getReplicaSet looks up the replica set in the informer cache first, the api-server is only
+// called when the cache is not synced yet, e.g during the recovery.
+func (os *Manager) getReplicaSet(namespace string, owner *metav1.OwnerReference) *appsv1.ReplicaSet {
+	var rs *appsv1.ReplicaSet
+	var err error
+	if os.replicaSetLister != nil {
+		rs, err = os.replicaSetLister.ReplicaSets(namespace).Get(owner.Name)
+	}
+	if rs == nil || err != nil {
+		rs, err = os.apiProvider.GetAPIs().KubeClient.GetClientSet().
+			AppsV1().ReplicaSets(namespace).Get(owner.Name, metav1.GetOptions{})
+		if err != nil {
+			log.Logger().Debug("unable to get the replica set of the pod",
+				zap.String("namespace", namespace),
+				zap.String("name", owner.Name),
+				zap.Error(err))
+			return nil
+		}
+	}
+	// a replica set recreated with the same name is a different owner
+	if rs.UID != owner.UID {
+		return nil
+	}
+	return rs
+}
+
+// generateAppID generates the app ID from the owner, the UID keeps the ID unique when
+// a controller is recreated with the same name.
+func generateAppID(namespace string, owner *metav1.OwnerReference) string {
+	return fmt.Sprintf("%s-%s-%s-%s", strings.ToLower(owner.Kind), namespace, owner.Name, owner.UID)
+}
+
+// getAppID returns the app ID of the pod along with the owner of the app
+func (os *Manager) getAppID(pod *v1.Pod) (string, *metav1.OwnerReference, bool) {
+	// the pods with an application ID are handled by the general app manager
+	if _, err := utils.GetApplicationIDFromPod(pod); err == nil {
+		return "", nil, false
+	}
+	owner := os.getAppOwner(pod)
+	if owner == nil {
+		return "", nil, false
+	}
+	return generateAppID(pod.Namespace, owner), owner, true
+}
+
+func (os *Manager) getTaskMetadata(pod *v1.Pod) (interfaces.TaskMetadata, bool) {
+	appID, _, ok := os.getAppID(pod)
+	if !ok {
+		return interfaces.TaskMetadata{}, false
+	}
+	return interfaces.TaskMetadata{
+		ApplicationID: appID,
+		TaskID:        string(pod.UID),
+		Pod:           pod,
+	}, true
+}
+
+func (os *Manager) getAppMetadata(pod *v1.Pod) (interfaces.ApplicationMetadata, bool) {
+	appID, owner, ok := os.getAppID(pod)
+	if !ok {
+		return interfaces.ApplicationMetadata{}, false
+	}
+
+	tags := map[string]string{}
+	if pod.Namespace == "" {
+		tags[constants.AppTagNamespace] = constants.DefaultAppNamespace
+	} else {
+		tags[constants.AppTagNamespace] = pod.Namespace
+	}
+	userGroup := utils.GetUserGroupFromPod(pod)
+	return interfaces.ApplicationMetadata{
+		ApplicationID:    appID,
+		QueueName:        utils.GetQueueNameFromPod(pod),
+		User:             userGroup.User,
+		Groups:           userGroup.Groups,
+		Tags:             tags,
+		OwnerReferences:  []metav1.OwnerReference{*owner},
+		ClusterID:        utils.GetClusterIDFromPod(pod),
+		Partition:        utils.GetPartitionFromPod(pod),
+		CompletionPolicy: os.apiProvider.GetAPIs().Conf.GetAppCompletionPolicy(os.Name()),
+		MaxParallelTasks: utils.GetMaxParallelTasks(pod),
+	}, true
+}
+
+// filter pods by scheduler name, the pods without a controller or with an application ID are skipped
+func (os *Manager) filterPods(obj interface{}) bool {
+	if pod, ok := obj.(*v1.Pod); ok {
+		if utils.GeneralPodFilter(pod) && metav1.GetControllerOf(pod) != nil {
+			_, err := utils.GetApplicationIDFromPod(pod)
+			return err != nil
+		}
+	}
+	return false
+}
+
+func (os *Manager) addPod(obj interface{}) {
+	pod, err := utils.Convert2Pod(obj)
+	if err != nil {
+		log.Logger().Error("failed to add pod", zap.Error(err))
+		return
+	}
+
+	recovery, err := utils.NeedRecovery(pod)
+	if err != nil {
+		log.Logger().Error("we can't tell to add or recover this pod",
+			zap.Error(err))
+		return
+	}
+
+	appMeta, ok := os.getAppMetadata(pod)
+	if !ok {
+		return
+	}
+	log.Logger().Debug("pod added",
+		zap.String("appType", os.Name()),
+		zap.String("Name", pod.Name),
+		zap.String("Namespace", pod.Namespace),
+		zap.String("appID", appMeta.ApplicationID),
+		zap.Bool("NeedsRecovery", recovery))
+
+	// a completed app is added again for a retry to reclaim its placeholders
+	app := os.amProtocol.GetApplication(appMeta.ApplicationID)
+	if app == nil || app.GetApplicationState() == events.States().Application.Completed {
+		app = os.amProtocol.AddApplication(&interfaces.AddApplicationRequest{
+			Metadata: appMeta,
+		})
+	}
+	if app == nil {
+		return
+	}
+	if _, taskErr := app.GetTask(string(pod.UID)); taskErr != nil {
+		os.amProtocol.AddTask(&interfaces.AddTaskRequest{
+			Metadata: interfaces.TaskMetadata{
+				ApplicationID: appMeta.ApplicationID,
+				TaskID:        string(pod.UID),
+				Pod:           pod,
+			},
+			Recovery: recovery,
+		})
+	}
+}
+
+func (os *Manager) updatePod(old, new interface{}) {
+	oldPod, err := utils.Convert2Pod(old)
+	if err != nil {
+		log.Logger().Error("expecting a pod object", zap.Error(err))
+		return
+	}
+	newPod, err := utils.Convert2Pod(new)
+	if err != nil {
+		log.Logger().Error("expecting a pod object", zap.Error(err))
+		return
+	}
+
+	// the task follows the phase of its pod, the resources of a terminated pod are released
+	if oldPod.Status.Phase != newPod.Status.Phase &&
+		(newPod.Status.Phase == v1.PodRunning || utils.IsPodTerminated(newPod)) {
+		if taskMeta, ok := os.getTaskMetadata(newPod); ok {
+			log.Logger().Info("task phase changes",
+				zap.String("appType", os.Name()),
+				zap.String("appID", taskMeta.ApplicationID),
+				zap.String("namespace", newPod.Namespace),
+				zap.String("podName", newPod.Name),
+				zap.String("podStatus", string(newPod.Status.Phase)))
+			os.amProtocol.NotifyTaskPhase(taskMeta.ApplicationID, taskMeta.TaskID, newPod.Status.Phase)
+		}
+	}
+}
+
+func (os *Manager) deletePod(obj interface{}) {
+	var pod *v1.Pod
+	switch t := obj.(type) {
+	case *v1.Pod:
+		pod = t
+	case k8sCache.DeletedFinalStateUnknown:
+		var err error
+		pod, err = utils.Convert2Pod(t.Obj)
+		if err != nil {
+			log.Logger().Error(err.Error())
+			return
+		}
+	default:
+		log.Logger().Error("cannot convert to pod")
+		return
+	}
+	os.completeTask(pod)
+}
+
+func (os *Manager) completeTask(pod *v1.Pod) {
+	if taskMeta, ok := os.getTaskMetadata(pod); ok {
+		if app := os.amProtocol.GetApplication(taskMeta.ApplicationID); app != nil {
+			log.Logger().Info("task completes",
+				zap.String("appType", os.Name()),
+				zap.String("appID", taskMeta.ApplicationID),
+				zap.String("namespace", pod.Namespace),
+				zap.String("podName", pod.Name),
+				zap.String("podStatus", string(pod.Status.Phase)))
+			os.amProtocol.NotifyTaskComplete(taskMeta.ApplicationID, taskMeta.TaskID)
+		}
+	}
+}
+
+func (os *Manager) ListApplications() (map[string]interfaces.ApplicationMetadata, error) {
+	pods, err := os.apiProvider.GetAPIs().PodInformer.Lister().List(labels.NewSelector())
+	if err != nil {
+		return nil, err
+	}
+
+	// the apps of the pods already scheduled by the scheduler
+	existingApps := make(map[string]interfaces.ApplicationMetadata)
+	for _, pod := range pods {
+		if os.filterPods(pod) && utils.IsAssignedPod(pod) {
+			if meta, ok := os.getAppMetadata(pod); ok {
+				if _, exist := existingApps[meta.ApplicationID]; !exist {
+					existingApps[meta.ApplicationID] = meta
+				}
+			}
+		}
+	}
+	return existingApps, nil
+}
+
+func (os *Manager) GetExistingAllocation(pod *v1.Pod) *si.Allocation {
+	if !os.filterPods(pod) {
+		return nil
+	}
+	if meta, ok := os.getAppMetadata(pod); ok {
+		return &si.Allocation{
+			AllocationKey:    string(pod.UID),
+			AllocationTags:   meta.Tags,
+			UUID:             string(pod.UID),
+			ResourcePerAlloc: common.GetPodResource(pod),
+			QueueName:        meta.QueueName,
+			NodeID:           pod.Spec.NodeName,
+			ApplicationID:    meta.ApplicationID,
+			PartitionName:    constants.DefaultPartition,
+		}
+	}
+	return nil
+}
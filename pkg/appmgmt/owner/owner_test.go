@@ -0,0 +1,170 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package owner
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/cache"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/test"
+)
+
+func newOwnerReference(kind, name string, uid types.UID) apis.OwnerReference {
+	controller := true
+	return apis.OwnerReference{
+		APIVersion: "apps/v1",
+		Kind:       kind,
+		Name:       name,
+		UID:        uid,
+		Controller: &controller,
+	}
+}
+
+func newPod(name string, uid types.UID, owner *apis.OwnerReference) *v1.Pod {
+	pod := &v1.Pod{
+		ObjectMeta: apis.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			UID:       uid,
+			Labels:    map[string]string{constants.LabelQueueName: "root.a"},
+		},
+		Spec: v1.PodSpec{SchedulerName: constants.SchedulerName},
+		Status: v1.PodStatus{
+			Phase: v1.PodPending,
+		},
+	}
+	if owner != nil {
+		pod.OwnerReferences = []apis.OwnerReference{*owner}
+	}
+	return pod
+}
+
+func TestGetAppMetadata(t *testing.T) {
+	apiProvider := client.NewMockedAPIProvider()
+	am := NewManager(cache.NewMockedAMProtocol(), apiProvider)
+
+	// a pod without controller has no app
+	_, ok := am.getAppMetadata(newPod("pod-1", "uid-1", nil))
+	assert.Assert(t, !ok)
+
+	// a pod with an application ID belongs to the general app manager
+	jobRef := newOwnerReference("Job", "job-1", "uid-job-1")
+	pod := newPod("pod-2", "uid-2", &jobRef)
+	pod.Labels[constants.LabelApplicationID] = "app-1"
+	assert.Assert(t, !am.filterPods(pod))
+	_, ok = am.getAppMetadata(pod)
+	assert.Assert(t, !ok)
+
+	// a job owns its pods
+	pod = newPod("pod-3", "uid-3", &jobRef)
+	assert.Assert(t, am.filterPods(pod))
+	app, ok := am.getAppMetadata(pod)
+	assert.Assert(t, ok)
+	assert.Equal(t, app.ApplicationID, "job-default-job-1-uid-job-1")
+	assert.Equal(t, app.QueueName, "root.a")
+	assert.DeepEqual(t, app.Tags, map[string]string{constants.AppTagNamespace: "default"})
+	assert.Equal(t, app.OwnerReferences[0].UID, types.UID("uid-job-1"))
+
+	// a replica set owned by a deployment, the pods belong to the deployment
+	deploymentRef := newOwnerReference("Deployment", "deploy-1", "uid-deploy-1")
+	rsRef := newOwnerReference("ReplicaSet", "rs-1", "uid-rs-1")
+	_, err := apiProvider.GetAPIs().KubeClient.GetClientSet().AppsV1().ReplicaSets("default").Create(
+		&appsv1.ReplicaSet{ObjectMeta: apis.ObjectMeta{
+			Name:            "rs-1",
+			Namespace:       "default",
+			UID:             "uid-rs-1",
+			OwnerReferences: []apis.OwnerReference{deploymentRef},
+		}})
+	assert.NilError(t, err)
+	app, ok = am.getAppMetadata(newPod("pod-4", "uid-4", &rsRef))
+	assert.Assert(t, ok)
+	assert.Equal(t, app.ApplicationID, "deployment-default-deploy-1-uid-deploy-1")
+	assert.Equal(t, app.OwnerReferences[0].Kind, "Deployment")
+
+	// a replica set which cannot be found owns its pods
+	otherRsRef := newOwnerReference("ReplicaSet", "rs-2", "uid-rs-2")
+	app, ok = am.getAppMetadata(newPod("pod-5", "uid-5", &otherRsRef))
+	assert.Assert(t, ok)
+	assert.Equal(t, app.ApplicationID, "replicaset-default-rs-2-uid-rs-2")
+}
+
+func TestAddAndDeletePod(t *testing.T) {
+	amProtocol := cache.NewMockedAMProtocol()
+	am := NewManager(amProtocol, client.NewMockedAPIProvider())
+
+	// the pods of the same job are added to the same app
+	jobRef := newOwnerReference("Job", "job-1", "uid-job-1")
+	pod1 := newPod("pod-1", "uid-1", &jobRef)
+	pod2 := newPod("pod-2", "uid-2", &jobRef)
+	am.addPod(pod1)
+	am.addPod(pod2)
+	app := amProtocol.GetApplication("job-default-job-1-uid-job-1")
+	assert.Assert(t, app != nil)
+	task1, err := app.GetTask("uid-1")
+	assert.NilError(t, err)
+	_, err = app.GetTask("uid-2")
+	assert.NilError(t, err)
+
+	// a pod of another job is added to another app
+	otherJobRef := newOwnerReference("Job", "job-2", "uid-job-2")
+	am.addPod(newPod("pod-3", "uid-3", &otherJobRef))
+	assert.Assert(t, amProtocol.GetApplication("job-default-job-2-uid-job-2") != nil)
+
+	// the task completes when the pod is deleted
+	am.deletePod(pod1)
+	assert.Equal(t, task1.GetTaskState(), events.States().Task.Completed)
+}
+
+func TestListApplications(t *testing.T) {
+	apiProvider := client.NewMockedAPIProvider()
+	podLister := test.NewPodListerMock()
+	apiProvider.SetPodLister(podLister)
+	am := NewManager(cache.NewMockedAMProtocol(), apiProvider)
+
+	jobRef := newOwnerReference("Job", "job-1", "uid-job-1")
+	assigned := newPod("pod-1", "uid-1", &jobRef)
+	assigned.Spec.NodeName = "node-1"
+	podLister.AddPod(assigned)
+	// pending pods and the pods without controller are skipped
+	podLister.AddPod(newPod("pod-2", "uid-2", &jobRef))
+	noOwner := newPod("pod-3", "uid-3", nil)
+	noOwner.Spec.NodeName = "node-1"
+	podLister.AddPod(noOwner)
+
+	apps, err := am.ListApplications()
+	assert.NilError(t, err)
+	assert.Equal(t, len(apps), 1)
+	_, ok := apps["job-default-job-1-uid-job-1"]
+	assert.Assert(t, ok)
+
+	alloc := am.GetExistingAllocation(assigned)
+	assert.Assert(t, alloc != nil)
+	assert.Equal(t, alloc.ApplicationID, "job-default-job-1-uid-job-1")
+	assert.Equal(t, alloc.NodeID, "node-1")
+	assert.Assert(t, am.GetExistingAllocation(noOwner) == nil)
+}
@@ -0,0 +1,27 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// Package benchmark measures the scheduling performance of the shim, run it with:
+//
+//	go test -run=^$ -bench=. -benchmem ./pkg/benchmark
+//
+// The benchmarks drive the real cache, dispatcher and scheduling loop with synthetic pods,
+// the API server is mocked and the core is replaced by the FakeCore of the testutils package,
+// which allocates every ask it receives immediately. The results are the cost of the shim alone and are meant as the
+// baseline to compare a performance change against, not as the scheduling rate of a cluster.
+package benchmark
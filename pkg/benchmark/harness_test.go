@@ -0,0 +1,214 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package benchmark
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/appmgmt/interfaces"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/cache"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/callback"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/dispatcher"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/testutils"
+)
+
+const (
+	// the interval of the scheduling loop, the shim default is one second
+	// which would hide the cost of the shim behind the loop interval
+	benchSchedulingInterval = time.Millisecond
+	benchNodes              = 100
+	benchScheduleTimeout    = 5 * time.Minute
+)
+
+var dispatcherOnce sync.Once
+
+// harness runs the cache of the shim against the fake core of the testutils package, it replaces the KubernetesShim:
+// it runs the scheduling loop and passes the responses of the fake core to the callback.
+// The pods are bound by the mocked kube client, the time a pod takes from being added to
+// being bound is recorded for every pod.
+type harness struct {
+	context  *cache.Context
+	callback *callback.AsyncRMCallback
+	added    map[string]time.Time
+	bound    map[string]time.Duration
+	stopChan chan struct{}
+	sync.Mutex
+}
+
+func newHarness(b *testing.B) *harness {
+	b.Helper()
+	conf.GetSchedulerConf().SetTestMode(true)
+	// the fake recorder of the test mode blocks once its buffer is full
+	events.SetRecorderForTest(events.NewMockedRecorder())
+	apiProvider := client.NewMockedAPIProvider()
+	h := &harness{
+		context:  cache.NewContext(apiProvider),
+		added:    make(map[string]time.Time),
+		bound:    make(map[string]time.Duration),
+		stopChan: make(chan struct{}),
+	}
+	h.callback = callback.NewAsyncRMCallback(h.context)
+	nodes := make([]string, benchNodes)
+	for i := range nodes {
+		nodes[i] = fmt.Sprintf("node-%03d", i)
+	}
+	core := testutils.NewFakeCore(nodes...)
+	core.SetCallback(h.callback)
+	apiProvider.GetAPIs().SchedulerAPI = core
+	apiProvider.MockBindFn(h.bind)
+
+	// the dispatcher is global and takes a second to stop,
+	// it is started once and shared by all the harnesses
+	dispatcherOnce.Do(dispatcher.Start)
+	dispatcher.RegisterEventHandler(dispatcher.EventTypeApp, h.context.ApplicationEventHandler())
+	dispatcher.RegisterEventHandler(dispatcher.EventTypeTask, h.context.TaskEventHandler())
+	dispatcher.RegisterEventHandler(dispatcher.EventTypeNode, h.context.SchedulerNodeEventHandler())
+
+	go h.run()
+	return h
+}
+
+// run is the scheduling loop of the shim, a round is skipped while the dispatcher has events queued:
+// with the short interval the loop would otherwise flood the dispatcher, e.g. with the run events of
+// the accepted apps, and measure the queue instead of the shim.
+func (h *harness) run() {
+	for {
+		select {
+		case <-h.stopChan:
+			return
+		case <-time.After(benchSchedulingInterval):
+			if dispatcher.GetEventQueueLength() > 0 {
+				continue
+			}
+			for _, app := range h.context.GetSchedulableApplications() {
+				app.Schedule()
+			}
+		}
+	}
+}
+
+func (h *harness) stop() {
+	close(h.stopChan)
+}
+
+func (h *harness) bind(pod *v1.Pod, hostID string) error {
+	h.Lock()
+	defer h.Unlock()
+	h.bound[string(pod.UID)] = time.Since(h.added[string(pod.UID)])
+	return nil
+}
+
+// addApp adds an app with the given number of pods, the pods are scheduled by the next loop
+func (h *harness) addApp(appID string, pods int) {
+	h.context.AddApplication(&interfaces.AddApplicationRequest{
+		Metadata: interfaces.ApplicationMetadata{
+			ApplicationID: appID,
+			QueueName:     "root.bench",
+			User:          "bench-user",
+		},
+	})
+	for i := 0; i < pods; i++ {
+		pod := newPod(appID, i)
+		h.Lock()
+		h.added[string(pod.UID)] = time.Now()
+		h.Unlock()
+		h.context.AddTask(&interfaces.AddTaskRequest{
+			Metadata: interfaces.TaskMetadata{
+				ApplicationID: appID,
+				TaskID:        string(pod.UID),
+				Pod:           pod,
+			},
+		})
+	}
+}
+
+func (h *harness) boundCount() int {
+	h.Lock()
+	defer h.Unlock()
+	return len(h.bound)
+}
+
+// waitForBound waits until the given number of pods is bound
+func (h *harness) waitForBound(b *testing.B, count int) {
+	b.Helper()
+	deadline := time.Now().Add(benchScheduleTimeout)
+	for h.boundCount() < count {
+		if time.Now().After(deadline) {
+			b.Fatalf("%d pods bound after %v, expected %d", h.boundCount(), benchScheduleTimeout, count)
+		}
+		time.Sleep(100 * time.Microsecond)
+	}
+}
+
+// latencies returns the add to bind latencies of the bound pods, sorted
+func (h *harness) latencies() []time.Duration {
+	h.Lock()
+	defer h.Unlock()
+	result := make([]time.Duration, 0, len(h.bound))
+	for _, latency := range h.bound {
+		result = append(result, latency)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i] < result[j]
+	})
+	return result
+}
+
+func newPod(appID string, i int) *v1.Pod {
+	name := fmt.Sprintf("%s-pod-%04d", appID, i)
+	return &v1.Pod{
+		TypeMeta: apis.TypeMeta{
+			Kind:       "Pod",
+			APIVersion: "v1",
+		},
+		ObjectMeta: apis.ObjectMeta{
+			Name:      name,
+			Namespace: "bench",
+			UID:       types.UID(name),
+			Labels: map[string]string{
+				constants.LabelApplicationID: appID,
+			},
+		},
+		Spec: v1.PodSpec{
+			SchedulerName: constants.SchedulerName,
+			Containers: []v1.Container{{
+				Name: "container",
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{
+						v1.ResourceCPU:    resource.MustParse("100m"),
+						v1.ResourceMemory: resource.MustParse("100Mi"),
+					},
+				},
+			}},
+		},
+	}
+}
@@ -0,0 +1,94 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package benchmark
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// the pods are submitted in apps of benchPodsPerApp pods,
+// the scheduling of an app starts when the app is accepted by the core.
+const benchPodsPerApp = 10
+
+// BenchmarkSchedulingThroughput schedules b.N pods at once, one op is one pod scheduled.
+// The pods scheduled per second, the add to bind latency and the memory used by a task are logged.
+func BenchmarkSchedulingThroughput(b *testing.B) {
+	h := newHarness(b)
+	defer h.stop()
+	before := heapAlloc()
+
+	b.ResetTimer()
+	start := time.Now()
+	for i := 0; i*benchPodsPerApp < b.N; i++ {
+		h.addApp(fmt.Sprintf("app-%06d", i), minInt(benchPodsPerApp, b.N-i*benchPodsPerApp))
+	}
+	h.waitForBound(b, b.N)
+	elapsed := time.Since(start)
+	b.StopTimer()
+
+	// the memory includes the latency records of the harness, a few tens of bytes per pod
+	perTask := (int64(heapAlloc()) - int64(before)) / int64(b.N)
+	runtime.KeepAlive(h)
+	latencies := h.latencies()
+	b.Logf("pods: %d, pods/s: %.0f, latency p50: %v, p99: %v, max: %v, heap bytes/task: %d",
+		b.N, float64(b.N)/elapsed.Seconds(), percentile(latencies, 50), percentile(latencies, 99),
+		latencies[len(latencies)-1], perTask)
+}
+
+// BenchmarkSchedulingLatency schedules one pod at a time, one op is the time a pod takes from
+// being added to being bound: the state transitions of the app and task, including the wait for
+// the next scheduling loop, the round trips to the core and the bind.
+func BenchmarkSchedulingLatency(b *testing.B) {
+	h := newHarness(b)
+	defer h.stop()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.addApp(fmt.Sprintf("app-%06d", i), 1)
+		h.waitForBound(b, i+1)
+	}
+	b.StopTimer()
+
+	latencies := h.latencies()
+	b.Logf("pods: %d, latency p50: %v, p99: %v, max: %v",
+		b.N, percentile(latencies, 50), percentile(latencies, 99), latencies[len(latencies)-1])
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// percentile returns the p-th percentile of the sorted latencies
+func percentile(latencies []time.Duration, p int) time.Duration {
+	return latencies[(len(latencies)-1)*p/100]
+}
+
+// heapAlloc returns the bytes allocated on the heap after a garbage collection
+func heapAlloc() uint64 {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
@@ -0,0 +1,80 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"sync"
+)
+
+// allocationIndex maps the allocation UUIDs assigned by the core to the tasks of an app, this avoids
+// walking all the tasks of the app to find the task of an allocation. It has its own lock because it is
+// updated from the task state transitions, which hold the task lock and must not take the app lock.
+// No other lock is taken while the index lock is held.
+type allocationIndex struct {
+	tasks map[string]*Task
+	sync.RWMutex
+}
+
+func newAllocationIndex() *allocationIndex {
+	return &allocationIndex{
+		tasks: make(map[string]*Task),
+	}
+}
+
+// add indexes the task by its allocation, an empty UUID is ignored
+func (i *allocationIndex) add(allocUUID string, task *Task) {
+	if allocUUID == "" {
+		return
+	}
+	i.Lock()
+	defer i.Unlock()
+	i.tasks[allocUUID] = task
+}
+
+// remove drops the allocation only when it still belongs to the task
+func (i *allocationIndex) remove(allocUUID string, task *Task) {
+	i.Lock()
+	defer i.Unlock()
+	if i.tasks[allocUUID] == task {
+		delete(i.tasks, allocUUID)
+	}
+}
+
+func (i *allocationIndex) get(allocUUID string) *Task {
+	i.RLock()
+	defer i.RUnlock()
+	return i.tasks[allocUUID]
+}
+
+func (i *allocationIndex) size() int {
+	i.RLock()
+	defer i.RUnlock()
+	return len(i.tasks)
+}
+
+// list returns the allocations of the app
+func (i *allocationIndex) list() []string {
+	i.RLock()
+	defer i.RUnlock()
+	allocations := make([]string, 0, len(i.tasks))
+	for allocUUID := range i.tasks {
+		allocations = append(allocations, allocUUID)
+	}
+	return allocations
+}
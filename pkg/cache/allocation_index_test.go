@@ -0,0 +1,101 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/appmgmt/interfaces"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+)
+
+func TestAllocationIndex(t *testing.T) {
+	index := newAllocationIndex()
+	task1 := &Task{taskID: "task-01"}
+	task2 := &Task{taskID: "task-02"}
+
+	index.add("", task1)
+	assert.Equal(t, index.size(), 0)
+	index.add("uuid-01", task1)
+	index.add("uuid-02", task2)
+	assert.Equal(t, index.size(), 2)
+	assert.Equal(t, index.get("uuid-01"), task1)
+	assert.Assert(t, index.get("uuid-03") == nil)
+
+	// an allocation is only removed by the task it belongs to
+	index.remove("uuid-01", task2)
+	assert.Equal(t, index.get("uuid-01"), task1)
+	index.remove("uuid-01", task1)
+	assert.Assert(t, index.get("uuid-01") == nil)
+	assert.Equal(t, index.size(), 1)
+}
+
+func TestGetTaskByAllocation(t *testing.T) {
+	context := initContextForTest()
+	context.AddApplication(&interfaces.AddApplicationRequest{
+		Metadata: interfaces.ApplicationMetadata{
+			ApplicationID: "app00001",
+			QueueName:     "root.a",
+			User:          "test-user",
+		},
+	})
+	for _, taskID := range []string{"task00001", "task00002"} {
+		context.AddTask(&interfaces.AddTaskRequest{
+			Metadata: interfaces.TaskMetadata{
+				ApplicationID: "app00001",
+				TaskID:        taskID,
+				Pod: &v1.Pod{
+					ObjectMeta: apis.ObjectMeta{
+						Name: "pod-" + taskID,
+					},
+				},
+			},
+		})
+	}
+	task1, err := context.getTask("app00001", "task00001")
+	assert.NilError(t, err)
+	task2, err := context.getTask("app00001", "task00002")
+	assert.NilError(t, err)
+
+	// the tasks are indexed once they get an allocation
+	_, err = context.GetTaskByAllocation("app00001", "uuid-01")
+	assert.ErrorContains(t, err, "allocation uuid-01 doesn't exist")
+	task1.setAllocated("node-1", "uuid-01")
+	task2.setPreBound("node-1", false)
+	found, err := context.GetTaskByAllocation("app00001", "uuid-01")
+	assert.NilError(t, err)
+	assert.Equal(t, found.GetTaskID(), "task00001")
+	found, err = context.GetTaskByAllocation("app00001", "task00002")
+	assert.NilError(t, err)
+	assert.Equal(t, found.GetTaskID(), "task00002")
+	_, err = context.GetTaskByAllocation("app00002", "uuid-01")
+	assert.ErrorContains(t, err, "application app00002 is not found")
+
+	// the allocation of a terminated or removed task is dropped
+	assert.NilError(t, task1.handle(NewSimpleTaskEvent("app00001", "task00001", events.CompleteTask)))
+	_, err = context.GetTaskByAllocation("app00001", "uuid-01")
+	assert.ErrorContains(t, err, "allocation uuid-01 doesn't exist")
+	assert.NilError(t, context.RemoveTask("app00001", "task00002"))
+	_, err = context.GetTaskByAllocation("app00001", "task00002")
+	assert.ErrorContains(t, err, "allocation task00002 doesn't exist")
+}
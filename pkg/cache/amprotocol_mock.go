@@ -20,6 +20,9 @@ package cache
 import (
 	"fmt"
 
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/apis/yunikorn.apache.org/v1alpha1"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/appmgmt/interfaces"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/test"
@@ -106,6 +109,55 @@ func (m *MockedAMProtocol) NotifyApplicationFail(appID string) {
 	}
 }
 
+func (m *MockedAMProtocol) ResumeApplication(appID string) error {
+	if app := m.GetApplication(appID); app != nil {
+		if p, valid := app.(*Application); valid {
+			if p.GetApplicationState() != events.States().Application.Failed {
+				return fmt.Errorf("application %s is not failed", appID)
+			}
+			p.SetState(events.States().Application.Submitted)
+			return nil
+		}
+	}
+	return fmt.Errorf("application %s is not found", appID)
+}
+
+func (m *MockedAMProtocol) KillApplication(appID string) error {
+	if app := m.GetApplication(appID); app != nil {
+		if p, valid := app.(*Application); valid {
+			if !p.canHandle(NewKillApplicationEvent(appID, "", true)) {
+				return fmt.Errorf("application %s cannot be killed", appID)
+			}
+			p.SetState(events.States().Application.Killed)
+			return nil
+		}
+	}
+	return fmt.Errorf("application %s is not found", appID)
+}
+
+func (m *MockedAMProtocol) ReleaseTaskGroup(appID, taskGroupName string) error {
+	if app := m.GetApplication(appID); app != nil {
+		if p, valid := app.(*Application); valid {
+			if !p.canHandle(NewReleaseTaskGroupEvent(appID, taskGroupName)) {
+				return fmt.Errorf("task groups of application %s cannot be released", appID)
+			}
+			taskGroups := make([]v1alpha1.TaskGroup, 0)
+			for _, tg := range p.getTaskGroups() {
+				if tg.Name != taskGroupName {
+					taskGroups = append(taskGroups, tg)
+				}
+			}
+			p.setTaskGroups(taskGroups)
+			return nil
+		}
+	}
+	return fmt.Errorf("application %s is not found", appID)
+}
+
+func (m *MockedAMProtocol) IsRecoveryRequired(appID string) bool {
+	return true
+}
+
 func (m *MockedAMProtocol) NotifyTaskComplete(appID, taskID string) {
 	if app := m.GetApplication(appID); app != nil {
 		if task, err := app.GetTask(taskID); err == nil {
@@ -115,3 +167,35 @@ func (m *MockedAMProtocol) NotifyTaskComplete(appID, taskID string) {
 		}
 	}
 }
+
+func (m *MockedAMProtocol) AdoptPod(appID string, pod *v1.Pod) bool {
+	if app, ok := m.applications[appID]; ok {
+		if _, err := app.GetTask(string(pod.UID)); err != nil {
+			task := NewTask(string(pod.UID), app, nil, pod)
+			task.sm.SetState(events.States().Task.Bound)
+			app.addTask(task)
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MockedAMProtocol) NotifyTaskPhase(appID, taskID string, phase v1.PodPhase) {
+	if app := m.GetApplication(appID); app != nil {
+		if task, err := app.GetTask(taskID); err == nil {
+			if t, ok := task.(*Task); ok {
+				bound := isBoundState(t.GetTaskState())
+				switch {
+				case phase == v1.PodRunning && bound:
+					t.sm.SetState(events.States().Task.Running)
+				case phase == v1.PodSucceeded && bound:
+					t.sm.SetState(events.States().Task.Succeeded)
+				case phase == v1.PodFailed && bound:
+					t.sm.SetState(events.States().Task.Failed)
+				case phase == v1.PodSucceeded || phase == v1.PodFailed:
+					t.sm.SetState(events.States().Task.Completed)
+				}
+			}
+		}
+	}
+}
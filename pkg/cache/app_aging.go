@@ -0,0 +1,166 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/sirequest"
+)
+
+// priorityAging raises the priority of the asks of an app that waits for an allocation, so a starving app
+// eventually gets ahead of the newer asks. The policy is set per queue with the priority aging annotation
+// on the namespace of the apps.
+type priorityAging struct {
+	interval time.Duration // time the app waits without an allocation before its priority is raised
+	step     int32         // priority added every interval
+	max      int32         // max priority added to the asks of the app
+}
+
+// parsePriorityAging parses the aging parameters, e.g. "interval=10m step=10 max=100".
+// The interval is required, the step defaults to 1 and the priority is not capped by default.
+func parsePriorityAging(value string) (*priorityAging, error) {
+	aging := &priorityAging{
+		step: 1,
+		max:  math.MaxInt32,
+	}
+	for _, param := range strings.Split(value, constants.SchedulingPolicyParamDelimiter) {
+		if param == "" {
+			continue
+		}
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid priority aging parameter %s, expecting name=value", param)
+		}
+		var err error
+		switch kv[0] {
+		case constants.PriorityAgingIntervalParam:
+			aging.interval, err = time.ParseDuration(kv[1])
+		case constants.PriorityAgingStepParam:
+			aging.step, err = parsePositiveInt32(kv[1])
+		case constants.PriorityAgingMaxParam:
+			aging.max, err = parsePositiveInt32(kv[1])
+		default:
+			err = fmt.Errorf("unknown parameter")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid priority aging parameter %s: %v", param, err)
+		}
+	}
+	if aging.interval <= 0 {
+		return nil, fmt.Errorf("priority aging requires a positive %s", constants.PriorityAgingIntervalParam)
+	}
+	return aging, nil
+}
+
+func parsePositiveInt32(value string) (int32, error) {
+	parsed, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	if parsed <= 0 {
+		return 0, fmt.Errorf("value must be positive")
+	}
+	return int32(parsed), nil
+}
+
+// addPriority adds the aged priority to the priority of a pod, the result is capped at the max int32
+func addPriority(priority, aged int32) int32 {
+	if sum := int64(priority) + int64(aged); sum < math.MaxInt32 {
+		return int32(sum)
+	}
+	return math.MaxInt32
+}
+
+// getAgedPriority returns the priority added to the asks of the app by aging, the value is read
+// while the lock of a task is held so it is accessed atomically instead of under the app lock
+func (app *Application) getAgedPriority() int32 {
+	return atomic.LoadInt32(&app.agedPriority)
+}
+
+// agePriority raises the priority of the app when it has been waiting for an allocation for the aging
+// interval, the pending asks of the app are sent to the core again with the new priority, the core
+// replaces the asks with the same allocation key. The wait restarts when the app gets an allocation,
+// the aged priority is dropped once the app has no pending ask left.
+func (app *Application) agePriority(now time.Time) {
+	if app.aging == nil {
+		return
+	}
+	app.lock.Lock()
+	pending := app.getTasks(events.States().Task.Scheduling)
+	allocations := app.allocations.size()
+	progress := allocations > app.agingAllocations
+	app.agingAllocations = allocations
+	switch {
+	case len(pending) == 0:
+		app.agingTime = time.Time{}
+		atomic.StoreInt32(&app.agedPriority, 0)
+		app.lock.Unlock()
+		return
+	case app.agingTime.IsZero() || progress:
+		app.agingTime = now
+		app.lock.Unlock()
+		return
+	case now.Sub(app.agingTime) < app.aging.interval:
+		app.lock.Unlock()
+		return
+	}
+	app.agingTime = now
+	aged := app.getAgedPriority()
+	next := aged + app.aging.step
+	if next > app.aging.max || next < aged {
+		next = app.aging.max
+	}
+	atomic.StoreInt32(&app.agedPriority, next)
+	app.lock.Unlock()
+	if next == aged {
+		return
+	}
+
+	app.logger().Info("raising the priority of the waiting app",
+		zap.Int32("agedPriority", next),
+		zap.Int("pendingAsks", len(pending)))
+	builder := sirequest.New(app.getRmID())
+	asks := 0
+	for _, task := range pending {
+		if ask := task.getPendingAsk(); ask != nil {
+			builder.AddAsk(ask)
+			asks++
+		}
+	}
+	if asks == 0 {
+		return
+	}
+	request, err := builder.Build()
+	if err == nil {
+		err = app.schedulerAPI.Update(request)
+	}
+	if err != nil {
+		app.logger().Warn("failed to send the aged asks to the core", zap.Error(err))
+	}
+}
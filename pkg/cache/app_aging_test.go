@@ -0,0 +1,134 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+func TestParsePriorityAging(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    string
+		expected *priorityAging
+	}{
+		{"all parameters", "interval=10m step=5 max=20", &priorityAging{interval: 10 * time.Minute, step: 5, max: 20}},
+		{"defaults", "interval=30s", &priorityAging{interval: 30 * time.Second, step: 1, max: math.MaxInt32}},
+		{"extra spaces", " interval=1h  step=2 ", &priorityAging{interval: time.Hour, step: 2, max: math.MaxInt32}},
+		{"no interval", "step=5", nil},
+		{"invalid interval", "interval=10", nil},
+		{"negative step", "interval=1m step=-1", nil},
+		{"invalid max", "interval=1m max=abc", nil},
+		{"unknown parameter", "interval=1m speed=1", nil},
+		{"no value", "interval", nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			aging, err := parsePriorityAging(tc.value)
+			if tc.expected == nil {
+				assert.Assert(t, err != nil)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, *aging, *tc.expected)
+		})
+	}
+}
+
+func TestAgePriority(t *testing.T) {
+	context := NewContext(client.NewMockedAPIProvider())
+	schedulerAPI := newMockSchedulerAPI()
+	var asks []*si.AllocationAsk
+	schedulerAPI.updateFn = func(request *si.UpdateRequest) error {
+		asks = append(asks, request.Asks...)
+		return nil
+	}
+	tags := map[string]string{constants.AppTagNamespacePriorityAging: "interval=1m step=10 max=25"}
+	app := NewApplication("app00001", "root.a", "test-user", tags, schedulerAPI)
+	assert.Assert(t, app.aging != nil)
+	priority := int32(100)
+	addTask := func(taskID, state string) *Task {
+		task := NewTask(taskID, app, context, &v1.Pod{
+			ObjectMeta: apis.ObjectMeta{
+				Name: "pod-" + taskID,
+				UID:  types.UID(taskID),
+			},
+			Spec: v1.PodSpec{Priority: &priority},
+		})
+		task.sm.SetState(state)
+		app.addTask(task)
+		return task
+	}
+	pending := addTask("task-01", events.States().Task.Scheduling)
+	addTask("task-02", events.States().Task.Bound)
+
+	// the first check starts the wait, the priority is raised once the interval is over
+	start := time.Now()
+	app.agePriority(start)
+	assert.Equal(t, app.getAgedPriority(), int32(0))
+	app.agePriority(start.Add(30 * time.Second))
+	assert.Equal(t, app.getAgedPriority(), int32(0))
+	assert.Equal(t, len(asks), 0)
+	app.agePriority(start.Add(time.Minute))
+	assert.Equal(t, app.getAgedPriority(), int32(10))
+	assert.Equal(t, len(asks), 1)
+	assert.Equal(t, asks[0].AllocationKey, "task-01")
+	assert.Equal(t, asks[0].GetPriority().GetPriorityValue(), int32(110))
+
+	// an allocation restarts the wait
+	app.allocations.add("alloc-01", pending)
+	app.agePriority(start.Add(2 * time.Minute))
+	assert.Equal(t, app.getAgedPriority(), int32(10))
+	app.agePriority(start.Add(3 * time.Minute))
+	assert.Equal(t, app.getAgedPriority(), int32(20))
+
+	// the priority is capped, the asks are not sent again once the max is reached
+	app.agePriority(start.Add(4 * time.Minute))
+	assert.Equal(t, app.getAgedPriority(), int32(25))
+	assert.Equal(t, len(asks), 3)
+	app.agePriority(start.Add(5 * time.Minute))
+	assert.Equal(t, app.getAgedPriority(), int32(25))
+	assert.Equal(t, len(asks), 3)
+
+	// a new ask carries the aged priority
+	assert.Equal(t, pending.createAskRequest().Asks[0].GetPriority().GetPriorityValue(), int32(125))
+
+	// the aged priority is dropped when the app has no pending ask
+	pending.sm.SetState(events.States().Task.Bound)
+	app.agePriority(start.Add(6 * time.Minute))
+	assert.Equal(t, app.getAgedPriority(), int32(0))
+	assert.Assert(t, pending.createAskRequest().Asks[0].Priority == nil)
+
+	// an app without a valid policy is never aged
+	tags[constants.AppTagNamespacePriorityAging] = "step=10"
+	app = NewApplication("app00002", "root.a", "test-user", tags, schedulerAPI)
+	assert.Assert(t, app.aging == nil)
+}
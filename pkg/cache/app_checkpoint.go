@@ -0,0 +1,239 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+)
+
+// the checkpoints are written at most once per interval
+const appCheckpointInterval = 5 * time.Second
+
+// appCheckpoint is the state of an app saved in the checkpoint configmap,
+// it is used to seed the app in the cache when the shim restarts
+type appCheckpoint struct {
+	State        string                         `json:"state"`
+	Placeholders map[string]taskGroupCheckpoint `json:"placeholders,omitempty"`
+}
+
+type taskGroupCheckpoint struct {
+	Desired int32 `json:"desired"`
+	Created int32 `json:"created"`
+	Failed  int32 `json:"failed"`
+}
+
+// appCheckpointer saves the state of the apps on every state transition, the checkpoints
+// are kept in memory and written to the checkpoint configmap periodically, one entry per app.
+// The checkpoints read at the first use are the state of the apps before the restart.
+type appCheckpointer struct {
+	clients     *client.Clients
+	checkpoints map[string]appCheckpoint
+	dirty       bool
+	restored    map[string]appCheckpoint
+	restoreOnce sync.Once
+	stopChan    chan struct{}
+	sync.Mutex
+}
+
+func newAppCheckpointer(clients *client.Clients) *appCheckpointer {
+	return &appCheckpointer{
+		clients:     clients,
+		checkpoints: make(map[string]appCheckpoint),
+		restored:    make(map[string]appCheckpoint),
+	}
+}
+
+// getAppCheckpointer returns nil when the app checkpoint is disabled or the app is not added to a context,
+// the context of the app does not change once it is set
+func (app *Application) getAppCheckpointer() *appCheckpointer {
+	if app.context == nil {
+		return nil
+	}
+	return app.context.checkpointer
+}
+
+// record saves the state of the app, the caller must hold the app lock
+func (c *appCheckpointer) record(app *Application, state string) {
+	checkpoint := appCheckpoint{
+		State: state,
+	}
+	if progress := app.placeholderProgress; progress != nil {
+		checkpoint.Placeholders = make(map[string]taskGroupCheckpoint)
+		for _, tg := range app.taskGroups {
+			if p, ok := progress.get(tg.Name); ok {
+				checkpoint.Placeholders[tg.Name] = taskGroupCheckpoint{
+					Desired: p.desired,
+					Created: p.created,
+					Failed:  p.failed,
+				}
+			}
+		}
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.checkpoints[app.applicationID] = checkpoint
+	c.dirty = true
+}
+
+func (c *appCheckpointer) remove(appID string) {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.checkpoints[appID]; ok {
+		delete(c.checkpoints, appID)
+		c.dirty = true
+	}
+}
+
+// getRestored returns the checkpoint of the app saved before the restart
+func (c *appCheckpointer) getRestored(appID string) (appCheckpoint, bool) {
+	c.restoreOnce.Do(c.load)
+	c.Lock()
+	defer c.Unlock()
+	checkpoint, ok := c.restored[appID]
+	return checkpoint, ok
+}
+
+// isFinished returns true if the app was completed or killed before the restart,
+// the checkpoint of a finished app is kept as the app is not recovered
+func (c *appCheckpointer) isFinished(appID string) bool {
+	checkpoint, ok := c.getRestored(appID)
+	if !ok {
+		return false
+	}
+	states := events.States().Application
+	if checkpoint.State != states.Completed && checkpoint.State != states.Killed {
+		return false
+	}
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.checkpoints[appID]; !ok {
+		c.checkpoints[appID] = checkpoint
+	}
+	return true
+}
+
+// load reads the checkpoints saved before the restart, a missing or broken checkpoint
+// only means the apps are recovered from scratch
+func (c *appCheckpointer) load() {
+	configMap, err := c.clients.KubeClient.GetClientSet().CoreV1().
+		ConfigMaps(c.clients.Conf.AppCheckpointNamespace).
+		Get(constants.AppCheckpointConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			log.Logger().Warn("failed to read the app checkpoint", zap.Error(err))
+		}
+		return
+	}
+	restored := make(map[string]appCheckpoint, len(configMap.Data))
+	for appID, data := range configMap.Data {
+		checkpoint := appCheckpoint{}
+		if err := json.Unmarshal([]byte(data), &checkpoint); err != nil {
+			log.Logger().Warn("skipping broken app checkpoint",
+				zap.String("appID", appID),
+				zap.Error(err))
+			continue
+		}
+		restored[appID] = checkpoint
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.restored = restored
+	log.Logger().Info("app checkpoint loaded", zap.Int("apps", len(restored)))
+}
+
+// flush writes the checkpoints to the configmap if they changed since the last write
+func (c *appCheckpointer) flush() {
+	c.Lock()
+	if !c.dirty {
+		c.Unlock()
+		return
+	}
+	data := make(map[string]string, len(c.checkpoints))
+	for appID, checkpoint := range c.checkpoints {
+		if bytes, err := json.Marshal(checkpoint); err == nil {
+			data[appID] = string(bytes)
+		}
+	}
+	c.dirty = false
+	c.Unlock()
+
+	configMaps := c.clients.KubeClient.GetClientSet().CoreV1().ConfigMaps(c.clients.Conf.AppCheckpointNamespace)
+	configMap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      constants.AppCheckpointConfigMapName,
+			Namespace: c.clients.Conf.AppCheckpointNamespace,
+		},
+		Data: data,
+	}
+	_, err := configMaps.Update(configMap)
+	if apierrors.IsNotFound(err) {
+		_, err = configMaps.Create(configMap)
+	}
+	if err != nil {
+		log.Logger().Warn("failed to write the app checkpoint", zap.Error(err))
+		// retry on the next flush
+		c.Lock()
+		c.dirty = true
+		c.Unlock()
+	}
+}
+
+func (c *appCheckpointer) start() {
+	c.Lock()
+	defer c.Unlock()
+	if c.stopChan != nil {
+		return
+	}
+	c.stopChan = make(chan struct{})
+	go func(stopChan chan struct{}) {
+		ticker := time.NewTicker(appCheckpointInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.flush()
+			case <-stopChan:
+				return
+			}
+		}
+	}(c.stopChan)
+}
+
+// stop ends the periodic writes, the last changes are written before returning
+func (c *appCheckpointer) stop() {
+	c.Lock()
+	if c.stopChan != nil {
+		close(c.stopChan)
+		c.stopChan = nil
+	}
+	c.Unlock()
+	c.flush()
+}
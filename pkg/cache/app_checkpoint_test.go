@@ -0,0 +1,123 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/apis/yunikorn.apache.org/v1alpha1"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/appmgmt/interfaces"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/dispatcher"
+)
+
+func TestAppCheckpoint(t *testing.T) {
+	apiProvider := client.NewMockedAPIProvider()
+	apiProvider.GetAPIs().Conf.EnableAppCheckpoint = true
+	apiProvider.GetAPIs().Conf.AppCheckpointNamespace = "yunikorn"
+	context := NewContext(apiProvider)
+	NewPlaceholderManager(apiProvider.GetAPIs())
+	checkpointer := context.checkpointer
+	assert.Assert(t, checkpointer != nil)
+	taskGroups := []v1alpha1.TaskGroup{
+		{
+			Name:      "test-group",
+			MinMember: 2,
+		},
+	}
+
+	// the state transitions are saved
+	addApp := func(appID string) *Application {
+		return context.AddApplication(&interfaces.AddApplicationRequest{
+			Metadata: interfaces.ApplicationMetadata{
+				ApplicationID: appID,
+				QueueName:     "root.a",
+				User:          "test-user",
+				Tags:          map[string]string{},
+				TaskGroups:    taskGroups,
+			},
+		}).(*Application)
+	}
+	running := addApp("app-running")
+	assert.NilError(t, running.handle(NewSubmitApplicationEvent(running.applicationID)))
+	assert.Equal(t, checkpointer.checkpoints[running.applicationID].State, events.States().Application.Submitted)
+	running.lock.Lock()
+	running.placeholderProgress = newPlaceholderProgress(taskGroups)
+	running.placeholderProgress.onCreated("test-group")
+	running.placeholderProgress.onCreated("test-group")
+	checkpointer.record(running, events.States().Application.Running)
+	running.lock.Unlock()
+	completed := addApp("app-completed")
+	completed.SetState(events.States().Application.Running)
+	assert.NilError(t, completed.handle(NewSimpleApplicationEvent(completed.applicationID, events.CompleteApplication)))
+	removed := addApp("app-removed")
+	assert.NilError(t, removed.handle(NewSubmitApplicationEvent(removed.applicationID)))
+	assert.NilError(t, context.RemoveApplicationInternal(removed.applicationID))
+
+	// the checkpoints are written once
+	checkpointer.flush()
+	assert.Equal(t, checkpointer.dirty, false)
+	configMap, err := apiProvider.GetAPIs().KubeClient.GetClientSet().CoreV1().ConfigMaps("yunikorn").
+		Get(constants.AppCheckpointConfigMapName, metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, len(configMap.Data), 2)
+	assert.Equal(t, configMap.Data["app-completed"], `{"state":"Completed"}`)
+
+	// a restarted shim reads the checkpoints, the completed app is not recovered
+	context = NewContext(apiProvider)
+	dispatcher.RegisterEventHandler(dispatcher.EventTypeApp, context.ApplicationEventHandler())
+	dispatcher.Start()
+	defer dispatcher.Stop()
+	assert.Equal(t, context.IsRecoveryRequired("app-completed"), false)
+	assert.Equal(t, context.IsRecoveryRequired("app-running"), true)
+	assert.Equal(t, context.IsRecoveryRequired("app-unknown"), true)
+
+	// the app that was running skips the reserving phase after the recovery
+	recovered := addApp("app-running")
+	assert.Equal(t, recovered.restoredState, events.States().Application.Running)
+	progress, ok := recovered.getPlaceholderProgress().get("test-group")
+	assert.Assert(t, ok)
+	assert.Equal(t, progress.created, int32(2))
+	recovered.SetState(events.States().Application.Accepted)
+	recovered.postAppAccepted()
+	assertAppState(t, recovered, events.States().Application.Running, 3*time.Second)
+
+	// the checkpoint of the completed app is kept
+	context.checkpointer.flush()
+	configMap, err = apiProvider.GetAPIs().KubeClient.GetClientSet().CoreV1().ConfigMaps("yunikorn").
+		Get(constants.AppCheckpointConfigMapName, metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, configMap.Data["app-completed"], `{"state":"Completed"}`)
+	assert.Equal(t, len(configMap.Data), 2)
+}
+
+func TestAppCheckpointDisabled(t *testing.T) {
+	context := initContextForTest()
+	assert.Assert(t, context.checkpointer == nil)
+	assert.Equal(t, context.IsRecoveryRequired("app-unknown"), true)
+	// noop without the checkpoint
+	context.StartAppCheckpoint()
+	context.StopAppCheckpoint()
+}
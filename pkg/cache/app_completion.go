@@ -0,0 +1,122 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+)
+
+// completeIfTasksTerminated moves a running app to Completed once all its tasks, the placeholders
+// aside, are terminated. This only applies to the apps with the all tasks terminated completion policy,
+// the check and the transition are done under the app lock so no task is added in between.
+// With a linger window the bound placeholders of the app are kept for a retry instead of being cleaned up.
+func (app *Application) completeIfTasksTerminated(lingerWindow time.Duration) bool {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	if app.completionPolicy != conf.AppCompletionAllTasksTerminated ||
+		app.sm.Current() != events.States().Application.Running {
+		return false
+	}
+	tasks, failed := 0, 0
+	for _, task := range app.taskMap {
+		if task.placeholder {
+			continue
+		}
+		if !task.isTerminated() {
+			return false
+		}
+		if task.GetTaskState() == events.States().Task.Failed {
+			failed++
+		}
+		tasks++
+	}
+	if tasks == 0 {
+		return false
+	}
+	app.lingering = lingerWindow > 0 && app.canLinger()
+	if err := app.sm.Event(string(events.CompleteApplication)); err != nil {
+		app.lingering = false
+		app.logger().Warn("failed to complete application", zap.Error(err))
+		return false
+	}
+	app.logger().Info("app is completed, all its tasks are terminated",
+		zap.Int("tasks", tasks),
+		zap.Int("failedTasks", failed))
+	return true
+}
+
+// onTaskTerminated completes the app of a terminated task when the app completion policy allows it.
+// The core is told the app is done, the app is kept in the context for the tombstone period so that
+// its state stays visible and the late events of its pods are still handled, then it is removed.
+func (ctx *Context) onTaskTerminated(app *Application) {
+	lingerWindow := ctx.apiProvider.GetAPIs().Conf.GetPlaceholderLingerWindow()
+	if !app.completeIfTasksTerminated(lingerWindow) {
+		return
+	}
+	app.publishAppEvent(v1.EventTypeNormal, "ApplicationCompleted", "all tasks are terminated")
+	if ctx.startLinger(app, lingerWindow) {
+		return
+	}
+	ctx.releaseCompletedApplication(app)
+}
+
+// releaseCompletedApplication tells the core the app is done, this releases all its allocations.
+// The app is removed from the context once the tombstone period is over.
+func (ctx *Context) releaseCompletedApplication(app *Application) {
+	rr := common.CreateUpdateRequestForRemoveApplication(app.applicationID, app.partition)
+	rr.RmID = app.getRmID()
+	if err := ctx.apiProvider.GetAPIs().SchedulerAPI.Update(&rr); err != nil {
+		app.logger().Warn("failed to remove completed app from the core", zap.Error(err))
+	}
+	tombstone := ctx.apiProvider.GetAPIs().Conf.GetCompletedAppTombstone()
+	time.AfterFunc(tombstone, func() {
+		ctx.removeCompletedApplication(app.applicationID)
+	})
+}
+
+// removeCompletedApplication removes the tombstone of a completed app, an app that left the Completed
+// state in the meantime is kept.
+func (ctx *Context) removeCompletedApplication(appID string) {
+	app, err := ctx.applications.removeIf(appID, func(app *Application) error {
+		if state := app.GetApplicationState(); state != events.States().Application.Completed {
+			return fmt.Errorf("application %s is in state %s", appID, state)
+		}
+		return nil
+	})
+	if err != nil || app == nil {
+		log.Logger().Debug("completed app is not removed",
+			zap.String("appID", appID),
+			zap.Error(err))
+		return
+	}
+	if ctx.checkpointer != nil {
+		ctx.checkpointer.remove(appID)
+	}
+	log.Logger().Info("completed app removed",
+		zap.String("appID", appID))
+}
@@ -0,0 +1,176 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/appmgmt/interfaces"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+func TestCompleteIfTasksTerminated(t *testing.T) {
+	conf.GetSchedulerConf().SetTestMode(true)
+	mockedAPIProvider := client.NewMockedAPIProvider()
+	context := NewContext(mockedAPIProvider)
+	NewPlaceholderManager(mockedAPIProvider.GetAPIs())
+	deleted := make(chan string, 1)
+	mockedAPIProvider.MockDeleteFn(func(pod *v1.Pod) error {
+		deleted <- pod.Name
+		return nil
+	})
+	app := NewApplication("app00001", "root.a", "test-user", map[string]string{}, newMockSchedulerAPI())
+	app.setCompletionPolicy(conf.AppCompletionAllTasksTerminated)
+	newTask := func(taskID string, placeholder bool, state string) *Task {
+		task := NewTask(taskID, app, context, &v1.Pod{
+			ObjectMeta: apis.ObjectMeta{
+				Name: "pod-" + taskID,
+				UID:  types.UID("UID-" + taskID),
+			},
+		})
+		task.placeholder = placeholder
+		task.sm.SetState(state)
+		app.addTask(task)
+		return task
+	}
+	states := events.States()
+
+	// an app without tasks is not completed
+	app.sm.SetState(states.Application.Running)
+	assert.Assert(t, !app.completeIfTasksTerminated(0))
+
+	// the running placeholders do not keep the app running
+	newTask("ph-01", true, states.Task.Bound)
+	task := newTask("task-01", false, states.Task.Bound)
+	newTask("task-02", false, states.Task.Failed)
+	assert.Assert(t, !app.completeIfTasksTerminated(0))
+	task.sm.SetState(states.Task.Completed)
+
+	// only running apps are completed
+	app.sm.SetState(states.Application.Accepted)
+	assert.Assert(t, !app.completeIfTasksTerminated(0))
+
+	// the manual policy leaves the completion to the owner of the app
+	app.sm.SetState(states.Application.Running)
+	app.setCompletionPolicy(conf.AppCompletionManual)
+	assert.Assert(t, !app.completeIfTasksTerminated(0))
+
+	app.setCompletionPolicy(conf.AppCompletionAllTasksTerminated)
+	assert.Assert(t, app.completeIfTasksTerminated(0))
+	assert.Equal(t, app.GetApplicationState(), states.Application.Completed)
+	assert.Assert(t, !app.completeIfTasksTerminated(0))
+
+	// the placeholders are cleaned up once the app is completed
+	select {
+	case name := <-deleted:
+		assert.Equal(t, name, "pod-ph-01")
+	case <-time.After(5 * time.Second):
+		t.Fatal("placeholder is not deleted")
+	}
+}
+
+func TestCompleteApplicationOnTaskTerminated(t *testing.T) {
+	conf.GetSchedulerConf().SetTestMode(true)
+	mockedAPIProvider := client.NewMockedAPIProvider()
+	// the tombstone outlives the test, the removal is triggered explicitly
+	mockedAPIProvider.GetAPIs().Conf.CompletedAppTombstone = time.Hour
+	context := NewContext(mockedAPIProvider)
+	NewPlaceholderManager(mockedAPIProvider.GetAPIs())
+	var lock sync.Mutex
+	removed := make([]string, 0)
+	mockedAPIProvider.MockSchedulerApiUpdateFn(func(request *si.UpdateRequest) error {
+		lock.Lock()
+		defer lock.Unlock()
+		for _, remove := range request.RemoveApplications {
+			removed = append(removed, remove.ApplicationID)
+		}
+		return nil
+	})
+
+	context.AddApplication(&interfaces.AddApplicationRequest{
+		Metadata: interfaces.ApplicationMetadata{
+			ApplicationID:    "app00001",
+			QueueName:        "root.a",
+			User:             "test-user",
+			CompletionPolicy: conf.AppCompletionAllTasksTerminated,
+		},
+	})
+	app := context.applications.get("app00001")
+	assert.Assert(t, app != nil)
+	for _, taskID := range []string{"task-01", "task-02"} {
+		context.AddTask(&interfaces.AddTaskRequest{
+			Metadata: interfaces.TaskMetadata{
+				ApplicationID: "app00001",
+				TaskID:        taskID,
+				Pod: &v1.Pod{
+					ObjectMeta: apis.ObjectMeta{
+						Name: "pod-" + taskID,
+						UID:  types.UID("UID-" + taskID),
+					},
+				},
+			},
+		})
+	}
+	app.sm.SetState(events.States().Application.Running)
+
+	// the app keeps running until the last task is terminated
+	task, err := context.getTask("app00001", "task-01")
+	assert.NilError(t, err)
+	assert.NilError(t, task.handle(NewSimpleTaskEvent("app00001", "task-01", events.CompleteTask)))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, app.GetApplicationState(), events.States().Application.Running)
+
+	task, err = context.getTask("app00001", "task-02")
+	assert.NilError(t, err)
+	assert.NilError(t, task.handle(NewSimpleTaskEvent("app00001", "task-02", events.CompleteTask)))
+	deadline := time.Now().Add(5 * time.Second)
+	for app.GetApplicationState() != events.States().Application.Completed && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, app.GetApplicationState(), events.States().Application.Completed)
+
+	// the core is told the app is done, the app is kept until the tombstone is removed
+	lock.Lock()
+	assert.DeepEqual(t, removed, []string{"app00001"})
+	lock.Unlock()
+	assert.Assert(t, context.applications.get("app00001") != nil)
+	context.removeCompletedApplication("app00001")
+	assert.Assert(t, context.applications.get("app00001") == nil)
+
+	// an app that is not completed is not removed
+	context.AddApplication(&interfaces.AddApplicationRequest{
+		Metadata: interfaces.ApplicationMetadata{
+			ApplicationID: "app00002",
+			QueueName:     "root.a",
+			User:          "test-user",
+		},
+	})
+	context.removeCompletedApplication("app00002")
+	assert.Assert(t, context.applications.get("app00002") != nil)
+}
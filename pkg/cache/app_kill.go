@@ -0,0 +1,313 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/flowcontrol"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/dispatcher"
+)
+
+// how often a killed app checks if its pods are terminated and its allocations released
+const killPollInterval = 100 * time.Millisecond
+
+// killProgress tracks a killed app: the deletion of its pods, which is updated by the deletion workers without
+// holding the app lock, the termination of the deleted pods, and the release of the allocations of the app by the core.
+type killProgress struct {
+	total   int32
+	deleted int32
+	failed  int32
+	// the tasks whose pod is deleted, the app waits for them to terminate
+	tasks []*Task
+	// the pods not waited for: already gone, or failed to be deleted
+	skipped map[string]bool
+	// the allocations of the app the core has not released yet
+	allocations map[string]bool
+	// the app could not be removed from the core, its allocations are not waited for
+	removeFailed bool
+	sync.RWMutex
+}
+
+func newKillProgress(tasks []*Task, allocations []string) *killProgress {
+	progress := &killProgress{
+		total:       int32(len(tasks)),
+		tasks:       tasks,
+		skipped:     make(map[string]bool),
+		allocations: make(map[string]bool, len(allocations)),
+	}
+	for _, allocUUID := range allocations {
+		progress.allocations[allocUUID] = true
+	}
+	return progress
+}
+
+// onDeleted counts a deleted pod, a pod that is already gone is not waited for
+func (p *killProgress) onDeleted(task *Task, gone bool) {
+	p.Lock()
+	defer p.Unlock()
+	p.deleted++
+	if gone {
+		p.skipped[task.taskID] = true
+	}
+}
+
+func (p *killProgress) onFailed(task *Task) {
+	p.Lock()
+	defer p.Unlock()
+	p.failed++
+	p.skipped[task.taskID] = true
+}
+
+// onReleased confirms the release of an allocation of the app by the core
+func (p *killProgress) onReleased(allocUUID string) {
+	p.Lock()
+	defer p.Unlock()
+	delete(p.allocations, allocUUID)
+}
+
+func (p *killProgress) onRemoveFailed() {
+	p.Lock()
+	defer p.Unlock()
+	p.removeFailed = true
+	p.allocations = make(map[string]bool)
+}
+
+// get returns the total number of pods to delete, the deleted pods and the pods that failed to be deleted
+func (p *killProgress) get() (int32, int32, int32) {
+	p.RLock()
+	defer p.RUnlock()
+	return p.total, p.deleted, p.failed
+}
+
+// getPending returns the number of deleted pods not terminated yet and the allocations not released by the core yet
+func (p *killProgress) getPending() (int, int) {
+	p.RLock()
+	defer p.RUnlock()
+	pods := 0
+	for _, task := range p.tasks {
+		if !p.skipped[task.taskID] && !task.isTerminated() {
+			pods++
+		}
+	}
+	return pods, len(p.allocations)
+}
+
+// setAllocations replaces the allocations of the app the core has to release
+func (p *killProgress) setAllocations(allocations []string) {
+	p.Lock()
+	defer p.Unlock()
+	p.allocations = make(map[string]bool, len(allocations))
+	for _, allocUUID := range allocations {
+		p.allocations[allocUUID] = true
+	}
+}
+
+// hasTrackedPods returns true if a pod to delete is tracked by the Job controller
+func (p *killProgress) hasTrackedPods() bool {
+	p.RLock()
+	defer p.RUnlock()
+	for _, task := range p.tasks {
+		if utils.IsTrackedByJob(task.GetTaskPod()) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTerminated returns true once all the deleted pods are terminated
+func (p *killProgress) isTerminated() bool {
+	pods, _ := p.getPending()
+	return pods == 0
+}
+
+func (p *killProgress) isDone() bool {
+	pods, allocations := p.getPending()
+	return pods == 0 && allocations == 0
+}
+
+func (p *killProgress) isRemoveFailed() bool {
+	p.RLock()
+	defer p.RUnlock()
+	return p.removeFailed
+}
+
+// killApp removes the killed app from the core, which releases all its allocations, and deletes its pods.
+// The app moves to Killed once the deleted pods are terminated and the core confirmed the release of the
+// allocations of the app, or once the kill timeout is passed: the kill is then reported as partial.
+// With the job tracking compatibility enabled an app with pods tracked by the Job controller is removed
+// from the core once its pods are gone, after the Job controller has accounted for them.
+func killApp(app *Application, stages [][]*Task, progress *killProgress) {
+	gracePeriod, timeout := conf.GetSchedulerConf().GetKillTimeouts()
+	deadline := time.Now().Add(timeout)
+	if conf.GetSchedulerConf().JobTrackingCompatibility && progress.hasTrackedPods() {
+		// the pods tracked by the Job controller keep their allocations until they are accounted for and gone,
+		// their allocations are released by their tasks and the core only releases the remaining ones
+		deleteKilledPods(app, stages, progress, gracePeriod)
+		if err := utils.WaitForCondition(progress.isTerminated, killPollInterval, time.Until(deadline)); err != nil {
+			app.logger().Warn("pods tracked by the Job controller are not terminated before the kill timeout")
+		}
+		progress.setAllocations(app.allocations.list())
+		removeKilledApp(app, progress)
+	} else {
+		removeKilledApp(app, progress)
+		deleteKilledPods(app, stages, progress, gracePeriod)
+	}
+	err := utils.WaitForCondition(progress.isDone, killPollInterval, time.Until(deadline))
+
+	total, deleted, failed := progress.get()
+	pods, allocations := progress.getPending()
+	if err == nil && failed == 0 && !progress.isRemoveFailed() {
+		app.publishAppEvent(v1.EventTypeNormal, "ApplicationKilled",
+			"killed, %d/%d pods deleted", deleted, total)
+	} else {
+		app.logger().Warn("app is partially killed",
+			zap.Int32("total", total),
+			zap.Int32("deleted", deleted),
+			zap.Int32("failed", failed),
+			zap.Int("notTerminated", pods),
+			zap.Int("notReleased", allocations),
+			zap.Bool("removeFailed", progress.isRemoveFailed()))
+		app.publishAppEvent(v1.EventTypeWarning, "ApplicationKillIncomplete",
+			"killed, %d/%d pods deleted, %d failed to be deleted, %d not terminated, "+
+				"%d allocations not released by the scheduler, removed from the scheduler: %t",
+			deleted, total, failed, pods, allocations, !progress.isRemoveFailed())
+	}
+	dispatcher.Dispatch(NewSimpleApplicationEvent(app.applicationID, events.KilledApplication))
+}
+
+// getKillKind returns the kind of the task used to order the deletion when the app is killed,
+// the drivers are identified by the spark role label.
+func getKillKind(task *Task) string {
+	if task.placeholder {
+		return conf.KillOrderPlaceholder
+	}
+	if task.pod.Labels[constants.SparkLabelRole] == constants.SparkLabelRoleDriver {
+		return conf.KillOrderDriver
+	}
+	return conf.KillOrderWorker
+}
+
+// getKillStages groups the tasks in the stages they are deleted in, following the deletion order.
+// The tasks of a kind that is not in the order are deleted in the last stage, empty stages are skipped.
+func getKillStages(tasks []*Task, order []string) [][]*Task {
+	stageIndex := make(map[string]int, len(order))
+	for i, kind := range order {
+		stageIndex[kind] = i
+	}
+	stages := make([][]*Task, len(order)+1)
+	for _, task := range tasks {
+		i, ok := stageIndex[getKillKind(task)]
+		if !ok {
+			i = len(order)
+		}
+		stages[i] = append(stages[i], task)
+	}
+	result := make([][]*Task, 0, len(stages))
+	for _, stage := range stages {
+		if len(stage) > 0 {
+			result = append(result, stage)
+		}
+	}
+	return result
+}
+
+// removeKilledApp removes the killed app from the core, which releases all its allocations
+func removeKilledApp(app *Application, progress *killProgress) {
+	rr := common.CreateUpdateRequestForRemoveApplication(app.applicationID, app.partition)
+	rr.RmID = app.getRmID()
+	if err := app.schedulerAPI.Update(&rr); err != nil {
+		app.logger().Warn("failed to remove killed app from the core", zap.Error(err))
+		progress.onRemoveFailed()
+	}
+}
+
+// deleteKilledPods deletes the pods of a killed app stage by stage, the pods of a stage are deleted
+// by a pool of workers and throttled by the configured rate. A stage is started once all the pods
+// of the previous stage have been deleted. This blocks until all the stages are done.
+func deleteKilledPods(app *Application, stages [][]*Task, progress *killProgress, gracePeriod time.Duration) {
+	workers, qps := conf.GetSchedulerConf().GetKillDeletionLimits()
+	var limiter flowcontrol.RateLimiter
+	if qps > 0 {
+		limiter = flowcontrol.NewTokenBucketRateLimiter(float32(qps), workers)
+		defer limiter.Stop()
+	}
+	for _, stage := range stages {
+		tasks := make(chan *Task)
+		var wg sync.WaitGroup
+		for i := 0; i < workers && i < len(stage); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for task := range tasks {
+					if limiter != nil {
+						limiter.Accept()
+					}
+					deleteKilledPod(task, progress, gracePeriod)
+				}
+			}()
+		}
+		for _, task := range stage {
+			tasks <- task
+		}
+		close(tasks)
+		wg.Wait()
+		total, deleted, failed := progress.get()
+		app.logger().Info("deleted a stage of the pods of the killed app",
+			zap.String("kind", getKillKind(stage[0])),
+			zap.Int32("total", total),
+			zap.Int32("deleted", deleted),
+			zap.Int32("failed", failed))
+	}
+}
+
+// deleteKilledPod deletes the pod of a task of a killed app with the grace period, a pod that is already gone counts
+// as deleted. With the job tracking compatibility enabled a terminating pod tracked by the Job controller is not
+// deleted again, the app waits for it. A placeholder that failed to be deleted is handed over to the placeholder
+// manager to be retried.
+func deleteKilledPod(task *Task, progress *killProgress, gracePeriod time.Duration) {
+	// the pod is already deleted, the Job controller removes it once it has accounted for it
+	if conf.GetSchedulerConf().JobTrackingCompatibility && utils.IsTerminatingTrackedPod(task.GetTaskPod()) {
+		progress.onDeleted(task, false)
+		return
+	}
+	err := task.context.apiProvider.GetAPIs().KubeClient.DeleteWithGracePeriod(task.pod, gracePeriod)
+	if err == nil || apierrors.IsNotFound(err) {
+		progress.onDeleted(task, err != nil)
+		return
+	}
+	task.logger().Warn("failed to delete the pod of a killed app", zap.Error(err))
+	progress.onFailed(task)
+	if task.placeholder {
+		mgr := getPlaceholderManager()
+		mgr.Lock()
+		defer mgr.Unlock()
+		mgr.addOrphanPlaceholder(task.taskID, task.pod, err)
+	}
+}
@@ -0,0 +1,238 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/dispatcher"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+func TestDeleteKilledPods(t *testing.T) {
+	mockedAPIProvider := client.NewMockedAPIProvider()
+	context := NewContext(mockedAPIProvider)
+	mgr := NewPlaceholderManager(mockedAPIProvider.GetAPIs())
+	app := NewApplication("app00001", "root.a", "test-user", map[string]string{}, newMockSchedulerAPI())
+
+	var lock sync.Mutex
+	deleted := make([]string, 0)
+	mockedAPIProvider.MockDeleteFn(func(pod *v1.Pod) error {
+		lock.Lock()
+		defer lock.Unlock()
+		switch pod.Name {
+		case "worker-gone":
+			return apierrors.NewNotFound(schema.GroupResource{Resource: "pods"}, pod.Name)
+		case "placeholder-failed":
+			return fmt.Errorf("delete failed")
+		}
+		deleted = append(deleted, pod.Name)
+		return nil
+	})
+
+	newTask := func(name string, placeholder bool, labels map[string]string) *Task {
+		task := NewTask(name, app, context, &v1.Pod{
+			ObjectMeta: apis.ObjectMeta{
+				Name:   name,
+				Labels: labels,
+			},
+		})
+		task.placeholder = placeholder
+		return task
+	}
+	driverLabels := map[string]string{constants.SparkLabelRole: constants.SparkLabelRoleDriver}
+	tasks := []*Task{
+		newTask("driver", false, driverLabels),
+		newTask("worker-1", false, nil),
+		newTask("placeholder-1", true, nil),
+		newTask("worker-gone", false, nil),
+		newTask("placeholder-failed", true, nil),
+	}
+	for _, task := range tasks {
+		app.addTask(task)
+	}
+	assert.Equal(t, getKillKind(tasks[0]), conf.KillOrderDriver)
+	assert.Equal(t, getKillKind(tasks[1]), conf.KillOrderWorker)
+	assert.Equal(t, getKillKind(tasks[2]), conf.KillOrderPlaceholder)
+
+	// the kinds missing from the order are in the last stage
+	stages := getKillStages(tasks, []string{conf.KillOrderDriver, conf.KillOrderPlaceholder})
+	assert.Equal(t, len(stages), 3)
+	assert.Equal(t, len(stages[0]), 1)
+	assert.Equal(t, len(stages[1]), 2)
+	assert.Equal(t, len(stages[2]), 2)
+
+	// placeholders first and the driver last, every stage is done before the next one starts
+	stages = getKillStages(tasks, []string{conf.KillOrderPlaceholder, conf.KillOrderWorker, conf.KillOrderDriver})
+	assert.Equal(t, len(stages), 3)
+	progress := newKillProgress(tasks, nil)
+	deleteKilledPods(app, stages, progress, time.Second)
+	assert.DeepEqual(t, deleted, []string{"placeholder-1", "worker-1", "driver"})
+	total, deletedPods, failed := progress.get()
+	assert.Equal(t, total, int32(5))
+	assert.Equal(t, deletedPods, int32(4))
+	assert.Equal(t, failed, int32(1))
+
+	// the placeholder that failed to be deleted is retried by the placeholder manager
+	mgr.Lock()
+	defer mgr.Unlock()
+	_, ok := mgr.orphanPods["placeholder-failed"]
+	assert.Assert(t, ok)
+
+	// the pods already gone and the ones that failed to be deleted are not waited for
+	pods, allocations := progress.getPending()
+	assert.Equal(t, pods, 3)
+	assert.Equal(t, allocations, 0)
+}
+
+func TestKillProgress(t *testing.T) {
+	context := initContextForTest()
+	app := NewApplication("app-kill-progress", "root.a", "test-user", map[string]string{}, newMockSchedulerAPI())
+	tasks := make([]*Task, 0)
+	for i := 0; i < 3; i++ {
+		taskID := fmt.Sprintf("task-%d", i)
+		task := NewTask(taskID, app, context, &v1.Pod{
+			ObjectMeta: apis.ObjectMeta{
+				Name: taskID,
+			},
+		})
+		task.sm.SetState(events.States().Task.Bound)
+		tasks = append(tasks, task)
+	}
+	progress := newKillProgress(tasks, []string{"alloc-0", "alloc-1"})
+	progress.onDeleted(tasks[0], false)
+	progress.onDeleted(tasks[1], true)
+	progress.onFailed(tasks[2])
+	pods, allocations := progress.getPending()
+	assert.Equal(t, pods, 1)
+	assert.Equal(t, allocations, 2)
+	assert.Assert(t, !progress.isDone())
+
+	// the pod is terminated and the core released the allocations
+	tasks[0].sm.SetState(events.States().Task.Completed)
+	progress.onReleased("alloc-0")
+	progress.onReleased("unknown")
+	pods, allocations = progress.getPending()
+	assert.Equal(t, pods, 0)
+	assert.Equal(t, allocations, 1)
+	progress.onReleased("alloc-1")
+	assert.Assert(t, progress.isDone())
+
+	// the allocations are not waited for when the app could not be removed from the core
+	progress = newKillProgress(nil, []string{"alloc-0"})
+	assert.Assert(t, !progress.isDone())
+	progress.onRemoveFailed()
+	assert.Assert(t, progress.isDone())
+	assert.Assert(t, progress.isRemoveFailed())
+}
+
+func TestKillAppWithTrackedPods(t *testing.T) {
+	context := initContextForTest()
+	NewPlaceholderManager(context.apiProvider.GetAPIs())
+	dispatcher.RegisterEventHandler(dispatcher.EventTypeApp, context.ApplicationEventHandler())
+	dispatcher.RegisterEventHandler(dispatcher.EventTypeTask, context.TaskEventHandler())
+	dispatcher.Start()
+	defer dispatcher.Stop()
+	conf.GetSchedulerConf().JobTrackingCompatibility = true
+	defer func() { conf.GetSchedulerConf().JobTrackingCompatibility = false }()
+
+	appID := "app-kill-tracked"
+	var lock sync.Mutex
+	deleted := make([]string, 0)
+	// the pods are gone once the Job controller has accounted for them
+	context.apiProvider.(*client.MockedAPIProvider).MockDeleteFn(func(pod *v1.Pod) error {
+		lock.Lock()
+		defer lock.Unlock()
+		deleted = append(deleted, pod.Name)
+		context.NotifyTaskComplete(appID, string(pod.UID))
+		return nil
+	})
+	var terminatedOnRemoval []bool
+	mockedSchedulerAPI := newMockSchedulerAPI()
+	app := NewApplication(appID, "root.a", "test-user", map[string]string{}, mockedSchedulerAPI)
+	context.applications.put(app)
+	tasks := make([]*Task, 0)
+	mockedSchedulerAPI.updateFn = func(request *si.UpdateRequest) error {
+		if len(request.RemoveApplications) > 0 {
+			lock.Lock()
+			defer lock.Unlock()
+			for _, task := range tasks {
+				terminatedOnRemoval = append(terminatedOnRemoval, task.isTerminated())
+			}
+		}
+		return nil
+	}
+	for i, finalizers := range [][]string{{constants.JobTrackingFinalizer}, nil} {
+		taskID := fmt.Sprintf("task-tracked-%d", i)
+		task := NewTask(taskID, app, context, &v1.Pod{
+			ObjectMeta: apis.ObjectMeta{
+				Name:       taskID,
+				UID:        types.UID(taskID),
+				Finalizers: finalizers,
+			},
+		})
+		task.sm.SetState(events.States().Task.Bound)
+		task.setAllocationUUID("alloc-" + taskID)
+		app.addTask(task)
+		tasks = append(tasks, task)
+	}
+	// a tracked pod that is already terminating is not deleted again
+	now := apis.Now()
+	terminating := NewTask("task-terminating", app, context, &v1.Pod{
+		ObjectMeta: apis.ObjectMeta{
+			Name:              "task-terminating",
+			UID:               "task-terminating",
+			Finalizers:        []string{constants.JobTrackingFinalizer},
+			DeletionTimestamp: &now,
+		},
+	})
+	terminating.sm.SetState(events.States().Task.Running)
+	app.addTask(terminating)
+	tasks = append(tasks, terminating)
+
+	// the app is removed from the core once the pods are gone, their tasks released the allocations
+	app.SetState(events.States().Application.Running)
+	assert.NilError(t, context.KillApplication(appID))
+	assertAppState(t, app, events.States().Application.Killing, 300*time.Millisecond)
+	lock.Lock()
+	assert.Equal(t, len(terminatedOnRemoval), 0)
+	lock.Unlock()
+	context.NotifyTaskComplete(appID, terminating.taskID)
+	assertAppState(t, app, events.States().Application.Killed, 3*time.Second)
+	lock.Lock()
+	defer lock.Unlock()
+	sort.Strings(deleted)
+	assert.DeepEqual(t, deleted, []string{"task-tracked-0", "task-tracked-1"})
+	assert.DeepEqual(t, terminatedOnRemoval, []bool{true, true, true})
+}
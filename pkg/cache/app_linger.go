@@ -0,0 +1,145 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/apis/yunikorn.apache.org/v1alpha1"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/appmgmt/interfaces"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
+)
+
+// canLinger returns true if the app holds a reservation a retry could reclaim: it has task groups
+// and some of its placeholders are still bound. The caller must hold the app lock.
+func (app *Application) canLinger() bool {
+	if len(app.taskGroups) == 0 {
+		return false
+	}
+	for _, task := range app.taskMap {
+		if task.placeholder && isBoundState(task.GetTaskState()) {
+			return true
+		}
+	}
+	return false
+}
+
+// isLingering returns true if the app is completed and keeps its placeholders for a retry
+func (app *Application) isLingering() bool {
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	return app.lingering
+}
+
+// startLinger starts the linger window of a completed app that kept its placeholders, the app stays in the core
+// with its reservation until the window ends or a retry reclaims it. It returns false if the app does not linger.
+func (ctx *Context) startLinger(app *Application, window time.Duration) bool {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	if !app.lingering {
+		return false
+	}
+	app.logger().Info("keeping the placeholders of the completed app for a retry",
+		zap.Duration("lingerWindow", window))
+	app.publishAppEvent(v1.EventTypeNormal, "PlaceholdersLingering",
+		"placeholders are kept for %s for a retry of the app", window)
+	app.lingerTimer = time.AfterFunc(window, func() {
+		ctx.endLinger(app)
+	})
+	return true
+}
+
+// endLinger ends the linger window of a completed app, the placeholders that were not reclaimed
+// are cleaned up and the app is released like any other completed app.
+func (ctx *Context) endLinger(app *Application) {
+	app.lock.Lock()
+	if !app.lingering {
+		app.lock.Unlock()
+		return
+	}
+	app.lingering = false
+	if app.lingerTimer != nil {
+		app.lingerTimer.Stop()
+		app.lingerTimer = nil
+	}
+	app.lock.Unlock()
+	app.logger().Info("placeholders of the completed app are not reclaimed, cleaning up")
+	go func() {
+		getPlaceholderManager().cleanUp(app)
+	}()
+	ctx.releaseCompletedApplication(app)
+}
+
+// reclaimReservation hands the placeholders kept by a completed app to its retry, the retry is recognised
+// by the app ID and the task groups it is submitted with. The app runs again, the members of the retry replace
+// the placeholders that are still bound. A resubmission with other task groups ends the linger window instead.
+func (ctx *Context) reclaimReservation(app *Application, request *interfaces.AddApplicationRequest) bool {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	if !app.lingering {
+		return false
+	}
+	if !sameGangShape(app.taskGroups, request.Metadata.TaskGroups) {
+		app.logger().Info("resubmitted app has other task groups, the placeholders are not reclaimed")
+		go ctx.endLinger(app)
+		return false
+	}
+	if err := app.sm.Event(string(events.ReclaimApplication)); err != nil {
+		app.logger().Warn("failed to reclaim the placeholders of the completed app", zap.Error(err))
+		return false
+	}
+	app.lingering = false
+	if app.lingerTimer != nil {
+		app.lingerTimer.Stop()
+		app.lingerTimer = nil
+	}
+	app.logger().Info("retry of the completed app reclaimed its placeholders")
+	app.publishAppEvent(v1.EventTypeNormal, "ReservationReclaimed",
+		"retry of the app reclaimed the placeholders kept after completion")
+	return true
+}
+
+// sameGangShape returns true if both lists have the same task groups with the same min members and min resources
+func sameGangShape(current, retry []v1alpha1.TaskGroup) bool {
+	if len(current) != len(retry) {
+		return false
+	}
+	taskGroups := make(map[string]v1alpha1.TaskGroup, len(current))
+	for _, tg := range current {
+		taskGroups[tg.Name] = tg
+	}
+	for _, tg := range retry {
+		// the task groups of the app are the ones its placeholders are created from
+		tg = utils.GetPlaceholderTaskGroup(tg)
+		existing, ok := taskGroups[tg.Name]
+		if !ok || existing.MinMember != tg.MinMember || len(existing.MinResource) != len(tg.MinResource) {
+			return false
+		}
+		for name, quantity := range tg.MinResource {
+			if existingQuantity, ok := existing.MinResource[name]; !ok || existingQuantity.Cmp(quantity) != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
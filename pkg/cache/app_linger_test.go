@@ -0,0 +1,205 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/apis/yunikorn.apache.org/v1alpha1"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/appmgmt/interfaces"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+func TestPlaceholderLinger(t *testing.T) {
+	conf.GetSchedulerConf().SetTestMode(true)
+	mockedAPIProvider := client.NewMockedAPIProvider()
+	mockedAPIProvider.GetAPIs().Conf.PlaceholderLingerWindow = time.Hour
+	mockedAPIProvider.GetAPIs().Conf.CompletedAppTombstone = time.Hour
+	context := NewContext(mockedAPIProvider)
+	NewPlaceholderManager(mockedAPIProvider.GetAPIs())
+	var lock sync.Mutex
+	removed := make([]string, 0)
+	deleted := make([]string, 0)
+	mockedAPIProvider.MockSchedulerApiUpdateFn(func(request *si.UpdateRequest) error {
+		lock.Lock()
+		defer lock.Unlock()
+		for _, remove := range request.RemoveApplications {
+			removed = append(removed, remove.ApplicationID)
+		}
+		return nil
+	})
+	mockedAPIProvider.MockDeleteFn(func(pod *v1.Pod) error {
+		lock.Lock()
+		defer lock.Unlock()
+		deleted = append(deleted, pod.Name)
+		return nil
+	})
+	getCalls := func() ([]string, []string) {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]string{}, removed...), append([]string{}, deleted...)
+	}
+
+	taskGroups := []v1alpha1.TaskGroup{{
+		Name:        "tg",
+		MinMember:   1,
+		MinResource: map[string]resource.Quantity{"cpu": resource.MustParse("1")},
+	}}
+	request := &interfaces.AddApplicationRequest{
+		Metadata: interfaces.ApplicationMetadata{
+			ApplicationID:    "app00001",
+			QueueName:        "root.a",
+			User:             "test-user",
+			TaskGroups:       taskGroups,
+			CompletionPolicy: conf.AppCompletionAllTasksTerminated,
+		},
+	}
+	context.AddApplication(request)
+	app := context.applications.get("app00001")
+	assert.Assert(t, app != nil)
+	addTask := func(taskID string, placeholder bool, state string) *Task {
+		context.AddTask(&interfaces.AddTaskRequest{
+			Metadata: interfaces.TaskMetadata{
+				ApplicationID: "app00001",
+				TaskID:        taskID,
+				Pod: &v1.Pod{
+					ObjectMeta: apis.ObjectMeta{
+						Name: "pod-" + taskID,
+						UID:  types.UID(taskID),
+					},
+				},
+				Placeholder:   placeholder,
+				TaskGroupName: "tg",
+			},
+		})
+		task, err := context.getTask("app00001", taskID)
+		assert.NilError(t, err)
+		task.sm.SetState(state)
+		return task
+	}
+	completeTask := func(task *Task) {
+		assert.NilError(t, task.handle(NewSimpleTaskEvent("app00001", task.taskID, events.CompleteTask)))
+		assert.NilError(t, utils.WaitForCondition(func() bool {
+			return app.GetApplicationState() == events.States().Application.Completed
+		}, 10*time.Millisecond, 5*time.Second))
+	}
+	addTask("ph-01", true, events.States().Task.Bound)
+	app.sm.SetState(events.States().Application.Running)
+
+	// the completed app keeps its placeholders and stays in the core
+	completeTask(addTask("task-01", false, events.States().Task.Bound))
+	assert.Assert(t, app.isLingering())
+	time.Sleep(50 * time.Millisecond)
+	removedApps, deletedPods := getCalls()
+	assert.Equal(t, len(removedApps), 0)
+	assert.Equal(t, len(deletedPods), 0)
+
+	// the retry with the same task groups runs the app again with the placeholders
+	assert.Equal(t, context.AddApplication(request), app)
+	assert.Equal(t, app.GetApplicationState(), events.States().Application.Running)
+	assert.Assert(t, !app.isLingering())
+
+	// a resubmission with other task groups ends the linger window
+	completeTask(addTask("task-02", false, events.States().Task.Bound))
+	assert.Assert(t, app.isLingering())
+	other := *request
+	other.Metadata.TaskGroups = []v1alpha1.TaskGroup{{Name: "tg", MinMember: 2}}
+	context.AddApplication(&other)
+	assert.NilError(t, utils.WaitForCondition(func() bool {
+		removedApps, deletedPods = getCalls()
+		return len(removedApps) == 1 && len(deletedPods) == 1
+	}, 10*time.Millisecond, 5*time.Second))
+	assert.DeepEqual(t, removedApps, []string{"app00001"})
+	assert.DeepEqual(t, deletedPods, []string{"pod-ph-01"})
+	assert.Equal(t, app.GetApplicationState(), events.States().Application.Completed)
+	assert.Assert(t, !app.isLingering())
+}
+
+func TestPlaceholderLingerWindowEnds(t *testing.T) {
+	conf.GetSchedulerConf().SetTestMode(true)
+	mockedAPIProvider := client.NewMockedAPIProvider()
+	mockedAPIProvider.GetAPIs().Conf.CompletedAppTombstone = time.Hour
+	context := NewContext(mockedAPIProvider)
+	NewPlaceholderManager(mockedAPIProvider.GetAPIs())
+	removed := make(chan string, 1)
+	mockedAPIProvider.MockSchedulerApiUpdateFn(func(request *si.UpdateRequest) error {
+		for _, remove := range request.RemoveApplications {
+			removed <- remove.ApplicationID
+		}
+		return nil
+	})
+
+	app := NewApplication("app00001", "root.a", "test-user", map[string]string{}, newMockSchedulerAPI())
+	app.setTaskGroups([]v1alpha1.TaskGroup{{Name: "tg", MinMember: 1}})
+	app.setCompletionPolicy(conf.AppCompletionAllTasksTerminated)
+	context.applications.put(app)
+	for _, taskID := range []string{"ph-01", "task-01"} {
+		task := NewTask(taskID, app, context, &v1.Pod{
+			ObjectMeta: apis.ObjectMeta{
+				Name: "pod-" + taskID,
+				UID:  types.UID(taskID),
+			},
+		})
+		task.placeholder = taskID == "ph-01"
+		task.sm.SetState(events.States().Task.Bound)
+		app.addTask(task)
+	}
+	app.sm.SetState(events.States().Application.Running)
+
+	// the app is released to the core once the window is over
+	app.taskMap["task-01"].sm.SetState(events.States().Task.Completed)
+	assert.Assert(t, app.completeIfTasksTerminated(100*time.Millisecond))
+	assert.Assert(t, context.startLinger(app, 100*time.Millisecond))
+	select {
+	case appID := <-removed:
+		assert.Equal(t, appID, "app00001")
+	case <-time.After(5 * time.Second):
+		t.Fatal("app is not removed from the core")
+	}
+	assert.Assert(t, !app.isLingering())
+	assert.Assert(t, !context.reclaimReservation(app, &interfaces.AddApplicationRequest{}))
+}
+
+func TestSameGangShape(t *testing.T) {
+	tg := func(name string, minMember int32, cpu string) v1alpha1.TaskGroup {
+		return v1alpha1.TaskGroup{
+			Name:        name,
+			MinMember:   minMember,
+			MinResource: map[string]resource.Quantity{"cpu": resource.MustParse(cpu)},
+		}
+	}
+	current := []v1alpha1.TaskGroup{tg("a", 1, "1"), tg("b", 2, "500m")}
+	assert.Assert(t, sameGangShape(current, []v1alpha1.TaskGroup{tg("b", 2, "0.5"), tg("a", 1, "1000m")}))
+	assert.Assert(t, !sameGangShape(current, []v1alpha1.TaskGroup{tg("a", 1, "1")}))
+	assert.Assert(t, !sameGangShape(current, []v1alpha1.TaskGroup{tg("a", 1, "1"), tg("c", 2, "500m")}))
+	assert.Assert(t, !sameGangShape(current, []v1alpha1.TaskGroup{tg("a", 2, "1"), tg("b", 2, "500m")}))
+	assert.Assert(t, !sameGangShape(current, []v1alpha1.TaskGroup{tg("a", 1, "2"), tg("b", 2, "500m")}))
+}
@@ -0,0 +1,130 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/dispatcher"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+)
+
+// the reasons given by the core when it rejects an app because its queue does not exist
+var unknownQueueReasons = []string{
+	"cannot create queue",
+	"rejected by placement rules",
+}
+
+// the reason of the pod condition and the events of the pods of an app whose queue does not exist
+const queueNotFoundReason = "QueueNotFound"
+
+func isUnknownQueueRejection(reason string) bool {
+	for _, unknownQueue := range unknownQueueReasons {
+		if strings.Contains(reason, unknownQueue) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleUnknownQueue applies the unknown queue policy to an app rejected because its queue does not exist:
+// the app is resubmitted once, to the fallback queue or with its queue in the placement tag so that a tag
+// placement rule can create the queue. When the app cannot be resubmitted, it fails and its pods are marked.
+// The caller must hold the app lock for writing, the queue of the app may be changed.
+func (app *Application) handleUnknownQueue(reason string) {
+	if !app.unknownQueueRetried {
+		switch conf.GetSchedulerConf().GetUnknownQueuePolicy() {
+		case conf.UnknownQueueFallback:
+			if fallback := conf.GetSchedulerConf().GetUnknownQueueFallback(); fallback != app.queue {
+				app.resubmitUnknownQueue(fallback, fmt.Sprintf("resubmitted to queue %s", fallback))
+				return
+			}
+		case conf.UnknownQueueCreate:
+			app.tags[constants.AppTagRequestedQueue] = app.queue
+			app.resubmitUnknownQueue(app.queue, "resubmitted for the queue to be created by the placement rules")
+			return
+		}
+	}
+	app.failUnknownQueue(reason)
+}
+
+func (app *Application) resubmitUnknownQueue(queue, message string) {
+	app.logger().Info("queue of the app does not exist, resubmitting the app",
+		zap.String("queue", app.queue),
+		zap.String("resubmitQueue", queue))
+	app.publishAppEvent(v1.EventTypeNormal, queueNotFoundReason, "queue %s does not exist, %s", app.queue, message)
+	app.unknownQueueRetried = true
+	app.queue = queue
+	app.setIdentity()
+	dispatcher.Dispatch(NewSubmitApplicationEvent(app.applicationID))
+}
+
+// failUnknownQueue fails the app, the pods waiting for the app get an explicit event and are marked
+// with the PodScheduled=False condition so that the cause is visible without reading the scheduler logs
+func (app *Application) failUnknownQueue(reason string) {
+	message := fmt.Sprintf("queue %s does not exist: %s", app.queue, reason)
+	// the app was never accepted, none of its tasks was submitted
+	tasks := app.getTasks(events.States().Task.New)
+	tasks = append(tasks, app.getTasks(events.States().Task.Gated)...)
+	pods := make([]*v1.Pod, 0, len(tasks))
+	for _, task := range tasks {
+		events.GetRecorder().Eventf(task.GetTaskPod(), v1.EventTypeWarning, queueNotFoundReason,
+			"Application %s rejected, %s", app.applicationID, message)
+		pods = append(pods, task.GetTaskPod())
+	}
+	if len(tasks) > 0 {
+		// the pod status is updated outside of the state transition
+		go tasks[0].context.updateQueueNotFoundCondition(pods, message)
+	}
+	dispatcher.Dispatch(NewFailApplicationEvent(app.applicationID, message))
+}
+
+// updateQueueNotFoundCondition sets the PodScheduled=False condition of the pods of an app whose queue does not exist
+func (ctx *Context) updateQueueNotFoundCondition(pods []*v1.Pod, message string) {
+	if ctx.apiProvider.IsTestingMode() {
+		return
+	}
+	condition := &v1.PodCondition{
+		Type:    v1.PodScheduled,
+		Status:  v1.ConditionFalse,
+		Reason:  queueNotFoundReason,
+		Message: message,
+	}
+	for _, pod := range pods {
+		updated := pod.DeepCopy()
+		if !podutil.UpdatePodCondition(&updated.Status, condition) {
+			continue
+		}
+		if _, err := ctx.apiProvider.GetAPIs().KubeClient.GetClientSet().CoreV1().
+			Pods(updated.Namespace).UpdateStatus(updated); err != nil {
+			log.Logger().Warn("failed to update the pod condition",
+				zap.String("namespace", updated.Namespace),
+				zap.String("podName", updated.Name),
+				zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,120 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+const unknownQueueReason = "application 'app00001' rejected, cannot create queue 'root.missing' without placement rules"
+
+func TestIsUnknownQueueRejection(t *testing.T) {
+	assert.Assert(t, isUnknownQueueRejection(unknownQueueReason))
+	assert.Assert(t, isUnknownQueueRejection("application rejected by placement rules: app00001"))
+	assert.Assert(t, !isUnknownQueueRejection("queue root.a cannot fit application app00001"))
+	assert.Assert(t, !isUnknownQueueRejection(""))
+}
+
+func TestRejectUnknownQueue(t *testing.T) {
+	context := initContextForTest()
+	recorder := record.NewFakeRecorder(1024)
+	events.SetRecorderForTest(recorder)
+	defer events.SetRecorderForTest(events.NewMockedRecorder())
+	schedulerConf := conf.GetSchedulerConf()
+	defer func() {
+		schedulerConf.UnknownQueuePolicy = ""
+		schedulerConf.UnknownQueueFallback = ""
+	}()
+
+	submitted := make([]*si.AddApplicationRequest, 0)
+	schedulerAPI := newMockSchedulerAPI()
+	schedulerAPI.updateFn = func(request *si.UpdateRequest) error {
+		submitted = append(submitted, request.NewApplications...)
+		return nil
+	}
+	newRejectedApp := func() (*Application, *Task) {
+		app := NewApplication("app00001", "root.missing", "test-user", map[string]string{}, schedulerAPI)
+		app.setOwnReferences([]apis.OwnerReference{{APIVersion: "batch/v1", Kind: "Job", Name: "job", UID: "UID-job"}})
+		task := NewTask("task00001", app, context, &v1.Pod{
+			ObjectMeta: apis.ObjectMeta{Name: "pod00001", UID: "UID-00001"},
+		})
+		app.addTask(task)
+		assert.NilError(t, app.handle(NewSubmitApplicationEvent(app.applicationID)))
+		assert.NilError(t, app.handle(NewRejectApplicationEvent(app.applicationID, unknownQueueReason)))
+		assert.Equal(t, app.GetApplicationState(), events.States().Application.Rejected)
+		return app, task
+	}
+	drain := func() []string {
+		recorded := make([]string, 0)
+		for len(recorder.Events) > 0 {
+			recorded = append(recorded, <-recorder.Events)
+		}
+		return recorded
+	}
+
+	// the app is resubmitted once to the fallback queue
+	schedulerConf.UnknownQueuePolicy = conf.UnknownQueueFallback
+	schedulerConf.UnknownQueueFallback = "root.sandbox"
+	app, _ := newRejectedApp()
+	assert.Equal(t, app.GetQueue(), "root.sandbox")
+	assert.Equal(t, app.metricsContext("tg").Queue, "root.sandbox", "the logs and metrics must follow the queue change")
+	assert.Assert(t, app.unknownQueueRetried)
+	assert.Assert(t, contains(drain(), "Normal QueueNotFound Application app00001: queue root.missing does not exist, resubmitted to queue root.sandbox"))
+	assert.NilError(t, app.handle(NewSubmitApplicationEvent(app.applicationID)))
+	assert.Equal(t, app.GetApplicationState(), events.States().Application.Submitted)
+	assert.Equal(t, submitted[len(submitted)-1].QueueName, "root.sandbox")
+	// a second rejection fails the app
+	assert.NilError(t, app.handle(NewRejectApplicationEvent(app.applicationID, unknownQueueReason)))
+	assert.Assert(t, contains(drain(), "Warning QueueNotFound Application app00001 rejected, queue root.sandbox does not exist: "+unknownQueueReason))
+
+	// the app is resubmitted with its queue in the placement tag
+	schedulerConf.UnknownQueuePolicy = conf.UnknownQueueCreate
+	app, _ = newRejectedApp()
+	assert.Equal(t, app.GetQueue(), "root.missing")
+	assert.Equal(t, app.GetTags()[constants.AppTagRequestedQueue], "root.missing")
+	assert.NilError(t, app.handle(NewSubmitApplicationEvent(app.applicationID)))
+	assert.Equal(t, submitted[len(submitted)-1].Tags[constants.AppTagRequestedQueue], "root.missing")
+	drain()
+
+	// the app and its pods fail
+	schedulerConf.UnknownQueuePolicy = conf.UnknownQueueFail
+	app, task := newRejectedApp()
+	assert.Assert(t, !app.unknownQueueRetried)
+	assert.Equal(t, task.GetTaskState(), events.States().Task.New)
+	assert.Assert(t, contains(drain(), "Warning QueueNotFound Application app00001 rejected, queue root.missing does not exist: "+unknownQueueReason))
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/looplab/fsm"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/dispatcher"
+)
+
+// setReservationProgressPolicy sets how long the reservation of the app may go without a new placeholder
+// bound, and what happens once it stalls, this is only called before the app is added to the cache
+func (app *Application) setReservationProgressPolicy(timeoutInSec int64, style string) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	if timeoutInSec > 0 {
+		app.progressTimeout = time.Duration(timeoutInSec) * time.Second
+	}
+	if style != "" {
+		app.gangSchedulingStyle = style
+	}
+}
+
+// startProgressTimer (re)starts the timer of the reservation, each new placeholder bound restarts it.
+// The caller must hold the app lock, the timer takes it when it fires.
+func (app *Application) startProgressTimer() {
+	app.stopProgressTimer()
+	if app.progressTimeout <= 0 {
+		return
+	}
+	progressTime := time.Now()
+	app.progressTime = progressTime
+	app.progressTimer = time.AfterFunc(app.progressTimeout, func() {
+		app.handleReservationProgressTimeout(progressTime)
+	})
+}
+
+// stopProgressTimer stops the timer of the reservation, the caller must hold the app lock
+func (app *Application) stopProgressTimer() {
+	if app.progressTimer != nil {
+		app.progressTimer.Stop()
+		app.progressTimer = nil
+	}
+}
+
+// startReservationWatchdog starts the timer ending the reservation once the placeholder timeout of the app
+// and the grace period are passed, in case the timeout of the core was lost or never sent.
+// The caller must hold the app lock, the timer takes it when it fires.
+func (app *Application) startReservationWatchdog() {
+	app.stopReservationWatchdog()
+	grace, enabled := conf.GetSchedulerConf().GetReservationWatchdogGrace()
+	if !enabled {
+		return
+	}
+	timeout := conf.DefaultPlaceholderTimeout
+	if app.placeholderTimeoutInSec > 0 {
+		timeout = time.Duration(app.placeholderTimeoutInSec) * time.Second
+	}
+	reservationStart := time.Now()
+	app.reservationStart = reservationStart
+	app.reservationTimer = time.AfterFunc(timeout+grace, func() {
+		app.handleReservationWatchdog(reservationStart, timeout+grace)
+	})
+}
+
+// stopReservationWatchdog stops the timer of the reservation watchdog, the caller must hold the app lock
+func (app *Application) stopReservationWatchdog() {
+	if app.reservationTimer != nil {
+		app.reservationTimer.Stop()
+		app.reservationTimer = nil
+	}
+}
+
+func (app *Application) leaveReserving(event *fsm.Event) {
+	app.stopProgressTimer()
+	app.stopReservationWatchdog()
+	app.stopGangStatusTimer()
+	app.releaseReservation()
+	// the final statistics of the reservation are kept in the CRD
+	dispatcher.Dispatch(NewApplicationTaskGroupsChangeEvent(app.applicationID))
+}
+
+// leaveAccepted gives up the place of the app waiting to reserve, or the slot it acquired,
+// when the app moves on without reserving, e.g. it is killed while waiting
+func (app *Application) leaveAccepted(event *fsm.Event) {
+	if event.Dst != events.States().Application.Reserving {
+		app.releaseReservation()
+	}
+}
+
+// handleReservationWatchdog is called when the app is still reserving after its placeholder timeout
+// and the grace period, the core should have timed out the placeholders by then.
+func (app *Application) handleReservationWatchdog(reservationStart time.Time, deadline time.Duration) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+
+	// the app has already left the Reserving state, or entered it again after the timer was started
+	if app.sm.Current() != events.States().Application.Reserving || !app.reservationStart.Equal(reservationStart) {
+		return
+	}
+	app.reservationTimer = nil
+	message := fmt.Sprintf("still reserving after %s, %d/%d placeholders bound",
+		deadline.String(), app.reportedBoundPlaceholders, app.getDesiredPlaceholders())
+	app.logger().Warn("reservation not timed out by the core",
+		zap.String("message", message),
+		zap.String("gangSchedulingStyle", app.gangSchedulingStyle))
+	app.endReservation("ReservationTimedOut", message)
+}
+
+// handleReservationProgressTimeout is called when no placeholder is bound within the progress timeout.
+// A Soft gang falls back to the normal scheduling: the placeholders are cleaned up and the app runs,
+// a Hard gang is failed.
+func (app *Application) handleReservationProgressTimeout(progressTime time.Time) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+
+	// the app has already left the Reserving state,
+	// or the reservation made progress after the timer was started
+	if app.sm.Current() != events.States().Application.Reserving || !app.progressTime.Equal(progressTime) {
+		return
+	}
+	app.progressTimer = nil
+	message := fmt.Sprintf("no placeholder bound in %s, %d/%d placeholders bound",
+		app.progressTimeout.String(), app.reportedBoundPlaceholders, app.getDesiredPlaceholders())
+	app.logger().Info("reservation stalled",
+		zap.String("message", message),
+		zap.String("gangSchedulingStyle", app.gangSchedulingStyle))
+	app.endReservation("ReservationStalled", message)
+}
+
+// endReservation gives up the reservation of the app following its gang scheduling style:
+// a Soft gang falls back to the normal scheduling once its placeholders are cleaned up, a Hard gang is failed.
+// The caller holds the app lock.
+func (app *Application) endReservation(reason, message string) {
+	if app.gangSchedulingStyle == constants.SchedulingPolicyStyleHard {
+		app.publishAppEvent(v1.EventTypeWarning, reason, "%s, the app is failed", message)
+		dispatcher.Dispatch(NewFailApplicationEvent(app.applicationID, message))
+		return
+	}
+	app.publishAppEvent(v1.EventTypeWarning, reason,
+		"%s, the app falls back to the normal scheduling", message)
+	go func() {
+		getPlaceholderManager().cleanUp(app)
+		dispatcher.Dispatch(NewRunApplicationEvent(app.applicationID))
+	}()
+}
@@ -0,0 +1,196 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/apis/yunikorn.apache.org/v1alpha1"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/dispatcher"
+)
+
+func TestSetReservationProgressPolicy(t *testing.T) {
+	app := NewApplication("app00001", "root.abc", "test-user", map[string]string{}, newMockSchedulerAPI())
+	assert.Equal(t, app.progressTimeout, time.Duration(0))
+	assert.Equal(t, app.gangSchedulingStyle, constants.SchedulingPolicyStyleSoft)
+	app.setReservationProgressPolicy(0, "")
+	assert.Equal(t, app.progressTimeout, time.Duration(0))
+	assert.Equal(t, app.gangSchedulingStyle, constants.SchedulingPolicyStyleSoft)
+	app.setReservationProgressPolicy(30, constants.SchedulingPolicyStyleHard)
+	assert.Equal(t, app.progressTimeout, 30*time.Second)
+	assert.Equal(t, app.gangSchedulingStyle, constants.SchedulingPolicyStyleHard)
+}
+
+func TestReservationProgressTimeout(t *testing.T) {
+	context := initContextForTest()
+	dispatcher.RegisterEventHandler(dispatcher.EventTypeApp, context.ApplicationEventHandler())
+	dispatcher.Start()
+	defer dispatcher.Stop()
+
+	createdPods := newThreadSafePodsMap()
+	mockedAPIProvider := client.NewMockedAPIProvider()
+	mockedAPIProvider.MockCreateFn(func(pod *v1.Pod) (*v1.Pod, error) {
+		createdPods.add(pod)
+		return pod, nil
+	})
+	mgr := NewPlaceholderManager(mockedAPIProvider.GetAPIs())
+	mgr.Start()
+	defer mgr.Stop()
+
+	reserve := func(appID, style string) *Application {
+		app := NewApplication(appID, "root.abc", "test-user",
+			map[string]string{}, mockedAPIProvider.GetAPIs().SchedulerAPI)
+		app.setTaskGroups([]v1alpha1.TaskGroup{
+			{
+				Name:      "test-group-1",
+				MinMember: 2,
+				MinResource: map[string]resource.Quantity{
+					v1.ResourceCPU.String(): resource.MustParse("500m"),
+				},
+			},
+		})
+		app.gangSchedulingStyle = style
+		app.progressTimeout = 200 * time.Millisecond
+		context.applications.put(app)
+		assert.NilError(t, app.handle(NewSubmitApplicationEvent(app.applicationID)))
+		assert.NilError(t, app.handle(NewSimpleApplicationEvent(app.applicationID, events.AcceptApplication)))
+		app.Schedule()
+		assertAppState(t, app, events.States().Application.Reserving, 3*time.Second)
+		return app
+	}
+
+	// no placeholder is bound, the Hard gang is failed
+	app := reserve("app00001", constants.SchedulingPolicyStyleHard)
+	assertAppState(t, app, events.States().Application.Failed, 3*time.Second)
+
+	// the Soft gang falls back to the normal scheduling
+	app = reserve("app00002", constants.SchedulingPolicyStyleSoft)
+	assertAppState(t, app, events.States().Application.Running, 3*time.Second)
+	err := utils.WaitForCondition(func() bool {
+		return createdPods.count() == 4
+	}, 100*time.Millisecond, 3*time.Second)
+	assert.NilError(t, err, "placeholders are not created")
+}
+
+func TestReservationProgressTimerRestarted(t *testing.T) {
+	app := NewApplication("app00001", "root.abc", "test-user", map[string]string{}, newMockSchedulerAPI())
+	app.progressTimeout = time.Hour
+	app.sm.SetState(events.States().Application.Reserving)
+	app.startProgressTimer()
+	started := app.progressTime
+	assert.Assert(t, app.progressTimer != nil)
+
+	// the timer of an earlier progress does not stall the reservation
+	time.Sleep(time.Millisecond)
+	app.startProgressTimer()
+	assert.Assert(t, app.progressTime.After(started))
+	app.handleReservationProgressTimeout(started)
+	assert.Equal(t, app.GetApplicationState(), events.States().Application.Reserving)
+	assert.Assert(t, app.progressTimer != nil)
+
+	// leaving the Reserving state stops the timer
+	app.leaveReserving(nil)
+	assert.Assert(t, app.progressTimer == nil)
+
+	// no timer without a progress timeout
+	app.progressTimeout = 0
+	app.startProgressTimer()
+	assert.Assert(t, app.progressTimer == nil)
+}
+
+func TestReservationWatchdog(t *testing.T) {
+	schedulerConf := conf.GetSchedulerConf()
+	grace := schedulerConf.ReservationWatchdogGrace
+	schedulerConf.ReservationWatchdogGrace = 0
+	defer func() {
+		schedulerConf.ReservationWatchdogGrace = grace
+	}()
+
+	context := initContextForTest()
+	dispatcher.RegisterEventHandler(dispatcher.EventTypeApp, context.ApplicationEventHandler())
+	dispatcher.Start()
+	defer dispatcher.Stop()
+
+	mockedAPIProvider := client.NewMockedAPIProvider()
+	mgr := NewPlaceholderManager(mockedAPIProvider.GetAPIs())
+	mgr.Start()
+	defer mgr.Stop()
+
+	// the core never times out the placeholders, the shim fails the Hard gang itself
+	app := NewApplication("app00001", "root.abc", "test-user",
+		map[string]string{}, mockedAPIProvider.GetAPIs().SchedulerAPI)
+	app.setTaskGroups([]v1alpha1.TaskGroup{
+		{
+			Name:      "test-group-1",
+			MinMember: 1,
+			MinResource: map[string]resource.Quantity{
+				v1.ResourceCPU.String(): resource.MustParse("500m"),
+			},
+		},
+	})
+	app.gangSchedulingStyle = constants.SchedulingPolicyStyleHard
+	app.SetPlaceholderTimeout(1)
+	context.applications.put(app)
+	assert.NilError(t, app.handle(NewSubmitApplicationEvent(app.applicationID)))
+	assert.NilError(t, app.handle(NewSimpleApplicationEvent(app.applicationID, events.AcceptApplication)))
+	app.Schedule()
+	assertAppState(t, app, events.States().Application.Reserving, 3*time.Second)
+	assertAppState(t, app, events.States().Application.Failed, 5*time.Second)
+}
+
+func TestReservationWatchdogStopped(t *testing.T) {
+	schedulerConf := conf.GetSchedulerConf()
+	grace := schedulerConf.ReservationWatchdogGrace
+	defer func() {
+		schedulerConf.ReservationWatchdogGrace = grace
+	}()
+
+	app := NewApplication("app00001", "root.abc", "test-user", map[string]string{}, newMockSchedulerAPI())
+	app.sm.SetState(events.States().Application.Reserving)
+	schedulerConf.ReservationWatchdogGrace = time.Hour
+	app.startReservationWatchdog()
+	started := app.reservationStart
+	assert.Assert(t, app.reservationTimer != nil)
+
+	// the timer of an earlier reservation does not end the current one
+	time.Sleep(time.Millisecond)
+	app.startReservationWatchdog()
+	app.handleReservationWatchdog(started, time.Hour)
+	assert.Equal(t, app.GetApplicationState(), events.States().Application.Reserving)
+	assert.Assert(t, app.reservationTimer != nil)
+
+	// leaving the Reserving state stops the timer
+	app.leaveReserving(nil)
+	assert.Assert(t, app.reservationTimer == nil)
+
+	// no timer when the watchdog is disabled
+	schedulerConf.ReservationWatchdogGrace = -1
+	app.startReservationWatchdog()
+	assert.Assert(t, app.reservationTimer == nil)
+}
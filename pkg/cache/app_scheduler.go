@@ -0,0 +1,138 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/metrics"
+)
+
+// appScheduler runs the scheduling cycles of the apps. The apps that are not reserving or running only
+// trigger their state transitions, these are handled one by one in the order of the apps so the submissions
+// to the core keep their order. The new tasks of the reserving and running apps are scheduled by up to
+// maxWorkers workers in parallel, an app submits at most budget new tasks in a cycle so an app with a
+// large number of new tasks does not delay the other apps. The first app handed to the workers rotates
+// from one cycle to the next, the same app is not always ahead of the others.
+type appScheduler struct {
+	maxWorkers int
+	budget     int
+	// the position of the first app handed to the workers in the next cycle
+	offset int
+	lock   sync.Mutex
+}
+
+func newAppScheduler(maxWorkers, budget int) *appScheduler {
+	if maxWorkers <= 0 {
+		maxWorkers = 1
+	}
+	return &appScheduler{
+		maxWorkers: maxWorkers,
+		budget:     budget,
+	}
+}
+
+// setLimits changes the limits of the next scheduling cycles, the cycle in progress keeps its limits
+func (s *appScheduler) setLimits(maxWorkers, budget int) {
+	if maxWorkers <= 0 {
+		maxWorkers = 1
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.maxWorkers = maxWorkers
+	s.budget = budget
+}
+
+// schedule runs one scheduling cycle over the apps, it returns once all apps are scheduled
+func (s *appScheduler) schedule(apps []*Application) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	start := time.Now()
+	states := events.States().Application
+	pending := make([]*Application, 0, len(apps))
+	for _, app := range apps {
+		switch app.GetApplicationState() {
+		case states.Reserving, states.Running:
+			pending = append(pending, app)
+		default:
+			app.schedule(s.budget)
+		}
+	}
+	s.scheduleTasks(s.rotate(pending))
+	metrics.GetSchedulingMetrics().ObserveCycle(time.Since(start))
+}
+
+// rotate returns the apps starting from the offset of the cycle and moves the offset to the next app
+func (s *appScheduler) rotate(apps []*Application) []*Application {
+	if len(apps) == 0 {
+		return apps
+	}
+	offset := s.offset % len(apps)
+	s.offset = offset + 1
+	rotated := make([]*Application, 0, len(apps))
+	rotated = append(rotated, apps[offset:]...)
+	return append(rotated, apps[:offset]...)
+}
+
+// scheduleTasks schedules the new tasks of the apps in parallel, the time spent on each app is reported
+// as a share of the time spent on all apps
+func (s *appScheduler) scheduleTasks(apps []*Application) {
+	if len(apps) == 0 {
+		return
+	}
+	workers := s.maxWorkers
+	if workers > len(apps) {
+		workers = len(apps)
+	}
+	queue := make(chan int, len(apps))
+	for i := range apps {
+		queue <- i
+	}
+	close(queue)
+	durations := make([]time.Duration, len(apps))
+	deferred := make([]int, len(apps))
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				start := time.Now()
+				deferred[i] = apps[i].schedule(s.budget)
+				durations[i] = time.Since(start)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var total time.Duration
+	totalDeferred := 0
+	for i := range apps {
+		total += durations[i]
+		totalDeferred += deferred[i]
+	}
+	if total > 0 {
+		for _, duration := range durations {
+			metrics.GetSchedulingMetrics().ObserveAppTimeShare(float64(duration) / float64(total))
+		}
+	}
+	metrics.GetSchedulingMetrics().AddDeferredTasks(totalDeferred)
+}
@@ -0,0 +1,103 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"testing"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/metrics"
+)
+
+func TestAppSchedulerBudget(t *testing.T) {
+	context := initContextForTest()
+	newApp := func(appID string, numTasks int) *Application {
+		app := NewApplication(appID, "root.abc", "test-user", map[string]string{}, newMockSchedulerAPI())
+		for i := 0; i < numTasks; i++ {
+			taskID := fmt.Sprintf("%s-task-%d", appID, i)
+			app.addTask(NewTask(taskID, app, context, &v1.Pod{
+				ObjectMeta: apis.ObjectMeta{
+					Name: taskID,
+					UID:  types.UID(taskID),
+				},
+			}))
+		}
+		return app
+	}
+	large := newApp("app-budget-large", 10)
+	large.SetState(events.States().Application.Running)
+	small := newApp("app-budget-small", 2)
+	small.SetState(events.States().Application.Running)
+	submitted := newApp("app-budget-new", 0)
+
+	deferred := metrics.GetSchedulingMetrics().GetDeferredTasks()
+	scheduler := newAppScheduler(2, 4)
+	apps := []*Application{large, small, submitted}
+
+	// the large app submits 4 tasks per cycle, the small app is not delayed
+	scheduler.schedule(apps)
+	assert.Equal(t, len(large.getTasks(events.States().Task.New)), 6)
+	assert.Equal(t, len(small.getTasks(events.States().Task.New)), 0)
+	assert.Equal(t, metrics.GetSchedulingMetrics().GetDeferredTasks(), deferred+6)
+	// the new app is submitted outside of the workers
+	assert.Equal(t, submitted.GetApplicationState(), events.States().Application.Submitted)
+
+	scheduler.schedule(apps)
+	assert.Equal(t, len(large.getTasks(events.States().Task.New)), 2)
+	scheduler.schedule(apps)
+	assert.Equal(t, len(large.getTasks(events.States().Task.New)), 0)
+	assert.Equal(t, metrics.GetSchedulingMetrics().GetDeferredTasks(), deferred+8)
+
+	// no budget
+	app := newApp("app-budget-unlimited", 10)
+	app.SetState(events.States().Application.Running)
+	newAppScheduler(0, 0).schedule([]*Application{app})
+	assert.Equal(t, len(app.getTasks(events.States().Task.New)), 0)
+}
+
+func TestAppSchedulerRotation(t *testing.T) {
+	apps := make([]*Application, 0)
+	for i := 0; i < 3; i++ {
+		apps = append(apps, NewApplication(fmt.Sprintf("app-rotate-%d", i), "root.abc", "test-user",
+			map[string]string{}, newMockSchedulerAPI()))
+	}
+	ids := func(apps []*Application) []string {
+		result := make([]string, 0, len(apps))
+		for _, app := range apps {
+			result = append(result, app.applicationID)
+		}
+		return result
+	}
+
+	scheduler := newAppScheduler(1, 0)
+	assert.DeepEqual(t, ids(scheduler.rotate(apps)), []string{"app-rotate-0", "app-rotate-1", "app-rotate-2"})
+	assert.DeepEqual(t, ids(scheduler.rotate(apps)), []string{"app-rotate-1", "app-rotate-2", "app-rotate-0"})
+	assert.DeepEqual(t, ids(scheduler.rotate(apps)), []string{"app-rotate-2", "app-rotate-0", "app-rotate-1"})
+	// the apps are not modified
+	assert.DeepEqual(t, ids(apps), []string{"app-rotate-0", "app-rotate-1", "app-rotate-2"})
+	// fewer apps in the next cycle
+	assert.DeepEqual(t, ids(scheduler.rotate(apps[:2])), []string{"app-rotate-1", "app-rotate-0"})
+	assert.Equal(t, len(scheduler.rotate(nil)), 0)
+}
@@ -21,7 +21,10 @@ package cache
 import (
 	"fmt"
 	"sort"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/looplab/fsm"
 	"go.uber.org/zap"
@@ -34,10 +37,13 @@ import (
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/sirequest"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/dispatcher"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/metrics"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/webservice/dao"
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
 
@@ -45,7 +51,9 @@ type Application struct {
 	applicationID              string
 	queue                      string
 	partition                  string
+	rmID                       string // the cluster the app is routed to, empty for the local cluster
 	user                       string
+	groups                     []string
 	taskMap                    map[string]*Task
 	tags                       map[string]string
 	schedulingPolicy           v1alpha1.SchedulingPolicy
@@ -54,8 +62,80 @@ type Application struct {
 	sm                         *fsm.FSM
 	lock                       *sync.RWMutex
 	schedulerAPI               api.SchedulerAPI
-	placeholderAsk             *si.Resource // total placeholder request for the app (all task groups)
+	placeholderAsk             *si.Resource            // total placeholder request for the app (all task groups)
+	resourceGangs              map[string]*si.Resource // the min resource of the task groups without min member
 	placeholderTimeoutInSec    int64
+	gangInfeasibleReason       string          // set when the gang of the app can never be satisfied
+	unschedulableTaskGroups    map[string]bool // task groups with placeholders that cannot be scheduled
+	taskOrderingPolicy         string          // the order new tasks are submitted to the core
+	placeholderProgress        *placeholderProgress
+	taskGroupIndexes           map[string]map[int32]bool // indexes taken by the real members per task group
+	restoredState              string                    // state of the app before the shim restarted, if checkpointed
+	reservedTaskGroups         map[string]bool           // task groups with placeholders requested in the current reservation
+	reportedBoundPlaceholders  int32                     // bound placeholders last published to the owner of the app
+	killProgress               *killProgress             // deletion of the pods of the app while it is killed
+	createTime                 time.Time                 // creation time of the oldest pod of the app, used to order the apps of a queue
+	completionPolicy           string                    // how the app is completed, set before the app is added to the cache
+	allocations                *allocationIndex          // tasks by the allocation UUID assigned by the core
+	progressTimeout            time.Duration             // max time the reservation may go without a new placeholder bound
+	gangSchedulingStyle        string                    // what happens when the reservation stalls: Soft falls back, Hard fails
+	progressTimer              *time.Timer               // fires when the reservation stalls
+	progressTime               time.Time                 // last time the reservation made progress
+	reservationTimer           *time.Timer               // fires when the core did not time out the reservation
+	reservationStart           time.Time                 // time the app entered the Reserving state
+	gangStatusTimer            *time.Timer               // fires when the statistics of the reserving gang are published
+	gangStatusRound            int64                     // identifies the reservation the statistics timer belongs to
+	maxParallelTasks           int                       // max tasks being scheduled at a time, 0 for no limit
+	maxReservingApps           int                       // max apps of the queue reserving at a time, 0 for no limit
+	runtimeClassName           string                    // runtime class of the pods, the placeholders run with it
+	unknownQueueRetried        bool                      // the app was resubmitted after its queue was not found
+	defaultTaskGroup           string                    // task group of the members without one, when the task groups are the namespace defaults
+	lingering                  bool                      // the completed app keeps its placeholders for a retry to reclaim
+	lingerTimer                *time.Timer               // fires when the placeholders kept after completion are not reclaimed
+	aging                      *priorityAging            // raises the priority of the app while it waits, nil if disabled
+	agedPriority               int32                     // priority added to the asks of the app by aging, accessed atomically
+	agingTime                  time.Time                 // start of the current wait of the app for an allocation
+	agingAllocations           int                       // allocations of the app at the last aging check
+	context                    *Context                  // context the app is added to, set before the app is added to the cache
+	identity                   atomic.Value              // *appIdentity, replaced when the queue of the app changes
+}
+
+// appIdentity is the queue and user of an app with the logger tagged with them,
+// it is immutable so that it can be read without the app lock.
+type appIdentity struct {
+	queue  string
+	user   string
+	logger *zap.Logger
+}
+
+// setIdentity captures the queue and user of the app, it must be called each time the queue changes.
+// The caller must hold the app lock, unless the app is not yet added to the cache.
+func (app *Application) setIdentity() {
+	app.identity.Store(&appIdentity{
+		queue:  app.queue,
+		user:   app.user,
+		logger: log.ForApplication(app.applicationID, app.queue, app.user),
+	})
+}
+
+func (app *Application) getIdentity() *appIdentity {
+	if identity, ok := app.identity.Load().(*appIdentity); ok {
+		return identity
+	}
+	return &appIdentity{logger: log.ForApplication(app.applicationID, "", "")}
+}
+
+// logger returns a logger tagged with the application context, it does not take the app lock
+// and can be used from the state machine callbacks as well as from other goroutines.
+func (app *Application) logger() *zap.Logger {
+	return app.getIdentity().logger
+}
+
+// metricsContext returns the labels of the metrics of the app and the given task group,
+// like the logger it does not take the app lock.
+func (app *Application) metricsContext(taskGroup string) metrics.Context {
+	identity := app.getIdentity()
+	return metrics.Context{Queue: identity.queue, User: identity.user, TaskGroup: taskGroup}
 }
 
 func (app *Application) String() string {
@@ -78,6 +158,23 @@ func NewApplication(appID, queueName, user string, tags map[string]string, sched
 		lock:                    &sync.RWMutex{},
 		schedulerAPI:            scheduler,
 		placeholderTimeoutInSec: 0,
+		unschedulableTaskGroups: make(map[string]bool),
+		taskOrderingPolicy:      TaskOrderingFIFO,
+		taskGroupIndexes:        make(map[string]map[int32]bool),
+		reservedTaskGroups:      make(map[string]bool),
+		createTime:              time.Now(),
+		allocations:             newAllocationIndex(),
+		gangSchedulingStyle:     constants.SchedulingPolicyStyleSoft,
+	}
+	app.setIdentity()
+	if value := tags[constants.AppTagNamespacePriorityAging]; value != "" {
+		aging, err := parsePriorityAging(value)
+		if err != nil {
+			app.logger().Warn("invalid priority aging policy, the priority of the app is not aged",
+				zap.String("priorityAging", value),
+				zap.Error(err))
+		}
+		app.aging = aging
 	}
 
 	var states = events.States().Application
@@ -85,7 +182,7 @@ func NewApplication(appID, queueName, user string, tags map[string]string, sched
 		states.New,
 		fsm.Events{
 			{Name: string(events.SubmitApplication),
-				Src: []string{states.New},
+				Src: []string{states.New, states.Rejected},
 				Dst: states.Submitted},
 			{Name: string(events.RecoverApplication),
 				Src: []string{states.New},
@@ -114,9 +211,18 @@ func NewApplication(appID, queueName, user string, tags map[string]string, sched
 			{Name: string(events.ReleaseAppAllocationAsk),
 				Src: []string{states.Failed},
 				Dst: states.Failed},
+			{Name: string(events.ReleaseTaskGroup),
+				Src: []string{states.Accepted},
+				Dst: states.Accepted},
+			{Name: string(events.ReleaseTaskGroup),
+				Src: []string{states.Reserving},
+				Dst: states.Reserving},
 			{Name: string(events.CompleteApplication),
 				Src: []string{states.Running},
 				Dst: states.Completed},
+			{Name: string(events.ReclaimApplication),
+				Src: []string{states.Completed},
+				Dst: states.Running},
 			{Name: string(events.RejectApplication),
 				Src: []string{states.Submitted},
 				Dst: states.Rejected},
@@ -129,6 +235,9 @@ func NewApplication(appID, queueName, user string, tags map[string]string, sched
 			{Name: string(events.KilledApplication),
 				Src: []string{states.Killing},
 				Dst: states.Killed},
+			{Name: string(events.ResumeApplication),
+				Src: []string{states.Failed},
+				Dst: states.Submitted},
 		},
 		fsm.Callbacks{
 			string(events.SubmitApplication):       app.handleSubmitApplicationEvent,
@@ -136,10 +245,16 @@ func NewApplication(appID, queueName, user string, tags map[string]string, sched
 			string(events.RejectApplication):       app.handleRejectApplicationEvent,
 			string(events.CompleteApplication):     app.handleCompleteApplicationEvent,
 			string(events.FailApplication):         app.handleFailApplicationEvent,
+			string(events.KillApplication):         app.handleKillApplicationEvent,
 			string(events.UpdateReservation):       app.onReservationStateChange,
 			events.States().Application.Reserving:  app.onReserving,
+			leaveHook(states.Accepted):             app.leaveAccepted,
+			leaveHook(states.Reserving):            app.leaveReserving,
 			string(events.ReleaseAppAllocation):    app.handleReleaseAppAllocationEvent,
 			string(events.ReleaseAppAllocationAsk): app.handleReleaseAppAllocationAskEvent,
+			string(events.ReleaseTaskGroup):        app.handleReleaseTaskGroupEvent,
+			string(events.ResumeApplication):       app.handleResumeApplicationEvent,
+			events.LeaveState:                      app.leaveState,
 			events.EnterState:                      app.enterState,
 		},
 	)
@@ -183,6 +298,15 @@ func (app *Application) GetTask(taskID string) (interfaces.ManagedTask, error) {
 		taskID, app.applicationID)
 }
 
+// GetTaskByAllocation returns the task of an allocation assigned by the core
+func (app *Application) GetTaskByAllocation(allocUUID string) (interfaces.ManagedTask, error) {
+	if task := app.allocations.get(allocUUID); task != nil {
+		return task, nil
+	}
+	return nil, fmt.Errorf("allocation %s doesn't exist in application %s",
+		allocUUID, app.applicationID)
+}
+
 func (app *Application) GetApplicationID() string {
 	app.lock.RLock()
 	defer app.lock.RUnlock()
@@ -201,6 +325,19 @@ func (app *Application) GetUser() string {
 	return app.user
 }
 
+// GetGroups returns the groups of the user the app is submitted as
+func (app *Application) GetGroups() []string {
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	return app.groups
+}
+
+func (app *Application) setGroups(groups []string) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.groups = groups
+}
+
 func (app *Application) setSchedulingPolicy(policy v1alpha1.SchedulingPolicy) {
 	app.lock.Lock()
 	defer app.lock.Unlock()
@@ -214,30 +351,432 @@ func (app *Application) getSchedulingPolicy() v1alpha1.SchedulingPolicy {
 }
 
 func (app *Application) setTaskGroups(taskGroups []v1alpha1.TaskGroup) {
+	// invalid dependencies are ignored, all the task groups are reserved at once
+	if err := utils.ValidateTaskGroupDependencies(taskGroups); err != nil {
+		app.logger().Warn("invalid taskGroup dependencies, reserving all taskGroups at once",
+			zap.Error(err))
+		independent := make([]v1alpha1.TaskGroup, len(taskGroups))
+		for i, tg := range taskGroups {
+			independent[i] = tg
+			independent[i].DependsOn = nil
+		}
+		taskGroups = independent
+	}
+	// the task groups without min member reserve their min resource with placeholders of the same size
+	resourceGangs := make(map[string]*si.Resource)
+	placeholderTaskGroups := make([]v1alpha1.TaskGroup, len(taskGroups))
+	for i, tg := range taskGroups {
+		if utils.IsResourceGang(tg) {
+			resourceGangs[tg.Name] = common.GetTGResource(tg.MinResource, 1)
+		}
+		placeholderTaskGroups[i] = utils.GetPlaceholderTaskGroup(tg)
+	}
 	app.lock.Lock()
 	defer app.lock.Unlock()
-	app.taskGroups = taskGroups
+	app.taskGroups = placeholderTaskGroups
+	app.resourceGangs = resourceGangs
 	for _, taskGroup := range app.taskGroups {
-		app.placeholderAsk = common.Add(app.placeholderAsk, common.GetTGResource(taskGroup.MinResource, int64(taskGroup.MinMember)))
+		app.placeholderAsk = common.Add(app.placeholderAsk, app.getTaskGroupAsk(taskGroup))
 	}
 }
 
+// getTaskGroupAsk returns the resources of the placeholders of the task group, the same as the placeholder pods
+// ask for. The caller must hold the app lock, the runtime class of the app is read.
+func (app *Application) getTaskGroupAsk(taskGroup v1alpha1.TaskGroup) *si.Resource {
+	return utils.GetPlaceholderResource(taskGroup.MinResource, app.runtimeClassName, int64(taskGroup.MinMember))
+}
+
+// setRuntimeClassName sets the runtime class the placeholders run with,
+// this must be called before the task groups are set
+func (app *Application) setRuntimeClassName(runtimeClassName string) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.runtimeClassName = runtimeClassName
+}
+
+func (app *Application) getRuntimeClassName() string {
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	return app.runtimeClassName
+}
+
+// setDefaultTaskGroup sets the task group the real members without one join,
+// this is only called before the app is added to the cache
+func (app *Application) setDefaultTaskGroup(taskGroupName string) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.defaultTaskGroup = taskGroupName
+}
+
+func (app *Application) getDefaultTaskGroup() string {
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	return app.defaultTaskGroup
+}
+
 func (app *Application) getPlaceholderAsk() *si.Resource {
 	app.lock.RLock()
 	defer app.lock.RUnlock()
 	return app.placeholderAsk
 }
 
+func (app *Application) setGangInfeasibleReason(reason string) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.gangInfeasibleReason = reason
+}
+
+func (app *Application) setTaskOrderingPolicy(policy string) {
+	if policy == "" {
+		return
+	}
+	if _, ok := getTaskOrderingPolicy(policy); !ok {
+		app.logger().Warn("unknown task ordering policy, fallback to FIFO",
+			zap.String("policy", policy))
+		return
+	}
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.taskOrderingPolicy = policy
+}
+
+// setCompletionPolicy sets how the app is completed, this is only called before the app is added to the cache,
+// the policy is read without holding the app lock afterwards
+func (app *Application) setCompletionPolicy(policy string) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.completionPolicy = policy
+}
+
+// setMaxParallelTasks sets the maximum number of tasks of the app being scheduled at a time
+func (app *Application) setMaxParallelTasks(maxTasks int) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.maxParallelTasks = maxTasks
+}
+
+// setMaxReservingApps sets the maximum number of apps of the queue reserving at a time requested by the app
+func (app *Application) setMaxReservingApps(maxApps int) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.maxReservingApps = maxApps
+}
+
+// getMaxReservingApps returns the maximum number of apps of the queue reserving at a time, 0 for no limit.
+// The limit configured for the queue wins over the limit requested by the app.
+// The caller must hold the app lock.
+func (app *Application) getMaxReservingApps() int {
+	if limit := conf.GetSchedulerConf().GetMaxReservingApps(app.queue); limit > 0 {
+		return limit
+	}
+	return app.maxReservingApps
+}
+
+// getParallelTaskSlots returns how many more tasks of the app can be scheduled, -1 if there is no limit.
+// The tasks being scheduled are the tasks submitted to the core and not bound yet, the placeholders
+// are not throttled: holding back a part of the gang would only delay the reservation.
+func (app *Application) getParallelTaskSlots() int {
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	if app.maxParallelTasks <= 0 {
+		return -1
+	}
+	states := events.States().Task
+	inFlight := 0
+	for _, task := range app.taskMap {
+		if task.placeholder {
+			continue
+		}
+		switch task.GetTaskState() {
+		case states.Pending, states.Scheduling, states.Allocated:
+			inFlight++
+		}
+	}
+	if inFlight >= app.maxParallelTasks {
+		return 0
+	}
+	return app.maxParallelTasks - inFlight
+}
+
+// setRoute sets the cluster and the partition the app is scheduled in, this is only called
+// before the app is added to the cache. An app routed to a cluster the shim is not registered
+// with falls back to the local cluster, it cannot be scheduled otherwise.
+func (app *Application) setRoute(clusterID, partition string) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	if clusterID != "" {
+		if conf.GetSchedulerConf().IsRegisteredCluster(clusterID) {
+			app.rmID = clusterID
+		} else {
+			app.logger().Warn("app is routed to an unknown cluster, fallback to the local cluster",
+				zap.String("clusterID", clusterID))
+		}
+	}
+	if partition != "" {
+		app.partition = partition
+	}
+}
+
+// getRmID returns the id of the RM the app is registered with,
+// this is lock free because the route of the app never changes once it is added.
+func (app *Application) getRmID() string {
+	if app.rmID != "" {
+		return app.rmID
+	}
+	return conf.GetSchedulerConf().ClusterID
+}
+
+// returns the new tasks in the order defined by the task ordering policy
+func (app *Application) getNewTasksInSubmitOrder() []*Task {
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	tasks := app.getTasks(events.States().Task.New)
+	sortTasks(tasks, app.taskOrderingPolicy)
+	return tasks
+}
+
+// mark the task group as unschedulable, returns false if it has already been marked
+func (app *Application) markTaskGroupUnschedulable(taskGroupName string) bool {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	if app.unschedulableTaskGroups[taskGroupName] {
+		return false
+	}
+	app.unschedulableTaskGroups[taskGroupName] = true
+	return true
+}
+
+// clear the unschedulable mark of the task group, e.g. when capacity has been provisioned for it
+func (app *Application) clearTaskGroupUnschedulable(taskGroupName string) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	delete(app.unschedulableTaskGroups, taskGroupName)
+}
+
+// returns the placeholders of the task group that are not allocated yet
+func (app *Application) getUnscheduledPlaceholders(taskGroupName string) []*Task {
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	placeholders := make([]*Task, 0)
+	for _, task := range app.getTasks(pendingTaskStates...) {
+		if task.IsPlaceholder() && task.getTaskGroupName() == taskGroupName {
+			placeholders = append(placeholders, task)
+		}
+	}
+	return placeholders
+}
+
 func (app *Application) getTaskGroups() []v1alpha1.TaskGroup {
 	app.lock.RLock()
 	defer app.lock.RUnlock()
 	return app.taskGroups
 }
 
+func (app *Application) setPlaceholderProgress(progress *placeholderProgress) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.placeholderProgress = progress
+}
+
+// addPlaceholderProgress adds the task groups of a reservation stage to the placeholder progress,
+// the progress is created for the first stage.
+func (app *Application) addPlaceholderProgress(taskGroups []v1alpha1.TaskGroup) *placeholderProgress {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	if app.placeholderProgress == nil {
+		app.placeholderProgress = newPlaceholderProgress(taskGroups)
+	} else {
+		app.placeholderProgress.add(taskGroups)
+	}
+	return app.placeholderProgress
+}
+
+func (app *Application) getPlaceholderProgress() *placeholderProgress {
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	return app.placeholderProgress
+}
+
+// publishPlaceholderProgress publishes the result of the placeholder creation of a task group
+// to the pods of the app, placeholders are skipped.
+func (app *Application) publishPlaceholderProgress(taskGroupName string, progress taskGroupProgress) {
+	eventType, reason := v1.EventTypeNormal, "PlaceholdersCreated"
+	if progress.failed > 0 {
+		eventType, reason = v1.EventTypeWarning, "PlaceholderCreationFailed"
+	}
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	for _, task := range app.taskMap {
+		if !task.IsPlaceholder() {
+			events.GetRecorder().Eventf(task.GetTaskPod(), eventType, reason,
+				"Task group %s of application %s: %d of %d placeholders created, %d failed",
+				taskGroupName, app.applicationID, progress.created, progress.desired, progress.failed)
+		}
+	}
+}
+
+// onPlaceholderPreempted is called when a placeholder is removed from its node without being released
+// by the scheduler, e.g. the placeholder was preempted by a pod with a higher priority. The creation of
+// the placeholder is rolled back, and the placeholder is created again while the app is still reserving.
+func (app *Application) onPlaceholderPreempted(taskGroupName string, index int32) {
+	app.lock.RLock()
+	state := app.sm.Current()
+	progress := app.placeholderProgress
+	var taskGroup *v1alpha1.TaskGroup
+	for i := range app.taskGroups {
+		if app.taskGroups[i].Name == taskGroupName {
+			taskGroup = &app.taskGroups[i]
+			break
+		}
+	}
+	app.lock.RUnlock()
+
+	states := events.States().Application
+	if state != states.Reserving && state != states.Running {
+		return
+	}
+	app.logger().Warn("placeholder is preempted",
+		zap.String("taskGroup", taskGroupName),
+		zap.Int32("taskGroupIndex", index))
+	metrics.GetPlaceholderMetrics().IncPlaceholderPreempted(app.metricsContext(taskGroupName))
+	if progress != nil {
+		progress.onPreempted(taskGroupName)
+	}
+	app.publishAppEvent(v1.EventTypeWarning, "PlaceholderPreempted",
+		"placeholder %d of task group %s is preempted", index, taskGroupName)
+
+	if state != states.Reserving || taskGroup == nil || progress == nil || index < 0 {
+		return
+	}
+	placeholderName := utils.GeneratePlaceholderName(taskGroupName, app.applicationID, index)
+	if err := getPlaceholderManager().createPlaceholder(app,
+		newPlaceholder(placeholderName, app.getPlaceholderSpec(), *taskGroup, index), progress); err != nil {
+		app.logger().Error("failed to re-create the preempted placeholder",
+			zap.String("placeholder", placeholderName),
+			zap.Error(err))
+		return
+	}
+	dispatcher.Dispatch(NewUpdateApplicationReservationEvent(app.applicationID))
+}
+
+// restorePlaceholder re-creates the placeholder replaced by a real member of a task group that failed shortly
+// after it was bound, the reservation of the gang is kept for the member restarted by its controller.
+// It runs asynchronously as the member failed with its task lock held.
+func (app *Application) restorePlaceholder(taskGroupName string, index int32, member string) {
+	app.lock.RLock()
+	state := app.sm.Current()
+	progress := app.placeholderProgress
+	var taskGroup *v1alpha1.TaskGroup
+	for i := range app.taskGroups {
+		if app.taskGroups[i].Name == taskGroupName {
+			taskGroup = &app.taskGroups[i]
+			break
+		}
+	}
+	existing := app.getTaskGroupMember(taskGroupName, index, true)
+	app.lock.RUnlock()
+
+	if state != events.States().Application.Running || taskGroup == nil || progress == nil {
+		return
+	}
+	// the placeholder is back already, e.g. the member failed again before its restart replaced it
+	if existing != nil && !existing.isTerminated() {
+		return
+	}
+	app.logger().Info("real member failed shortly after replacing its placeholder, restoring the placeholder",
+		zap.String("member", member),
+		zap.String("taskGroup", taskGroupName),
+		zap.Int32("taskGroupIndex", index))
+	progress.onRestoring(taskGroupName)
+	placeholderName := utils.GeneratePlaceholderName(taskGroupName, app.applicationID, index)
+	if err := getPlaceholderManager().createPlaceholder(app,
+		newPlaceholder(placeholderName, app.getPlaceholderSpec(), *taskGroup, index), progress); err != nil {
+		app.logger().Error("failed to restore the placeholder",
+			zap.String("placeholder", placeholderName),
+			zap.Error(err))
+		return
+	}
+	metrics.GetPlaceholderMetrics().IncPlaceholderRestored(app.metricsContext(taskGroupName))
+	app.publishAppEvent(v1.EventTypeNormal, "PlaceholderRestored",
+		"placeholder %d of task group %s is restored after %s failed", index, taskGroupName, member)
+}
+
+// getOwnerObjectReference returns a reference to the object owning the app, the controller
+// is preferred when the app has multiple owners. Nil is returned if the app has no owner.
+// It does not take the app lock: the owners are set before the app is added to the cache and never change.
+func (app *Application) getOwnerObjectReference() *v1.ObjectReference {
+	if len(app.placeholderOwnerReferences) == 0 {
+		return nil
+	}
+	owner := app.placeholderOwnerReferences[0]
+	for _, ref := range app.placeholderOwnerReferences {
+		if ref.Controller != nil && *ref.Controller {
+			owner = ref
+			break
+		}
+	}
+	return &v1.ObjectReference{
+		APIVersion: owner.APIVersion,
+		Kind:       owner.Kind,
+		Namespace:  app.tags[constants.AppTagNamespace],
+		Name:       owner.Name,
+		UID:        owner.UID,
+	}
+}
+
+// publishAppEvent publishes an app lifecycle event to the object owning the app,
+// e.g the Job or the SparkApplication, or the pod itself for a standalone pod.
+// It can be called with or without the app lock held, see getOwnerObjectReference.
+func (app *Application) publishAppEvent(eventType, reason, messageFmt string, args ...interface{}) {
+	owner := app.getOwnerObjectReference()
+	if owner == nil {
+		return
+	}
+	events.GetRecorder().Eventf(owner, eventType, reason,
+		"Application %s: "+messageFmt, append([]interface{}{app.applicationID}, args...)...)
+}
+
+func (app *Application) getKillProgress() *killProgress {
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	return app.killProgress
+}
+
+// setOwnReferences sets the owners of the app, the references are copied:
+// they come from a pod of the informer cache which must not be modified.
 func (app *Application) setOwnReferences(ref []metav1.OwnerReference) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.placeholderOwnerReferences = make([]metav1.OwnerReference, 0, len(ref))
+	for _, r := range ref {
+		app.placeholderOwnerReferences = append(app.placeholderOwnerReferences, *r.DeepCopy())
+	}
+}
+
+// getOwnReferences returns a copy of the owners of the app
+func (app *Application) getOwnReferences() []metav1.OwnerReference {
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	refs := make([]metav1.OwnerReference, 0, len(app.placeholderOwnerReferences))
+	for _, r := range app.placeholderOwnerReferences {
+		refs = append(refs, *r.DeepCopy())
+	}
+	return refs
+}
+
+// getPlaceholderSpec returns the metadata the placeholders of the app are created with
+func (app *Application) getPlaceholderSpec() *PlaceholderSpec {
 	app.lock.RLock()
 	defer app.lock.RUnlock()
-	app.placeholderOwnerReferences = ref
+	return &PlaceholderSpec{
+		appID:            app.applicationID,
+		queue:            app.queue,
+		namespace:        app.tags[constants.AppTagNamespace],
+		runtimeClassName: app.runtimeClassName,
+		ownerReferences:  getPlaceholderOwnerReferences(app.placeholderOwnerReferences),
+		timeoutInSec:     app.placeholderTimeoutInSec,
+		ask:              app.placeholderAsk,
+	}
 }
 
 func (app *Application) addTask(task *Task) {
@@ -248,16 +787,86 @@ func (app *Application) addTask(task *Task) {
 		return
 	}
 	app.taskMap[task.taskID] = task
+	app.setTaskGroupIndex(task)
+	// the app is as old as its oldest pod, this keeps the order of the apps after a restart
+	if !task.createTime.IsZero() && task.createTime.Before(app.createTime) {
+		app.createTime = task.createTime
+	}
+}
+
+func (app *Application) getCreateTime() time.Time {
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	return app.createTime
+}
+
+// setTaskGroupIndex sets the gang topology of a task, placeholders carry their index in the pod.
+// A real member keeps the index set in its pod if it is not taken by another member, otherwise it
+// gets the lowest free index, in the order the members are added. A real member and the placeholder
+// with the same index are mapped to each other when the placeholder is replaced.
+func (app *Application) setTaskGroupIndex(task *Task) {
+	if task.taskGroupName == "" {
+		return
+	}
+	var total int32
+	for _, tg := range app.taskGroups {
+		if tg.Name == task.taskGroupName {
+			total = tg.MinMember
+		}
+	}
+	index := task.taskGroupIndex
+	if !task.placeholder {
+		taken, ok := app.taskGroupIndexes[task.taskGroupName]
+		if !ok {
+			taken = make(map[int32]bool)
+			app.taskGroupIndexes[task.taskGroupName] = taken
+		}
+		if index >= 0 && taken[index] {
+			task.logger().Warn("task group index is already taken by another member, using the next free index",
+				zap.String("taskGroup", task.taskGroupName),
+				zap.Int32("taskGroupIndex", index))
+			index = -1
+		}
+		if index < 0 {
+			for index = 0; taken[index]; index++ {
+			}
+		}
+		taken[index] = true
+	}
+	task.setTaskGroupIndex(index, total)
+}
+
+// getTaskGroupPlaceholderNode returns the node of the placeholder with the given index in the task group,
+// an empty string is returned if the placeholder is not found or not allocated.
+func (app *Application) getTaskGroupPlaceholderNode(taskGroupName string, index int32) string {
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	if placeholder := app.getTaskGroupMember(taskGroupName, index, true); placeholder != nil {
+		return placeholder.getNodeName()
+	}
+	return ""
+}
+
+// getTaskGroupMember returns the task with the given index in the task group,
+// either the placeholder or the real member, nil is returned if the task is not found.
+func (app *Application) getTaskGroupMember(taskGroupName string, index int32, placeholder bool) *Task {
+	for _, task := range app.taskMap {
+		if task.placeholder == placeholder && task.getTaskGroupName() == taskGroupName &&
+			task.getTaskGroupIndex() == index {
+			return task
+		}
+	}
+	return nil
 }
 
 func (app *Application) removeTask(taskID string) error {
 	app.lock.Lock()
 	defer app.lock.Unlock()
-	if _, ok := app.taskMap[taskID]; ok {
+	if task, ok := app.taskMap[taskID]; ok {
 		delete(app.taskMap, taskID)
-		log.Logger().Info("task removed",
-			zap.String("appID", app.applicationID),
-			zap.String("taskID", taskID))
+		app.allocations.remove(task.getTaskAllocationUUID(), task)
+		app.logger().Info("task removed",
+			zap.String(log.FieldTaskID, taskID))
 		return nil
 	}
 	return fmt.Errorf("task %s is not found in application %s",
@@ -286,12 +895,48 @@ func (app *Application) GetAllocatedTasks() []*Task {
 	return app.getTasks(events.States().Task.Allocated)
 }
 
-func (app *Application) getTasks(state string) []*Task {
+// the states of the tasks whose pod is bound to its node, before and after the pod is reported running
+var boundTaskStates = []string{events.States().Task.Bound, events.States().Task.Running}
+
+func (app *Application) getBoundTasks() []*Task {
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	return app.getTasks(boundTaskStates...)
+}
+
+// getAllocatedAndBoundTasks returns the tasks the core assigned an allocation to
+func (app *Application) getAllocatedAndBoundTasks() []*Task {
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	return app.getTasks(append([]string{events.States().Task.Allocated}, boundTaskStates...)...)
+}
+
+// getPlaceholderTasks returns a snapshot of the placeholders of the task group, or of all the task groups
+// if the task group is empty. The caller must not hold the app lock, the placeholders are deleted from
+// goroutines that cannot iterate the task map while the state machine of the app changes it.
+func (app *Application) getPlaceholderTasks(taskGroupName string) []*Task {
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	placeholders := make([]*Task, 0)
+	for _, task := range app.taskMap {
+		if task.IsPlaceholder() && (taskGroupName == "" || task.getTaskGroupName() == taskGroupName) {
+			placeholders = append(placeholders, task)
+		}
+	}
+	return placeholders
+}
+
+// getTasks returns the tasks in any of the states sorted by creation time
+func (app *Application) getTasks(states ...string) []*Task {
 	taskList := make([]*Task, 0)
 	if len(app.taskMap) > 0 {
 		for _, task := range app.taskMap {
-			if task.GetTaskState() == state {
-				taskList = append(taskList, task)
+			taskState := task.GetTaskState()
+			for _, state := range states {
+				if taskState == state {
+					taskList = append(taskList, task)
+					break
+				}
 			}
 		}
 	}
@@ -306,6 +951,26 @@ func (app *Application) getTasks(state string) []*Task {
 	return taskList
 }
 
+// getQueueAsks returns the asks of the app known to the core grouped by the queue they are scheduled in,
+// the tasks without a queue of their own are asked in the queue of the app. The caller holds the app lock.
+func (app *Application) getQueueAsks() map[string][]string {
+	queueAsks := make(map[string][]string)
+	for _, task := range app.taskMap {
+		if task.GetTaskState() != events.States().Task.Scheduling {
+			continue
+		}
+		queue := task.getQueue()
+		if queue == "" {
+			queue = app.queue
+		}
+		queueAsks[queue] = append(queueAsks[queue], task.taskID)
+	}
+	for _, asks := range queueAsks {
+		sort.Strings(asks)
+	}
+	return queueAsks
+}
+
 func (app *Application) GetTags() map[string]string {
 	return app.tags
 }
@@ -339,12 +1004,19 @@ func (app *Application) TriggerAppRecovery() error {
 // ensure non of these calls is expensive, usually, they
 // do nothing more than just triggering the state transition.
 func (app *Application) Schedule() {
+	app.schedule(0)
+}
+
+// schedule submits at most budget new tasks of the app, 0 submits all of them.
+// It returns the number of new tasks left for the next scheduling cycle because the budget is used up.
+func (app *Application) schedule(budget int) int {
 	var states = events.States().Application
+	deferred := 0
 	switch app.GetApplicationState() {
 	case states.New:
 		ev := NewSubmitApplicationEvent(app.GetApplicationID())
 		if err := app.handle(ev); err != nil {
-			log.Logger().Warn("failed to handle SUBMIT app event",
+			app.logger().Warn("failed to handle SUBMIT app event",
 				zap.Error(err))
 		}
 	case states.Accepted:
@@ -355,120 +1027,177 @@ func (app *Application) Schedule() {
 		app.postAppAccepted()
 	case states.Reserving:
 		// during the Reserving state, only the placeholders
-		// can be scheduled
-		app.scheduleTasks(func(t *Task) bool {
-			return t.placeholder
+		// and the pods that opted out of the gang can be scheduled
+		deferred = app.scheduleTasks(budget, func(t *Task) bool {
+			return t.placeholder || t.nonGang
 		})
+		app.agePriority(time.Now())
 	case states.Running:
 		// during the Running state, only the regular pods
 		// can be scheduled
-		app.scheduleTasks(func(t *Task) bool {
+		deferred = app.scheduleTasks(budget, func(t *Task) bool {
 			return !t.placeholder
 		})
+		app.agePriority(time.Now())
 	default:
-		log.Logger().Debug("skipping scheduling application",
-			zap.String("appState", app.GetApplicationState()),
-			zap.String("appID", app.GetApplicationID()),
+		app.logger().Debug("skipping scheduling application",
 			zap.String("appState", app.GetApplicationState()))
 	}
+	return deferred
 }
 
-func (app *Application) scheduleTasks(taskScheduleCondition func(t *Task) bool) {
-	for _, task := range app.GetNewTasks() {
+func (app *Application) scheduleTasks(budget int, taskScheduleCondition func(t *Task) bool) int {
+	if budget <= 0 {
+		// no limit, the budget is never used up
+		budget = -1
+	}
+	slots := app.getParallelTaskSlots()
+	held := 0
+	deferred := 0
+	for _, task := range app.getNewTasksInSubmitOrder() {
+		// the task stays New until a task of the app being scheduled is bound
+		if slots == 0 && !task.placeholder {
+			held++
+			continue
+		}
 		if taskScheduleCondition(task) {
+			// the tasks over the budget wait for the next cycle, the other apps are not delayed
+			if budget == 0 {
+				deferred++
+				continue
+			}
+			budget--
 			// for each new task, we do a sanity check before moving the state to Pending_Schedule
 			if err := task.sanityCheckBeforeScheduling(); err == nil {
 				// note, if we directly trigger submit task event, it may spawn too many duplicate
 				// events, because a task might be submitted multiple times before its state transits to PENDING.
-				if handleErr := task.handle(
-					NewSimpleTaskEvent(task.applicationID, task.taskID, events.InitTask)); handleErr != nil {
+				if handleErr := task.initTask(); handleErr != nil {
 					// something goes wrong when transit task to PENDING state,
 					// this should not happen because we already checked the state
 					// before calling the transition. Nowhere to go, just log the error.
-					log.Logger().Warn("init task failed", zap.Error(err))
+					task.logger().Warn("init task failed", zap.Error(handleErr))
+				} else if slots > 0 && !task.placeholder && task.GetTaskState() != events.States().Task.Gated {
+					slots--
 				}
 			} else {
 				events.GetRecorder().Event(task.GetTaskPod(), v1.EventTypeWarning, "FailedScheduling", err.Error())
-				log.Logger().Debug("task is not ready for scheduling",
-					zap.String("appID", task.applicationID),
-					zap.String("taskID", task.taskID),
+				task.logger().Debug("task is not ready for scheduling",
 					zap.Error(err))
 			}
 		}
 	}
+	if held > 0 {
+		app.logger().Debug("max parallel tasks reached, holding the remaining tasks",
+			zap.Int("heldTasks", held))
+	}
+	if deferred > 0 {
+		app.logger().Debug("scheduling budget used up, deferring the remaining tasks to the next cycle",
+			zap.Int("deferredTasks", deferred))
+	}
+	return deferred
 }
 
 func (app *Application) handleSubmitApplicationEvent(event *fsm.Event) {
-	log.Logger().Info("handle app submission",
+	app.logger().Info("handle app submission",
 		zap.String("app", app.String()),
-		zap.String("clusterID", conf.GetSchedulerConf().ClusterID))
-	err := app.schedulerAPI.Update(
-		&si.UpdateRequest{
-			NewApplications: []*si.AddApplicationRequest{
-				{
-					ApplicationID: app.applicationID,
-					QueueName:     app.queue,
-					PartitionName: app.partition,
-					Ugi: &si.UserGroupInformation{
-						User: app.user,
-					},
-					Tags:                         app.tags,
-					PlaceholderAsk:               app.placeholderAsk,
-					ExecutionTimeoutMilliSeconds: app.placeholderTimeoutInSec * 1000,
-				},
-			},
-			RmID: conf.GetSchedulerConf().ClusterID,
-		})
-
+		zap.String("clusterID", app.getRmID()))
+	// the gang can never be satisfied, fail the app immediately
+	// rather than waiting for the placeholder timeout
+	if app.gangInfeasibleReason != "" {
+		app.logger().Warn("app gang is not feasible",
+			zap.String("reason", app.gangInfeasibleReason))
+		dispatcher.Dispatch(NewFailApplicationEvent(app.applicationID, app.gangInfeasibleReason))
+		return
+	}
+	// the placeholders can never fit in the queue, the core would keep the app reserving until the timeout
+	if capacities := app.getQueueCapacities(); capacities != nil {
+		if err := capacities.checkAsk(app.getRmID(), app.partition, app.queue, app.placeholderAsk); err != nil {
+			app.logger().Warn("app exceeds the max capacity of its queue", zap.Error(err))
+			app.publishAppEvent(v1.EventTypeWarning, "QueueCapacityExceeded", "%v", err)
+			dispatcher.Dispatch(NewFailApplicationEvent(app.applicationID, err.Error()))
+			return
+		}
+	}
+	request, err := app.addApplicationRequest(app.placeholderAsk)
+	if err == nil {
+		err = app.schedulerAPI.Update(request)
+	}
 	if err != nil {
 		// submission failed
-		log.Logger().Warn("failed to submit app", zap.Error(err))
+		app.logger().Warn("failed to submit app", zap.Error(err))
 		dispatcher.Dispatch(NewFailApplicationEvent(app.applicationID, err.Error()))
+		return
 	}
+	app.publishAppEvent(v1.EventTypeNormal, "ApplicationSubmitted",
+		"submitted to queue %s", app.queue)
 }
 
 func (app *Application) handleRecoverApplicationEvent(event *fsm.Event) {
-	log.Logger().Info("handle app recovering",
+	app.addRecoveryProgress(RecoveryPhaseApplications, 1, 0, 0)
+	app.logger().Info("handle app recovering",
 		zap.String("app", app.String()),
-		zap.String("clusterID", conf.GetSchedulerConf().ClusterID))
-	err := app.schedulerAPI.Update(
-		&si.UpdateRequest{
-			NewApplications: []*si.AddApplicationRequest{
-				{
-					ApplicationID: app.applicationID,
-					QueueName:     app.queue,
-					PartitionName: app.partition,
-					Ugi: &si.UserGroupInformation{
-						User: app.user,
-					},
-					Tags:                         app.tags,
-					ExecutionTimeoutMilliSeconds: app.placeholderTimeoutInSec * 1000,
-				},
-			},
-			RmID: conf.GetSchedulerConf().ClusterID,
-		})
-
+		zap.String("clusterID", app.getRmID()))
+	// the placeholders of a recovered app are recovered as allocations, the app does not ask for them again
+	request, err := app.addApplicationRequest(nil)
+	if err == nil {
+		err = app.schedulerAPI.Update(request)
+	}
 	if err != nil {
 		// submission failed
-		log.Logger().Warn("failed to submit app", zap.Error(err))
+		app.logger().Warn("failed to submit app", zap.Error(err))
 		dispatcher.Dispatch(NewFailApplicationEvent(app.applicationID, err.Error()))
 	}
 }
 
+// addApplicationRequest returns the request adding the app to the core, the caller must hold the app lock.
+func (app *Application) addApplicationRequest(placeholderAsk *si.Resource) (*si.UpdateRequest, error) {
+	return sirequest.New(app.getRmID()).
+		AddApp(&si.AddApplicationRequest{
+			ApplicationID: app.applicationID,
+			QueueName:     app.queue,
+			PartitionName: app.partition,
+			Ugi: &si.UserGroupInformation{
+				User:   app.user,
+				Groups: app.groups,
+			},
+			Tags:                         app.tags,
+			PlaceholderAsk:               placeholderAsk,
+			ExecutionTimeoutMilliSeconds: app.placeholderTimeoutInSec * 1000,
+		}).
+		Build()
+}
+
 func (app *Application) postAppAccepted() {
 	// if app has taskGroups defined, and it has no allocated tasks,
 	// it goes to the Reserving state before getting to Running.
 	// app could have allocated tasks upon a recovery, and in that case,
 	// the reserving phase has already passed, no need to trigger that again.
 	var ev events.SchedulingEvent
-	log.Logger().Debug("postAppAccepted on cached app",
-		zap.String("appID", app.applicationID),
+	app.logger().Debug("postAppAccepted on cached app",
 		zap.Int("numTaskGroups", len(app.taskGroups)),
 		zap.Int("numAllocatedTasks", len(app.getTasks(events.States().Task.Allocated))))
-	if len(app.taskGroups) != 0 &&
+	// an app that was running before the restart has passed the reserving phase too,
+	// even if all its allocations are gone
+	if len(app.taskGroups) != 0 && app.restoredState != events.States().Application.Running &&
 		len(app.getTasks(events.States().Task.Allocated)) == 0 {
+		// the app stays Accepted until its queue allows another reserving app
+		if app.context != nil {
+			gate, queue := app.context.reservations, app.getReservationQueue()
+			acquired, queued := gate.tryAcquire(app.applicationID, queue, app.getMaxReservingApps())
+			if !acquired {
+				if queued {
+					reserving, waiting := gate.get(queue)
+					app.logger().Info("too many apps reserving in the queue, waiting to reserve",
+						zap.Int("reserving", reserving),
+						zap.Int("waiting", waiting))
+					app.publishAppEvent(v1.EventTypeNormal, "ReservationWaiting",
+						"waiting to reserve resources, %d apps of queue %s are reserving", reserving, app.queue)
+				}
+				return
+			}
+		}
 		ev = NewSimpleApplicationEvent(app.applicationID, events.TryReserve)
-		log.Logger().Info("app has taskGroups defined, trying to reserve resources for gang members")
+		app.logger().Info("app has taskGroups defined, trying to reserve resources for gang members")
 		dispatcher.Dispatch(ev)
 	} else {
 		ev = NewRunApplicationEvent(app.applicationID)
@@ -476,17 +1205,77 @@ func (app *Application) postAppAccepted() {
 	dispatcher.Dispatch(ev)
 }
 
+// onReserving starts the reservation with the task groups that do not depend on other task groups,
+// the dependent task groups are reserved once the task groups they depend on are fully bound.
 func (app *Application) onReserving(event *fsm.Event) {
-	go func() {
-		// while doing reserving
-		if err := getPlaceholderManager().createAppPlaceholders(app); err != nil {
-			// creating placeholder failed
-			// put the app into recycling queue and turn the app to running state
-			getPlaceholderManager().cleanUp(app)
-			ev := NewRunApplicationEvent(app.applicationID)
-			dispatcher.Dispatch(ev)
+	app.reservedTaskGroups = make(map[string]bool)
+	app.placeholderProgress = nil
+	app.reportedBoundPlaceholders = 0
+	app.publishAppEvent(v1.EventTypeNormal, "ApplicationReserving",
+		"reserving resources, 0/%d placeholders bound", app.getDesiredPlaceholders())
+	app.setGangReservingConditions(0)
+	taskGroups := app.nextTaskGroupsToReserve(utils.NewTaskGroupInstanceCountMap())
+	go app.reserveTaskGroups(taskGroups)
+	app.startProgressTimer()
+	app.startReservationWatchdog()
+	app.startGangStatusTimer()
+}
+
+// reserveTaskGroups creates the placeholders of a reservation stage
+func (app *Application) reserveTaskGroups(taskGroups []v1alpha1.TaskGroup) {
+	mgr := getPlaceholderManager()
+	if err := mgr.createTaskGroupPlaceholders(app, taskGroups); err != nil {
+		if mgr.clients.Conf.PlaceholderRollbackPolicy == conf.PlaceholderRollbackTaskGroup {
+			// only release the task groups of this stage with failed placeholders,
+			// the reservation of the other task groups is kept
+			stage := make(map[string]bool, len(taskGroups))
+			for _, tg := range taskGroups {
+				stage[tg.Name] = true
+			}
+			for _, taskGroupName := range app.getPlaceholderProgress().failedTaskGroups() {
+				if stage[taskGroupName] {
+					dispatcher.Dispatch(NewReleaseTaskGroupEvent(app.applicationID, taskGroupName))
+				}
+			}
+			return
 		}
-	}()
+		// creating placeholder failed
+		// put the app into recycling queue and turn the app to running state
+		mgr.cleanUp(app)
+		ev := NewRunApplicationEvent(app.applicationID)
+		dispatcher.Dispatch(ev)
+	}
+}
+
+// nextTaskGroupsToReserve returns the task groups that are not reserved yet and whose dependencies
+// are all satisfied, the returned task groups are marked as reserved. A dependency is satisfied when
+// all the placeholders of the task group are bound, or when the task group has been released.
+// The caller must hold the app lock for writing.
+func (app *Application) nextTaskGroupsToReserve(boundCounts *utils.TaskGroupInstanceCountMap) []v1alpha1.TaskGroup {
+	minMembers := make(map[string]int32, len(app.taskGroups))
+	for _, tg := range app.taskGroups {
+		minMembers[tg.Name] = tg.MinMember
+	}
+	stage := make([]v1alpha1.TaskGroup, 0)
+	for _, tg := range app.taskGroups {
+		if app.reservedTaskGroups[tg.Name] {
+			continue
+		}
+		satisfied := true
+		for _, dep := range tg.DependsOn {
+			if minMember, ok := minMembers[dep]; ok && boundCounts.GetTaskGroupInstanceCount(dep) < minMember {
+				satisfied = false
+				break
+			}
+		}
+		if satisfied {
+			stage = append(stage, tg)
+		}
+	}
+	for _, tg := range stage {
+		app.reservedTaskGroups[tg.Name] = true
+	}
+	return stage
 }
 
 func (app *Application) onReservationStateChange(event *fsm.Event) {
@@ -497,30 +1286,148 @@ func (app *Application) onReservationStateChange(event *fsm.Event) {
 	}
 
 	actualCounts := utils.NewTaskGroupInstanceCountMap()
-	for _, t := range app.getTasks(events.States().Task.Bound) {
-		if t.placeholder {
+	boundResources := make(map[string]*si.Resource)
+	bound := int32(0)
+	for _, t := range app.getTasks(boundTaskStates...) {
+		// placeholders of a released task group may not be deleted yet, skip them
+		if t.placeholder && desireCounts.GetTaskGroupInstanceCount(t.taskGroupName) > 0 {
 			actualCounts.AddOne(t.taskGroupName)
+			boundResources[t.taskGroupName] = common.Add(boundResources[t.taskGroupName], t.resource)
+			bound++
 		}
 	}
+	if bound != app.reportedBoundPlaceholders {
+		// a new placeholder bound is progress, the reservation gets another progress timeout
+		if bound > app.reportedBoundPlaceholders {
+			app.startProgressTimer()
+		}
+		app.reportedBoundPlaceholders = bound
+		app.publishAppEvent(v1.EventTypeNormal, "ApplicationReserving",
+			"reserving resources, %d/%d placeholders bound", bound, app.getDesiredPlaceholders())
+		app.setGangReservingConditions(bound)
+	}
 
-	// min member all satisfied
-	if desireCounts.Equals(actualCounts) {
+	// min member and min resource all satisfied
+	if app.isReservationComplete(desireCounts, actualCounts, boundResources) {
 		ev := NewRunApplicationEvent(app.applicationID)
 		dispatcher.Dispatch(ev)
+		return
+	}
+
+	// reserve the task groups whose dependencies have been bound
+	if taskGroups := app.nextTaskGroupsToReserve(actualCounts); len(taskGroups) > 0 {
+		names := make([]string, 0, len(taskGroups))
+		for _, tg := range taskGroups {
+			names = append(names, tg.Name)
+		}
+		app.logger().Info("dependencies of taskGroups are bound, reserving the next stage",
+			zap.Strings("taskGroups", names))
+		go app.reserveTaskGroups(taskGroups)
+	}
+}
+
+// isReservationComplete returns true when all the task groups are reserved: all the placeholders of a task group
+// with min member are bound, the bound placeholders of a task group without min member cover its min resource.
+// The caller must hold the app lock.
+func (app *Application) isReservationComplete(desired, bound *utils.TaskGroupInstanceCountMap,
+	boundResources map[string]*si.Resource) bool {
+	for _, tg := range app.taskGroups {
+		if minResource, ok := app.resourceGangs[tg.Name]; ok {
+			if !common.FitIn(boundResources[tg.Name], minResource) {
+				return false
+			}
+			continue
+		}
+		if bound.GetTaskGroupInstanceCount(tg.Name) != desired.GetTaskGroupInstanceCount(tg.Name) {
+			return false
+		}
+	}
+	return true
+}
+
+// getDesiredPlaceholders returns the number of placeholders of all the task groups,
+// the caller must hold the app lock.
+func (app *Application) getDesiredPlaceholders() int32 {
+	desired := int32(0)
+	for _, tg := range app.taskGroups {
+		desired += tg.MinMember
+	}
+	return desired
+}
+
+// handleReleaseTaskGroupEvent cancels the reservation of a single task group, the task group is
+// removed from the app and its placeholders are deleted, the reservation of the other task groups
+// is kept. The members of the released task group are scheduled without placeholders.
+func (app *Application) handleReleaseTaskGroupEvent(event *fsm.Event) {
+	eventArgs := make([]string, 1)
+	if err := events.GetEventArgsAsStrings(eventArgs, event.Args); err != nil {
+		app.logger().Error("fail to parse event arg", zap.Error(err))
+		return
+	}
+	taskGroupName := eventArgs[0]
+
+	taskGroups := make([]v1alpha1.TaskGroup, 0, len(app.taskGroups))
+	for _, tg := range app.taskGroups {
+		if tg.Name == taskGroupName {
+			app.placeholderAsk = common.Sub(app.placeholderAsk, app.getTaskGroupAsk(tg))
+			continue
+		}
+		taskGroups = append(taskGroups, tg)
+	}
+	if len(taskGroups) == len(app.taskGroups) {
+		app.logger().Warn("task group is not found, skip releasing it",
+			zap.String("taskGroup", taskGroupName))
+		return
+	}
+	app.taskGroups = taskGroups
+	app.logger().Info("release task group",
+		zap.String("taskGroup", taskGroupName))
+
+	go func() {
+		getPlaceholderManager().cleanUpTaskGroup(app, taskGroupName)
+	}()
+
+	// the desired placeholders have changed, re-evaluate the reservation
+	if event.Dst == events.States().Application.Reserving {
+		app.onReservationStateChange(event)
 	}
 }
 
 func (app *Application) handleRejectApplicationEvent(event *fsm.Event) {
-	log.Logger().Info("app is rejected by scheduler", zap.String("appID", app.applicationID))
+	reason := fmt.Sprintf("application %s is rejected by scheduler", app.applicationID)
+	eventArgs := make([]string, 1)
+	if err := events.GetEventArgsAsStrings(eventArgs, event.Args); err == nil && eventArgs[0] != "" {
+		reason = eventArgs[0]
+	}
+	app.logger().Info("app is rejected by scheduler",
+		zap.String("reason", reason))
+	if isUnknownQueueRejection(reason) {
+		app.handleUnknownQueue(reason)
+		return
+	}
 	// for rejected apps, we directly move them to failed state
-	dispatcher.Dispatch(NewFailApplicationEvent(app.applicationID,
-		fmt.Sprintf("application %s is rejected by scheduler", app.applicationID)))
+	dispatcher.Dispatch(NewFailApplicationEvent(app.applicationID, reason))
 }
 
+// handleCompleteApplicationEvent cleans up the placeholders of a completed app. An app completed by the core
+// is already removed from the core, it is removed from the context once its placeholders are cleaned up.
 func (app *Application) handleCompleteApplicationEvent(event *fsm.Event) {
-	// TODO app lifecycle updates
+	// the placeholders kept for a retry are cleaned up when the linger window ends
+	if app.lingering {
+		return
+	}
+	eventArgs := make([]string, 1)
+	if err := events.GetEventArgsAsStrings(eventArgs, event.Args); err != nil {
+		app.logger().Error("fail to parse event arg", zap.Error(err))
+	}
+	removed, err := strconv.ParseBool(eventArgs[0])
 	go func() {
 		getPlaceholderManager().cleanUp(app)
+		if err == nil && removed && app.context != nil {
+			if err := app.context.RemoveApplicationInternal(app.applicationID); err != nil {
+				app.logger().Error("failed to delete application", zap.Error(err))
+			}
+		}
 	}()
 }
 
@@ -530,10 +1437,12 @@ func (app *Application) handleFailApplicationEvent(event *fsm.Event) {
 	}()
 	eventArgs := make([]string, 1)
 	if err := events.GetEventArgsAsStrings(eventArgs, event.Args); err != nil {
-		log.Logger().Error("fail to parse event arg", zap.Error(err))
+		app.logger().Error("fail to parse event arg", zap.Error(err))
 		return
 	}
 	errMess := eventArgs[0]
+	app.publishAppEvent(v1.EventTypeWarning, "ApplicationFailed",
+		"scheduling failed, reason: %s", errMess)
 	// unallocated task states include New, Pending and Scheduling
 	unalloc := app.getTasks(events.States().Task.New)
 	unalloc = append(unalloc, app.getTasks(events.States().Task.Pending)...)
@@ -545,67 +1454,237 @@ func (app *Application) handleFailApplicationEvent(event *fsm.Event) {
 	}
 }
 
+// handleKillApplicationEvent cleans up the placeholders of a killed app. The app is removed from the core, this
+// releases all its allocations, and when the app is killed on request its pods are deleted in the background in
+// the configured order. Otherwise the pods of the app are already gone, e.g. its namespace is deleted. The app
+// stays in Killing until its pods are terminated and the core released its allocations, see killApp.
+func (app *Application) handleKillApplicationEvent(event *fsm.Event) {
+	eventArgs := make([]string, 2)
+	if err := events.GetEventArgsAsStrings(eventArgs, event.Args); err != nil {
+		app.logger().Error("fail to parse event arg", zap.Error(err))
+	}
+	reason := eventArgs[0]
+	app.logger().Info("app is killed", zap.String("reason", reason))
+	if deletePods, err := strconv.ParseBool(eventArgs[1]); err == nil && deletePods {
+		tasks := make([]*Task, 0, len(app.taskMap))
+		for _, task := range app.taskMap {
+			if task.placeholder {
+				tasks = append(tasks, task)
+				continue
+			}
+			if task.isTerminated() {
+				continue
+			}
+			events.GetRecorder().Eventf(task.GetTaskPod(), v1.EventTypeWarning, "ApplicationKilled",
+				"Application %s is killed, reason: %s", app.applicationID, reason)
+			tasks = append(tasks, task)
+		}
+		stages := getKillStages(tasks, conf.GetSchedulerConf().GetKillDeletionOrder())
+		progress := newKillProgress(tasks, app.allocations.list())
+		app.killProgress = progress
+		go killApp(app, stages, progress)
+		return
+	}
+	go func() {
+		getPlaceholderManager().cleanUp(app)
+	}()
+	progress := newKillProgress(nil, app.allocations.list())
+	app.killProgress = progress
+	go killApp(app, nil, progress)
+}
+
+// handleResumeApplicationEvent retries a failed app, the failure of the app is cleared, the tasks
+// waiting for an allocation are reset to rebuild their asks, and the app is resubmitted to the core.
+func (app *Application) handleResumeApplicationEvent(event *fsm.Event) {
+	app.logger().Info("resuming failed app")
+	app.unschedulableTaskGroups = make(map[string]bool)
+	app.placeholderProgress = nil
+	s := events.States().Task
+	for _, task := range append(app.getTasks(s.Pending), app.getTasks(s.Scheduling)...) {
+		// the placeholders are cleaned up when the app failed, they are not rescheduled
+		if task.placeholder {
+			continue
+		}
+		if err := task.handle(NewSimpleTaskEvent(app.applicationID, task.taskID, events.ResetTask)); err != nil {
+			task.logger().Warn("failed to reset task", zap.Error(err))
+		}
+	}
+	// the core may still track the failed app, remove it before the app is resubmitted
+	rr := common.CreateUpdateRequestForRemoveApplication(app.applicationID, app.partition)
+	rr.RmID = app.getRmID()
+	if err := app.schedulerAPI.Update(&rr); err != nil {
+		app.logger().Warn("failed to remove app from the core", zap.Error(err))
+	}
+	app.handleSubmitApplicationEvent(event)
+}
+
 func (app *Application) handleReleaseAppAllocationEvent(event *fsm.Event) {
 	eventArgs := make([]string, 2)
 	if err := events.GetEventArgsAsStrings(eventArgs, event.Args); err != nil {
-		log.Logger().Error("fail to parse event arg", zap.Error(err))
+		app.logger().Error("fail to parse event arg", zap.Error(err))
 		return
 	}
 	allocUUID := eventArgs[0]
 	terminationTypeStr := eventArgs[1]
-	log.Logger().Info("try to release pod from application",
-		zap.String("appID", app.applicationID),
+	app.logger().Info("try to release pod from application",
 		zap.String("allocationUUID", allocUUID),
 		zap.String("terminationType", terminationTypeStr))
 
-	for _, task := range app.taskMap {
-		if task.allocationUUID == allocUUID {
-			task.setTaskTerminationType(terminationTypeStr)
-			err := task.DeleteTaskPod(task.pod)
-			if err != nil {
-				log.Logger().Error("failed to release allocation from application", zap.Error(err))
-			}
+	task := app.allocations.get(allocUUID)
+	if task == nil {
+		app.logger().Debug("allocation is not found in application",
+			zap.String("allocationUUID", allocUUID))
+		return
+	}
+	if task.placeholder && terminationTypeStr == si.TerminationType_name[int32(si.TerminationType_PLACEHOLDER_REPLACED)] {
+		if progress := app.placeholderProgress; progress != nil {
+			progress.onReplaced(task.taskGroupName)
+		}
+		if member := app.getTaskGroupMember(task.taskGroupName, task.taskGroupIndex, false); member != nil {
+			task.logger().Info("placeholder is replaced by a real member of the task group",
+				zap.String("member", member.alias),
+				zap.Int32("taskGroupIndex", task.taskGroupIndex))
+			member.onPlaceholderReplaced(task.getBoundTime())
+			app.publishDecision(dao.SchedulingDecision{
+				Type:          dao.DecisionPlaceholderReplaced,
+				ApplicationID: app.applicationID,
+				Queue:         app.queue,
+				TaskID:        task.taskID,
+				Pod:           task.alias,
+				NodeID:        task.nodeName,
+				Placeholder:   true,
+				TaskGroup:     task.taskGroupName,
+				ReplacedBy:    member.alias,
+			})
 		}
 	}
+	if task.placeholder && terminationTypeStr == si.TerminationType_name[int32(si.TerminationType_TIMEOUT)] {
+		metrics.GetPlaceholderMetrics().IncPlaceholderTimedOut(app.metricsContext(task.taskGroupName))
+		if progress := app.placeholderProgress; progress != nil {
+			progress.onTimedOut(task.taskGroupName)
+		}
+	}
+	task.setTaskTerminationType(terminationTypeStr)
+	err := task.deleteReleasedPod(terminationTypeStr)
+	if err != nil {
+		task.logger().Error("failed to release allocation from application", zap.Error(err))
+	}
 }
 
 func (app *Application) handleReleaseAppAllocationAskEvent(event *fsm.Event) {
-	eventArgs := make([]string, 2)
+	eventArgs := make([]string, 3)
 	if err := events.GetEventArgsAsStrings(eventArgs, event.Args); err != nil {
-		log.Logger().Error("fail to parse event arg", zap.Error(err))
+		app.logger().Error("fail to parse event arg", zap.Error(err))
 		return
 	}
 	taskID := eventArgs[0]
 	terminationTypeStr := eventArgs[1]
-	log.Logger().Info("try to release pod from application",
-		zap.String("appID", app.applicationID),
-		zap.String("taskID", taskID),
+	policy := eventArgs[2]
+	app.logger().Info("try to release pod from application",
+		zap.String(log.FieldTaskID, taskID),
 		zap.String("terminationType", terminationTypeStr))
 	if task, ok := app.taskMap[taskID]; ok {
 		task.setTaskTerminationType(terminationTypeStr)
 		if task.IsPlaceholder() {
-			err := task.DeleteTaskPod(task.pod)
+			if terminationTypeStr == si.TerminationType_name[int32(si.TerminationType_TIMEOUT)] {
+				metrics.GetPlaceholderMetrics().IncPlaceholderTimedOut(app.metricsContext(task.taskGroupName))
+				if progress := app.placeholderProgress; progress != nil {
+					progress.onTimedOut(task.taskGroupName)
+				}
+			}
+			err := task.deleteReleasedPod(terminationTypeStr)
 			if err != nil {
-				log.Logger().Error("failed to release allocation ask from application", zap.Error(err))
+				task.logger().Error("failed to release allocation ask from application", zap.Error(err))
 			}
 		} else {
-			log.Logger().Warn("skip to release allocation ask, ask is not a placeholder",
-				zap.String("appID", app.applicationID),
-				zap.String("taskID", taskID))
+			app.releaseTaskAsk(task, terminationTypeStr, policy)
 		}
 	} else {
-		log.Logger().Warn("task not found",
-			zap.String("appID", app.applicationID),
-			zap.String("taskID", taskID))
+		app.logger().Warn("task not found",
+			zap.String(log.FieldTaskID, taskID))
+	}
+}
+
+// releaseTaskAsk applies the released ask policy to a real task whose pending ask is released by the core,
+// e.g. its queue shrinks while its pod is pending. The caller must hold the app lock, the task is failed
+// or resubmitted through the dispatcher as its state machine must not run under the app lock.
+func (app *Application) releaseTaskAsk(task *Task, terminationType, policy string) {
+	// only a task waiting for its ask is affected, the ask of a task in any other state is already gone
+	if task.GetTaskState() != events.States().Task.Scheduling {
+		task.logger().Debug("skip to release allocation ask, task is not scheduling",
+			zap.String("state", task.GetTaskState()))
+		return
+	}
+	message := fmt.Sprintf("the request of task %s is released by the scheduler (%s)", task.alias, terminationType)
+	switch policy {
+	case conf.ReleasedAskFail:
+		task.logger().Info("failing the task whose ask is released", zap.String("message", message))
+		events.GetRecorder().Eventf(task.pod, v1.EventTypeWarning, "AskReleased", "%s, the pod is failed", message)
+		dispatcher.Dispatch(NewFailTaskEvent(app.applicationID, task.taskID, message))
+		task.failTaskPod("AskReleased", message)
+	case conf.ReleasedAskRequeue:
+		task.logger().Info("resubmitting the task whose ask is released", zap.String("message", message))
+		events.GetRecorder().Eventf(task.pod, v1.EventTypeWarning, "AskReleased", "%s, the request is resubmitted", message)
+		dispatcher.Dispatch(NewSimpleTaskEvent(app.applicationID, task.taskID, events.TaskAskReleased))
+	default:
+		task.logger().Warn("skip to release allocation ask, ask is not a placeholder")
 	}
 }
 
+// leaveState runs the hooks registered before the state transitions of the apps
+func (app *Application) leaveState(event *fsm.Event) {
+	runStateHooks(applicationHook, BeforeTransition, app.applicationID, "", event)
+}
+
 func (app *Application) enterState(event *fsm.Event) {
-	log.Logger().Debug("shim app state transition",
-		zap.String("app", app.applicationID),
+	app.logger().Debug("shim app state transition",
 		zap.String("source", event.Src),
 		zap.String("destination", event.Dst),
 		zap.String("event", event.Event))
+	defer runStateHooks(applicationHook, AfterTransition, app.applicationID, "", event)
+	if checkpointer := app.getAppCheckpointer(); checkpointer != nil {
+		checkpointer.record(app, event.Dst)
+	}
+	// the reservation is over, the capacity is either consumed or no longer needed
+	if requests := app.getProvisioningRequests(); requests != nil &&
+		event.Src == events.States().Application.Reserving && event.Dst != events.States().Application.Reserving {
+		go requests.release(app.applicationID)
+	}
+	app.publishDecision(dao.SchedulingDecision{
+		Type:          dao.DecisionAppStateChange,
+		ApplicationID: app.applicationID,
+		Queue:         app.queue,
+		FromState:     event.Src,
+		ToState:       event.Dst,
+	})
+	switch event.Dst {
+	case events.States().Application.Accepted:
+		if event.Src == events.States().Application.Recovering {
+			app.addRecoveryProgress(RecoveryPhaseApplications, 0, 1, 0)
+		}
+		app.publishAppEvent(v1.EventTypeNormal, "ApplicationAccepted",
+			"accepted by the scheduler in partition %s", app.partition)
+	case events.States().Application.Running:
+		app.publishAppEvent(v1.EventTypeNormal, "ApplicationRunning",
+			"is running")
+	}
+}
+
+// restoreCheckpoint seeds the app with the state saved before the shim restarted
+func (app *Application) restoreCheckpoint(checkpoint appCheckpoint) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.restoredState = checkpoint.State
+	if len(checkpoint.Placeholders) > 0 {
+		progress := newPlaceholderProgress(app.taskGroups)
+		for name, tg := range checkpoint.Placeholders {
+			if p, ok := progress.groups[name]; ok {
+				p.created = tg.Created
+				p.failed = tg.Failed
+			}
+		}
+		app.placeholderProgress = progress
+	}
 }
 
 func (app *Application) SetPlaceholderTimeout(timeout int64) {
@@ -613,3 +1692,9 @@ func (app *Application) SetPlaceholderTimeout(timeout int64) {
 	defer app.lock.Unlock()
 	app.placeholderTimeoutInSec = timeout
 }
+
+func (app *Application) getPlaceholderTimeout() int64 {
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	return app.placeholderTimeoutInSec
+}
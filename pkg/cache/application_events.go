@@ -19,6 +19,8 @@
 package cache
 
 import (
+	"strconv"
+
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
@@ -83,6 +85,15 @@ func (st ApplicationStatusChangeEvent) GetState() string {
 	return st.state
 }
 
+// NewApplicationTaskGroupsChangeEvent updates the placeholder statistics of the task groups in the application CRD,
+// the state of the app is not changed
+func NewApplicationTaskGroupsChangeEvent(appID string) ApplicationStatusChangeEvent {
+	return ApplicationStatusChangeEvent{
+		applicationID: appID,
+		event:         events.AppTaskGroupsChange,
+	}
+}
+
 // ------------------------
 // SubmitTask application
 // ------------------------
@@ -168,6 +179,107 @@ func (fe FailApplicationEvent) GetApplicationID() string {
 	return fe.applicationID
 }
 
+// ------------------------
+// Reject application
+// ------------------------
+type RejectApplicationEvent struct {
+	applicationID string
+	event         events.ApplicationEventType
+	reason        string
+}
+
+// NewRejectApplicationEvent rejects an app with the reason given by the core
+func NewRejectApplicationEvent(appID, reason string) RejectApplicationEvent {
+	return RejectApplicationEvent{
+		applicationID: appID,
+		event:         events.RejectApplication,
+		reason:        reason,
+	}
+}
+
+func (re RejectApplicationEvent) GetEvent() events.ApplicationEventType {
+	return re.event
+}
+
+func (re RejectApplicationEvent) GetArgs() []interface{} {
+	args := make([]interface{}, 1)
+	args[0] = re.reason
+	return args
+}
+
+func (re RejectApplicationEvent) GetApplicationID() string {
+	return re.applicationID
+}
+
+// ------------------------
+// Kill application
+// ------------------------
+type KillApplicationEvent struct {
+	applicationID string
+	event         events.ApplicationEventType
+	reason        string
+	deletePods    bool
+}
+
+// NewKillApplicationEvent kills an app, when deletePods is set the pods of the app are
+// deleted and the app is removed from the core, otherwise the pods are expected to be gone
+func NewKillApplicationEvent(appID, reason string, deletePods bool) KillApplicationEvent {
+	return KillApplicationEvent{
+		applicationID: appID,
+		event:         events.KillApplication,
+		reason:        reason,
+		deletePods:    deletePods,
+	}
+}
+
+func (ke KillApplicationEvent) GetEvent() events.ApplicationEventType {
+	return ke.event
+}
+
+func (ke KillApplicationEvent) GetArgs() []interface{} {
+	args := make([]interface{}, 2)
+	args[0] = ke.reason
+	args[1] = strconv.FormatBool(ke.deletePods)
+	return args
+}
+
+func (ke KillApplicationEvent) GetApplicationID() string {
+	return ke.applicationID
+}
+
+// ------------------------
+// Complete application
+// ------------------------
+type CompleteApplicationEvent struct {
+	applicationID string
+	event         events.ApplicationEventType
+	removed       bool
+}
+
+// NewCompleteApplicationEvent completes an app, when removed is set the app is already removed from
+// the core and it is removed from the context once its placeholders are cleaned up
+func NewCompleteApplicationEvent(appID string, removed bool) CompleteApplicationEvent {
+	return CompleteApplicationEvent{
+		applicationID: appID,
+		event:         events.CompleteApplication,
+		removed:       removed,
+	}
+}
+
+func (ce CompleteApplicationEvent) GetEvent() events.ApplicationEventType {
+	return ce.event
+}
+
+func (ce CompleteApplicationEvent) GetArgs() []interface{} {
+	args := make([]interface{}, 1)
+	args[0] = strconv.FormatBool(ce.removed)
+	return args
+}
+
+func (ce CompleteApplicationEvent) GetApplicationID() string {
+	return ce.applicationID
+}
+
 // ------------------------
 // Reservation Update Event
 // ------------------------
@@ -233,14 +345,19 @@ type ReleaseAppAllocationAskEvent struct {
 	applicationID   string
 	taskID          string
 	terminationType string
+	policy          string
 	event           events.ApplicationEventType
 }
 
-func NewReleaseAppAllocationAskEvent(appID string, allocTermination si.TerminationType, taskID string) ReleaseAppAllocationAskEvent {
+// NewReleaseAppAllocationAskEvent releases the pending ask of a task, the placeholder of the ask is deleted,
+// a real task is handled following the released ask policy
+func NewReleaseAppAllocationAskEvent(appID string, allocTermination si.TerminationType, taskID string,
+	policy string) ReleaseAppAllocationAskEvent {
 	return ReleaseAppAllocationAskEvent{
 		applicationID:   appID,
 		taskID:          taskID,
 		terminationType: si.TerminationType_name[int32(allocTermination)],
+		policy:          policy,
 		event:           events.ReleaseAppAllocationAsk,
 	}
 }
@@ -250,12 +367,44 @@ func (re ReleaseAppAllocationAskEvent) GetApplicationID() string {
 }
 
 func (re ReleaseAppAllocationAskEvent) GetArgs() []interface{} {
-	args := make([]interface{}, 2)
+	args := make([]interface{}, 3)
 	args[0] = re.taskID
 	args[1] = re.terminationType
+	args[2] = re.policy
 	return args
 }
 
 func (re ReleaseAppAllocationAskEvent) GetEvent() events.ApplicationEventType {
 	return re.event
 }
+
+// ------------------------
+// Release the reservation of a single task group
+// ------------------------
+type ReleaseTaskGroupEvent struct {
+	applicationID string
+	taskGroupName string
+	event         events.ApplicationEventType
+}
+
+func NewReleaseTaskGroupEvent(appID string, taskGroupName string) ReleaseTaskGroupEvent {
+	return ReleaseTaskGroupEvent{
+		applicationID: appID,
+		taskGroupName: taskGroupName,
+		event:         events.ReleaseTaskGroup,
+	}
+}
+
+func (re ReleaseTaskGroupEvent) GetApplicationID() string {
+	return re.applicationID
+}
+
+func (re ReleaseTaskGroupEvent) GetArgs() []interface{} {
+	args := make([]interface{}, 1)
+	args[0] = re.taskGroupName
+	return args
+}
+
+func (re ReleaseTaskGroupEvent) GetEvent() events.ApplicationEventType {
+	return re.event
+}
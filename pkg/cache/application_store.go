@@ -0,0 +1,131 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// number of shards of the application store
+const appStoreShards = 32
+
+// applicationStore holds the apps of the context. The apps are spread over shards, each shard
+// is guarded by its own lock, so the informer callbacks, the scheduling loop and the core callbacks
+// only contend when they access apps in the same shard. The store only guards the membership of
+// the apps, the state of an app is guarded by the app lock.
+type applicationStore struct {
+	shards [appStoreShards]*appStoreShard
+}
+
+type appStoreShard struct {
+	apps map[string]*Application
+	sync.RWMutex
+}
+
+func newApplicationStore() *applicationStore {
+	store := &applicationStore{}
+	for i := range store.shards {
+		store.shards[i] = &appStoreShard{
+			apps: make(map[string]*Application),
+		}
+	}
+	return store
+}
+
+func (s *applicationStore) shard(appID string) *appStoreShard {
+	h := fnv.New32a()
+	// writing to a hash never returns an error
+	//nolint:errcheck
+	_, _ = h.Write([]byte(appID))
+	return s.shards[h.Sum32()%appStoreShards]
+}
+
+// get returns the app with the given ID, or nil if the app does not exist
+func (s *applicationStore) get(appID string) *Application {
+	shard := s.shard(appID)
+	shard.RLock()
+	defer shard.RUnlock()
+	return shard.apps[appID]
+}
+
+func (s *applicationStore) put(app *Application) {
+	shard := s.shard(app.applicationID)
+	shard.Lock()
+	defer shard.Unlock()
+	shard.apps[app.applicationID] = app
+}
+
+// putIfAbsent adds the app unless an app with the same ID already exists,
+// the app that is in the store after the call is returned.
+func (s *applicationStore) putIfAbsent(app *Application) *Application {
+	shard := s.shard(app.applicationID)
+	shard.Lock()
+	defer shard.Unlock()
+	if existing, ok := shard.apps[app.applicationID]; ok {
+		return existing
+	}
+	shard.apps[app.applicationID] = app
+	return app
+}
+
+// removeIf removes the app when the check passes. The check runs without the shard lock, it may take
+// the app lock. The app is only removed if it has not been replaced in the meantime, the removed app is
+// returned, nil if the app does not exist or has been replaced.
+func (s *applicationStore) removeIf(appID string, check func(app *Application) error) (*Application, error) {
+	app := s.get(appID)
+	if app == nil {
+		return nil, nil
+	}
+	if check != nil {
+		if err := check(app); err != nil {
+			return nil, err
+		}
+	}
+	shard := s.shard(appID)
+	shard.Lock()
+	defer shard.Unlock()
+	if shard.apps[appID] != app {
+		return nil, nil
+	}
+	delete(shard.apps, appID)
+	return app, nil
+}
+
+// forEach calls the function for each app, only one shard is locked at a time,
+// the apps added or removed during the iteration may or may not be visited.
+func (s *applicationStore) forEach(fn func(app *Application)) {
+	for _, shard := range s.shards {
+		shard.RLock()
+		for _, app := range shard.apps {
+			fn(app)
+		}
+		shard.RUnlock()
+	}
+}
+
+func (s *applicationStore) size() int {
+	size := 0
+	for _, shard := range s.shards {
+		shard.RLock()
+		size += len(shard.apps)
+		shard.RUnlock()
+	}
+	return size
+}
@@ -0,0 +1,96 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestApplicationStore(t *testing.T) {
+	store := newApplicationStore()
+	assert.Equal(t, store.size(), 0)
+	assert.Assert(t, store.get("app-1") == nil)
+
+	app1 := NewApplication("app-1", "root.a", "bob", map[string]string{}, newMockSchedulerAPI())
+	store.put(app1)
+	assert.Equal(t, store.size(), 1)
+	assert.Equal(t, store.get("app-1"), app1)
+
+	// putIfAbsent keeps the existing app
+	dup := NewApplication("app-1", "root.b", "bob", map[string]string{}, newMockSchedulerAPI())
+	assert.Equal(t, store.putIfAbsent(dup), app1)
+	assert.Equal(t, store.get("app-1"), app1)
+
+	// failed check leaves the app in the store
+	removed, err := store.removeIf("app-1", func(app *Application) error {
+		return fmt.Errorf("not removable")
+	})
+	assert.ErrorContains(t, err, "not removable")
+	assert.Assert(t, removed == nil)
+	assert.Equal(t, store.size(), 1)
+
+	// the check runs without the shard lock, the app replaced in the meantime is kept
+	removed, err = store.removeIf("app-1", func(app *Application) error {
+		assert.Equal(t, store.get("app-1"), app1)
+		store.put(dup)
+		return nil
+	})
+	assert.NilError(t, err)
+	assert.Assert(t, removed == nil)
+	assert.Equal(t, store.get("app-1"), dup)
+	store.put(app1)
+
+	removed, err = store.removeIf("app-1", nil)
+	assert.NilError(t, err)
+	assert.Equal(t, removed, app1)
+	assert.Equal(t, store.size(), 0)
+
+	// removing an unknown app is not an error
+	removed, err = store.removeIf("app-1", nil)
+	assert.NilError(t, err)
+	assert.Assert(t, removed == nil)
+}
+
+func TestApplicationStoreConcurrentAccess(t *testing.T) {
+	store := newApplicationStore()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				appID := fmt.Sprintf("app-%d-%d", i, j)
+				store.putIfAbsent(NewApplication(appID, "root.a", "bob", map[string]string{}, newMockSchedulerAPI()))
+				store.forEach(func(app *Application) {})
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, store.size(), 1000)
+
+	count := 0
+	store.forEach(func(app *Application) {
+		count++
+	})
+	assert.Equal(t, count, 1000)
+}
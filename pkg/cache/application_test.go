@@ -19,6 +19,7 @@
 package cache
 
 import (
+	"fmt"
 	"sort"
 	"strings"
 	"sync"
@@ -30,10 +31,12 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 
 	"github.com/apache/incubator-yunikorn-core/pkg/api"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/apis/yunikorn.apache.org/v1alpha1"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/appmgmt/interfaces"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
@@ -41,6 +44,7 @@ import (
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/dispatcher"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/metrics"
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
 
@@ -75,6 +79,65 @@ func TestSubmitApplication(t *testing.T) {
 	assertAppState(t, app, events.States().Application.Submitted, 10*time.Second)
 }
 
+func TestApplicationRoute(t *testing.T) {
+	schedulerConf := conf.GetSchedulerConf()
+	federated := schedulerConf.FederatedClusterIDs
+	schedulerConf.FederatedClusterIDs = "cluster-b"
+	defer func() {
+		schedulerConf.FederatedClusterIDs = federated
+	}()
+
+	var rmID, partition string
+	mockedSchedulerAPI := newMockSchedulerAPI()
+	mockedSchedulerAPI.updateFn = func(request *si.UpdateRequest) error {
+		rmID = request.RmID
+		partition = request.NewApplications[0].PartitionName
+		return nil
+	}
+
+	// app routed to a federated cluster
+	app := NewApplication("app00001", "root.abc", "testuser", map[string]string{}, mockedSchedulerAPI)
+	app.setRoute("cluster-b", "gpu")
+	err := app.handle(NewSubmitApplicationEvent(app.applicationID))
+	assert.NilError(t, err)
+	assert.Equal(t, rmID, "cluster-b")
+	assert.Equal(t, partition, "gpu")
+
+	// app routed to an unknown cluster falls back to the local cluster
+	app = NewApplication("app00002", "root.abc", "testuser", map[string]string{}, mockedSchedulerAPI)
+	app.setRoute("cluster-x", "")
+	err = app.handle(NewSubmitApplicationEvent(app.applicationID))
+	assert.NilError(t, err)
+	assert.Equal(t, rmID, schedulerConf.ClusterID)
+	assert.Equal(t, partition, constants.DefaultPartition)
+}
+
+func TestApplicationUserGroups(t *testing.T) {
+	var ugi *si.UserGroupInformation
+	mockedSchedulerAPI := newMockSchedulerAPI()
+	mockedSchedulerAPI.updateFn = func(request *si.UpdateRequest) error {
+		ugi = request.NewApplications[0].Ugi
+		return nil
+	}
+
+	// the groups are passed to the core along with the user
+	app := NewApplication("app00001", "root.abc", "testuser", map[string]string{}, mockedSchedulerAPI)
+	app.setGroups([]string{"dev", "system:authenticated"})
+	err := app.handle(NewSubmitApplicationEvent(app.applicationID))
+	assert.NilError(t, err)
+	assert.Equal(t, ugi.User, "testuser")
+	assert.DeepEqual(t, ugi.Groups, []string{"dev", "system:authenticated"})
+	assert.DeepEqual(t, app.GetGroups(), []string{"dev", "system:authenticated"})
+
+	// the same information is sent when the app is recovered
+	app = NewApplication("app00002", "root.abc", "testuser", map[string]string{}, mockedSchedulerAPI)
+	app.setGroups([]string{"ops"})
+	err = app.handle(NewSimpleApplicationEvent(app.applicationID, events.RecoverApplication))
+	assert.NilError(t, err)
+	assert.Equal(t, ugi.User, "testuser")
+	assert.DeepEqual(t, ugi.Groups, []string{"ops"})
+}
+
 func TestRunApplication(t *testing.T) {
 	ms := &mockSchedulerAPI{}
 	ms.updateFn = func(request *si.UpdateRequest) error {
@@ -201,6 +264,54 @@ func TestFailApplication(t *testing.T) {
 	events.SetRecorderForTest(record.NewFakeRecorder(1024))
 }
 
+func TestPublishAppEvents(t *testing.T) {
+	mockedAPIProvider := client.NewMockedAPIProvider()
+	mgr := NewPlaceholderManager(mockedAPIProvider.GetAPIs())
+	mgr.Start()
+	defer mgr.Stop()
+
+	recorder := record.NewFakeRecorder(1024)
+	events.SetRecorderForTest(recorder)
+	defer events.SetRecorderForTest(record.NewFakeRecorder(1024))
+
+	// an app without owner does not publish any app event
+	app := NewApplication("app-test-001", "root.abc", "testuser",
+		map[string]string{constants.AppTagNamespace: "test-ns"}, newMockSchedulerAPI())
+	assert.Assert(t, app.getOwnerObjectReference() == nil)
+	err := app.handle(NewSubmitApplicationEvent(app.applicationID))
+	assert.NilError(t, err)
+	assert.Equal(t, len(recorder.Events), 0)
+
+	// the controller is preferred over the other owners
+	notController := false
+	controller := true
+	app = NewApplication("app-test-002", "root.abc", "testuser",
+		map[string]string{constants.AppTagNamespace: "test-ns"}, newMockSchedulerAPI())
+	app.setOwnReferences([]apis.OwnerReference{
+		{APIVersion: "v1", Kind: "ConfigMap", Name: "cm", UID: "UID-cm", Controller: &notController},
+		{APIVersion: "batch/v1", Kind: "Job", Name: "job", UID: "UID-job", Controller: &controller},
+	})
+	owner := app.getOwnerObjectReference()
+	assert.Equal(t, owner.Kind, "Job")
+	assert.Equal(t, owner.Name, "job")
+	assert.Equal(t, owner.Namespace, "test-ns")
+	assert.Equal(t, string(owner.UID), "UID-job")
+
+	// lifecycle milestones
+	err = app.handle(NewSubmitApplicationEvent(app.applicationID))
+	assert.NilError(t, err)
+	assert.Equal(t, <-recorder.Events, "Normal ApplicationSubmitted Application app-test-002: submitted to queue root.abc")
+	err = app.handle(NewSimpleApplicationEvent(app.applicationID, events.AcceptApplication))
+	assert.NilError(t, err)
+	assert.Equal(t, <-recorder.Events, "Normal ApplicationAccepted Application app-test-002: accepted by the scheduler in partition default")
+	err = app.handle(NewRunApplicationEvent(app.applicationID))
+	assert.NilError(t, err)
+	assert.Equal(t, <-recorder.Events, "Normal ApplicationRunning Application app-test-002: is running")
+	err = app.handle(NewFailApplicationEvent(app.applicationID, "test failure"))
+	assert.NilError(t, err)
+	assert.Equal(t, <-recorder.Events, "Warning ApplicationFailed Application app-test-002: scheduling failed, reason: test failure")
+}
+
 func TestReleaseAppAllocation(t *testing.T) {
 	context := initContextForTest()
 	ms := &mockSchedulerAPI{}
@@ -231,6 +342,7 @@ func TestReleaseAppAllocation(t *testing.T) {
 	task := NewTask("task01", app, context, pod)
 	app.addTask(task)
 	task.allocationUUID = UUID
+	app.allocations.add(UUID, task)
 	// app must be running states
 	err := app.handle(NewReleaseAppAllocationEvent(appID, si.TerminationType_TIMEOUT, UUID))
 	if err == nil {
@@ -246,6 +358,62 @@ func TestReleaseAppAllocation(t *testing.T) {
 	assertAppState(t, app, events.States().Application.Running, 3*time.Second)
 }
 
+func TestReleaseAppAllocationAskOfRealTask(t *testing.T) {
+	context := initContextForTest()
+	dispatcher.RegisterEventHandler(dispatcher.EventTypeApp, context.ApplicationEventHandler())
+	dispatcher.RegisterEventHandler(dispatcher.EventTypeTask, context.TaskEventHandler())
+	dispatcher.Start()
+	defer dispatcher.Stop()
+
+	appID := "app-released-ask-01"
+	app := NewApplication(appID, "root.abc", "testuser", map[string]string{}, newMockSchedulerAPI())
+	context.applications.put(app)
+	app.SetState(events.States().Application.Running)
+	pod := &v1.Pod{
+		ObjectMeta: apis.ObjectMeta{
+			Name: "pod-released-ask-01",
+			UID:  "UID-released-ask-01",
+		},
+	}
+	task := NewTask("UID-released-ask-01", app, context, pod)
+	app.addTask(task)
+	task.sm.SetState(events.States().Task.Scheduling)
+	release := func(policy string) {
+		err := app.handle(NewReleaseAppAllocationAskEvent(appID, si.TerminationType_STOPPED_BY_RM, task.taskID, policy))
+		assert.NilError(t, err)
+	}
+
+	// by default the real task keeps waiting
+	release(conf.ReleasedAskSkip)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, task.GetTaskState(), events.States().Task.Scheduling)
+
+	// the task goes back to Pending and is resubmitted to the core
+	mockedAPIProvider, ok := context.apiProvider.(*client.MockedAPIProvider)
+	assert.Assert(t, ok, "expecting MockedAPIProvider")
+	release(conf.ReleasedAskRequeue)
+	err := utils.WaitForCondition(func() bool {
+		return mockedAPIProvider.GetSchedulerApiUpdateCount() == int32(1)
+	}, 10*time.Millisecond, time.Second)
+	assert.NilError(t, err, "task is not requeued after its ask is released")
+	assert.Equal(t, task.GetTaskState(), events.States().Task.Scheduling)
+
+	// a task that is not waiting for its ask is not affected
+	task.sm.SetState(events.States().Task.Pending)
+	release(conf.ReleasedAskFail)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, task.GetTaskState(), events.States().Task.Pending)
+
+	// the task is failed
+	task.sm.SetState(events.States().Task.Scheduling)
+	release(conf.ReleasedAskFail)
+	err = utils.WaitForCondition(func() bool {
+		return task.GetTaskState() == events.States().Task.Failed
+	}, 10*time.Millisecond, time.Second)
+	assert.NilError(t, err, "task is not failed after its ask is released")
+	assertAppState(t, app, events.States().Application.Running, time.Second)
+}
+
 func newMockSchedulerAPI() *mockSchedulerAPI {
 	return &mockSchedulerAPI{
 		registerFn: func(request *si.RegisterResourceManagerRequest, callback api.ResourceManagerCallback) (response *si.RegisterResourceManagerResponse, e error) {
@@ -294,7 +462,7 @@ func TestGetNonTerminatedTaskAlias(t *testing.T) {
 	context := initContextForTest()
 	appID := "app00001"
 	app := NewApplication(appID, "root.a", "testuser", map[string]string{}, newMockSchedulerAPI())
-	context.applications[appID] = app
+	context.applications.put(app)
 	// app doesn't have any task
 	res := app.getNonTerminatedTaskAlias()
 	assert.Equal(t, len(res), 0)
@@ -426,7 +594,7 @@ func TestSetTaskGroupsAndSchedulingPolicy(t *testing.T) {
 	assert.Equal(t, tg2.Tolerations[0].Effect, v1.TaintEffectNoSchedule)
 	assert.Equal(t, tg2.Tolerations[0].TolerationSeconds, &duration)
 
-	// This is a weird calculation for memory as the request is rounded up before multiplying.
+	// The memory of each placeholder is rounded up to MB before multiplying, the same as the placeholder pods ask.
 	// TG1: 500Mi, 10 members: each member 525M -> total 5250M
 	// TG2: 1000Mi, 20 members: each member 1049M -> total 20980
 	// overall usage 5250M + 20980M -> 26230M. This will also be the queue usage so the correct handling
@@ -434,6 +602,12 @@ func TestSetTaskGroupsAndSchedulingPolicy(t *testing.T) {
 	expectedPlaceholderAsk := common.NewResourceBuilder().AddResource(constants.Memory, 26230).AddResource(constants.CPU, 25000).Build()
 	actualPlaceholderAsk := app.getPlaceholderAsk()
 	assert.DeepEqual(t, actualPlaceholderAsk, expectedPlaceholderAsk)
+
+	// the exact bytes are asked when the memory is not rounded
+	conf.GetSchedulerConf().MemoryConversion = conf.MemoryConversionBytes
+	defer func() { conf.GetSchedulerConf().MemoryConversion = "" }()
+	expectedPlaceholderAsk = common.NewResourceBuilder().AddResource(constants.Memory, 10*500*1024*1024).AddResource(constants.CPU, 5000).Build()
+	assert.DeepEqual(t, app.getTaskGroupAsk(tg1), expectedPlaceholderAsk)
 }
 
 type threadSafePodsMap struct {
@@ -479,7 +653,7 @@ func TestTryReserve(t *testing.T) {
 	// create a new app
 	app := NewApplication("app00001", "root.abc", "test-user",
 		map[string]string{}, mockedAPIProvider.GetAPIs().SchedulerAPI)
-	context.applications[app.applicationID] = app
+	context.applications.put(app)
 
 	// set app scheduling policy
 	app.setSchedulingPolicy(v1alpha1.SchedulingPolicy{
@@ -553,7 +727,7 @@ func TestTryReservePostRestart(t *testing.T) {
 	// create a new app
 	app := NewApplication("app00001", "root.abc", "test-user",
 		map[string]string{}, mockedAPIProvider.GetAPIs().SchedulerAPI)
-	context.applications[app.applicationID] = app
+	context.applications.put(app)
 
 	// set taskGroups
 	app.setTaskGroups([]v1alpha1.TaskGroup{
@@ -654,6 +828,245 @@ func TestTryReservePostRestart(t *testing.T) {
 	assert.Equal(t, createdPods.count(), 0)
 }
 
+func TestTryReserveTaskGroupDependencies(t *testing.T) {
+	context := initContextForTest()
+	dispatcher.RegisterEventHandler(dispatcher.EventTypeApp, context.ApplicationEventHandler())
+	dispatcher.Start()
+	defer dispatcher.Stop()
+
+	// inject the mocked clients to the placeholder manager
+	createdPods := newThreadSafePodsMap()
+	mockedAPIProvider := client.NewMockedAPIProvider()
+	mockedAPIProvider.MockCreateFn(func(pod *v1.Pod) (*v1.Pod, error) {
+		createdPods.add(pod)
+		return pod, nil
+	})
+	mgr := NewPlaceholderManager(mockedAPIProvider.GetAPIs())
+	mgr.Start()
+	defer mgr.Stop()
+
+	app := NewApplication("app00001", "root.abc", "test-user",
+		map[string]string{}, mockedAPIProvider.GetAPIs().SchedulerAPI)
+	context.applications.put(app)
+
+	// the workers are only reserved once the driver is bound
+	app.setTaskGroups([]v1alpha1.TaskGroup{
+		{
+			Name:      "driver",
+			MinMember: 1,
+			MinResource: map[string]resource.Quantity{
+				v1.ResourceCPU.String(): resource.MustParse("500m"),
+			},
+		},
+		{
+			Name:      "workers",
+			MinMember: 2,
+			MinResource: map[string]resource.Quantity{
+				v1.ResourceCPU.String(): resource.MustParse("1000m"),
+			},
+			DependsOn: []string{"driver"},
+		},
+	})
+
+	err := app.handle(NewSubmitApplicationEvent(app.applicationID))
+	assert.NilError(t, err)
+	assertAppState(t, app, events.States().Application.Submitted, 3*time.Second)
+	err = app.handle(NewSimpleApplicationEvent(app.GetApplicationID(), events.AcceptApplication))
+	assert.NilError(t, err)
+	app.Schedule()
+	assertAppState(t, app, events.States().Application.Reserving, 3*time.Second)
+
+	// only the placeholder of the driver is created in the first stage
+	driverName := utils.GeneratePlaceholderName("driver", app.applicationID, 0)
+	err = utils.WaitForCondition(func() bool {
+		return createdPods.count() == 1
+	}, 100*time.Millisecond, 3*time.Second)
+	assert.NilError(t, err, "placeholder of the driver is not created")
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, createdPods.count(), 1)
+	progress, ok := app.getPlaceholderProgress().get("driver")
+	assert.Assert(t, ok)
+	assert.Equal(t, progress.created, int32(1))
+	_, ok = app.getPlaceholderProgress().get("workers")
+	assert.Assert(t, !ok)
+
+	// bind the driver placeholder, the workers are reserved in the next stage
+	placeholder := NewFromTaskMeta(driverName, app, context, interfaces.TaskMetadata{
+		ApplicationID: app.applicationID,
+		TaskID:        driverName,
+		Pod: &v1.Pod{
+			ObjectMeta: apis.ObjectMeta{
+				Name: driverName,
+				UID:  types.UID(driverName),
+			},
+		},
+		Placeholder:   true,
+		TaskGroupName: "driver",
+	})
+	placeholder.sm.SetState(events.States().Task.Bound)
+	app.addTask(placeholder)
+	err = app.handle(NewSimpleApplicationEvent(app.applicationID, events.UpdateReservation))
+	assert.NilError(t, err)
+	err = utils.WaitForCondition(func() bool {
+		return createdPods.count() == 3
+	}, 100*time.Millisecond, 3*time.Second)
+	assert.NilError(t, err, "placeholders of the workers are not created")
+	progress, ok = app.getPlaceholderProgress().get("workers")
+	assert.Assert(t, ok)
+	assert.Equal(t, progress.created, int32(2))
+	assertAppState(t, app, events.States().Application.Reserving, time.Second)
+}
+
+func TestResourceGangReservation(t *testing.T) {
+	app := NewApplication("app-resource-gang", "root.abc", "test-user",
+		map[string]string{}, newMockSchedulerAPI())
+	app.setTaskGroups([]v1alpha1.TaskGroup{
+		{
+			Name:      "members",
+			MinMember: 1,
+			MinResource: map[string]resource.Quantity{
+				v1.ResourceCPU.String(): resource.MustParse("1"),
+			},
+		},
+		{
+			Name: "resources",
+			MinResource: map[string]resource.Quantity{
+				v1.ResourceCPU.String(): resource.MustParse("3"),
+			},
+			MaxPlaceholderResource: map[string]resource.Quantity{
+				v1.ResourceCPU.String(): resource.MustParse("2"),
+			},
+		},
+	})
+	// the min resource is reserved by 2 placeholders of 1.5 cpu
+	taskGroups := app.getTaskGroups()
+	assert.Equal(t, taskGroups[1].MinMember, int32(2))
+	assert.Equal(t, taskGroups[1].MinResource[v1.ResourceCPU.String()], *resource.NewMilliQuantity(1500, resource.DecimalSI))
+	assert.Equal(t, app.getPlaceholderAsk().Resources[constants.CPU].Value, int64(4000))
+
+	cpu := func(milli int64) *si.Resource {
+		return common.NewResourceBuilder().AddResource(constants.CPU, milli).Build()
+	}
+	desired := utils.NewTaskGroupInstanceCountMap()
+	desired.Add("members", 1)
+	desired.Add("resources", 2)
+	bound := utils.NewTaskGroupInstanceCountMap()
+	bound.Add("resources", 2)
+	// the task group with min members needs all its placeholders
+	assert.Assert(t, !app.isReservationComplete(desired, bound, map[string]*si.Resource{"resources": cpu(3000)}))
+	// the task group without min member needs its min resource
+	bound.Add("members", 1)
+	assert.Assert(t, !app.isReservationComplete(desired, bound, map[string]*si.Resource{"resources": cpu(2999)}))
+	assert.Assert(t, app.isReservationComplete(desired, bound, map[string]*si.Resource{"resources": cpu(3000)}))
+}
+
+func TestScheduleNonGangTask(t *testing.T) {
+	context := initContextForTest()
+	app := NewApplication("app00001", "root.abc", "test-user",
+		map[string]string{}, newMockSchedulerAPI())
+	app.setTaskGroups([]v1alpha1.TaskGroup{
+		{Name: "test-group-1", MinMember: 2},
+	})
+	newTask := func(taskID string, annotations map[string]string) *Task {
+		task := NewTask(taskID, app, context, &v1.Pod{
+			ObjectMeta: apis.ObjectMeta{
+				Name:        taskID,
+				UID:         types.UID(taskID),
+				Annotations: annotations,
+			},
+		})
+		app.addTask(task)
+		return task
+	}
+	member := newTask("member", map[string]string{
+		constants.AnnotationTaskGroupName: "test-group-1",
+	})
+	monitor := newTask("monitor", map[string]string{
+		constants.AnnotationTaskGroupName:  "test-group-1",
+		constants.AnnotationTaskGroupIndex: "0",
+		constants.AnnotationNonGang:        "true",
+	})
+	assert.Equal(t, member.getTaskGroupName(), "test-group-1")
+	// the non-gang pod is not a member of its task group
+	assert.Assert(t, monitor.nonGang)
+	assert.Equal(t, monitor.getTaskGroupName(), "")
+	assert.Equal(t, monitor.taskGroupIndex, int32(-1))
+
+	// while reserving, the non-gang pod is scheduled, the gang member waits for the reservation
+	app.SetState(events.States().Application.Reserving)
+	app.Schedule()
+	assert.Equal(t, member.GetTaskState(), events.States().Task.New)
+	assert.Equal(t, monitor.GetTaskState(), events.States().Task.Pending)
+}
+
+func TestScheduleMaxParallelTasks(t *testing.T) {
+	context := initContextForTest()
+	app := NewApplication("app00001", "root.abc", "test-user",
+		map[string]string{}, newMockSchedulerAPI())
+	app.setMaxParallelTasks(2)
+	tasks := make([]*Task, 0)
+	for i := 0; i < 3; i++ {
+		taskID := fmt.Sprintf("task-%d", i)
+		task := NewTask(taskID, app, context, &v1.Pod{
+			ObjectMeta: apis.ObjectMeta{
+				Name: taskID,
+				UID:  types.UID(taskID),
+			},
+		})
+		app.addTask(task)
+		tasks = append(tasks, task)
+	}
+	countTasks := func(state string) int {
+		count := 0
+		for _, task := range tasks {
+			if task.GetTaskState() == state {
+				count++
+			}
+		}
+		return count
+	}
+
+	// only 2 tasks are scheduled at a time, the third one is held
+	app.SetState(events.States().Application.Running)
+	app.Schedule()
+	assert.Equal(t, countTasks(events.States().Task.Pending), 2)
+	assert.Equal(t, countTasks(events.States().Task.New), 1)
+	app.Schedule()
+	assert.Equal(t, countTasks(events.States().Task.New), 1)
+
+	// a bound task frees a slot
+	for _, task := range tasks {
+		if task.GetTaskState() == events.States().Task.Pending {
+			task.sm.SetState(events.States().Task.Bound)
+			break
+		}
+	}
+	app.Schedule()
+	assert.Equal(t, countTasks(events.States().Task.Pending), 2)
+	assert.Equal(t, countTasks(events.States().Task.New), 0)
+
+	// no limit
+	app.setMaxParallelTasks(0)
+	assert.Equal(t, app.getParallelTaskSlots(), -1)
+}
+
+func TestSetTaskGroupsInvalidDependencies(t *testing.T) {
+	app := NewApplication("app00001", "root.abc", "test-user",
+		map[string]string{}, newMockSchedulerAPI())
+	taskGroups := []v1alpha1.TaskGroup{
+		{Name: "a", MinMember: 1, DependsOn: []string{"b"}},
+		{Name: "b", MinMember: 1, DependsOn: []string{"a"}},
+	}
+	app.setTaskGroups(taskGroups)
+	// the cycle is dropped, all the task groups are reserved at once
+	assert.Equal(t, len(app.getTaskGroups()), 2)
+	for _, tg := range app.getTaskGroups() {
+		assert.Equal(t, len(tg.DependsOn), 0)
+	}
+	// the task groups of the caller are not modified
+	assert.Equal(t, len(taskGroups[0].DependsOn), 1)
+}
+
 func TestTriggerAppRecovery(t *testing.T) {
 	// Trigger app recovery should be successful if the app is in New state
 	app := NewApplication("app00001", "root.abc", "test-user",
@@ -671,3 +1084,174 @@ func TestTriggerAppRecovery(t *testing.T) {
 	err = app.TriggerAppRecovery()
 	assert.ErrorContains(t, err, "event RecoverApplication inappropriate in current state Submitted")
 }
+
+func TestReleaseTaskGroup(t *testing.T) {
+	context := initContextForTest()
+	dispatcher.RegisterEventHandler(dispatcher.EventTypeApp, context.ApplicationEventHandler())
+	dispatcher.Start()
+	defer dispatcher.Stop()
+
+	// inject the mocked clients to the placeholder manager
+	deletedPods := newThreadSafePodsMap()
+	mockedAPIProvider := client.NewMockedAPIProvider()
+	mockedAPIProvider.MockDeleteFn(func(pod *v1.Pod) error {
+		deletedPods.add(pod)
+		return nil
+	})
+	mgr := NewPlaceholderManager(mockedAPIProvider.GetAPIs())
+	mgr.Start()
+	defer mgr.Stop()
+
+	app := NewApplication("app00001", "root.abc", "test-user",
+		map[string]string{}, mockedAPIProvider.GetAPIs().SchedulerAPI)
+	context.applications.put(app)
+	app.setTaskGroups([]v1alpha1.TaskGroup{
+		{
+			Name:      "test-group-1",
+			MinMember: 1,
+			MinResource: map[string]resource.Quantity{
+				v1.ResourceCPU.String(): resource.MustParse("500m"),
+			},
+		},
+		{
+			Name:      "test-group-2",
+			MinMember: 1,
+			MinResource: map[string]resource.Quantity{
+				v1.ResourceCPU.String(): resource.MustParse("1000m"),
+			},
+		},
+	})
+
+	// placeholder of the first group is bound, the second one is still pending
+	for i, tg := range app.getTaskGroups() {
+		name := utils.GeneratePlaceholderName(tg.Name, app.applicationID, 0)
+		placeholder := NewFromTaskMeta(name, app, context, interfaces.TaskMetadata{
+			ApplicationID: app.applicationID,
+			TaskID:        name,
+			Pod: &v1.Pod{
+				ObjectMeta: apis.ObjectMeta{
+					Name: name,
+					UID:  types.UID(name),
+				},
+			},
+			Placeholder:   true,
+			TaskGroupName: tg.Name,
+		})
+		if i == 0 {
+			placeholder.sm.SetState(events.States().Task.Bound)
+		} else {
+			placeholder.sm.SetState(events.States().Task.Scheduling)
+		}
+		app.addTask(placeholder)
+	}
+	app.SetState(events.States().Application.Running)
+
+	// the task groups can only be released while the app is accepted or reserving
+	err := context.ReleaseTaskGroup("app00002", "test-group-2")
+	assert.ErrorContains(t, err, "application app00002 is not found")
+	err = context.ReleaseTaskGroup(app.applicationID, "test-group-2")
+	assert.ErrorContains(t, err, "cannot be released in state Running")
+	app.SetState(events.States().Application.Reserving)
+	err = context.ReleaseTaskGroup(app.applicationID, "unknown-group")
+	assert.ErrorContains(t, err, "task group unknown-group of application app00001 is not found")
+
+	// releasing an unknown task group is a no-op
+	err = app.handle(NewReleaseTaskGroupEvent(app.applicationID, "unknown-group"))
+	assert.NilError(t, err)
+	assert.Equal(t, len(app.getTaskGroups()), 2)
+
+	// release the second group, the rest of the gang is satisfied
+	err = context.ReleaseTaskGroup(app.applicationID, "test-group-2")
+	assert.NilError(t, err)
+	assertAppState(t, app, events.States().Application.Running, 3*time.Second)
+	assert.Equal(t, len(app.getTaskGroups()), 1)
+	assert.Equal(t, app.getTaskGroups()[0].Name, "test-group-1")
+	assert.Assert(t, common.Equals(app.getPlaceholderAsk(),
+		common.NewResourceBuilder().AddResource(constants.CPU, 500).Build()))
+	assertAppState(t, app, events.States().Application.Running, 3*time.Second)
+
+	// only the placeholder of the released group is deleted
+	err = utils.WaitForCondition(func() bool {
+		return deletedPods.count() == 1
+	}, 100*time.Millisecond, 3*time.Second)
+	assert.NilError(t, err, "placeholder is not deleted")
+	_, ok := deletedPods.pods[utils.GeneratePlaceholderName("test-group-2", app.applicationID, 0)]
+	assert.Assert(t, ok)
+}
+
+func TestPlaceholderReplacementMetrics(t *testing.T) {
+	context := initContextForTest()
+	// the metrics are global, use a task group name no other test uses
+	taskGroup := "tg-replacement-metrics"
+	app := NewApplication("app01", "root.default", "bob", map[string]string{}, newMockSchedulerAPI())
+	app.setTaskGroups([]v1alpha1.TaskGroup{
+		{
+			Name:      taskGroup,
+			MinMember: 3,
+		},
+	})
+	app.SetState(events.States().Application.Running)
+	newTask := func(uid string, placeholder bool, index string) *Task {
+		task := NewFromTaskMeta(uid, app, context, interfaces.TaskMetadata{
+			ApplicationID: app.applicationID,
+			TaskID:        uid,
+			Pod: &v1.Pod{
+				ObjectMeta: apis.ObjectMeta{
+					Name: "pod-" + uid,
+					UID:  types.UID(uid),
+					Annotations: map[string]string{
+						constants.AnnotationTaskGroupName:  taskGroup,
+						constants.AnnotationTaskGroupIndex: index,
+					},
+				},
+			},
+			Placeholder:   placeholder,
+			TaskGroupName: taskGroup,
+		})
+		task.allocationUUID = "uuid-" + uid
+		app.addTask(task)
+		app.allocations.add(task.allocationUUID, task)
+		return task
+	}
+	bind := func(task *Task) {
+		task.sm.SetState(events.States().Task.Allocated)
+		assert.NilError(t, task.handle(NewBindTaskEvent(app.applicationID, task.taskID)))
+	}
+	phs := []*Task{newTask("ph-0", true, "0"), newTask("ph-1", true, "1"), newTask("ph-2", true, "2")}
+	members := []*Task{newTask("task-0", false, ""), newTask("task-1", false, "")}
+	for _, ph := range phs {
+		ph.boundTime = time.Now().Add(-2 * time.Minute)
+	}
+	counts := func() metrics.PlaceholderCounts {
+		return metrics.GetPlaceholderMetrics().GetPlaceholderCounts(taskGroup)
+	}
+
+	// the placeholder is released before the real member is bound
+	err := app.handle(NewReleaseAppAllocationEvent(app.applicationID, si.TerminationType_PLACEHOLDER_REPLACED, "uuid-ph-0"))
+	assert.NilError(t, err)
+	assert.Equal(t, counts().Replaced, 0)
+	bind(members[0])
+	assert.Equal(t, counts().Replaced, 1)
+	assert.Assert(t, members[0].boundTime.Sub(members[0].replacedPlaceholderBoundTime) >= 2*time.Minute)
+
+	// the real member is bound before the placeholder is released
+	bind(members[1])
+	assert.Equal(t, counts().Replaced, 1)
+	err = app.handle(NewReleaseAppAllocationEvent(app.applicationID, si.TerminationType_PLACEHOLDER_REPLACED, "uuid-ph-1"))
+	assert.NilError(t, err)
+	assert.Equal(t, counts().Replaced, 2)
+
+	// a placeholder released on timeout is not replaced
+	err = app.handle(NewReleaseAppAllocationEvent(app.applicationID, si.TerminationType_TIMEOUT, "uuid-ph-2"))
+	assert.NilError(t, err)
+	assert.Equal(t, counts(), metrics.PlaceholderCounts{Replaced: 2, TimedOut: 1})
+}
+
+func TestApplicationInfoCopiesTags(t *testing.T) {
+	app := NewApplication("app00001", "root.a", "testuser", map[string]string{"tag": "value"}, newMockSchedulerAPI())
+	info := app.getApplicationInfo()
+	assert.DeepEqual(t, info.Tags, map[string]string{"tag": "value"})
+	// the dump is encoded without the app lock, it must not share the tags of the app
+	info.Tags["tag"] = "changed"
+	assert.Equal(t, app.GetTags()["tag"], "value")
+}
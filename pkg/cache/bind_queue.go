@@ -0,0 +1,137 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/metrics"
+)
+
+// bindRequest is an allocation waiting to be bound to its node
+type bindRequest struct {
+	nodeID   string
+	bind     func()
+	queuedAt time.Time
+}
+
+// bindQueue binds the allocations from the core outside of the task state transitions,
+// a slow bind call to the API server does not block the processing of other allocations.
+// Up to maxWorkers binds run in parallel, the binds on the same node are done one at a time in
+// the order they are queued. Workers are started on demand and exit when there is nothing left to bind.
+type bindQueue struct {
+	maxWorkers int
+	workers    int
+	// requests ready to be bound, their node has no other bind in progress
+	ready []*bindRequest
+	// requests queued behind a bind in progress on the same node, the key exists while a bind is in progress
+	waiting map[string][]*bindRequest
+	lock    sync.Mutex
+}
+
+func newBindQueue(maxWorkers int) *bindQueue {
+	return &bindQueue{
+		maxWorkers: maxWorkers,
+		ready:      make([]*bindRequest, 0),
+		waiting:    make(map[string][]*bindRequest),
+	}
+}
+
+// submit queues the bind of an allocation on the node, this never blocks
+func (q *bindQueue) submit(nodeID string, bind func()) {
+	req := &bindRequest{
+		nodeID:   nodeID,
+		bind:     bind,
+		queuedAt: time.Now(),
+	}
+	metrics.GetBindMetrics().IncPending()
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if pending, ok := q.waiting[nodeID]; ok {
+		q.waiting[nodeID] = append(pending, req)
+		return
+	}
+	q.waiting[nodeID] = make([]*bindRequest, 0)
+	q.ready = append(q.ready, req)
+	if q.workers < q.maxWorkers {
+		q.workers++
+		go q.work()
+	}
+}
+
+// setMaxWorkers changes the number of binds run in parallel, more workers are started at once for the requests
+// that are ready, the workers over the limit exit once their bind is done
+func (q *bindQueue) setMaxWorkers(maxWorkers int) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.maxWorkers = maxWorkers
+	for q.workers < q.maxWorkers && q.workers < len(q.ready) {
+		q.workers++
+		go q.work()
+	}
+}
+
+func (q *bindQueue) work() {
+	for req := q.next(); req != nil; req = q.next() {
+		metrics.GetBindMetrics().ObserveBindWait(time.Since(req.queuedAt))
+		req.bind()
+		metrics.GetBindMetrics().DecPending()
+		q.done(req.nodeID)
+	}
+}
+
+// next returns the next request to bind, nil means the worker has exited
+func (q *bindQueue) next() *bindRequest {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if len(q.ready) == 0 || q.workers > q.maxWorkers {
+		q.workers--
+		return nil
+	}
+	req := q.ready[0]
+	q.ready[0] = nil
+	q.ready = q.ready[1:]
+	return req
+}
+
+// done releases the node of a finished bind, the next request on the node becomes ready
+func (q *bindQueue) done(nodeID string) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	pending := q.waiting[nodeID]
+	if len(pending) == 0 {
+		delete(q.waiting, nodeID)
+		return
+	}
+	q.ready = append(q.ready, pending[0])
+	q.waiting[nodeID] = pending[1:]
+}
+
+// getPendingCount returns the number of requests queued or being bound
+func (q *bindQueue) getPendingCount() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	count := 0
+	for _, pending := range q.waiting {
+		// the request ready or in progress on the node and the ones waiting behind it
+		count += len(pending) + 1
+	}
+	return count
+}
@@ -0,0 +1,163 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
+)
+
+func TestBindQueue(t *testing.T) {
+	queue := newBindQueue(3)
+	var lock sync.Mutex
+	running := make(map[string]int)
+	maxRunning := 0
+	maxRunningNode := 0
+	bound := make(map[string][]int)
+	release := make(chan struct{})
+
+	for i := 0; i < 4; i++ {
+		for n := 0; n < 4; n++ {
+			nodeID := fmt.Sprintf("node-%d", n)
+			seq := i
+			queue.submit(nodeID, func() {
+				lock.Lock()
+				running[nodeID]++
+				total := 0
+				for _, r := range running {
+					total += r
+				}
+				if total > maxRunning {
+					maxRunning = total
+				}
+				if running[nodeID] > maxRunningNode {
+					maxRunningNode = running[nodeID]
+				}
+				lock.Unlock()
+
+				<-release
+
+				lock.Lock()
+				running[nodeID]--
+				bound[nodeID] = append(bound[nodeID], seq)
+				lock.Unlock()
+			})
+		}
+	}
+	// the binds wait for the release, all the requests are pending
+	assert.Equal(t, queue.getPendingCount(), 16)
+	close(release)
+
+	err := utils.WaitForCondition(func() bool {
+		return queue.getPendingCount() == 0
+	}, 10*time.Millisecond, 5*time.Second)
+	assert.NilError(t, err)
+
+	lock.Lock()
+	defer lock.Unlock()
+	// the number of workers is limited and a node has one bind at a time
+	assert.Assert(t, maxRunning <= 3, "running binds %d", maxRunning)
+	assert.Equal(t, maxRunningNode, 1)
+	// the binds of a node are done in the order they were queued
+	for n := 0; n < 4; n++ {
+		assert.DeepEqual(t, bound[fmt.Sprintf("node-%d", n)], []int{0, 1, 2, 3})
+	}
+
+	// the workers exit once there is nothing left to bind
+	err = utils.WaitForCondition(func() bool {
+		queue.lock.Lock()
+		defer queue.lock.Unlock()
+		return queue.workers == 0
+	}, 10*time.Millisecond, time.Second)
+	assert.NilError(t, err)
+}
+
+func TestBindQueueSlowNode(t *testing.T) {
+	queue := newBindQueue(2)
+	slow := make(chan struct{})
+	done := make(chan string, 10)
+
+	queue.submit("slow-node", func() {
+		<-slow
+		done <- "slow-node"
+	})
+	// a slow bind does not block the binds on other nodes
+	for i := 0; i < 5; i++ {
+		queue.submit("fast-node", func() {
+			done <- "fast-node"
+		})
+	}
+	for i := 0; i < 5; i++ {
+		select {
+		case nodeID := <-done:
+			assert.Equal(t, nodeID, "fast-node")
+		case <-time.After(5 * time.Second):
+			t.Fatal("binds on the fast node are blocked by the slow node")
+		}
+	}
+	close(slow)
+	assert.Equal(t, <-done, "slow-node")
+}
+
+func TestBindQueueSetMaxWorkers(t *testing.T) {
+	queue := newBindQueue(1)
+	release := make(chan struct{})
+	var lock sync.Mutex
+	running, maxRunning := 0, 0
+	for n := 0; n < 4; n++ {
+		queue.submit(fmt.Sprintf("node-%d", n), func() {
+			lock.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			lock.Unlock()
+			<-release
+			lock.Lock()
+			running--
+			lock.Unlock()
+		})
+	}
+	// the ready requests are picked up by the extra workers at once
+	queue.setMaxWorkers(4)
+	err := utils.WaitForCondition(func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return running == 4
+	}, 10*time.Millisecond, 5*time.Second)
+	assert.NilError(t, err)
+
+	// the workers over the lowered limit exit once their bind is done
+	queue.setMaxWorkers(1)
+	close(release)
+	err = utils.WaitForCondition(func() bool {
+		queue.lock.Lock()
+		defer queue.lock.Unlock()
+		return queue.workers == 0
+	}, 10*time.Millisecond, 5*time.Second)
+	assert.NilError(t, err)
+	assert.Equal(t, queue.getPendingCount(), 0)
+	assert.Equal(t, maxRunning, 4)
+}
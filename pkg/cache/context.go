@@ -28,7 +28,6 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/tools/cache"
-	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
 
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/appmgmt/interfaces"
 	schedulercache "github.com/apache/incubator-yunikorn-k8shim/pkg/cache/external"
@@ -43,14 +42,34 @@ import (
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
 
+// states of an app in the scheduler core the shim converges the state of the app to
+const (
+	coreAppRejected  = "Rejected"
+	coreAppCompleted = "Completed"
+	coreAppExpired   = "Expired"
+	coreAppFailing   = "Failing"
+	coreAppFailed    = "Failed"
+)
+
 // context maintains scheduling state, like apps and apps' tasks.
 type Context struct {
-	applications   map[string]*Application        // apps
+	applications   *applicationStore              // apps
 	nodes          *schedulerNodes                // nodes
 	schedulerCache *schedulercache.SchedulerCache // external cache
 	apiProvider    client.APIProvider             // apis to interact with api-server, scheduler-core, etc
 	predictor      *plugin.Predictor              // K8s predicates
-	lock           *sync.RWMutex                  // lock
+	bindQueue      *bindQueue                     // binds the allocations outside of the task state transitions
+	appScheduler   *appScheduler                  // runs the scheduling cycles of the apps
+	burst          *throughputBurst               // raises the scheduling limits after the recovery
+	reconciler     *stateReconciler               // compares the allocations with the core, nil if disabled
+	adoptedPods    *adoptedPods                   // pods of other schedulers adopted by an app
+	checkpointer   *appCheckpointer               // saves the state of the apps, nil if disabled
+	provisioning   *provisioningRequests          // asks the autoscaler for the capacity of the task groups, nil if disabled
+	capacities     *queueCapacities               // max capacities of the queues of the core, nil if disabled
+	reservations   *reservationGate               // limits the apps of a queue reserving for their gang at the same time
+	recovery       *recoveryProgress              // progress of the recovery after a restart
+	decisions      *decisionStream                // fans out the scheduling decisions to the subscribers
+	lock           *sync.RWMutex                  // lock for the pod and volume updates in the external cache
 }
 
 // Create a new context for the scheduler.
@@ -61,8 +80,15 @@ func NewContext(apis client.APIProvider) *Context {
 	// nodecontroller needs the cache
 	// predictor need the cache, volumebinder and informers
 	ctx := &Context{
-		applications: make(map[string]*Application),
+		applications: newApplicationStore(),
 		apiProvider:  apis,
+		bindQueue:    newBindQueue(apis.GetAPIs().Conf.GetBindWorkers()),
+		appScheduler: newAppScheduler(apis.GetAPIs().Conf.GetScheduleWorkers(), apis.GetAPIs().Conf.GetScheduleTaskBudget()),
+		burst:        &throughputBurst{},
+		adoptedPods:  newAdoptedPods(),
+		reservations: newReservationGate(),
+		recovery:     newRecoveryProgress(),
+		decisions:    newDecisionStream(),
 		lock:         &sync.RWMutex{},
 	}
 
@@ -73,9 +99,63 @@ func NewContext(apis client.APIProvider) *Context {
 	ctx.nodes = newSchedulerNodes(apis.GetAPIs().SchedulerAPI, ctx.schedulerCache)
 	ctx.predictor = plugin.NewPredictor(schedulercache.GetPluginArgs(), apis.IsTestingMode())
 
+	// the app checkpoint is reached by the apps through their context
+	if apis.GetAPIs().Conf.EnableAppCheckpoint {
+		ctx.checkpointer = newAppCheckpointer(apis.GetAPIs())
+	}
+	if apis.GetAPIs().Conf.GetQueueCapacityRefresh() > 0 {
+		ctx.capacities = newQueueCapacities(listCoreQueues(apis.GetAPIs().Conf.GetCoreWebAddress()))
+	}
+	if apis.GetAPIs().Conf.EnableProvisioningRequest && apis.GetAPIs().DynamicClient != nil {
+		ctx.provisioning = newProvisioningRequests(apis.GetAPIs())
+	}
+	// the overhead of the runtime classes is added to the pods admitted without it
+	common.SetRuntimeClassOverheadResolver(nil)
+	if informer := apis.GetAPIs().RuntimeClassInformer; informer != nil {
+		common.SetRuntimeClassOverheadResolver(runtimeClassOverhead(informer.Lister()))
+	}
+
 	return ctx
 }
 
+// StartAppCheckpoint starts writing the app checkpoints periodically, this is a noop if the checkpoint is disabled
+func (ctx *Context) StartAppCheckpoint() {
+	if ctx.checkpointer != nil {
+		ctx.checkpointer.start()
+	}
+}
+
+// StopAppCheckpoint writes the pending app checkpoints and stops the periodic writes
+func (ctx *Context) StopAppCheckpoint() {
+	if ctx.checkpointer != nil {
+		ctx.checkpointer.stop()
+	}
+}
+
+// StartProvisioningRequests starts checking the status of the provisioning requests of the unschedulable task groups,
+// this is a noop if the provisioning requests are disabled
+func (ctx *Context) StartProvisioningRequests() {
+	if ctx.provisioning != nil {
+		ctx.provisioning.start()
+	}
+}
+
+// StopProvisioningRequests stops checking the status of the provisioning requests
+func (ctx *Context) StopProvisioningRequests() {
+	if ctx.provisioning != nil {
+		ctx.provisioning.stop()
+	}
+}
+
+// IsRecoveryRequired returns false for an app that was completed or killed before the restart
+// according to the app checkpoint, such an app does not need to be recovered.
+func (ctx *Context) IsRecoveryRequired(appID string) bool {
+	if ctx.checkpointer != nil {
+		return !ctx.checkpointer.isFinished(appID)
+	}
+	return true
+}
+
 func (ctx *Context) AddSchedulingEventHandlers() {
 	ctx.apiProvider.AddEventHandler(&client.ResourceEventHandlers{
 		Type:     client.NodeInformerHandlers,
@@ -92,7 +172,7 @@ func (ctx *Context) AddSchedulingEventHandlers() {
 		DeleteFn: ctx.removePodFromCache,
 	})
 
-	nodeCoordinator := newNodeResourceCoordinator(ctx.nodes)
+	nodeCoordinator := newNodeResourceCoordinator(ctx.nodes, ctx.adoptedPods)
 	ctx.apiProvider.AddEventHandler(&client.ResourceEventHandlers{
 		Type:     client.PodInformerHandlers,
 		FilterFn: nodeCoordinator.filterPods,
@@ -100,6 +180,13 @@ func (ctx *Context) AddSchedulingEventHandlers() {
 		DeleteFn: nodeCoordinator.deletePod,
 	})
 
+	ctx.apiProvider.AddEventHandler(&client.ResourceEventHandlers{
+		Type:     client.PodInformerHandlers,
+		FilterFn: ctx.adoptedPods.filterPods,
+		UpdateFn: ctx.updateAdoptedPod,
+		DeleteFn: ctx.deleteAdoptedPod,
+	})
+
 	ctx.apiProvider.AddEventHandler(&client.ResourceEventHandlers{
 		Type:     client.ConfigMapInformerHandlers,
 		FilterFn: ctx.filterConfigMaps,
@@ -107,6 +194,11 @@ func (ctx *Context) AddSchedulingEventHandlers() {
 		UpdateFn: ctx.updateConfigMaps,
 		DeleteFn: ctx.deleteConfigMaps,
 	})
+
+	ctx.apiProvider.AddEventHandler(&client.ResourceEventHandlers{
+		Type:     client.NamespaceInformerHandlers,
+		DeleteFn: ctx.deleteNamespace,
+	})
 }
 
 func (ctx *Context) addNode(obj interface{}) {
@@ -245,6 +337,39 @@ func (ctx *Context) updatePodInCache(oldObj, newObj interface{}) {
 			zap.String("podName", oldPod.Name),
 			zap.Error(err))
 	}
+
+	// scheduling gates can only be removed from a pod,
+	// the gated task is released once all the gates are gone
+	if len(utils.GetSchedulingGates(oldPod)) > 0 && len(utils.GetSchedulingGates(newPod)) == 0 {
+		ctx.ungateTask(newPod)
+	}
+}
+
+func (ctx *Context) ungateTask(pod *v1.Pod) {
+	appID, err := utils.GetApplicationIDFromPod(pod)
+	if err != nil {
+		log.Logger().Debug("unable to get task by given pod", zap.Error(err))
+		return
+	}
+	taskID := string(pod.UID)
+	task, err := ctx.getTask(appID, taskID)
+	if err != nil {
+		log.Logger().Debug("task of the ungated pod is not found",
+			zap.String("podName", pod.Name),
+			zap.Error(err))
+		return
+	}
+	// the last gate might be the queue admission gate removed by the shim itself,
+	// the task has moved on already and must not be ungated again
+	if task.GetTaskState() != events.States().Task.Gated {
+		task.logger().Debug("task of the ungated pod is not gated, ignoring the update",
+			zap.String("podName", pod.Name),
+			zap.String("state", task.GetTaskState()))
+		return
+	}
+	task.logger().Info("all scheduling gates are removed from pod",
+		zap.String("podName", pod.Name))
+	dispatcher.Dispatch(NewSimpleTaskEvent(appID, taskID, events.UngateTask))
 }
 
 // filter pods by scheduler name and state
@@ -276,6 +401,7 @@ func (ctx *Context) filterConfigMaps(obj interface{}) bool {
 // when detects the configMap for the scheduler is added, trigger hot-refresh
 func (ctx *Context) addConfigMaps(obj interface{}) {
 	log.Logger().Debug("configMap added")
+	ctx.updatePredicates(obj)
 	ctx.triggerReloadConfig()
 }
 
@@ -292,6 +418,7 @@ func (ctx *Context) updateConfigMaps(obj, newObj interface{}) {
 		// We trigger configuration reload, on yunikorn-core side, it keeps checking config
 		// file state once this is called. And the actual reload happens when it detects
 		// actual changes on the content.
+		ctx.updatePredicates(newObj)
 		ctx.triggerReloadConfig()
 	} else {
 		log.Logger().Warn("Skip to reload scheduler configuration")
@@ -304,6 +431,62 @@ func (ctx *Context) deleteConfigMaps(obj interface{}) {
 	log.Logger().Debug("configMap deleted")
 }
 
+// when a namespace is deleted, all pods in the namespace are gone, the apps that
+// live in the namespace are killed, this releases their stale placeholders.
+func (ctx *Context) deleteNamespace(obj interface{}) {
+	var namespace *v1.Namespace
+	switch t := obj.(type) {
+	case *v1.Namespace:
+		namespace = t
+	case cache.DeletedFinalStateUnknown:
+		var ok bool
+		namespace, ok = t.Obj.(*v1.Namespace)
+		if !ok {
+			log.Logger().Error("Cannot convert to *v1.Namespace", zap.Any("namespace", obj))
+			return
+		}
+	default:
+		log.Logger().Error("Cannot convert to *v1.Namespace", zap.Any("namespace", obj))
+		return
+	}
+
+	log.Logger().Info("namespace deleted, killing applications in the namespace",
+		zap.String("namespace", namespace.Name))
+	for _, app := range ctx.getApplicationsInNamespace(namespace.Name) {
+		// the pods are deleted with the namespace, the app is removed from the core
+		killEvent := NewKillApplicationEvent(app.applicationID, fmt.Sprintf("namespace %s is deleted", namespace.Name), false)
+		if app.canHandle(killEvent) {
+			dispatcher.Dispatch(killEvent)
+			continue
+		}
+		// apps that are not yet accepted by the core cannot be killed, fail them instead
+		failEvent := NewFailApplicationEvent(app.applicationID,
+			fmt.Sprintf("namespace %s is deleted", namespace.Name))
+		if app.canHandle(failEvent) {
+			dispatcher.Dispatch(failEvent)
+		}
+	}
+}
+
+func (ctx *Context) getApplicationsInNamespace(namespace string) []*Application {
+	return ctx.SelectApplications(func(app *Application) bool {
+		return app.GetTags()[constants.AppTagNamespace] == namespace
+	})
+}
+
+// the predicates are configured in the configmap, these changes are applied by the shim
+// directly, the predicates set by the flags are restored when the configuration is removed.
+func (ctx *Context) updatePredicates(obj interface{}) {
+	configMap, ok := obj.(*v1.ConfigMap)
+	if !ok {
+		log.Logger().Error("Cannot convert to *v1.ConfigMap", zap.Any("configMap", obj))
+		return
+	}
+	if err := ctx.predictor.UpdatePredicates(plugin.ParsePredicatesConfig(configMap.Data)); err != nil {
+		log.Logger().Error("invalid predicates configuration, keep the current predicates", zap.Error(err))
+	}
+}
+
 func (ctx *Context) triggerReloadConfig() {
 	log.Logger().Info("trigger scheduler configuration reloading")
 	clusterId := ctx.apiProvider.GetAPIs().Conf.ClusterID
@@ -344,6 +527,9 @@ func (ctx *Context) bindPodVolumes(pod *v1.Pod) error {
 		if ctx.schedulerCache.ArePodVolumesAllBound(podKey) {
 			log.Logger().Info("Binding Pod Volumes skipped: all volumes already bound",
 				zap.String("podName", pod.Name))
+		} else if ctx.apiProvider.GetAPIs().Conf.DryRun {
+			// the volume binder waits for the claims to be bound, they never are in the dry-run mode
+			log.Logger().Info("dry-run: skipped binding pod volumes", zap.String("podName", pod.Name))
 		} else {
 			log.Logger().Info("Binding Pod Volumes", zap.String("podName", pod.Name))
 			return ctx.apiProvider.GetAPIs().VolumeBinder.Binder.BindPodVolumes(assumedPod)
@@ -402,18 +588,95 @@ func (ctx *Context) ForgetPod(name string) error {
 }
 
 func (ctx *Context) UpdateApplication(app *Application) {
-	ctx.lock.Lock()
-	defer ctx.lock.Unlock()
-	ctx.applications[app.applicationID] = app
+	ctx.applications.put(app)
+}
+
+// ResumeApplication retries a failed application, the app is resubmitted to the scheduler core
+// and the asks of the tasks that are not allocated yet are rebuilt. An app with allocated tasks
+// cannot be resumed, removing the app from the core would release these allocations.
+func (ctx *Context) ResumeApplication(appID string) error {
+	app := ctx.applications.get(appID)
+	if app == nil {
+		return fmt.Errorf("application %s is not found in context", appID)
+	}
+	ev := NewSimpleApplicationEvent(appID, events.ResumeApplication)
+	if !app.canHandle(ev) {
+		return fmt.Errorf("application %s cannot be resumed in state %s", appID, app.GetApplicationState())
+	}
+	if allocated := len(app.GetAllocatedTasks()) + len(app.getBoundTasks()); allocated > 0 {
+		return fmt.Errorf("application %s cannot be resumed, it has %d allocated tasks", appID, allocated)
+	}
+	// the cause of an infeasible gang may be gone, check it again
+	if ctx.apiProvider.GetAPIs().Conf.EnableGangFeasibilityCheck && len(app.getTaskGroups()) > 0 {
+		if err := ctx.checkGangFeasibility(app); err != nil {
+			return fmt.Errorf("application %s cannot be resumed: %v", appID, err)
+		}
+	}
+	app.setGangInfeasibleReason("")
+	app.logger().Info("resuming application")
+	dispatcher.Dispatch(ev)
+	return nil
+}
+
+// KillApplication kills an app on request, the pods of the app are deleted and the app is removed
+// from the core, which releases all its allocations. Only the apps accepted by the core can be killed.
+func (ctx *Context) KillApplication(appID string) error {
+	app := ctx.applications.get(appID)
+	if app == nil {
+		return fmt.Errorf("application %s is not found in context", appID)
+	}
+	ev := NewKillApplicationEvent(appID, "killed by the administrator", true)
+	if !app.canHandle(ev) {
+		return fmt.Errorf("application %s cannot be killed in state %s", appID, app.GetApplicationState())
+	}
+	app.logger().Info("killing application")
+	dispatcher.Dispatch(ev)
+	return nil
+}
+
+// ReleaseTaskGroup cancels the reservation of one task group of an app on request, the placeholders of the
+// task group are deleted and its members are scheduled without placeholders. The reservation of the other
+// task groups is kept. Only the task groups of the apps that are accepted or reserving can be released.
+func (ctx *Context) ReleaseTaskGroup(appID, taskGroupName string) error {
+	app := ctx.applications.get(appID)
+	if app == nil {
+		return fmt.Errorf("application %s is not found in context", appID)
+	}
+	ev := NewReleaseTaskGroupEvent(appID, taskGroupName)
+	if !app.canHandle(ev) {
+		return fmt.Errorf("task groups of application %s cannot be released in state %s", appID, app.GetApplicationState())
+	}
+	found := false
+	for _, tg := range app.getTaskGroups() {
+		if tg.Name == taskGroupName {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("task group %s of application %s is not found", taskGroupName, appID)
+	}
+	app.logger().Info("releasing task group", zap.String("taskGroup", taskGroupName))
+	dispatcher.Dispatch(ev)
+	return nil
+}
+
+// NotifyAllocationReleased confirms the release of an allocation by the core when the app is removed from it,
+// a killed app waits for the core to release all its allocations
+func (ctx *Context) NotifyAllocationReleased(appID, allocUUID string) {
+	if app := ctx.applications.get(appID); app != nil {
+		if progress := app.getKillProgress(); progress != nil {
+			progress.onReleased(allocUUID)
+		}
+	}
 }
 
 // inform the scheduler that the application is completed,
 // the complete state may further explained to completed_with_errors(failed) or successfully_completed,
 // either way we need to release all allocations (if exists) for this application
 func (ctx *Context) NotifyApplicationComplete(appID string) {
-	if app := ctx.GetApplication(appID); app != nil {
-		log.Logger().Debug("NotifyApplicationComplete",
-			zap.String("appID", appID),
+	if app := ctx.applications.get(appID); app != nil {
+		app.logger().Debug("NotifyApplicationComplete",
 			zap.String("currentAppState", app.GetApplicationState()))
 		ev := NewSimpleApplicationEvent(appID, events.CompleteApplication)
 		dispatcher.Dispatch(ev)
@@ -421,9 +684,8 @@ func (ctx *Context) NotifyApplicationComplete(appID string) {
 }
 
 func (ctx *Context) NotifyApplicationFail(appID string) {
-	if app := ctx.GetApplication(appID); app != nil {
-		log.Logger().Debug("NotifyApplicationFail",
-			zap.String("appID", appID),
+	if app := ctx.applications.get(appID); app != nil {
+		app.logger().Debug("NotifyApplicationFail",
 			zap.String("currentAppState", app.GetApplicationState()))
 		ev := NewSimpleApplicationEvent(appID, events.FailApplication)
 		dispatcher.Dispatch(ev)
@@ -434,19 +696,51 @@ func (ctx *Context) NotifyTaskComplete(appID, taskID string) {
 	log.Logger().Debug("NotifyTaskComplete",
 		zap.String("appID", appID),
 		zap.String("taskID", taskID))
-	if app := ctx.GetApplication(appID); app != nil {
-		log.Logger().Debug("release allocation",
-			zap.String("appID", appID),
+	if app := ctx.applications.get(appID); app != nil {
+		// the task was already terminated by the phase of its pod
+		if task, err := app.GetTask(taskID); err == nil {
+			if state := task.GetTaskState(); state == events.States().Task.Succeeded ||
+				state == events.States().Task.Failed {
+				return
+			}
+		}
+		app.logger().Debug("release allocation",
 			zap.String("taskID", taskID))
 		ev := NewSimpleTaskEvent(appID, taskID, events.CompleteTask)
 		dispatcher.Dispatch(ev)
 	}
 }
 
+// NotifyTaskPhase moves a bound task along with the phase of its pod: a running pod moves the task to Running,
+// a succeeded or failed pod terminates the task as Succeeded or Failed. A task that is not bound yet when its
+// pod terminates, e.g. a recovered task, is completed.
+func (ctx *Context) NotifyTaskPhase(appID, taskID string, phase v1.PodPhase) {
+	task, err := ctx.getTask(appID, taskID)
+	if err != nil {
+		return
+	}
+	task.logger().Debug("NotifyTaskPhase",
+		zap.String("phase", string(phase)))
+	bound := isBoundState(task.GetTaskState())
+	switch {
+	case phase == v1.PodRunning && bound:
+		dispatcher.Dispatch(NewSimpleTaskEvent(appID, taskID, events.TaskRunning))
+	case phase == v1.PodSucceeded && bound:
+		dispatcher.Dispatch(NewSimpleTaskEvent(appID, taskID, events.TaskSucceeded))
+	case phase == v1.PodFailed && bound:
+		dispatcher.Dispatch(NewFailTaskEvent(appID, taskID, "pod failed"))
+	case phase == v1.PodSucceeded || phase == v1.PodFailed:
+		ctx.NotifyTaskComplete(appID, taskID)
+	}
+}
+
 // update application tags in the AddApplicationRequest based on the namespace annotation
 // adds the following tags to the request based on annotations (if exist):
 //    - namespace.resourcequota
 //    - namespace.parentqueue
+//    - namespace.strictfifo
+//    - namespace.priorityaging
+//    - namespace.label.<key> and namespace.annotation.<key> for the labels and annotations in the allow-lists
 func (ctx *Context) updateApplicationTags(request *interfaces.AddApplicationRequest, namespace string) {
 	namespaceObj := ctx.getNamespaceObject(namespace)
 	if namespaceObj == nil {
@@ -460,10 +754,76 @@ func (ctx *Context) updateApplicationTags(request *interfaces.AddApplicationRequ
 		}
 	}
 	// add parent queue info as an app tag
-	parentQueue := namespaceObj.Annotations["yunikorn.apache.org/parentqueue"]
+	parentQueue := namespaceObj.Annotations[constants.AnnotationParentQueue]
 	if parentQueue != "" {
 		request.Metadata.Tags[constants.AppTagNamespaceParentQueue] = parentQueue
 	}
+	// add the strict FIFO submission of the queue as an app tag
+	if strictFIFO := namespaceObj.Annotations[constants.AnnotationStrictFIFO]; strictFIFO != "" {
+		request.Metadata.Tags[constants.AppTagNamespaceStrictFIFO] = strictFIFO
+	}
+	// add the priority aging policy of the queue as an app tag
+	if aging := namespaceObj.Annotations[constants.AnnotationPriorityAging]; aging != "" {
+		request.Metadata.Tags[constants.AppTagNamespacePriorityAging] = aging
+	}
+	// add the allowed namespace labels and annotations as app tags
+	schedulerConf := ctx.apiProvider.GetAPIs().Conf
+	copyNamespaceTags(request.Metadata.Tags, namespaceObj.Labels, schedulerConf.GetNamespaceLabelTags(), constants.AppTagNamespaceLabelPrefix)
+	copyNamespaceTags(request.Metadata.Tags, namespaceObj.Annotations, schedulerConf.GetNamespaceAnnotationTags(), constants.AppTagNamespaceAnnotationPrefix)
+}
+
+// copyNamespaceTags copies the allowed keys of the namespace metadata into the app tags,
+// the tags already set on the app are not overwritten
+func copyNamespaceTags(tags map[string]string, metadata map[string]string, allowed []string, prefix string) {
+	for _, key := range allowed {
+		value, ok := metadata[key]
+		if !ok {
+			continue
+		}
+		if _, exist := tags[prefix+key]; !exist {
+			tags[prefix+key] = value
+		}
+	}
+}
+
+// applyNamespaceGangDefaults gives an app without task groups of its own the default task groups and gang
+// policy of its namespace, the timeouts set on the app are kept. The real members of the app that are not
+// in a task group join the first default task group, which is returned, empty if no defaults are applied.
+func (ctx *Context) applyNamespaceGangDefaults(request *interfaces.AddApplicationRequest, namespace string) string {
+	if len(request.Metadata.TaskGroups) > 0 {
+		return ""
+	}
+	namespaceObj := ctx.getNamespaceObject(namespace)
+	if namespaceObj == nil {
+		return ""
+	}
+	defaults, err := utils.GetNamespaceGangDefaults(namespaceObj)
+	if err != nil {
+		log.Logger().Warn("invalid default taskGroups of the namespace, the app is not gang scheduled",
+			zap.String("appID", request.Metadata.ApplicationID),
+			zap.String("namespace", namespace),
+			zap.Error(err))
+		return ""
+	}
+	if defaults == nil {
+		return ""
+	}
+	metadata := &request.Metadata
+	metadata.TaskGroups = defaults.TaskGroups
+	if metadata.PlaceholderTimeoutInSec <= 0 {
+		metadata.PlaceholderTimeoutInSec = defaults.PlaceholderTimeoutInSec
+	}
+	if metadata.PlaceholderProgressTimeoutInSec <= 0 {
+		metadata.PlaceholderProgressTimeoutInSec = defaults.ProgressTimeoutInSec
+	}
+	if defaults.GangSchedulingStyle != "" {
+		metadata.GangSchedulingStyle = defaults.GangSchedulingStyle
+	}
+	log.Logger().Info("app gets the default taskGroups of its namespace",
+		zap.String("appID", metadata.ApplicationID),
+		zap.String("namespace", namespace),
+		zap.Int("taskGroups", len(defaults.TaskGroups)))
+	return defaults.TaskGroups[0].Name
 }
 
 // returns the namespace object from the namespace's name
@@ -488,18 +848,19 @@ func (ctx *Context) getNamespaceObject(namespace string) *v1.Namespace {
 
 func (ctx *Context) AddApplication(request *interfaces.AddApplicationRequest) interfaces.ManagedApp {
 	log.Logger().Debug("AddApplication", zap.Any("Request", request))
-	if app := ctx.GetApplication(request.Metadata.ApplicationID); app != nil {
+	if app := ctx.applications.get(request.Metadata.ApplicationID); app != nil {
+		// a retry of a completed app that kept its placeholders reclaims them
+		ctx.reclaimReservation(app, request)
 		return app
 	}
 
-	ctx.lock.Lock()
-	defer ctx.lock.Unlock()
-
+	defaultTaskGroup := ""
 	if ns, ok := request.Metadata.Tags[constants.AppTagNamespace]; ok {
 		log.Logger().Debug("app namespace info",
 			zap.String("appID", request.Metadata.ApplicationID),
 			zap.String("namespace", ns))
 		ctx.updateApplicationTags(request, ns)
+		defaultTaskGroup = ctx.applyNamespaceGangDefaults(request, ns)
 	}
 
 	app := NewApplication(
@@ -508,61 +869,159 @@ func (ctx *Context) AddApplication(request *interfaces.AddApplicationRequest) in
 		request.Metadata.User,
 		request.Metadata.Tags,
 		ctx.apiProvider.GetAPIs().SchedulerAPI)
+	app.setGroups(request.Metadata.Groups)
+	app.setRuntimeClassName(request.Metadata.RuntimeClassName)
 	app.setTaskGroups(request.Metadata.TaskGroups)
 	app.SetPlaceholderTimeout(request.Metadata.PlaceholderTimeoutInSec)
 	app.setOwnReferences(request.Metadata.OwnerReferences)
+	app.setTaskOrderingPolicy(request.Metadata.TaskOrderingPolicy)
+	app.setRoute(request.Metadata.ClusterID, request.Metadata.Partition)
+	app.setCompletionPolicy(request.Metadata.CompletionPolicy)
+	app.setReservationProgressPolicy(request.Metadata.PlaceholderProgressTimeoutInSec, request.Metadata.GangSchedulingStyle)
+	app.setMaxParallelTasks(request.Metadata.MaxParallelTasks)
+	app.setMaxReservingApps(request.Metadata.MaxReservingApps)
+	app.setDefaultTaskGroup(defaultTaskGroup)
+	app.context = ctx
+	if ctx.checkpointer != nil {
+		if checkpoint, ok := ctx.checkpointer.getRestored(app.applicationID); ok {
+			app.restoreCheckpoint(checkpoint)
+		}
+	}
+	var memberErr error
+	if len(request.Metadata.TaskGroups) > 0 {
+		if ctx.apiProvider.GetAPIs().Conf.EnableGangFeasibilityCheck {
+			if err := ctx.checkGangFeasibility(app); err != nil {
+				app.setGangInfeasibleReason(err.Error())
+			}
+		} else {
+			memberErr = ctx.nodes.checkTaskGroupMembers(app.getTaskGroups(), app.getRuntimeClassName())
+		}
+	}
 
-	// add into cache
-	ctx.applications[app.applicationID] = app
-	log.Logger().Info("app added",
-		zap.String("appID", app.applicationID))
+	// add into cache, the app is built without holding any lock,
+	// if the same app is added in parallel, the first one wins
+	if existing := ctx.applications.putIfAbsent(app); existing != app {
+		return existing
+	}
+	app.logger().Info("app added")
+	// without the gang feasibility check the app is not failed, the owner is told why it stays pending
+	if memberErr != nil {
+		app.logger().Warn("task group member does not fit in any node", zap.Error(memberErr))
+		app.publishAppEvent(v1.EventTypeWarning, "TaskGroupMemberTooLarge", "%v", memberErr)
+	}
 
 	return app
 }
 
+// checkGangFeasibility checks if the gang of the app can ever be satisfied, the gang must fit in the
+// namespace quota (if there is one), in the max capacity of the queue (if it is known) and in the cluster.
+func (ctx *Context) checkGangFeasibility(app *Application) error {
+	if quotaStr, ok := app.tags[constants.AppTagNamespaceResourceQuota]; ok {
+		quota := &si.Resource{}
+		if err := json.Unmarshal([]byte(quotaStr), quota); err == nil {
+			if !common.FitIn(quota, app.getPlaceholderAsk()) {
+				return fmt.Errorf("gang can never be satisfied: gang requires %s, "+
+					"which exceeds the namespace quota %s", app.getPlaceholderAsk().String(), quota.String())
+			}
+		}
+	}
+	if ctx.capacities != nil {
+		if err := ctx.capacities.checkAsk(app.getRmID(), app.partition, app.GetQueue(), app.getPlaceholderAsk()); err != nil {
+			return fmt.Errorf("gang can never be satisfied: %v", err)
+		}
+	}
+	return ctx.nodes.checkGangFeasibility(app.getTaskGroups(), app.getRuntimeClassName())
+}
+
 func (ctx *Context) GetApplication(appID string) interfaces.ManagedApp {
-	ctx.lock.RLock()
-	defer ctx.lock.RUnlock()
-	if app, ok := ctx.applications[appID]; ok {
+	if app := ctx.applications.get(appID); app != nil {
 		return app
 	}
 	return nil
 }
 
 func (ctx *Context) RemoveApplication(appID string) error {
-	ctx.lock.Lock()
-	defer ctx.lock.Unlock()
-	if app, exist := ctx.applications[appID]; exist {
+	app, err := ctx.applications.removeIf(appID, func(app *Application) error {
 		//get the non-terminated task alias
 		nonTerminatedTaskAlias := app.getNonTerminatedTaskAlias()
 		// check there are any non-terminated task or not
 		if len(nonTerminatedTaskAlias) > 0 {
 			return fmt.Errorf("failed to remove application %s because it still has task in non-terminated task, tasks: %s", appID, strings.Join(nonTerminatedTaskAlias, ","))
 		}
-		// send the update request to scheduler core
-		rr := common.CreateUpdateRequestForRemoveApplication(app.applicationID, app.partition)
-		if err := ctx.apiProvider.GetAPIs().SchedulerAPI.Update(&rr); err != nil {
-			log.Logger().Error("failed to send remove application request to core", zap.Error(err))
-		}
-		delete(ctx.applications, appID)
-		log.Logger().Info("app removed",
-			zap.String("appID", appID))
-
 		return nil
+	})
+	if err != nil {
+		return err
 	}
-	return fmt.Errorf("application %s is not found in the context", appID)
+	if app == nil {
+		return fmt.Errorf("application %s is not found in the context", appID)
+	}
+	// send the update request to scheduler core, the app is already removed
+	// from the cache, so the request does not block other apps
+	rr := common.CreateUpdateRequestForRemoveApplication(app.applicationID, app.partition)
+	rr.RmID = app.getRmID()
+	if err := ctx.apiProvider.GetAPIs().SchedulerAPI.Update(&rr); err != nil {
+		app.logger().Error("failed to send remove application request to core", zap.Error(err))
+	}
+	if ctx.checkpointer != nil {
+		ctx.checkpointer.remove(appID)
+	}
+	ctx.reservations.release(appID)
+	app.logger().Info("app removed")
+	return nil
 }
 
 func (ctx *Context) RemoveApplicationInternal(appID string) error {
-	ctx.lock.Lock()
-	defer ctx.lock.Unlock()
-	if _, exist := ctx.applications[appID]; exist {
-		delete(ctx.applications, appID)
+	if app, _ := ctx.applications.removeIf(appID, nil); app != nil {
+		if ctx.checkpointer != nil {
+			ctx.checkpointer.remove(appID)
+		}
+		ctx.reservations.release(appID)
 		return nil
 	}
 	return fmt.Errorf("application %s is not found in the context", appID)
 }
 
+// HandleApplicationStateUpdate converges the state of an app with the state the core moved it to,
+// the core can complete or fail an app on its own, e.g when the placeholders of the app time out.
+// A completed app is removed from the context once its placeholders are cleaned up.
+func (ctx *Context) HandleApplicationStateUpdate(updated *si.UpdatedApplication) {
+	app := ctx.applications.get(updated.ApplicationID)
+	if app == nil {
+		log.Logger().Debug("state update of an unknown application",
+			zap.String("appID", updated.ApplicationID),
+			zap.String("state", updated.State))
+		return
+	}
+	switch updated.State {
+	case coreAppCompleted, coreAppExpired:
+		ev := NewCompleteApplicationEvent(app.applicationID, true)
+		if app.canHandle(ev) {
+			// the app is removed once its placeholders are cleaned up
+			dispatcher.Dispatch(ev)
+			return
+		}
+		// the app never ran, e.g all its placeholders timed out
+		go func() {
+			getPlaceholderManager().cleanUp(app)
+			if err := ctx.RemoveApplicationInternal(app.applicationID); err != nil {
+				app.logger().Error("failed to delete application", zap.Error(err))
+			}
+		}()
+		return
+	case coreAppFailing, coreAppFailed:
+		message := updated.Message
+		if message == "" {
+			message = fmt.Sprintf("application %s is failed by the scheduler", app.applicationID)
+		}
+		dispatcher.Dispatch(NewFailApplicationEvent(app.applicationID, message))
+	case coreAppRejected:
+		dispatcher.Dispatch(NewRejectApplicationEvent(app.applicationID, updated.Message))
+	}
+	// handle status update
+	dispatcher.Dispatch(NewApplicationStatusChangeEvent(app.applicationID, events.AppStateChange, updated.State))
+}
+
 // this implements ApplicationManagementProtocol
 func (ctx *Context) AddTask(request *interfaces.AddTaskRequest) interfaces.ManagedTask {
 	log.Logger().Debug("AddTask",
@@ -573,17 +1032,28 @@ func (ctx *Context) AddTask(request *interfaces.AddTaskRequest) interfaces.Manag
 		if app, valid := managedApp.(*Application); valid {
 			existingTask, err := app.GetTask(request.Metadata.TaskID)
 			if err != nil {
-				task := NewFromTaskMeta(request.Metadata.TaskID, app, ctx, request.Metadata)
+				metadata := request.Metadata
+				// the members of an app with the namespace default task groups do not name their task group
+				if metadata.TaskGroupName == "" && !metadata.Placeholder && metadata.Pod != nil &&
+					!utils.IsNonGangPod(metadata.Pod) {
+					metadata.TaskGroupName = app.getDefaultTaskGroup()
+				}
+				task := NewFromTaskMeta(request.Metadata.TaskID, app, ctx, metadata)
 				// in recovery mode, task is considered as allocated
 				if request.Recovery {
 					// in scheduling, allocationUUID is assigned by scheduler-core
 					// in recovery mode, allocationUuid equals to taskID, which also equals to the pod UID
 					task.setAllocated(request.Metadata.Pod.Spec.NodeName, request.Metadata.TaskID)
+				} else if pod := request.Metadata.Pod; pod != nil && utils.IsAssignedPod(pod) && !utils.IsPodTerminated(pod) {
+					ctx.addPreBoundTask(app, task)
+				} else if conflicts := app.validateTask(task); len(conflicts) > 0 {
+					// the task is added to the app but never scheduled, it is listed by the REST API
+					if err = task.quarantine(conflicts); err != nil {
+						task.logger().Warn("failed to quarantine task", zap.Error(err))
+					}
 				}
 				app.addTask(task)
-				log.Logger().Info("task added",
-					zap.String("appID", app.applicationID),
-					zap.String("taskID", task.taskID),
+				task.logger().Info("task added",
 					zap.String("taskState", task.GetTaskState()))
 
 				return task
@@ -594,19 +1064,47 @@ func (ctx *Context) AddTask(request *interfaces.AddTaskRequest) interfaces.Manag
 	return nil
 }
 
+// addPreBoundTask accounts for a pod that was bound to its node before it reached the scheduler,
+// e.g. with spec.nodeName set at creation. The pod is not scheduled again, the task goes straight to Bound.
+// The allocation is reported to the core with the node if the node is not registered yet, like in recovery,
+// otherwise the pod resources are added to the occupied resources of the node.
+func (ctx *Context) addPreBoundTask(app *Application, task *Task) {
+	nodeID := task.pod.Spec.NodeName
+	occupied := true
+	if node := ctx.nodes.getNode(nodeID); node != nil {
+		occupied = !node.addPreBoundAllocation(&si.Allocation{
+			AllocationKey:    task.taskID,
+			AllocationTags:   app.tags,
+			UUID:             task.taskID,
+			ResourcePerAlloc: task.resource,
+			QueueName:        app.queue,
+			NodeID:           nodeID,
+			ApplicationID:    app.applicationID,
+			Placeholder:      task.placeholder,
+			TaskGroupName:    task.taskGroupName,
+			PartitionName:    app.partition,
+		})
+	}
+	if occupied {
+		ctx.nodes.updateNodeOccupiedResources(nodeID, task.resource, AddOccupiedResource)
+	}
+	task.setPreBound(nodeID, occupied)
+	task.logger().Info("pod is bound to a node outside of the scheduler",
+		zap.String("nodeID", nodeID),
+		zap.Bool("occupiedResource", occupied))
+	events.GetRecorder().Eventf(task.pod, v1.EventTypeNormal, "PreBound",
+		"%s was bound to node %s before it was scheduled, it is not scheduled again", task.alias, nodeID)
+}
+
 func (ctx *Context) RemoveTask(appID, taskID string) error {
-	ctx.lock.RLock()
-	defer ctx.lock.RUnlock()
-	if app, ok := ctx.applications[appID]; ok {
+	if app := ctx.applications.get(appID); app != nil {
 		return app.removeTask(taskID)
 	}
 	return fmt.Errorf("application %s is not found in the context", appID)
 }
 
 func (ctx *Context) getTask(appID string, taskID string) (*Task, error) {
-	ctx.lock.RLock()
-	defer ctx.lock.RUnlock()
-	if app, ok := ctx.applications[appID]; ok {
+	if app := ctx.applications.get(appID); app != nil {
 		if managedTask, err := app.GetTask(taskID); err == nil {
 			if task, valid := managedTask.(*Task); valid {
 				return task, nil
@@ -616,21 +1114,32 @@ func (ctx *Context) getTask(appID string, taskID string) (*Task, error) {
 	return nil, fmt.Errorf("application %s is not found in context", appID)
 }
 
-func (ctx *Context) SelectApplications(filter func(app *Application) bool) []*Application {
-	ctx.lock.RLock()
-	defer ctx.lock.RUnlock()
+// GetTaskByAllocation returns the task of an allocation assigned by the core,
+// the lookup does not walk the tasks of the app
+func (ctx *Context) GetTaskByAllocation(appID, allocUUID string) (interfaces.ManagedTask, error) {
+	if app := ctx.applications.get(appID); app != nil {
+		return app.GetTaskByAllocation(allocUUID)
+	}
+	return nil, fmt.Errorf("application %s is not found in context", appID)
+}
 
+// SelectApplications returns the apps that pass the filter, the filter runs with
+// a shard of the application store locked and must not access the context.
+func (ctx *Context) SelectApplications(filter func(app *Application) bool) []*Application {
 	apps := make([]*Application, 0)
-	for _, app := range ctx.applications {
-		if filter != nil && !filter(app) {
-			continue
+	ctx.applications.forEach(func(app *Application) {
+		if filter == nil || filter(app) {
+			apps = append(apps, app)
 		}
-		apps = append(apps, app)
-	}
-
+	})
 	return apps
 }
 
+// HasInformersSynced returns true when the caches of all informers are synced
+func (ctx *Context) HasInformersSynced() bool {
+	return ctx.apiProvider.HasSynced()
+}
+
 func (ctx *Context) PublishEvents(eventRecords []*si.EventRecord) {
 	if len(eventRecords) > 0 {
 		for _, record := range eventRecords {
@@ -677,27 +1186,11 @@ func (ctx *Context) PublishEvents(eventRecords []*si.EventRecord) {
 // return true if the update was done and false if the update is skipped due to any error, or a dup operation
 func (ctx *Context) updatePodCondition(task *Task, podCondition *v1.PodCondition) bool {
 	if task.GetTaskState() == events.States().Task.Scheduling {
-		// only update the pod when pod condition changes
-		// minimize the overhead added to the api-server/etcd
-		if !utils.PodUnderCondition(task.pod, podCondition) {
-			log.Logger().Debug("updating pod condition",
-				zap.String("namespace", task.pod.Namespace),
-				zap.String("name", task.pod.Name),
-				zap.Any("podCondition", podCondition))
-			// call api-server to do the pod condition update
-			if podutil.UpdatePodCondition(&task.pod.Status, podCondition) {
-				if !ctx.apiProvider.IsTestingMode() {
-					_, err := ctx.apiProvider.GetAPIs().KubeClient.GetClientSet().CoreV1().
-						Pods(task.pod.Namespace).UpdateStatus(task.pod)
-					if err == nil {
-						return true
-					}
-					// only log the error here, no need to handle it if the update failed
-					log.Logger().Error("update pod condition failed",
-						zap.Error(err))
-				}
-			}
-		}
+		// only update the pod when the reason changes, minimize the overhead added to the api-server/etcd
+		return task.updatePodScheduledCondition(podCondition.Reason, podCondition.Message,
+			func(current *v1.PodCondition) bool {
+				return current.Status == podCondition.Status && current.Reason == podCondition.Reason
+			})
 	}
 	return false
 }
@@ -716,7 +1209,7 @@ func (ctx *Context) HandleContainerStateUpdate(request *si.UpdateContainerSchedu
 				&v1.PodCondition{
 					Type:    v1.PodScheduled,
 					Status:  v1.ConditionFalse,
-					Reason:  "SchedulingSkipped",
+					Reason:  conditionQueueQuotaExceeded,
 					Message: request.Reason,
 				}) {
 				events.GetRecorder().Eventf(task.pod,
@@ -732,9 +1225,19 @@ func (ctx *Context) HandleContainerStateUpdate(request *si.UpdateContainerSchedu
 					Reason:  v1.PodReasonUnschedulable,
 					Message: request.Reason,
 				}) {
-				events.GetRecorder().Eventf(task.pod,
-					v1.EventTypeNormal, "PodUnschedulable",
-					"Task %s is pending for the requested resources become available", task.alias)
+				if task.IsPlaceholder() {
+					// use the same event reason as the default scheduler for the placeholders,
+					// so the gang asks are reported the same way as any other unschedulable pod
+					events.GetRecorder().Eventf(task.pod,
+						v1.EventTypeWarning, "FailedScheduling",
+						"Placeholder %s of task group %s is pending for the requested resources become available",
+						task.alias, task.getTaskGroupName())
+					ctx.publishGangMembersEvent(task)
+				} else {
+					events.GetRecorder().Eventf(task.pod,
+						v1.EventTypeNormal, "PodUnschedulable",
+						"Task %s is pending for the requested resources become available", task.alias)
+				}
 			}
 		default:
 			log.Logger().Warn("no handler for container scheduling state",
@@ -743,6 +1246,29 @@ func (ctx *Context) HandleContainerStateUpdate(request *si.UpdateContainerSchedu
 	}
 }
 
+// publish an event to the real pods that are waiting for the placeholders of the same task group,
+// this is only done once per task group. Only events are published to the real pods, their pod
+// condition is left untouched, otherwise the autoscaler counts the gang twice and over-provisions.
+func (ctx *Context) publishGangMembersEvent(placeholder *Task) {
+	app := placeholder.application
+	taskGroupName := placeholder.getTaskGroupName()
+	if !app.markTaskGroupUnschedulable(taskGroupName) {
+		return
+	}
+	// ask the autoscaler for the capacity of the whole task group
+	if ctx.provisioning != nil {
+		ctx.provisioning.request(app, taskGroupName)
+	}
+	for _, task := range app.GetPendingTasks() {
+		if !task.IsPlaceholder() && task.getTaskGroupName() == taskGroupName {
+			events.GetRecorder().Eventf(task.GetTaskPod(),
+				v1.EventTypeNormal, "GangUnschedulable",
+				"Task %s is waiting for the placeholders of task group %s, "+
+					"which are pending for the requested resources become available", task.alias, taskGroupName)
+		}
+	}
+}
+
 func (ctx *Context) ApplicationEventHandler() func(obj interface{}) {
 	return func(obj interface{}) {
 		if event, ok := obj.(events.ApplicationEvent); ok {
@@ -750,6 +1276,7 @@ func (ctx *Context) ApplicationEventHandler() func(obj interface{}) {
 			if managedApp == nil {
 				log.Logger().Error("failed to handle application event",
 					zap.String("reason", "application not exist"))
+				dispatcher.RecordEventError(fmt.Errorf("application %s does not exist", event.GetApplicationID()))
 				return
 			}
 
@@ -759,6 +1286,7 @@ func (ctx *Context) ApplicationEventHandler() func(obj interface{}) {
 						log.Logger().Error("failed to handle application event",
 							zap.String("event", string(event.GetEvent())),
 							zap.Error(err))
+						dispatcher.RecordEventError(err)
 					}
 				}
 			}
@@ -772,17 +1300,20 @@ func (ctx *Context) TaskEventHandler() func(obj interface{}) {
 			task, err := ctx.getTask(event.GetApplicationID(), event.GetTaskID())
 			if err != nil {
 				log.Logger().Error("failed to handle application event", zap.Error(err))
+				dispatcher.RecordEventError(err)
 				return
 			}
 
 			if task.canHandle(event) {
 				if err = task.handle(event); err != nil {
-					log.Logger().Error("failed to handle task event",
-						zap.String("applicationID", task.applicationID),
-						zap.String("taskID", task.taskID),
+					task.logger().Error("failed to handle task event",
 						zap.String("event", string(event.GetEvent())),
 						zap.Error(err))
+					dispatcher.RecordEventError(err)
 				}
+			} else if allocated, ok := event.(AllocatedTaskEvent); ok && task.isTerminated() {
+				// the pod was deleted before the allocation arrived, nothing is bound for it
+				task.releaseLateAllocation(allocated.allocationUUID)
 			}
 		}
 	}
@@ -0,0 +1,156 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/appmgmt/interfaces"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+)
+
+// the size of the cache the benchmarks run against: 5k apps with 20 pods each.
+// building the cache logs every app and task, redirect the output when running the benchmarks.
+const (
+	benchApps        = 5000
+	benchPodsPerApp  = 20
+	benchWriteRatio  = 10 // one in every benchWriteRatio operations modifies the cache
+	benchSelectRatio = 100
+)
+
+func benchAppID(i int) string {
+	return fmt.Sprintf("app-%05d", i)
+}
+
+func benchPod(appID string, j int) *v1.Pod {
+	name := fmt.Sprintf("%s-pod-%02d", appID, j)
+	return &v1.Pod{
+		TypeMeta: apis.TypeMeta{
+			Kind:       "Pod",
+			APIVersion: "v1",
+		},
+		ObjectMeta: apis.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			UID:       types.UID(name),
+			Labels: map[string]string{
+				constants.LabelApplicationID: appID,
+			},
+		},
+		Spec: v1.PodSpec{SchedulerName: constants.SchedulerName},
+	}
+}
+
+func addBenchApp(ctx *Context, appID string, pods int) {
+	ctx.AddApplication(&interfaces.AddApplicationRequest{
+		Metadata: interfaces.ApplicationMetadata{
+			ApplicationID: appID,
+			QueueName:     "root.a",
+			User:          "bench-user",
+		},
+	})
+	for j := 0; j < pods; j++ {
+		pod := benchPod(appID, j)
+		ctx.AddTask(&interfaces.AddTaskRequest{
+			Metadata: interfaces.TaskMetadata{
+				ApplicationID: appID,
+				TaskID:        string(pod.UID),
+				Pod:           pod,
+			},
+		})
+	}
+}
+
+var benchContext *Context
+var benchContextOnce sync.Once
+
+// initContextForBenchmark returns a context with 5k apps and 100k pods, the context is
+// shared by all the benchmarks, the benchmarks must leave the context as they found it.
+func initContextForBenchmark(b *testing.B) *Context {
+	b.Helper()
+	benchContextOnce.Do(func() {
+		benchContext = initContextForTest()
+		for i := 0; i < benchApps; i++ {
+			addBenchApp(benchContext, benchAppID(i), benchPodsPerApp)
+		}
+	})
+	b.ResetTimer()
+	return benchContext
+}
+
+// core callbacks look up apps by ID
+func BenchmarkContextGetApplication(b *testing.B) {
+	ctx := initContextForBenchmark(b)
+	var counter int64
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			i := int(atomic.AddInt64(&counter, 1))
+			if ctx.GetApplication(benchAppID(i%benchApps)) == nil {
+				b.Fatal("app not found")
+			}
+		}
+	})
+}
+
+// informer callbacks add and remove apps while core callbacks
+// and the scheduling loop read the cache.
+func BenchmarkContextMixedWorkload(b *testing.B) {
+	ctx := initContextForBenchmark(b)
+	var counter int64
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			i := int(atomic.AddInt64(&counter, 1))
+			switch {
+			case i%benchSelectRatio == 0:
+				ctx.SelectApplications(func(app *Application) bool {
+					return app.GetApplicationState() == "Running"
+				})
+			case i%benchWriteRatio == 0:
+				appID := fmt.Sprintf("bench-new-%d", i)
+				addBenchApp(ctx, appID, 1)
+				if err := ctx.RemoveTask(appID, string(benchPod(appID, 0).UID)); err != nil {
+					b.Fatal(err)
+				}
+				if err := ctx.RemoveApplication(appID); err != nil {
+					b.Fatal(err)
+				}
+			default:
+				appID := benchAppID(i % benchApps)
+				pod := benchPod(appID, i%benchPodsPerApp)
+				if ctx.GetApplication(appID) == nil {
+					b.Fatal("app not found")
+				}
+				ctx.AddTask(&interfaces.AddTaskRequest{
+					Metadata: interfaces.TaskMetadata{
+						ApplicationID: appID,
+						TaskID:        string(pod.UID),
+						Pod:           pod,
+					},
+				})
+			}
+		}
+	})
+}
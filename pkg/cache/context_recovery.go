@@ -20,11 +20,11 @@ package cache
 
 import (
 	"fmt"
+	"sort"
 	"time"
 
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/appmgmt/interfaces"
@@ -34,9 +34,25 @@ import (
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/dispatcher"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/webservice/dao"
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
 
+// StartRecovery resets the recovery progress, the recovery must be done before the deadline
+func (ctx *Context) StartRecovery(deadline time.Time) {
+	ctx.recovery.start(deadline)
+}
+
+// FinishRecovery records the outcome of the recovery, an error fails the recovery
+func (ctx *Context) FinishRecovery(err error) {
+	ctx.recovery.finish(err)
+}
+
+// GetRecoveryProgress returns the progress of the recovery per phase, nil if the recovery did not start
+func (ctx *Context) GetRecoveryProgress() *dao.RecoveryProgress {
+	return ctx.recovery.getProgress()
+}
+
 func (ctx *Context) WaitForRecovery(recoverableAppManagers []interfaces.Recoverable, maxTimeout time.Duration) error {
 	// Currently, disable recovery when testing in a mocked cluster,
 	// because mock pod/node lister is not easy. We do have unit tests for
@@ -51,6 +67,9 @@ func (ctx *Context) WaitForRecovery(recoverableAppManagers []interfaces.Recovera
 	return nil
 }
 
+// maximum number of the objects still recovering listed when the recovery times out
+const maxRecoveryDiagnostics = 10
+
 // for a given pod, return an allocation if found
 func getExistingAllocation(recoverableAppManagers []interfaces.Recoverable, pod *corev1.Pod) *si.Allocation {
 	for _, mgr := range recoverableAppManagers {
@@ -81,21 +100,22 @@ func (ctx *Context) recover(mgr []interfaces.Recoverable, due time.Duration) err
 	for _, node := range allNodes {
 		ctx.nodes.addAndReportNode(node, false)
 	}
+	ctx.recovery.set(RecoveryPhaseNodes, len(allNodes), 0, 0)
 
 	// current, disable getting pods for a node during test,
 	// because in the tests, we don't really send existing allocations
 	// we simply simulate to accept or reject nodes on conditions.
 	if !ctx.apiProvider.IsTestingMode() {
-		var podList *corev1.PodList
-		podList, err = ctx.apiProvider.GetAPIs().KubeClient.GetClientSet().
-			CoreV1().Pods("").
-			List(metav1.ListOptions{})
+		ctx.recovery.setPhase(RecoveryPhaseTasks)
+		var pods []corev1.Pod
+		pods, err = client.ListPods(ctx.apiProvider.GetAPIs().KubeClient.GetClientSet(), "",
+			ctx.apiProvider.GetAPIs().Conf.GetKubeListPageSize())
 		if err != nil {
 			return err
 		}
 
 		nodeOccupiedResources := make(map[string]*si.Resource)
-		for _, pod := range podList.Items {
+		for _, pod := range pods {
 			// only handle assigned pods
 			if !utils.IsAssignedPod(&pod) {
 				continue
@@ -109,11 +129,15 @@ func (ctx *Context) recover(mgr []interfaces.Recoverable, due time.Duration) err
 						zap.String("podNodeName", existingAlloc.NodeID))
 					if err = ctx.nodes.addExistingAllocation(existingAlloc); err != nil {
 						log.Logger().Warn("add existing allocation failed", zap.Error(err))
+						ctx.recovery.add(RecoveryPhaseTasks, 1, 0, 1)
+					} else {
+						ctx.recovery.add(RecoveryPhaseTasks, 1, 1, 0)
 					}
 				}
-			} else if utils.IsPodRunning(&pod) {
+			} else if utils.IsPodRunning(&pod) && !ctx.adoptedPods.isAdopted(&pod) {
 				// pod is running but not scheduled by us
-				// we should report this occupied resource to scheduler-core
+				// we should report this occupied resource to scheduler-core,
+				// a pod adopted by an app is reported as an allocation of its app instead
 				occupiedResource := nodeOccupiedResources[pod.Spec.NodeName]
 				if occupiedResource == nil {
 					occupiedResource = common.NewResourceBuilder().Build()
@@ -141,10 +165,17 @@ func (ctx *Context) recover(mgr []interfaces.Recoverable, due time.Duration) err
 		}
 	}
 
+	ctx.recovery.setPhase(RecoveryPhaseNodes)
+	// the nodes added after the bulk recovery are recovered one by one below
+	if batchSize := ctx.apiProvider.GetAPIs().Conf.GetRecoveryBatchSize(); batchSize > 0 {
+		ctx.nodes.recoverInBulk(batchSize)
+	}
+	var pendingNodes []string
 	if err = utils.WaitForCondition(func() bool {
-		nodesRecovered := 0
+		nodesRecovered, nodesRejected := 0, 0
+		pendingNodes = pendingNodes[:0]
 		for _, node := range ctx.nodes.nodesMap {
-			log.Logger().Info("node state",
+			log.Logger().Debug("node state",
 				zap.String("nodeName", node.name),
 				zap.String("nodeState", node.getNodeState()))
 			switch node.getNodeState() {
@@ -153,26 +184,34 @@ func (ctx *Context) recover(mgr []interfaces.Recoverable, due time.Duration) err
 					NodeID: node.name,
 					Event:  events.RecoverNode,
 				})
+				pendingNodes = append(pendingNodes, fmt.Sprintf("%s (%s)", node.name, node.getNodeState()))
 			case events.States().Node.Healthy:
 				nodesRecovered++
 			case events.States().Node.Draining:
 				nodesRecovered++
 			case events.States().Node.Rejected:
-				nodesRecovered++
+				nodesRejected++
+			default:
+				pendingNodes = append(pendingNodes, fmt.Sprintf("%s (%s)", node.name, node.getNodeState()))
 			}
 		}
+		ctx.recovery.set(RecoveryPhaseNodes, len(allNodes), nodesRecovered, nodesRejected)
 
-		if nodesRecovered == len(allNodes) {
+		if nodesRecovered+nodesRejected == len(allNodes) {
 			log.Logger().Info("nodes recovery is successful",
-				zap.Int("recoveredNodes", nodesRecovered))
+				zap.Int("recoveredNodes", nodesRecovered),
+				zap.Int("rejectedNodes", nodesRejected))
 			return true
 		}
 		log.Logger().Info("still waiting for recovering nodes",
 			zap.Int("totalNodes", len(allNodes)),
-			zap.Int("recoveredNodes", nodesRecovered))
+			zap.Int("recoveredNodes", nodesRecovered),
+			zap.Int("rejectedNodes", nodesRejected))
 		return false
 	}, time.Second, due); err != nil {
-		return fmt.Errorf("timeout waiting for app recovery in %s", due.String())
+		sort.Strings(pendingNodes)
+		return fmt.Errorf("timeout waiting for node recovery in %s, %d of %d nodes not recovered: %s",
+			due.String(), len(pendingNodes), len(allNodes), utils.JoinWithLimit(pendingNodes, maxRecoveryDiagnostics))
 	}
 
 	return nil
@@ -35,6 +35,7 @@ import (
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/test"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/dispatcher"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/webservice/dao"
 )
 
 func TestNodeRecoveringState(t *testing.T) {
@@ -140,9 +141,17 @@ func TestNodesRecovery(t *testing.T) {
 	apiProvide4test.SetNodeLister(nodeLister)
 
 	mockedAppRecover := test.NewMockedRecoverableAppManager()
-	if err := context.recover([]interfaces.Recoverable{mockedAppRecover}, 1*time.Second); err == nil {
+	context.StartRecovery(time.Now().Add(time.Minute))
+	err := context.recover([]interfaces.Recoverable{mockedAppRecover}, 1*time.Second)
+	if err == nil {
 		t.Fatalf("expecting timeout here!")
 	}
+	// the nodes still recovering are listed
+	assert.ErrorContains(t, err, "3 of 3 nodes not recovered")
+	assert.ErrorContains(t, err, nodes[0].Name+" (Recovering)")
+	progress := context.GetRecoveryProgress()
+	assert.Equal(t, progress.Phase, RecoveryPhaseNodes)
+	assert.DeepEqual(t, progress.Phases[2], dao.RecoveryPhaseProgress{Phase: RecoveryPhaseNodes, Total: 3})
 
 	// verify all nodes were added into context
 	schedulerNodes := make([]*SchedulerNode, len(nodes))
@@ -157,7 +166,7 @@ func TestNodesRecovery(t *testing.T) {
 		Event:  events.NodeAccepted,
 	})
 	expectedStates[0] = events.States().Node.Healthy
-	err := utils.WaitForCondition(func() bool {
+	err = utils.WaitForCondition(func() bool {
 		return reflect.DeepEqual(getNodeStates(schedulerNodes), expectedStates)
 	}, 100*time.Millisecond, 3*time.Second)
 	assert.NilError(t, err, "unexpected node states, actual: %v, expected: %v", getNodeStates(schedulerNodes), expectedStates)
@@ -183,6 +192,9 @@ func TestNodesRecovery(t *testing.T) {
 	err = context.recover([]interfaces.Recoverable{mockedAppRecover}, 3*time.Second)
 	assert.NilError(t, err, "recovery should be successful, however got error")
 	assert.DeepEqual(t, getNodeStates(schedulerNodes), expectedStates)
+	// the rejected node is reported as failed
+	progress = context.GetRecoveryProgress()
+	assert.DeepEqual(t, progress.Phases[2], dao.RecoveryPhaseProgress{Phase: RecoveryPhaseNodes, Total: 3, Recovered: 2, Failed: 1})
 }
 
 func getNodeStates(schedulerNodes []*SchedulerNode) []string {
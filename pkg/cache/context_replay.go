@@ -0,0 +1,214 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"sort"
+
+	"go.uber.org/zap"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/sirequest"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+// ReplayToCore rebuilds the state of the core from the cache of the shim after the shim registered with the core
+// again, e.g. when the core restarted and lost the state of the shim. The core drops the state of a registered
+// resource manager when it registers again, so the replay is the same for a core that kept running.
+// The apps known to the core are added first, then the accepted nodes with the allocations of the tasks on them
+// and finally the asks of the tasks waiting for an allocation. The shim keeps its cache and stays running,
+// nothing is recovered from the api-server again.
+func (ctx *Context) ReplayToCore() {
+	clusterID := conf.GetSchedulerConf().ClusterID
+	newApps := make(map[string][]*si.AddApplicationRequest)
+	allocations := make(map[string][]*si.Allocation)
+	asks := make(map[string][]*si.AllocationAsk)
+	replayedApps, replayedAllocations := 0, 0
+	for _, app := range ctx.SelectApplications(nil) {
+		request, tasks := app.getReplayRequest()
+		if request == nil {
+			continue
+		}
+		rmID := app.getRmID()
+		newApps[rmID] = append(newApps[rmID], request)
+		replayedApps++
+		for _, task := range tasks {
+			if ask := task.getPendingAsk(); ask != nil {
+				asks[rmID] = append(asks[rmID], ask)
+				continue
+			}
+			// the nodes of the other clusters are reported by the shims of these clusters
+			if rmID != clusterID {
+				continue
+			}
+			if allocation := task.getReplayAllocation(request.QueueName, request.PartitionName); allocation != nil {
+				allocations[allocation.NodeID] = append(allocations[allocation.NodeID], allocation)
+			}
+		}
+	}
+
+	rmIDs := make([]string, 0, len(newApps))
+	for rmID := range newApps {
+		rmIDs = append(rmIDs, rmID)
+	}
+	sort.Strings(rmIDs)
+	for _, rmID := range rmIDs {
+		builder := sirequest.New(rmID)
+		for _, request := range newApps[rmID] {
+			builder.AddApp(request)
+		}
+		ctx.sendReplay(builder)
+	}
+
+	nodes := sirequest.New(clusterID)
+	replayedNodes := 0
+	for _, node := range ctx.nodes.getNodes() {
+		info, draining := node.getReplayInfo(allocations[node.name])
+		if info == nil {
+			continue
+		}
+		delete(allocations, node.name)
+		replayedAllocations += len(info.ExistingAllocations)
+		replayedNodes++
+		nodes.AddNode(info)
+		if draining {
+			nodes.UpdateNode(sirequest.NodeAction(node.name, si.UpdateNodeInfo_DRAIN_NODE))
+		}
+	}
+	for nodeName, lost := range allocations {
+		log.Logger().Warn("allocations on a node not known to the core are not replayed",
+			zap.String("nodeID", nodeName),
+			zap.Int("allocations", len(lost)))
+	}
+	if replayedNodes > 0 {
+		ctx.sendReplay(nodes)
+	}
+
+	replayedAsks := 0
+	for _, rmID := range rmIDs {
+		if len(asks[rmID]) == 0 {
+			continue
+		}
+		replayedAsks += len(asks[rmID])
+		builder := sirequest.New(rmID)
+		for _, ask := range asks[rmID] {
+			builder.AddAsk(ask)
+		}
+		ctx.sendReplay(builder)
+	}
+	log.Logger().Info("replayed the shim cache to the core",
+		zap.Int("applications", replayedApps),
+		zap.Int("nodes", replayedNodes),
+		zap.Int("allocations", replayedAllocations),
+		zap.Int("asks", replayedAsks))
+}
+
+func (ctx *Context) sendReplay(builder *sirequest.Builder) {
+	request, err := builder.Build()
+	if err == nil {
+		err = ctx.apiProvider.GetAPIs().SchedulerAPI.Update(request)
+	}
+	if err != nil {
+		log.Logger().Error("failed to replay the shim cache to the core",
+			zap.String("clusterID", request.RmID),
+			zap.Error(err))
+	}
+}
+
+// getReplayRequest returns the request adding the app to the core again and the tasks of the app,
+// nil is returned if the core does not know the app: it is not submitted yet or it is done.
+func (app *Application) getReplayRequest() (*si.AddApplicationRequest, []*Task) {
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	states := events.States().Application
+	switch app.sm.Current() {
+	case states.Recovering, states.Submitted, states.Accepted, states.Reserving, states.Running, states.Killing:
+	case states.Completed:
+		// a completed app is only kept in the core while a retry may reclaim its placeholders
+		if !app.lingering {
+			return nil, nil
+		}
+	default:
+		return nil, nil
+	}
+	tasks := make([]*Task, 0, len(app.taskMap))
+	for _, task := range app.taskMap {
+		tasks = append(tasks, task)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].createTime.Before(tasks[j].createTime)
+	})
+	return &si.AddApplicationRequest{
+		ApplicationID: app.applicationID,
+		QueueName:     app.queue,
+		PartitionName: app.partition,
+		Ugi: &si.UserGroupInformation{
+			User:   app.user,
+			Groups: app.groups,
+		},
+		Tags:                         app.tags,
+		ExecutionTimeoutMilliSeconds: app.placeholderTimeoutInSec * 1000,
+	}, tasks
+}
+
+// getReplayAllocation returns the allocation the core assigned to the task, nil if the task has no allocation
+func (task *Task) getReplayAllocation(queue, partition string) *si.Allocation {
+	task.lock.RLock()
+	defer task.lock.RUnlock()
+	state := task.sm.Current()
+	if state != events.States().Task.Allocated && !isBoundState(state) {
+		return nil
+	}
+	if task.allocationUUID == "" || task.nodeName == "" {
+		return nil
+	}
+	if task.queue != "" {
+		queue = task.queue
+	}
+	return &si.Allocation{
+		AllocationKey:    task.taskID,
+		UUID:             task.allocationUUID,
+		ResourcePerAlloc: task.resource,
+		QueueName:        queue,
+		NodeID:           task.nodeName,
+		ApplicationID:    task.applicationID,
+		PartitionName:    partition,
+		Placeholder:      task.placeholder,
+		TaskGroupName:    task.taskGroupName,
+	}
+}
+
+// getReplayInfo returns the node as reported to the core when the state of the core is replayed,
+// with the given allocations as its existing allocations. Nil is returned if the core did not accept the node.
+func (n *SchedulerNode) getReplayInfo(allocations []*si.Allocation) (*si.NewNodeInfo, bool) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	states := events.States().Node
+	state := n.getNodeState()
+	if state != states.Healthy && state != states.Draining {
+		return nil, false
+	}
+	info := n.newNodeInfo()
+	info.ExistingAllocations = allocations
+	n.reportedCapacity = n.capacity
+	n.reportedOccupied = n.occupied
+	return info, state == states.Draining
+}
@@ -0,0 +1,116 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"sync"
+	"testing"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+func TestReplayToCore(t *testing.T) {
+	mockedAPIProvider := client.NewMockedAPIProvider()
+	context := NewContext(mockedAPIProvider)
+	var lock sync.Mutex
+	var requests []*si.UpdateRequest
+	mockedAPIProvider.MockSchedulerApiUpdateFn(func(request *si.UpdateRequest) error {
+		lock.Lock()
+		defer lock.Unlock()
+		requests = append(requests, request)
+		return nil
+	})
+
+	for _, name := range []string{"node-1", "node-2", "node-3"} {
+		context.nodes.addAndReportNode(&v1.Node{
+			ObjectMeta: apis.ObjectMeta{
+				Name: name,
+				UID:  types.UID("uid-" + name),
+			},
+		}, false)
+	}
+	context.nodes.getNode("node-1").fsm.SetState(events.States().Node.Healthy)
+	context.nodes.getNode("node-2").fsm.SetState(events.States().Node.Draining)
+	context.nodes.getNode("node-3").fsm.SetState(events.States().Node.Rejected)
+
+	addApp := func(appID, state string) *Application {
+		app := NewApplication(appID, "root.a", "test-user", map[string]string{}, newMockSchedulerAPI())
+		app.sm.SetState(state)
+		context.applications.put(app)
+		return app
+	}
+	addTask := func(app *Application, taskID, state, nodeName string) {
+		task := NewTask(taskID, app, context, &v1.Pod{
+			ObjectMeta: apis.ObjectMeta{
+				Name: "pod-" + taskID,
+				UID:  types.UID(taskID),
+			},
+		})
+		task.sm.SetState(state)
+		if nodeName != "" {
+			task.nodeName = nodeName
+			task.allocationUUID = "uuid-" + taskID
+		}
+		app.addTask(task)
+	}
+	running := addApp("app-1", events.States().Application.Running)
+	addTask(running, "task-1", events.States().Task.Bound, "node-1")
+	addTask(running, "task-2", events.States().Task.Allocated, "node-2")
+	addTask(running, "task-3", events.States().Task.Scheduling, "")
+	addTask(running, "task-4", events.States().Task.Completed, "node-1")
+	addTask(running, "task-5", events.States().Task.Running, "node-3")
+	addApp("app-2", events.States().Application.New)
+	addApp("app-3", events.States().Application.Completed)
+
+	context.ReplayToCore()
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, len(requests), 3)
+
+	// only the apps known to the core are added
+	assert.Equal(t, len(requests[0].NewApplications), 1)
+	assert.Equal(t, requests[0].NewApplications[0].ApplicationID, "app-1")
+	assert.Equal(t, requests[0].NewApplications[0].QueueName, "root.a")
+
+	// the accepted nodes carry the allocations of the tasks on them, a draining node is drained again
+	nodes := requests[1].NewSchedulableNodes
+	assert.Equal(t, len(nodes), 2)
+	assert.Equal(t, nodes[0].NodeID, "node-1")
+	assert.Equal(t, len(nodes[0].ExistingAllocations), 1)
+	assert.Equal(t, nodes[0].ExistingAllocations[0].AllocationKey, "task-1")
+	assert.Equal(t, nodes[0].ExistingAllocations[0].UUID, "uuid-task-1")
+	assert.Equal(t, nodes[0].ExistingAllocations[0].QueueName, "root.a")
+	assert.Equal(t, nodes[1].NodeID, "node-2")
+	assert.Equal(t, len(nodes[1].ExistingAllocations), 1)
+	assert.Equal(t, nodes[1].ExistingAllocations[0].AllocationKey, "task-2")
+	assert.Equal(t, len(requests[1].UpdatedNodes), 1)
+	assert.Equal(t, requests[1].UpdatedNodes[0].NodeID, "node-2")
+	assert.Equal(t, requests[1].UpdatedNodes[0].Action, si.UpdateNodeInfo_DRAIN_NODE)
+
+	// the tasks waiting for an allocation are asked again
+	assert.Equal(t, len(requests[2].Asks), 1)
+	assert.Equal(t, requests[2].Asks[0].AllocationKey, "task-3")
+}
@@ -21,17 +21,23 @@ package cache
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"gotest.tools/assert"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 
 	"github.com/apache/incubator-yunikorn-core/pkg/common"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/apis/yunikorn.apache.org/v1alpha1"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/appmgmt/interfaces"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
@@ -41,6 +47,8 @@ import (
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/dispatcher"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/metrics"
+	plugin "github.com/apache/incubator-yunikorn-k8shim/pkg/plugin/predicates"
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
 
@@ -62,10 +70,10 @@ func TestAddApplications(t *testing.T) {
 			Tags:          nil,
 		},
 	})
-	assert.Equal(t, len(context.applications), 1)
-	assert.Assert(t, context.applications["app00001"] != nil)
-	assert.Equal(t, context.applications["app00001"].GetApplicationState(), events.States().Application.New)
-	assert.Equal(t, len(context.applications["app00001"].GetPendingTasks()), 0)
+	assert.Equal(t, context.applications.size(), 1)
+	assert.Assert(t, context.applications.get("app00001") != nil)
+	assert.Equal(t, context.applications.get("app00001").GetApplicationState(), events.States().Application.New)
+	assert.Equal(t, len(context.applications.get("app00001").GetPendingTasks()), 0)
 
 	// add an app but app already exists
 	app := context.AddApplication(&interfaces.AddApplicationRequest{
@@ -126,9 +134,9 @@ func TestRemoveApplication(t *testing.T) {
 	app1 := NewApplication(appID1, "root.a", "testuser", map[string]string{}, newMockSchedulerAPI())
 	app2 := NewApplication(appID2, "root.b", "testuser", map[string]string{}, newMockSchedulerAPI())
 	app3 := NewApplication(appID3, "root.c", "testuser", map[string]string{}, newMockSchedulerAPI())
-	context.applications[appID1] = app1
-	context.applications[appID2] = app2
-	context.applications[appID3] = app3
+	context.applications.put(app1)
+	context.applications.put(app2)
+	context.applications.put(app3)
 	pod1 := &v1.Pod{
 		TypeMeta: apis.TypeMeta{
 			Kind:       "Pod",
@@ -162,7 +170,7 @@ func TestRemoveApplication(t *testing.T) {
 
 	// remove application 1 which have non-terminated task
 	// this should fail
-	assert.Equal(t, len(context.applications), 3)
+	assert.Equal(t, context.applications.size(), 3)
 	err := context.RemoveApplication(appID1)
 	assert.Assert(t, err != nil)
 	assert.ErrorContains(t, err, "application app00001 because it still has task in non-terminated task, tasks: /remove-test-00001")
@@ -195,24 +203,24 @@ func TestRemoveApplicationInternal(t *testing.T) {
 	appID2 := "app00002"
 	app1 := NewApplication(appID1, "root.a", "testuser", map[string]string{}, newMockSchedulerAPI())
 	app2 := NewApplication(appID2, "root.b", "testuser", map[string]string{}, newMockSchedulerAPI())
-	context.applications[appID1] = app1
-	context.applications[appID2] = app2
-	assert.Equal(t, len(context.applications), 2)
+	context.applications.put(app1)
+	context.applications.put(app2)
+	assert.Equal(t, context.applications.size(), 2)
 	// remove non-exist app
 	err := context.RemoveApplicationInternal("app00003")
 	assert.Assert(t, err != nil)
-	assert.Equal(t, len(context.applications), 2)
+	assert.Equal(t, context.applications.size(), 2)
 	// remove app1
 	err = context.RemoveApplicationInternal(appID1)
 	assert.NilError(t, err)
-	assert.Equal(t, len(context.applications), 1)
-	_, ok := context.applications[appID1]
+	assert.Equal(t, context.applications.size(), 1)
+	ok := context.applications.get(appID1) != nil
 	assert.Equal(t, ok, false)
 	// remove app2
 	err = context.RemoveApplicationInternal(appID2)
 	assert.NilError(t, err)
-	assert.Equal(t, len(context.applications), 0)
-	_, ok = context.applications[appID2]
+	assert.Equal(t, context.applications.size(), 0)
+	ok = context.applications.get(appID2) != nil
 	assert.Equal(t, ok, false)
 }
 
@@ -228,10 +236,10 @@ func TestAddTask(t *testing.T) {
 			Tags:          nil,
 		},
 	})
-	assert.Equal(t, len(context.applications), 1)
-	assert.Assert(t, context.applications["app00001"] != nil)
-	assert.Equal(t, context.applications["app00001"].GetApplicationState(), events.States().Application.New)
-	assert.Equal(t, len(context.applications["app00001"].GetPendingTasks()), 0)
+	assert.Equal(t, context.applications.size(), 1)
+	assert.Assert(t, context.applications.get("app00001") != nil)
+	assert.Equal(t, context.applications.get("app00001").GetApplicationState(), events.States().Application.New)
+	assert.Equal(t, len(context.applications.get("app00001").GetPendingTasks()), 0)
 
 	// add a tasks to the existing application
 	task := context.AddTask(&interfaces.AddTaskRequest{
@@ -281,7 +289,7 @@ func TestAddTask(t *testing.T) {
 	assert.Assert(t, task == nil)
 
 	// verify number of tasks in cache
-	assert.Equal(t, len(context.applications["app00001"].GetNewTasks()), 2)
+	assert.Equal(t, len(context.applications.get("app00001").GetNewTasks()), 2)
 }
 
 func TestRecoverTask(t *testing.T) {
@@ -301,9 +309,9 @@ func TestRecoverTask(t *testing.T) {
 			Tags:          nil,
 		},
 	})
-	assert.Equal(t, len(context.applications), 1)
-	assert.Assert(t, context.applications[appID] != nil)
-	assert.Equal(t, len(context.applications[appID].GetPendingTasks()), 0)
+	assert.Equal(t, context.applications.size(), 1)
+	assert.Assert(t, context.applications.get(appID) != nil)
+	assert.Equal(t, len(context.applications.get(appID).GetPendingTasks()), 0)
 
 	// add a tasks to the existing application
 	task := context.AddTask(&interfaces.AddTaskRequest{
@@ -330,7 +338,8 @@ func TestRecoverTask(t *testing.T) {
 	assert.Equal(t, task.GetTaskState(), events.States().Task.Allocated)
 
 	// make sure the recovered task is added to the app
-	app, exist := context.applications[appID]
+	app := context.applications.get(appID)
+	exist := app != nil
 	assert.Equal(t, exist, true)
 	assert.Equal(t, len(app.GetAllocatedTasks()), 1)
 
@@ -371,9 +380,9 @@ func TestTaskReleaseAfterRecovery(t *testing.T) {
 			Tags:          nil,
 		},
 	})
-	assert.Equal(t, len(context.applications), 1)
-	assert.Assert(t, context.applications[appID] != nil)
-	assert.Equal(t, len(context.applications[appID].GetPendingTasks()), 0)
+	assert.Equal(t, context.applications.size(), 1)
+	assert.Assert(t, context.applications.get(appID) != nil)
+	assert.Equal(t, len(context.applications.get(appID).GetPendingTasks()), 0)
 
 	// add a tasks to the existing application
 	task0 := context.AddTask(&interfaces.AddTaskRequest{
@@ -425,7 +434,8 @@ func TestTaskReleaseAfterRecovery(t *testing.T) {
 	assert.Equal(t, task1.GetTaskState(), events.States().Task.Allocated)
 
 	// app should have 2 tasks recovered
-	app, exist := context.applications[appID]
+	app := context.applications.get(appID)
+	exist := app != nil
 	assert.Equal(t, exist, true)
 	assert.Equal(t, len(app.GetAllocatedTasks()), 2)
 
@@ -450,6 +460,89 @@ func TestTaskReleaseAfterRecovery(t *testing.T) {
 	assert.Equal(t, t1.GetTaskState(), events.States().Task.Completed)
 }
 
+func TestNotifyTaskPhase(t *testing.T) {
+	context := initContextForTest()
+	dispatcher.RegisterEventHandler(dispatcher.EventTypeApp, context.ApplicationEventHandler())
+	dispatcher.RegisterEventHandler(dispatcher.EventTypeTask, context.TaskEventHandler())
+	dispatcher.Start()
+	defer dispatcher.Stop()
+	mockedAPIProvider, ok := context.apiProvider.(*client.MockedAPIProvider)
+	assert.Assert(t, ok)
+	var lock sync.Mutex
+	released := make([]string, 0)
+	mockedAPIProvider.MockSchedulerApiUpdateFn(func(request *si.UpdateRequest) error {
+		lock.Lock()
+		defer lock.Unlock()
+		if request.Releases != nil {
+			for _, release := range request.Releases.AllocationsToRelease {
+				released = append(released, release.UUID)
+			}
+		}
+		return nil
+	})
+	getReleased := func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]string{}, released...)
+	}
+
+	app := NewApplication("app00001", "root.a", "bob",
+		map[string]string{}, mockedAPIProvider.GetAPIs().SchedulerAPI)
+	context.applications.put(app)
+	newTask := func(taskID, state string) *Task {
+		task := NewTask(taskID, app, context, &v1.Pod{
+			ObjectMeta: apis.ObjectMeta{Name: "pod-" + taskID, UID: types.UID(taskID)},
+		})
+		app.addTask(task)
+		task.setAllocated("node-1", "uuid-"+taskID)
+		task.sm.SetState(state)
+		return task
+	}
+	task1 := newTask("task00001", events.States().Task.Bound)
+	task2 := newTask("task00002", events.States().Task.Bound)
+	// a recovered task is not bound, it is completed when its pod terminates
+	task3 := newTask("task00003", events.States().Task.Allocated)
+	waitForState := func(task *Task, state string) {
+		err := common.WaitFor(10*time.Millisecond, 3*time.Second, func() bool {
+			return task.GetTaskState() == state
+		})
+		assert.NilError(t, err, "task %s is in state %s, expected %s", task.taskID, task.GetTaskState(), state)
+	}
+	taskMetrics := metrics.GetTaskMetrics()
+	running := taskMetrics.GetRunning()
+	succeeded := taskMetrics.GetTerminated(metrics.TaskSucceeded)
+	failed := taskMetrics.GetTerminated(metrics.TaskFailed)
+
+	context.NotifyTaskPhase(app.applicationID, task1.taskID, v1.PodRunning)
+	waitForState(task1, events.States().Task.Running)
+	assert.Equal(t, taskMetrics.GetRunning(), running+1)
+	assert.Equal(t, len(app.getBoundTasks()), 2)
+	context.NotifyTaskPhase(app.applicationID, task1.taskID, v1.PodSucceeded)
+	waitForState(task1, events.States().Task.Succeeded)
+	assert.Equal(t, taskMetrics.GetRunning(), running)
+	assert.Equal(t, taskMetrics.GetTerminated(metrics.TaskSucceeded), succeeded+1)
+	assert.DeepEqual(t, getReleased(), []string{"uuid-task00001"})
+
+	// a pod that fails without being reported running
+	context.NotifyTaskPhase(app.applicationID, task2.taskID, v1.PodFailed)
+	waitForState(task2, events.States().Task.Failed)
+	assert.Equal(t, taskMetrics.GetTerminated(metrics.TaskFailed), failed+1)
+	assert.DeepEqual(t, getReleased(), []string{"uuid-task00001", "uuid-task00002"})
+
+	context.NotifyTaskPhase(app.applicationID, task3.taskID, v1.PodRunning)
+	context.NotifyTaskPhase(app.applicationID, task3.taskID, v1.PodSucceeded)
+	waitForState(task3, events.States().Task.Completed)
+	assert.Equal(t, taskMetrics.GetTerminated(metrics.TaskSucceeded), succeeded+1)
+
+	// the pods are deleted, the tasks keep the result of their pod
+	context.NotifyTaskComplete(app.applicationID, task1.taskID)
+	context.NotifyTaskComplete(app.applicationID, task2.taskID)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, task1.GetTaskState(), events.States().Task.Succeeded)
+	assert.Equal(t, task2.GetTaskState(), events.States().Task.Failed)
+	assert.Equal(t, len(getReleased()), 3)
+}
+
 func TestRemoveTask(t *testing.T) {
 	context := initContextForTest()
 
@@ -715,6 +808,8 @@ func TestAddApplicationsWithTags(t *testing.T) {
 			Annotations: map[string]string{
 				"yunikorn.apache.org/namespace.max.memory": "256M",
 				"yunikorn.apache.org/parentqueue":          "root.test",
+				"yunikorn.apache.org/strict-fifo":          "true",
+				"yunikorn.apache.org/priority-aging":       "interval=10m",
 			},
 		},
 	}
@@ -788,6 +883,114 @@ func TestAddApplicationsWithTags(t *testing.T) {
 		t.Fatalf("parent queue tag is not updated from the namespace")
 	}
 	assert.Equal(t, parentQueue, "root.test")
+	assert.Equal(t, request.Metadata.Tags[constants.AppTagNamespaceStrictFIFO], "true")
+	assert.Equal(t, request.Metadata.Tags[constants.AppTagNamespacePriorityAging], "interval=10m")
+}
+
+func TestAddApplicationWithNamespaceMetadataTags(t *testing.T) {
+	context := initContextForTest()
+	lister, ok := context.apiProvider.GetAPIs().NamespaceInformer.Lister().(*test.MockNamespaceLister)
+	if !ok {
+		t.Fatalf("could not mock NamespaceLister")
+	}
+	lister.Add(&v1.Namespace{
+		ObjectMeta: apis.ObjectMeta{
+			Name: "team-a",
+			Labels: map[string]string{
+				"team":        "a",
+				"environment": "prod",
+				"other":       "ignored",
+			},
+			Annotations: map[string]string{
+				"cost-center": "cc-1",
+				"other":       "ignored",
+			},
+		},
+	})
+	schedulerConf := context.apiProvider.GetAPIs().Conf
+	schedulerConf.NamespaceLabelTags = "team, environment, missing"
+	schedulerConf.NamespaceAnnotationTags = "cost-center"
+
+	request := &interfaces.AddApplicationRequest{
+		Metadata: interfaces.ApplicationMetadata{
+			ApplicationID: "app00001",
+			QueueName:     "root.a",
+			User:          "test-user",
+			Tags: map[string]string{
+				constants.AppTagNamespace: "team-a",
+				// tags set on the app are kept
+				constants.AppTagNamespaceLabelPrefix + "environment": "dev",
+			},
+		},
+	}
+	context.AddApplication(request)
+	assert.DeepEqual(t, request.Metadata.Tags, map[string]string{
+		constants.AppTagNamespace:                                 "team-a",
+		constants.AppTagNamespaceLabelPrefix + "team":             "a",
+		constants.AppTagNamespaceLabelPrefix + "environment":      "dev",
+		constants.AppTagNamespaceAnnotationPrefix + "cost-center": "cc-1",
+	})
+	app := context.applications.get("app00001")
+	assert.Equal(t, app.GetTags()[constants.AppTagNamespaceLabelPrefix+"team"], "a")
+}
+
+func TestAddApplicationWithNamespaceGangDefaults(t *testing.T) {
+	context := initContextForTest()
+	lister, ok := context.apiProvider.GetAPIs().NamespaceInformer.Lister().(*test.MockNamespaceLister)
+	if !ok {
+		t.Fatalf("could not mock NamespaceLister")
+	}
+	lister.Add(&v1.Namespace{
+		ObjectMeta: apis.ObjectMeta{
+			Name: "tenant",
+			Annotations: map[string]string{
+				constants.AnnotationTaskGroups:            `[{"name": "workers", "minMember": 2, "minResource": {"cpu": "1"}}]`,
+				constants.AnnotationSchedulingPolicyParam: "placeholderTimeoutInSeconds=60 gangSchedulingStyle=Hard",
+			},
+		},
+	})
+	addApp := func(appID string, metadata interfaces.ApplicationMetadata) *Application {
+		metadata.ApplicationID = appID
+		metadata.QueueName = "root.a"
+		metadata.Tags = map[string]string{constants.AppTagNamespace: "tenant"}
+		context.AddApplication(&interfaces.AddApplicationRequest{Metadata: metadata})
+		return context.applications.get(appID)
+	}
+	addTask := func(appID, taskID string, annotations map[string]string) *Task {
+		context.AddTask(&interfaces.AddTaskRequest{
+			Metadata: interfaces.TaskMetadata{
+				ApplicationID: appID,
+				TaskID:        taskID,
+				Pod: &v1.Pod{
+					ObjectMeta: apis.ObjectMeta{
+						Name:        taskID,
+						Annotations: annotations,
+					},
+				},
+			},
+		})
+		task, err := context.getTask(appID, taskID)
+		assert.NilError(t, err)
+		return task
+	}
+
+	// the app without task groups gets the defaults of its namespace, its members join the default task group
+	app := addApp("app00001", interfaces.ApplicationMetadata{PlaceholderTimeoutInSec: 30})
+	assert.Equal(t, len(app.getTaskGroups()), 1)
+	assert.Equal(t, app.getTaskGroups()[0].Name, "workers")
+	assert.Equal(t, app.placeholderTimeoutInSec, int64(30))
+	assert.Equal(t, app.gangSchedulingStyle, constants.SchedulingPolicyStyleHard)
+	assert.Equal(t, addTask("app00001", "task00001", nil).getTaskGroupName(), "workers")
+	nonGang := addTask("app00001", "task00002", map[string]string{constants.AnnotationNonGang: "true"})
+	assert.Equal(t, nonGang.getTaskGroupName(), "")
+
+	// the task groups of the app are kept
+	app = addApp("app00002", interfaces.ApplicationMetadata{
+		TaskGroups: []v1alpha1.TaskGroup{{Name: "own", MinMember: 1}},
+	})
+	assert.Equal(t, app.getTaskGroups()[0].Name, "own")
+	assert.Equal(t, app.placeholderTimeoutInSec, int64(0))
+	assert.Equal(t, addTask("app00002", "task00003", nil).getTaskGroupName(), "")
 }
 
 func TestFindYKConfigMap(t *testing.T) {
@@ -853,3 +1056,1145 @@ func TestSaveConfigmap(t *testing.T) {
 	assert.Equal(t, false, resp.Success, "Failure is expected")
 	assert.Assert(t, strings.Contains(resp.Reason, "hot-refresh is enabled"), "Unexpected reason")
 }
+
+func TestAddApplicationGangFeasibility(t *testing.T) {
+	context := initContextForTest()
+	context.apiProvider.GetAPIs().Conf.EnableGangFeasibilityCheck = true
+	dispatcher.RegisterEventHandler(dispatcher.EventTypeApp, context.ApplicationEventHandler())
+	dispatcher.Start()
+	defer dispatcher.Stop()
+
+	// no nodes in the cluster yet, no decision can be made
+	tooLargeGroup := v1alpha1.TaskGroup{
+		Name:      "test-group-1",
+		MinMember: 1,
+		MinResource: map[string]resource.Quantity{
+			v1.ResourceCPU.String(): resource.MustParse("8"),
+		},
+	}
+	context.AddApplication(&interfaces.AddApplicationRequest{
+		Metadata: interfaces.ApplicationMetadata{
+			ApplicationID: "app00001",
+			QueueName:     "root.a",
+			User:          "test-user",
+			TaskGroups:    []v1alpha1.TaskGroup{tooLargeGroup},
+		},
+	})
+	assert.Equal(t, context.applications.get("app00001").gangInfeasibleReason, "")
+
+	for _, name := range []string{"host0001", "host0002"} {
+		context.addNode(&v1.Node{
+			ObjectMeta: apis.ObjectMeta{
+				Name: name,
+				UID:  types.UID("uid_" + name),
+			},
+			Status: v1.NodeStatus{
+				Allocatable: v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse("4"),
+					v1.ResourceMemory: resource.MustParse("8Gi"),
+				},
+			},
+		})
+	}
+
+	// the member of the task group does not fit in any node
+	context.AddApplication(&interfaces.AddApplicationRequest{
+		Metadata: interfaces.ApplicationMetadata{
+			ApplicationID: "app00002",
+			QueueName:     "root.a",
+			User:          "test-user",
+			TaskGroups:    []v1alpha1.TaskGroup{tooLargeGroup},
+		},
+	})
+	app := context.applications.get("app00002")
+	assert.Assert(t, strings.Contains(app.gangInfeasibleReason, "does not fit in any node"))
+	assert.Assert(t, strings.Contains(app.gangInfeasibleReason,
+		"vcore 8000 is requested and the largest node host0001 has 4000 allocatable"), app.gangInfeasibleReason)
+
+	// every member fits in a node, but the gang exceeds the cluster capacity
+	context.AddApplication(&interfaces.AddApplicationRequest{
+		Metadata: interfaces.ApplicationMetadata{
+			ApplicationID: "app00003",
+			QueueName:     "root.a",
+			User:          "test-user",
+			TaskGroups: []v1alpha1.TaskGroup{
+				{
+					Name:      "test-group-2",
+					MinMember: 3,
+					MinResource: map[string]resource.Quantity{
+						v1.ResourceCPU.String(): resource.MustParse("3"),
+					},
+				},
+			},
+		},
+	})
+	assert.Assert(t, strings.Contains(context.applications.get("app00003").gangInfeasibleReason,
+		"exceeds the cluster capacity"))
+
+	// the gang fits in the cluster
+	context.AddApplication(&interfaces.AddApplicationRequest{
+		Metadata: interfaces.ApplicationMetadata{
+			ApplicationID: "app00004",
+			QueueName:     "root.a",
+			User:          "test-user",
+			TaskGroups: []v1alpha1.TaskGroup{
+				{
+					Name:      "test-group-3",
+					MinMember: 2,
+					MinResource: map[string]resource.Quantity{
+						v1.ResourceCPU.String(): resource.MustParse("4"),
+					},
+				},
+			},
+		},
+	})
+	assert.Equal(t, context.applications.get("app00004").gangInfeasibleReason, "")
+
+	// the placeholders cannot be spread over enough nodes
+	context.AddApplication(&interfaces.AddApplicationRequest{
+		Metadata: interfaces.ApplicationMetadata{
+			ApplicationID: "app00005",
+			QueueName:     "root.a",
+			User:          "test-user",
+			TaskGroups: []v1alpha1.TaskGroup{
+				{
+					Name:       "test-group-4",
+					MinMember:  3,
+					MaxPerNode: 1,
+					MinResource: map[string]resource.Quantity{
+						v1.ResourceCPU.String(): resource.MustParse("1"),
+					},
+				},
+			},
+		},
+	})
+	assert.Assert(t, strings.Contains(context.applications.get("app00005").gangInfeasibleReason,
+		"needs 3 nodes with at most 1 members per node, the cluster has 2 nodes"))
+
+	// an infeasible app fails immediately on submission
+	err := app.handle(NewSubmitApplicationEvent(app.applicationID))
+	assert.NilError(t, err)
+	assertAppState(t, app, events.States().Application.Failed, 3*time.Second)
+}
+
+func TestAddApplicationTaskGroupMemberTooLarge(t *testing.T) {
+	context := initContextForTest()
+	context.addNode(&v1.Node{
+		ObjectMeta: apis.ObjectMeta{
+			Name: "host0001",
+			UID:  "uid_host0001",
+		},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("4"),
+				v1.ResourceMemory: resource.MustParse("8Gi"),
+			},
+		},
+	})
+	recorder := record.NewFakeRecorder(100)
+	events.SetRecorderForTest(recorder)
+	defer events.SetRecorderForTest(events.NewMockedRecorder())
+
+	addApp := func(appID, cpu string) *Application {
+		context.AddApplication(&interfaces.AddApplicationRequest{
+			Metadata: interfaces.ApplicationMetadata{
+				ApplicationID: appID,
+				QueueName:     "root.a",
+				User:          "test-user",
+				Tags:          map[string]string{constants.AppTagNamespace: "default"},
+				TaskGroups: []v1alpha1.TaskGroup{
+					{
+						Name:      "test-group",
+						MinMember: 2,
+						MinResource: map[string]resource.Quantity{
+							v1.ResourceCPU.String(): resource.MustParse(cpu),
+						},
+					},
+				},
+				OwnerReferences: []apis.OwnerReference{
+					{APIVersion: "batch/v1", Kind: "Job", Name: "job-" + appID, UID: "uid-" + types.UID(appID)},
+				},
+			},
+		})
+		return context.applications.get(appID)
+	}
+
+	// without the gang feasibility check the app is only flagged
+	app := addApp("app00001", "8")
+	assert.Equal(t, app.gangInfeasibleReason, "")
+	select {
+	case event := <-recorder.Events:
+		assert.Equal(t, event, "Warning TaskGroupMemberTooLarge Application app00001: member of task group "+
+			"test-group requires resources:<key:\"vcore\" value:<value:8000 > > , which does not fit in any node, "+
+			"vcore 8000 is requested and the largest node host0001 has 4000 allocatable")
+	default:
+		t.Fatal("the app is not flagged")
+	}
+
+	addApp("app00002", "2")
+	assert.Equal(t, len(recorder.Events), 0)
+}
+
+func TestPublishGangMembersEvent(t *testing.T) {
+	context := initContextForTest()
+	recorded := 0
+	mr := events.NewMockedRecorder()
+	mr.OnEventf = func() {
+		recorded++
+	}
+	events.SetRecorderForTest(mr)
+	defer events.SetRecorderForTest(record.NewFakeRecorder(1024))
+
+	pod := &v1.Pod{
+		ObjectMeta: apis.ObjectMeta{
+			Name: "pod-test-00001",
+			UID:  "UID-00001",
+		},
+	}
+	app := NewApplication("app00001", "root.a", "test-user", map[string]string{}, newMockSchedulerAPI())
+	placeholder := NewFromTaskMeta("ph-01", app, context, interfaces.TaskMetadata{
+		ApplicationID: app.applicationID,
+		TaskID:        "ph-01",
+		Pod:           pod,
+		Placeholder:   true,
+		TaskGroupName: "test-group-1",
+	})
+	member := NewFromTaskMeta("task-01", app, context, interfaces.TaskMetadata{
+		ApplicationID: app.applicationID,
+		TaskID:        "task-01",
+		Pod:           pod,
+		TaskGroupName: "test-group-1",
+	})
+	otherMember := NewFromTaskMeta("task-02", app, context, interfaces.TaskMetadata{
+		ApplicationID: app.applicationID,
+		TaskID:        "task-02",
+		Pod:           pod,
+		TaskGroupName: "test-group-2",
+	})
+	placeholder.sm.SetState(events.States().Task.Scheduling)
+	member.sm.SetState(events.States().Task.Pending)
+	otherMember.sm.SetState(events.States().Task.Pending)
+	app.addTask(placeholder)
+	app.addTask(member)
+	app.addTask(otherMember)
+
+	// only the pending member of the same task group is notified
+	context.publishGangMembersEvent(placeholder)
+	assert.Equal(t, recorded, 1)
+
+	// the task group is only notified once
+	context.publishGangMembersEvent(placeholder)
+	assert.Equal(t, recorded, 1)
+}
+
+func TestUpdatePredicatesFromConfigMap(t *testing.T) {
+	context := initContextForTest()
+	assert.Equal(t, len(context.predictor.GetEnabledPredicates()), 0)
+
+	configMap := &v1.ConfigMap{
+		ObjectMeta: apis.ObjectMeta{
+			Name: constants.DefaultConfigMapName,
+		},
+		Data: map[string]string{
+			plugin.ConfigKeyEnabledPredicates: "HostName,PodToleratesNodeTaints",
+		},
+	}
+	context.addConfigMaps(configMap)
+	assert.DeepEqual(t, context.predictor.GetEnabledPredicates(), []string{"HostName", "PodToleratesNodeTaints"})
+
+	// updates are only applied when the hot-refresh is enabled
+	newConfigMap := configMap.DeepCopy()
+	newConfigMap.Data[plugin.ConfigKeyDisabledPredicates] = "PodToleratesNodeTaints"
+	context.updateConfigMaps(configMap, newConfigMap)
+	assert.DeepEqual(t, context.predictor.GetEnabledPredicates(), []string{"HostName", "PodToleratesNodeTaints"})
+	context.apiProvider.GetAPIs().Conf.EnableConfigHotRefresh = true
+	context.updateConfigMaps(configMap, newConfigMap)
+	assert.DeepEqual(t, context.predictor.GetEnabledPredicates(), []string{"HostName"})
+
+	// an invalid configuration keeps the current predicates
+	invalidConfigMap := newConfigMap.DeepCopy()
+	invalidConfigMap.Data[plugin.ConfigKeyDisabledPredicates] = "xxx"
+	context.updateConfigMaps(newConfigMap, invalidConfigMap)
+	assert.DeepEqual(t, context.predictor.GetEnabledPredicates(), []string{"HostName"})
+}
+
+func TestResumeApplication(t *testing.T) {
+	context := initContextForTest()
+	NewPlaceholderManager(context.apiProvider.GetAPIs())
+	dispatcher.RegisterEventHandler(dispatcher.EventTypeApp, context.ApplicationEventHandler())
+	dispatcher.RegisterEventHandler(dispatcher.EventTypeTask, context.TaskEventHandler())
+	dispatcher.Start()
+	defer dispatcher.Stop()
+
+	// record the requests sent to the core in order
+	var lock sync.Mutex
+	requests := make([]string, 0)
+	record := func(request *si.UpdateRequest) error {
+		lock.Lock()
+		defer lock.Unlock()
+		switch {
+		case request.Releases != nil:
+			requests = append(requests, "release")
+		case len(request.RemoveApplications) > 0:
+			requests = append(requests, "remove")
+		case len(request.NewApplications) > 0:
+			requests = append(requests, "submit")
+		}
+		return nil
+	}
+	context.apiProvider.(*client.MockedAPIProvider).MockSchedulerApiUpdateFn(record)
+	mockedSchedulerAPI := newMockSchedulerAPI()
+	mockedSchedulerAPI.updateFn = record
+
+	err := context.ResumeApplication("app00001")
+	assert.ErrorContains(t, err, "not found")
+
+	app := NewApplication("app00001", "root.a", "test-user", map[string]string{}, mockedSchedulerAPI)
+	context.applications.put(app)
+	app.SetState(events.States().Application.Running)
+	err = context.ResumeApplication(app.applicationID)
+	assert.ErrorContains(t, err, "cannot be resumed in state Running")
+
+	newTask := func(taskID, state string) *Task {
+		pod := &v1.Pod{
+			ObjectMeta: apis.ObjectMeta{
+				Name: taskID,
+				UID:  types.UID(taskID),
+			},
+		}
+		task := NewTask(taskID, app, context, pod)
+		task.sm.SetState(state)
+		app.addTask(task)
+		return task
+	}
+	scheduling := newTask("task00001", events.States().Task.Scheduling)
+	pending := newTask("task00002", events.States().Task.Pending)
+	bound := newTask("task00003", events.States().Task.Bound)
+
+	// an app with allocated tasks cannot be resumed
+	app.SetState(events.States().Application.Failed)
+	err = context.ResumeApplication(app.applicationID)
+	assert.ErrorContains(t, err, "it has 1 allocated tasks")
+
+	// the unallocated tasks are reset, the app is removed from the core and resubmitted
+	bound.sm.SetState(events.States().Task.Completed)
+	err = context.ResumeApplication(app.applicationID)
+	assert.NilError(t, err)
+	assertAppState(t, app, events.States().Application.Submitted, 3*time.Second)
+	err = utils.WaitForCondition(func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(requests) == 4
+	}, 10*time.Millisecond, 3*time.Second)
+	assert.NilError(t, err, "app is not resubmitted to the core")
+	assert.DeepEqual(t, requests, []string{"release", "release", "remove", "submit"})
+	assert.Equal(t, scheduling.GetTaskState(), events.States().Task.New)
+	assert.Equal(t, pending.GetTaskState(), events.States().Task.New)
+}
+
+func TestKillApplication(t *testing.T) {
+	context := initContextForTest()
+	NewPlaceholderManager(context.apiProvider.GetAPIs())
+	dispatcher.RegisterEventHandler(dispatcher.EventTypeApp, context.ApplicationEventHandler())
+	dispatcher.RegisterEventHandler(dispatcher.EventTypeTask, context.TaskEventHandler())
+	dispatcher.Start()
+	defer dispatcher.Stop()
+
+	var lock sync.Mutex
+	deleted := make([]string, 0)
+	terminate := true
+	context.apiProvider.(*client.MockedAPIProvider).MockDeleteFn(func(pod *v1.Pod) error {
+		lock.Lock()
+		defer lock.Unlock()
+		deleted = append(deleted, pod.Name)
+		// the pod is gone once its grace period is over
+		if terminate {
+			context.NotifyTaskComplete(pod.Labels[constants.LabelApplicationID], string(pod.UID))
+		}
+		return nil
+	})
+	removed := false
+	mockedSchedulerAPI := newMockSchedulerAPI()
+	mockedSchedulerAPI.updateFn = func(request *si.UpdateRequest) error {
+		if len(request.RemoveApplications) > 0 {
+			lock.Lock()
+			defer lock.Unlock()
+			removed = true
+		}
+		return nil
+	}
+
+	err := context.KillApplication("app00001")
+	assert.ErrorContains(t, err, "not found")
+
+	newApp := func(appID string) *Application {
+		app := NewApplication(appID, "root.a", "test-user", map[string]string{}, mockedSchedulerAPI)
+		context.applications.put(app)
+		return app
+	}
+	newTask := func(app *Application, taskID, state string, placeholder bool) *Task {
+		pod := &v1.Pod{
+			ObjectMeta: apis.ObjectMeta{
+				Name:   taskID,
+				UID:    types.UID(taskID),
+				Labels: map[string]string{constants.LabelApplicationID: app.applicationID},
+			},
+		}
+		task := NewTask(taskID, app, context, pod)
+		task.placeholder = placeholder
+		task.sm.SetState(state)
+		app.addTask(task)
+		return task
+	}
+	app := newApp("app00001")
+	err = context.KillApplication(app.applicationID)
+	assert.ErrorContains(t, err, "cannot be killed in state New")
+
+	newTask(app, "task00001", events.States().Task.Bound, false).setAllocationUUID("alloc00001")
+	newTask(app, "task00002", events.States().Task.Pending, false)
+	newTask(app, "task00003", events.States().Task.Completed, false)
+	newTask(app, "placeholder", events.States().Task.Bound, true)
+
+	// the app is removed from the core and its pods are deleted,
+	// the app is killed once the core released its allocations
+	app.SetState(events.States().Application.Running)
+	err = context.KillApplication(app.applicationID)
+	assert.NilError(t, err)
+	err = utils.WaitForCondition(func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(deleted) == 3
+	}, 10*time.Millisecond, 3*time.Second)
+	assert.NilError(t, err, "pods of the killed app are not deleted")
+	sort.Strings(deleted)
+	assert.DeepEqual(t, deleted, []string{"placeholder", "task00001", "task00002"})
+	lock.Lock()
+	assert.Assert(t, removed, "app is not removed from the core")
+	lock.Unlock()
+	assertAppState(t, app, events.States().Application.Killing, 300*time.Millisecond)
+	context.NotifyAllocationReleased(app.applicationID, "alloc00001")
+	assertAppState(t, app, events.States().Application.Killed, 3*time.Second)
+
+	// the app is killed once the timeout is passed, even if its pods are not terminated
+	killTimeout := conf.GetSchedulerConf().KillTimeout
+	conf.GetSchedulerConf().KillTimeout = 300 * time.Millisecond
+	defer func() {
+		conf.GetSchedulerConf().KillTimeout = killTimeout
+	}()
+	lock.Lock()
+	terminate = false
+	lock.Unlock()
+	app = newApp("app00002")
+	newTask(app, "task00004", events.States().Task.Running, false)
+	app.SetState(events.States().Application.Running)
+	err = context.KillApplication(app.applicationID)
+	assert.NilError(t, err)
+	assertAppState(t, app, events.States().Application.Killed, 3*time.Second)
+	pods, allocations := app.getKillProgress().getPending()
+	assert.Equal(t, pods, 1)
+	assert.Equal(t, allocations, 0)
+}
+
+func TestHandleApplicationStateUpdate(t *testing.T) {
+	context := initContextForTest()
+	NewPlaceholderManager(context.apiProvider.GetAPIs())
+	dispatcher.RegisterEventHandler(dispatcher.EventTypeApp, context.ApplicationEventHandler())
+	// the status changes are only consumed by the application CRD controller
+	dispatcher.RegisterEventHandler(dispatcher.EventTypeAppStatus, func(obj interface{}) {})
+	dispatcher.Start()
+	defer dispatcher.Stop()
+
+	var lock sync.Mutex
+	deleted := make([]string, 0)
+	context.apiProvider.(*client.MockedAPIProvider).MockDeleteFn(func(pod *v1.Pod) error {
+		lock.Lock()
+		defer lock.Unlock()
+		deleted = append(deleted, pod.Name)
+		return nil
+	})
+
+	newApp := func(appID, state string) *Application {
+		app := NewApplication(appID, "root.a", "test-user", map[string]string{}, newMockSchedulerAPI())
+		placeholder := NewTask("placeholder-"+appID, app, context, &v1.Pod{
+			ObjectMeta: apis.ObjectMeta{
+				Name: "placeholder-" + appID,
+				UID:  types.UID("placeholder-" + appID),
+			},
+		})
+		placeholder.placeholder = true
+		app.addTask(placeholder)
+		app.SetState(state)
+		app.context = context
+		context.applications.put(app)
+		return app
+	}
+	assertRemoved := func(app *Application) {
+		err := utils.WaitForCondition(func() bool {
+			return context.GetApplication(app.applicationID) == nil
+		}, 10*time.Millisecond, 3*time.Second)
+		assert.NilError(t, err, "application %s is not removed", app.applicationID)
+	}
+
+	// unknown apps are ignored
+	context.HandleApplicationStateUpdate(&si.UpdatedApplication{ApplicationID: "unknown", State: "Completed"})
+
+	// a running app completed by the core is completed and removed
+	running := newApp("app00001", events.States().Application.Running)
+	context.HandleApplicationStateUpdate(&si.UpdatedApplication{ApplicationID: running.applicationID, State: "Completed"})
+	assertAppState(t, running, events.States().Application.Completed, 3*time.Second)
+	assertRemoved(running)
+
+	// the placeholders of a reserving app completed by the core are cleaned up
+	reserving := newApp("app00002", events.States().Application.Reserving)
+	context.HandleApplicationStateUpdate(&si.UpdatedApplication{ApplicationID: reserving.applicationID, State: "Expired"})
+	assertRemoved(reserving)
+	err := utils.WaitForCondition(func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(deleted) == 2
+	}, 10*time.Millisecond, 3*time.Second)
+	assert.NilError(t, err, "placeholders are not cleaned up")
+
+	// an app failed by the core is failed in the shim
+	failing := newApp("app00003", events.States().Application.Reserving)
+	context.HandleApplicationStateUpdate(&si.UpdatedApplication{ApplicationID: failing.applicationID, State: "Failing",
+		Message: "ResourceReservationTimeout"})
+	assertAppState(t, failing, events.States().Application.Failed, 3*time.Second)
+	assert.Assert(t, context.GetApplication(failing.applicationID) != nil)
+
+	// an app rejected by the core is rejected in the shim
+	rejected := newApp("app00004", events.States().Application.Submitted)
+	context.HandleApplicationStateUpdate(&si.UpdatedApplication{ApplicationID: rejected.applicationID, State: "Rejected"})
+	assertAppState(t, rejected, events.States().Application.Failed, 3*time.Second)
+
+	// other states do not change the state of the app
+	accepted := newApp("app00005", events.States().Application.Accepted)
+	context.HandleApplicationStateUpdate(&si.UpdatedApplication{ApplicationID: accepted.applicationID, State: "Starting"})
+	assertAppState(t, accepted, events.States().Application.Accepted, time.Second)
+}
+
+func TestDeleteNamespace(t *testing.T) {
+	context := initContextForTest()
+	NewPlaceholderManager(context.apiProvider.GetAPIs())
+	dispatcher.RegisterEventHandler(dispatcher.EventTypeApp, context.ApplicationEventHandler())
+	dispatcher.Start()
+	defer dispatcher.Stop()
+
+	newApp := func(appID, namespace string) *Application {
+		app := NewApplication(appID, "root.a", "test-user",
+			map[string]string{constants.AppTagNamespace: namespace}, newMockSchedulerAPI())
+		context.applications.put(app)
+		return app
+	}
+	runningApp := newApp("app00001", "ns1")
+	runningApp.SetState(events.States().Application.Running)
+	removed := make(chan string, 1)
+	removingSchedulerAPI := newMockSchedulerAPI()
+	removingSchedulerAPI.updateFn = func(request *si.UpdateRequest) error {
+		for _, remove := range request.RemoveApplications {
+			removed <- remove.ApplicationID
+		}
+		return nil
+	}
+	runningApp.schedulerAPI = removingSchedulerAPI
+	task := NewTask("task00001", runningApp, context, &v1.Pod{
+		ObjectMeta: apis.ObjectMeta{Name: "pod00001", UID: "UID-00001"},
+	})
+	task.sm.SetState(events.States().Task.Bound)
+	task.setAllocationUUID("alloc-00001")
+	runningApp.addTask(task)
+	reservingApp := newApp("app00002", "ns1")
+	reservingApp.SetState(events.States().Application.Reserving)
+	submittedApp := newApp("app00003", "ns1")
+	submittedApp.SetState(events.States().Application.Submitted)
+	otherApp := newApp("app00004", "ns2")
+	otherApp.SetState(events.States().Application.Running)
+
+	context.deleteNamespace(&v1.Namespace{
+		ObjectMeta: apis.ObjectMeta{
+			Name: "ns1",
+		},
+	})
+	// the app is removed from the core and waits for the core to release its allocations
+	select {
+	case appID := <-removed:
+		assert.Equal(t, appID, runningApp.applicationID)
+	case <-time.After(3 * time.Second):
+		t.Fatal("the app of the deleted namespace is not removed from the core")
+	}
+	assertAppState(t, runningApp, events.States().Application.Killing, 300*time.Millisecond)
+	context.NotifyAllocationReleased(runningApp.applicationID, "alloc-00001")
+	assertAppState(t, runningApp, events.States().Application.Killed, 3*time.Second)
+	assertAppState(t, reservingApp, events.States().Application.Killed, 3*time.Second)
+	assertAppState(t, submittedApp, events.States().Application.Failed, 3*time.Second)
+	assert.Equal(t, otherApp.GetApplicationState(), events.States().Application.Running)
+
+	// a tombstone of the deleted namespace is also handled
+	tombstoneApp := newApp("app00005", "ns3")
+	tombstoneApp.SetState(events.States().Application.Accepted)
+	context.deleteNamespace(cache.DeletedFinalStateUnknown{
+		Key: "ns3",
+		Obj: &v1.Namespace{
+			ObjectMeta: apis.ObjectMeta{
+				Name: "ns3",
+			},
+		},
+	})
+	assertAppState(t, tombstoneApp, events.States().Application.Killed, 3*time.Second)
+}
+
+func TestSchedulingGates(t *testing.T) {
+	context := initContextForTest()
+	var ungated int32
+	handler := context.TaskEventHandler()
+	dispatcher.RegisterEventHandler(dispatcher.EventTypeTask, func(obj interface{}) {
+		if event, ok := obj.(events.TaskEvent); ok && event.GetEvent() == events.UngateTask {
+			atomic.AddInt32(&ungated, 1)
+		}
+		handler(obj)
+	})
+	dispatcher.Start()
+	defer dispatcher.Stop()
+
+	app := NewApplication("app00001", "root.a", "test-user", map[string]string{}, newMockSchedulerAPI())
+	context.applications.put(app)
+	pod := &v1.Pod{
+		ObjectMeta: apis.ObjectMeta{
+			Name: "pod-test-00001",
+			UID:  "UID-00001",
+			Labels: map[string]string{
+				constants.LabelApplicationID: app.applicationID,
+			},
+			Annotations: map[string]string{
+				constants.AnnotationSchedulingGates: "example.com/gate," + constants.SchedulingGateQueueAdmission,
+			},
+		},
+	}
+	task := NewTask(string(pod.UID), app, context, pod)
+	app.addTask(task)
+
+	// the queue admission gate is removed, the task is held by the remaining gate
+	err := task.initTask()
+	assert.NilError(t, err)
+	assert.Equal(t, task.GetTaskState(), events.States().Task.Gated)
+
+	// removing one of the gates does not release the task
+	ungatedPod := pod.DeepCopy()
+	ungatedPod.Annotations[constants.AnnotationSchedulingGates] = "example.com/other-gate"
+	context.updatePodInCache(pod, ungatedPod)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, task.GetTaskState(), events.States().Task.Gated)
+
+	// the task is released once all the gates are removed
+	oldPod := ungatedPod.DeepCopy()
+	delete(ungatedPod.Annotations, constants.AnnotationSchedulingGates)
+	context.updatePodInCache(oldPod, ungatedPod)
+	err = utils.WaitForCondition(func() bool {
+		return task.GetTaskState() == events.States().Task.Scheduling
+	}, 10*time.Millisecond, time.Second)
+	assert.NilError(t, err, "task is not released after the scheduling gates are removed")
+	assert.Equal(t, atomic.LoadInt32(&ungated), int32(1))
+
+	// removing the queue admission gate itself does not ungate the task again
+	task3 := NewTask("UID-00003", app, context, &v1.Pod{
+		ObjectMeta: apis.ObjectMeta{
+			Name: "pod-test-00003",
+			UID:  "UID-00003",
+			Labels: map[string]string{
+				constants.LabelApplicationID: app.applicationID,
+			},
+			Annotations: map[string]string{
+				constants.AnnotationSchedulingGates: constants.SchedulingGateQueueAdmission,
+			},
+		},
+	})
+	app.addTask(task3)
+	err = task3.initTask()
+	assert.NilError(t, err)
+	assert.Assert(t, task3.GetTaskState() != events.States().Task.Gated)
+	ungatedPod = task3.GetTaskPod().DeepCopy()
+	delete(ungatedPod.Annotations, constants.AnnotationSchedulingGates)
+	context.updatePodInCache(task3.GetTaskPod(), ungatedPod)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, atomic.LoadInt32(&ungated), int32(1))
+
+	// a pod without gates is not gated
+	task2 := NewTask("UID-00002", app, context, &v1.Pod{
+		ObjectMeta: apis.ObjectMeta{
+			Name: "pod-test-00002",
+			UID:  "UID-00002",
+		},
+	})
+	app.addTask(task2)
+	err = task2.initTask()
+	assert.NilError(t, err)
+	assert.Assert(t, task2.GetTaskState() != events.States().Task.Gated)
+}
+
+func TestGetResourceUsage(t *testing.T) {
+	context := initContextForTest()
+	newPod := func(uid, cpu string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: apis.ObjectMeta{
+				Name: "pod-" + uid,
+				UID:  types.UID(uid),
+			},
+			Spec: v1.PodSpec{
+				Containers: []v1.Container{
+					{
+						Resources: v1.ResourceRequirements{
+							Requests: v1.ResourceList{
+								v1.ResourceCPU: resource.MustParse(cpu),
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	app1 := NewApplication("app00001", "root.a", "alice", map[string]string{}, newMockSchedulerAPI())
+	app2 := NewApplication("app00002", "root.a", "bob", map[string]string{}, newMockSchedulerAPI())
+	app3 := NewApplication("app00003", "root.b", "bob", map[string]string{}, newMockSchedulerAPI())
+	for _, app := range []*Application{app1, app2, app3} {
+		context.applications.put(app)
+	}
+	addTask := func(app *Application, uid, cpu, state string) {
+		task := NewTask(uid, app, context, newPod(uid, cpu))
+		task.sm.SetState(state)
+		app.addTask(task)
+	}
+	addTask(app1, "uid-1", "1", events.States().Task.Bound)
+	addTask(app1, "uid-2", "2", events.States().Task.Bound)
+	addTask(app2, "uid-3", "4", events.States().Task.Bound)
+	addTask(app3, "uid-4", "8", events.States().Task.Bound)
+	// only the bound tasks are counted
+	addTask(app3, "uid-5", "16", events.States().Task.Scheduling)
+	addTask(app3, "uid-6", "32", events.States().Task.Completed)
+
+	usage := context.GetResourceUsage()
+	assert.Equal(t, len(usage.Queues), 2)
+	assert.Equal(t, usage.Queues[0].QueueName, "root.a")
+	assert.Equal(t, usage.Queues[0].Tasks, 3)
+	assert.Equal(t, usage.Queues[0].Allocated[constants.CPU], int64(7000))
+	assert.Equal(t, usage.Queues[1].QueueName, "root.b")
+	assert.Equal(t, usage.Queues[1].Tasks, 1)
+	assert.Equal(t, usage.Queues[1].Allocated[constants.CPU], int64(8000))
+
+	assert.Equal(t, len(usage.Users), 2)
+	assert.Equal(t, usage.Users[0].User, "alice")
+	assert.Equal(t, usage.Users[0].Allocated[constants.CPU], int64(3000))
+	assert.Equal(t, usage.Users[1].User, "bob")
+	assert.Equal(t, usage.Users[1].Tasks, 2)
+	assert.Equal(t, usage.Users[1].Allocated[constants.CPU], int64(12000))
+}
+
+func TestGetPendingResources(t *testing.T) {
+	context := initContextForTest()
+	newPod := func(uid, cpu string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: apis.ObjectMeta{
+				Name: "pod-" + uid,
+				UID:  types.UID(uid),
+			},
+			Spec: v1.PodSpec{
+				Containers: []v1.Container{
+					{
+						Resources: v1.ResourceRequirements{
+							Requests: v1.ResourceList{
+								v1.ResourceCPU: resource.MustParse(cpu),
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	app1 := NewApplication("app00001", "root.a", "alice", map[string]string{}, newMockSchedulerAPI())
+	app2 := NewApplication("app00002", "root.b", "bob", map[string]string{}, newMockSchedulerAPI())
+	context.applications.put(app1)
+	context.applications.put(app2)
+	addTask := func(app *Application, uid, cpu, state, taskGroup string) {
+		task := NewTask(uid, app, context, newPod(uid, cpu))
+		task.taskGroupName = taskGroup
+		task.sm.SetState(state)
+		app.addTask(task)
+	}
+	addTask(app1, "uid-1", "1", events.States().Task.New, "")
+	addTask(app1, "uid-2", "2", events.States().Task.Pending, "tg-1")
+	addTask(app1, "uid-3", "4", events.States().Task.Scheduling, "tg-1")
+	addTask(app1, "uid-4", "8", events.States().Task.Scheduling, "tg-2")
+	// allocated and bound tasks are not pending
+	addTask(app1, "uid-5", "16", events.States().Task.Allocated, "tg-2")
+	addTask(app2, "uid-6", "32", events.States().Task.Bound, "")
+
+	pending := app1.GetPendingResource()
+	assert.Equal(t, pending.ApplicationID, "app00001")
+	assert.Equal(t, pending.Queue, "root.a")
+	assert.Equal(t, pending.Tasks, 4)
+	assert.Equal(t, pending.Pending[constants.CPU], int64(15000))
+	assert.Equal(t, len(pending.States), 3)
+	assert.Equal(t, pending.States[0].State, events.States().Task.New)
+	assert.Equal(t, pending.States[0].Pending[constants.CPU], int64(1000))
+	assert.Equal(t, pending.States[1].State, events.States().Task.Pending)
+	assert.Equal(t, pending.States[1].Pending[constants.CPU], int64(2000))
+	assert.Equal(t, pending.States[2].State, events.States().Task.Scheduling)
+	assert.Equal(t, pending.States[2].Tasks, 2)
+	assert.Equal(t, pending.States[2].Pending[constants.CPU], int64(12000))
+	assert.Equal(t, len(pending.TaskGroups), 2)
+	assert.Equal(t, pending.TaskGroups[0].TaskGroup, "tg-1")
+	assert.Equal(t, pending.TaskGroups[0].Pending[constants.CPU], int64(6000))
+	assert.Equal(t, pending.TaskGroups[1].TaskGroup, "tg-2")
+	assert.Equal(t, pending.TaskGroups[1].Tasks, 1)
+
+	// only the apps with pending tasks are listed
+	all := context.GetPendingResources()
+	assert.Equal(t, len(all), 1)
+	assert.Equal(t, all[0].ApplicationID, "app00001")
+
+	_, err := context.GetApplicationPendingResource("app00003")
+	assert.ErrorContains(t, err, "not found")
+	pending, err = context.GetApplicationPendingResource("app00002")
+	assert.NilError(t, err)
+	assert.Equal(t, pending.Tasks, 0)
+	assert.Equal(t, len(pending.States), 0)
+}
+
+func TestGetApplicationsByQueueAndUser(t *testing.T) {
+	context := initContextForTest()
+	newPod := func(uid, cpu string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: apis.ObjectMeta{
+				Name: "pod-" + uid,
+				UID:  types.UID(uid),
+			},
+			Spec: v1.PodSpec{
+				Containers: []v1.Container{
+					{
+						Resources: v1.ResourceRequirements{
+							Requests: v1.ResourceList{
+								v1.ResourceCPU: resource.MustParse(cpu),
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	app1 := NewApplication("app00001", "root.a", "alice", map[string]string{}, newMockSchedulerAPI())
+	app2 := NewApplication("app00002", "root.a.child", "bob", map[string]string{}, newMockSchedulerAPI())
+	app3 := NewApplication("app00003", "root.ab", "bob", map[string]string{}, newMockSchedulerAPI())
+	for _, app := range []*Application{app1, app2, app3} {
+		context.applications.put(app)
+	}
+	addTask := func(app *Application, uid, cpu, state string) {
+		task := NewTask(uid, app, context, newPod(uid, cpu))
+		task.sm.SetState(state)
+		app.addTask(task)
+	}
+	addTask(app1, "uid-1", "1", events.States().Task.Running)
+	addTask(app1, "uid-2", "2", events.States().Task.Scheduling)
+	addTask(app2, "uid-3", "4", events.States().Task.Bound)
+	addTask(app2, "uid-4", "8", events.States().Task.New)
+	addTask(app3, "uid-5", "16", events.States().Task.Running)
+	// neither running nor pending
+	addTask(app3, "uid-6", "32", events.States().Task.Completed)
+
+	// the apps of the child queues are listed, the apps of the sibling queues are not
+	apps := context.GetApplicationsByQueue("root.a")
+	assert.Equal(t, len(apps.Applications), 2)
+	assert.Equal(t, apps.Applications[0].ApplicationID, "app00001")
+	assert.Equal(t, apps.Applications[0].User, "alice")
+	assert.Equal(t, apps.Applications[0].State, events.States().Application.New)
+	assert.Equal(t, apps.Applications[0].RunningTasks, 1)
+	assert.Equal(t, apps.Applications[0].Running[constants.CPU], int64(1000))
+	assert.Equal(t, apps.Applications[0].PendingTasks, 1)
+	assert.Equal(t, apps.Applications[0].Pending[constants.CPU], int64(2000))
+	assert.Equal(t, apps.Applications[1].ApplicationID, "app00002")
+	assert.Equal(t, apps.RunningTasks, 2)
+	assert.Equal(t, apps.Running[constants.CPU], int64(5000))
+	assert.Equal(t, apps.PendingTasks, 2)
+	assert.Equal(t, apps.Pending[constants.CPU], int64(10000))
+
+	apps = context.GetApplicationsByUser("bob")
+	assert.Equal(t, len(apps.Applications), 2)
+	assert.Equal(t, apps.Applications[0].ApplicationID, "app00002")
+	assert.Equal(t, apps.Applications[1].ApplicationID, "app00003")
+	assert.Equal(t, apps.Applications[1].RunningTasks, 1)
+	assert.Equal(t, apps.Applications[1].PendingTasks, 0)
+	assert.Equal(t, apps.Running[constants.CPU], int64(20000))
+	assert.Equal(t, apps.Pending[constants.CPU], int64(8000))
+
+	apps = context.GetApplicationsByUser("carol")
+	assert.Equal(t, len(apps.Applications), 0)
+	assert.Equal(t, apps.RunningTasks, 0)
+	assert.Equal(t, len(apps.Running), 0)
+}
+
+func TestGetNodeViews(t *testing.T) {
+	context := initContextForTest()
+	newPod := func(uid, nodeName, schedulerName, cpu string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: apis.ObjectMeta{
+				Name:      "pod-" + uid,
+				Namespace: "default",
+				UID:       types.UID(uid),
+			},
+			Spec: v1.PodSpec{
+				NodeName:      nodeName,
+				SchedulerName: schedulerName,
+				Containers: []v1.Container{
+					{
+						Resources: v1.ResourceRequirements{
+							Requests: v1.ResourceList{
+								v1.ResourceCPU: resource.MustParse(cpu),
+							},
+						},
+					},
+				},
+			},
+			Status: v1.PodStatus{Phase: v1.PodRunning},
+		}
+	}
+	for _, name := range []string{"host0002", "host0001"} {
+		context.nodes.addAndReportNode(&v1.Node{
+			ObjectMeta: apis.ObjectMeta{
+				Name: name,
+				UID:  types.UID("uid-" + name),
+			},
+		}, false)
+	}
+	app1 := NewApplication("app00001", "root.a", "alice", map[string]string{}, newMockSchedulerAPI())
+	app2 := NewApplication("app00002", "root.b", "bob", map[string]string{}, newMockSchedulerAPI())
+	context.applications.put(app1)
+	context.applications.put(app2)
+	addTask := func(app *Application, uid, nodeName, cpu, state string, placeholder bool) {
+		task := NewTask(uid, app, context, newPod(uid, nodeName, constants.SchedulerName, cpu))
+		task.nodeName = nodeName
+		task.allocationUUID = "alloc-" + uid
+		task.placeholder = placeholder
+		task.sm.SetState(state)
+		app.addTask(task)
+	}
+	addTask(app2, "uid-1", "host0001", "1", events.States().Task.Bound, false)
+	addTask(app1, "uid-2", "host0001", "2", events.States().Task.Allocated, false)
+	addTask(app1, "uid-3", "host0001", "4", events.States().Task.Bound, true)
+	addTask(app1, "uid-4", "host0002", "8", events.States().Task.Bound, false)
+	// only the tasks that hold an allocation are listed
+	addTask(app1, "uid-5", "host0001", "16", events.States().Task.Completed, false)
+	addTask(app1, "uid-6", "", "32", events.States().Task.Scheduling, false)
+
+	// the pods of other schedulers are listed, the pods of the shim are already listed as tasks
+	assert.NilError(t, context.schedulerCache.AddPod(newPod("uid-7", "host0001", "default-scheduler", "1")))
+	assert.NilError(t, context.schedulerCache.AddPod(newPod("uid-1", "host0001", constants.SchedulerName, "1")))
+
+	views := context.GetNodeViews()
+	assert.Equal(t, len(views), 2)
+	assert.Equal(t, views[0].NodeName, "host0001")
+	assert.Equal(t, views[0].State, events.States().Node.New)
+	assert.Equal(t, views[0].Allocated[constants.CPU], int64(7000))
+	assert.Equal(t, len(views[0].Tasks), 2)
+	assert.Equal(t, views[0].Tasks[0].ApplicationID, "app00001")
+	assert.Equal(t, views[0].Tasks[0].TaskID, "uid-2")
+	assert.Equal(t, views[0].Tasks[0].AllocationUUID, "alloc-uid-2")
+	assert.Equal(t, views[0].Tasks[0].State, events.States().Task.Allocated)
+	assert.Equal(t, views[0].Tasks[1].ApplicationID, "app00002")
+	assert.Equal(t, len(views[0].Placeholders), 1)
+	assert.Equal(t, views[0].Placeholders[0].TaskID, "uid-3")
+	assert.Equal(t, views[0].Placeholders[0].Resource[constants.CPU], int64(4000))
+	assert.Equal(t, len(views[0].ForeignPods), 1)
+	assert.Equal(t, views[0].ForeignPods[0].Name, "pod-uid-7")
+	assert.Equal(t, views[0].ForeignPods[0].SchedulerName, "default-scheduler")
+	assert.Equal(t, views[0].ForeignPods[0].Resource[constants.CPU], int64(1000))
+	assert.Equal(t, views[1].NodeName, "host0002")
+	assert.Equal(t, views[1].Allocated[constants.CPU], int64(8000))
+	assert.Equal(t, len(views[1].Tasks), 1)
+	assert.Equal(t, len(views[1].Placeholders), 0)
+	assert.Equal(t, len(views[1].ForeignPods), 0)
+
+	view, err := context.GetNodeView("host0002")
+	assert.NilError(t, err)
+	assert.Equal(t, view.NodeName, "host0002")
+	assert.Equal(t, view.Tasks[0].TaskID, "uid-4")
+	_, err = context.GetNodeView("host0003")
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestAddPreBoundTask(t *testing.T) {
+	context := initContextForTest()
+	recorder := record.NewFakeRecorder(100)
+	events.SetRecorderForTest(recorder)
+	defer events.SetRecorderForTest(events.NewMockedRecorder())
+
+	context.AddApplication(&interfaces.AddApplicationRequest{
+		Metadata: interfaces.ApplicationMetadata{
+			ApplicationID: "app00001",
+			QueueName:     "root.a",
+			User:          "test-user",
+			Tags:          map[string]string{"namespace": "default"},
+		},
+	})
+	for _, name := range []string{"host0001", "host0002"} {
+		context.nodes.addAndReportNode(&v1.Node{
+			ObjectMeta: apis.ObjectMeta{
+				Name: name,
+				UID:  types.UID("uid-" + name),
+			},
+		}, false)
+	}
+	// host0002 is already registered with the core
+	context.nodes.getNode("host0002").fsm.SetState(events.States().Node.Healthy)
+
+	addTask := func(taskID, nodeName string, phase v1.PodPhase) *Task {
+		managedTask := context.AddTask(&interfaces.AddTaskRequest{
+			Metadata: interfaces.TaskMetadata{
+				ApplicationID: "app00001",
+				TaskID:        taskID,
+				Pod: &v1.Pod{
+					ObjectMeta: apis.ObjectMeta{
+						Name: "pod-" + taskID,
+						UID:  types.UID(taskID),
+					},
+					Spec: v1.PodSpec{
+						NodeName: nodeName,
+						Containers: []v1.Container{
+							{
+								Resources: v1.ResourceRequirements{
+									Requests: v1.ResourceList{
+										v1.ResourceCPU: resource.MustParse("1"),
+									},
+								},
+							},
+						},
+					},
+					Status: v1.PodStatus{Phase: phase},
+				},
+			},
+		})
+		task, ok := managedTask.(*Task)
+		assert.Assert(t, ok)
+		return task
+	}
+
+	// a pod without a node is scheduled as usual
+	task := addTask("task00001", "", v1.PodPending)
+	assert.Equal(t, task.GetTaskState(), events.States().Task.New)
+	assert.Assert(t, !task.preBound)
+
+	// the node is not reported to the core yet, the allocation is reported with the node
+	task = addTask("task00002", "host0001", v1.PodPending)
+	assert.Equal(t, task.GetTaskState(), events.States().Task.Bound)
+	assert.Assert(t, task.preBound)
+	assert.Assert(t, !task.preBoundOccupied)
+	assert.Equal(t, task.getNodeName(), "host0001")
+	assert.Equal(t, task.allocationUUID, "task00002")
+	node := context.nodes.getNode("host0001")
+	assert.Equal(t, len(node.existingAllocations), 1)
+	assert.Equal(t, node.existingAllocations[0].UUID, "task00002")
+	assert.Equal(t, node.existingAllocations[0].ApplicationID, "app00001")
+	assert.Equal(t, node.existingAllocations[0].QueueName, "root.a")
+	assert.Equal(t, <-recorder.Events,
+		"Normal PreBound /pod-task00002 was bound to node host0001 before it was scheduled, it is not scheduled again")
+
+	// the node is registered, the resources are occupied on the node
+	task = addTask("task00003", "host0002", v1.PodPending)
+	assert.Equal(t, task.GetTaskState(), events.States().Task.Bound)
+	assert.Assert(t, task.preBoundOccupied)
+	node = context.nodes.getNode("host0002")
+	assert.Equal(t, len(node.existingAllocations), 0)
+	assert.Equal(t, node.occupied.Resources[constants.CPU].Value, int64(1000))
+
+	// the occupied resources are returned once the pod completes
+	err := task.handle(NewSimpleTaskEvent("app00001", "task00003", events.CompleteTask))
+	assert.NilError(t, err)
+	assert.Equal(t, task.GetTaskState(), events.States().Task.Completed)
+	assert.Equal(t, node.occupied.Resources[constants.CPU].Value, int64(0))
+	assert.Assert(t, !task.preBoundOccupied)
+
+	// a terminated pod is not accounted for
+	task = addTask("task00004", "host0002", v1.PodSucceeded)
+	assert.Equal(t, task.GetTaskState(), events.States().Task.New)
+}
+
+func TestAddTaskQuarantine(t *testing.T) {
+	context := initContextForTest()
+	recorder := record.NewFakeRecorder(100)
+	events.SetRecorderForTest(recorder)
+	defer events.SetRecorderForTest(events.NewMockedRecorder())
+
+	context.AddApplication(&interfaces.AddApplicationRequest{
+		Metadata: interfaces.ApplicationMetadata{
+			ApplicationID: "app00001",
+			QueueName:     "root.a",
+			User:          "test-user",
+			Tags:          map[string]string{"namespace": "default"},
+			TaskGroups: []v1alpha1.TaskGroup{
+				{Name: "tg-1", MinMember: 1},
+			},
+		},
+	})
+
+	addTask := func(taskID string, placeholder bool, labels, annotations map[string]string) *Task {
+		managedTask := context.AddTask(&interfaces.AddTaskRequest{
+			Metadata: interfaces.TaskMetadata{
+				ApplicationID: "app00001",
+				TaskID:        taskID,
+				Placeholder:   placeholder,
+				Pod: &v1.Pod{
+					ObjectMeta: apis.ObjectMeta{
+						Name:        "pod-" + taskID,
+						Namespace:   "default",
+						UID:         types.UID(taskID),
+						Labels:      labels,
+						Annotations: annotations,
+					},
+				},
+			},
+		})
+		task, ok := managedTask.(*Task)
+		assert.Assert(t, ok)
+		return task
+	}
+
+	// consistent directives
+	task := addTask("task00001", false,
+		map[string]string{constants.LabelApplicationID: "app00001", constants.LabelQueueName: "root.a"},
+		map[string]string{constants.AnnotationApplicationID: "app00001", constants.AnnotationTaskGroupName: "tg-1"})
+	assert.Equal(t, task.GetTaskState(), events.States().Task.New)
+	assert.Equal(t, task.getQuarantined(), "")
+
+	// the app ID label names another app, the task group is not defined in the app
+	task = addTask("task00002", false,
+		map[string]string{constants.LabelApplicationID: "app00002"},
+		map[string]string{constants.AnnotationApplicationID: "app00001", constants.AnnotationTaskGroupName: "tg-2"})
+	assert.Equal(t, task.GetTaskState(), events.States().Task.Quarantined)
+	assert.Equal(t, task.getQuarantined(),
+		"label applicationId=app00002 conflicts with annotation yunikorn.apache.org/app-id=app00001; "+
+			"task group tg-2 is not defined in the task groups of application app00001")
+	event := <-recorder.Events
+	assert.Assert(t, strings.HasPrefix(event, "Warning "+conflictingDirectivesReason+" "), event)
+
+	// the queue label names another queue
+	task = addTask("task00003", false,
+		map[string]string{constants.LabelApplicationID: "app00001", constants.LabelQueueName: "root.b"}, nil)
+	assert.Equal(t, task.GetTaskState(), events.States().Task.Quarantined)
+	assert.Equal(t, task.getQuarantined(), "label queue=root.b conflicts with queue root.a of application app00001")
+
+	// the placeholders are not validated
+	task = addTask("task00004", true,
+		map[string]string{constants.LabelApplicationID: "app00001", constants.LabelQueueName: "root.b"}, nil)
+	assert.Equal(t, task.GetTaskState(), events.States().Task.New)
+
+	quarantined := context.GetQuarantinedTasks()
+	assert.Equal(t, len(quarantined), 2)
+	ids := []string{quarantined[0].TaskID, quarantined[1].TaskID}
+	sort.Strings(ids)
+	assert.DeepEqual(t, ids, []string{"task00002", "task00003"})
+
+	// the quarantined task is completed when its pod is deleted
+	task, err := context.getTask("app00001", "task00003")
+	assert.NilError(t, err)
+	err = task.handle(NewSimpleTaskEvent("app00001", "task00003", events.CompleteTask))
+	assert.NilError(t, err)
+	assert.Equal(t, task.GetTaskState(), events.States().Task.Completed)
+	assert.Equal(t, len(context.GetQuarantinedTasks()), 1)
+}
@@ -0,0 +1,94 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/webservice/dao"
+)
+
+// decisionStream fans out the scheduling decisions to the subscribers, e.g the web UI.
+// Publishing never blocks the scheduling, a decision is dropped for a subscriber that is too slow.
+type decisionStream struct {
+	subscribers map[int]chan dao.SchedulingDecision
+	nextID      int
+	sync.RWMutex
+}
+
+func newDecisionStream() *decisionStream {
+	return &decisionStream{
+		subscribers: make(map[int]chan dao.SchedulingDecision),
+	}
+}
+
+// subscribe returns a channel receiving the decisions published from now on,
+// and the function to call to stop receiving them.
+func (s *decisionStream) subscribe(bufferSize int) (<-chan dao.SchedulingDecision, func()) {
+	s.Lock()
+	defer s.Unlock()
+	id := s.nextID
+	s.nextID++
+	ch := make(chan dao.SchedulingDecision, bufferSize)
+	s.subscribers[id] = ch
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			s.Lock()
+			defer s.Unlock()
+			delete(s.subscribers, id)
+			close(ch)
+		})
+	}
+}
+
+func (s *decisionStream) publish(decision dao.SchedulingDecision) {
+	s.RLock()
+	defer s.RUnlock()
+	if len(s.subscribers) == 0 {
+		return
+	}
+	decision.Timestamp = time.Now()
+	for _, ch := range s.subscribers {
+		select {
+		case ch <- decision:
+		default:
+			log.Logger().Debug("subscriber is too slow, scheduling decision dropped",
+				zap.String("type", decision.Type),
+				zap.String("appID", decision.ApplicationID))
+		}
+	}
+}
+
+// SubscribeSchedulingDecisions returns a channel receiving the scheduling decisions taken from now on,
+// the returned function must be called once the decisions are no longer consumed.
+func (ctx *Context) SubscribeSchedulingDecisions(bufferSize int) (<-chan dao.SchedulingDecision, func()) {
+	return ctx.decisions.subscribe(bufferSize)
+}
+
+// publishDecision publishes a decision taken for the app, this is a noop if the app is not added to a context
+func (app *Application) publishDecision(decision dao.SchedulingDecision) {
+	if app.context != nil {
+		app.context.decisions.publish(decision)
+	}
+}
@@ -0,0 +1,69 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/webservice/dao"
+)
+
+func TestDecisionStream(t *testing.T) {
+	stream := newDecisionStream()
+	// no subscriber, nothing is kept
+	stream.publish(dao.SchedulingDecision{Type: dao.DecisionTaskScheduled, ApplicationID: "app-0"})
+
+	fast, unsubscribeFast := stream.subscribe(2)
+	slow, unsubscribeSlow := stream.subscribe(1)
+	stream.publish(dao.SchedulingDecision{Type: dao.DecisionTaskScheduled, ApplicationID: "app-1"})
+	stream.publish(dao.SchedulingDecision{Type: dao.DecisionTaskScheduled, ApplicationID: "app-2"})
+
+	// the slow subscriber misses the decisions that do not fit in its buffer
+	assert.Equal(t, (<-fast).ApplicationID, "app-1")
+	assert.Equal(t, (<-fast).ApplicationID, "app-2")
+	decision := <-slow
+	assert.Equal(t, decision.ApplicationID, "app-1")
+	assert.Assert(t, !decision.Timestamp.IsZero())
+	assert.Equal(t, len(slow), 0)
+
+	// the channel is closed once unsubscribed, unsubscribing twice is a no-op
+	unsubscribeSlow()
+	unsubscribeSlow()
+	_, ok := <-slow
+	assert.Assert(t, !ok)
+	stream.publish(dao.SchedulingDecision{Type: dao.DecisionTaskScheduled, ApplicationID: "app-3"})
+	assert.Equal(t, (<-fast).ApplicationID, "app-3")
+	unsubscribeFast()
+	assert.Equal(t, len(stream.subscribers), 0)
+}
+
+func TestDecisionStreamPerContext(t *testing.T) {
+	first := initContextForTest()
+	decisions, unsubscribe := first.SubscribeSchedulingDecisions(1)
+	defer unsubscribe()
+
+	// the subscribers of a context do not receive the decisions of another one
+	second := initContextForTest()
+	second.decisions.publish(dao.SchedulingDecision{Type: dao.DecisionTaskScheduled, ApplicationID: "app-1"})
+	assert.Equal(t, len(decisions), 0)
+	first.decisions.publish(dao.SchedulingDecision{Type: dao.DecisionTaskScheduled, ApplicationID: "app-1"})
+	assert.Equal(t, len(decisions), 1)
+}
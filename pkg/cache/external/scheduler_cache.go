@@ -86,6 +86,21 @@ func (cache *SchedulerCache) GetNode(name string) *schedulernode.NodeInfo {
 	return nil
 }
 
+// GetNodePods returns a copy of the pods the cache knows to be running on the node,
+// this includes the pods assumed by the shim that are not bound yet.
+func (cache *SchedulerCache) GetNodePods(name string) []*v1.Pod {
+	cache.lock.RLock()
+	defer cache.lock.RUnlock()
+
+	n, ok := cache.nodesMap[name]
+	if !ok {
+		return nil
+	}
+	pods := make([]*v1.Pod, len(n.Pods()))
+	copy(pods, n.Pods())
+	return pods
+}
+
 func (cache *SchedulerCache) AddNode(node *v1.Node) {
 	cache.lock.Lock()
 	defer cache.lock.Unlock()
@@ -0,0 +1,122 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/apis/yunikorn.apache.org/v1alpha1"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/dispatcher"
+)
+
+// GetTaskGroupStatuses returns the placeholder statistics of the task groups of the app,
+// nil if the app has not created any placeholder
+func (app *Application) GetTaskGroupStatuses() []v1alpha1.TaskGroupStatus {
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	return app.getTaskGroupStatuses()
+}
+
+// getTaskGroupStatuses is GetTaskGroupStatuses for a caller that already holds the app lock
+func (app *Application) getTaskGroupStatuses() []v1alpha1.TaskGroupStatus {
+	progress := app.placeholderProgress
+	if progress == nil || len(app.taskGroups) == 0 {
+		return nil
+	}
+	bound := utils.NewTaskGroupInstanceCountMap()
+	for _, t := range app.getTasks(boundTaskStates...) {
+		if t.placeholder {
+			bound.AddOne(t.taskGroupName)
+		}
+	}
+	statuses := make([]v1alpha1.TaskGroupStatus, 0, len(app.taskGroups))
+	for _, tg := range app.taskGroups {
+		p, _ := progress.get(tg.Name)
+		statuses = append(statuses, v1alpha1.TaskGroupStatus{
+			Name:     tg.Name,
+			Desired:  tg.MinMember,
+			Bound:    bound.GetTaskGroupInstanceCount(tg.Name),
+			Replaced: p.replaced,
+			TimedOut: p.timedOut,
+		})
+	}
+	return statuses
+}
+
+// gangStatusMessage summarizes the statistics of the task groups, e.g. "test-group-2: 14/20 reserved"
+func gangStatusMessage(statuses []v1alpha1.TaskGroupStatus) string {
+	parts := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		part := fmt.Sprintf("%s: %d/%d reserved", status.Name, status.Bound, status.Desired)
+		if status.TimedOut > 0 {
+			part += fmt.Sprintf(", %d timed out", status.TimedOut)
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// startGangStatusTimer starts publishing the statistics of the task groups while the app is reserving.
+// The caller must hold the app lock, the timer takes it when it fires.
+func (app *Application) startGangStatusTimer() {
+	app.stopGangStatusTimer()
+	app.gangStatusRound++
+	interval := conf.GetSchedulerConf().GetGangStatusInterval()
+	if interval <= 0 {
+		return
+	}
+	round := app.gangStatusRound
+	app.gangStatusTimer = time.AfterFunc(interval, func() {
+		app.handleGangStatusTimer(round, interval)
+	})
+}
+
+// stopGangStatusTimer stops publishing the statistics of the task groups, the caller must hold the app lock
+func (app *Application) stopGangStatusTimer() {
+	if app.gangStatusTimer != nil {
+		app.gangStatusTimer.Stop()
+		app.gangStatusTimer = nil
+	}
+}
+
+// handleGangStatusTimer publishes the statistics of the task groups as an event of the app
+// and in the CRD of the app, until the app leaves the Reserving state
+func (app *Application) handleGangStatusTimer(round int64, interval time.Duration) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+
+	// the app has already left the Reserving state, or entered it again after the timer was started
+	if app.sm.Current() != events.States().Application.Reserving || app.gangStatusRound != round {
+		return
+	}
+	if statuses := app.getTaskGroupStatuses(); len(statuses) > 0 {
+		app.publishAppEvent(v1.EventTypeNormal, "GangStatus", "%s", gangStatusMessage(statuses))
+		dispatcher.Dispatch(NewApplicationTaskGroupsChangeEvent(app.applicationID))
+	}
+	app.gangStatusTimer = time.AfterFunc(interval, func() {
+		app.handleGangStatusTimer(round, interval)
+	})
+}
@@ -0,0 +1,107 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/apis/yunikorn.apache.org/v1alpha1"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+)
+
+func TestGetTaskGroupStatuses(t *testing.T) {
+	app := NewApplication("app-gang-status-01", "root.abc", "test-user", map[string]string{}, newMockSchedulerAPI())
+	app.taskGroups = []v1alpha1.TaskGroup{
+		{Name: "test-group-1", MinMember: 2},
+		{Name: "test-group-2", MinMember: 20},
+	}
+	// no placeholder is created for the app
+	assert.Assert(t, app.GetTaskGroupStatuses() == nil)
+
+	app.placeholderProgress = newPlaceholderProgress(app.taskGroups)
+	addPlaceholder := func(name, taskGroupName, state string) {
+		pod := &v1.Pod{ObjectMeta: apis.ObjectMeta{Name: name, UID: types.UID("UID-" + name)}}
+		task := NewTask("UID-"+name, app, nil, pod)
+		task.placeholder = true
+		task.taskGroupName = taskGroupName
+		task.sm.SetState(state)
+		app.taskMap[task.taskID] = task
+	}
+	addPlaceholder("ph-1", "test-group-1", events.States().Task.Bound)
+	addPlaceholder("ph-2", "test-group-1", events.States().Task.Bound)
+	for _, name := range []string{"ph-3", "ph-4", "ph-5"} {
+		addPlaceholder(name, "test-group-2", events.States().Task.Bound)
+	}
+	addPlaceholder("ph-6", "test-group-2", events.States().Task.Pending)
+	app.placeholderProgress.onReplaced("test-group-1")
+	app.placeholderProgress.onTimedOut("test-group-2")
+
+	statuses := app.GetTaskGroupStatuses()
+	assert.DeepEqual(t, statuses, []v1alpha1.TaskGroupStatus{
+		{Name: "test-group-1", Desired: 2, Bound: 2, Replaced: 1},
+		{Name: "test-group-2", Desired: 20, Bound: 3, TimedOut: 1},
+	})
+	assert.Equal(t, gangStatusMessage(statuses),
+		"test-group-1: 2/2 reserved; test-group-2: 3/20 reserved, 1 timed out")
+}
+
+func TestGangStatusTimer(t *testing.T) {
+	schedulerConf := conf.GetSchedulerConf()
+	interval := schedulerConf.GangStatusInterval
+	defer func() {
+		schedulerConf.GangStatusInterval = interval
+	}()
+
+	app := NewApplication("app-gang-status-02", "root.abc", "test-user", map[string]string{}, newMockSchedulerAPI())
+	app.sm.SetState(events.States().Application.Reserving)
+
+	// no timer when the statistics are only published on state changes
+	schedulerConf.GangStatusInterval = 0
+	app.startGangStatusTimer()
+	assert.Assert(t, app.gangStatusTimer == nil)
+
+	schedulerConf.GangStatusInterval = time.Hour
+	app.startGangStatusTimer()
+	assert.Assert(t, app.gangStatusTimer != nil)
+	round := app.gangStatusRound
+
+	// the timer keeps publishing while the app is reserving
+	app.handleGangStatusTimer(round, time.Hour)
+	assert.Assert(t, app.gangStatusTimer != nil)
+
+	// the timer of an earlier reservation is not re-armed
+	app.startGangStatusTimer()
+	current := app.gangStatusTimer
+	app.handleGangStatusTimer(round, time.Hour)
+	assert.Equal(t, app.gangStatusTimer, current)
+
+	// leaving the Reserving state stops the timer
+	app.leaveReserving(nil)
+	assert.Assert(t, app.gangStatusTimer == nil)
+	app.sm.SetState(events.States().Application.Running)
+	app.handleGangStatusTimer(app.gangStatusRound, time.Hour)
+	assert.Assert(t, app.gangStatusTimer == nil)
+}
@@ -20,6 +20,7 @@ package cache
 
 import (
 	"sync"
+	"time"
 
 	"github.com/looplab/fsm"
 	"go.uber.org/zap"
@@ -28,6 +29,7 @@ import (
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/sirequest"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/dispatcher"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
@@ -42,6 +44,9 @@ type SchedulerNode struct {
 	occupied            *si.Resource
 	schedulable         bool
 	existingAllocations []*si.Allocation
+	reportedCapacity    *si.Resource
+	reportedOccupied    *si.Resource
+	pendingUpdate       *time.Timer
 	schedulerAPI        api.SchedulerAPI
 	fsm                 *fsm.FSM
 	lock                *sync.RWMutex
@@ -109,6 +114,22 @@ func (n *SchedulerNode) addExistingAllocation(allocation *si.Allocation) {
 	n.existingAllocations = append(n.existingAllocations, allocation)
 }
 
+// addPreBoundAllocation adds the allocation of a pod bound to the node outside of the scheduler,
+// this only succeeds when the node is not reported to the core yet, the allocation is then
+// reported as an existing allocation of the node like in recovery.
+func (n *SchedulerNode) addPreBoundAllocation(allocation *si.Allocation) bool {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.getNodeState() != events.States().Node.New {
+		return false
+	}
+	log.Logger().Info("add pre-bound allocation",
+		zap.String("nodeID", n.name),
+		zap.String("allocationKey", allocation.AllocationKey))
+	n.existingAllocations = append(n.existingAllocations, allocation)
+	return true
+}
+
 func (n *SchedulerNode) setOccupiedResource(resource *si.Resource) {
 	n.lock.Lock()
 	defer n.lock.Unlock()
@@ -117,6 +138,86 @@ func (n *SchedulerNode) setOccupiedResource(resource *si.Resource) {
 	n.occupied = resource
 }
 
+// setCapacity updates the capacity of the node, returns true if the capacity changed
+func (n *SchedulerNode) setCapacity(capacity *si.Resource) bool {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if common.Equals(n.capacity, capacity) {
+		return false
+	}
+	log.Logger().Info("set node capacity",
+		zap.String("nodeID", n.name),
+		zap.String("capacity", capacity.String()))
+	n.capacity = capacity
+	return true
+}
+
+// updateOccupiedResource adds or subtracts the resource from the occupied resource of the node
+func (n *SchedulerNode) updateOccupiedResource(resource *si.Resource, opt updateType) bool {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	switch opt {
+	case AddOccupiedResource:
+		n.occupied = common.Add(n.occupied, resource)
+	case SubOccupiedResource:
+		n.occupied = common.Sub(n.occupied, resource)
+	default:
+		return false
+	}
+	return true
+}
+
+// reportUpdate sends the capacity and occupied resource of the node to the scheduler-core.
+// With a coalesce period the update is delayed, the changes made in the meantime are sent
+// together, a node flapping back to the reported values does not send anything.
+func (n *SchedulerNode) reportUpdate(coalescePeriod time.Duration) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if coalescePeriod <= 0 {
+		n.sendUpdate()
+		return
+	}
+	if n.pendingUpdate == nil {
+		n.pendingUpdate = time.AfterFunc(coalescePeriod, func() {
+			n.lock.Lock()
+			defer n.lock.Unlock()
+			n.pendingUpdate = nil
+			n.sendUpdate()
+		})
+	}
+}
+
+// cancelPendingUpdate drops the update waiting for the coalesce period, e.g when the node is deleted
+func (n *SchedulerNode) cancelPendingUpdate() {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.pendingUpdate != nil {
+		n.pendingUpdate.Stop()
+		n.pendingUpdate = nil
+	}
+}
+
+// sendUpdate sends the update if the resources differ from the last reported ones, the lock must be held
+func (n *SchedulerNode) sendUpdate() {
+	if n.reportedCapacity != nil && common.Equals(n.reportedCapacity, n.capacity) &&
+		common.Equals(n.reportedOccupied, n.occupied) {
+		log.Logger().Debug("node resources are the same as reported, skip the update",
+			zap.String("nodeID", n.name))
+		return
+	}
+	log.Logger().Info("report node resources updates",
+		zap.String("nodeID", n.name),
+		zap.String("capacityDelta", common.Sub(n.capacity, n.reportedCapacity).String()),
+		zap.String("occupiedDelta", common.Sub(n.occupied, n.reportedOccupied).String()))
+	request := common.CreateUpdateRequestForUpdatedNode(common.NewNode(n.name, n.uid, n.capacity, n.occupied))
+	if err := n.schedulerAPI.Update(&request); err != nil {
+		log.Logger().Info("hitting error while handling UpdateNode", zap.Error(err))
+		return
+	}
+	n.reportedCapacity = n.capacity
+	n.reportedOccupied = n.occupied
+}
+
 func (n *SchedulerNode) getNodeState() string {
 	// fsm has its own internal lock, we don't need to hold node's lock here
 	return n.fsm.Current()
@@ -145,28 +246,58 @@ func (n *SchedulerNode) handleNodeRecovery(event *fsm.Event) {
 		zap.String("nodeID", n.name),
 		zap.Bool("schedulable", n.schedulable))
 
-	request := &si.UpdateRequest{
-		Asks:     nil,
-		Releases: nil,
-		NewSchedulableNodes: []*si.NewNodeInfo{
-			{
-				NodeID:              n.name,
-				SchedulableResource: n.capacity,
-				OccupiedResource:    n.occupied,
-				Attributes: map[string]string{
-					constants.DefaultNodeAttributeHostNameKey: n.name,
-					constants.DefaultNodeAttributeRackNameKey: constants.DefaultRackName,
-				},
-				ExistingAllocations: n.existingAllocations,
-			},
-		},
-		RmID: conf.GetSchedulerConf().ClusterID,
+	// a node recovered in bulk is reported by the caller together with the other nodes
+	if len(event.Args) > 0 {
+		if bulk, ok := event.Args[0].(bool); ok && bulk {
+			n.reportedCapacity = n.capacity
+			n.reportedOccupied = n.occupied
+			return
+		}
 	}
-
-	// send request to scheduler-core
-	if err := n.schedulerAPI.Update(request); err != nil {
+	request, err := sirequest.New(conf.GetSchedulerConf().ClusterID).AddNode(n.newNodeInfo()).Build()
+	if err == nil {
+		// send request to scheduler-core
+		err = n.schedulerAPI.Update(request)
+	}
+	if err != nil {
 		log.Logger().Error("failed to send request",
-			zap.Any("request", request))
+			zap.Any("request", request),
+			zap.Error(err))
+		return
+	}
+	n.reportedCapacity = n.capacity
+	n.reportedOccupied = n.occupied
+}
+
+// startBulkRecovery moves a new node to the recovering state without reporting it,
+// the returned node info must be reported to the core by the caller. nil is returned if the node is not new.
+func (n *SchedulerNode) startBulkRecovery() *si.NewNodeInfo {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.getNodeState() != events.States().Node.New {
+		return nil
+	}
+	if err := n.fsm.Event(string(events.RecoverNode), true); err != nil {
+		log.Logger().Warn("failed to recover node in bulk",
+			zap.String("nodeID", n.name),
+			zap.Error(err))
+		return nil
+	}
+	return n.newNodeInfo()
+}
+
+// newNodeInfo returns the node with its existing allocations as reported to the core when it is recovered,
+// the caller must hold the node lock.
+func (n *SchedulerNode) newNodeInfo() *si.NewNodeInfo {
+	return &si.NewNodeInfo{
+		NodeID:              n.name,
+		SchedulableResource: n.capacity,
+		OccupiedResource:    n.occupied,
+		Attributes: map[string]string{
+			constants.DefaultNodeAttributeHostNameKey: n.name,
+			constants.DefaultNodeAttributeRackNameKey: constants.DefaultRackName,
+		},
+		ExistingAllocations: n.existingAllocations,
 	}
 }
 
@@ -174,26 +305,17 @@ func (n *SchedulerNode) handleDrainNode(event *fsm.Event) {
 	log.Logger().Info("node enters draining mode",
 		zap.String("nodeID", n.name))
 
-	request := &si.UpdateRequest{
-		Asks:     nil,
-		Releases: nil,
-		UpdatedNodes: []*si.UpdateNodeInfo{
-			{
-				NodeID: n.name,
-				Action: si.UpdateNodeInfo_DRAIN_NODE,
-				Attributes: map[string]string{
-					constants.DefaultNodeAttributeHostNameKey: n.name,
-					constants.DefaultNodeAttributeRackNameKey: constants.DefaultRackName,
-				},
-			},
-		},
-		RmID: conf.GetSchedulerConf().ClusterID,
+	request, err := sirequest.New(conf.GetSchedulerConf().ClusterID).
+		UpdateNode(sirequest.NodeAction(n.name, si.UpdateNodeInfo_DRAIN_NODE)).
+		Build()
+	if err == nil {
+		// send request to scheduler-core
+		err = n.schedulerAPI.Update(request)
 	}
-
-	// send request to scheduler-core
-	if err := n.schedulerAPI.Update(request); err != nil {
+	if err != nil {
 		log.Logger().Error("failed to send request",
-			zap.Any("request", request))
+			zap.Any("request", request),
+			zap.Error(err))
 	}
 }
 
@@ -201,26 +323,17 @@ func (n *SchedulerNode) handleRestoreNode(event *fsm.Event) {
 	log.Logger().Info("restore node from draining mode",
 		zap.String("nodeID", n.name))
 
-	request := &si.UpdateRequest{
-		Asks:     nil,
-		Releases: nil,
-		UpdatedNodes: []*si.UpdateNodeInfo{
-			{
-				NodeID: n.name,
-				Action: si.UpdateNodeInfo_DRAIN_TO_SCHEDULABLE,
-				Attributes: map[string]string{
-					constants.DefaultNodeAttributeHostNameKey: n.name,
-					constants.DefaultNodeAttributeRackNameKey: constants.DefaultRackName,
-				},
-			},
-		},
-		RmID: conf.GetSchedulerConf().ClusterID,
+	request, err := sirequest.New(conf.GetSchedulerConf().ClusterID).
+		UpdateNode(sirequest.NodeAction(n.name, si.UpdateNodeInfo_DRAIN_TO_SCHEDULABLE)).
+		Build()
+	if err == nil {
+		// send request to scheduler-core
+		err = n.schedulerAPI.Update(request)
 	}
-
-	// send request to scheduler-core
-	if err := n.schedulerAPI.Update(request); err != nil {
+	if err != nil {
 		log.Logger().Error("failed to send request",
-			zap.Any("request", request))
+			zap.Any("request", request),
+			zap.Error(err))
 	}
 }
 
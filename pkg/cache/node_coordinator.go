@@ -39,11 +39,12 @@ import (
 // each of these updates will trigger a node UPDATE action to update the occupied
 // resource in the scheduler-core.
 type nodeResourceCoordinator struct {
-	nodes *schedulerNodes
+	nodes   *schedulerNodes
+	adopted *adoptedPods // the pods adopted by an app are tracked by their tasks, nil if none
 }
 
-func newNodeResourceCoordinator(nodes *schedulerNodes) *nodeResourceCoordinator {
-	return &nodeResourceCoordinator{nodes}
+func newNodeResourceCoordinator(nodes *schedulerNodes, adopted *adoptedPods) *nodeResourceCoordinator {
+	return &nodeResourceCoordinator{nodes, adopted}
 }
 
 // filter pods that not scheduled by us
@@ -51,7 +52,7 @@ func (c *nodeResourceCoordinator) filterPods(obj interface{}) bool {
 	switch obj.(type) {
 	case *v1.Pod:
 		pod := obj.(*v1.Pod)
-		return !utils.GeneralPodFilter(pod)
+		return !utils.GeneralPodFilter(pod) && !c.adopted.isAdopted(pod)
 	default:
 		return false
 	}
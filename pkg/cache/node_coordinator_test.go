@@ -36,7 +36,7 @@ func TestUpdatePod(t *testing.T) {
 	host2 := utils.NodeForTest("HOST2", "10G", "10")
 	nodes.addNode(host1)
 	nodes.addNode(host2)
-	coordinator := newNodeResourceCoordinator(nodes)
+	coordinator := newNodeResourceCoordinator(nodes, nil)
 
 	// pod state not changed, verify this won't trigger the update
 	pod1 := utils.PodForTest("pod1", "1G", "500m")
@@ -142,7 +142,7 @@ func TestDeletePod(t *testing.T) {
 	nodes := newSchedulerNodes(mockedSchedulerApi, NewTestSchedulerCache())
 	host1 := utils.NodeForTest("HOST1", "10G", "10")
 	nodes.addNode(host1)
-	coordinator := newNodeResourceCoordinator(nodes)
+	coordinator := newNodeResourceCoordinator(nodes, nil)
 
 	// pod from pending to running
 	// occupied resources should be added to the node
@@ -190,7 +190,7 @@ func TestDeleteTerminatedPod(t *testing.T) {
 	nodes := newSchedulerNodes(mockedSchedulerApi, NewTestSchedulerCache())
 	host1 := utils.NodeForTest("HOST1", "10G", "10")
 	nodes.addNode(host1)
-	coordinator := newNodeResourceCoordinator(nodes)
+	coordinator := newNodeResourceCoordinator(nodes, nil)
 
 	// pod from pending to running
 	// occupied resources should be added to the node
@@ -0,0 +1,140 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/webservice/dao"
+)
+
+// GetNodeViews returns what the shim believes is allocated on each of the nodes, sorted by node name
+func (ctx *Context) GetNodeViews() []*dao.NodeView {
+	tasks := ctx.getNodeTasks()
+	ctx.nodes.lock.RLock()
+	nodes := make([]*SchedulerNode, 0, len(ctx.nodes.nodesMap))
+	for _, node := range ctx.nodes.nodesMap {
+		nodes = append(nodes, node)
+	}
+	ctx.nodes.lock.RUnlock()
+	views := make([]*dao.NodeView, 0, len(nodes))
+	for _, node := range nodes {
+		views = append(views, ctx.getNodeView(node, tasks[node.name]))
+	}
+	sort.Slice(views, func(i, j int) bool {
+		return views[i].NodeName < views[j].NodeName
+	})
+	return views
+}
+
+// GetNodeView returns what the shim believes is allocated on the node
+func (ctx *Context) GetNodeView(name string) (*dao.NodeView, error) {
+	node := ctx.nodes.getNode(name)
+	if node == nil {
+		return nil, fmt.Errorf("node %s is not found in context", name)
+	}
+	return ctx.getNodeView(node, ctx.getNodeTasks()[name]), nil
+}
+
+// getNodeTasks returns the tasks that hold an allocation, grouped by the node they are allocated on
+func (ctx *Context) getNodeTasks() map[string][]*Task {
+	result := make(map[string][]*Task)
+	ctx.applications.forEach(func(app *Application) {
+		for _, task := range app.getAllocatedAndBoundTasks() {
+			if nodeName := task.getNodeName(); nodeName != "" {
+				result[nodeName] = append(result[nodeName], task)
+			}
+		}
+	})
+	return result
+}
+
+// getNodeView builds the view of the node from the tasks allocated on it and the pods
+// of other schedulers the external cache knows to be running on it.
+func (ctx *Context) getNodeView(node *SchedulerNode, tasks []*Task) *dao.NodeView {
+	info := node.getNodeInfo()
+	view := &dao.NodeView{
+		NodeName:     info.NodeName,
+		State:        info.State,
+		Schedulable:  info.Schedulable,
+		Capacity:     info.Capacity,
+		Occupied:     info.Occupied,
+		Tasks:        make([]dao.NodeTaskInfo, 0),
+		Placeholders: make([]dao.NodeTaskInfo, 0),
+		ForeignPods:  make([]dao.ForeignPodInfo, 0),
+	}
+	allocated := &resourceUsage{}
+	for _, task := range tasks {
+		taskInfo, placeholder := task.getNodeTaskInfo()
+		allocated.add(task.resource)
+		if placeholder {
+			view.Placeholders = append(view.Placeholders, taskInfo)
+		} else {
+			view.Tasks = append(view.Tasks, taskInfo)
+		}
+	}
+	view.Allocated = getResourceMap(allocated.allocated)
+	for _, pod := range ctx.schedulerCache.GetNodePods(node.name) {
+		if utils.GeneralPodFilter(pod) {
+			continue
+		}
+		view.ForeignPods = append(view.ForeignPods, dao.ForeignPodInfo{
+			Name:          pod.Name,
+			Namespace:     pod.Namespace,
+			SchedulerName: pod.Spec.SchedulerName,
+			Phase:         string(pod.Status.Phase),
+			Resource:      getResourceMap(common.GetPodResource(pod)),
+		})
+	}
+	sortNodeTasks(view.Tasks)
+	sortNodeTasks(view.Placeholders)
+	sort.Slice(view.ForeignPods, func(i, j int) bool {
+		if view.ForeignPods[i].Namespace != view.ForeignPods[j].Namespace {
+			return view.ForeignPods[i].Namespace < view.ForeignPods[j].Namespace
+		}
+		return view.ForeignPods[i].Name < view.ForeignPods[j].Name
+	})
+	return view
+}
+
+func (task *Task) getNodeTaskInfo() (dao.NodeTaskInfo, bool) {
+	task.lock.RLock()
+	defer task.lock.RUnlock()
+	return dao.NodeTaskInfo{
+		ApplicationID:  task.applicationID,
+		TaskID:         task.taskID,
+		TaskAlias:      task.alias,
+		State:          task.sm.Current(),
+		AllocationUUID: task.allocationUUID,
+		TaskGroupName:  task.taskGroupName,
+		Resource:       getResourceMap(task.resource),
+	}, task.placeholder
+}
+
+func sortNodeTasks(tasks []dao.NodeTaskInfo) {
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].ApplicationID != tasks[j].ApplicationID {
+			return tasks[i].ApplicationID < tasks[j].ApplicationID
+		}
+		return tasks[i].TaskID < tasks[j].TaskID
+	})
+}
@@ -20,17 +20,23 @@ package cache
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 
 	"go.uber.org/zap"
 	v1 "k8s.io/api/core/v1"
 
 	"github.com/apache/incubator-yunikorn-core/pkg/api"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/apis/yunikorn.apache.org/v1alpha1"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/cache/external"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/sirequest"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/dispatcher"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/metrics"
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
 
@@ -58,6 +64,20 @@ func newSchedulerNodes(schedulerAPI api.SchedulerAPI, cache *external.SchedulerC
 	}
 }
 
+// getNodes returns all the nodes sorted by name
+func (nc *schedulerNodes) getNodes() []*SchedulerNode {
+	nc.lock.RLock()
+	nodes := make([]*SchedulerNode, 0, len(nc.nodesMap))
+	for _, node := range nc.nodesMap {
+		nodes = append(nodes, node)
+	}
+	nc.lock.RUnlock()
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].name < nodes[j].name
+	})
+	return nodes
+}
+
 func (nc *schedulerNodes) getNode(name string) *SchedulerNode {
 	nc.lock.RLock()
 	defer nc.lock.RUnlock()
@@ -123,6 +143,86 @@ func (nc *schedulerNodes) addAndReportNode(node *v1.Node, reportNode bool) {
 	}
 }
 
+// recoverInBulk reports all the new nodes with their existing allocations to the core in as few requests as possible,
+// a request carries up to batchSize allocations and a node is never split across requests. The core answers each
+// node as if it was recovered on its own. The number of requests and allocations sent is reported as a metric.
+func (nc *schedulerNodes) recoverInBulk(batchSize int) {
+	nc.lock.RLock()
+	names := make([]string, 0, len(nc.nodesMap))
+	for name := range nc.nodesMap {
+		names = append(names, name)
+	}
+	nc.lock.RUnlock()
+	sort.Strings(names)
+
+	requests, reported := 0, 0
+	var page []*si.NewNodeInfo
+	pageSize := 0
+	send := func() {
+		if len(page) == 0 {
+			return
+		}
+		builder := sirequest.New(conf.GetSchedulerConf().ClusterID)
+		for _, info := range page {
+			builder.AddNode(info)
+		}
+		request, err := builder.Build()
+		if err == nil {
+			err = nc.proxy.Update(request)
+		}
+		if err != nil {
+			// the nodes are not new anymore, they are reported one by one instead
+			log.Logger().Warn("failed to recover nodes in bulk, reporting them one by one",
+				zap.Int("nodes", len(page)),
+				zap.Error(err))
+			for _, info := range page {
+				request, err = sirequest.New(conf.GetSchedulerConf().ClusterID).AddNode(info).Build()
+				if err == nil {
+					err = nc.proxy.Update(request)
+				}
+				if err != nil {
+					log.Logger().Error("failed to send request",
+						zap.String("nodeID", info.NodeID),
+						zap.Error(err))
+				}
+			}
+			page, pageSize = nil, 0
+			return
+		}
+		requests++
+		for _, info := range page {
+			reported += len(info.ExistingAllocations)
+		}
+		metrics.GetRecoveryMetrics().SetBulkProgress(requests, reported)
+		log.Logger().Info("nodes recovery request sent",
+			zap.Int("request", requests),
+			zap.Int("nodes", len(page)),
+			zap.Int("reportedAllocations", reported))
+		page, pageSize = nil, 0
+	}
+	for _, name := range names {
+		node := nc.getNode(name)
+		if node == nil {
+			continue
+		}
+		info := node.startBulkRecovery()
+		if info == nil {
+			continue
+		}
+		// a node without allocations still takes a slot, the size of the requests stays bounded
+		size := len(info.ExistingAllocations)
+		if size == 0 {
+			size = 1
+		}
+		if pageSize > 0 && pageSize+size > batchSize {
+			send()
+		}
+		page = append(page, info)
+		pageSize += size
+	}
+	send()
+}
+
 func (nc *schedulerNodes) drainNode(node *v1.Node) {
 	log.Logger().Info("draining node", zap.String("name", node.Name))
 	if node, ok := nc.nodesMap[node.Name]; ok {
@@ -153,26 +253,8 @@ func (nc *schedulerNodes) updateNodeOccupiedResources(name string, resource *si.
 	}
 
 	if schedulerNode := nc.getNode(name); schedulerNode != nil {
-		nc.lock.Lock()
-		defer nc.lock.Unlock()
-
-		switch opt {
-		case AddOccupiedResource:
-			schedulerNode.occupied = common.Add(schedulerNode.occupied, resource)
-		case SubOccupiedResource:
-			schedulerNode.occupied = common.Sub(schedulerNode.occupied, resource)
-		default:
-			// noop
-			return
-		}
-
-		node := common.NewNode(schedulerNode.name, schedulerNode.uid, schedulerNode.capacity, schedulerNode.occupied)
-		request := common.CreateUpdateRequestForUpdatedNode(node)
-		log.Logger().Info("report occupied resources updates",
-			zap.String("node", schedulerNode.name),
-			zap.Any("request", request))
-		if err := nc.proxy.Update(&request); err != nil {
-			log.Logger().Info("hitting error while handling UpdateNode", zap.Error(err))
+		if schedulerNode.updateOccupiedResource(resource, opt) {
+			schedulerNode.reportUpdate(conf.GetSchedulerConf().GetNodeUpdateCoalescePeriod())
 		}
 	}
 }
@@ -196,16 +278,16 @@ func (nc *schedulerNodes) updateNode(oldNode, newNode *v1.Node) {
 		nc.restoreNode(newNode)
 	}
 
-	// node resource changes
+	// node resource changes, e.g a device plugin registration or a kubelet restart,
+	// the capacity is sent along with the current occupied resource of the node
 	if equals(oldNode, newNode) {
 		return
 	}
 
-	node := common.CreateFrom(newNode)
-	request := common.CreateUpdateRequestForUpdatedNode(node)
-	log.Logger().Info("report updated nodes to scheduler", zap.Any("request", request))
-	if err := nc.proxy.Update(&request); err != nil {
-		log.Logger().Info("hitting error while handling UpdateNode", zap.Error(err))
+	if schedulerNode, ok := nc.nodesMap[newNode.Name]; ok {
+		if schedulerNode.setCapacity(common.GetNodeResource(&newNode.Status)) {
+			schedulerNode.reportUpdate(conf.GetSchedulerConf().GetNodeUpdateCoalescePeriod())
+		}
 	}
 }
 
@@ -213,6 +295,9 @@ func (nc *schedulerNodes) deleteNode(node *v1.Node) {
 	nc.lock.Lock()
 	defer nc.lock.Unlock()
 
+	if schedulerNode, ok := nc.nodesMap[node.Name]; ok {
+		schedulerNode.cancelPendingUpdate()
+	}
 	delete(nc.nodesMap, node.Name)
 
 	n := common.CreateFrom(node)
@@ -223,6 +308,127 @@ func (nc *schedulerNodes) deleteNode(node *v1.Node) {
 	}
 }
 
+// checkGangFeasibility checks if the gang defined by the task groups can ever be satisfied
+// by the nodes known to the cache. Only the node capacity is considered, the resources that
+// are currently in use are ignored because they will be released eventually. An error
+// describing the reason is returned when the gang can never be satisfied. The members are sized like
+// the placeholders, which run with the runtime class of the app.
+// The limits of the app that do not depend on the nodes, e.g. the queue max capacity, are checked by the context.
+func (nc *schedulerNodes) checkGangFeasibility(taskGroups []v1alpha1.TaskGroup, runtimeClassName string) error {
+	nc.lock.RLock()
+	defer nc.lock.RUnlock()
+
+	// the cluster is not known yet, we cannot make any decision
+	if len(nc.nodesMap) == 0 {
+		return nil
+	}
+
+	capacities := nc.getNodeCapacities()
+	if err := checkTaskGroupMemberFit(taskGroups, runtimeClassName, capacities); err != nil {
+		return fmt.Errorf("gang can never be satisfied: %v", err)
+	}
+	// the placeholders of a task group with a max per node must be spread over enough nodes
+	for _, taskGroup := range taskGroups {
+		if taskGroup.MaxPerNode <= 0 {
+			continue
+		}
+		needed := (int(taskGroup.MinMember) + int(taskGroup.MaxPerNode) - 1) / int(taskGroup.MaxPerNode)
+		if needed > len(capacities) {
+			return fmt.Errorf("gang can never be satisfied: taskGroup %s needs %d nodes with at most %d members per node, "+
+				"the cluster has %d nodes", taskGroup.Name, needed, taskGroup.MaxPerNode, len(capacities))
+		}
+	}
+
+	clusterCapacity := common.NewResourceBuilder().Build()
+	for _, capacity := range capacities {
+		clusterCapacity = common.Add(clusterCapacity, capacity)
+	}
+	gangResource := common.NewResourceBuilder().Build()
+	for _, taskGroup := range taskGroups {
+		gangResource = common.Add(gangResource,
+			utils.GetPlaceholderResource(taskGroup.MinResource, runtimeClassName, int64(taskGroup.MinMember)))
+	}
+
+	// the entire gang must fit in the cluster
+	if !common.FitIn(clusterCapacity, gangResource) {
+		return fmt.Errorf("gang can never be satisfied: gang requires %s, "+
+			"which exceeds the cluster capacity %s", gangResource.String(), clusterCapacity.String())
+	}
+	return nil
+}
+
+// checkTaskGroupMembers checks if a single member of each task group fits in the largest node known to
+// the cache, a member that does not fit in any node keeps its placeholders pending forever.
+// No decision is made while no nodes are known.
+func (nc *schedulerNodes) checkTaskGroupMembers(taskGroups []v1alpha1.TaskGroup, runtimeClassName string) error {
+	nc.lock.RLock()
+	defer nc.lock.RUnlock()
+	if len(nc.nodesMap) == 0 {
+		return nil
+	}
+	return checkTaskGroupMemberFit(taskGroups, runtimeClassName, nc.getNodeCapacities())
+}
+
+// getNodeCapacities returns the capacity of the nodes per node name, the caller must hold the lock
+func (nc *schedulerNodes) getNodeCapacities() map[string]*si.Resource {
+	capacities := make(map[string]*si.Resource, len(nc.nodesMap))
+	for name, node := range nc.nodesMap {
+		node.lock.RLock()
+		capacities[name] = node.capacity
+		node.lock.RUnlock()
+	}
+	return capacities
+}
+
+// checkTaskGroupMemberFit returns an error naming the task group and the resource that exceeds
+// the largest node when a member of a task group does not fit in any of the nodes. A member asks
+// what its placeholder asks, including the overhead of the runtime class.
+func checkTaskGroupMemberFit(taskGroups []v1alpha1.TaskGroup, runtimeClassName string,
+	capacities map[string]*si.Resource) error {
+	for _, taskGroup := range taskGroups {
+		memberResource := utils.GetPlaceholderResource(taskGroup.MinResource, runtimeClassName, 1)
+		fits := false
+		for _, capacity := range capacities {
+			if common.FitIn(capacity, memberResource) {
+				fits = true
+				break
+			}
+		}
+		if fits {
+			continue
+		}
+		return fmt.Errorf("member of task group %s requires %s, which does not fit in any node, %s",
+			taskGroup.Name, memberResource.String(), describeLargestNode(memberResource, capacities))
+	}
+	return nil
+}
+
+// describeLargestNode describes the first resource of the member that exceeds the largest node for that resource,
+// a member can also exceed the nodes by its combination of resources, each of them fitting in a different node.
+func describeLargestNode(memberResource *si.Resource, capacities map[string]*si.Resource) string {
+	names := make([]string, 0, len(memberResource.Resources))
+	for name := range memberResource.Resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		requested := memberResource.Resources[name].GetValue()
+		largestNode := ""
+		var largest int64
+		for nodeName, capacity := range capacities {
+			value := capacity.GetResources()[name].GetValue()
+			if largestNode == "" || value > largest || (value == largest && nodeName < largestNode) {
+				largestNode, largest = nodeName, value
+			}
+		}
+		if requested > largest {
+			return fmt.Sprintf("%s %d is requested and the largest node %s has %d allocatable",
+				name, requested, largestNode, largest)
+		}
+	}
+	return "no single node has all the requested resources allocatable"
+}
+
 func (nc *schedulerNodes) schedulerNodeEventHandler() func(obj interface{}) {
 	return func(obj interface{}) {
 		if event, ok := obj.(events.SchedulerNodeEvent); ok {
@@ -232,6 +438,7 @@ func (nc *schedulerNodes) schedulerNodeEventHandler() func(obj interface{}) {
 						log.Logger().Error("failed to handle scheduler node event",
 							zap.String("event", string(event.GetEvent())),
 							zap.Error(err))
+						dispatcher.RecordEventError(err)
 					}
 				}
 			}
@@ -19,6 +19,8 @@
 package cache
 
 import (
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -26,15 +28,20 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
 
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/apis/yunikorn.apache.org/v1alpha1"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/cache/external"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/test"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/dispatcher"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/metrics"
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
 
@@ -554,3 +561,168 @@ func TestCordonNode(t *testing.T) {
 	}, 1*time.Second, 5*time.Second)
 	assert.NilError(t, err)
 }
+
+func TestUpdateNodeResources(t *testing.T) {
+	api := test.NewSchedulerAPIMock()
+	var lock sync.Mutex
+	var lastUpdate *si.UpdateNodeInfo
+	api.UpdateFunction(func(request *si.UpdateRequest) error {
+		lock.Lock()
+		defer lock.Unlock()
+		lastUpdate = request.UpdatedNodes[0]
+		return nil
+	})
+	getLastUpdate := func() *si.UpdateNodeInfo {
+		lock.Lock()
+		defer lock.Unlock()
+		return lastUpdate
+	}
+
+	schedulerConf := conf.GetSchedulerConf()
+	defer func() { schedulerConf.NodeUpdateCoalescePeriod = 0 }()
+	nodes := newSchedulerNodes(api, NewTestSchedulerCache())
+	host1 := utils.NodeForTest("HOST1", "10G", "10")
+	nodes.addNode(host1)
+	podResource := common.NewResourceBuilder().
+		AddResource(constants.Memory, 1000).
+		AddResource(constants.CPU, 500).
+		Build()
+
+	// the capacity update carries the occupied resource of the node
+	nodes.updateNodeOccupiedResources("HOST1", podResource, AddOccupiedResource)
+	assert.Equal(t, api.GetUpdateCount(), int32(1))
+	host1Resized := utils.NodeForTest("HOST1", "20G", "10")
+	nodes.updateNode(host1, host1Resized)
+	assert.Equal(t, api.GetUpdateCount(), int32(2))
+	assert.Equal(t, getLastUpdate().SchedulableResource.Resources[constants.Memory].Value, int64(20000))
+	assert.Equal(t, getLastUpdate().OccupiedResource.Resources[constants.Memory].Value, int64(1000))
+	assert.Equal(t, nodes.getNode("HOST1").capacity.Resources[constants.Memory].Value, int64(20000))
+
+	// a flapping node sends nothing within the coalesce period
+	schedulerConf.NodeUpdateCoalescePeriod = 50 * time.Millisecond
+	nodes.updateNodeOccupiedResources("HOST1", podResource, SubOccupiedResource)
+	nodes.updateNodeOccupiedResources("HOST1", podResource, AddOccupiedResource)
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, api.GetUpdateCount(), int32(2))
+
+	// multiple changes are merged into a single update with the latest values
+	host1Shrunk := utils.NodeForTest("HOST1", "15G", "10")
+	host1Resized2 := utils.NodeForTest("HOST1", "16G", "10")
+	nodes.updateNode(host1Resized, host1Shrunk)
+	nodes.updateNode(host1Shrunk, host1Resized2)
+	assert.NilError(t, utils.WaitForCondition(func() bool {
+		return api.GetUpdateCount() == 3
+	}, 10*time.Millisecond, time.Second))
+	assert.Equal(t, getLastUpdate().SchedulableResource.Resources[constants.Memory].Value, int64(16000))
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, api.GetUpdateCount(), int32(3))
+
+	// the pending update is dropped when the node is deleted
+	nodes.updateNodeOccupiedResources("HOST1", podResource, AddOccupiedResource)
+	nodes.deleteNode(host1Resized2)
+	assert.Equal(t, api.GetUpdateCount(), int32(4))
+	assert.Equal(t, getLastUpdate().Action, si.UpdateNodeInfo_DECOMISSION)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, api.GetUpdateCount(), int32(4))
+}
+
+func TestCheckTaskGroupMemberFit(t *testing.T) {
+	capacities := map[string]*si.Resource{
+		"host0001": common.NewResourceBuilder().AddResource(constants.CPU, 4000).AddResource(constants.Memory, 1024).Build(),
+		"host0002": common.NewResourceBuilder().AddResource(constants.CPU, 2000).AddResource(constants.Memory, 4096).Build(),
+	}
+	taskGroup := func(cpu, memory string) v1alpha1.TaskGroup {
+		return v1alpha1.TaskGroup{
+			Name:      "test-group",
+			MinMember: 1,
+			MinResource: map[string]resource.Quantity{
+				v1.ResourceCPU.String():    resource.MustParse(cpu),
+				v1.ResourceMemory.String(): resource.MustParse(memory),
+			},
+		}
+	}
+
+	assert.NilError(t, checkTaskGroupMemberFit([]v1alpha1.TaskGroup{taskGroup("4", "1M")}, "", capacities))
+	assert.NilError(t, checkTaskGroupMemberFit([]v1alpha1.TaskGroup{taskGroup("2", "4096M")}, "", capacities))
+
+	// a single resource exceeds the largest node
+	err := checkTaskGroupMemberFit([]v1alpha1.TaskGroup{taskGroup("6", "1M")}, "", capacities)
+	assert.ErrorContains(t, err, "vcore 6000 is requested and the largest node host0001 has 4000 allocatable")
+	err = checkTaskGroupMemberFit([]v1alpha1.TaskGroup{taskGroup("1", "5000M")}, "", capacities)
+	assert.ErrorContains(t, err, "memory 5000 is requested and the largest node host0002 has 4096 allocatable")
+
+	// every resource fits in a node, but not on the same node
+	err = checkTaskGroupMemberFit([]v1alpha1.TaskGroup{taskGroup("4", "4096M")}, "", capacities)
+	assert.ErrorContains(t, err, "no single node has all the requested resources allocatable")
+
+	// the overhead of the runtime class is part of the member
+	common.SetRuntimeClassOverheadResolver(func(runtimeClassName string) v1.ResourceList {
+		return v1.ResourceList{v1.ResourceCPU: resource.MustParse("500m")}
+	})
+	defer common.SetRuntimeClassOverheadResolver(nil)
+	assert.NilError(t, checkTaskGroupMemberFit([]v1alpha1.TaskGroup{taskGroup("4", "1M")}, "", capacities))
+	err = checkTaskGroupMemberFit([]v1alpha1.TaskGroup{taskGroup("4", "1M")}, "kata", capacities)
+	assert.ErrorContains(t, err, "vcore 4500 is requested and the largest node host0001 has 4000 allocatable")
+
+	// no decision without nodes
+	nodes := newSchedulerNodes(newMockSchedulerAPI(), nil)
+	assert.NilError(t, nodes.checkTaskGroupMembers([]v1alpha1.TaskGroup{taskGroup("64", "1M")}, ""))
+}
+
+func TestRecoverNodesInBulk(t *testing.T) {
+	api := test.NewSchedulerAPIMock()
+	var requests [][]string
+	api.UpdateFunction(func(request *si.UpdateRequest) error {
+		nodeIDs := make([]string, 0, len(request.NewSchedulableNodes))
+		for _, info := range request.NewSchedulableNodes {
+			nodeIDs = append(nodeIDs, info.NodeID)
+		}
+		requests = append(requests, nodeIDs)
+		return nil
+	})
+	nodes := newSchedulerNodes(api, NewTestSchedulerCache())
+	allocations := map[string]int{"node-1": 3, "node-2": 0, "node-3": 4, "node-4": 1, "node-5": 0, "node-6": 2}
+	for name, count := range allocations {
+		nodes.addAndReportNode(&v1.Node{
+			ObjectMeta: apis.ObjectMeta{
+				Name: name,
+				UID:  types.UID("uid-" + name),
+			},
+		}, false)
+		for i := 0; i < count; i++ {
+			assert.NilError(t, nodes.addExistingAllocation(&si.Allocation{
+				AllocationKey: fmt.Sprintf("%s-%d", name, i),
+				NodeID:        name,
+			}))
+		}
+	}
+	// a node already recovered is not reported again
+	nodes.getNode("node-6").fsm.SetState(events.States().Node.Healthy)
+
+	nodes.recoverInBulk(4)
+	// a node is never split, the nodes without allocations take one slot
+	assert.DeepEqual(t, requests, [][]string{{"node-1", "node-2"}, {"node-3"}, {"node-4", "node-5"}})
+	for name := range allocations {
+		if name != "node-6" {
+			assert.Equal(t, nodes.getNode(name).getNodeState(), events.States().Node.Recovering)
+		}
+	}
+	assert.Equal(t, nodes.getNode("node-6").getNodeState(), events.States().Node.Healthy)
+	sent, reported := metrics.GetRecoveryMetrics().GetBulkProgress()
+	assert.Equal(t, sent, 3)
+	assert.Equal(t, reported, 8)
+
+	// the nodes of a request that failed are reported one by one
+	requests = nil
+	api.UpdateFunction(func(request *si.UpdateRequest) error {
+		if len(request.NewSchedulableNodes) > 1 {
+			return fmt.Errorf("request too large")
+		}
+		requests = append(requests, []string{request.NewSchedulableNodes[0].NodeID})
+		return nil
+	})
+	nodes.addAndReportNode(&v1.Node{ObjectMeta: apis.ObjectMeta{Name: "node-7"}}, false)
+	nodes.addAndReportNode(&v1.Node{ObjectMeta: apis.ObjectMeta{Name: "node-8"}}, false)
+	nodes.recoverInBulk(10)
+	assert.DeepEqual(t, requests, [][]string{{"node-7"}, {"node-8"}})
+}
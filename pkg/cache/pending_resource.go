@@ -0,0 +1,113 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/webservice/dao"
+)
+
+// the states of the tasks with an ask that is not allocated yet, in the order they are reported
+var pendingTaskStates = []string{
+	events.States().Task.New,
+	events.States().Task.Gated,
+	events.States().Task.Pending,
+	events.States().Task.Scheduling,
+}
+
+// GetPendingResource returns the resources of the tasks of the app that are not allocated yet,
+// aggregated per task state and per task group.
+func (app *Application) GetPendingResource() *dao.ApplicationPendingResource {
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+
+	total := &resourceUsage{}
+	states := make(map[string]*resourceUsage)
+	taskGroups := make(map[string]*resourceUsage)
+	for _, state := range pendingTaskStates {
+		for _, task := range app.getTasks(state) {
+			if _, ok := states[state]; !ok {
+				states[state] = &resourceUsage{}
+			}
+			total.add(task.resource)
+			states[state].add(task.resource)
+			if task.taskGroupName != "" {
+				if _, ok := taskGroups[task.taskGroupName]; !ok {
+					taskGroups[task.taskGroupName] = &resourceUsage{}
+				}
+				taskGroups[task.taskGroupName].add(task.resource)
+			}
+		}
+	}
+
+	pending := &dao.ApplicationPendingResource{
+		ApplicationID: app.applicationID,
+		Queue:         app.queue,
+		Pending:       getResourceMap(total.allocated),
+		Tasks:         total.tasks,
+		States:        make([]dao.StatePendingResource, 0, len(states)),
+		TaskGroups:    make([]dao.TaskGroupPendingResource, 0, len(taskGroups)),
+	}
+	for _, state := range pendingTaskStates {
+		if u, ok := states[state]; ok {
+			pending.States = append(pending.States, dao.StatePendingResource{
+				State:   state,
+				Pending: getResourceMap(u.allocated),
+				Tasks:   u.tasks,
+			})
+		}
+	}
+	for name, u := range taskGroups {
+		pending.TaskGroups = append(pending.TaskGroups, dao.TaskGroupPendingResource{
+			TaskGroup: name,
+			Pending:   getResourceMap(u.allocated),
+			Tasks:     u.tasks,
+		})
+	}
+	sort.Slice(pending.TaskGroups, func(i, j int) bool {
+		return pending.TaskGroups[i].TaskGroup < pending.TaskGroups[j].TaskGroup
+	})
+	return pending
+}
+
+// GetApplicationPendingResource returns the resources the app is still waiting for
+func (ctx *Context) GetApplicationPendingResource(appID string) (*dao.ApplicationPendingResource, error) {
+	app := ctx.applications.get(appID)
+	if app == nil {
+		return nil, fmt.Errorf("application %s is not found in context", appID)
+	}
+	return app.GetPendingResource(), nil
+}
+
+// GetPendingResources returns the resources the apps are still waiting for, apps without pending tasks are left out
+func (ctx *Context) GetPendingResources() []*dao.ApplicationPendingResource {
+	result := make([]*dao.ApplicationPendingResource, 0)
+	ctx.applications.forEach(func(app *Application) {
+		if pending := app.GetPendingResource(); pending.Tasks > 0 {
+			result = append(result, pending)
+		}
+	})
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ApplicationID < result[j].ApplicationID
+	})
+	return result
+}
@@ -20,6 +20,7 @@ package cache
 
 import (
 	"fmt"
+	"strconv"
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -27,6 +28,7 @@ import (
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/apis/yunikorn.apache.org/v1alpha1"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
 
 // MUST: run the placeholder pod as non-root user
@@ -45,29 +47,43 @@ type Placeholder struct {
 	pod           *v1.Pod
 }
 
-func newPlaceholder(placeholderName string, app *Application, taskGroup v1alpha1.TaskGroup) *Placeholder {
-	ownerRefs := app.placeholderOwnerReferences
-	// we need to set the controller field to false, because since we don't know what exactly the controller will do,
-	// we might have some unexpected behaviour.
-	// For example if it is a replication controller, some pods (placeholders and/or real pods) might be deleted
-	// in order to meet the requested replication factor.
-	// Since we need the owner reference only for having the placeholders garbage collected,
-	// we can just set the controller field = false, so we can avoid any kind of side effects.
-	for _, r := range ownerRefs {
-		*r.Controller = false
+// PlaceholderSpec is the metadata of an app its placeholders are created with. It is copied from the app
+// with its lock held, the placeholder manager then creates the placeholders without accessing the app fields.
+type PlaceholderSpec struct {
+	appID            string
+	queue            string
+	namespace        string
+	runtimeClassName string
+	// the owners of the placeholders, see getPlaceholderOwnerReferences
+	ownerReferences []metav1.OwnerReference
+	timeoutInSec    int64
+	// the total resources of the placeholders of all the task groups
+	ask *si.Resource
+}
+
+func newPlaceholder(placeholderName string, spec *PlaceholderSpec, taskGroup v1alpha1.TaskGroup, index int32) *Placeholder {
+	slot, affinity := utils.GetPlaceholderSpread(spec.appID, taskGroup.Name, taskGroup.MaxPerNode, index)
+	// the placeholders run with the runtime class of the members, the reservation includes its overhead
+	var runtimeClassName *string
+	if spec.runtimeClassName != "" {
+		name := spec.runtimeClassName
+		runtimeClassName = &name
+	}
+	// each placeholder gets its own references, the pods are not sharing any field
+	ownerRefs := make([]metav1.OwnerReference, 0, len(spec.ownerReferences))
+	for _, ref := range spec.ownerReferences {
+		ownerRefs = append(ownerRefs, *ref.DeepCopy())
 	}
 	placeholderPod := &v1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      placeholderName,
-			Namespace: app.tags[constants.AppTagNamespace],
-			Labels: utils.MergeMaps(taskGroup.Labels, map[string]string{
-				constants.LabelApplicationID:   app.GetApplicationID(),
-				constants.LabelQueueName:       app.GetQueue(),
-				constants.LabelPlaceholderFlag: "true",
-			}),
+			Namespace: spec.namespace,
+			Labels: utils.MergeMaps(utils.MergeMaps(taskGroup.Labels, slot),
+				utils.GetPlaceholderLabels(spec.appID, spec.queue, taskGroup.Name)),
 			Annotations: utils.MergeMaps(taskGroup.Annotations, map[string]string{
 				constants.AnnotationPlaceholderFlag: "true",
 				constants.AnnotationTaskGroupName:   taskGroup.Name,
+				constants.AnnotationTaskGroupIndex:  strconv.Itoa(int(index)),
 			}),
 			OwnerReferences: ownerRefs,
 		},
@@ -82,24 +98,83 @@ func newPlaceholder(placeholderName string, app *Application, taskGroup v1alpha1
 					Image: constants.PlaceholderContainerImage,
 					Resources: v1.ResourceRequirements{
 						Requests: utils.GetPlaceholderResourceRequest(taskGroup.MinResource),
+						Limits:   utils.GetPlaceholderResourceLimits(taskGroup.MinResource),
 					},
 				},
 			},
-			RestartPolicy: constants.PlaceholderPodRestartPolicy,
-			SchedulerName: constants.SchedulerName,
-			NodeSelector:  taskGroup.NodeSelector,
-			Tolerations:   taskGroup.Tolerations,
+			RestartPolicy:     constants.PlaceholderPodRestartPolicy,
+			SchedulerName:     constants.SchedulerName,
+			NodeSelector:      taskGroup.NodeSelector,
+			Tolerations:       taskGroup.Tolerations,
+			Affinity:          affinity,
+			RuntimeClassName:  runtimeClassName,
+			PriorityClassName: taskGroup.PriorityClassName,
 		},
 	}
 
 	return &Placeholder{
-		appID:         app.GetApplicationID(),
+		appID:         spec.appID,
 		taskGroupName: taskGroup.Name,
 		pod:           placeholderPod,
 	}
 }
 
+// getPlaceholderOwnerReferences returns the owner references of the placeholders of an app owned by the given objects.
+// The placeholders are owned by the workload the app originates from: the controller of the pods,
+// e.g. the Job or the SparkApplication, or all the owners of the pods when they have no controller.
+// Deleting the workload then garbage collects the placeholders, even when the scheduler is down.
+// The controller field is set to false, because since we don't know what exactly the controller will do,
+// we might have some unexpected behaviour.
+// For example if it is a replication controller, some pods (placeholders and/or real pods) might be deleted
+// in order to meet the requested replication factor.
+// Since we need the owner reference only for having the placeholders garbage collected,
+// we can just set the controller field = false, so we can avoid any kind of side effects.
+func getPlaceholderOwnerReferences(appOwners []metav1.OwnerReference) []metav1.OwnerReference {
+	owners := appOwners
+	for _, ref := range appOwners {
+		if ref.Controller != nil && *ref.Controller {
+			owners = []metav1.OwnerReference{ref}
+			break
+		}
+	}
+	ownerRefs := make([]metav1.OwnerReference, 0, len(owners))
+	for _, ref := range owners {
+		ownerRef := *ref.DeepCopy()
+		controller := false
+		ownerRef.Controller = &controller
+		ownerRefs = append(ownerRefs, ownerRef)
+	}
+	return ownerRefs
+}
+
 func (p *Placeholder) String() string {
 	return fmt.Sprintf("appID: %s, taskGroup: %s, podName: %s/%s",
 		p.appID, p.taskGroupName, p.pod.Namespace, p.pod.Name)
 }
+
+// excludePlaceholderNode keeps the placeholder pod off the given node with a required node affinity,
+// the node is excluded from every term as the terms of a node selector are ORed
+func excludePlaceholderNode(pod *v1.Pod, nodeName string) {
+	exclude := v1.NodeSelectorRequirement{
+		Key:      v1.LabelHostname,
+		Operator: v1.NodeSelectorOpNotIn,
+		Values:   []string{nodeName},
+	}
+	if pod.Spec.Affinity == nil {
+		pod.Spec.Affinity = &v1.Affinity{}
+	}
+	if pod.Spec.Affinity.NodeAffinity == nil {
+		pod.Spec.Affinity.NodeAffinity = &v1.NodeAffinity{}
+	}
+	nodeAffinity := pod.Spec.Affinity.NodeAffinity
+	if nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &v1.NodeSelector{}
+	}
+	selector := nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if len(selector.NodeSelectorTerms) == 0 {
+		selector.NodeSelectorTerms = []v1.NodeSelectorTerm{{}}
+	}
+	for i := range selector.NodeSelectorTerms {
+		selector.NodeSelectorTerms[i].MatchExpressions = append(selector.NodeSelectorTerms[i].MatchExpressions, exclude)
+	}
+}
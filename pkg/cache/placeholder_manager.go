@@ -26,10 +26,17 @@ import (
 
 	"go.uber.org/zap"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
 
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/apis/yunikorn.apache.org/v1alpha1"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/metrics"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
 
 // PlaceholderManager is a service to manage the lifecycle of app placeholders
@@ -40,75 +47,195 @@ type PlaceholderManager struct {
 	// this pod becomes to be an "orphan" pod. We add them to a map
 	// and keep retrying deleting them in order to avoid wasting resources.
 	orphanPods map[string]*v1.Pod
-	stopChan   chan struct{}
-	running    atomic.Value
+	// the janitor deletes the placeholder pods of the apps unknown to the shim,
+	// e.g. the pods left behind by a crash, it is disabled until the app lookup is set
+	appLookup      func(appID string) bool
+	lastJanitorRun time.Time
+	stopChan       chan struct{}
+	running        atomic.Value
 	// a simple mutex will do we do not have separate read and write paths
 	sync.Mutex
 }
 
-var placeholderMgr *PlaceholderManager
+// the placeholder manager is shared with the apps, it is read by the goroutines the apps start
+var (
+	placeholderMgr     *PlaceholderManager
+	placeholderMgrLock sync.RWMutex
+)
 
 func NewPlaceholderManager(clients *client.Clients) *PlaceholderManager {
 	var r atomic.Value
 	r.Store(false)
-	placeholderMgr = &PlaceholderManager{
+	mgr := &PlaceholderManager{
 		clients:    clients,
 		running:    r,
 		orphanPods: make(map[string]*v1.Pod),
 		stopChan:   make(chan struct{}),
 	}
-	return placeholderMgr
+	placeholderMgrLock.Lock()
+	defer placeholderMgrLock.Unlock()
+	placeholderMgr = mgr
+	return mgr
 }
 
 func getPlaceholderManager() *PlaceholderManager {
+	placeholderMgrLock.RLock()
+	defer placeholderMgrLock.RUnlock()
 	return placeholderMgr
 }
 
+// createAppPlaceholders creates the placeholders for all the min members of the app task groups,
+// the placeholders are created by a pool of workers and the progress of each task group is tracked
+// in the app. The first creation error is returned once all the workers are done, with the "All"
+// rollback policy the remaining placeholders are not created after the first failure.
+// The manager lock is not held while creating the pods, this does not block the cleanup of other apps.
 func (mgr *PlaceholderManager) createAppPlaceholders(app *Application) error {
-	mgr.Lock()
-	defer mgr.Unlock()
+	taskGroups := app.getTaskGroups()
+	progress := newPlaceholderProgress(taskGroups)
+	app.setPlaceholderProgress(progress)
+	return mgr.createPlaceholders(app, taskGroups, progress)
+}
+
+// createTaskGroupPlaceholders creates the placeholders for a stage of the app task groups,
+// the progress of the stage is added to the progress of the stages reserved before.
+func (mgr *PlaceholderManager) createTaskGroupPlaceholders(app *Application, taskGroups []v1alpha1.TaskGroup) error {
+	progress := app.addPlaceholderProgress(taskGroups)
+	return mgr.createPlaceholders(app, taskGroups, progress)
+}
+
+func (mgr *PlaceholderManager) createPlaceholders(app *Application, taskGroups []v1alpha1.TaskGroup, progress *placeholderProgress) error {
+	stopOnFailure := mgr.clients.Conf.PlaceholderRollbackPolicy != conf.PlaceholderRollbackTaskGroup
+	spec := app.getPlaceholderSpec()
+	app.logger().Info("creating placeholders",
+		zap.Int("taskGroups", len(taskGroups)),
+		zap.Any("placeholderAsk", getResourceMap(spec.ask)),
+		zap.Int64("placeholderTimeoutInSec", spec.timeoutInSec))
+
+	var createErr error
+	var errLock sync.RWMutex
+	var wg sync.WaitGroup
+	placeholders := make(chan *Placeholder)
+	for i := 0; i < mgr.getWorkers(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for placeholder := range placeholders {
+				if err := mgr.createPlaceholder(app, placeholder, progress); err != nil {
+					// only the first error is kept
+					errLock.Lock()
+					if createErr == nil {
+						createErr = err
+					}
+					errLock.Unlock()
+				}
+			}
+		}()
+	}
 
 	// iterate all task groups, create placeholders for all the min members
-	for _, tg := range app.getTaskGroups() {
+produce:
+	for _, tg := range taskGroups {
 		for i := int32(0); i < tg.MinMember; i++ {
-			placeholderName := utils.GeneratePlaceholderName(tg.Name, app.GetApplicationID(), i)
-			placeholder := newPlaceholder(placeholderName, app, tg)
-			// create the placeholder on K8s
-			_, err := mgr.clients.KubeClient.Create(placeholder.pod)
-			if err != nil {
-				log.Logger().Error("failed to create placeholder pod",
-					zap.Error(err))
-				return err
+			errLock.RLock()
+			failed := createErr != nil
+			errLock.RUnlock()
+			if stopOnFailure && failed {
+				break produce
 			}
-			log.Logger().Info("placeholder created",
-				zap.String("placeholder", placeholder.String()))
+			placeholderName := utils.GeneratePlaceholderName(tg.Name, spec.appID, i)
+			placeholders <- newPlaceholder(placeholderName, spec, tg, i)
 		}
 	}
+	close(placeholders)
+	wg.Wait()
+
+	return createErr
+}
+
+// createPlaceholder creates the placeholder on K8s and updates the progress of its task group,
+// the app is notified once all the placeholders of the task group have been attempted.
+func (mgr *PlaceholderManager) createPlaceholder(app *Application, placeholder *Placeholder, progress *placeholderProgress) error {
+	var tgProgress taskGroupProgress
+	_, err := mgr.clients.KubeClient.Create(placeholder.pod)
+	// the placeholder can exist already when the app is reserving again after a restart
+	if apierrors.IsAlreadyExists(err) {
+		app.logger().Info("placeholder already exists",
+			zap.String("placeholder", placeholder.String()))
+		err = nil
+	}
+	if err != nil {
+		app.logger().Error("failed to create placeholder pod",
+			zap.String("placeholder", placeholder.String()),
+			zap.Error(err))
+		tgProgress = progress.onFailed(placeholder.taskGroupName)
+	} else {
+		app.logger().Info("placeholder created",
+			zap.String("placeholder", placeholder.String()))
+		tgProgress = progress.onCreated(placeholder.taskGroupName)
+	}
+	if tgProgress.done() {
+		app.publishPlaceholderProgress(placeholder.taskGroupName, tgProgress)
+	}
+	return err
+}
 
-	return nil
+func (mgr *PlaceholderManager) getWorkers() int {
+	if workers := mgr.clients.Conf.PlaceholderWorkers; workers > 0 {
+		return workers
+	}
+	return conf.DefaultPlaceholderWorkers
 }
 
 // clean up all the placeholders for an application
 func (mgr *PlaceholderManager) cleanUp(app *Application) {
 	mgr.Lock()
 	defer mgr.Unlock()
-	log.Logger().Info("start to clean up app placeholders",
-		zap.String("appID", app.GetApplicationID()))
-	for taskID, task := range app.taskMap {
-		if task.IsPlaceholder() {
-			// remove pod
-			err := mgr.clients.KubeClient.Delete(task.pod)
-			if err != nil {
-				log.Logger().Warn("failed to clean up placeholder pod",
-					zap.Error(err))
-				if !strings.Contains(err.Error(), "not found") {
-					mgr.orphanPods[taskID] = task.pod
-				}
-			}
-		}
+	app.logger().Info("start to clean up app placeholders")
+	for _, task := range app.getPlaceholderTasks("") {
+		task.setTaskTerminationType(si.TerminationType_name[int32(si.TerminationType_STOPPED_BY_RM)])
+		mgr.deletePlaceholder(task.taskID, task.GetTaskPod())
+	}
+	app.logger().Info("finished cleaning up app placeholders")
+}
+
+// clean up the placeholders of a single task group of an application
+func (mgr *PlaceholderManager) cleanUpTaskGroup(app *Application, taskGroupName string) {
+	mgr.Lock()
+	defer mgr.Unlock()
+	app.logger().Info("start to clean up task group placeholders",
+		zap.String("taskGroup", taskGroupName))
+	for _, task := range app.getPlaceholderTasks(taskGroupName) {
+		task.setTaskTerminationType(si.TerminationType_name[int32(si.TerminationType_STOPPED_BY_RM)])
+		mgr.deletePlaceholder(task.taskID, task.GetTaskPod())
 	}
-	log.Logger().Info("finished cleaning up app placeholders",
-		zap.String("appID", app.GetApplicationID()))
+	app.logger().Info("finished cleaning up task group placeholders",
+		zap.String("taskGroup", taskGroupName))
+}
+
+// delete the placeholder pod, the caller must hold the lock
+func (mgr *PlaceholderManager) deletePlaceholder(taskID string, pod *v1.Pod) {
+	err := mgr.clients.KubeClient.Delete(pod)
+	if err != nil {
+		log.Logger().Warn("failed to clean up placeholder pod",
+			zap.Error(err))
+		mgr.addOrphanPlaceholder(taskID, pod, err)
+	}
+}
+
+// addOrphanPlaceholder keeps a placeholder that failed to be deleted, the deletion is retried later.
+// The caller must hold the manager lock.
+func (mgr *PlaceholderManager) addOrphanPlaceholder(taskID string, pod *v1.Pod, err error) {
+	if strings.Contains(err.Error(), "not found") {
+		return
+	}
+	if _, ok := mgr.orphanPods[taskID]; !ok {
+		// the placeholder pod only carries the queue of the app, the user is not known here
+		metrics.GetPlaceholderMetrics().IncPlaceholderOrphaned(metrics.Context{
+			Queue:     utils.GetQueueNameFromPod(pod),
+			TaskGroup: utils.GetTaskGroupFromPodSpec(pod),
+		})
+	}
+	mgr.orphanPods[taskID] = pod
 }
 
 func (mgr *PlaceholderManager) cleanOrphanPlaceholders() {
@@ -127,6 +254,70 @@ func (mgr *PlaceholderManager) cleanOrphanPlaceholders() {
 	}
 }
 
+// SetApplicationLookup sets the function the janitor uses to check if an app is known by the shim
+func (mgr *PlaceholderManager) SetApplicationLookup(appLookup func(appID string) bool) {
+	mgr.Lock()
+	defer mgr.Unlock()
+	mgr.appLookup = appLookup
+}
+
+func (mgr *PlaceholderManager) getApplicationLookup() func(appID string) bool {
+	mgr.Lock()
+	defer mgr.Unlock()
+	return mgr.appLookup
+}
+
+// runJanitor cleans the leaked placeholders when the janitor interval has elapsed since the last run
+func (mgr *PlaceholderManager) runJanitor() {
+	interval := mgr.clients.Conf.PlaceholderJanitorInterval
+	if interval <= 0 || time.Since(mgr.lastJanitorRun) < interval {
+		return
+	}
+	mgr.lastJanitorRun = time.Now()
+	mgr.cleanLeakedPlaceholders()
+}
+
+// cleanLeakedPlaceholders deletes the placeholder pods whose app is not known by the shim, these are left
+// behind when the shim restarts before the placeholders of a finished app are deleted. The pods are listed
+// from the informer cache, in dry-run mode they are only logged. The leaked pods are returned.
+func (mgr *PlaceholderManager) cleanLeakedPlaceholders() []*v1.Pod {
+	appLookup := mgr.getApplicationLookup()
+	if appLookup == nil {
+		return nil
+	}
+	selector := labels.SelectorFromSet(labels.Set{constants.LabelPlaceholderFlag: "true"})
+	pods, err := mgr.clients.PodInformer.Lister().List(selector)
+	if err != nil {
+		log.Logger().Warn("placeholder janitor failed to list the placeholder pods", zap.Error(err))
+		return nil
+	}
+	dryRun := mgr.clients.Conf.PlaceholderJanitorDryRun
+	leaked := make([]*v1.Pod, 0)
+	for _, pod := range pods {
+		// skip the pods of other schedulers and the pods already being deleted
+		if pod.Spec.SchedulerName != constants.SchedulerName || pod.DeletionTimestamp != nil {
+			continue
+		}
+		appID, taskGroupName, _, _ := utils.GetPlaceholderOwner(pod)
+		if appLookup(appID) {
+			continue
+		}
+		leaked = append(leaked, pod)
+		log.Logger().Info("placeholder janitor found a leaked placeholder",
+			zap.String("appID", appID),
+			zap.String("taskGroup", taskGroupName),
+			zap.String("namespace", pod.Namespace),
+			zap.String("podName", pod.Name),
+			zap.Bool("dryRun", dryRun))
+		if !dryRun {
+			mgr.Lock()
+			mgr.deletePlaceholder(string(pod.UID), pod)
+			mgr.Unlock()
+		}
+	}
+	return leaked
+}
+
 func (mgr *PlaceholderManager) Start() {
 	if mgr.isRunning() {
 		log.Logger().Info("PlaceholderManager is already started")
@@ -134,10 +325,13 @@ func (mgr *PlaceholderManager) Start() {
 	}
 	log.Logger().Info("starting the PlaceholderManager")
 	mgr.setRunning(true)
+	// the first janitor run happens one interval after the start
+	mgr.lastJanitorRun = time.Now()
 	go func() {
 		// clean orphan placeholders approximately every 5 seconds, check for stop every 100 milliseconds
 		for {
 			mgr.cleanOrphanPlaceholders()
+			mgr.runJanitor()
 			for i := 0; i < 50; i++ {
 				select {
 				case <-mgr.stopChan:
@@ -20,18 +20,28 @@ package cache
 
 import (
 	"fmt"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 
 	"gotest.tools/assert"
 	is "gotest.tools/assert/cmp"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/apis/yunikorn.apache.org/v1alpha1"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/test"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/metrics"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
 
 const (
@@ -64,13 +74,71 @@ func TestCreateAppPlaceholders(t *testing.T) {
 	// simulate placeholder creation failures
 	// failed to create one placeholder
 	mockedAPIProvider.MockCreateFn(func(pod *v1.Pod) (*v1.Pod, error) {
-		if pod.Name == "tg-test-group-2-app01-15" {
+		if pod.Name == utils.GeneratePlaceholderName("test-group-2", "app01", 15) {
 			return nil, fmt.Errorf("failed to create pod %s", pod.Name)
 		}
 		return pod, nil
 	})
 	err := placeholderMgr.createAppPlaceholders(app)
-	assert.Error(t, err, "failed to create pod tg-test-group-2-app01-15")
+	assert.Error(t, err, "failed to create pod "+utils.GeneratePlaceholderName("test-group-2", "app01", 15))
+}
+
+func TestCreateAppPlaceholdersAlreadyExist(t *testing.T) {
+	app := createAppWIthTaskGroupForTest()
+	mockedAPIProvider := client.NewMockedAPIProvider()
+	// placeholders created before a restart are reused
+	mockedAPIProvider.MockCreateFn(func(pod *v1.Pod) (*v1.Pod, error) {
+		if pod.Name == utils.GeneratePlaceholderName("test-group-2", "app01", 15) {
+			return nil, apierrors.NewAlreadyExists(v1.Resource("pods"), pod.Name)
+		}
+		return pod, nil
+	})
+	placeholderMgr := NewPlaceholderManager(mockedAPIProvider.GetAPIs())
+	err := placeholderMgr.createAppPlaceholders(app)
+	assert.NilError(t, err)
+	progress, ok := app.getPlaceholderProgress().get("test-group-2")
+	assert.Assert(t, ok)
+	assert.Equal(t, progress.failed, int32(0))
+	assert.Equal(t, progress.created, progress.desired)
+}
+
+func TestCreateAppPlaceholdersProgress(t *testing.T) {
+	app := createAppWIthTaskGroupForTest()
+	mockedAPIProvider := client.NewMockedAPIProvider()
+	mockedAPIProvider.GetAPIs().Conf.PlaceholderWorkers = 4
+	mockedAPIProvider.GetAPIs().Conf.PlaceholderRollbackPolicy = conf.PlaceholderRollbackTaskGroup
+	createdPods := make(map[string]*v1.Pod)
+	mockedAPIProvider.MockCreateFn(func(pod *v1.Pod) (*v1.Pod, error) {
+		if pod.Name == utils.GeneratePlaceholderName("test-group-2", "app01", 15) {
+			return nil, fmt.Errorf("failed to create pod %s", pod.Name)
+		}
+		createdPods[pod.Name] = pod
+		return pod, nil
+	})
+	placeholderMgr := NewPlaceholderManager(mockedAPIProvider.GetAPIs())
+
+	// with the task group rollback policy, the remaining placeholders are still created
+	err := placeholderMgr.createAppPlaceholders(app)
+	assert.Error(t, err, "failed to create pod "+utils.GeneratePlaceholderName("test-group-2", "app01", 15))
+	assert.Equal(t, len(createdPods), 29)
+
+	progress := app.getPlaceholderProgress()
+	tg1, ok := progress.get("test-group-1")
+	assert.Assert(t, ok)
+	assert.Equal(t, tg1, taskGroupProgress{desired: 10, created: 10, failed: 0})
+	tg2, ok := progress.get("test-group-2")
+	assert.Assert(t, ok)
+	assert.Equal(t, tg2, taskGroupProgress{desired: 20, created: 19, failed: 1})
+	assert.Assert(t, tg2.done())
+	assert.DeepEqual(t, progress.failedTaskGroups(), []string{"test-group-2"})
+
+	// the progress is surfaced in the app info
+	appInfo := app.getApplicationInfo()
+	assert.Equal(t, len(appInfo.TaskGroups), 2)
+	for _, tg := range appInfo.TaskGroups {
+		assert.Assert(t, tg.Progress != nil)
+		assert.Equal(t, tg.Progress.Desired, tg.MinMember)
+	}
 }
 
 func createAndCheckPlaceholderCreate(mockedAPIProvider *client.MockedAPIProvider, app *Application, t *testing.T) map[string]*v1.Pod {
@@ -136,7 +204,7 @@ func TestCleanUp(t *testing.T) {
 	mockedSchedulerAPI := newMockSchedulerAPI()
 	app := NewApplication(appID, queue,
 		"bob", map[string]string{constants.AppTagNamespace: namespace}, mockedSchedulerAPI)
-	mockedContext.applications[appID] = app
+	mockedContext.applications.put(app)
 	res := app.getNonTerminatedTaskAlias()
 	assert.Equal(t, len(res), 0)
 
@@ -205,6 +273,159 @@ func TestCleanUp(t *testing.T) {
 	}
 	assert.Equal(t, exist, false)
 	assert.Equal(t, len(placeholderMgr.orphanPods), 0)
+	// placeholders deleted by the shim are not seen as preempted
+	assert.Equal(t, task1.terminationType, "STOPPED_BY_RM")
+	assert.Equal(t, task2.terminationType, "STOPPED_BY_RM")
+	assert.Equal(t, task3.terminationType, "")
+}
+
+func TestCleanUpTaskGroupWhileAddingTasks(t *testing.T) {
+	mockedContext := initContextForTest()
+	app := NewApplication(appID, queue,
+		"bob", map[string]string{constants.AppTagNamespace: namespace}, newMockSchedulerAPI())
+	newPlaceholder := func(i int, taskGroup string) *Task {
+		task := NewTask(fmt.Sprintf("task-%d", i), app, mockedContext, &v1.Pod{
+			ObjectMeta: apis.ObjectMeta{
+				Name:        fmt.Sprintf("pod-%d", i),
+				UID:         types.UID(fmt.Sprintf("UID-%d", i)),
+				Annotations: map[string]string{constants.AnnotationTaskGroupName: taskGroup},
+			},
+		})
+		task.placeholder = true
+		task.taskGroupName = taskGroup
+		return task
+	}
+	for i := 0; i < 3; i++ {
+		app.addTask(newPlaceholder(i, "tg-1"))
+	}
+	app.addTask(newPlaceholder(3, "tg-2"))
+
+	var deleted sync.Map
+	mockedAPIProvider := client.NewMockedAPIProvider()
+	mockedAPIProvider.MockDeleteFn(func(pod *v1.Pod) error {
+		deleted.Store(pod.Name, true)
+		return nil
+	})
+	placeholderMgr := NewPlaceholderManager(mockedAPIProvider.GetAPIs())
+	// the task map changes while the placeholders are cleaned up, the race detector must not report it
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 10; i < 210; i++ {
+			app.addTask(newPlaceholder(i, "tg-3"))
+		}
+	}()
+	for cleaning := true; cleaning; {
+		select {
+		case <-done:
+			cleaning = false
+		default:
+			placeholderMgr.cleanUpTaskGroup(app, "tg-1")
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		_, ok := deleted.Load(fmt.Sprintf("pod-%d", i))
+		assert.Assert(t, ok, "placeholder %d of the task group is not deleted", i)
+	}
+	_, ok := deleted.Load("pod-3")
+	assert.Assert(t, !ok, "placeholder of another task group is deleted")
+}
+
+func TestPlaceholderPreempted(t *testing.T) {
+	app := createAppWIthTaskGroupForTest()
+	mockedAPIProvider := client.NewMockedAPIProvider()
+	createdPods := createAndCheckPlaceholderCreate(mockedAPIProvider, app, t)
+	app.sm.SetState(events.States().Application.Reserving)
+
+	// the preempted placeholder is created again while the app is reserving
+	delete(createdPods, utils.GeneratePlaceholderName("test-group-1", "app01", 3))
+	before := metrics.GetPlaceholderMetrics().GetPlaceholderCounts("test-group-1").Preempted
+	app.onPlaceholderPreempted("test-group-1", 3)
+	assert.Equal(t, metrics.GetPlaceholderMetrics().GetPlaceholderCounts("test-group-1").Preempted, before+1)
+	pod, ok := createdPods[utils.GeneratePlaceholderName("test-group-1", "app01", 3)]
+	assert.Assert(t, ok, "preempted placeholder is not re-created")
+	assert.Equal(t, pod.Annotations[constants.AnnotationTaskGroupName], "test-group-1")
+	progress, ok := app.getPlaceholderProgress().get("test-group-1")
+	assert.Assert(t, ok)
+	assert.Equal(t, progress, taskGroupProgress{desired: 10, created: 10, preempted: 1})
+
+	// once the app is running only the reservation is rolled back
+	app.sm.SetState(events.States().Application.Running)
+	delete(createdPods, utils.GeneratePlaceholderName("test-group-2", "app01", 0))
+	app.onPlaceholderPreempted("test-group-2", 0)
+	_, ok = createdPods[utils.GeneratePlaceholderName("test-group-2", "app01", 0)]
+	assert.Assert(t, !ok, "placeholder should not be re-created for a running app")
+	progress, ok = app.getPlaceholderProgress().get("test-group-2")
+	assert.Assert(t, ok)
+	assert.Equal(t, progress, taskGroupProgress{desired: 20, created: 19, preempted: 1})
+
+	// placeholders of a killed app are not preempted
+	app.sm.SetState(events.States().Application.Killing)
+	app.onPlaceholderPreempted("test-group-2", 1)
+	progress, ok = app.getPlaceholderProgress().get("test-group-2")
+	assert.Assert(t, ok)
+	assert.Equal(t, progress.preempted, int32(1))
+}
+
+func TestPlaceholderRestored(t *testing.T) {
+	conf.GetSchedulerConf().SetTestMode(true)
+	app := createAppWIthTaskGroupForTest()
+	mockedAPIProvider := client.NewMockedAPIProvider()
+	mockedAPIProvider.GetAPIs().Conf.PlaceholderRestoreWindow = time.Minute
+	createdPods := createAndCheckPlaceholderCreate(mockedAPIProvider, app, t)
+	app.sm.SetState(events.States().Application.Running)
+	context := NewContext(mockedAPIProvider)
+
+	// the real member replaced the placeholder with index 3 and its pod failed right after
+	pod := &v1.Pod{
+		ObjectMeta: apis.ObjectMeta{
+			Name:      "member-3",
+			Namespace: namespace,
+			UID:       "UID-member-3",
+			Annotations: map[string]string{
+				constants.AnnotationTaskGroupName:  "test-group-1",
+				constants.AnnotationTaskGroupIndex: "3",
+			},
+		},
+	}
+	failedPod := pod.DeepCopy()
+	failedPod.Status.Phase = v1.PodFailed
+	podLister := test.NewPodListerMock()
+	podLister.AddPod(failedPod)
+	mockedAPIProvider.SetPodLister(podLister)
+	member := NewTask("UID-member-3", app, context, pod)
+	member.boundTime = time.Now()
+	member.replacedPlaceholderBoundTime = member.boundTime.Add(-time.Second)
+	assert.Assert(t, member.isFailedReplacement())
+
+	// a member released by the shim or the scheduler, or failed after the window, keeps no reservation
+	member.terminationType = si.TerminationType_name[int32(si.TerminationType_PREEMPTED_BY_SCHEDULER)]
+	assert.Assert(t, !member.isFailedReplacement())
+	member.terminationType = ""
+	member.boundTime = time.Now().Add(-2 * time.Minute)
+	assert.Assert(t, !member.isFailedReplacement())
+	member.boundTime = time.Now()
+	mockedAPIProvider.GetAPIs().Conf.PlaceholderRestoreWindow = 0
+	assert.Assert(t, !member.isFailedReplacement())
+	mockedAPIProvider.GetAPIs().Conf.PlaceholderRestoreWindow = time.Minute
+
+	delete(createdPods, utils.GeneratePlaceholderName("test-group-1", "app01", 3))
+	before := metrics.GetPlaceholderMetrics().GetPlaceholderCounts("test-group-1").Restored
+	app.restorePlaceholder("test-group-1", 3, member.alias)
+	assert.Equal(t, metrics.GetPlaceholderMetrics().GetPlaceholderCounts("test-group-1").Restored, before+1)
+	_, ok := createdPods[utils.GeneratePlaceholderName("test-group-1", "app01", 3)]
+	assert.Assert(t, ok, "placeholder is not restored")
+	progress, ok := app.getPlaceholderProgress().get("test-group-1")
+	assert.Assert(t, ok)
+	assert.Equal(t, progress, taskGroupProgress{desired: 10, created: 10, restored: 1})
+
+	// nothing is restored once the app is no longer running
+	app.sm.SetState(events.States().Application.Killing)
+	delete(createdPods, utils.GeneratePlaceholderName("test-group-1", "app01", 3))
+	app.restorePlaceholder("test-group-1", 3, member.alias)
+	_, ok = createdPods[utils.GeneratePlaceholderName("test-group-1", "app01", 3)]
+	assert.Assert(t, !ok, "placeholder should not be restored for a killed app")
 }
 
 func TestCleanOrphanPlaceholders(t *testing.T) {
@@ -226,6 +447,28 @@ func TestCleanOrphanPlaceholders(t *testing.T) {
 	assert.Equal(t, len(placeholderMgr.orphanPods), 0)
 }
 
+func TestDeletePlaceholderOrphaned(t *testing.T) {
+	mockedAPIProvider := client.NewMockedAPIProvider()
+	mockedAPIProvider.MockDeleteFn(func(pod *v1.Pod) error {
+		return fmt.Errorf("connection refused")
+	})
+	placeholderMgr := NewPlaceholderManager(mockedAPIProvider.GetAPIs())
+	// the metrics are global, use a task group name no other test uses
+	taskGroup := "tg-orphan-metrics"
+	pod := &v1.Pod{
+		ObjectMeta: apis.ObjectMeta{
+			Name:        "ph-01",
+			UID:         "UID-01",
+			Annotations: map[string]string{constants.AnnotationTaskGroupName: taskGroup},
+		},
+	}
+	// a retry of the same placeholder is counted once
+	placeholderMgr.deletePlaceholder("ph-01", pod)
+	placeholderMgr.deletePlaceholder("ph-01", pod)
+	assert.Equal(t, len(placeholderMgr.orphanPods), 1)
+	assert.Equal(t, metrics.GetPlaceholderMetrics().GetPlaceholderCounts(taskGroup).Orphaned, 1)
+}
+
 func TestPlaceholderManagerStartStop(t *testing.T) {
 	mockedAPIProvider := client.NewMockedAPIProvider()
 	mgr := NewPlaceholderManager(mockedAPIProvider.GetAPIs())
@@ -261,3 +504,59 @@ func TestPlaceholderManagerStartStop(t *testing.T) {
 	time.Sleep(5 * time.Millisecond)
 	assert.Equal(t, mgr.isRunning(), false, "placeholder manager has not stopped")
 }
+
+func TestCleanLeakedPlaceholders(t *testing.T) {
+	mockedAPIProvider := client.NewMockedAPIProvider()
+	deleted := make([]string, 0)
+	mockedAPIProvider.MockDeleteFn(func(pod *v1.Pod) error {
+		deleted = append(deleted, pod.Name)
+		return nil
+	})
+	newPod := func(name, appID, schedulerName string, placeholder bool) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: apis.ObjectMeta{
+				Name: name,
+				UID:  types.UID("UID-" + name),
+				Labels: map[string]string{
+					constants.LabelApplicationID:   appID,
+					constants.LabelPlaceholderFlag: strconv.FormatBool(placeholder),
+				},
+			},
+			Spec: v1.PodSpec{
+				SchedulerName: schedulerName,
+			},
+		}
+	}
+	podLister := test.NewPodListerMock()
+	podLister.AddPod(newPod("ph-known", "app-known", constants.SchedulerName, true))
+	podLister.AddPod(newPod("ph-leaked", "app-gone", constants.SchedulerName, true))
+	podLister.AddPod(newPod("ph-other-scheduler", "app-gone", "default-scheduler", true))
+	podLister.AddPod(newPod("task-gone", "app-gone", constants.SchedulerName, false))
+	mockedAPIProvider.SetPodLister(podLister)
+	placeholderMgr := NewPlaceholderManager(mockedAPIProvider.GetAPIs())
+
+	// the janitor does nothing until the apps are known
+	assert.Equal(t, len(placeholderMgr.cleanLeakedPlaceholders()), 0)
+
+	placeholderMgr.SetApplicationLookup(func(appID string) bool {
+		return appID == "app-known"
+	})
+	mockedAPIProvider.GetAPIs().Conf.PlaceholderJanitorDryRun = true
+	leaked := placeholderMgr.cleanLeakedPlaceholders()
+	assert.Equal(t, len(leaked), 1)
+	assert.Equal(t, leaked[0].Name, "ph-leaked")
+	assert.Equal(t, len(deleted), 0)
+
+	mockedAPIProvider.GetAPIs().Conf.PlaceholderJanitorDryRun = false
+	leaked = placeholderMgr.cleanLeakedPlaceholders()
+	assert.Equal(t, len(leaked), 1)
+	assert.DeepEqual(t, deleted, []string{"ph-leaked"})
+
+	// the janitor runs once per interval
+	deleted = deleted[:0]
+	mockedAPIProvider.GetAPIs().Conf.PlaceholderJanitorInterval = time.Hour
+	placeholderMgr.lastJanitorRun = time.Now().Add(-2 * time.Hour)
+	placeholderMgr.runJanitor()
+	placeholderMgr.runJanitor()
+	assert.DeepEqual(t, deleted, []string{"ph-leaked"})
+}
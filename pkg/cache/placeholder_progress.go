@@ -0,0 +1,186 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/apis/yunikorn.apache.org/v1alpha1"
+)
+
+// taskGroupProgress is the placeholder creation progress of a single task group
+type taskGroupProgress struct {
+	desired   int32
+	created   int32
+	failed    int32
+	preempted int32
+	restored  int32
+	// placeholders replaced by the administrator
+	forceReplaced int32
+	// placeholders replaced by the real members of the task group
+	replaced int32
+	timedOut int32
+}
+
+// done returns true when every placeholder of the task group has been attempted
+func (p taskGroupProgress) done() bool {
+	return p.created+p.failed >= p.desired
+}
+
+// placeholderProgress tracks the placeholder creation of all task groups of an app,
+// it is updated by the placeholder creation workers without holding the app lock,
+// so it is protected by its own lock.
+type placeholderProgress struct {
+	groups map[string]*taskGroupProgress
+	sync.RWMutex
+}
+
+func newPlaceholderProgress(taskGroups []v1alpha1.TaskGroup) *placeholderProgress {
+	groups := make(map[string]*taskGroupProgress, len(taskGroups))
+	for _, tg := range taskGroups {
+		groups[tg.Name] = &taskGroupProgress{
+			desired: tg.MinMember,
+		}
+	}
+	return &placeholderProgress{
+		groups: groups,
+	}
+}
+
+// add starts tracking the task groups that are reserved in a later stage,
+// the progress of a task group that is already tracked is kept
+func (p *placeholderProgress) add(taskGroups []v1alpha1.TaskGroup) {
+	p.Lock()
+	defer p.Unlock()
+	for _, tg := range taskGroups {
+		if _, ok := p.groups[tg.Name]; !ok {
+			p.groups[tg.Name] = &taskGroupProgress{
+				desired: tg.MinMember,
+			}
+		}
+	}
+}
+
+// onCreated records a created placeholder and returns the updated progress of the task group
+func (p *placeholderProgress) onCreated(taskGroupName string) taskGroupProgress {
+	p.Lock()
+	defer p.Unlock()
+	if progress, ok := p.groups[taskGroupName]; ok {
+		progress.created++
+		return *progress
+	}
+	return taskGroupProgress{}
+}
+
+// onFailed records a placeholder that failed to be created and returns the updated progress of the task group
+func (p *placeholderProgress) onFailed(taskGroupName string) taskGroupProgress {
+	p.Lock()
+	defer p.Unlock()
+	if progress, ok := p.groups[taskGroupName]; ok {
+		progress.failed++
+		return *progress
+	}
+	return taskGroupProgress{}
+}
+
+// onPreempted rolls back the creation of a placeholder that was preempted, the placeholder is
+// counted as created again once it is re-created
+func (p *placeholderProgress) onPreempted(taskGroupName string) taskGroupProgress {
+	p.Lock()
+	defer p.Unlock()
+	if progress, ok := p.groups[taskGroupName]; ok {
+		if progress.created > 0 {
+			progress.created--
+		}
+		progress.preempted++
+		return *progress
+	}
+	return taskGroupProgress{}
+}
+
+// onRestoring rolls back the creation of a placeholder replaced by a real member that failed,
+// the placeholder is counted as created again once it is re-created
+func (p *placeholderProgress) onRestoring(taskGroupName string) taskGroupProgress {
+	p.Lock()
+	defer p.Unlock()
+	if progress, ok := p.groups[taskGroupName]; ok {
+		if progress.created > 0 {
+			progress.created--
+		}
+		progress.restored++
+		return *progress
+	}
+	return taskGroupProgress{}
+}
+
+// onForceReplacing rolls back the creation of a placeholder replaced by the administrator before it is created again
+func (p *placeholderProgress) onForceReplacing(taskGroupName string) taskGroupProgress {
+	p.Lock()
+	defer p.Unlock()
+	if progress, ok := p.groups[taskGroupName]; ok {
+		if progress.created > 0 {
+			progress.created--
+		}
+		progress.forceReplaced++
+		return *progress
+	}
+	return taskGroupProgress{}
+}
+
+// onReplaced records a placeholder replaced by a real member of the task group
+func (p *placeholderProgress) onReplaced(taskGroupName string) {
+	p.Lock()
+	defer p.Unlock()
+	if progress, ok := p.groups[taskGroupName]; ok {
+		progress.replaced++
+	}
+}
+
+// onTimedOut records a placeholder released by the core because the reservation timed out
+func (p *placeholderProgress) onTimedOut(taskGroupName string) {
+	p.Lock()
+	defer p.Unlock()
+	if progress, ok := p.groups[taskGroupName]; ok {
+		progress.timedOut++
+	}
+}
+
+func (p *placeholderProgress) get(taskGroupName string) (taskGroupProgress, bool) {
+	p.RLock()
+	defer p.RUnlock()
+	if progress, ok := p.groups[taskGroupName]; ok {
+		return *progress, true
+	}
+	return taskGroupProgress{}, false
+}
+
+// failedTaskGroups returns the sorted names of the task groups that have at least one failed placeholder
+func (p *placeholderProgress) failedTaskGroups() []string {
+	p.RLock()
+	defer p.RUnlock()
+	failed := make([]string, 0)
+	for name, progress := range p.groups {
+		if progress.failed > 0 {
+			failed = append(failed, name)
+		}
+	}
+	sort.Strings(failed)
+	return failed
+}
@@ -0,0 +1,144 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/apis/yunikorn.apache.org/v1alpha1"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/dispatcher"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+// ReplacePlaceholder deletes a placeholder of an app that is reserving and creates it again once it is gone,
+// e.g. a placeholder stuck on a bad node that blocks the gang. The rest of the reservation is kept. With
+// excludeNode set, the placeholder is created again with a node affinity that keeps it off its current node.
+func (ctx *Context) ReplacePlaceholder(appID, placeholderName string, excludeNode bool) error {
+	app := ctx.applications.get(appID)
+	if app == nil {
+		return fmt.Errorf("application %s is not found in context", appID)
+	}
+	if state := app.GetApplicationState(); state != events.States().Application.Reserving {
+		return fmt.Errorf("placeholders of application %s can only be replaced while reserving, the state is %s",
+			appID, state)
+	}
+	task := app.getPlaceholderByName(placeholderName)
+	if task == nil {
+		return fmt.Errorf("placeholder %s of application %s is not found", placeholderName, appID)
+	}
+	excludedNode := ""
+	if excludeNode {
+		if excludedNode = task.getNodeName(); excludedNode == "" {
+			return fmt.Errorf("placeholder %s is not on a node, there is no node to exclude", placeholderName)
+		}
+	}
+	if !task.markForceReplaced(excludedNode) {
+		return fmt.Errorf("placeholder %s is already being replaced", placeholderName)
+	}
+	log.Logger().Info("replacing placeholder",
+		zap.String("appID", appID),
+		zap.String("placeholder", placeholderName),
+		zap.String("excludedNode", excludedNode))
+	if err := ctx.apiProvider.GetAPIs().KubeClient.Delete(task.GetTaskPod()); err != nil {
+		task.unmarkForceReplaced()
+		return fmt.Errorf("failed to delete placeholder %s: %v", placeholderName, err)
+	}
+	app.publishAppEvent(v1.EventTypeNormal, "PlaceholderReplacing",
+		"placeholder %s is replaced by the administrator", placeholderName)
+	return nil
+}
+
+// getPlaceholderByName returns the placeholder of the app with the given pod name, nil if it is not found or terminated
+func (app *Application) getPlaceholderByName(placeholderName string) *Task {
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	for _, task := range app.taskMap {
+		if task.placeholder && task.pod.Name == placeholderName && !task.isTerminated() {
+			return task
+		}
+	}
+	return nil
+}
+
+// markForceReplaced marks the placeholder to be created again once its pod is deleted, the allocation of the
+// placeholder is released as stopped by the shim. Returns false if the placeholder is being replaced already.
+func (task *Task) markForceReplaced(excludedNode string) bool {
+	task.lock.Lock()
+	defer task.lock.Unlock()
+	if task.forceReplaced {
+		return false
+	}
+	task.forceReplaced = true
+	task.excludedNode = excludedNode
+	task.terminationType = si.TerminationType_name[int32(si.TerminationType_STOPPED_BY_RM)]
+	return true
+}
+
+// unmarkForceReplaced reverts the mark of a placeholder whose pod could not be deleted
+func (task *Task) unmarkForceReplaced() {
+	task.lock.Lock()
+	defer task.lock.Unlock()
+	task.forceReplaced = false
+	task.excludedNode = ""
+	task.terminationType = ""
+}
+
+// recreatePlaceholder creates a placeholder replaced by the administrator again, its index in the task group is kept.
+// It runs asynchronously as the placeholder is terminated with its task lock held.
+func (app *Application) recreatePlaceholder(taskGroupName string, index int32, excludedNode string) {
+	app.lock.RLock()
+	state := app.sm.Current()
+	progress := app.placeholderProgress
+	var taskGroup *v1alpha1.TaskGroup
+	for i := range app.taskGroups {
+		if app.taskGroups[i].Name == taskGroupName {
+			taskGroup = &app.taskGroups[i]
+			break
+		}
+	}
+	app.lock.RUnlock()
+
+	if state != events.States().Application.Reserving || taskGroup == nil || progress == nil || index < 0 {
+		app.logger().Info("app is not reserving anymore, the replaced placeholder is not created again",
+			zap.String("taskGroup", taskGroupName),
+			zap.Int32("taskGroupIndex", index))
+		return
+	}
+	progress.onForceReplacing(taskGroupName)
+	placeholderName := utils.GeneratePlaceholderName(taskGroupName, app.applicationID, index)
+	placeholder := newPlaceholder(placeholderName, app.getPlaceholderSpec(), *taskGroup, index)
+	if excludedNode != "" {
+		excludePlaceholderNode(placeholder.pod, excludedNode)
+	}
+	if err := getPlaceholderManager().createPlaceholder(app, placeholder, progress); err != nil {
+		app.logger().Error("failed to re-create the replaced placeholder",
+			zap.String("placeholder", placeholderName),
+			zap.Error(err))
+		return
+	}
+	app.publishAppEvent(v1.EventTypeNormal, "PlaceholderReplaced",
+		"placeholder %d of task group %s is created again", index, taskGroupName)
+	dispatcher.Dispatch(NewUpdateApplicationReservationEvent(app.applicationID))
+}
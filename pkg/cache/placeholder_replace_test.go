@@ -0,0 +1,144 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"testing"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+)
+
+func TestReplacePlaceholder(t *testing.T) {
+	conf.GetSchedulerConf().SetTestMode(true)
+	app := createAppWIthTaskGroupForTest()
+	mockedAPIProvider := client.NewMockedAPIProvider()
+	createdPods := createAndCheckPlaceholderCreate(mockedAPIProvider, app, t)
+	context := NewContext(mockedAPIProvider)
+	context.applications.put(app)
+	deleted := make([]string, 0)
+	mockedAPIProvider.MockDeleteFn(func(pod *v1.Pod) error {
+		deleted = append(deleted, pod.Name)
+		return nil
+	})
+
+	placeholderName := utils.GeneratePlaceholderName("test-group-1", appID, 3)
+	pod := &v1.Pod{
+		ObjectMeta: apis.ObjectMeta{
+			Name:      placeholderName,
+			Namespace: namespace,
+			UID:       "UID-ph-3",
+		},
+	}
+	task := NewTask("UID-ph-3", app, context, pod)
+	task.placeholder = true
+	task.taskGroupName = "test-group-1"
+	task.taskGroupIndex = 3
+	app.taskMap[task.taskID] = task
+
+	err := context.ReplacePlaceholder("app-unknown", placeholderName, false)
+	assert.ErrorContains(t, err, "application app-unknown is not found")
+	err = context.ReplacePlaceholder(appID, placeholderName, false)
+	assert.ErrorContains(t, err, "can only be replaced while reserving")
+
+	app.sm.SetState(events.States().Application.Reserving)
+	err = context.ReplacePlaceholder(appID, "ph-unknown", false)
+	assert.ErrorContains(t, err, "placeholder ph-unknown of application app01 is not found")
+	// the node can only be excluded once the placeholder is allocated
+	err = context.ReplacePlaceholder(appID, placeholderName, true)
+	assert.ErrorContains(t, err, "there is no node to exclude")
+
+	// a failed delete leaves the placeholder untouched
+	mockedAPIProvider.MockDeleteFn(func(pod *v1.Pod) error {
+		return fmt.Errorf("connection refused")
+	})
+	task.nodeName = "node-1"
+	err = context.ReplacePlaceholder(appID, placeholderName, true)
+	assert.ErrorContains(t, err, "connection refused")
+	assert.Assert(t, !task.forceReplaced)
+	assert.Equal(t, task.terminationType, "")
+
+	mockedAPIProvider.MockDeleteFn(func(pod *v1.Pod) error {
+		deleted = append(deleted, pod.Name)
+		return nil
+	})
+	err = context.ReplacePlaceholder(appID, placeholderName, true)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, deleted, []string{placeholderName})
+	assert.Assert(t, task.forceReplaced)
+	assert.Equal(t, task.excludedNode, "node-1")
+	// a replaced placeholder is released by the shim, it is not seen as preempted
+	assert.Equal(t, task.terminationType, "STOPPED_BY_RM")
+	err = context.ReplacePlaceholder(appID, placeholderName, true)
+	assert.ErrorContains(t, err, "is already being replaced")
+
+	// once the pod is gone the placeholder is created again off the excluded node
+	delete(createdPods, placeholderName)
+	app.recreatePlaceholder("test-group-1", 3, "node-1")
+	recreated, ok := createdPods[placeholderName]
+	assert.Assert(t, ok, "replaced placeholder is not created again")
+	terms := recreated.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	assert.DeepEqual(t, terms, []v1.NodeSelectorTerm{{
+		MatchExpressions: []v1.NodeSelectorRequirement{{
+			Key:      v1.LabelHostname,
+			Operator: v1.NodeSelectorOpNotIn,
+			Values:   []string{"node-1"},
+		}},
+	}})
+	progress, ok := app.getPlaceholderProgress().get("test-group-1")
+	assert.Assert(t, ok)
+	assert.Equal(t, progress, taskGroupProgress{desired: 10, created: 10, forceReplaced: 1})
+
+	// nothing is created once the app is no longer reserving
+	app.sm.SetState(events.States().Application.Running)
+	delete(createdPods, placeholderName)
+	app.recreatePlaceholder("test-group-1", 3, "")
+	_, ok = createdPods[placeholderName]
+	assert.Assert(t, !ok, "placeholder should not be created again for a running app")
+}
+
+func TestExcludePlaceholderNode(t *testing.T) {
+	pod := &v1.Pod{
+		Spec: v1.PodSpec{
+			Affinity: &v1.Affinity{
+				NodeAffinity: &v1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+						NodeSelectorTerms: []v1.NodeSelectorTerm{
+							{MatchExpressions: []v1.NodeSelectorRequirement{{Key: "zone", Operator: v1.NodeSelectorOpIn, Values: []string{"a"}}}},
+							{MatchExpressions: []v1.NodeSelectorRequirement{{Key: "zone", Operator: v1.NodeSelectorOpIn, Values: []string{"b"}}}},
+						},
+					},
+				},
+			},
+		},
+	}
+	excludePlaceholderNode(pod, "node-1")
+	for _, term := range pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		assert.Equal(t, len(term.MatchExpressions), 2)
+		assert.Equal(t, term.MatchExpressions[1].Key, v1.LabelHostname)
+		assert.DeepEqual(t, term.MatchExpressions[1].Values, []string{"node-1"})
+	}
+}
@@ -19,15 +19,19 @@
 package cache
 
 import (
+	"fmt"
 	"testing"
 
 	"gotest.tools/assert"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/apis/yunikorn.apache.org/v1alpha1"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
 )
 
 func TestNewPlaceholder(t *testing.T) {
@@ -50,17 +54,20 @@ func TestNewPlaceholder(t *testing.T) {
 		},
 	})
 
-	holder := newPlaceholder("ph-name", app, app.taskGroups[0])
+	holder := newPlaceholder("ph-name", app.getPlaceholderSpec(), app.taskGroups[0], 0)
 	assert.Equal(t, holder.appID, appID)
 	assert.Equal(t, holder.taskGroupName, app.taskGroups[0].Name)
 	assert.Equal(t, holder.pod.Spec.SchedulerName, constants.SchedulerName)
 	assert.Equal(t, holder.pod.Name, "ph-name")
 	assert.Equal(t, holder.pod.Namespace, namespace)
-	assert.Equal(t, len(holder.pod.Labels), 3)
+	assert.Equal(t, len(holder.pod.Labels), 5)
 	assert.Equal(t, holder.pod.Labels[constants.LabelApplicationID], appID)
 	assert.Equal(t, holder.pod.Labels[constants.LabelQueueName], queue)
-	assert.Equal(t, len(holder.pod.Annotations), 2)
+	assert.Equal(t, holder.pod.Labels[constants.LabelTaskGroupName], app.taskGroups[0].Name)
+	assert.Equal(t, holder.pod.Labels[constants.LabelTaskGroupHash], utils.GetTaskGroupHash(app.taskGroups[0].Name, appID))
+	assert.Equal(t, len(holder.pod.Annotations), 3)
 	assert.Equal(t, holder.pod.Annotations[constants.AnnotationTaskGroupName], app.taskGroups[0].Name)
+	assert.Equal(t, holder.pod.Annotations[constants.AnnotationTaskGroupIndex], "0")
 	assert.Equal(t, common.GetPodResource(holder.pod).Resources[constants.CPU].Value, int64(500))
 	assert.Equal(t, common.GetPodResource(holder.pod).Resources[constants.Memory].Value, int64(1024))
 	assert.Equal(t, len(holder.pod.Spec.NodeSelector), 0)
@@ -90,6 +97,8 @@ func TestNewPlaceholderWithLabelsAndAnnotations(t *testing.T) {
 			Labels: map[string]string{
 				"labelKey0": "labelKeyValue0",
 				"labelKey1": "labelKeyValue1",
+				// the reserved labels cannot be overridden
+				constants.LabelApplicationID: "app02",
 			},
 			Annotations: map[string]string{
 				"annotationKey0": "annotationValue0",
@@ -99,9 +108,10 @@ func TestNewPlaceholderWithLabelsAndAnnotations(t *testing.T) {
 		},
 	})
 
-	holder := newPlaceholder("ph-name", app, app.taskGroups[0])
-	assert.Equal(t, len(holder.pod.Labels), 5)
-	assert.Equal(t, len(holder.pod.Annotations), 5)
+	holder := newPlaceholder("ph-name", app.getPlaceholderSpec(), app.taskGroups[0], 0)
+	assert.Equal(t, len(holder.pod.Labels), 7)
+	assert.Equal(t, len(holder.pod.Annotations), 6)
+	assert.Equal(t, holder.pod.Labels[constants.LabelApplicationID], appID)
 	assert.Equal(t, holder.pod.Labels["labelKey0"], "labelKeyValue0")
 	assert.Equal(t, holder.pod.Labels["labelKey1"], "labelKeyValue1")
 	assert.Equal(t, holder.pod.Annotations["annotationKey0"], "annotationValue0")
@@ -133,7 +143,7 @@ func TestNewPlaceholderWithNodeSelectors(t *testing.T) {
 		},
 	})
 
-	holder := newPlaceholder("ph-name", app, app.taskGroups[0])
+	holder := newPlaceholder("ph-name", app.getPlaceholderSpec(), app.taskGroups[0], 0)
 	assert.Equal(t, len(holder.pod.Spec.NodeSelector), 2)
 	assert.Equal(t, holder.pod.Spec.NodeSelector["nodeType"], "test")
 	assert.Equal(t, holder.pod.Spec.NodeSelector["nodeState"], "healthy")
@@ -167,7 +177,7 @@ func TestNewPlaceholderWithTolerations(t *testing.T) {
 		},
 	})
 
-	holder := newPlaceholder("ph-name", app, app.taskGroups[0])
+	holder := newPlaceholder("ph-name", app.getPlaceholderSpec(), app.taskGroups[0], 0)
 	assert.Equal(t, len(holder.pod.Spec.Tolerations), 1)
 	tlr := holder.pod.Spec.Tolerations[0]
 	assert.Equal(t, tlr.Key, "key1")
@@ -175,3 +185,190 @@ func TestNewPlaceholderWithTolerations(t *testing.T) {
 	assert.Equal(t, tlr.Operator, v1.TolerationOpEqual)
 	assert.Equal(t, tlr.Effect, v1.TaintEffectNoSchedule)
 }
+
+func TestNewPlaceholderWithPriorityClass(t *testing.T) {
+	mockedSchedulerAPI := newMockSchedulerAPI()
+	app := NewApplication("app01", "root.default",
+		"bob", map[string]string{constants.AppTagNamespace: "test"}, mockedSchedulerAPI)
+	app.setTaskGroups([]v1alpha1.TaskGroup{
+		{
+			Name:              "test-group-1",
+			MinMember:         10,
+			PriorityClassName: "high-priority",
+		},
+		{
+			Name:      "test-group-2",
+			MinMember: 5,
+		},
+	})
+
+	holder := newPlaceholder("ph-name", app.getPlaceholderSpec(), app.taskGroups[0], 0)
+	assert.Equal(t, holder.pod.Spec.PriorityClassName, "high-priority")
+	holder = newPlaceholder("ph-name", app.getPlaceholderSpec(), app.taskGroups[1], 0)
+	assert.Equal(t, holder.pod.Spec.PriorityClassName, "")
+}
+
+func TestNewPlaceholderWithMaxPerNode(t *testing.T) {
+	mockedSchedulerAPI := newMockSchedulerAPI()
+	app := NewApplication("app01", "root.default",
+		"bob", map[string]string{constants.AppTagNamespace: "test"}, mockedSchedulerAPI)
+	app.setTaskGroups([]v1alpha1.TaskGroup{
+		{
+			Name:       "test-group-1",
+			MinMember:  10,
+			MaxPerNode: 2,
+			Labels:     map[string]string{"labelKey0": "labelKeyValue0"},
+		},
+		{
+			Name:      "test-group-2",
+			MinMember: 5,
+		},
+	})
+
+	holder := newPlaceholder("ph-name", app.getPlaceholderSpec(), app.taskGroups[0], 3)
+	assert.Equal(t, holder.pod.Labels[constants.LabelPlaceholderSlot], "1")
+	assert.Equal(t, holder.pod.Labels["labelKey0"], "labelKeyValue0")
+	assert.Equal(t, holder.pod.Labels[constants.LabelApplicationID], "app01")
+	assert.Assert(t, holder.pod.Spec.Affinity != nil && holder.pod.Spec.Affinity.PodAntiAffinity != nil)
+	term := holder.pod.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution[0]
+	assert.Equal(t, term.LabelSelector.MatchLabels[constants.LabelPlaceholderSlot], "1")
+	assert.Equal(t, term.LabelSelector.MatchLabels[constants.LabelTaskGroupHash],
+		holder.pod.Labels[constants.LabelTaskGroupHash])
+
+	holder = newPlaceholder("ph-name", app.getPlaceholderSpec(), app.taskGroups[1], 3)
+	_, ok := holder.pod.Labels[constants.LabelPlaceholderSlot]
+	assert.Assert(t, !ok)
+	assert.Assert(t, holder.pod.Spec.Affinity == nil)
+}
+
+func TestNewPlaceholderWithExtendedResources(t *testing.T) {
+	mockedSchedulerAPI := newMockSchedulerAPI()
+	app := NewApplication("app01", "root.default",
+		"bob", map[string]string{constants.AppTagNamespace: "test"}, mockedSchedulerAPI)
+	app.setTaskGroups([]v1alpha1.TaskGroup{
+		{
+			Name:      "test-group-1",
+			MinMember: 2,
+			MinResource: map[string]resource.Quantity{
+				"cpu":               resource.MustParse("500m"),
+				"memory":            resource.MustParse("1024M"),
+				"ephemeral-storage": resource.MustParse("1Gi"),
+				"hugepages-2Mi":     resource.MustParse("4Mi"),
+				"nvidia.com/gpu":    resource.MustParse("2"),
+			},
+		},
+	})
+
+	holder := newPlaceholder("ph-name", app.getPlaceholderSpec(), app.taskGroups[0], 0)
+	container := holder.pod.Spec.Containers[0]
+	assert.Equal(t, len(container.Resources.Requests), 5)
+	// resources that cannot be overcommitted must have limits equal to the requests
+	assert.Equal(t, len(container.Resources.Limits), 2)
+	assert.Assert(t, container.Resources.Limits["nvidia.com/gpu"].Equal(resource.MustParse("2")))
+	assert.Assert(t, container.Resources.Limits["hugepages-2Mi"].Equal(resource.MustParse("4Mi")))
+
+	// the placeholder ask matches the placeholder pod resource
+	assert.Assert(t, common.Equals(common.GetPodResource(holder.pod),
+		common.GetTGResource(app.taskGroups[0].MinResource, 1)))
+	assert.Equal(t, app.getPlaceholderAsk().Resources["nvidia.com/gpu"].Value, int64(4))
+}
+
+func TestNewPlaceholderOwnerReferences(t *testing.T) {
+	app := NewApplication("app01", "root.default", "bob",
+		map[string]string{constants.AppTagNamespace: "test"}, newMockSchedulerAPI())
+	app.setTaskGroups([]v1alpha1.TaskGroup{{Name: "test-group-1", MinMember: 1}})
+
+	// an app without owner creates placeholders without owner
+	holder := newPlaceholder("ph-name", app.getPlaceholderSpec(), app.taskGroups[0], 0)
+	assert.Equal(t, len(holder.pod.OwnerReferences), 0)
+
+	// the owners without a controller all own the placeholders
+	blockOwnerDeletion := true
+	podOwners := []metav1.OwnerReference{
+		{APIVersion: "v1", Kind: "ConfigMap", Name: "cm", UID: "UID-cm"},
+		{APIVersion: "v1", Kind: "Pod", Name: "pod", UID: "UID-pod", BlockOwnerDeletion: &blockOwnerDeletion},
+	}
+	app.setOwnReferences(podOwners)
+	holder = newPlaceholder("ph-name", app.getPlaceholderSpec(), app.taskGroups[0], 0)
+	assert.Equal(t, len(holder.pod.OwnerReferences), 2)
+	for i, ref := range holder.pod.OwnerReferences {
+		assert.Equal(t, ref.UID, podOwners[i].UID)
+		assert.Assert(t, ref.Controller != nil && !*ref.Controller)
+	}
+	assert.Assert(t, *holder.pod.OwnerReferences[1].BlockOwnerDeletion)
+
+	// the controller of the pods is the only owner of the placeholders
+	controller := true
+	podOwners = append(podOwners, metav1.OwnerReference{
+		APIVersion: "batch/v1", Kind: "Job", Name: "job", UID: "UID-job", Controller: &controller})
+	app.setOwnReferences(podOwners)
+	holder = newPlaceholder("ph-name", app.getPlaceholderSpec(), app.taskGroups[0], 0)
+	assert.Equal(t, len(holder.pod.OwnerReferences), 1)
+	assert.Equal(t, holder.pod.OwnerReferences[0].Kind, "Job")
+	assert.Equal(t, holder.pod.OwnerReferences[0].Name, "job")
+	assert.Equal(t, holder.pod.OwnerReferences[0].UID, types.UID("UID-job"))
+	assert.Assert(t, !*holder.pod.OwnerReferences[0].Controller)
+
+	// the owner references of the pod are left untouched
+	assert.Assert(t, *podOwners[2].Controller)
+	assert.Assert(t, podOwners[0].Controller == nil)
+	assert.Equal(t, app.getOwnerObjectReference().Kind, "Job")
+}
+
+func TestPlaceholderSpec(t *testing.T) {
+	app := NewApplication("app01", "root.default", "bob",
+		map[string]string{constants.AppTagNamespace: "test"}, newMockSchedulerAPI())
+	app.setRuntimeClassName("kata")
+	app.setTaskGroups([]v1alpha1.TaskGroup{{Name: "test-group-1", MinMember: 2,
+		MinResource: map[string]resource.Quantity{"cpu": resource.MustParse("1")}}})
+	app.SetPlaceholderTimeout(30)
+	controller := true
+	app.setOwnReferences([]metav1.OwnerReference{
+		{APIVersion: "batch/v1", Kind: "Job", Name: "job", UID: "UID-job", Controller: &controller}})
+
+	spec := app.getPlaceholderSpec()
+	assert.Equal(t, spec.appID, "app01")
+	assert.Equal(t, spec.queue, "root.default")
+	assert.Equal(t, spec.namespace, "test")
+	assert.Equal(t, spec.runtimeClassName, "kata")
+	assert.Equal(t, spec.timeoutInSec, int64(30))
+	assert.Equal(t, app.getPlaceholderTimeout(), int64(30))
+	assert.Assert(t, common.Equals(spec.ask, app.getPlaceholderAsk()))
+	assert.Equal(t, len(spec.ownerReferences), 1)
+	assert.Assert(t, !*spec.ownerReferences[0].Controller)
+
+	// the owners of the app are copies, changing them does not change the app or the spec taken before
+	owners := app.getOwnReferences()
+	assert.Assert(t, *owners[0].Controller)
+	owners[0].Name = "changed"
+	assert.Equal(t, app.getOwnReferences()[0].Name, "job")
+	app.setOwnReferences(nil)
+	assert.Equal(t, len(app.getOwnReferences()), 0)
+	assert.Equal(t, spec.ownerReferences[0].Name, "job")
+
+	// the placeholders do not share the references of the spec
+	holder := newPlaceholder("ph-name", spec, app.taskGroups[0], 0)
+	holder.pod.OwnerReferences[0].Name = "changed"
+	assert.Equal(t, spec.ownerReferences[0].Name, "job")
+}
+
+// run with -race: the placeholders are created while the owners of the app are updated
+func TestPlaceholderSpecConcurrency(t *testing.T) {
+	app := NewApplication("app01", "root.default", "bob",
+		map[string]string{constants.AppTagNamespace: "test"}, newMockSchedulerAPI())
+	app.setTaskGroups([]v1alpha1.TaskGroup{{Name: "test-group-1", MinMember: 1}})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			app.setOwnReferences([]metav1.OwnerReference{
+				{APIVersion: "batch/v1", Kind: "Job", Name: "job", UID: types.UID(fmt.Sprintf("UID-%d", i))}})
+			app.SetPlaceholderTimeout(int64(i))
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		holder := newPlaceholder("ph-name", app.getPlaceholderSpec(), app.getTaskGroups()[0], 0)
+		assert.Assert(t, len(holder.pod.OwnerReferences) <= 1)
+	}
+	<-done
+}
@@ -0,0 +1,183 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	k8sCache "k8s.io/client-go/tools/cache"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/appmgmt/interfaces"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+)
+
+// adoptedPods keeps the pods not scheduled by yunikorn that are adopted by an app, e.g. the pods that were
+// running before yunikorn was rolled out. An adopted pod is tracked as a bound task of its app instead of
+// the occupied resources of its node, the node coordinator ignores it.
+type adoptedPods struct {
+	pods map[string]string // pod UID -> app ID
+	sync.RWMutex
+}
+
+func newAdoptedPods() *adoptedPods {
+	return &adoptedPods{
+		pods: make(map[string]string),
+	}
+}
+
+// add records the pod as adopted by the app, false is returned if the pod is already adopted
+func (a *adoptedPods) add(pod *v1.Pod, appID string) bool {
+	a.Lock()
+	defer a.Unlock()
+	if _, ok := a.pods[string(pod.UID)]; ok {
+		return false
+	}
+	a.pods[string(pod.UID)] = appID
+	return true
+}
+
+func (a *adoptedPods) remove(pod *v1.Pod) {
+	a.Lock()
+	defer a.Unlock()
+	delete(a.pods, string(pod.UID))
+}
+
+// getAppID returns the app that adopted the pod, this is nil safe
+func (a *adoptedPods) getAppID(pod *v1.Pod) (string, bool) {
+	if a == nil {
+		return "", false
+	}
+	a.RLock()
+	defer a.RUnlock()
+	appID, ok := a.pods[string(pod.UID)]
+	return appID, ok
+}
+
+func (a *adoptedPods) isAdopted(pod *v1.Pod) bool {
+	_, ok := a.getAppID(pod)
+	return ok
+}
+
+// filterPods keeps the adopted pods
+func (a *adoptedPods) filterPods(obj interface{}) bool {
+	switch obj := obj.(type) {
+	case *v1.Pod:
+		return a.isAdopted(obj)
+	case k8sCache.DeletedFinalStateUnknown:
+		if pod, err := utils.Convert2Pod(obj.Obj); err == nil {
+			return a.isAdopted(pod)
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+// AdoptPod adds a pod that is already bound to a node by another scheduler to the app, the pod is not scheduled,
+// its task is bound right away like a pod bound outside of the scheduler. The allocation of the pod is reported
+// to the core when its node is not registered yet, otherwise it is occupied on the node.
+// The pods scheduled by yunikorn, the pods not bound or terminated and the pods already adopted are not adopted.
+func (ctx *Context) AdoptPod(appID string, pod *v1.Pod) bool {
+	if utils.GeneralPodFilter(pod) || !utils.IsAssignedPod(pod) || utils.IsPodTerminated(pod) {
+		return false
+	}
+	if ctx.GetApplication(appID) == nil {
+		return false
+	}
+	if !ctx.adoptedPods.add(pod, appID) {
+		return false
+	}
+	// a running pod is part of the occupied resources of its node already, it is tracked by its task instead
+	if utils.IsPodRunning(pod) {
+		ctx.nodes.updateNodeOccupiedResources(pod.Spec.NodeName, common.GetPodResource(pod), SubOccupiedResource)
+	}
+	ctx.AddTask(&interfaces.AddTaskRequest{
+		Metadata: interfaces.TaskMetadata{
+			ApplicationID: appID,
+			TaskID:        string(pod.UID),
+			Pod:           pod,
+		},
+	})
+	log.Logger().Info("pod adopted by app",
+		zap.String("appID", appID),
+		zap.String("namespace", pod.Namespace),
+		zap.String("podName", pod.Name),
+		zap.String("nodeID", pod.Spec.NodeName))
+	return true
+}
+
+// updateAdoptedPod moves the task of an adopted pod along with the phase of the pod,
+// the pod is kept in the scheduler cache while it is running like the other pods not scheduled by yunikorn.
+func (ctx *Context) updateAdoptedPod(old, new interface{}) {
+	oldPod, err := utils.Convert2Pod(old)
+	if err != nil {
+		log.Logger().Error("expecting a pod object", zap.Error(err))
+		return
+	}
+	newPod, err := utils.Convert2Pod(new)
+	if err != nil {
+		log.Logger().Error("expecting a pod object", zap.Error(err))
+		return
+	}
+	appID, ok := ctx.adoptedPods.getAppID(newPod)
+	if !ok || oldPod.Status.Phase == newPod.Status.Phase {
+		return
+	}
+	if utils.IsPodRunning(newPod) {
+		if err = ctx.nodes.cache.AddPod(newPod); err != nil {
+			log.Logger().Warn("failed to update scheduler-cache", zap.Error(err))
+		}
+	} else if utils.IsPodTerminated(newPod) {
+		if err = ctx.nodes.cache.RemovePod(newPod); err != nil {
+			log.Logger().Debug("failed to update scheduler-cache", zap.Error(err))
+		}
+	}
+	ctx.NotifyTaskPhase(appID, string(newPod.UID), newPod.Status.Phase)
+}
+
+// deleteAdoptedPod completes the task of an adopted pod that is deleted
+func (ctx *Context) deleteAdoptedPod(obj interface{}) {
+	var pod *v1.Pod
+	switch t := obj.(type) {
+	case *v1.Pod:
+		pod = t
+	case k8sCache.DeletedFinalStateUnknown:
+		var err error
+		if pod, err = utils.Convert2Pod(t.Obj); err != nil {
+			log.Logger().Error(err.Error())
+			return
+		}
+	default:
+		log.Logger().Error("cannot convert to pod")
+		return
+	}
+	appID, ok := ctx.adoptedPods.getAppID(pod)
+	if !ok {
+		return
+	}
+	ctx.adoptedPods.remove(pod)
+	if err := ctx.nodes.cache.RemovePod(pod); err != nil {
+		log.Logger().Debug("failed to update scheduler-cache", zap.Error(err))
+	}
+	ctx.NotifyTaskComplete(appID, string(pod.UID))
+}
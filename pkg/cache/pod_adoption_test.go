@@ -0,0 +1,142 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8sCache "k8s.io/client-go/tools/cache"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/appmgmt/interfaces"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/dispatcher"
+)
+
+func newPodForAdoption(name, schedulerName, nodeName string, phase v1.PodPhase) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: apis.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			UID:       types.UID("uid-" + name),
+		},
+		Spec: v1.PodSpec{
+			SchedulerName: schedulerName,
+			NodeName:      nodeName,
+			Containers: []v1.Container{
+				{
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{
+							v1.ResourceCPU: resource.MustParse("1"),
+						},
+					},
+				},
+			},
+		},
+		Status: v1.PodStatus{Phase: phase},
+	}
+}
+
+func TestAdoptPod(t *testing.T) {
+	context := initContextForTest()
+	dispatcher.RegisterEventHandler(dispatcher.EventTypeTask, context.TaskEventHandler())
+	dispatcher.Start()
+	defer dispatcher.Stop()
+
+	context.AddApplication(&interfaces.AddApplicationRequest{
+		Metadata: interfaces.ApplicationMetadata{
+			ApplicationID: "app00001",
+			QueueName:     "root.a",
+			User:          "test-user",
+			Tags:          map[string]string{"namespace": "default"},
+		},
+	})
+	for _, name := range []string{"host0001", "host0002"} {
+		context.nodes.addAndReportNode(&v1.Node{
+			ObjectMeta: apis.ObjectMeta{
+				Name: name,
+				UID:  types.UID("uid-" + name),
+			},
+		}, false)
+	}
+	// host0002 is registered with the core, the running pod of another scheduler is occupied on it
+	node := context.nodes.getNode("host0002")
+	node.fsm.SetState(events.States().Node.Healthy)
+	running := newPodForAdoption("pod-running", "default-scheduler", "host0002", v1.PodRunning)
+	context.nodes.updateNodeOccupiedResources("host0002", common.GetPodResource(running), AddOccupiedResource)
+	coordinator := newNodeResourceCoordinator(context.nodes, context.adoptedPods)
+	assert.Assert(t, coordinator.filterPods(running))
+
+	// the pods that cannot be adopted
+	assert.Assert(t, !context.AdoptPod("app00001",
+		newPodForAdoption("pod-yunikorn", constants.SchedulerName, "host0001", v1.PodRunning)))
+	assert.Assert(t, !context.AdoptPod("app00001",
+		newPodForAdoption("pod-pending", "default-scheduler", "", v1.PodPending)))
+	assert.Assert(t, !context.AdoptPod("app00001",
+		newPodForAdoption("pod-succeeded", "default-scheduler", "host0001", v1.PodSucceeded)))
+	assert.Assert(t, !context.AdoptPod("app00002", running))
+
+	// the pod on a node not registered yet is reported as an existing allocation of the app
+	pod := newPodForAdoption("pod-starting", "default-scheduler", "host0001", v1.PodPending)
+	assert.Assert(t, context.AdoptPod("app00001", pod))
+	task, err := context.getTask("app00001", string(pod.UID))
+	assert.NilError(t, err)
+	assert.Equal(t, task.GetTaskState(), events.States().Task.Bound)
+	assert.Equal(t, len(context.nodes.getNode("host0001").existingAllocations), 1)
+	assert.Equal(t, context.nodes.getNode("host0001").existingAllocations[0].ApplicationID, "app00001")
+
+	// the running pod is moved from the occupied resources to its task
+	assert.Assert(t, context.AdoptPod("app00001", running))
+	assert.Assert(t, !coordinator.filterPods(running))
+	assert.Assert(t, context.adoptedPods.filterPods(running))
+	assert.Assert(t, context.adoptedPods.filterPods(k8sCache.DeletedFinalStateUnknown{Obj: running}))
+	assert.Equal(t, node.occupied.Resources[constants.CPU].Value, int64(1000))
+	task, err = context.getTask("app00001", string(running.UID))
+	assert.NilError(t, err)
+	assert.Assert(t, task.preBoundOccupied)
+	// a pod is adopted once
+	assert.Assert(t, !context.AdoptPod("app00001", running))
+
+	// the task follows the phase of the pod
+	err = utils.WaitForCondition(func() bool {
+		return task.GetTaskState() == events.States().Task.Running
+	}, 10*time.Millisecond, time.Second)
+	assert.NilError(t, err, "task is in state %s", task.GetTaskState())
+	succeeded := running.DeepCopy()
+	succeeded.Status.Phase = v1.PodSucceeded
+	context.updateAdoptedPod(running, succeeded)
+	err = utils.WaitForCondition(func() bool {
+		return task.GetTaskState() == events.States().Task.Succeeded
+	}, 10*time.Millisecond, time.Second)
+	assert.NilError(t, err, "task is in state %s", task.GetTaskState())
+	assert.Equal(t, node.occupied.Resources[constants.CPU].Value, int64(0))
+
+	// the deleted pod is not adopted anymore
+	context.deleteAdoptedPod(succeeded)
+	assert.Assert(t, !context.adoptedPods.isAdopted(running))
+	assert.Assert(t, coordinator.filterPods(running))
+}
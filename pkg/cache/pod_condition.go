@@ -0,0 +1,117 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+)
+
+// reasons of the PodScheduled=False condition set by the shim, the condition tells where the scheduling of the pod is
+const (
+	conditionQueueQuotaExceeded   = "QueueQuotaExceeded"
+	conditionGangReserving        = "GangReserving"
+	conditionAwaitingCoreDecision = "AwaitingCoreDecision"
+)
+
+// updatePodScheduledCondition sets the PodScheduled=False condition of the task pod, nothing is updated when the
+// condition is unchanged or when skip returns true for the current condition. The updated pod is kept next to the
+// pod of the task, the next update is based on the latest version of the pod. The pod of the task is never replaced,
+// it is read without the lock by the state machine callbacks. Returns true if the condition was updated.
+// This must not be called from the state machine callbacks of the task, the task lock is taken.
+func (task *Task) updatePodScheduledCondition(reason, message string, skip func(current *v1.PodCondition) bool) bool {
+	task.conditionLock.Lock()
+	defer task.conditionLock.Unlock()
+
+	pod := task.conditionPod
+	if pod == nil {
+		pod = task.GetTaskPod()
+	}
+	pod = pod.DeepCopy()
+	if _, current := podutil.GetPodCondition(&pod.Status, v1.PodScheduled); current != nil && skip != nil && skip(current) {
+		return false
+	}
+	condition := &v1.PodCondition{
+		Type:    v1.PodScheduled,
+		Status:  v1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	}
+	if !podutil.UpdatePodCondition(&pod.Status, condition) {
+		return false
+	}
+	task.logger().Debug("updating pod condition",
+		zap.String("reason", reason),
+		zap.String("message", message))
+	if !task.context.apiProvider.IsTestingMode() {
+		updated, err := task.context.apiProvider.GetAPIs().KubeClient.GetClientSet().CoreV1().
+			Pods(pod.Namespace).UpdateStatus(pod)
+		if err != nil {
+			// only log the error here, no need to handle it if the update failed
+			task.logger().Warn("update pod condition failed", zap.Error(err))
+			return false
+		}
+		pod = updated
+	}
+	task.conditionPod = pod
+	return true
+}
+
+// setAwaitingCoreDecision marks the pod of a task submitted to the core, a decision already taken by the core
+// for the pod is not overwritten. Placeholders are skipped, they are not looked at by the users.
+func (task *Task) setAwaitingCoreDecision() {
+	if task.placeholder {
+		return
+	}
+	queue := task.queue
+	if queue == "" {
+		queue = task.application.GetQueue()
+	}
+	task.updatePodScheduledCondition(conditionAwaitingCoreDecision,
+		fmt.Sprintf("%s is queued in %s and waiting for a decision of the scheduler", task.alias, queue),
+		func(current *v1.PodCondition) bool {
+			return current.Reason == v1.PodReasonUnschedulable || current.Reason == conditionQueueQuotaExceeded
+		})
+}
+
+// setGangReservingConditions marks the gang members waiting for the placeholders of the app with the number of
+// placeholders bound. The caller must hold the app lock, the pods are updated on another goroutine.
+func (app *Application) setGangReservingConditions(bound int32) {
+	members := make([]*Task, 0)
+	for _, task := range app.getTasks(events.States().Task.New) {
+		if !task.placeholder && !task.nonGang && task.taskGroupName != "" {
+			members = append(members, task)
+		}
+	}
+	if len(members) == 0 {
+		return
+	}
+	message := fmt.Sprintf("%d/%d placeholders bound, waiting for the gang to be reserved",
+		bound, app.getDesiredPlaceholders())
+	go func() {
+		for _, task := range members {
+			task.updatePodScheduledCondition(conditionGangReserving, message, nil)
+		}
+	}()
+}
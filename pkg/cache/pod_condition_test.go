@@ -0,0 +1,134 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/apis/yunikorn.apache.org/v1alpha1"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
+)
+
+func newConditionTestTask(app *Application, context *Context, taskID string) *Task {
+	task := NewTask(taskID, app, context, &v1.Pod{
+		ObjectMeta: apis.ObjectMeta{
+			Name:      "pod-" + taskID,
+			Namespace: "default",
+			UID:       types.UID(taskID),
+		},
+	})
+	app.addTask(task)
+	return task
+}
+
+// getPodScheduledCondition returns the condition last set by the shim, the pod of the task is not updated
+func getPodScheduledCondition(task *Task) *v1.PodCondition {
+	task.conditionLock.Lock()
+	defer task.conditionLock.Unlock()
+	pod := task.conditionPod
+	if pod == nil {
+		pod = task.GetTaskPod()
+	}
+	_, condition := podutil.GetPodCondition(&pod.Status, v1.PodScheduled)
+	return condition
+}
+
+func TestUpdatePodScheduledCondition(t *testing.T) {
+	context := NewContext(client.NewMockedAPIProvider())
+	app := NewApplication("app00001", "root.a", "test-user", map[string]string{}, newMockSchedulerAPI())
+	task := newConditionTestTask(app, context, "task-01")
+
+	assert.Assert(t, task.updatePodScheduledCondition(conditionAwaitingCoreDecision, "waiting", nil))
+	condition := getPodScheduledCondition(task)
+	assert.Equal(t, condition.Status, v1.ConditionFalse)
+	assert.Equal(t, condition.Reason, conditionAwaitingCoreDecision)
+	assert.Equal(t, condition.Message, "waiting")
+
+	// an unchanged condition is not updated
+	assert.Assert(t, !task.updatePodScheduledCondition(conditionAwaitingCoreDecision, "waiting", nil))
+	// a new message is an update
+	assert.Assert(t, task.updatePodScheduledCondition(conditionAwaitingCoreDecision, "still waiting", nil))
+	assert.Equal(t, getPodScheduledCondition(task).Message, "still waiting")
+
+	// the current condition can block the update
+	skip := func(current *v1.PodCondition) bool {
+		return current.Reason == conditionAwaitingCoreDecision
+	}
+	assert.Assert(t, !task.updatePodScheduledCondition(conditionQueueQuotaExceeded, "quota", skip))
+	assert.Equal(t, getPodScheduledCondition(task).Reason, conditionAwaitingCoreDecision)
+}
+
+func TestSetAwaitingCoreDecision(t *testing.T) {
+	context := NewContext(client.NewMockedAPIProvider())
+	app := NewApplication("app00001", "root.a", "test-user", map[string]string{}, newMockSchedulerAPI())
+	task := newConditionTestTask(app, context, "task-01")
+	task.setAwaitingCoreDecision()
+	condition := getPodScheduledCondition(task)
+	assert.Equal(t, condition.Reason, conditionAwaitingCoreDecision)
+	assert.Equal(t, condition.Message, "default/pod-task-01 is queued in root.a and waiting for a decision of the scheduler")
+
+	// a decision of the core is kept
+	assert.Assert(t, task.updatePodScheduledCondition(v1.PodReasonUnschedulable, "no resources", nil))
+	task.setAwaitingCoreDecision()
+	assert.Equal(t, getPodScheduledCondition(task).Reason, v1.PodReasonUnschedulable)
+
+	// placeholders are skipped
+	placeholder := newConditionTestTask(app, context, "ph-01")
+	placeholder.placeholder = true
+	placeholder.setAwaitingCoreDecision()
+	assert.Assert(t, getPodScheduledCondition(placeholder) == nil)
+}
+
+func TestSetGangReservingConditions(t *testing.T) {
+	context := NewContext(client.NewMockedAPIProvider())
+	app := NewApplication("app00001", "root.a", "test-user", map[string]string{}, newMockSchedulerAPI())
+	app.taskGroups = []v1alpha1.TaskGroup{{Name: "tg", MinMember: 3}}
+	member := newConditionTestTask(app, context, "task-01")
+	member.taskGroupName = "tg"
+	nonGang := newConditionTestTask(app, context, "task-02")
+	nonGang.taskGroupName = "tg"
+	nonGang.nonGang = true
+	submitted := newConditionTestTask(app, context, "task-03")
+	submitted.taskGroupName = "tg"
+	submitted.sm.SetState(events.States().Task.Scheduling)
+	placeholder := newConditionTestTask(app, context, "ph-01")
+	placeholder.taskGroupName = "tg"
+	placeholder.placeholder = true
+
+	app.setGangReservingConditions(2)
+	err := utils.WaitForCondition(func() bool {
+		return getPodScheduledCondition(member) != nil
+	}, 10*time.Millisecond, time.Second)
+	assert.NilError(t, err)
+	condition := getPodScheduledCondition(member)
+	assert.Equal(t, condition.Reason, conditionGangReserving)
+	assert.Equal(t, condition.Message, "2/3 placeholders bound, waiting for the gang to be reserved")
+	assert.Assert(t, getPodScheduledCondition(nonGang) == nil)
+	assert.Assert(t, getPodScheduledCondition(submitted) == nil)
+	assert.Assert(t, getPodScheduledCondition(placeholder) == nil)
+}
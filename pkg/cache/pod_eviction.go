@@ -0,0 +1,97 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/metrics"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+// the wait before the eviction of a released pod blocked by a disruption budget is retried
+var evictionRetryInterval = 10 * time.Second
+
+// deleteReleasedPod removes the pod of a task released by the core, e.g. a timed out placeholder or a preempted pod.
+// With the release eviction enabled the pod is evicted so its disruption budgets are honored, a blocked eviction is
+// retried until the pod is gone. A preempted pod is still deleted right away with the hard preemption enabled.
+// With the job tracking compatibility enabled a pod tracked by the Job controller is not deleted again.
+// The caller must hold the task lock, a blocked eviction is retried on another goroutine.
+func (task *Task) deleteReleasedPod(terminationType string) error {
+	configs := task.context.apiProvider.GetAPIs().Conf
+	// the pod is already deleted, the Job controller removes it once it has accounted for it
+	if configs.JobTrackingCompatibility && utils.IsTerminatingTrackedPod(task.pod) {
+		task.logger().Debug("released pod is terminating, waiting for the Job controller",
+			zap.String("terminationType", terminationType))
+		return nil
+	}
+	preempted := terminationType == si.TerminationType_name[int32(si.TerminationType_PREEMPTED_BY_SCHEDULER)]
+	if !configs.ReleaseEviction || (preempted && configs.HardPreemption) {
+		return task.DeleteTaskPod(task.pod)
+	}
+	return task.evictReleasedPod(task.pod, terminationType)
+}
+
+// evictReleasedPod evicts the pod, an eviction blocked by a disruption budget is reported and retried later
+func (task *Task) evictReleasedPod(pod *v1.Pod, terminationType string) error {
+	err := task.context.apiProvider.GetAPIs().KubeClient.Evict(pod)
+	switch {
+	case err == nil:
+		metrics.GetTaskMetrics().IncEviction(metrics.EvictionSucceeded)
+		return nil
+	case apierrors.IsNotFound(err):
+		return nil
+	case apierrors.IsTooManyRequests(err):
+		metrics.GetTaskMetrics().IncEviction(metrics.EvictionBlocked)
+		task.logger().Warn("eviction of the released pod is blocked by a disruption budget",
+			zap.String("terminationType", terminationType),
+			zap.Duration("retryInterval", evictionRetryInterval),
+			zap.Error(err))
+		events.GetRecorder().Eventf(pod, v1.EventTypeWarning, "EvictionBlocked",
+			"Pod %s released by the scheduler (%s) is not evicted, it is blocked by a disruption budget: %v",
+			task.alias, terminationType, err)
+		time.AfterFunc(evictionRetryInterval, func() {
+			task.retryEviction(pod, terminationType)
+		})
+		return nil
+	default:
+		metrics.GetTaskMetrics().IncEviction(metrics.EvictionFailed)
+		return err
+	}
+}
+
+// retryEviction evicts the pod again, unless the task was terminated or its pod is left to the Job controller
+func (task *Task) retryEviction(pod *v1.Pod, terminationType string) {
+	if task.isTerminated() {
+		return
+	}
+	if task.context.apiProvider.GetAPIs().Conf.JobTrackingCompatibility &&
+		utils.IsTerminatingTrackedPod(task.GetTaskPod()) {
+		return
+	}
+	if err := task.evictReleasedPod(pod, terminationType); err != nil {
+		task.logger().Error("failed to evict the released pod", zap.Error(err))
+	}
+}
@@ -0,0 +1,136 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/metrics"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+func TestDeleteReleasedPod(t *testing.T) {
+	conf.GetSchedulerConf().SetTestMode(true)
+	mockedAPIProvider := client.NewMockedAPIProvider()
+	context := NewContext(mockedAPIProvider)
+	app := NewApplication("app-eviction", "root.default", "bob",
+		map[string]string{}, mockedAPIProvider.GetAPIs().SchedulerAPI)
+	pod := &v1.Pod{
+		ObjectMeta: apis.ObjectMeta{
+			Name:      "pod-eviction",
+			Namespace: "default",
+			UID:       "UID-eviction",
+		},
+	}
+	task := NewTask("UID-eviction", app, context, pod)
+
+	var lock sync.Mutex
+	deleted, evicted := 0, 0
+	blocked := 2
+	mockedAPIProvider.MockDeleteFn(func(pod *v1.Pod) error {
+		lock.Lock()
+		defer lock.Unlock()
+		deleted++
+		return nil
+	})
+	mockedAPIProvider.MockEvictFn(func(pod *v1.Pod) error {
+		lock.Lock()
+		defer lock.Unlock()
+		if blocked > 0 {
+			blocked--
+			return apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+		}
+		evicted++
+		return nil
+	})
+	counts := func() (int, int) {
+		lock.Lock()
+		defer lock.Unlock()
+		return deleted, evicted
+	}
+	timeout := si.TerminationType_name[int32(si.TerminationType_TIMEOUT)]
+	preempted := si.TerminationType_name[int32(si.TerminationType_PREEMPTED_BY_SCHEDULER)]
+
+	// the eviction is disabled by default
+	assert.NilError(t, task.deleteReleasedPod(timeout))
+	d, e := counts()
+	assert.Equal(t, d, 1)
+	assert.Equal(t, e, 0)
+
+	// a blocked eviction is retried until the pod is evicted
+	configs := mockedAPIProvider.GetAPIs().Conf
+	configs.ReleaseEviction = true
+	defer func() { configs.ReleaseEviction = false }()
+	defer func(interval time.Duration) { evictionRetryInterval = interval }(evictionRetryInterval)
+	evictionRetryInterval = 10 * time.Millisecond
+	blockedBefore := metrics.GetTaskMetrics().GetEvictions(metrics.EvictionBlocked)
+	assert.NilError(t, task.deleteReleasedPod(timeout))
+	err := utils.WaitForCondition(func() bool {
+		_, e = counts()
+		return e == 1
+	}, 10*time.Millisecond, 3*time.Second)
+	assert.NilError(t, err, "blocked eviction is not retried")
+	assert.Equal(t, metrics.GetTaskMetrics().GetEvictions(metrics.EvictionBlocked), blockedBefore+2)
+	d, _ = counts()
+	assert.Equal(t, d, 1)
+
+	// a preempted pod is deleted with the hard preemption
+	assert.NilError(t, task.deleteReleasedPod(preempted))
+	_, e = counts()
+	assert.Equal(t, e, 2)
+	configs.HardPreemption = true
+	defer func() { configs.HardPreemption = false }()
+	assert.NilError(t, task.deleteReleasedPod(preempted))
+	d, e = counts()
+	assert.Equal(t, d, 2)
+	assert.Equal(t, e, 2)
+
+	// a pod already gone is not an error, other failures are returned
+	mockedAPIProvider.MockEvictFn(func(pod *v1.Pod) error {
+		return apierrors.NewNotFound(v1.Resource("pods"), pod.Name)
+	})
+	assert.NilError(t, task.deleteReleasedPod(timeout))
+	mockedAPIProvider.MockEvictFn(func(pod *v1.Pod) error {
+		return fmt.Errorf("connection refused")
+	})
+	assert.ErrorContains(t, task.deleteReleasedPod(timeout), "connection refused")
+
+	// a terminating pod tracked by the Job controller is left to the Job controller
+	configs.JobTrackingCompatibility = true
+	defer func() { configs.JobTrackingCompatibility = false }()
+	now := apis.Now()
+	task.pod.Finalizers = []string{constants.JobTrackingFinalizer}
+	task.pod.DeletionTimestamp = &now
+	assert.NilError(t, task.deleteReleasedPod(timeout))
+	assert.NilError(t, task.deleteReleasedPod(preempted))
+	d, _ = counts()
+	assert.Equal(t, d, 2)
+}
@@ -0,0 +1,374 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+)
+
+// the status of the provisioning requests is checked at most once per interval
+const provisioningRequestInterval = 5 * time.Second
+
+// the conditions of a provisioning request set by the cluster autoscaler
+const (
+	provisioningRequestProvisioned = "Provisioned"
+	provisioningRequestFailed      = "Failed"
+)
+
+// ProvisioningRequest of the cluster autoscaler, the API is not part of client-go
+var provisioningRequestResource = schema.GroupVersionResource{
+	Group:    "autoscaling.x-k8s.io",
+	Version:  "v1beta1",
+	Resource: "provisioningrequests",
+}
+
+// provisioningRequest is the request created for the placeholders of a task group that cannot be scheduled,
+// the request refers to a PodTemplate of the same name holding the spec of the placeholders.
+type provisioningRequest struct {
+	app           *Application
+	taskGroupName string
+	namespace     string
+	name          string
+	placeholders  []*v1.Pod
+	provisioned   bool
+}
+
+// provisioningRequests asks the cluster autoscaler for the capacity of the task groups whose placeholders
+// cannot be scheduled. All the placeholders of a task group are described by one request, the autoscaler
+// provisions the capacity of the whole task group at once instead of scaling up for each pending pod.
+// The placeholders are tied to the request, they are retried by the core once the capacity is provisioned.
+// The requests of an app are deleted once the app leaves the Reserving state.
+type provisioningRequests struct {
+	clients  *client.Clients
+	requests map[string]map[string]*provisioningRequest
+	stopChan chan struct{}
+	sync.Mutex
+}
+
+func newProvisioningRequests(clients *client.Clients) *provisioningRequests {
+	return &provisioningRequests{
+		clients:  clients,
+		requests: make(map[string]map[string]*provisioningRequest),
+	}
+}
+
+// getProvisioningRequests returns nil when the provisioning requests are disabled or the app is not added to a context
+func (app *Application) getProvisioningRequests() *provisioningRequests {
+	if app.context == nil {
+		return nil
+	}
+	return app.context.provisioning
+}
+
+// request creates the provisioning request of the task group, it is a noop if the task group already has one.
+// A failure is only logged, the placeholders are then left to the regular scale up of the autoscaler.
+func (p *provisioningRequests) request(app *Application, taskGroupName string) {
+	placeholders := app.getUnscheduledPlaceholders(taskGroupName)
+	if len(placeholders) == 0 {
+		return
+	}
+	pr := &provisioningRequest{
+		app:           app,
+		taskGroupName: taskGroupName,
+		namespace:     placeholders[0].GetTaskPod().Namespace,
+		name:          utils.GenerateProvisioningRequestName(taskGroupName, app.applicationID),
+	}
+	p.Lock()
+	if _, ok := p.requests[app.applicationID][taskGroupName]; ok {
+		p.Unlock()
+		return
+	}
+	if p.requests[app.applicationID] == nil {
+		p.requests[app.applicationID] = make(map[string]*provisioningRequest)
+	}
+	p.requests[app.applicationID][taskGroupName] = pr
+	p.Unlock()
+
+	if err := p.create(pr, placeholders[0].GetTaskPod(), len(placeholders)); err != nil {
+		app.logger().Warn("failed to create the provisioning request",
+			zap.String("taskGroup", taskGroupName),
+			zap.String("name", pr.name),
+			zap.Error(err))
+		p.delete(pr)
+		p.Lock()
+		delete(p.requests[app.applicationID], taskGroupName)
+		p.Unlock()
+		return
+	}
+	tied := make([]*v1.Pod, 0, len(placeholders))
+	for _, placeholder := range placeholders {
+		pod := placeholder.GetTaskPod()
+		if err := p.patchPlaceholder(pod, pr.name); err != nil {
+			placeholder.logger().Warn("failed to tie the placeholder to the provisioning request",
+				zap.String("name", pr.name),
+				zap.Error(err))
+			continue
+		}
+		tied = append(tied, pod)
+	}
+	p.Lock()
+	pr.placeholders = tied
+	// the app may have left the Reserving state while the request was created
+	released := p.requests[app.applicationID][taskGroupName] != pr
+	p.Unlock()
+	if released {
+		p.delete(pr)
+		return
+	}
+	app.logger().Info("provisioning request created",
+		zap.String("taskGroup", taskGroupName),
+		zap.String("name", pr.name),
+		zap.Int("placeholders", len(placeholders)))
+	app.publishAppEvent(v1.EventTypeNormal, "ProvisioningRequestCreated",
+		"provisioning request %s created for %d placeholders of task group %s",
+		pr.name, len(placeholders), taskGroupName)
+}
+
+// create creates the PodTemplate with the spec of the placeholder and the provisioning request referring to it,
+// the objects left behind by a previous run of the shim are reused.
+func (p *provisioningRequests) create(pr *provisioningRequest, placeholder *v1.Pod, count int) error {
+	spec := placeholder.Spec.DeepCopy()
+	spec.NodeName = ""
+	template := &v1.PodTemplate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            pr.name,
+			Namespace:       pr.namespace,
+			Labels:          placeholder.Labels,
+			OwnerReferences: placeholder.OwnerReferences,
+		},
+		Template: v1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels:      placeholder.Labels,
+				Annotations: placeholder.Annotations,
+			},
+			Spec: *spec,
+		},
+	}
+	_, err := p.clients.KubeClient.GetClientSet().CoreV1().PodTemplates(pr.namespace).Create(template)
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	request := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": provisioningRequestResource.GroupVersion().String(),
+			"kind":       "ProvisioningRequest",
+			"spec": map[string]interface{}{
+				"provisioningClassName": p.clients.Conf.GetProvisioningClassName(),
+				"podSets": []interface{}{
+					map[string]interface{}{
+						"count": int64(count),
+						"podTemplateRef": map[string]interface{}{
+							"name": pr.name,
+						},
+					},
+				},
+			},
+		},
+	}
+	request.SetName(pr.name)
+	request.SetNamespace(pr.namespace)
+	request.SetLabels(placeholder.Labels)
+	request.SetOwnerReferences(placeholder.OwnerReferences)
+	_, err = p.clients.DynamicClient.Resource(provisioningRequestResource).Namespace(pr.namespace).
+		Create(request, metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// patchPlaceholder ties the placeholder to the provisioning request, the autoscaler does not scale up
+// for the pod on its own. An empty name unties it.
+func (p *provisioningRequests) patchPlaceholder(pod *v1.Pod, name string) error {
+	patch, err := utils.GetConsumeProvisioningRequestPatch(name)
+	if err != nil {
+		return err
+	}
+	_, err = p.clients.KubeClient.GetClientSet().CoreV1().
+		Pods(pod.Namespace).Patch(pod.Name, types.MergePatchType, patch)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// delete deletes the provisioning request and its PodTemplate
+func (p *provisioningRequests) delete(pr *provisioningRequest) {
+	err := p.clients.DynamicClient.Resource(provisioningRequestResource).Namespace(pr.namespace).
+		Delete(pr.name, &metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		log.Logger().Warn("failed to delete the provisioning request",
+			zap.String("namespace", pr.namespace),
+			zap.String("name", pr.name),
+			zap.Error(err))
+	}
+	err = p.clients.KubeClient.GetClientSet().CoreV1().PodTemplates(pr.namespace).
+		Delete(pr.name, &metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		log.Logger().Warn("failed to delete the pod template of the provisioning request",
+			zap.String("namespace", pr.namespace),
+			zap.String("name", pr.name),
+			zap.Error(err))
+	}
+}
+
+// release deletes the provisioning requests of the app, the placeholders keep their annotation:
+// they are either allocated or deleted with the reservation.
+func (p *provisioningRequests) release(appID string) {
+	p.Lock()
+	requests := p.requests[appID]
+	delete(p.requests, appID)
+	p.Unlock()
+	for _, pr := range requests {
+		p.delete(pr)
+	}
+}
+
+// check reads the conditions of the requests that are not provisioned yet. Once the capacity is provisioned
+// the task group is no longer marked as unschedulable, the placeholders are retried by the core on the new
+// nodes. A failed request is deleted and its placeholders are left to the regular scale up.
+func (p *provisioningRequests) check() {
+	p.Lock()
+	pending := make([]*provisioningRequest, 0)
+	for _, requests := range p.requests {
+		for _, pr := range requests {
+			if !pr.provisioned {
+				pending = append(pending, pr)
+			}
+		}
+	}
+	p.Unlock()
+	for _, pr := range pending {
+		request, err := p.clients.DynamicClient.Resource(provisioningRequestResource).Namespace(pr.namespace).
+			Get(pr.name, metav1.GetOptions{})
+		if err != nil {
+			log.Logger().Debug("failed to read the provisioning request",
+				zap.String("namespace", pr.namespace),
+				zap.String("name", pr.name),
+				zap.Error(err))
+			continue
+		}
+		if message, ok := getProvisioningRequestCondition(request, provisioningRequestFailed); ok {
+			p.fail(pr, message)
+			continue
+		}
+		if _, ok := getProvisioningRequestCondition(request, provisioningRequestProvisioned); ok {
+			p.Lock()
+			pr.provisioned = true
+			p.Unlock()
+			pr.app.clearTaskGroupUnschedulable(pr.taskGroupName)
+			pr.app.logger().Info("capacity provisioned for the task group",
+				zap.String("taskGroup", pr.taskGroupName),
+				zap.String("name", pr.name))
+			pr.app.publishAppEvent(v1.EventTypeNormal, "CapacityProvisioned",
+				"capacity provisioned for the placeholders of task group %s by provisioning request %s",
+				pr.taskGroupName, pr.name)
+		}
+	}
+}
+
+// fail deletes a failed request and unties its placeholders, the task group stays marked as unschedulable
+// and no other request is created for it.
+func (p *provisioningRequests) fail(pr *provisioningRequest, message string) {
+	p.Lock()
+	if p.requests[pr.app.applicationID][pr.taskGroupName] != pr {
+		p.Unlock()
+		return
+	}
+	delete(p.requests[pr.app.applicationID], pr.taskGroupName)
+	placeholders := pr.placeholders
+	p.Unlock()
+	pr.app.logger().Warn("provisioning request failed",
+		zap.String("taskGroup", pr.taskGroupName),
+		zap.String("name", pr.name),
+		zap.String("message", message))
+	pr.app.publishAppEvent(v1.EventTypeWarning, "ProvisioningRequestFailed",
+		"provisioning request %s of task group %s failed: %s", pr.name, pr.taskGroupName, message)
+	for _, pod := range placeholders {
+		if err := p.patchPlaceholder(pod, ""); err != nil {
+			log.Logger().Warn("failed to untie the placeholder from the provisioning request",
+				zap.String("podName", pod.Name),
+				zap.Error(err))
+		}
+	}
+	p.delete(pr)
+}
+
+// getProvisioningRequestCondition returns the message of the condition if its status is true
+func getProvisioningRequestCondition(request *unstructured.Unstructured, conditionType string) (string, bool) {
+	conditions, _, err := unstructured.NestedSlice(request.Object, "status", "conditions")
+	if err != nil {
+		return "", false
+	}
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if condition["type"] == conditionType && condition["status"] == string(metav1.ConditionTrue) {
+			message, _ := condition["message"].(string)
+			return message, true
+		}
+	}
+	return "", false
+}
+
+func (p *provisioningRequests) start() {
+	p.Lock()
+	defer p.Unlock()
+	if p.stopChan != nil {
+		return
+	}
+	p.stopChan = make(chan struct{})
+	go func(stopChan chan struct{}) {
+		ticker := time.NewTicker(provisioningRequestInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.check()
+			case <-stopChan:
+				return
+			}
+		}
+	}(p.stopChan)
+}
+
+func (p *provisioningRequests) stop() {
+	p.Lock()
+	defer p.Unlock()
+	if p.stopChan == nil {
+		return
+	}
+	close(p.stopChan)
+	p.stopChan = nil
+}
@@ -0,0 +1,156 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/appmgmt/interfaces"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+)
+
+func TestProvisioningRequest(t *testing.T) {
+	apiProvider := client.NewMockedAPIProvider()
+	apiProvider.GetAPIs().Conf.EnableProvisioningRequest = true
+	context := NewContext(apiProvider)
+	requests := context.provisioning
+	assert.Assert(t, requests != nil)
+	clientSet := apiProvider.GetAPIs().KubeClient.GetClientSet()
+	prClient := apiProvider.GetAPIs().DynamicClient.Resource(provisioningRequestResource).Namespace("default")
+
+	app := NewApplication("app00001", "root.a", "test-user", map[string]string{}, newMockSchedulerAPI())
+	addPlaceholder := func(name, taskGroupName string) *Task {
+		pod := &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				UID:       types.UID("UID-" + name),
+				Labels:    utils.GetPlaceholderLabels(app.applicationID, app.queue, taskGroupName),
+			},
+			Spec: v1.PodSpec{
+				SchedulerName: constants.SchedulerName,
+				Containers:    []v1.Container{{Name: "pause", Image: "pause"}},
+			},
+		}
+		_, err := clientSet.CoreV1().Pods("default").Create(pod)
+		assert.NilError(t, err)
+		task := NewFromTaskMeta(name, app, context, interfaces.TaskMetadata{
+			ApplicationID: app.applicationID,
+			TaskID:        name,
+			Pod:           pod,
+			Placeholder:   true,
+			TaskGroupName: taskGroupName,
+		})
+		task.sm.SetState(events.States().Task.Scheduling)
+		app.addTask(task)
+		return task
+	}
+	getAnnotation := func(name string) string {
+		pod, err := clientSet.CoreV1().Pods("default").Get(name, metav1.GetOptions{})
+		assert.NilError(t, err)
+		return pod.Annotations[constants.AnnotationConsumeProvisioningRequest]
+	}
+	setCondition := func(name, conditionType string) {
+		request, err := prClient.Get(name, metav1.GetOptions{})
+		assert.NilError(t, err)
+		err = unstructured.SetNestedSlice(request.Object, []interface{}{
+			map[string]interface{}{
+				"type":    conditionType,
+				"status":  "True",
+				"message": "test message",
+			},
+		}, "status", "conditions")
+		assert.NilError(t, err)
+		_, err = prClient.Update(request, metav1.UpdateOptions{})
+		assert.NilError(t, err)
+	}
+
+	placeholder := addPlaceholder("ph-01", "test-group-1")
+	addPlaceholder("ph-02", "test-group-1")
+	addPlaceholder("ph-03", "test-group-2")
+
+	// one request for all the placeholders of the task group
+	context.publishGangMembersEvent(placeholder)
+	name := utils.GenerateProvisioningRequestName("test-group-1", app.applicationID)
+	template, err := clientSet.CoreV1().PodTemplates("default").Get(name, metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, template.Template.Spec.SchedulerName, constants.SchedulerName)
+	request, err := prClient.Get(name, metav1.GetOptions{})
+	assert.NilError(t, err)
+	className, _, _ := unstructured.NestedString(request.Object, "spec", "provisioningClassName")
+	assert.Equal(t, className, conf.DefaultProvisioningClass)
+	podSets, _, _ := unstructured.NestedSlice(request.Object, "spec", "podSets")
+	assert.Equal(t, len(podSets), 1)
+	count, _, _ := unstructured.NestedInt64(podSets[0].(map[string]interface{}), "count")
+	assert.Equal(t, count, int64(2))
+	assert.Equal(t, getAnnotation("ph-01"), name)
+	assert.Equal(t, getAnnotation("ph-02"), name)
+	assert.Equal(t, getAnnotation("ph-03"), "")
+
+	// the capacity is provisioned, the task group is no longer unschedulable
+	requests.check()
+	assert.Assert(t, app.unschedulableTaskGroups["test-group-1"])
+	setCondition(name, provisioningRequestProvisioned)
+	requests.check()
+	assert.Assert(t, !app.unschedulableTaskGroups["test-group-1"])
+	assert.Assert(t, requests.requests[app.applicationID]["test-group-1"].provisioned)
+	// no new request for a provisioned task group
+	requests.request(app, "test-group-1")
+	assert.Equal(t, len(requests.requests[app.applicationID]), 1)
+
+	// a failed request is deleted and its placeholders are untied
+	otherName := utils.GenerateProvisioningRequestName("test-group-2", app.applicationID)
+	assert.Assert(t, app.markTaskGroupUnschedulable("test-group-2"))
+	requests.request(app, "test-group-2")
+	assert.Equal(t, getAnnotation("ph-03"), otherName)
+	setCondition(otherName, provisioningRequestFailed)
+	requests.check()
+	_, err = prClient.Get(otherName, metav1.GetOptions{})
+	assert.Assert(t, apierrors.IsNotFound(err))
+	_, err = clientSet.CoreV1().PodTemplates("default").Get(otherName, metav1.GetOptions{})
+	assert.Assert(t, apierrors.IsNotFound(err))
+	assert.Equal(t, getAnnotation("ph-03"), "")
+	assert.Assert(t, app.unschedulableTaskGroups["test-group-2"])
+	_, ok := requests.requests[app.applicationID]["test-group-2"]
+	assert.Assert(t, !ok)
+
+	// the requests of the app are deleted with the reservation
+	requests.release(app.applicationID)
+	_, err = prClient.Get(name, metav1.GetOptions{})
+	assert.Assert(t, apierrors.IsNotFound(err))
+	_, err = clientSet.CoreV1().PodTemplates("default").Get(name, metav1.GetOptions{})
+	assert.Assert(t, apierrors.IsNotFound(err))
+	assert.Equal(t, len(requests.requests), 0)
+}
+
+func TestProvisioningRequestDisabled(t *testing.T) {
+	context := NewContext(client.NewMockedAPIProvider())
+	assert.Assert(t, context.provisioning == nil)
+}
@@ -0,0 +1,202 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	coredao "github.com/apache/incubator-yunikorn-core/pkg/webservice/dao"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+// coreQueueLister returns the partitions of the core with their queue hierarchy
+type coreQueueLister func() ([]*coredao.PartitionDAOInfo, error)
+
+// queueCapacities caches the max capacities of the queues of the core, they are read from its web service
+// periodically. It is used to fail an app at submission when its placeholders can never fit in its queue,
+// instead of leaving the app reserving until the placeholder timeout. The check is best effort: a queue
+// unknown to the cache, or created after the last refresh, is not checked.
+type queueCapacities struct {
+	list     coreQueueLister
+	queues   map[string]map[string]*si.Resource // core partition -> queue path -> max capacity
+	stopChan chan struct{}
+	stopOnce sync.Once
+	sync.RWMutex
+}
+
+func newQueueCapacities(list coreQueueLister) *queueCapacities {
+	return &queueCapacities{
+		list:     list,
+		queues:   make(map[string]map[string]*si.Resource),
+		stopChan: make(chan struct{}),
+	}
+}
+
+// getQueueCapacities returns nil when the queue capacity check is disabled or the app is not added to a context
+func (app *Application) getQueueCapacities() *queueCapacities {
+	if app.context == nil {
+		return nil
+	}
+	return app.context.capacities
+}
+
+// StartQueueCapacityRefresh starts reading the max capacities of the queues periodically,
+// this is a noop if the queue capacity check is disabled
+func (ctx *Context) StartQueueCapacityRefresh() {
+	if ctx.capacities != nil {
+		go wait.Until(ctx.capacities.refresh, ctx.apiProvider.GetAPIs().Conf.GetQueueCapacityRefresh(), ctx.capacities.stopChan)
+	}
+}
+
+// StopQueueCapacityRefresh stops reading the max capacities of the queues
+func (ctx *Context) StopQueueCapacityRefresh() {
+	if ctx.capacities != nil {
+		ctx.capacities.stopOnce.Do(func() {
+			close(ctx.capacities.stopChan)
+		})
+	}
+}
+
+// listCoreQueues reads the queues of the core from its web service,
+// the partitions are written one after the other as separate json documents
+func listCoreQueues(address string) coreQueueLister {
+	httpClient := &http.Client{Timeout: coreWebServiceTimeout}
+	url := fmt.Sprintf("http://%s/ws/v1/queues", address)
+	return func() ([]*coredao.PartitionDAOInfo, error) {
+		resp, err := httpClient.Get(url)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("listing the queues of the core failed with status %s", resp.Status)
+		}
+		partitions := make([]*coredao.PartitionDAOInfo, 0)
+		decoder := json.NewDecoder(resp.Body)
+		for {
+			partition := &coredao.PartitionDAOInfo{}
+			if err = decoder.Decode(partition); err == io.EOF {
+				return partitions, nil
+			} else if err != nil {
+				return nil, err
+			}
+			partitions = append(partitions, partition)
+		}
+	}
+}
+
+// refresh replaces the cached max capacities, the previous ones are kept when the core cannot be read
+func (c *queueCapacities) refresh() {
+	partitions, err := c.list()
+	if err != nil {
+		log.Logger().Warn("failed to list the queues of the core, keeping the cached queue capacities", zap.Error(err))
+		return
+	}
+	queues := make(map[string]map[string]*si.Resource, len(partitions))
+	for _, partition := range partitions {
+		queues[partition.PartitionName] = make(map[string]*si.Resource)
+		addQueueCapacities(queues[partition.PartitionName], "", partition.Queues)
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.queues = queues
+}
+
+// addQueueCapacities adds the max capacity of the queue and its children, the queues without a max are left out
+func addQueueCapacities(queues map[string]*si.Resource, parent string, queue coredao.QueueDAOInfo) {
+	path := strings.ToLower(queue.QueueName)
+	if parent != "" {
+		path = parent + "." + path
+	}
+	if maxCapacity, err := parseCoreResource(queue.Capacities.MaxCapacity); err != nil {
+		log.Logger().Warn("failed to parse the max capacity of the queue",
+			zap.String("queue", path),
+			zap.String("maxCapacity", queue.Capacities.MaxCapacity),
+			zap.Error(err))
+	} else if len(maxCapacity.Resources) > 0 {
+		queues[path] = maxCapacity
+	}
+	for _, child := range queue.ChildQueues {
+		addQueueCapacities(queues, path, child)
+	}
+}
+
+// parseCoreResource parses a resource as printed by the web service of the core, e.g. [memory:1024 vcore:2000]
+func parseCoreResource(value string) (*si.Resource, error) {
+	res := &si.Resource{Resources: make(map[string]*si.Quantity)}
+	for _, field := range strings.Fields(strings.Trim(value, "[]")) {
+		sep := strings.LastIndex(field, ":")
+		if sep <= 0 {
+			return nil, fmt.Errorf("invalid resource %s", field)
+		}
+		quantity, err := strconv.ParseInt(field[sep+1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quantity of resource %s: %v", field, err)
+		}
+		res.Resources[field[:sep]] = &si.Quantity{Value: quantity}
+	}
+	return res, nil
+}
+
+// checkAsk returns an error naming the queue and the resource when the ask exceeds the max capacity
+// of the queue or of one of its parents, only the resources limited by the max capacity are compared.
+func (c *queueCapacities) checkAsk(rmID, partition, queue string, ask *si.Resource) error {
+	if ask == nil {
+		return nil
+	}
+	c.RLock()
+	defer c.RUnlock()
+	queues, ok := c.queues[fmt.Sprintf("[%s]%s", rmID, partition)]
+	if !ok {
+		return nil
+	}
+	names := make([]string, 0, len(ask.Resources))
+	for name := range ask.Resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for path := strings.ToLower(queue); path != ""; {
+		if maxCapacity, ok := queues[path]; ok {
+			for _, name := range names {
+				requested := ask.Resources[name].GetValue()
+				if limit, ok := maxCapacity.Resources[name]; ok && requested > limit.GetValue() {
+					return fmt.Errorf("placeholders of the app require %s %d, which exceeds the max capacity %d "+
+						"of queue %s", name, requested, limit.GetValue(), path)
+				}
+			}
+		}
+		if sep := strings.LastIndex(path, "."); sep > 0 {
+			path = path[:sep]
+		} else {
+			path = ""
+		}
+	}
+	return nil
+}
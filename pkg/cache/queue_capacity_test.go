@@ -0,0 +1,215 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	coredao "github.com/apache/incubator-yunikorn-core/pkg/webservice/dao"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/apis/yunikorn.apache.org/v1alpha1"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/appmgmt/interfaces"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/dispatcher"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+func TestParseCoreResource(t *testing.T) {
+	res, err := parseCoreResource("[memory:1024 vcore:2000]")
+	assert.NilError(t, err)
+	assert.Equal(t, len(res.Resources), 2)
+	assert.Equal(t, res.Resources[constants.Memory].Value, int64(1024))
+	assert.Equal(t, res.Resources[constants.CPU].Value, int64(2000))
+
+	res, err = parseCoreResource("[]")
+	assert.NilError(t, err)
+	assert.Equal(t, len(res.Resources), 0)
+
+	_, err = parseCoreResource("[memory:lots]")
+	assert.ErrorContains(t, err, "invalid quantity")
+	_, err = parseCoreResource("[memory]")
+	assert.ErrorContains(t, err, "invalid resource")
+}
+
+func newTestPartition(name string) *coredao.PartitionDAOInfo {
+	return &coredao.PartitionDAOInfo{
+		PartitionName: name,
+		Queues: coredao.QueueDAOInfo{
+			QueueName:  "root",
+			Capacities: coredao.QueueCapacity{MaxCapacity: "[memory:10000 vcore:10000]"},
+			ChildQueues: []coredao.QueueDAOInfo{
+				{
+					QueueName:  "a",
+					Capacities: coredao.QueueCapacity{MaxCapacity: "[vcore:4000]"},
+					ChildQueues: []coredao.QueueDAOInfo{
+						{QueueName: "b", Capacities: coredao.QueueCapacity{MaxCapacity: "[]"}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestListCoreQueues(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.URL.Path, "/ws/v1/queues")
+		// the core writes one document per partition
+		for _, name := range []string{"[cluster]default", "[cluster]gpu"} {
+			assert.NilError(t, json.NewEncoder(w).Encode(newTestPartition(name)))
+		}
+	}))
+	defer server.Close()
+
+	partitions, err := listCoreQueues(strings.TrimPrefix(server.URL, "http://"))()
+	assert.NilError(t, err)
+	assert.Equal(t, len(partitions), 2)
+	assert.Equal(t, partitions[0].PartitionName, "[cluster]default")
+	assert.Equal(t, partitions[1].PartitionName, "[cluster]gpu")
+	assert.Equal(t, partitions[1].Queues.ChildQueues[0].QueueName, "a")
+}
+
+func TestQueueCapacitiesCheckAsk(t *testing.T) {
+	capacities := newQueueCapacities(func() ([]*coredao.PartitionDAOInfo, error) {
+		return []*coredao.PartitionDAOInfo{newTestPartition("[cluster]default")}, nil
+	})
+	capacities.refresh()
+	ask := func(memory, vcore int64) *si.Resource {
+		return &si.Resource{Resources: map[string]*si.Quantity{
+			constants.Memory: {Value: memory},
+			constants.CPU:    {Value: vcore},
+		}}
+	}
+
+	assert.NilError(t, capacities.checkAsk("cluster", "default", "root.a.b", ask(10000, 4000)))
+	assert.NilError(t, capacities.checkAsk("cluster", "default", "root.a.b", nil))
+	// the max of the parents apply to a queue without a max
+	err := capacities.checkAsk("cluster", "default", "root.a.b", ask(1000, 5000))
+	assert.Error(t, err, "placeholders of the app require vcore 5000, which exceeds the max capacity 4000 of queue root.a")
+	err = capacities.checkAsk("cluster", "default", "ROOT.c", ask(20000, 1000))
+	assert.Error(t, err, "placeholders of the app require memory 20000, which exceeds the max capacity 10000 of queue root")
+	// the queues of unknown partitions are not checked
+	assert.NilError(t, capacities.checkAsk("cluster", "gpu", "root.a", ask(20000, 20000)))
+	assert.NilError(t, capacities.checkAsk("other", "default", "root.a", ask(20000, 20000)))
+
+	// the cached capacities are kept when the core cannot be read
+	capacities.list = func() ([]*coredao.PartitionDAOInfo, error) {
+		return nil, http.ErrServerClosed
+	}
+	capacities.refresh()
+	assert.Assert(t, capacities.checkAsk("cluster", "default", "root.a", ask(0, 5000)) != nil)
+}
+
+func TestSubmitApplicationExceedsQueueCapacity(t *testing.T) {
+	context := initContextForTest()
+	dispatcher.RegisterEventHandler(dispatcher.EventTypeApp, context.ApplicationEventHandler())
+	dispatcher.Start()
+	defer dispatcher.Stop()
+	mgr := NewPlaceholderManager(client.NewMockedAPIProvider().GetAPIs())
+	mgr.Start()
+	defer mgr.Stop()
+	partition := "[" + conf.GetSchedulerConf().ClusterID + "]" + constants.DefaultPartition
+	context.capacities = newQueueCapacities(func() ([]*coredao.PartitionDAOInfo, error) {
+		return []*coredao.PartitionDAOInfo{newTestPartition(partition)}, nil
+	})
+	context.capacities.refresh()
+	submitted := make(chan string, 2)
+	mockedSchedulerAPI := newMockSchedulerAPI()
+	mockedSchedulerAPI.updateFn = func(request *si.UpdateRequest) error {
+		submitted <- request.NewApplications[0].ApplicationID
+		return nil
+	}
+
+	app := NewApplication("app00001", "root.a", "testuser", map[string]string{}, mockedSchedulerAPI)
+	app.placeholderAsk = &si.Resource{Resources: map[string]*si.Quantity{constants.CPU: {Value: 8000}}}
+	app.context = context
+	context.applications.put(app)
+	err := app.handle(NewSubmitApplicationEvent(app.applicationID))
+	assert.NilError(t, err)
+	assertAppState(t, app, events.States().Application.Failed, 3*time.Second)
+	assert.Equal(t, len(submitted), 0)
+
+	// the placeholders fit in the queue, the app is submitted to the core
+	app = NewApplication("app00002", "root.a", "testuser", map[string]string{}, mockedSchedulerAPI)
+	app.placeholderAsk = &si.Resource{Resources: map[string]*si.Quantity{constants.CPU: {Value: 2000}}}
+	app.context = context
+	context.applications.put(app)
+	err = app.handle(NewSubmitApplicationEvent(app.applicationID))
+	assert.NilError(t, err)
+	assert.Equal(t, <-submitted, "app00002")
+	assertAppState(t, app, events.States().Application.Submitted, 3*time.Second)
+}
+
+func TestGangFeasibilityExceedsQueueCapacity(t *testing.T) {
+	context := initContextForTest()
+	context.apiProvider.GetAPIs().Conf.EnableGangFeasibilityCheck = true
+	partition := "[" + conf.GetSchedulerConf().ClusterID + "]" + constants.DefaultPartition
+	context.capacities = newQueueCapacities(func() ([]*coredao.PartitionDAOInfo, error) {
+		return []*coredao.PartitionDAOInfo{newTestPartition(partition)}, nil
+	})
+	context.capacities.refresh()
+	for _, name := range []string{"host0001", "host0002"} {
+		context.addNode(&v1.Node{
+			ObjectMeta: apis.ObjectMeta{Name: name, UID: types.UID("uid_" + name)},
+			Status: v1.NodeStatus{
+				Allocatable: v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse("4"),
+					v1.ResourceMemory: resource.MustParse("8Gi"),
+				},
+			},
+		})
+	}
+	addGang := func(appID, queue string) *Application {
+		context.AddApplication(&interfaces.AddApplicationRequest{
+			Metadata: interfaces.ApplicationMetadata{
+				ApplicationID: appID,
+				QueueName:     queue,
+				User:          "test-user",
+				TaskGroups: []v1alpha1.TaskGroup{{
+					Name:      "test-group",
+					MinMember: 2,
+					MinResource: map[string]resource.Quantity{
+						v1.ResourceCPU.String(): resource.MustParse("3"),
+					},
+				}},
+			},
+		})
+		return context.applications.get(appID)
+	}
+
+	// the gang fits in the nodes but not in the max capacity of root.a
+	app := addGang("app00001", "root.a")
+	assert.Equal(t, app.gangInfeasibleReason, "gang can never be satisfied: placeholders of the app require vcore 6000, "+
+		"which exceeds the max capacity 4000 of queue root.a")
+	// the gang fits in the max capacity of the parent queue
+	app = addGang("app00002", "root.c")
+	assert.Equal(t, app.gangInfeasibleReason, "")
+}
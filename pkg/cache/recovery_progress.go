@@ -0,0 +1,210 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/metrics"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/webservice/dao"
+)
+
+// phases of the recovery after a restart, in the order they run
+const (
+	RecoveryPhaseApplications = "Applications"
+	RecoveryPhaseTasks        = "Tasks"
+	RecoveryPhaseNodes        = "Nodes"
+	RecoveryPhaseCompleted    = "Completed"
+	RecoveryPhaseFailed       = "Failed"
+)
+
+var recoveryPhases = []string{RecoveryPhaseApplications, RecoveryPhaseTasks, RecoveryPhaseNodes}
+
+type recoveryCount struct {
+	total     int
+	recovered int
+	failed    int
+}
+
+// recoveryProgress tracks the recovery of the shim after a restart, the number of apps, tasks and nodes
+// to recover and recovered is kept per phase and reported in the logs, the metrics and the readiness probe.
+type recoveryProgress struct {
+	phase     string
+	startTime time.Time
+	endTime   time.Time
+	deadline  time.Time
+	counts    map[string]*recoveryCount
+	err       error
+	sync.RWMutex
+}
+
+func newRecoveryProgress() *recoveryProgress {
+	return &recoveryProgress{
+		counts: make(map[string]*recoveryCount),
+	}
+}
+
+// addRecoveryProgress updates the counts of a recovery phase by the given deltas,
+// this is a noop if the app is not added to a context
+func (app *Application) addRecoveryProgress(phase string, total, recovered, failed int) {
+	if app.context != nil {
+		app.context.recovery.add(phase, total, recovered, failed)
+	}
+}
+
+// start resets the progress, the recovery starts with the applications phase
+func (p *recoveryProgress) start(deadline time.Time) {
+	p.Lock()
+	defer p.Unlock()
+	p.startTime = time.Now()
+	p.endTime = time.Time{}
+	p.deadline = deadline
+	p.err = nil
+	p.counts = make(map[string]*recoveryCount)
+	for _, phase := range recoveryPhases {
+		p.counts[phase] = &recoveryCount{}
+		metrics.GetRecoveryMetrics().SetProgress(phase, 0, 0)
+	}
+	p.phase = RecoveryPhaseApplications
+	log.Logger().Info("recovery started",
+		zap.String("phase", p.phase),
+		zap.Time("deadline", deadline))
+}
+
+func (p *recoveryProgress) setPhase(phase string) {
+	p.Lock()
+	defer p.Unlock()
+	if p.startTime.IsZero() || p.phase == phase {
+		return
+	}
+	log.Logger().Info("recovery phase started",
+		zap.String("phase", phase),
+		zap.String("previousPhase", p.phase),
+		zap.String("summary", p.summary()),
+		zap.Duration("elapsed", time.Since(p.startTime)),
+		zap.Duration("remaining", time.Until(p.deadline)))
+	p.phase = phase
+}
+
+// add updates the counts of a phase by the given deltas
+func (p *recoveryProgress) add(phase string, total, recovered, failed int) {
+	p.Lock()
+	defer p.Unlock()
+	count := p.getCount(phase)
+	count.total += total
+	count.recovered += recovered
+	count.failed += failed
+	metrics.GetRecoveryMetrics().SetProgress(phase, count.total, count.recovered)
+}
+
+// set replaces the counts of a phase
+func (p *recoveryProgress) set(phase string, total, recovered, failed int) {
+	p.Lock()
+	defer p.Unlock()
+	count := p.getCount(phase)
+	count.total, count.recovered, count.failed = total, recovered, failed
+	metrics.GetRecoveryMetrics().SetProgress(phase, count.total, count.recovered)
+}
+
+// finish ends the recovery, a recovery that returned an error is failed
+func (p *recoveryProgress) finish(err error) {
+	p.Lock()
+	defer p.Unlock()
+	if p.startTime.IsZero() {
+		return
+	}
+	p.endTime = time.Now()
+	p.err = err
+	elapsed := p.endTime.Sub(p.startTime)
+	metrics.GetRecoveryMetrics().SetDuration(elapsed)
+	if err != nil {
+		log.Logger().Error("recovery failed",
+			zap.String("phase", p.phase),
+			zap.String("summary", p.summary()),
+			zap.Duration("elapsed", elapsed),
+			zap.Error(err))
+		p.phase = RecoveryPhaseFailed
+		return
+	}
+	p.phase = RecoveryPhaseCompleted
+	log.Logger().Info("recovery completed",
+		zap.String("summary", p.summary()),
+		zap.Duration("elapsed", elapsed))
+}
+
+// getProgress returns the progress for the readiness probe, nil is returned if the recovery did not start
+func (p *recoveryProgress) getProgress() *dao.RecoveryProgress {
+	p.RLock()
+	defer p.RUnlock()
+	if p.startTime.IsZero() {
+		return nil
+	}
+	end := p.endTime
+	if end.IsZero() {
+		end = time.Now()
+	}
+	progress := &dao.RecoveryProgress{
+		Phase:     p.phase,
+		StartTime: p.startTime,
+		Deadline:  p.deadline,
+		Elapsed:   end.Sub(p.startTime).Round(time.Second).String(),
+		Phases:    make([]dao.RecoveryPhaseProgress, 0, len(recoveryPhases)),
+	}
+	for _, phase := range recoveryPhases {
+		count := p.counts[phase]
+		progress.Phases = append(progress.Phases, dao.RecoveryPhaseProgress{
+			Phase:     phase,
+			Total:     count.total,
+			Recovered: count.recovered,
+			Failed:    count.failed,
+		})
+	}
+	if p.err != nil {
+		progress.Error = p.err.Error()
+	}
+	return progress
+}
+
+// getCount returns the counts of a phase, the caller must hold the lock
+func (p *recoveryProgress) getCount(phase string) *recoveryCount {
+	count, ok := p.counts[phase]
+	if !ok {
+		count = &recoveryCount{}
+		p.counts[phase] = count
+	}
+	return count
+}
+
+// summary describes the counts of all the phases, the caller must hold the lock
+func (p *recoveryProgress) summary() string {
+	summary := ""
+	for i, phase := range recoveryPhases {
+		count := p.getCount(phase)
+		if i > 0 {
+			summary += ", "
+		}
+		summary += fmt.Sprintf("%s %d/%d recovered %d failed", phase, count.recovered, count.total, count.failed)
+	}
+	return summary
+}
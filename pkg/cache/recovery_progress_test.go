@@ -0,0 +1,90 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/metrics"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/webservice/dao"
+)
+
+func TestRecoveryProgress(t *testing.T) {
+	progress := newRecoveryProgress()
+	assert.Assert(t, progress.getProgress() == nil, "progress is reported before the recovery started")
+	// phase changes are ignored before the recovery started
+	progress.setPhase(RecoveryPhaseNodes)
+	assert.Equal(t, progress.phase, "")
+
+	deadline := time.Now().Add(time.Minute)
+	progress.start(deadline)
+	progress.add(RecoveryPhaseApplications, 2, 0, 0)
+	progress.add(RecoveryPhaseApplications, 0, 1, 0)
+	info := progress.getProgress()
+	assert.Equal(t, info.Phase, RecoveryPhaseApplications)
+	assert.Equal(t, info.Deadline, deadline)
+	assert.DeepEqual(t, info.Phases, []dao.RecoveryPhaseProgress{
+		{Phase: RecoveryPhaseApplications, Total: 2, Recovered: 1},
+		{Phase: RecoveryPhaseTasks},
+		{Phase: RecoveryPhaseNodes},
+	})
+
+	progress.setPhase(RecoveryPhaseTasks)
+	progress.add(RecoveryPhaseTasks, 1, 1, 0)
+	progress.add(RecoveryPhaseTasks, 1, 0, 1)
+	progress.setPhase(RecoveryPhaseNodes)
+	progress.set(RecoveryPhaseNodes, 5, 3, 0)
+	progress.set(RecoveryPhaseNodes, 5, 4, 1)
+	info = progress.getProgress()
+	assert.Equal(t, info.Phase, RecoveryPhaseNodes)
+	assert.DeepEqual(t, info.Phases[1], dao.RecoveryPhaseProgress{Phase: RecoveryPhaseTasks, Total: 2, Recovered: 1, Failed: 1})
+	assert.DeepEqual(t, info.Phases[2], dao.RecoveryPhaseProgress{Phase: RecoveryPhaseNodes, Total: 5, Recovered: 4, Failed: 1})
+	total, recovered := metrics.GetRecoveryMetrics().GetProgress(RecoveryPhaseNodes)
+	assert.Equal(t, total, 5)
+	assert.Equal(t, recovered, 4)
+
+	progress.finish(fmt.Errorf("timeout waiting for node recovery"))
+	info = progress.getProgress()
+	assert.Equal(t, info.Phase, RecoveryPhaseFailed)
+	assert.Equal(t, info.Error, "timeout waiting for node recovery")
+
+	// a new recovery starts from scratch
+	progress.start(deadline)
+	progress.finish(nil)
+	info = progress.getProgress()
+	assert.Equal(t, info.Phase, RecoveryPhaseCompleted)
+	assert.Equal(t, info.Error, "")
+	assert.DeepEqual(t, info.Phases[2], dao.RecoveryPhaseProgress{Phase: RecoveryPhaseNodes})
+}
+
+func TestRecoveryProgressPerContext(t *testing.T) {
+	first := initContextForTest()
+	first.StartRecovery(time.Now().Add(time.Minute))
+	first.recovery.add(RecoveryPhaseApplications, 2, 1, 0)
+
+	// a new context does not inherit the recovery of the previous one
+	second := initContextForTest()
+	assert.Assert(t, second.GetRecoveryProgress() == nil)
+	assert.DeepEqual(t, first.GetRecoveryProgress().Phases[0],
+		dao.RecoveryPhaseProgress{Phase: RecoveryPhaseApplications, Total: 2, Recovered: 1})
+}
@@ -0,0 +1,133 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"sort"
+	"strconv"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+// getPodDeletionCost returns the cost of deleting the pod set by its controller, 0 when it is not set or not valid
+func getPodDeletionCost(pod *v1.Pod) int32 {
+	if value, ok := pod.Annotations[constants.AnnotationPodDeletionCost]; ok {
+		if cost, err := strconv.ParseInt(value, 10, 32); err == nil {
+			return int32(cost)
+		}
+	}
+	return 0
+}
+
+// SelectReleaseVictims applies the victim selection policy to the allocations preempted by the core,
+// it must be called before the releases are dispatched. The allocations selected by the core are the hint:
+// with the deletionCost policy the pod of a preempted allocation is swapped with a cheaper pod of the same app
+// the core cannot tell apart, bound to the same node in the same queue with the same resources.
+// The tasks exchange their allocations, the core keeps the same usage and the cheaper pod is deleted instead.
+func (ctx *Context) SelectReleaseVictims(releases []*si.AllocationRelease) {
+	if conf.GetSchedulerConf().GetVictimSelectionPolicy() != conf.VictimSelectionDeletionCost {
+		return
+	}
+	appReleases := make(map[string][]string)
+	for _, release := range releases {
+		if release.TerminationType == si.TerminationType_PREEMPTED_BY_SCHEDULER && release.UUID != "" {
+			appReleases[release.ApplicationID] = append(appReleases[release.ApplicationID], release.UUID)
+		}
+	}
+	for appID, allocUUIDs := range appReleases {
+		if app := ctx.applications.get(appID); app != nil {
+			app.selectReleaseVictims(allocUUIDs)
+		}
+	}
+}
+
+// selectReleaseVictims swaps the preempted allocations with the cheapest interchangeable bound tasks,
+// the most expensive victims are swapped first and the core choice is kept on equal cost.
+func (app *Application) selectReleaseVictims(allocUUIDs []string) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	released := make(map[*Task]bool, len(allocUUIDs))
+	victims := make([]*Task, 0, len(allocUUIDs))
+	for _, allocUUID := range allocUUIDs {
+		if task := app.allocations.get(allocUUID); task != nil && !released[task] {
+			released[task] = true
+			victims = append(victims, task)
+		}
+	}
+	sort.SliceStable(victims, func(i, j int) bool {
+		return getPodDeletionCost(victims[i].GetTaskPod()) > getPodDeletionCost(victims[j].GetTaskPod())
+	})
+	candidates := app.getTasks(boundTaskStates...)
+	for _, victim := range victims {
+		if !isBoundState(victim.GetTaskState()) || !isSwappableVictim(victim) {
+			continue
+		}
+		victimCost := getPodDeletionCost(victim.GetTaskPod())
+		var cheapest *Task
+		cheapestCost := victimCost
+		for _, candidate := range candidates {
+			if released[candidate] || !isInterchangeable(victim, candidate) {
+				continue
+			}
+			if cost := getPodDeletionCost(candidate.GetTaskPod()); cost < cheapestCost {
+				cheapest, cheapestCost = candidate, cost
+			}
+		}
+		if cheapest == nil {
+			continue
+		}
+		released[cheapest] = true
+		victimUUID, cheapestUUID := victim.getTaskAllocationUUID(), cheapest.getTaskAllocationUUID()
+		victim.setAllocationUUID(cheapestUUID)
+		cheapest.setAllocationUUID(victimUUID)
+		app.logger().Info("deleting a cheaper pod for the allocation preempted by the core",
+			zap.String("allocationUUID", victimUUID),
+			zap.String("preemptedPod", victim.alias),
+			zap.Int32("preemptedCost", victimCost),
+			zap.String("deletedPod", cheapest.alias),
+			zap.Int32("deletedCost", cheapestCost))
+	}
+}
+
+// isSwappableVictim returns true if the allocation of the task can be handed to another task,
+// the placeholders keep their allocation for the replacement and an occupied pre-bound pod has none
+func isSwappableVictim(task *Task) bool {
+	task.lock.RLock()
+	defer task.lock.RUnlock()
+	return !task.placeholder && !task.preBoundOccupied && task.pod.DeletionTimestamp == nil
+}
+
+// isInterchangeable returns true if the candidate uses the same resources as the victim from the core point of view
+func isInterchangeable(victim, candidate *Task) bool {
+	if victim == candidate || !isSwappableVictim(candidate) {
+		return false
+	}
+	victim.lock.RLock()
+	nodeName, queue, resource := victim.nodeName, victim.queue, victim.resource
+	victim.lock.RUnlock()
+	candidate.lock.RLock()
+	defer candidate.lock.RUnlock()
+	return candidate.nodeName == nodeName && candidate.queue == queue && common.Equals(candidate.resource, resource)
+}
@@ -0,0 +1,133 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/appmgmt/interfaces"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+func TestGetPodDeletionCost(t *testing.T) {
+	pod := &v1.Pod{}
+	assert.Equal(t, getPodDeletionCost(pod), int32(0))
+	pod.Annotations = map[string]string{constants.AnnotationPodDeletionCost: "-10"}
+	assert.Equal(t, getPodDeletionCost(pod), int32(-10))
+	pod.Annotations[constants.AnnotationPodDeletionCost] = "cheap"
+	assert.Equal(t, getPodDeletionCost(pod), int32(0))
+}
+
+func TestSelectReleaseVictims(t *testing.T) {
+	schedulerConf := conf.GetSchedulerConf()
+	policy := schedulerConf.VictimSelectionPolicy
+	defer func() {
+		schedulerConf.VictimSelectionPolicy = policy
+	}()
+
+	context := initContextForTest()
+	context.AddApplication(&interfaces.AddApplicationRequest{
+		Metadata: interfaces.ApplicationMetadata{
+			ApplicationID: "app00001",
+			QueueName:     "root.a",
+			User:          "test-user",
+		},
+	})
+	// the task ID, node, cpu and deletion cost of the bound tasks
+	bound := []struct {
+		taskID string
+		node   string
+		cpu    string
+		cost   string
+	}{
+		{"task00001", "node-1", "1", "100"},
+		{"task00002", "node-1", "1", "-5"},
+		{"task00003", "node-1", "1", "10"},
+		{"task00004", "node-2", "1", "-100"},
+		{"task00005", "node-1", "2", "-100"},
+	}
+	for _, b := range bound {
+		context.AddTask(&interfaces.AddTaskRequest{
+			Metadata: interfaces.TaskMetadata{
+				ApplicationID: "app00001",
+				TaskID:        b.taskID,
+				Pod: &v1.Pod{
+					ObjectMeta: apis.ObjectMeta{
+						Name:        "pod-" + b.taskID,
+						Annotations: map[string]string{constants.AnnotationPodDeletionCost: b.cost},
+					},
+					Spec: v1.PodSpec{
+						Containers: []v1.Container{{
+							Resources: v1.ResourceRequirements{
+								Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse(b.cpu)},
+							},
+						}},
+					},
+				},
+			},
+		})
+		task, err := context.getTask("app00001", b.taskID)
+		assert.NilError(t, err)
+		task.setAllocated(b.node, "uuid-"+b.taskID)
+		task.sm.SetState(events.States().Task.Bound)
+	}
+	release := func(allocUUID string) []*si.AllocationRelease {
+		return []*si.AllocationRelease{{
+			ApplicationID:   "app00001",
+			UUID:            allocUUID,
+			TerminationType: si.TerminationType_PREEMPTED_BY_SCHEDULER,
+		}}
+	}
+	releasedTask := func(allocUUID string) string {
+		task, err := context.GetTaskByAllocation("app00001", allocUUID)
+		assert.NilError(t, err)
+		return task.GetTaskID()
+	}
+
+	// the core choice is kept by default
+	context.SelectReleaseVictims(release("uuid-task00001"))
+	assert.Equal(t, releasedTask("uuid-task00001"), "task00001")
+
+	// the cheapest pod on the same node with the same resources is deleted instead,
+	// the expensive task keeps running with the allocation of the cheap one
+	schedulerConf.VictimSelectionPolicy = conf.VictimSelectionDeletionCost
+	context.SelectReleaseVictims(release("uuid-task00001"))
+	assert.Equal(t, releasedTask("uuid-task00001"), "task00002")
+	assert.Equal(t, releasedTask("uuid-task00002"), "task00001")
+
+	// a timed out allocation is not swapped
+	releases := release("uuid-task00003")
+	releases[0].TerminationType = si.TerminationType_TIMEOUT
+	context.SelectReleaseVictims(releases)
+	assert.Equal(t, releasedTask("uuid-task00003"), "task00003")
+
+	// the victims released together are not swapped with each other
+	releases = append(release("uuid-task00003"), release("uuid-task00001")...)
+	context.SelectReleaseVictims(releases)
+	assert.Equal(t, releasedTask("uuid-task00003"), "task00003")
+	assert.Equal(t, releasedTask("uuid-task00001"), "task00002")
+}
@@ -0,0 +1,153 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/metrics"
+)
+
+// reservationGate limits how many apps of a queue reserve resources for their gang at the same time,
+// too many apps reserving at once can hold the whole cluster with placeholders without any gang being
+// satisfied. The apps over the limit wait in the Accepted state and are let in in arrival order.
+type reservationGate struct {
+	queues map[reservationQueue]*queueReservations // queue -> reserving and waiting apps
+	apps   map[string]reservationQueue             // app ID -> queue the app is tracked in
+	sync.Mutex
+}
+
+// reservationQueue identifies a queue of the core, the same queue exists in each partition of each RM
+type reservationQueue struct {
+	rmID      string
+	partition string
+	queue     string
+}
+
+type queueReservations struct {
+	reserving map[string]bool
+	waiting   []string             // app IDs in arrival order
+	waitStart map[string]time.Time // time the app started waiting
+}
+
+func newReservationGate() *reservationGate {
+	return &reservationGate{
+		queues: make(map[reservationQueue]*queueReservations),
+		apps:   make(map[string]reservationQueue),
+	}
+}
+
+// getReservationQueue returns the queue the app reserves in, the route of the app never changes once it is added
+func (app *Application) getReservationQueue() reservationQueue {
+	return reservationQueue{
+		rmID:      app.getRmID(),
+		partition: app.partition,
+		queue:     app.queue,
+	}
+}
+
+// releaseReservation gives up the slot of the app in its queue, or its place among the waiting apps,
+// this is a noop if the app is not added to a context
+func (app *Application) releaseReservation() {
+	if app.context != nil {
+		app.context.reservations.release(app.applicationID)
+	}
+}
+
+// tryAcquire returns true when the app may start reserving, the app then holds a slot of its queue until it
+// is released. An app that may not reserve yet is queued, queued returns true the first time the app waits.
+// A limit of 0 or less lets the app in immediately, the reserving app is still counted in the queue.
+func (g *reservationGate) tryAcquire(appID string, queue reservationQueue, limit int) (acquired bool, queued bool) {
+	g.Lock()
+	defer g.Unlock()
+	q, ok := g.queues[queue]
+	if !ok {
+		q = &queueReservations{
+			reserving: make(map[string]bool),
+			waitStart: make(map[string]time.Time),
+		}
+		g.queues[queue] = q
+	}
+	if q.reserving[appID] {
+		return true, false
+	}
+	g.apps[appID] = queue
+	position := -1
+	for i, waiting := range q.waiting {
+		if waiting == appID {
+			position = i
+			break
+		}
+	}
+	if position < 0 {
+		position = len(q.waiting)
+		q.waiting = append(q.waiting, appID)
+		q.waitStart[appID] = time.Now()
+		queued = true
+	}
+	// the apps ahead of this app take the free slots first
+	if limit > 0 && position >= limit-len(q.reserving) {
+		metrics.GetReservationMetrics().SetQueue(queue.queue, len(q.reserving), len(q.waiting))
+		return false, queued
+	}
+	q.waiting = append(q.waiting[:position], q.waiting[position+1:]...)
+	if !queued {
+		metrics.GetReservationMetrics().ObserveWait(time.Since(q.waitStart[appID]))
+	}
+	delete(q.waitStart, appID)
+	q.reserving[appID] = true
+	metrics.GetReservationMetrics().SetQueue(queue.queue, len(q.reserving), len(q.waiting))
+	return true, false
+}
+
+// release frees the slot held by the app, or removes the app from the waiting apps of its queue,
+// this is a noop if the app is not tracked
+func (g *reservationGate) release(appID string) {
+	g.Lock()
+	defer g.Unlock()
+	queue, ok := g.apps[appID]
+	if !ok {
+		return
+	}
+	delete(g.apps, appID)
+	q := g.queues[queue]
+	delete(q.reserving, appID)
+	delete(q.waitStart, appID)
+	for i, waiting := range q.waiting {
+		if waiting == appID {
+			q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+			break
+		}
+	}
+	metrics.GetReservationMetrics().SetQueue(queue.queue, len(q.reserving), len(q.waiting))
+	if len(q.reserving) == 0 && len(q.waiting) == 0 {
+		delete(g.queues, queue)
+	}
+}
+
+// get returns the number of reserving and waiting apps of a queue
+func (g *reservationGate) get(queue reservationQueue) (int, int) {
+	g.Lock()
+	defer g.Unlock()
+	if q, ok := g.queues[queue]; ok {
+		return len(q.reserving), len(q.waiting)
+	}
+	return 0, 0
+}
@@ -0,0 +1,150 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/apis/yunikorn.apache.org/v1alpha1"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/dispatcher"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/metrics"
+)
+
+func TestReservationGate(t *testing.T) {
+	gate := newReservationGate()
+	queue := reservationQueue{rmID: "rm-1", partition: "default", queue: "root.gate-test"}
+
+	// no limit, the apps are only counted
+	acquired, queued := gate.tryAcquire("app-1", queue, 0)
+	assert.Assert(t, acquired && !queued)
+	reserving, waiting := gate.get(queue)
+	assert.Equal(t, reserving, 1)
+	assert.Equal(t, waiting, 0)
+
+	// the limit is reached, the apps wait in arrival order
+	acquired, queued = gate.tryAcquire("app-2", queue, 1)
+	assert.Assert(t, !acquired && queued)
+	acquired, queued = gate.tryAcquire("app-3", queue, 1)
+	assert.Assert(t, !acquired && queued)
+	acquired, queued = gate.tryAcquire("app-2", queue, 1)
+	assert.Assert(t, !acquired && !queued, "a waiting app is queued once")
+	reserving, waiting = metrics.GetReservationMetrics().GetQueue(queue.queue)
+	assert.Equal(t, reserving, 1)
+	assert.Equal(t, waiting, 2)
+
+	// a reserving app keeps its slot
+	acquired, _ = gate.tryAcquire("app-1", queue, 1)
+	assert.Assert(t, acquired)
+
+	// the freed slot goes to the first waiting app
+	gate.release("app-1")
+	acquired, _ = gate.tryAcquire("app-3", queue, 1)
+	assert.Assert(t, !acquired, "app-3 must not pass app-2")
+	acquired, _ = gate.tryAcquire("app-2", queue, 1)
+	assert.Assert(t, acquired)
+
+	// a waiting app that gives up its place lets the next app in
+	acquired, queued = gate.tryAcquire("app-4", queue, 2)
+	assert.Assert(t, !acquired && queued)
+	gate.release("app-3")
+	acquired, _ = gate.tryAcquire("app-4", queue, 2)
+	assert.Assert(t, acquired)
+	reserving, waiting = gate.get(queue)
+	assert.Equal(t, reserving, 2)
+	assert.Equal(t, waiting, 0)
+
+	// the same queue of another partition has its own slots
+	otherQueue := reservationQueue{rmID: "rm-1", partition: "other", queue: "root.gate-test"}
+	acquired, _ = gate.tryAcquire("app-5", otherQueue, 1)
+	assert.Assert(t, acquired)
+	reserving, waiting = gate.get(otherQueue)
+	assert.Equal(t, reserving, 1)
+	assert.Equal(t, waiting, 0)
+	gate.release("app-5")
+
+	// the queue is forgotten once it has no app
+	gate.release("app-2")
+	gate.release("app-4")
+	gate.release("app-unknown")
+	assert.Equal(t, len(gate.queues), 0)
+	assert.Equal(t, len(gate.apps), 0)
+}
+
+func TestMaxReservingApps(t *testing.T) {
+	context := initContextForTest()
+	dispatcher.RegisterEventHandler(dispatcher.EventTypeApp, context.ApplicationEventHandler())
+	dispatcher.Start()
+	defer dispatcher.Stop()
+
+	mockedAPIProvider := client.NewMockedAPIProvider()
+	mgr := NewPlaceholderManager(mockedAPIProvider.GetAPIs())
+	mgr.Start()
+	defer mgr.Stop()
+
+	queue := "root.max-reserving"
+	apps := make([]*Application, 0, 2)
+	for _, appID := range []string{"app-max-reserving-1", "app-max-reserving-2"} {
+		app := NewApplication(appID, queue, "test-user",
+			map[string]string{}, mockedAPIProvider.GetAPIs().SchedulerAPI)
+		app.setTaskGroups([]v1alpha1.TaskGroup{
+			{
+				Name:      "test-group-1",
+				MinMember: 1,
+				MinResource: map[string]resource.Quantity{
+					v1.ResourceCPU.String(): resource.MustParse("500m"),
+				},
+			},
+		})
+		app.setMaxReservingApps(1)
+		app.context = context
+		context.applications.put(app)
+		assert.NilError(t, app.handle(NewSubmitApplicationEvent(app.applicationID)))
+		assert.NilError(t, app.handle(NewSimpleApplicationEvent(app.applicationID, events.AcceptApplication)))
+		apps = append(apps, app)
+	}
+
+	// only one app of the queue may reserve, the other one waits
+	apps[0].Schedule()
+	assertAppState(t, apps[0], events.States().Application.Reserving, 3*time.Second)
+	apps[1].Schedule()
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, apps[1].GetApplicationState(), events.States().Application.Accepted)
+	reserving, waiting := context.reservations.get(apps[0].getReservationQueue())
+	assert.Equal(t, reserving, 1)
+	assert.Equal(t, waiting, 1)
+
+	// the waiting app reserves once the first app has left the Reserving state
+	assert.NilError(t, apps[0].handle(NewRunApplicationEvent(apps[0].applicationID)))
+	apps[1].Schedule()
+	assertAppState(t, apps[1], events.States().Application.Reserving, 3*time.Second)
+	reserving, waiting = context.reservations.get(apps[0].getReservationQueue())
+	assert.Equal(t, reserving, 1)
+	assert.Equal(t, waiting, 0)
+
+	assert.NilError(t, context.RemoveApplicationInternal(apps[1].applicationID))
+	reserving, _ = context.reservations.get(apps[0].getReservationQueue())
+	assert.Equal(t, reserving, 0)
+}
@@ -0,0 +1,143 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/webservice/dao"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+type resourceUsage struct {
+	allocated *si.Resource
+	tasks     int
+}
+
+func (u *resourceUsage) add(res *si.Resource) {
+	u.allocated = common.Add(u.allocated, res)
+	u.tasks++
+}
+
+// GetResourceUsage returns the resources allocated to the bound and running tasks, aggregated per queue and per user,
+// the usage is computed from the shim cache only, so it can be compared against the core accounting.
+func (ctx *Context) GetResourceUsage() *dao.ResourceUsage {
+	queues := make(map[string]*resourceUsage)
+	users := make(map[string]*resourceUsage)
+	ctx.applications.forEach(func(app *Application) {
+		app.lock.RLock()
+		for _, task := range app.getTasks(boundTaskStates...) {
+			if _, ok := queues[app.queue]; !ok {
+				queues[app.queue] = &resourceUsage{}
+			}
+			if _, ok := users[app.user]; !ok {
+				users[app.user] = &resourceUsage{}
+			}
+			queues[app.queue].add(task.resource)
+			users[app.user].add(task.resource)
+		}
+		app.lock.RUnlock()
+	})
+
+	usage := &dao.ResourceUsage{
+		Queues: make([]dao.QueueResourceUsage, 0, len(queues)),
+		Users:  make([]dao.UserResourceUsage, 0, len(users)),
+	}
+	for queue, u := range queues {
+		usage.Queues = append(usage.Queues, dao.QueueResourceUsage{
+			QueueName: queue,
+			Allocated: getResourceMap(u.allocated),
+			Tasks:     u.tasks,
+		})
+	}
+	for user, u := range users {
+		usage.Users = append(usage.Users, dao.UserResourceUsage{
+			User:      user,
+			Allocated: getResourceMap(u.allocated),
+			Tasks:     u.tasks,
+		})
+	}
+	sort.Slice(usage.Queues, func(i, j int) bool {
+		return usage.Queues[i].QueueName < usage.Queues[j].QueueName
+	})
+	sort.Slice(usage.Users, func(i, j int) bool {
+		return usage.Users[i].User < usage.Users[j].User
+	})
+	return usage
+}
+
+// GetApplicationsByQueue returns the apps submitted to the queue or to one of its child queues,
+// with their running and pending resources.
+func (ctx *Context) GetApplicationsByQueue(queue string) *dao.ApplicationsUsage {
+	return ctx.getApplicationsUsage(func(app *Application) bool {
+		appQueue := app.GetQueue()
+		return appQueue == queue || strings.HasPrefix(appQueue, queue+".")
+	})
+}
+
+// GetApplicationsByUser returns the apps submitted by the user with their running and pending resources
+func (ctx *Context) GetApplicationsByUser(user string) *dao.ApplicationsUsage {
+	return ctx.getApplicationsUsage(func(app *Application) bool {
+		return app.GetUser() == user
+	})
+}
+
+// getApplicationsUsage aggregates the resources of the tasks of the selected apps, like the resource usage
+// the running resources are allocated to the bound and running tasks, computed from the shim cache only.
+func (ctx *Context) getApplicationsUsage(filter func(app *Application) bool) *dao.ApplicationsUsage {
+	running := &resourceUsage{}
+	pending := &resourceUsage{}
+	result := &dao.ApplicationsUsage{
+		Applications: make([]dao.ApplicationUsage, 0),
+	}
+	for _, app := range ctx.SelectApplications(filter) {
+		appRunning := &resourceUsage{}
+		appPending := &resourceUsage{}
+		app.lock.RLock()
+		for _, task := range app.getTasks(boundTaskStates...) {
+			appRunning.add(task.resource)
+			running.add(task.resource)
+		}
+		for _, task := range app.getTasks(pendingTaskStates...) {
+			appPending.add(task.resource)
+			pending.add(task.resource)
+		}
+		result.Applications = append(result.Applications, dao.ApplicationUsage{
+			ApplicationID: app.applicationID,
+			Queue:         app.queue,
+			User:          app.user,
+			State:         app.sm.Current(),
+			Running:       getResourceMap(appRunning.allocated),
+			RunningTasks:  appRunning.tasks,
+			Pending:       getResourceMap(appPending.allocated),
+			PendingTasks:  appPending.tasks,
+		})
+		app.lock.RUnlock()
+	}
+	result.Running = getResourceMap(running.allocated)
+	result.RunningTasks = running.tasks
+	result.Pending = getResourceMap(pending.allocated)
+	result.PendingTasks = pending.tasks
+	sort.Slice(result.Applications, func(i, j int) bool {
+		return result.Applications[i].ApplicationID < result.Applications[j].ApplicationID
+	})
+	return result
+}
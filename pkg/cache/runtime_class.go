@@ -0,0 +1,45 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	nodeListerV1beta1 "k8s.io/client-go/listers/node/v1beta1"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+)
+
+// runtimeClassOverhead returns the resolver of the fixed pod overhead of the runtime classes from the lister,
+// a runtime class that is not found has no overhead.
+func runtimeClassOverhead(lister nodeListerV1beta1.RuntimeClassLister) func(runtimeClassName string) v1.ResourceList {
+	return func(runtimeClassName string) v1.ResourceList {
+		runtimeClass, err := lister.Get(runtimeClassName)
+		if err != nil {
+			log.Logger().Debug("runtime class not found, no overhead is added",
+				zap.String("runtimeClassName", runtimeClassName),
+				zap.Error(err))
+			return nil
+		}
+		if runtimeClass.Overhead == nil {
+			return nil
+		}
+		return runtimeClass.Overhead.PodFixed
+	}
+}
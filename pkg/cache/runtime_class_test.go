@@ -0,0 +1,146 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	nodev1beta1 "k8s.io/api/node/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/apis/yunikorn.apache.org/v1alpha1"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/appmgmt/interfaces"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+)
+
+func TestRuntimeClassOverhead(t *testing.T) {
+	informer := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0).Node().V1beta1().RuntimeClasses()
+	assert.NilError(t, informer.Informer().GetIndexer().Add(&nodev1beta1.RuntimeClass{
+		ObjectMeta: apis.ObjectMeta{Name: "kata"},
+		Handler:    "kata",
+		Overhead: &nodev1beta1.Overhead{
+			PodFixed: v1.ResourceList{v1.ResourceCPU: resource.MustParse("250m")},
+		},
+	}))
+	assert.NilError(t, informer.Informer().GetIndexer().Add(&nodev1beta1.RuntimeClass{
+		ObjectMeta: apis.ObjectMeta{Name: "runc"},
+		Handler:    "runc",
+	}))
+	apiProvider := client.NewMockedAPIProvider()
+	apiProvider.GetAPIs().RuntimeClassInformer = informer
+	NewContext(apiProvider)
+	defer common.SetRuntimeClassOverheadResolver(nil)
+
+	overhead := common.GetRuntimeClassOverhead("kata")
+	assert.Equal(t, overhead.Cpu().MilliValue(), int64(250))
+	assert.Assert(t, common.GetRuntimeClassOverhead("runc") == nil)
+	assert.Assert(t, common.GetRuntimeClassOverhead("unknown") == nil)
+
+	// the placeholders run with the runtime class of the app, the placeholder ask includes the overhead
+	app := NewApplication("app01", "root.default",
+		"bob", map[string]string{constants.AppTagNamespace: "test"}, newMockSchedulerAPI())
+	app.setRuntimeClassName("kata")
+	app.setTaskGroups([]v1alpha1.TaskGroup{
+		{
+			Name:      "test-group-1",
+			MinMember: 4,
+			MinResource: map[string]resource.Quantity{
+				"cpu": resource.MustParse("1"),
+			},
+		},
+	})
+	assert.Equal(t, app.getPlaceholderAsk().Resources[constants.CPU].GetValue(), int64(5000))
+	holder := newPlaceholder("ph-name", app.getPlaceholderSpec(), app.taskGroups[0], 0)
+	assert.Equal(t, *holder.pod.Spec.RuntimeClassName, "kata")
+	assert.Equal(t, common.GetPodResource(holder.pod).Resources[constants.CPU].GetValue(), int64(1250))
+}
+
+func TestGangFeasibilityRuntimeClassOverhead(t *testing.T) {
+	informer := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0).Node().V1beta1().RuntimeClasses()
+	assert.NilError(t, informer.Informer().GetIndexer().Add(&nodev1beta1.RuntimeClass{
+		ObjectMeta: apis.ObjectMeta{Name: "kata"},
+		Handler:    "kata",
+		Overhead: &nodev1beta1.Overhead{
+			PodFixed: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+		},
+	}))
+	conf.GetSchedulerConf().SetTestMode(true)
+	apiProvider := client.NewMockedAPIProvider()
+	apiProvider.GetAPIs().RuntimeClassInformer = informer
+	apiProvider.GetAPIs().Conf.EnableGangFeasibilityCheck = true
+	context := NewContext(apiProvider)
+	defer common.SetRuntimeClassOverheadResolver(nil)
+	for _, name := range []string{"host0001", "host0002"} {
+		context.addNode(&v1.Node{
+			ObjectMeta: apis.ObjectMeta{
+				Name: name,
+				UID:  types.UID("uid_" + name),
+			},
+			Status: v1.NodeStatus{
+				Allocatable: v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse("4"),
+					v1.ResourceMemory: resource.MustParse("8Gi"),
+				},
+			},
+		})
+	}
+	addApp := func(appID, runtimeClassName string, members int32, cpu string) *Application {
+		context.AddApplication(&interfaces.AddApplicationRequest{
+			Metadata: interfaces.ApplicationMetadata{
+				ApplicationID:    appID,
+				QueueName:        "root.a",
+				User:             "test-user",
+				RuntimeClassName: runtimeClassName,
+				TaskGroups: []v1alpha1.TaskGroup{
+					{
+						Name:      "test-group",
+						MinMember: members,
+						MinResource: map[string]resource.Quantity{
+							v1.ResourceCPU.String(): resource.MustParse(cpu),
+						},
+					},
+				},
+			},
+		})
+		return context.applications.get(appID)
+	}
+
+	// the members fit in a node and the gang fits in the cluster without the overhead
+	assert.Equal(t, addApp("app00001", "", 2, "4").gangInfeasibleReason, "")
+	assert.Equal(t, addApp("app00002", "", 3, "2").gangInfeasibleReason, "")
+
+	// a member with the overhead does not fit in any node
+	app := addApp("app00003", "kata", 2, "4")
+	assert.Assert(t, strings.Contains(app.gangInfeasibleReason,
+		"vcore 5000 is requested and the largest node host0001 has 4000 allocatable"), app.gangInfeasibleReason)
+
+	// the gang with the overhead exceeds the cluster capacity
+	app = addApp("app00004", "kata", 3, "2")
+	assert.Assert(t, strings.Contains(app.gangInfeasibleReason, "exceeds the cluster capacity"), app.gangInfeasibleReason)
+}
@@ -0,0 +1,167 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/dispatcher"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/webservice/dao"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+// GetStateDump returns a snapshot of the entire cache, this includes the applications,
+// tasks, nodes, placeholder inventory, dispatcher queues and the scheduler configuration.
+func (ctx *Context) GetStateDump() *dao.StateDump {
+	dump := &dao.StateDump{
+		Timestamp:    time.Now(),
+		Applications: make([]dao.ApplicationInfo, 0),
+		Nodes:        ctx.nodes.getNodesInfo(),
+		Dispatcher: dao.DispatcherInfo{
+			EventQueueLength:   dispatcher.GetEventQueueLength(),
+			AsyncDispatchCount: dispatcher.GetAsyncDispatchCount(),
+		},
+		Config:     ctx.apiProvider.GetAPIs().Conf.Clone(),
+		Predicates: ctx.predictor.GetEnabledPredicates(),
+	}
+
+	ctx.applications.forEach(func(app *Application) {
+		appInfo := app.getApplicationInfo()
+		for _, task := range appInfo.Tasks {
+			if task.Placeholder {
+				dump.Placeholders.Total++
+			}
+		}
+		dump.Applications = append(dump.Applications, appInfo)
+	})
+
+	dump.Placeholders.OrphanPods = make([]string, 0)
+	if mgr := getPlaceholderManager(); mgr != nil {
+		dump.Placeholders.OrphanPods = mgr.getOrphanPodNames()
+	}
+	return dump
+}
+
+func (app *Application) getApplicationInfo() dao.ApplicationInfo {
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	appInfo := dao.ApplicationInfo{
+		ApplicationID:          app.applicationID,
+		QueueName:              app.queue,
+		Partition:              app.partition,
+		User:                   app.user,
+		Groups:                 append([]string(nil), app.groups...),
+		State:                  app.sm.Current(),
+		Tags:                   make(map[string]string, len(app.tags)),
+		TaskGroups:             make([]dao.TaskGroupInfo, 0, len(app.taskGroups)),
+		PlaceholderAsk:         getResourceMap(app.placeholderAsk),
+		PlaceholderTimeoutSecs: app.placeholderTimeoutInSec,
+		Tasks:                  make([]dao.TaskInfo, 0, len(app.taskMap)),
+		QueueAsks:              app.getQueueAsks(),
+	}
+	// the dump is encoded after the lock is released, it must not share the maps of the app
+	for k, v := range app.tags {
+		appInfo.Tags[k] = v
+	}
+	for _, tg := range app.taskGroups {
+		tgInfo := dao.TaskGroupInfo{
+			Name:        tg.Name,
+			MinMember:   tg.MinMember,
+			MinResource: getResourceMap(common.GetTGResource(tg.MinResource, 1)),
+		}
+		if app.placeholderProgress != nil {
+			if progress, ok := app.placeholderProgress.get(tg.Name); ok {
+				tgInfo.Progress = &dao.PlaceholderProgressInfo{
+					Desired: progress.desired,
+					Created: progress.created,
+					Failed:  progress.failed,
+				}
+			}
+		}
+		appInfo.TaskGroups = append(appInfo.TaskGroups, tgInfo)
+	}
+	for _, task := range app.taskMap {
+		appInfo.Tasks = append(appInfo.Tasks, task.getTaskInfo())
+	}
+	return appInfo
+}
+
+func (task *Task) getTaskInfo() dao.TaskInfo {
+	task.lock.RLock()
+	defer task.lock.RUnlock()
+	return dao.TaskInfo{
+		TaskID:         task.taskID,
+		TaskAlias:      task.alias,
+		State:          task.sm.Current(),
+		Resource:       getResourceMap(task.resource),
+		AllocationUUID: task.allocationUUID,
+		NodeName:       task.nodeName,
+		Placeholder:    task.placeholder,
+		TaskGroupName:  task.taskGroupName,
+		TaskGroupIndex: task.taskGroupIndex,
+		QueueName:      task.queue,
+		Quarantined:    task.quarantined,
+		CreateTime:     task.createTime,
+	}
+}
+
+func (nc *schedulerNodes) getNodesInfo() []dao.NodeInfo {
+	nc.lock.RLock()
+	defer nc.lock.RUnlock()
+	nodes := make([]dao.NodeInfo, 0, len(nc.nodesMap))
+	for _, node := range nc.nodesMap {
+		nodes = append(nodes, node.getNodeInfo())
+	}
+	return nodes
+}
+
+func (n *SchedulerNode) getNodeInfo() dao.NodeInfo {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+	return dao.NodeInfo{
+		NodeName:    n.name,
+		NodeUID:     n.uid,
+		State:       n.fsm.Current(),
+		Schedulable: n.schedulable,
+		Capacity:    getResourceMap(n.capacity),
+		Occupied:    getResourceMap(n.occupied),
+	}
+}
+
+func (mgr *PlaceholderManager) getOrphanPodNames() []string {
+	mgr.Lock()
+	defer mgr.Unlock()
+	names := make([]string, 0, len(mgr.orphanPods))
+	for _, pod := range mgr.orphanPods {
+		names = append(names, pod.Namespace+"/"+pod.Name)
+	}
+	return names
+}
+
+func getResourceMap(res *si.Resource) map[string]int64 {
+	result := make(map[string]int64)
+	if res == nil {
+		return result
+	}
+	for name, quantity := range res.Resources {
+		result[name] = quantity.Value
+	}
+	return result
+}
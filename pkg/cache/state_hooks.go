@@ -0,0 +1,171 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/looplab/fsm"
+	"go.uber.org/zap"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/metrics"
+)
+
+// TransitionPhase is the phase of a state transition a hook is run in
+type TransitionPhase string
+
+const (
+	// BeforeTransition hooks are run before the object leaves its current state
+	BeforeTransition TransitionPhase = "before"
+	// AfterTransition hooks are run once the object has entered its new state
+	AfterTransition TransitionPhase = "after"
+)
+
+// the kinds of objects whose state transitions are hooked
+const (
+	applicationHook = "application"
+	taskHook        = "task"
+)
+
+// StateTransition is the state transition of an app or a task passed to the state hooks
+type StateTransition struct {
+	ApplicationID string
+	// empty for the transitions of an app
+	TaskID string
+	Event  string
+	From   string
+	To     string
+}
+
+// StateHook is run on a state transition of an app or a task. The hooks are run by the state machine with
+// the lock of the app or the task held: a hook must return quickly and must not call back into the cache,
+// long running work, e.g. notifying an external workflow engine, must be done asynchronously.
+// A panic of a hook is recovered and logged, it does not affect the transition nor the other hooks.
+type StateHook func(transition StateTransition)
+
+type stateHook struct {
+	name   string
+	kind   string
+	phase  TransitionPhase
+	states map[string]bool
+	fn     StateHook
+}
+
+// the hooks in registration order
+var stateHooks = make([]*stateHook, 0)
+var stateHooksLock sync.RWMutex
+
+// RegisterApplicationStateHook registers a hook run before or after an app enters one of the given states,
+// the hook is run on all the transitions of the apps when no state is given.
+// A registered hook with the same name is replaced.
+func RegisterApplicationStateHook(name string, phase TransitionPhase, hook StateHook, states ...string) {
+	registerStateHook(name, applicationHook, phase, hook, states)
+}
+
+// RegisterTaskStateHook registers a hook run before or after a task enters one of the given states,
+// the hook is run on all the transitions of the tasks when no state is given.
+// A registered hook with the same name is replaced.
+func RegisterTaskStateHook(name string, phase TransitionPhase, hook StateHook, states ...string) {
+	registerStateHook(name, taskHook, phase, hook, states)
+}
+
+// UnregisterStateHook removes the hook with the given name, it is a noop if there is no such hook
+func UnregisterStateHook(name string) {
+	stateHooksLock.Lock()
+	defer stateHooksLock.Unlock()
+	for i, h := range stateHooks {
+		if h.name == name {
+			stateHooks = append(stateHooks[:i:i], stateHooks[i+1:]...)
+			return
+		}
+	}
+}
+
+func registerStateHook(name, kind string, phase TransitionPhase, fn StateHook, states []string) {
+	hook := &stateHook{
+		name:  name,
+		kind:  kind,
+		phase: phase,
+		fn:    fn,
+	}
+	if len(states) > 0 {
+		hook.states = make(map[string]bool, len(states))
+		for _, state := range states {
+			hook.states[state] = true
+		}
+	}
+	stateHooksLock.Lock()
+	defer stateHooksLock.Unlock()
+	for i, h := range stateHooks {
+		if h.name == name {
+			// the slice is shared with the running hooks, it is copied before it is changed
+			hooks := make([]*stateHook, len(stateHooks))
+			copy(hooks, stateHooks)
+			hooks[i] = hook
+			stateHooks = hooks
+			return
+		}
+	}
+	stateHooks = append(stateHooks[:len(stateHooks):len(stateHooks)], hook)
+}
+
+// runStateHooks runs the hooks of the kind and the phase matching the destination of the transition
+func runStateHooks(kind string, phase TransitionPhase, applicationID, taskID string, event *fsm.Event) {
+	stateHooksLock.RLock()
+	hooks := stateHooks
+	stateHooksLock.RUnlock()
+	if len(hooks) == 0 {
+		return
+	}
+	transition := StateTransition{
+		ApplicationID: applicationID,
+		TaskID:        taskID,
+		Event:         event.Event,
+		From:          event.Src,
+		To:            event.Dst,
+	}
+	for _, hook := range hooks {
+		if hook.kind == kind && hook.phase == phase && (hook.states == nil || hook.states[event.Dst]) {
+			hook.run(transition)
+		}
+	}
+}
+
+// run calls the hook, a panic is recovered so that it does not break the state machine of the object
+func (h *stateHook) run(transition StateTransition) {
+	start := time.Now()
+	defer func() {
+		metrics.GetStateHookMetrics().ObserveHook(h.name, time.Since(start))
+		if r := recover(); r != nil {
+			metrics.GetStateHookMetrics().IncHookPanic(h.name)
+			log.Logger().Error("state hook panicked",
+				zap.String("hook", h.name),
+				zap.String("phase", string(h.phase)),
+				zap.String("appID", transition.ApplicationID),
+				zap.String("taskID", transition.TaskID),
+				zap.String("source", transition.From),
+				zap.String("destination", transition.To),
+				zap.Any("panic", r),
+				zap.Stack("stack"))
+		}
+	}()
+	h.fn(transition)
+}
@@ -0,0 +1,118 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/metrics"
+)
+
+func TestApplicationStateHooks(t *testing.T) {
+	transitions := make([]string, 0)
+	record := func(prefix string) StateHook {
+		return func(transition StateTransition) {
+			assert.Equal(t, transition.ApplicationID, "app00001")
+			assert.Equal(t, transition.TaskID, "")
+			transitions = append(transitions, prefix+":"+transition.From+"->"+transition.To)
+		}
+	}
+	RegisterApplicationStateHook("test-before", BeforeTransition, record("before"),
+		events.States().Application.Submitted)
+	RegisterApplicationStateHook("test-after", AfterTransition, record("after"))
+	// the panic of a hook does not break the transition nor the other hooks
+	RegisterApplicationStateHook("test-panic", AfterTransition, func(transition StateTransition) {
+		panic("test panic")
+	})
+	RegisterApplicationStateHook("test-last", AfterTransition, record("last"))
+	// the hooks of the tasks are not run
+	RegisterTaskStateHook("test-task", BeforeTransition, record("task"))
+	defer func() {
+		for _, name := range []string{"test-before", "test-after", "test-panic", "test-last", "test-task"} {
+			UnregisterStateHook(name)
+		}
+	}()
+	panics := metrics.GetStateHookMetrics().GetHookPanics("test-panic")
+
+	app := NewApplication("app00001", "root.abc", "testuser", map[string]string{}, newMockSchedulerAPI())
+	err := app.handle(NewSubmitApplicationEvent(app.applicationID))
+	assert.NilError(t, err)
+	assertAppState(t, app, events.States().Application.Submitted, 10*time.Second)
+	assert.DeepEqual(t, transitions, []string{
+		"before:New->Submitted",
+		"after:New->Submitted",
+		"last:New->Submitted",
+	})
+	assert.Equal(t, metrics.GetStateHookMetrics().GetHookPanics("test-panic"), panics+1)
+	assert.Assert(t, metrics.GetStateHookMetrics().GetHookCount("test-after") > 0)
+
+	// a hook with the same name is replaced, the order of the hooks is kept
+	RegisterApplicationStateHook("test-after", AfterTransition, record("replaced"),
+		events.States().Application.Failed)
+	UnregisterStateHook("test-last")
+	transitions = transitions[:0]
+	err = app.handle(NewFailApplicationEvent(app.applicationID, "test failure"))
+	assert.NilError(t, err)
+	assertAppState(t, app, events.States().Application.Failed, 10*time.Second)
+	assert.DeepEqual(t, transitions, []string{
+		"replaced:Submitted->Failed",
+	})
+}
+
+func TestTaskStateHooks(t *testing.T) {
+	context := initContextForTest()
+	pod := &v1.Pod{
+		ObjectMeta: apis.ObjectMeta{
+			Name: "pod-hook-test-00001",
+			UID:  "UID-00001",
+		},
+	}
+	app := NewApplication("app00001", "root.abc", "testuser", map[string]string{}, newMockSchedulerAPI())
+	task := NewTask("task00001", app, context, pod)
+
+	transitions := make([]StateTransition, 0)
+	RegisterTaskStateHook("test-task", AfterTransition, func(transition StateTransition) {
+		transitions = append(transitions, transition)
+	}, events.States().Task.Pending)
+	defer UnregisterStateHook("test-task")
+
+	err := task.handle(NewSimpleTaskEvent(task.applicationID, task.taskID, events.InitTask))
+	assert.NilError(t, err)
+	assert.Equal(t, task.GetTaskState(), events.States().Task.Pending)
+	err = task.handle(NewSubmitTaskEvent(app.applicationID, task.taskID))
+	assert.NilError(t, err)
+	assert.Equal(t, task.GetTaskState(), events.States().Task.Scheduling)
+
+	// only the transition to the hooked state is seen
+	assert.DeepEqual(t, transitions, []StateTransition{
+		{
+			ApplicationID: "app00001",
+			TaskID:        "task00001",
+			Event:         string(events.InitTask),
+			From:          events.States().Task.New,
+			To:            events.States().Task.Pending,
+		},
+	})
+}
@@ -0,0 +1,232 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	coredao "github.com/apache/incubator-yunikorn-core/pkg/webservice/dao"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/metrics"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+// kinds of divergences between the allocations of the shim and the allocations of the core
+const (
+	DivergenceMissing = "missing" // a bound task whose allocation is unknown to the core
+	DivergenceGhost   = "ghost"   // an allocation of the core unknown to the shim
+)
+
+// the allocations of the core are read from its web service with this timeout
+const coreWebServiceTimeout = 10 * time.Second
+
+// coreApplicationLister returns the apps of the core with their allocations
+type coreApplicationLister func() ([]*coredao.ApplicationDAOInfo, error)
+
+// stateReconciler periodically compares the allocations of the bound tasks with the allocations of the core.
+// An allocation is in flight for a short time after it was made or released, a divergence is only reported
+// once it is found by two comparisons in a row. When healing is enabled the resources of a bound task unknown
+// to the core are occupied on its node, like for a pre-bound pod, and an allocation unknown to the shim is released.
+type stateReconciler struct {
+	ctx      *Context
+	list     coreApplicationLister
+	autoHeal bool
+	suspects map[string]bool
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+func newStateReconciler(ctx *Context, list coreApplicationLister, autoHeal bool) *stateReconciler {
+	return &stateReconciler{
+		ctx:      ctx,
+		list:     list,
+		autoHeal: autoHeal,
+		suspects: make(map[string]bool),
+		stopChan: make(chan struct{}),
+	}
+}
+
+// StartStateReconciler starts comparing the allocations with the core periodically, this is a noop if it is disabled
+func (ctx *Context) StartStateReconciler() {
+	configs := ctx.apiProvider.GetAPIs().Conf
+	interval := configs.GetReconcileInterval()
+	if interval == 0 || ctx.reconciler != nil {
+		return
+	}
+	ctx.reconciler = newStateReconciler(ctx, listCoreApplications(configs.GetCoreWebAddress()), configs.ReconcileAutoHeal)
+	go wait.Until(ctx.reconciler.reconcile, interval, ctx.reconciler.stopChan)
+}
+
+// StopStateReconciler stops the periodic comparison with the core
+func (ctx *Context) StopStateReconciler() {
+	if ctx.reconciler != nil {
+		ctx.reconciler.stop()
+	}
+}
+
+func (r *stateReconciler) stop() {
+	r.stopOnce.Do(func() {
+		close(r.stopChan)
+	})
+}
+
+// listCoreApplications reads the apps of the core from its web service
+func listCoreApplications(address string) coreApplicationLister {
+	httpClient := &http.Client{Timeout: coreWebServiceTimeout}
+	url := fmt.Sprintf("http://%s/ws/v1/apps", address)
+	return func() ([]*coredao.ApplicationDAOInfo, error) {
+		resp, err := httpClient.Get(url)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("listing the apps of the core failed with status %s", resp.Status)
+		}
+		var apps []*coredao.ApplicationDAOInfo
+		if err = json.NewDecoder(resp.Body).Decode(&apps); err != nil {
+			return nil, err
+		}
+		return apps, nil
+	}
+}
+
+// reconcile compares the allocations once, the divergences confirmed by the previous comparison are reported
+func (r *stateReconciler) reconcile() {
+	apps, err := r.list()
+	if err != nil {
+		log.Logger().Warn("failed to list the allocations of the core, skipping the comparison", zap.Error(err))
+		return
+	}
+	coreAllocations := make(map[string]coredao.AllocationDAOInfo)
+	for _, app := range apps {
+		if rmID, _ := splitCorePartition(app.Partition); !conf.GetSchedulerConf().IsRegisteredCluster(rmID) {
+			continue
+		}
+		for _, alloc := range app.Allocations {
+			coreAllocations[alloc.UUID] = alloc
+		}
+	}
+
+	suspects := make(map[string]bool)
+	missing := make([]*Task, 0)
+	known := make(map[string]bool)
+	for _, app := range r.ctx.SelectApplications(nil) {
+		for _, task := range app.getAllocatedAndBoundTasks() {
+			allocUUID := task.getTaskAllocationUUID()
+			known[allocUUID] = true
+			if _, ok := coreAllocations[allocUUID]; ok || task.GetTaskState() == events.States().Task.Allocated ||
+				task.isPreBoundOccupied() {
+				continue
+			}
+			key := DivergenceMissing + "/" + allocUUID
+			suspects[key] = true
+			if r.suspects[key] {
+				missing = append(missing, task)
+			}
+		}
+	}
+	ghosts := make([]coredao.AllocationDAOInfo, 0)
+	for allocUUID, alloc := range coreAllocations {
+		if known[allocUUID] {
+			continue
+		}
+		key := DivergenceGhost + "/" + allocUUID
+		suspects[key] = true
+		if r.suspects[key] {
+			ghosts = append(ghosts, alloc)
+		}
+	}
+	r.suspects = suspects
+
+	reconcileMetrics := metrics.GetReconcileMetrics()
+	reconcileMetrics.SetDivergences(DivergenceMissing, len(missing))
+	reconcileMetrics.SetDivergences(DivergenceGhost, len(ghosts))
+	if len(missing) == 0 && len(ghosts) == 0 {
+		return
+	}
+	missingNames := make([]string, 0, len(missing))
+	for _, task := range missing {
+		missingNames = append(missingNames, task.alias)
+	}
+	ghostNames := make([]string, 0, len(ghosts))
+	for _, alloc := range ghosts {
+		ghostNames = append(ghostNames, alloc.ApplicationID+"/"+alloc.UUID)
+	}
+	log.Logger().Warn("the allocations of the shim and the core diverged",
+		zap.Int("missingInCore", len(missing)),
+		zap.String("missingTasks", utils.JoinWithLimit(missingNames, 10)),
+		zap.Int("unknownToShim", len(ghosts)),
+		zap.String("unknownAllocations", utils.JoinWithLimit(ghostNames, 10)),
+		zap.Bool("autoHeal", r.autoHeal))
+	if r.autoHeal {
+		r.heal(missing, ghosts)
+	}
+}
+
+// heal occupies the resources of the tasks missing in the core on their nodes and releases the ghost allocations
+func (r *stateReconciler) heal(missing []*Task, ghosts []coredao.AllocationDAOInfo) {
+	healed := 0
+	for _, task := range missing {
+		if task.occupyMissingAllocation() {
+			healed++
+		}
+	}
+	metrics.GetReconcileMetrics().AddHealed(DivergenceMissing, healed)
+
+	healed = 0
+	for _, alloc := range ghosts {
+		rmID, partition := splitCorePartition(alloc.Partition)
+		request := common.CreateReleaseAllocationRequestForTask(alloc.ApplicationID, alloc.UUID, partition,
+			si.TerminationType_STOPPED_BY_RM.String())
+		request.RmID = rmID
+		if err := r.ctx.apiProvider.GetAPIs().SchedulerAPI.Update(&request); err != nil {
+			log.Logger().Warn("failed to release an allocation unknown to the shim",
+				zap.String("appID", alloc.ApplicationID),
+				zap.String("allocationUUID", alloc.UUID),
+				zap.Error(err))
+			continue
+		}
+		healed++
+	}
+	metrics.GetReconcileMetrics().AddHealed(DivergenceGhost, healed)
+}
+
+// splitCorePartition splits the partition name used by the core, e.g. "[my-kube-cluster]default",
+// into the ID of the resource manager and the name of the partition in the shim
+func splitCorePartition(name string) (string, string) {
+	if strings.HasPrefix(name, "[") {
+		if end := strings.Index(name, "]"); end > 0 {
+			return name[1:end], name[end+1:]
+		}
+	}
+	return "", name
+}
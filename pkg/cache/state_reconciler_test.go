@@ -0,0 +1,121 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"testing"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	coredao "github.com/apache/incubator-yunikorn-core/pkg/webservice/dao"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/metrics"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+func TestSplitCorePartition(t *testing.T) {
+	rmID, partition := splitCorePartition("[my-kube-cluster]default")
+	assert.Equal(t, rmID, "my-kube-cluster")
+	assert.Equal(t, partition, "default")
+	rmID, partition = splitCorePartition("default")
+	assert.Equal(t, rmID, "")
+	assert.Equal(t, partition, "default")
+}
+
+func TestStateReconciler(t *testing.T) {
+	context := initContextForTest()
+	mockedAPIProvider, ok := context.apiProvider.(*client.MockedAPIProvider)
+	assert.Assert(t, ok)
+	released := make([]string, 0)
+	mockedAPIProvider.MockSchedulerApiUpdateFn(func(request *si.UpdateRequest) error {
+		if request.Releases != nil {
+			for _, release := range request.Releases.AllocationsToRelease {
+				released = append(released, release.UUID)
+			}
+		}
+		return nil
+	})
+	app := NewApplication("app00001", "root.a", "bob",
+		map[string]string{}, mockedAPIProvider.GetAPIs().SchedulerAPI)
+	context.applications.put(app)
+	for i := 1; i <= 2; i++ {
+		task := NewTask(fmt.Sprintf("task%05d", i), app, context, &v1.Pod{
+			ObjectMeta: apis.ObjectMeta{Name: fmt.Sprintf("pod-%d", i)},
+		})
+		app.addTask(task)
+		task.setAllocated("node-1", fmt.Sprintf("uuid-%d", i))
+		task.sm.SetState(events.States().Task.Bound)
+	}
+
+	// uuid-1 is lost by the core, uuid-3 is unknown to the shim,
+	// the allocations of a cluster the shim does not register with are ignored
+	partition := "[" + conf.GetSchedulerConf().ClusterID + "]default"
+	coreApps := []*coredao.ApplicationDAOInfo{
+		{
+			ApplicationID: "app00001",
+			Partition:     partition,
+			Allocations: []coredao.AllocationDAOInfo{
+				{UUID: "uuid-2", ApplicationID: "app00001", Partition: partition},
+				{UUID: "uuid-3", ApplicationID: "app00001", Partition: partition},
+			},
+		},
+		{
+			ApplicationID: "app00002",
+			Partition:     "[other-cluster]default",
+			Allocations: []coredao.AllocationDAOInfo{
+				{UUID: "uuid-4", ApplicationID: "app00002", Partition: "[other-cluster]default"},
+			},
+		},
+	}
+	reconciler := newStateReconciler(context, func() ([]*coredao.ApplicationDAOInfo, error) {
+		return coreApps, nil
+	}, true)
+	reconcileMetrics := metrics.GetReconcileMetrics()
+
+	// the first comparison only suspects the divergences, the allocations might be in flight
+	reconciler.reconcile()
+	assert.Equal(t, reconcileMetrics.GetDivergences(DivergenceMissing), 0)
+	assert.Equal(t, reconcileMetrics.GetDivergences(DivergenceGhost), 0)
+	assert.Equal(t, len(released), 0)
+
+	// confirmed by the second comparison and healed
+	healedMissing := reconcileMetrics.GetHealed(DivergenceMissing)
+	healedGhost := reconcileMetrics.GetHealed(DivergenceGhost)
+	reconciler.reconcile()
+	assert.Equal(t, reconcileMetrics.GetDivergences(DivergenceMissing), 1)
+	assert.Equal(t, reconcileMetrics.GetDivergences(DivergenceGhost), 1)
+	assert.DeepEqual(t, released, []string{"uuid-3"})
+	task, err := context.getTask("app00001", "task00001")
+	assert.NilError(t, err)
+	assert.Assert(t, task.isPreBoundOccupied())
+	assert.Equal(t, reconcileMetrics.GetHealed(DivergenceMissing), healedMissing+1)
+	assert.Equal(t, reconcileMetrics.GetHealed(DivergenceGhost), healedGhost+1)
+
+	// the core released the ghost, the occupied task is no longer a divergence
+	coreApps[0].Allocations = coreApps[0].Allocations[:1]
+	reconciler.reconcile()
+	reconciler.reconcile()
+	assert.Equal(t, reconcileMetrics.GetDivergences(DivergenceMissing), 0)
+	assert.Equal(t, reconcileMetrics.GetDivergences(DivergenceGhost), 0)
+}
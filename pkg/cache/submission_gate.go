@@ -0,0 +1,88 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"sort"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+)
+
+// GetSchedulableApplications returns the apps the scheduling loop can move forward. With strict FIFO enabled
+// on the queue of an app, the app is not submitted to the core while an older app of the same queue is not
+// running yet, so no pod of an app is scheduled before the apps submitted to the queue earlier are running.
+// Strict FIFO is enabled with the yunikorn.apache.org/strict-fifo annotation on the namespace of the apps.
+func (ctx *Context) GetSchedulableApplications() []*Application {
+	apps := ctx.SelectApplications(nil)
+	createTimes := make(map[string]int64, len(apps))
+	for _, app := range apps {
+		createTimes[app.applicationID] = app.getCreateTime().UnixNano()
+	}
+	sort.Slice(apps, func(i, j int) bool {
+		l, r := createTimes[apps[i].applicationID], createTimes[apps[j].applicationID]
+		if l == r {
+			return apps[i].applicationID < apps[j].applicationID
+		}
+		return l < r
+	})
+
+	// queues with an app that is not running yet
+	pendingQueues := make(map[string]bool)
+	schedulable := make([]*Application, 0, len(apps))
+	for _, app := range apps {
+		state := app.GetApplicationState()
+		if state == events.States().Application.New && isStrictFIFO(app) && pendingQueues[app.queue] {
+			log.Logger().Debug("app submission is held back by an older app in the queue",
+				zap.String("appID", app.applicationID),
+				zap.String("queue", app.queue))
+			continue
+		}
+		if isPendingSubmission(state) {
+			pendingQueues[app.queue] = true
+		}
+		schedulable = append(schedulable, app)
+	}
+	return schedulable
+}
+
+// isStrictFIFO returns true if the apps of the queue of the app are submitted in strict FIFO order
+func isStrictFIFO(app *Application) bool {
+	strictFIFO, err := strconv.ParseBool(app.GetTags()[constants.AppTagNamespaceStrictFIFO])
+	return err == nil && strictFIFO
+}
+
+// isPendingSubmission returns true if an app in the state has not reached the Running state yet
+func isPendingSubmission(state string) bool {
+	states := events.States().Application
+	switch state {
+	case states.New, states.Recovering, states.Submitted, states.Accepted, states.Reserving:
+		return true
+	}
+	return false
+}
+
+// ScheduleApplications runs a scheduling cycle over the schedulable apps, it is called in every scheduling interval
+func (ctx *Context) ScheduleApplications() {
+	ctx.appScheduler.schedule(ctx.GetSchedulableApplications())
+}
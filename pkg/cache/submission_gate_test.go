@@ -0,0 +1,80 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+)
+
+func TestGetSchedulableApplications(t *testing.T) {
+	context := initContextForTest()
+	now := time.Now()
+	newApp := func(appID, queue string, strictFIFO bool, age time.Duration) *Application {
+		tags := map[string]string{}
+		if strictFIFO {
+			tags[constants.AppTagNamespaceStrictFIFO] = "true"
+		}
+		app := NewApplication(appID, queue, "test-user", tags, newMockSchedulerAPI())
+		app.createTime = now.Add(-age)
+		context.applications.put(app)
+		return app
+	}
+	getIDs := func() []string {
+		ids := make([]string, 0)
+		for _, app := range context.GetSchedulableApplications() {
+			ids = append(ids, app.applicationID)
+		}
+		return ids
+	}
+
+	first := newApp("app-1", "root.a", true, 3*time.Minute)
+	newApp("app-2", "root.a", true, 2*time.Minute)
+	newApp("app-3", "root.b", true, 2*time.Minute)
+	newApp("app-4", "root.a", false, time.Minute)
+
+	// the second app of root.a waits for the first one, the app without strict FIFO is not held back
+	assert.DeepEqual(t, getIDs(), []string{"app-1", "app-3", "app-4"})
+
+	// the gate is kept until the first app is running
+	first.SetState(events.States().Application.Reserving)
+	assert.DeepEqual(t, getIDs(), []string{"app-1", "app-3", "app-4"})
+	first.SetState(events.States().Application.Running)
+	assert.DeepEqual(t, getIDs(), []string{"app-1", "app-2", "app-3", "app-4"})
+	first.SetState(events.States().Application.Failed)
+	assert.DeepEqual(t, getIDs(), []string{"app-1", "app-2", "app-3", "app-4"})
+
+	// an app is as old as its oldest pod
+	app := NewApplication("app-5", "root.a", "test-user", map[string]string{}, newMockSchedulerAPI())
+	task := NewTask("task-1", app, context, &v1.Pod{
+		ObjectMeta: apis.ObjectMeta{
+			Name: "pod-1",
+		},
+	})
+	task.createTime = now.Add(-time.Hour)
+	app.addTask(task)
+	assert.Equal(t, app.getCreateTime(), now.Add(-time.Hour))
+}
@@ -20,6 +20,7 @@ package cache
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -27,14 +28,21 @@ import (
 
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/appmgmt/interfaces"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/dispatcher"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/metrics"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/trace"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/webservice/dao"
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 
 	"github.com/looplab/fsm"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
 )
 
 type Task struct {
@@ -49,12 +57,43 @@ type Task struct {
 	nodeName        string
 	createTime      time.Time
 	taskGroupName   string
+	taskGroupIndex  int32 // index of the member in the task group, -1 if not set
+	taskGroupTotal  int32 // min members of the task group
+	indexRequested  bool  // the index of a real member is set in its pod
 	placeholder     bool
+	nonGang         bool   // opted out of the gang reservation, scheduled while the app is reserving
+	queue           string // the queue the task is scheduled in, empty when it is the queue of the app
 	terminationType string
+	forceReplaced   bool   // the placeholder is deleted by the administrator and created again once it is gone
+	excludedNode    string // the node the replacement of a force replaced placeholder must not run on
+	quarantined     string // the conflicts of the scheduling directives of the pod, the task is never scheduled
 	sm              *fsm.FSM
 	lock            *sync.RWMutex
+	conditionLock   sync.Mutex // serializes the updates of the pod conditions
+	conditionPod    *v1.Pod    // the latest version of the pod written by the condition updates, guarded by conditionLock
+
+	// the time the task was last submitted to the scheduler core,
+	// and the timer that fires when the task is not allocated in time
+	schedulingStartTime time.Time
+	schedulingTimer     *time.Timer
+
+	// the time the task was bound, and for a real member of a task group,
+	// the bound time of the placeholder it replaced
+	boundTime                    time.Time
+	replacedPlaceholderBoundTime time.Time
+
+	// the time the pod of the task was reported running
+	runningTime time.Time
+
+	// the pod was bound to its node before it reached the scheduler, e.g. spec.nodeName set by the user,
+	// if the allocation could not be reported to the core its resources are occupied on the node instead
+	preBound         bool
+	preBoundOccupied bool
 }
 
+// replacements slower than this are logged, the latency is always reported in the metrics
+var slowPlaceholderReplacement = time.Minute
+
 func NewTask(tid string, app *Application, ctx *Context, pod *v1.Pod) *Task {
 	taskResource := common.GetPodResource(pod)
 	return createTaskInternal(tid, app, taskResource, pod, false, "", ctx)
@@ -88,6 +127,13 @@ func createTaskInternal(tid string, app *Application, resource *si.Resource,
 		context:       ctx,
 		lock:          &sync.RWMutex{},
 	}
+	task.taskGroupIndex, task.indexRequested = utils.GetTaskGroupIndexFromPodSpec(pod)
+	task.queue = utils.GetTaskQueue(pod)
+	if utils.IsNonGangPod(pod) {
+		task.nonGang = true
+		task.taskGroupName = ""
+		task.taskGroupIndex, task.indexRequested = -1, false
+	}
 
 	var states = events.States().Task
 	task.sm = fsm.NewFSM(
@@ -96,6 +142,15 @@ func createTaskInternal(tid string, app *Application, resource *si.Resource,
 			{Name: string(events.InitTask),
 				Src: []string{states.New},
 				Dst: states.Pending},
+			{Name: string(events.GateTask),
+				Src: []string{states.New},
+				Dst: states.Gated},
+			{Name: string(events.UngateTask),
+				Src: []string{states.Gated},
+				Dst: states.Pending},
+			{Name: string(events.QuarantineTask),
+				Src: []string{states.New},
+				Dst: states.Quarantined},
 			{Name: string(events.SubmitTask),
 				Src: []string{states.Pending},
 				Dst: states.Scheduling},
@@ -105,11 +160,19 @@ func createTaskInternal(tid string, app *Application, resource *si.Resource,
 			{Name: string(events.TaskBound),
 				Src: []string{states.Allocated},
 				Dst: states.Bound},
+			{Name: string(events.TaskRunning),
+				Src: []string{states.Bound},
+				Dst: states.Running},
+			{Name: string(events.TaskSucceeded),
+				Src: []string{states.Bound, states.Running},
+				Dst: states.Succeeded},
+			// a task terminated by the phase of its pod keeps its state when the pod is deleted
 			{Name: string(events.CompleteTask),
-				Src: states.Any,
+				Src: statesExcept(states.Any, states.Succeeded, states.Failed),
 				Dst: states.Completed},
 			{Name: string(events.KillTask),
-				Src: []string{states.Pending, states.Scheduling, states.Allocated, states.Bound},
+				Src: []string{states.Gated, states.Quarantined, states.Pending, states.Scheduling, states.Allocated,
+					states.Bound, states.Running},
 				Dst: states.Killing},
 			{Name: string(events.TaskKilled),
 				Src: []string{states.Killing},
@@ -118,19 +181,37 @@ func createTaskInternal(tid string, app *Application, resource *si.Resource,
 				Src: []string{states.New, states.Pending, states.Scheduling},
 				Dst: states.Rejected},
 			{Name: string(events.TaskFail),
-				Src: []string{states.Rejected, states.Allocated},
+				Src: []string{states.Scheduling, states.Rejected, states.Allocated, states.Bound, states.Running},
 				Dst: states.Failed},
+			{Name: string(events.TaskSchedulingTimeout),
+				Src: []string{states.Scheduling},
+				Dst: states.Pending},
+			{Name: string(events.ResetTask),
+				Src: []string{states.Pending, states.Scheduling},
+				Dst: states.New},
+			{Name: string(events.TaskAskReleased),
+				Src: []string{states.Scheduling},
+				Dst: states.Pending},
 		},
 		fsm.Callbacks{
-			string(events.SubmitTask):       task.handleSubmitTaskEvent,
-			string(events.TaskFail):         task.handleFailEvent,
-			states.Pending:                  task.postTaskPending,
-			states.Allocated:                task.postTaskAllocated,
-			states.Rejected:                 task.postTaskRejected,
-			beforeHook(events.CompleteTask): task.beforeTaskCompleted,
-			states.Failed:                   task.postTaskFailed,
-			states.Bound:                    task.postTaskBound,
-			events.EnterState:               task.enterState,
+			string(events.SubmitTask):                task.handleSubmitTaskEvent,
+			string(events.TaskFail):                  task.handleFailEvent,
+			states.Pending:                           task.postTaskPending,
+			states.Quarantined:                       task.postTaskQuarantined,
+			states.Allocated:                         task.postTaskAllocated,
+			states.Rejected:                          task.postTaskRejected,
+			beforeHook(events.CompleteTask):          task.beforeTaskCompleted,
+			beforeHook(events.TaskSucceeded):         task.beforeTaskSucceeded,
+			beforeHook(events.TaskFail):              task.beforeTaskFailed,
+			beforeHook(events.TaskSchedulingTimeout): task.beforeTaskSchedulingTimeout,
+			beforeHook(events.ResetTask):             task.beforeTaskReset,
+			leaveHook(states.Scheduling):             task.leaveTaskScheduling,
+			states.Failed:                            task.postTaskFailed,
+			states.Bound:                             task.postTaskBound,
+			states.Running:                           task.postTaskRunning,
+			leaveHook(states.Running):                task.leaveTaskRunning,
+			events.LeaveState:                        task.leaveState,
+			events.EnterState:                        task.enterState,
 		},
 	)
 
@@ -141,14 +222,45 @@ func createTaskInternal(tid string, app *Application, resource *si.Resource,
 	return task
 }
 
+// logger returns a logger tagged with both the application and the task context, it does not take
+// the task lock: the fields it reads are set when the task is created and never change.
+func (task *Task) logger() *zap.Logger {
+	return task.application.logger().With(
+		log.TaskFields(task.taskID, task.alias, task.taskGroupName)...)
+}
+
 func beforeHook(event events.TaskEventType) string {
 	return fmt.Sprintf("before_%s", string(event))
 }
 
+func leaveHook(state string) string {
+	return fmt.Sprintf("leave_%s", state)
+}
+
+func statesExcept(states []string, excluded ...string) []string {
+	result := make([]string, 0, len(states))
+	for _, state := range states {
+		keep := true
+		for _, e := range excluded {
+			if state == e {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			result = append(result, state)
+		}
+	}
+	return result
+}
+
 // event handling
 func (task *Task) handle(te events.TaskEvent) error {
 	task.lock.Lock()
 	defer task.lock.Unlock()
+	if timeout, ok := te.(SchedulingTimeoutTaskEvent); ok && !task.applySchedulingTimeout(timeout) {
+		return nil
+	}
 	err := task.sm.Event(string(te.GetEvent()), te.GetArgs()...)
 	// handle the same state transition not nil error (limit of fsm).
 	if err != nil && err.Error() != "no transition" {
@@ -198,12 +310,47 @@ func (task *Task) setTaskTerminationType(terminationTyp string) {
 	task.terminationType = terminationTyp
 }
 
+func (task *Task) setTaskGroupIndex(index, total int32) {
+	task.lock.Lock()
+	defer task.lock.Unlock()
+	// the requested index is dropped when it is taken by another member
+	task.indexRequested = task.indexRequested && task.taskGroupIndex == index
+	task.taskGroupIndex = index
+	task.taskGroupTotal = total
+}
+
+func (task *Task) getTaskGroupIndex() int32 {
+	task.lock.RLock()
+	defer task.lock.RUnlock()
+	return task.taskGroupIndex
+}
+
 func (task *Task) getTaskGroupName() string {
 	task.lock.RLock()
 	defer task.lock.RUnlock()
 	return task.taskGroupName
 }
 
+// getQuarantined returns the conflicts the task is quarantined for, empty if it is not quarantined
+func (task *Task) getQuarantined() string {
+	task.lock.RLock()
+	defer task.lock.RUnlock()
+	return task.quarantined
+}
+
+// getQueue returns the queue the task overrides the queue of its app with, empty if it does not
+func (task *Task) getQueue() string {
+	task.lock.RLock()
+	defer task.lock.RUnlock()
+	return task.queue
+}
+
+func (task *Task) getNodeName() string {
+	task.lock.RLock()
+	defer task.lock.RUnlock()
+	return task.nodeName
+}
+
 func (task *Task) getTaskAllocationUUID() string {
 	task.lock.RLock()
 	defer task.lock.RUnlock()
@@ -215,8 +362,23 @@ func (task *Task) DeleteTaskPod(pod *v1.Pod) error {
 }
 
 func (task *Task) isTerminated() bool {
-	for _, states := range events.States().Task.Terminated {
-		if task.GetTaskState() == states {
+	return task.isTerminatedState(task.GetTaskState())
+}
+
+// isTerminatedState returns true if the given task state is a terminated state
+func (task *Task) isTerminatedState(state string) bool {
+	for _, terminated := range events.States().Task.Terminated {
+		if state == terminated {
+			return true
+		}
+	}
+	return false
+}
+
+// isBoundState returns true for the states of a task whose pod is bound to its node
+func isBoundState(state string) bool {
+	for _, bound := range boundTaskStates {
+		if state == bound {
 			return true
 		}
 	}
@@ -229,6 +391,54 @@ func (task *Task) setAllocated(nodeName, allocationUUID string) {
 	task.allocationUUID = allocationUUID
 	task.nodeName = nodeName
 	task.sm.SetState(events.States().Task.Allocated)
+	task.application.allocations.add(allocationUUID, task)
+}
+
+// setAllocationUUID hands the task another allocation of its app, the allocation is indexed to the task
+func (task *Task) setAllocationUUID(allocationUUID string) {
+	task.lock.Lock()
+	defer task.lock.Unlock()
+	task.allocationUUID = allocationUUID
+	task.application.allocations.add(allocationUUID, task)
+}
+
+// setPreBound moves a pod that is already bound to its node straight to Bound,
+// like in recovery the allocation UUID is the task ID.
+func (task *Task) setPreBound(nodeName string, occupied bool) {
+	task.lock.Lock()
+	defer task.lock.Unlock()
+	task.allocationUUID = task.taskID
+	task.application.allocations.add(task.allocationUUID, task)
+	task.nodeName = nodeName
+	task.preBound = true
+	task.preBoundOccupied = occupied
+	task.boundTime = time.Now()
+	task.sm.SetState(events.States().Task.Bound)
+	// the state is set directly, the running pod is reported like a phase update
+	if task.pod.Status.Phase == v1.PodRunning {
+		dispatcher.Dispatch(NewSimpleTaskEvent(task.applicationID, task.taskID, events.TaskRunning))
+	}
+}
+
+func (task *Task) isPreBoundOccupied() bool {
+	task.lock.RLock()
+	defer task.lock.RUnlock()
+	return task.preBoundOccupied
+}
+
+// occupyMissingAllocation occupies the resources of a bound task on its node when the core lost its allocation,
+// the task is then released like a pre-bound pod, the resources are returned to the node.
+func (task *Task) occupyMissingAllocation() bool {
+	task.lock.Lock()
+	defer task.lock.Unlock()
+	if task.preBoundOccupied || !isBoundState(task.sm.Current()) {
+		return false
+	}
+	task.preBoundOccupied = true
+	task.context.nodes.updateNodeOccupiedResources(task.nodeName, task.resource, AddOccupiedResource)
+	task.logger().Info("occupying the resources of a bound pod unknown to the core",
+		zap.String("nodeID", task.nodeName))
+	return true
 }
 
 func (task *Task) handleFailEvent(event *fsm.Event) {
@@ -240,31 +450,31 @@ func (task *Task) handleFailEvent(event *fsm.Event) {
 		return
 	}
 
-	log.Logger().Error("task failed",
-		zap.String("appID", task.applicationID),
-		zap.String("taskID", task.taskID),
+	task.logger().Error("task failed",
 		zap.String("reason", eventArgs[0]))
 }
 
 func (task *Task) handleSubmitTaskEvent(event *fsm.Event) {
-	log.Logger().Debug("scheduling pod",
+	task.logger().Debug("scheduling pod",
 		zap.String("podName", task.pod.Name))
+	// the timer is started before sending the request,
+	// the task must not wait forever even when the request is lost
+	task.startSchedulingTimer()
 	// convert the request
-	rr := common.CreateUpdateRequestForTask(
-		task.applicationID,
-		task.taskID,
-		task.resource,
-		task.placeholder,
-		task.taskGroupName,
-		task.pod)
-	log.Logger().Debug("send update request", zap.String("request", rr.String()))
-	if err := task.context.apiProvider.GetAPIs().SchedulerAPI.Update(&rr); err != nil {
-		log.Logger().Debug("failed to send scheduling request to scheduler", zap.Error(err))
+	rr := task.createAskRequest()
+	task.logger().Debug("send update request", zap.String("request", rr.String()))
+	span := trace.StartTaskSpan(task.taskID, "SendAsk")
+	err := task.context.apiProvider.GetAPIs().SchedulerAPI.Update(&rr)
+	span.Finish()
+	if err != nil {
+		task.logger().Debug("failed to send scheduling request to scheduler", zap.Error(err))
 		return
 	}
 
 	events.GetRecorder().Eventf(task.pod, v1.EventTypeNormal, "Scheduling",
 		"%s is queued and waiting for allocation", task.alias)
+	// the pod status is updated outside of the state transition
+	go task.setAwaitingCoreDecision()
 	// if this task belongs to a task group, that means the app has gang scheduling enabled
 	// in this case, post an event to indicate the task is being gang scheduled
 	if !task.placeholder && task.taskGroupName != "" {
@@ -272,6 +482,48 @@ func (task *Task) handleSubmitTaskEvent(event *fsm.Event) {
 			v1.EventTypeNormal, "GangScheduling",
 			"Pod belongs to the taskGroup %s, it will be scheduled as a gang member", task.taskGroupName)
 	}
+	if task.nonGang {
+		events.GetRecorder().Eventf(task.pod,
+			v1.EventTypeNormal, "NonGangScheduling",
+			"Pod opted out of the gang reservation, it is scheduled without waiting for the gang members")
+	}
+}
+
+// createAskRequest converts the task to the request sending its ask to the core, the ask of an app whose
+// priority was raised by aging carries the priority of the pod raised by the aged priority of the app.
+func (task *Task) createAskRequest() si.UpdateRequest {
+	rr := common.CreateUpdateRequestForTask(
+		task.applicationID,
+		task.taskID,
+		task.resource,
+		task.placeholder,
+		task.taskGroupName,
+		task.pod)
+	if task.taskGroupName != "" && task.taskGroupIndex >= 0 {
+		common.AddTaskGroupTags(rr.Asks[0], task.taskGroupName, task.taskGroupIndex, task.taskGroupTotal)
+	}
+	common.AddPreferredNodesTag(rr.Asks[0], utils.GetPreferredNodes(task.pod))
+	common.AddTaskQueueTag(rr.Asks[0], task.queue)
+	common.AddTraceTags(rr.Asks[0], task.taskID)
+	if aged := task.application.getAgedPriority(); aged > 0 {
+		var priority int32
+		if task.pod.Spec.Priority != nil {
+			priority = *task.pod.Spec.Priority
+		}
+		common.SetAskPriority(rr.Asks[0], addPriority(priority, aged))
+	}
+	rr.RmID = task.application.getRmID()
+	return rr
+}
+
+// getPendingAsk returns the ask of the task while the task waits for an allocation, nil otherwise
+func (task *Task) getPendingAsk() *si.AllocationAsk {
+	task.lock.RLock()
+	defer task.lock.RUnlock()
+	if task.sm.Current() != events.States().Task.Scheduling {
+		return nil
+	}
+	return task.createAskRequest().Asks[0]
 }
 
 // this is called after task reaches PENDING state,
@@ -280,83 +532,198 @@ func (task *Task) postTaskPending(event *fsm.Event) {
 	dispatcher.Dispatch(NewSubmitTaskEvent(task.applicationID, task.taskID))
 }
 
+// quarantine holds a new task whose pod carries conflicting scheduling directives,
+// the task is never scheduled and stays quarantined until its pod is deleted.
+func (task *Task) quarantine(conflicts []string) error {
+	task.lock.Lock()
+	task.quarantined = strings.Join(conflicts, "; ")
+	task.lock.Unlock()
+	return task.handle(NewSimpleTaskEvent(task.applicationID, task.taskID, events.QuarantineTask))
+}
+
+// this is called after task reaches QUARANTINED state, the conflicts are published on the pod
+func (task *Task) postTaskQuarantined(event *fsm.Event) {
+	task.logger().Warn("task is quarantined, the scheduling directives of the pod conflict",
+		zap.String("conflicts", task.quarantined))
+	events.GetRecorder().Eventf(task.pod, v1.EventTypeWarning, conflictingDirectivesReason,
+		"Task %s is not scheduled, the scheduling directives of the pod conflict: %s. "+
+			"Delete the pod and create it again with consistent directives", task.alias, task.quarantined)
+}
+
 // this is called after task reaches ALLOCATED state,
-// we run this in a go routine to bind pod to the allocated node,
-// if successful, we move task to next state BOUND,
-// otherwise we fail the task
+// we queue the bind of the pod to the allocated node in the bind queue
 func (task *Task) postTaskAllocated(event *fsm.Event) {
+	eventArgs := make([]string, 2)
+	if err := events.GetEventArgsAsStrings(eventArgs, event.Args); err != nil {
+		task.logger().Error("error", zap.Error(err))
+		dispatcher.Dispatch(NewFailTaskEvent(task.applicationID, task.taskID, err.Error()))
+		return
+	}
+	allocUUID := eventArgs[0]
+	nodeID := eventArgs[1]
+
 	// delay binding task
 	// this calls K8s api to bind a pod to the assigned node, this may need some time,
 	// so we do a delay binding to avoid blocking main process. we tracks the result
 	// of the binding and properly handle failures.
-	go func(event *fsm.Event) {
-		// we need to obtain task's lock first,
-		// this ensures no other threads modifying task state at the time being
-		task.lock.Lock()
-		defer task.lock.Unlock()
-
-		var errorMessage string
-		eventArgs := make([]string, 2)
-		if err := events.GetEventArgsAsStrings(eventArgs, event.Args); err != nil {
-			errorMessage = err.Error()
-			log.Logger().Error("error", zap.Error(err))
-			dispatcher.Dispatch(NewFailTaskEvent(task.applicationID, task.taskID, errorMessage))
-			return
-		}
+	task.context.bindQueue.submit(nodeID, func() {
+		task.bind(allocUUID, nodeID)
+	})
+}
 
-		allocUUID := eventArgs[0]
-		nodeID := eventArgs[1]
+// bind binds the volumes and the pod of the task to the allocated node, this runs in a bind worker.
+// if successful, the task moves to BOUND, otherwise it fails.
+func (task *Task) bind(allocUUID, nodeID string) {
+	var errorMessage string
+	start := time.Now()
+	result := metrics.BindFailed
+	defer func() {
+		metrics.GetBindMetrics().ObserveBind(result, time.Since(start))
+	}()
 
-		// post a message to indicate the pod gets its allocation
-		events.GetRecorder().Eventf(task.pod,
-			v1.EventTypeNormal, "Scheduled",
-			"Successfully assigned %s to node %s", task.alias, nodeID)
-
-		// task allocation UID is assigned once we get allocation decision from scheduler core
-		task.allocationUUID = allocUUID
-
-		// before binding pod to node, first bind volumes to pod
-		log.Logger().Debug("bind pod volumes",
-			zap.String("podName", task.pod.Name),
-			zap.String("podUID", string(task.pod.UID)))
-		if task.context.apiProvider.GetAPIs().VolumeBinder != nil {
-			if err := task.context.bindPodVolumes(task.pod); err != nil {
-				errorMessage = fmt.Sprintf("bind pod volumes failed, name: %s, %s", task.alias, err.Error())
-				dispatcher.Dispatch(NewFailTaskEvent(task.applicationID, task.taskID, errorMessage))
-				events.GetRecorder().Eventf(task.pod,
-					v1.EventTypeWarning, "PodVolumesBindFailure", errorMessage)
-				return
-			}
-		}
+	// this takes the app lock, it must be done before the task lock is held
+	task.checkTaskGroupPlacement(nodeID)
+
+	// we need to obtain task's lock first,
+	// this ensures no other threads modifying task state at the time being
+	task.lock.Lock()
+	defer task.lock.Unlock()
+
+	// post a message to indicate the pod gets its allocation
+	events.GetRecorder().Eventf(task.pod,
+		v1.EventTypeNormal, "Scheduled",
+		"Successfully assigned %s to node %s", task.alias, nodeID)
+	task.context.decisions.publish(dao.SchedulingDecision{
+		Type:          dao.DecisionTaskScheduled,
+		ApplicationID: task.applicationID,
+		TaskID:        task.taskID,
+		Pod:           task.alias,
+		NodeID:        nodeID,
+		Placeholder:   task.placeholder,
+		TaskGroup:     task.taskGroupName,
+	})
 
-		log.Logger().Debug("bind pod",
-			zap.String("podName", task.pod.Name),
-			zap.String("podUID", string(task.pod.UID)))
+	// task allocation UID is assigned once we get allocation decision from scheduler core
+	task.allocationUUID = allocUUID
+	task.nodeName = nodeID
+	task.application.allocations.add(allocUUID, task)
 
-		if err := task.context.apiProvider.GetAPIs().KubeClient.Bind(task.pod, nodeID); err != nil {
+	// before binding pod to node, first bind volumes to pod
+	task.logger().Debug("bind pod volumes",
+		zap.String("podName", task.pod.Name),
+		zap.String("podUID", string(task.pod.UID)))
+	if task.context.apiProvider.GetAPIs().VolumeBinder != nil {
+		span := trace.StartTaskSpan(task.taskID, "BindPodVolumes")
+		err := task.context.bindPodVolumes(task.pod)
+		span.Finish()
+		if err != nil {
 			errorMessage = fmt.Sprintf("bind pod volumes failed, name: %s, %s", task.alias, err.Error())
-			log.Logger().Error(errorMessage)
 			dispatcher.Dispatch(NewFailTaskEvent(task.applicationID, task.taskID, errorMessage))
 			events.GetRecorder().Eventf(task.pod,
-				v1.EventTypeWarning, "PodBindFailure", errorMessage)
+				v1.EventTypeWarning, "PodVolumesBindFailure", errorMessage)
 			return
 		}
+	}
 
-		log.Logger().Info("successfully bound pod", zap.String("podName", task.pod.Name))
-		dispatcher.Dispatch(NewBindTaskEvent(task.applicationID, task.taskID))
+	task.logger().Debug("bind pod",
+		zap.String("podName", task.pod.Name),
+		zap.String("podUID", string(task.pod.UID)))
+
+	span := trace.StartTaskSpan(task.taskID, "BindPod")
+	err := task.context.apiProvider.GetAPIs().KubeClient.Bind(task.pod, nodeID)
+	span.Finish()
+	if err != nil {
+		errorMessage = fmt.Sprintf("bind pod volumes failed, name: %s, %s", task.alias, err.Error())
+		task.logger().Error(errorMessage)
+		dispatcher.Dispatch(NewFailTaskEvent(task.applicationID, task.taskID, errorMessage))
 		events.GetRecorder().Eventf(task.pod,
-			v1.EventTypeNormal, "PodBindSuccessful",
-			"Pod %s is successfully bound to node %s", task.alias, nodeID)
-	}(event)
+			v1.EventTypeWarning, "PodBindFailure", errorMessage)
+		return
+	}
+
+	result = metrics.BindSucceeded
+	task.logger().Info("successfully bound pod", zap.String("podName", task.pod.Name))
+	dispatcher.Dispatch(NewBindTaskEvent(task.applicationID, task.taskID))
+	events.GetRecorder().Eventf(task.pod,
+		v1.EventTypeNormal, "PodBindSuccessful",
+		"Pod %s is successfully bound to node %s", task.alias, nodeID)
+}
+
+// checkTaskGroupPlacement warns when a real member that requested its task group index is not allocated
+// on the node of the placeholder with the same index, the rank-to-node mapping is not kept in that case.
+func (task *Task) checkTaskGroupPlacement(nodeID string) {
+	task.lock.RLock()
+	taskGroupName, index, requested := task.taskGroupName, task.taskGroupIndex, task.indexRequested
+	task.lock.RUnlock()
+	if task.placeholder || taskGroupName == "" || !requested {
+		return
+	}
+	placeholderNode := task.application.getTaskGroupPlaceholderNode(taskGroupName, index)
+	if placeholderNode == "" || placeholderNode == nodeID {
+		return
+	}
+	task.logger().Warn("task group member is not placed on the node of its placeholder",
+		zap.String("taskGroup", taskGroupName),
+		zap.Int32("taskGroupIndex", index),
+		zap.String("nodeID", nodeID),
+		zap.String("placeholderNodeID", placeholderNode))
+	events.GetRecorder().Eventf(task.pod, v1.EventTypeWarning, "TaskGroupPlacementMismatch",
+		"member %d of task group %s is allocated on node %s, its placeholder is on node %s",
+		index, taskGroupName, nodeID, placeholderNode)
 }
 
 func (task *Task) postTaskBound(event *fsm.Event) {
+	task.boundTime = time.Now()
 	if task.placeholder {
-		log.Logger().Info("placeholder is bound",
-			zap.String("appID", task.applicationID),
-			zap.String("taskName", task.alias),
-			zap.String("taskGroupName", task.taskGroupName))
+		task.logger().Info("placeholder is bound")
 		dispatcher.Dispatch(NewUpdateApplicationReservationEvent(task.applicationID))
+		return
+	}
+	if !task.replacedPlaceholderBoundTime.IsZero() {
+		task.observePlaceholderReplacement()
+	}
+}
+
+func (task *Task) postTaskRunning(event *fsm.Event) {
+	task.runningTime = time.Now()
+	metrics.GetTaskMetrics().IncRunning()
+}
+
+func (task *Task) leaveTaskRunning(event *fsm.Event) {
+	metrics.GetTaskMetrics().DecRunning()
+}
+
+func (task *Task) getBoundTime() time.Time {
+	task.lock.RLock()
+	defer task.lock.RUnlock()
+	return task.boundTime
+}
+
+// onPlaceholderReplaced is called on the real member when the placeholder with the same index is released
+// by the core. The real member can be bound before or after the release is processed, the replacement is
+// measured by whichever comes last.
+func (task *Task) onPlaceholderReplaced(placeholderBoundTime time.Time) {
+	task.lock.Lock()
+	defer task.lock.Unlock()
+	if placeholderBoundTime.IsZero() || !task.replacedPlaceholderBoundTime.IsZero() {
+		return
+	}
+	task.replacedPlaceholderBoundTime = placeholderBoundTime
+	if !task.boundTime.IsZero() {
+		task.observePlaceholderReplacement()
+	}
+}
+
+// observePlaceholderReplacement reports the time between the placeholder and the real member being bound,
+// the caller must hold the task lock.
+func (task *Task) observePlaceholderReplacement() {
+	latency := task.boundTime.Sub(task.replacedPlaceholderBoundTime)
+	metrics.GetPlaceholderMetrics().ObservePlaceholderReplaced(task.application.metricsContext(task.taskGroupName), latency)
+	if latency > slowPlaceholderReplacement {
+		task.logger().Warn("slow placeholder replacement",
+			zap.String("taskGroup", task.taskGroupName),
+			zap.Int32("taskGroupIndex", task.taskGroupIndex),
+			zap.Duration("latency", latency))
 	}
 }
 
@@ -372,34 +739,215 @@ func (task *Task) postTaskRejected(event *fsm.Event) {
 		"Task %s is rejected by the scheduler", task.alias)
 }
 
-func (task *Task) postTaskFailed(event *fsm.Event) {
+func (task *Task) beforeTaskFailed(event *fsm.Event) {
+	// a bound task fails when its pod fails, it is released like a completed pod
+	if isBoundState(event.Src) {
+		task.releaseTerminatedPod()
+		metrics.GetTaskMetrics().IncTerminated(metrics.TaskFailed, task.runningTime,
+			task.application.metricsContext(task.taskGroupName))
+		return
+	}
 	// when task is failed, we need to do the cleanup,
-	// we need to release the allocation from scheduler core
+	// we need to release the allocation from scheduler core.
+	// this is done as a before hook because the releaseAllocation() call needs to
+	// send different requests to scheduler-core, depending on current task state
 	task.releaseAllocation()
+}
 
+func (task *Task) postTaskFailed(event *fsm.Event) {
 	events.GetRecorder().Eventf(task.pod,
 		v1.EventTypeNormal, "TaskFailed",
 		"Task %s is failed", task.alias)
 }
 
 func (task *Task) beforeTaskCompleted(event *fsm.Event) {
-	// before task transits to completed, release its allocation from scheduler core
+	task.releaseTerminatedPod()
+	events.GetRecorder().Eventf(task.pod,
+		v1.EventTypeNormal, "TaskCompleted",
+		"Task %s is completed", task.alias)
+}
+
+func (task *Task) beforeTaskSucceeded(event *fsm.Event) {
+	task.releaseTerminatedPod()
+	metrics.GetTaskMetrics().IncTerminated(metrics.TaskSucceeded, task.runningTime,
+		task.application.metricsContext(task.taskGroupName))
+	events.GetRecorder().Eventf(task.pod,
+		v1.EventTypeNormal, "TaskSucceeded",
+		"Task %s is succeeded", task.alias)
+}
+
+// releaseTerminatedPod releases the allocation of a task whose pod is terminated or deleted,
+// the caller must hold the task lock.
+func (task *Task) releaseTerminatedPod() {
+	// before task transits to a terminated state, release its allocation from scheduler core
 	// this is done as a before hook because the releaseAllocation() call needs to
 	// send different requests to scheduler-core, depending on current task state
 	task.releaseAllocation()
 
-	events.GetRecorder().Eventf(task.pod,
-		v1.EventTypeNormal, "TaskCompleted",
-		"Task %s is completed", task.alias)
+	// a placeholder that completes without being released by the shim or the scheduler is preempted,
+	// the app is notified asynchronously as the task lock is held here
+	if task.placeholder && task.terminationType == "" && task.application != nil {
+		go task.application.onPlaceholderPreempted(task.taskGroupName, task.taskGroupIndex)
+	}
+	// a placeholder replaced by the administrator is created again, the rest of the reservation is kept
+	if task.placeholder && task.forceReplaced && task.application != nil {
+		go task.application.recreatePlaceholder(task.taskGroupName, task.taskGroupIndex, task.excludedNode)
+	}
+	// a real member that crashed shortly after replacing a placeholder gets the placeholder back
+	if task.isFailedReplacement() {
+		go task.application.restorePlaceholder(task.taskGroupName, task.taskGroupIndex, task.alias)
+	}
+}
+
+// isFailedReplacement returns true for a real member that replaced a placeholder and whose pod failed within the
+// placeholder restore window. A member released by the shim or the scheduler, e.g. preempted or killed with its app,
+// has a termination type and does not get its placeholder back. The caller must hold the task lock.
+func (task *Task) isFailedReplacement() bool {
+	window := task.context.apiProvider.GetAPIs().Conf.GetPlaceholderRestoreWindow()
+	if window == 0 || task.placeholder || task.application == nil || task.terminationType != "" ||
+		task.replacedPlaceholderBoundTime.IsZero() || task.taskGroupIndex < 0 || time.Since(task.boundTime) > window {
+		return false
+	}
+	pod, err := task.context.apiProvider.GetAPIs().PodInformer.Lister().Pods(task.pod.Namespace).Get(task.pod.Name)
+	return err == nil && pod.UID == task.pod.UID && pod.Status.Phase == v1.PodFailed
+}
+
+// start the timer of the current scheduling attempt, once the timer fires and the task
+// is still waiting for an allocation, the task scheduling timeout policy is applied.
+// The caller must hold the task lock.
+func (task *Task) startSchedulingTimer() {
+	task.schedulingStartTime = time.Now()
+	timeout := task.context.apiProvider.GetAPIs().Conf.TaskSchedulingTimeout
+	if timeout <= 0 {
+		return
+	}
+	startTime := task.schedulingStartTime
+	fail := task.context.apiProvider.GetAPIs().Conf.TaskSchedulingTimeoutPolicy == conf.TaskSchedulingTimeoutFail
+	task.schedulingTimer = time.AfterFunc(timeout, func() {
+		message := fmt.Sprintf("task %s is not scheduled after %s", task.alias, time.Since(startTime).String())
+		dispatcher.Dispatch(NewSchedulingTimeoutTaskEvent(task.applicationID, task.taskID, startTime, fail, message))
+	})
+}
+
+func (task *Task) leaveTaskScheduling(event *fsm.Event) {
+	if task.schedulingTimer != nil {
+		task.schedulingTimer.Stop()
+		task.schedulingTimer = nil
+	}
+}
+
+// applySchedulingTimeout returns false when the timeout is stale: the task has already left the Scheduling state,
+// or the timer belongs to a previous scheduling attempt. Otherwise the timeout is recorded on the pod, which is
+// failed with the fail policy. The caller must hold the task lock.
+func (task *Task) applySchedulingTimeout(timeout SchedulingTimeoutTaskEvent) bool {
+	if task.sm.Current() != events.States().Task.Scheduling || !task.schedulingStartTime.Equal(timeout.startTime) {
+		task.logger().Debug("ignoring the timeout of a previous scheduling attempt")
+		return false
+	}
+	task.logger().Info("task scheduling timeout", zap.String("message", timeout.message))
+	if timeout.GetEvent() == events.TaskFail {
+		events.GetRecorder().Eventf(task.pod,
+			v1.EventTypeWarning, "SchedulingTimeout", "%s, the pod is failed", timeout.message)
+		task.failTaskPod("SchedulingTimeout", timeout.message)
+	} else {
+		events.GetRecorder().Eventf(task.pod,
+			v1.EventTypeWarning, "SchedulingTimeout", "%s, the request is resubmitted", timeout.message)
+	}
+	return true
+}
+
+func (task *Task) beforeTaskSchedulingTimeout(event *fsm.Event) {
+	// release the pending ask before the task is resubmitted
+	task.releaseAllocation()
+}
+
+// beforeTaskReset releases the pending ask of a task that is reset to New,
+// the ask is rebuilt when the task is scheduled again.
+func (task *Task) beforeTaskReset(event *fsm.Event) {
+	task.releaseAllocation()
+}
+
+// mark the task pod as failed in the api-server
+func (task *Task) failTaskPod(reason, message string) {
+	if task.context.apiProvider.IsTestingMode() {
+		return
+	}
+	pod := task.pod.DeepCopy()
+	pod.Status.Phase = v1.PodFailed
+	pod.Status.Reason = reason
+	pod.Status.Message = message
+	go func() {
+		if _, err := task.context.apiProvider.GetAPIs().KubeClient.GetClientSet().CoreV1().
+			Pods(pod.Namespace).UpdateStatus(pod); err != nil {
+			task.logger().Error("failed to update pod status", zap.Error(err))
+		}
+	}()
+}
+
+// initTask moves a new task to Pending, or holds it in Gated while its pod still has scheduling gates.
+// The app has been accepted by its queue at this point, so the queue admission gate added by
+// the admission controller is removed here, the other gates are removed by their owners.
+func (task *Task) initTask() error {
+	gates := utils.GetSchedulingGates(task.GetTaskPod())
+	if len(gates) > 0 {
+		remaining := utils.RemoveSchedulingGate(gates, constants.SchedulingGateQueueAdmission)
+		if len(remaining) != len(gates) {
+			task.removeSchedulingGate(constants.SchedulingGateQueueAdmission)
+		}
+		if len(remaining) > 0 {
+			events.GetRecorder().Eventf(task.GetTaskPod(), v1.EventTypeNormal, "SchedulingGated",
+				"Task %s is waiting for the scheduling gates to be removed: %s",
+				task.alias, strings.Join(remaining, constants.SchedulingGatesDelimiter))
+			return task.handle(NewSimpleTaskEvent(task.applicationID, task.taskID, events.GateTask))
+		}
+	}
+	return task.handle(NewSimpleTaskEvent(task.applicationID, task.taskID, events.InitTask))
+}
+
+// removeSchedulingGate removes a gate from the task pod on K8s, the cached pod is not replaced.
+// The gates on K8s might have been changed by their owners since the pod was cached,
+// the gate is removed from the latest version of the pod so those changes are never overwritten.
+func (task *Task) removeSchedulingGate(gate string) {
+	pod := task.GetTaskPod()
+	if task.context.apiProvider.IsTestingMode() {
+		return
+	}
+	go func() {
+		pods := task.context.apiProvider.GetAPIs().KubeClient.GetClientSet().CoreV1().Pods(pod.Namespace)
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			latest, err := pods.Get(pod.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			gates := utils.GetSchedulingGates(latest)
+			remaining := utils.RemoveSchedulingGate(gates, gate)
+			if len(remaining) == len(gates) {
+				return nil
+			}
+			utils.SetSchedulingGates(latest, remaining)
+			_, err = pods.Update(latest)
+			return err
+		})
+		if err != nil {
+			task.logger().Error("failed to remove pod scheduling gate",
+				zap.String("gate", gate),
+				zap.Error(err))
+		}
+	}()
 }
 
 func (task *Task) releaseAllocation() {
+	// the core does not know about the allocation, only the resources occupied on the node are returned
+	if task.preBoundOccupied {
+		task.preBoundOccupied = false
+		task.logger().Info("releasing the occupied resources of a pre-bound pod",
+			zap.String("nodeID", task.nodeName))
+		task.context.nodes.updateNodeOccupiedResources(task.nodeName, task.resource, SubOccupiedResource)
+		return
+	}
 	// scheduler api might be nil in some tests
 	if task.context.apiProvider.GetAPIs().SchedulerAPI != nil {
-		log.Logger().Debug("prepare to send release request",
-			zap.String("applicationID", task.applicationID),
-			zap.String("taskID", task.taskID),
-			zap.String("taskAlias", task.alias),
+		task.logger().Debug("prepare to send release request",
 			zap.String("allocationUUID", task.allocationUUID),
 			zap.String("task", task.GetTaskState()),
 			zap.String("terminationType", task.terminationType))
@@ -411,6 +959,9 @@ func (task *Task) releaseAllocation() {
 		var releaseRequest si.UpdateRequest
 		s := events.States().Task
 		switch task.GetTaskState() {
+		case s.Gated, s.Quarantined:
+			// the ask of a gated or quarantined task was never sent to the core, there is nothing to release
+			return
 		case s.New, s.Pending, s.Scheduling:
 			releaseRequest = common.CreateReleaseAskRequestForTask(
 				task.applicationID, task.taskID, task.application.partition)
@@ -419,12 +970,9 @@ func (task *Task) releaseAllocation() {
 			// log a warning and skip the release request. this may leak some resource
 			// in the scheduler, collect logs and check why this happens.
 			if task.allocationUUID == "" {
-				log.Logger().Warn("task allocation UUID is empty, sending this release request "+
+				task.logger().Warn("task allocation UUID is empty, sending this release request "+
 					"to yunikorn-core could cause all allocations of this app get released. skip this "+
 					"request, this may cause some resource leak. check the logs for more info!",
-					zap.String("applicationID", task.applicationID),
-					zap.String("taskID", task.taskID),
-					zap.String("taskAlias", task.alias),
 					zap.String("allocationUUID", task.allocationUUID),
 					zap.String("task", task.GetTaskState()))
 				return
@@ -433,17 +981,36 @@ func (task *Task) releaseAllocation() {
 				task.applicationID, task.allocationUUID, task.application.partition, task.terminationType)
 		}
 
+		releaseRequest.RmID = task.application.getRmID()
 		if releaseRequest.Releases != nil {
-			log.Logger().Info("releasing allocations",
+			task.logger().Info("releasing allocations",
 				zap.Int("numOfAsksToRelease", len(releaseRequest.Releases.AllocationAsksToRelease)),
 				zap.Int("numOfAllocationsToRelease", len(releaseRequest.Releases.AllocationsToRelease)))
 		}
 		if err := task.context.apiProvider.GetAPIs().SchedulerAPI.Update(&releaseRequest); err != nil {
-			log.Logger().Debug("failed to send scheduling request to scheduler", zap.Error(err))
+			task.logger().Debug("failed to send scheduling request to scheduler", zap.Error(err))
 		}
 	}
 }
 
+// releaseLateAllocation releases an allocation the core made for a task that was terminated before it was
+// allocated, e.g. the pod was deleted while the allocation was on its way. The ask was released already,
+// the allocation would be kept by the core forever as no pod is bound for it.
+func (task *Task) releaseLateAllocation(allocUUID string) {
+	if allocUUID == "" || task.context.apiProvider.GetAPIs().SchedulerAPI == nil {
+		return
+	}
+	task.logger().Info("releasing the allocation of a terminated task",
+		zap.String("allocationUUID", allocUUID),
+		zap.String("task", task.GetTaskState()))
+	releaseRequest := common.CreateReleaseAllocationRequestForTask(task.applicationID, allocUUID,
+		task.application.partition, si.TerminationType_STOPPED_BY_RM.String())
+	releaseRequest.RmID = task.application.getRmID()
+	if err := task.context.apiProvider.GetAPIs().SchedulerAPI.Update(&releaseRequest); err != nil {
+		task.logger().Debug("failed to send scheduling request to scheduler", zap.Error(err))
+	}
+}
+
 // some sanity checks before sending task for scheduling,
 // this reduces the scheduling overhead by blocking such
 // request away from the core scheduler.
@@ -458,7 +1025,7 @@ func (task *Task) sanityCheckBeforeScheduling() error {
 			continue
 		}
 		pvcName := volume.PersistentVolumeClaim.ClaimName
-		log.Logger().Debug("checking PVC", zap.String("name", pvcName))
+		task.logger().Debug("checking PVC", zap.String("name", pvcName))
 		pvc, err := task.context.apiProvider.GetAPIs().PVCInformer.Lister().PersistentVolumeClaims(namespace).Get(pvcName)
 		if err != nil {
 			return err
@@ -470,12 +1037,48 @@ func (task *Task) sanityCheckBeforeScheduling() error {
 	return nil
 }
 
+// traceState moves the trace of the pod to the scheduling stage of the state the task entered,
+// the trace is finished once the pod is bound, or when the task is terminated before it is bound.
+// A recovered task is not traced, it is allocated without passing through the pending state.
+func (task *Task) traceState(state string) {
+	switch state {
+	case events.States().Task.Pending:
+		trace.StartTaskTrace(task.applicationID, task.taskID, task.createTime)
+		trace.SetTaskStage(task.taskID, "Pending")
+	case events.States().Task.Scheduling:
+		trace.SetTaskStage(task.taskID, "Scheduling")
+	case events.States().Task.Allocated:
+		trace.SetTaskStage(task.taskID, "Binding")
+	case events.States().Task.Bound:
+		trace.FinishTaskTrace(task.taskID, "")
+	default:
+		if task.isTerminatedState(state) {
+			trace.FinishTaskTrace(task.taskID, state)
+		}
+	}
+}
+
+// leaveState runs the hooks registered before the state transitions of the tasks
+func (task *Task) leaveState(event *fsm.Event) {
+	runStateHooks(taskHook, BeforeTransition, task.applicationID, task.taskID, event)
+}
+
 func (task *Task) enterState(event *fsm.Event) {
-	log.Logger().Debug("shim task state transition",
-		zap.String("app", task.applicationID),
-		zap.String("task", task.taskID),
-		zap.String("taskAlias", task.alias),
+	task.logger().Debug("shim task state transition",
 		zap.String("source", event.Src),
 		zap.String("destination", event.Dst),
 		zap.String("event", event.Event))
+	defer runStateHooks(taskHook, AfterTransition, task.applicationID, task.taskID, event)
+	task.traceState(event.Dst)
+	if !task.isTerminatedState(event.Dst) || task.application == nil {
+		return
+	}
+	// the allocation of a terminated task is not tracked anymore
+	task.application.allocations.remove(task.allocationUUID, task)
+	// the app may be completed once its last task is terminated,
+	// this is done asynchronously as the task lock is held here
+	if !task.placeholder && task.context != nil &&
+		task.application.completionPolicy == conf.AppCompletionAllTasksTerminated {
+		go task.context.onTaskTerminated(task.application)
+	}
 }
@@ -18,7 +18,11 @@
 
 package cache
 
-import "github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+import (
+	"time"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+)
 
 // ------------------------
 // Simple task Event simply moves task to next state, it has no arguments provided
@@ -232,3 +236,28 @@ func (re RejectTaskEvent) GetTaskID() string {
 func (re RejectTaskEvent) GetApplicationID() string {
 	return re.applicationID
 }
+
+// ------------------------
+// Scheduling timeout Event, sent by the timer of a scheduling attempt. It wraps the event of the timeout policy,
+// the task ignores it when the attempt it was started for is over.
+// ------------------------
+type SchedulingTimeoutTaskEvent struct {
+	events.TaskEvent
+	startTime time.Time
+	message   string
+}
+
+// NewSchedulingTimeoutTaskEvent returns the event failing the task, or resubmitting it, when the scheduling
+// attempt started at the given time is not allocated in time
+func NewSchedulingTimeoutTaskEvent(appID string, taskID string, startTime time.Time, fail bool,
+	message string) SchedulingTimeoutTaskEvent {
+	var event events.TaskEvent = NewSimpleTaskEvent(appID, taskID, events.TaskSchedulingTimeout)
+	if fail {
+		event = NewFailTaskEvent(appID, taskID, message)
+	}
+	return SchedulingTimeoutTaskEvent{
+		TaskEvent: event,
+		startTime: startTime,
+		message:   message,
+	}
+}
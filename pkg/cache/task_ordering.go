@@ -0,0 +1,116 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+)
+
+// task ordering policies, a policy decides the order in which
+// the new tasks of an application are submitted to the scheduler core
+const (
+	TaskOrderingFIFO         = "FIFO"
+	TaskOrderingPriority     = "Priority"
+	TaskOrderingResourceSize = "ResourceSize"
+)
+
+// TaskLessFunc reports whether the task l should be submitted before the task r
+type TaskLessFunc func(l, r *Task) bool
+
+var taskOrderingPolicies = map[string]TaskLessFunc{
+	TaskOrderingFIFO:         fifoLess,
+	TaskOrderingPriority:     priorityLess,
+	TaskOrderingResourceSize: resourceSizeLess,
+}
+var taskOrderingLock sync.RWMutex
+
+// RegisterTaskOrderingPolicy registers a task ordering policy with the given name,
+// the policy can then be referred by the application's scheduling policy parameters.
+// a registered policy with the same name is replaced.
+func RegisterTaskOrderingPolicy(name string, less TaskLessFunc) {
+	taskOrderingLock.Lock()
+	defer taskOrderingLock.Unlock()
+	taskOrderingPolicies[name] = less
+}
+
+func getTaskOrderingPolicy(name string) (TaskLessFunc, bool) {
+	taskOrderingLock.RLock()
+	defer taskOrderingLock.RUnlock()
+	less, ok := taskOrderingPolicies[name]
+	return less, ok
+}
+
+// sort the tasks with the given policy, fallback to FIFO if the policy is unknown
+func sortTasks(tasks []*Task, policy string) {
+	less, ok := getTaskOrderingPolicy(policy)
+	if !ok {
+		less = fifoLess
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return less(tasks[i], tasks[j])
+	})
+}
+
+// submit the tasks in creation time order
+func fifoLess(l, r *Task) bool {
+	return l.createTime.Before(r.createTime)
+}
+
+// submit the tasks with a higher pod priority first
+func priorityLess(l, r *Task) bool {
+	lp := getPodPriority(l)
+	rp := getPodPriority(r)
+	if lp != rp {
+		return lp > rp
+	}
+	return fifoLess(l, r)
+}
+
+// submit the tasks asking for more resources first, cpu is compared before memory
+func resourceSizeLess(l, r *Task) bool {
+	for _, name := range []string{constants.CPU, constants.Memory} {
+		lv := getResourceValue(l, name)
+		rv := getResourceValue(r, name)
+		if lv != rv {
+			return lv > rv
+		}
+	}
+	return fifoLess(l, r)
+}
+
+func getPodPriority(task *Task) int32 {
+	pod := task.GetTaskPod()
+	if pod.Spec.Priority == nil {
+		return 0
+	}
+	return *pod.Spec.Priority
+}
+
+func getResourceValue(task *Task, name string) int64 {
+	if task.resource == nil {
+		return 0
+	}
+	if quantity, ok := task.resource.Resources[name]; ok {
+		return quantity.Value
+	}
+	return 0
+}
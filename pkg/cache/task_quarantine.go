@@ -0,0 +1,88 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/webservice/dao"
+)
+
+// the reason of the pod events of the tasks quarantined for conflicting scheduling directives
+const conflictingDirectivesReason = "ConflictingSchedulingDirectives"
+
+// validateTask returns the conflicts between the scheduling directives of the pod of a new task and its app:
+// the app IDs of the pod must name the same app, the queue label of the pod must be the queue of the app
+// and the task group of the pod must be one of the task groups of the app.
+// The placeholders are created by the shim from the app, they are not validated.
+func (app *Application) validateTask(task *Task) []string {
+	if task.placeholder || task.pod == nil {
+		return nil
+	}
+	conflicts := utils.GetApplicationIDConflicts(task.pod)
+
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	// the queue of an app resubmitted after its queue was not found is not the queue of its pods anymore
+	if queue, ok := task.pod.Labels[constants.LabelQueueName]; ok && queue != app.queue && !app.unknownQueueRetried {
+		conflicts = append(conflicts, fmt.Sprintf("label %s=%s conflicts with queue %s of application %s",
+			constants.LabelQueueName, queue, app.queue, app.applicationID))
+	}
+	if task.taskGroupName != "" {
+		found := false
+		for _, tg := range app.taskGroups {
+			if tg.Name == task.taskGroupName {
+				found = true
+				break
+			}
+		}
+		if !found {
+			conflicts = append(conflicts, fmt.Sprintf("task group %s is not defined in the task groups of application %s",
+				task.taskGroupName, app.applicationID))
+		}
+	}
+	return conflicts
+}
+
+// GetQuarantinedTasks returns the tasks that are not scheduled because the scheduling directives of their pods conflict
+func (ctx *Context) GetQuarantinedTasks() []*dao.QuarantinedTask {
+	result := make([]*dao.QuarantinedTask, 0)
+	ctx.applications.forEach(func(app *Application) {
+		app.lock.RLock()
+		defer app.lock.RUnlock()
+		for _, task := range app.getTasks(events.States().Task.Quarantined) {
+			result = append(result, &dao.QuarantinedTask{
+				ApplicationID: app.applicationID,
+				TaskID:        task.taskID,
+				TaskAlias:     task.alias,
+				TaskGroupName: task.getTaskGroupName(),
+				Conflicts:     task.getQuarantined(),
+				CreateTime:    task.createTime,
+			})
+		}
+	})
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].ApplicationID < result[j].ApplicationID
+	})
+	return result
+}
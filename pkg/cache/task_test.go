@@ -19,23 +19,32 @@
 package cache
 
 import (
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/opentracing/opentracing-go/mocktracer"
 	"gotest.tools/assert"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 
 	"github.com/apache/incubator-yunikorn-core/pkg/common"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/apis/yunikorn.apache.org/v1alpha1"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/appmgmt/interfaces"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/dispatcher"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/trace"
+	siCommon "github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/common"
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
 
@@ -401,6 +410,75 @@ func TestSortTasks(t *testing.T) {
 	assert.Equal(t, tasks[2], task2)
 }
 
+func TestSortTasksWithOrderingPolicy(t *testing.T) {
+	time0 := time.Now()
+	mockedContext := initContextForTest()
+	app := NewApplication("app01", "root.default",
+		"bob", map[string]string{}, newMockSchedulerAPI())
+
+	newPod := func(name string, created time.Time, priority int32, cpu string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: apis.ObjectMeta{
+				Name:              name,
+				UID:               types.UID("UID-" + name),
+				CreationTimestamp: metav1.Time{Time: created},
+			},
+			Spec: v1.PodSpec{
+				Priority: &priority,
+				Containers: []v1.Container{
+					{
+						Name: "container-01",
+						Resources: v1.ResourceRequirements{
+							Requests: v1.ResourceList{
+								v1.ResourceCPU: resource.MustParse(cpu),
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	// task0 is the oldest, task1 has the highest priority, task2 asks for the most resources
+	task0 := NewTask("task00", app, mockedContext, newPod("pod-00", time0, 0, "1"))
+	task1 := NewTask("task01", app, mockedContext, newPod("pod-01", time0.Add(time.Second), 100, "1"))
+	task2 := NewTask("task02", app, mockedContext, newPod("pod-02", time0.Add(2*time.Second), 0, "4"))
+	app.addTask(task0)
+	app.addTask(task1)
+	app.addTask(task2)
+
+	// default is FIFO
+	tasks := app.getNewTasksInSubmitOrder()
+	assert.DeepEqual(t, getTaskIDs(tasks), []string{"task00", "task01", "task02"})
+
+	app.setTaskOrderingPolicy(TaskOrderingPriority)
+	tasks = app.getNewTasksInSubmitOrder()
+	assert.DeepEqual(t, getTaskIDs(tasks), []string{"task01", "task00", "task02"})
+
+	app.setTaskOrderingPolicy(TaskOrderingResourceSize)
+	tasks = app.getNewTasksInSubmitOrder()
+	assert.DeepEqual(t, getTaskIDs(tasks), []string{"task02", "task00", "task01"})
+
+	// unknown policy is ignored
+	app.setTaskOrderingPolicy("unknown")
+	assert.Equal(t, app.taskOrderingPolicy, TaskOrderingResourceSize)
+
+	// custom policy, reverse creation time
+	RegisterTaskOrderingPolicy("LIFO", func(l, r *Task) bool {
+		return l.createTime.After(r.createTime)
+	})
+	app.setTaskOrderingPolicy("LIFO")
+	tasks = app.getNewTasksInSubmitOrder()
+	assert.DeepEqual(t, getTaskIDs(tasks), []string{"task02", "task01", "task00"})
+}
+
+func getTaskIDs(tasks []*Task) []string {
+	taskIDs := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		taskIDs = append(taskIDs, task.taskID)
+	}
+	return taskIDs
+}
+
 func TestIsTerminated(t *testing.T) {
 	mockedContext := initContextForTest()
 	mockedSchedulerAPI := newMockSchedulerAPI()
@@ -523,3 +601,515 @@ func TestHandleSubmitTaskEvent(t *testing.T) {
 	// Test over, set Recorder back fake type
 	events.SetRecorderForTest(record.NewFakeRecorder(1024))
 }
+
+func TestTaskSchedulingTimeout(t *testing.T) {
+	mockedContext := initContextForTest()
+	mockedApiProvider, ok := mockedContext.apiProvider.(*client.MockedAPIProvider)
+	if !ok {
+		t.Fatal("expecting MockedAPIProvider")
+	}
+	mockedApiProvider.GetAPIs().Conf.TaskSchedulingTimeout = 100 * time.Millisecond
+	pod := &v1.Pod{
+		TypeMeta: apis.TypeMeta{
+			Kind:       "Pod",
+			APIVersion: "v1",
+		},
+		ObjectMeta: apis.ObjectMeta{
+			Name: "pod-resource-test-00001",
+			UID:  "UID-00001",
+		},
+	}
+	// the timeout is applied through the dispatcher, like any other task event
+	dispatcher.RegisterEventHandler(dispatcher.EventTypeTask, mockedContext.TaskEventHandler())
+	dispatcher.Start()
+	defer dispatcher.Stop()
+	app := NewApplication("app01", "root.default",
+		"bob", map[string]string{}, newMockSchedulerAPI())
+	mockedContext.applications.put(app)
+	newTask := func(taskID string) *Task {
+		task := NewTask(taskID, app, mockedContext, pod)
+		task.sm.SetState(events.States().Task.Pending)
+		app.addTask(task)
+		return task
+	}
+
+	// fail policy, the task is failed
+	mockedApiProvider.GetAPIs().Conf.TaskSchedulingTimeoutPolicy = conf.TaskSchedulingTimeoutFail
+	task := newTask("task01")
+	err := task.handle(NewSubmitTaskEvent(app.applicationID, task.taskID))
+	assert.NilError(t, err, "failed to handle SubmitTask event")
+	assert.Equal(t, task.GetTaskState(), events.States().Task.Scheduling)
+
+	// the timer of a previous scheduling attempt is ignored
+	assert.NilError(t, task.handle(NewSchedulingTimeoutTaskEvent(app.applicationID, task.taskID, time.Time{}, true, "stale")))
+	assert.Equal(t, task.GetTaskState(), events.States().Task.Scheduling)
+
+	err = utils.WaitForCondition(func() bool {
+		return task.GetTaskState() == events.States().Task.Failed
+	}, 10*time.Millisecond, time.Second)
+	assert.NilError(t, err, "task is not failed after the scheduling timeout")
+	// 2 updates call, 1 for submit, 1 for releasing the ask
+	assert.Equal(t, mockedApiProvider.GetSchedulerApiUpdateCount(), int32(2))
+
+	// an allocated task is not affected by the timer
+	task = newTask("task02")
+	err = task.handle(NewSubmitTaskEvent(app.applicationID, task.taskID))
+	assert.NilError(t, err, "failed to handle SubmitTask event")
+	startTime := task.schedulingStartTime
+	err = task.handle(NewAllocateTaskEvent(app.applicationID, task.taskID, string(pod.UID), "node-1"))
+	assert.NilError(t, err, "failed to handle AllocateTask event")
+	err = utils.WaitForCondition(func() bool {
+		return task.GetTaskState() == events.States().Task.Bound
+	}, 10*time.Millisecond, time.Second)
+	assert.NilError(t, err, "task is not bound")
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, task.GetTaskState(), events.States().Task.Bound)
+	// nor by a timeout dispatched before the allocation but handled after it
+	assert.NilError(t, task.handle(NewSchedulingTimeoutTaskEvent(app.applicationID, task.taskID, startTime, true, "late")))
+	assert.Equal(t, task.GetTaskState(), events.States().Task.Bound)
+
+	// retry policy, the ask is released and the task is submitted again in a new scheduling attempt
+	mockedApiProvider.GetAPIs().Conf.TaskSchedulingTimeoutPolicy = conf.TaskSchedulingTimeoutRetry
+	task = newTask("task03")
+	err = task.handle(NewSubmitTaskEvent(app.applicationID, task.taskID))
+	assert.NilError(t, err, "failed to handle SubmitTask event")
+	task.lock.RLock()
+	startTime = task.schedulingStartTime
+	task.lock.RUnlock()
+	err = utils.WaitForCondition(func() bool {
+		task.lock.RLock()
+		defer task.lock.RUnlock()
+		return task.sm.Current() == events.States().Task.Scheduling && !task.schedulingStartTime.Equal(startTime)
+	}, 10*time.Millisecond, time.Second)
+	assert.NilError(t, err, "task is not resubmitted after the scheduling timeout")
+	// at least 3 more updates: the submit, the release of the ask and the submit of the new attempt
+	assert.Assert(t, mockedApiProvider.GetSchedulerApiUpdateCount() >= int32(5))
+}
+
+func TestTaskGroupTopology(t *testing.T) {
+	mockedContext := initContextForTest()
+	mockedApiProvider, ok := mockedContext.apiProvider.(*client.MockedAPIProvider)
+	if !ok {
+		t.Fatal("expecting MockedAPIProvider")
+	}
+	app := NewApplication("app01", "root.default",
+		"bob", map[string]string{}, newMockSchedulerAPI())
+	app.setTaskGroups([]v1alpha1.TaskGroup{
+		{
+			Name:      "test-group",
+			MinMember: 2,
+		},
+	})
+	newPod := func(uid string, annotations map[string]string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: apis.ObjectMeta{
+				Name:        "pod-" + uid,
+				UID:         types.UID(uid),
+				Annotations: annotations,
+			},
+		}
+	}
+
+	// placeholders keep the index of their pod
+	placeholder := NewFromTaskMeta("ph-01", app, mockedContext, interfaces.TaskMetadata{
+		ApplicationID: app.applicationID,
+		TaskID:        "ph-01",
+		Pod: newPod("ph-01", map[string]string{
+			constants.AnnotationTaskGroupName:  "test-group",
+			constants.AnnotationTaskGroupIndex: "1",
+		}),
+		Placeholder:   true,
+		TaskGroupName: "test-group",
+	})
+	app.addTask(placeholder)
+	assert.Equal(t, placeholder.getTaskGroupIndex(), int32(1))
+	assert.Equal(t, placeholder.taskGroupTotal, int32(2))
+
+	// real members are indexed in the order they are added
+	members := make([]*Task, 0)
+	for _, uid := range []string{"task-01", "task-02"} {
+		member := NewTask(uid, app, mockedContext, newPod(uid, map[string]string{
+			constants.AnnotationTaskGroupName: "test-group",
+		}))
+		app.addTask(member)
+		members = append(members, member)
+	}
+	assert.Equal(t, members[0].getTaskGroupIndex(), int32(0))
+	assert.Equal(t, members[1].getTaskGroupIndex(), int32(1))
+	assert.Equal(t, app.getTaskGroupMember("test-group", 1, false), members[1])
+	assert.Equal(t, app.getTaskGroupMember("test-group", 1, true), placeholder)
+	assert.Assert(t, app.getTaskGroupMember("test-group", 0, true) == nil)
+
+	// a task without task group has no index
+	task := NewTask("task-03", app, mockedContext, newPod("task-03", nil))
+	app.addTask(task)
+	assert.Equal(t, task.getTaskGroupIndex(), int32(-1))
+
+	// the gang topology is passed to the core in the ask tags
+	var tags map[string]string
+	mockedApiProvider.MockSchedulerApiUpdateFn(func(request *si.UpdateRequest) error {
+		tags = request.Asks[0].Tags
+		return nil
+	})
+	placeholder.sm.SetState(events.States().Task.Pending)
+	err := placeholder.handle(NewSubmitTaskEvent(app.applicationID, placeholder.taskID))
+	assert.NilError(t, err, "failed to handle SubmitTask event")
+	prefix := siCommon.DomainYuniKorn + siCommon.GroupMeta
+	assert.Equal(t, tags[prefix+constants.TagKeyTaskGroupName], "test-group")
+	assert.Equal(t, tags[prefix+constants.TagKeyTaskGroupIndex], "1")
+	assert.Equal(t, tags[prefix+constants.TagKeyTaskGroupTotal], "2")
+}
+
+func TestPreferredNodesHint(t *testing.T) {
+	mockedContext := initContextForTest()
+	mockedApiProvider, ok := mockedContext.apiProvider.(*client.MockedAPIProvider)
+	if !ok {
+		t.Fatal("expecting MockedAPIProvider")
+	}
+	app := NewApplication("app01", "root.default",
+		"bob", map[string]string{}, newMockSchedulerAPI())
+	var tags map[string]string
+	mockedApiProvider.MockSchedulerApiUpdateFn(func(request *si.UpdateRequest) error {
+		tags = request.Asks[0].Tags
+		return nil
+	})
+	key := siCommon.DomainYuniKorn + siCommon.GroupMeta + constants.TagKeyPreferredNodes
+
+	// the nodes of the previous run are passed to the core in the ask tags
+	task := NewTask("task-01", app, mockedContext, &v1.Pod{
+		ObjectMeta: apis.ObjectMeta{
+			Name: "pod-01",
+			UID:  "task-01",
+			Annotations: map[string]string{
+				constants.AnnotationPreferredNodes: "node-2, node-1,node-2",
+			},
+		},
+	})
+	task.sm.SetState(events.States().Task.Pending)
+	err := task.handle(NewSubmitTaskEvent(app.applicationID, task.taskID))
+	assert.NilError(t, err, "failed to handle SubmitTask event")
+	assert.Equal(t, tags[key], "node-2,node-1")
+
+	// no hint without the annotation
+	task = NewTask("task-02", app, mockedContext, &v1.Pod{
+		ObjectMeta: apis.ObjectMeta{
+			Name: "pod-02",
+			UID:  "task-02",
+		},
+	})
+	task.sm.SetState(events.States().Task.Pending)
+	err = task.handle(NewSubmitTaskEvent(app.applicationID, task.taskID))
+	assert.NilError(t, err, "failed to handle SubmitTask event")
+	_, ok = tags[key]
+	assert.Assert(t, !ok)
+}
+
+func TestTaskQueueOverride(t *testing.T) {
+	mockedContext := initContextForTest()
+	mockedApiProvider, ok := mockedContext.apiProvider.(*client.MockedAPIProvider)
+	if !ok {
+		t.Fatal("expecting MockedAPIProvider")
+	}
+	app := NewApplication("app01", "root.default",
+		"bob", map[string]string{}, newMockSchedulerAPI())
+	var tags map[string]string
+	mockedApiProvider.MockSchedulerApiUpdateFn(func(request *si.UpdateRequest) error {
+		tags = request.Asks[0].Tags
+		return nil
+	})
+	key := siCommon.DomainYuniKorn + siCommon.GroupMeta + constants.TagKeyTaskQueue
+	newTask := func(uid string, queue string) *Task {
+		pod := &v1.Pod{
+			ObjectMeta: apis.ObjectMeta{
+				Name: "pod-" + uid,
+				UID:  types.UID(uid),
+			},
+		}
+		if queue != "" {
+			pod.Annotations = map[string]string{constants.AnnotationTaskQueue: queue}
+		}
+		task := NewTask(uid, app, mockedContext, pod)
+		app.addTask(task)
+		task.sm.SetState(events.States().Task.Pending)
+		return task
+	}
+
+	// the driver goes to the queue of its own, the app queue is not changed
+	driver := newTask("task-01", "root.high")
+	err := driver.handle(NewSubmitTaskEvent(app.applicationID, driver.taskID))
+	assert.NilError(t, err, "failed to handle SubmitTask event")
+	assert.Equal(t, tags[key], "root.high")
+	assert.Equal(t, app.GetQueue(), "root.default")
+
+	// the workers stay in the queue of the app
+	for _, uid := range []string{"task-02", "task-03"} {
+		worker := newTask(uid, "")
+		err = worker.handle(NewSubmitTaskEvent(app.applicationID, worker.taskID))
+		assert.NilError(t, err, "failed to handle SubmitTask event")
+		_, ok = tags[key]
+		assert.Assert(t, !ok)
+	}
+
+	// tasks not asked yet are not part of the queue asks
+	newTask("task-04", "root.high")
+	info := app.getApplicationInfo()
+	assert.DeepEqual(t, info.QueueAsks, map[string][]string{
+		"root.high":    {"task-01"},
+		"root.default": {"task-02", "task-03"},
+	})
+}
+
+func TestTaskTracing(t *testing.T) {
+	mockedContext := initContextForTest()
+	mockedApiProvider, ok := mockedContext.apiProvider.(*client.MockedAPIProvider)
+	if !ok {
+		t.Fatal("expecting MockedAPIProvider")
+	}
+	tracer := mocktracer.New()
+	trace.SetTracer(tracer, nil)
+	defer trace.Close()
+	var lock sync.Mutex
+	var tags map[string]string
+	mockedApiProvider.MockSchedulerApiUpdateFn(func(request *si.UpdateRequest) error {
+		lock.Lock()
+		defer lock.Unlock()
+		if len(request.Asks) > 0 {
+			tags = request.Asks[0].Tags
+		}
+		return nil
+	})
+	app := NewApplication("app01", "root.default",
+		"bob", map[string]string{}, newMockSchedulerAPI())
+	createTime := apis.NewTime(time.Now().Add(-time.Second).Truncate(time.Second))
+	newTask := func(uid string) *Task {
+		task := NewTask(uid, app, mockedContext, &v1.Pod{
+			ObjectMeta: apis.ObjectMeta{
+				Name:              "pod-" + uid,
+				UID:               types.UID(uid),
+				CreationTimestamp: createTime,
+			},
+		})
+		app.addTask(task)
+		return task
+	}
+	getSpans := func() map[string]*mocktracer.MockSpan {
+		spans := make(map[string]*mocktracer.MockSpan)
+		for _, span := range tracer.FinishedSpans() {
+			if span.Tag(trace.TagTaskID) != nil || spans[span.OperationName] == nil {
+				spans[span.OperationName] = span
+			}
+		}
+		return spans
+	}
+
+	// the pod is traced from its add to its bind
+	task := newTask("task-01")
+	assert.NilError(t, task.handle(NewSimpleTaskEvent(app.applicationID, task.taskID, events.InitTask)))
+	assert.NilError(t, task.handle(NewSubmitTaskEvent(app.applicationID, task.taskID)))
+	lock.Lock()
+	spanContext, err := trace.ExtractTaskTrace(tracer, tags)
+	lock.Unlock()
+	assert.NilError(t, err, "the ask does not carry the span context of the task")
+	assert.NilError(t, task.handle(NewAllocateTaskEvent(app.applicationID, task.taskID, "uuid-01", "node-1")))
+	assert.NilError(t, task.handle(NewBindTaskEvent(app.applicationID, task.taskID)))
+	spans := getSpans()
+	root := spans[trace.SchedulePod]
+	assert.Assert(t, root != nil, "the trace of the pod is not finished")
+	assert.Equal(t, root.Tag(trace.TagAppID), "app01")
+	assert.Equal(t, root.Tag(trace.TagTaskID), "task-01")
+	assert.Equal(t, root.Tag("error"), nil)
+	// the trace starts when the pod is created
+	assert.Equal(t, root.StartTime, createTime.Time)
+	assert.Equal(t, spanContext.(mocktracer.MockSpanContext).SpanID, root.SpanContext.SpanID)
+	for _, stage := range []string{"Pending", "Scheduling", "Binding"} {
+		assert.Assert(t, spans[stage] != nil, "stage %s is not traced", stage)
+		assert.Equal(t, spans[stage].ParentID, root.SpanContext.SpanID)
+	}
+	assert.Equal(t, spans["SendAsk"].ParentID, spans["Scheduling"].SpanContext.SpanID)
+
+	// a pod that fails before it is bound ends its trace with an error
+	tracer.Reset()
+	task = newTask("task-02")
+	assert.NilError(t, task.handle(NewSimpleTaskEvent(app.applicationID, task.taskID, events.InitTask)))
+	assert.NilError(t, task.handle(NewSubmitTaskEvent(app.applicationID, task.taskID)))
+	assert.NilError(t, task.handle(NewFailTaskEvent(app.applicationID, task.taskID, "test failure")))
+	root = getSpans()[trace.SchedulePod]
+	assert.Assert(t, root != nil, "the trace of the pod is not finished")
+	assert.Equal(t, root.Tag("error"), true)
+	assert.Equal(t, root.Tag(trace.TagState), events.States().Task.Failed)
+
+	// a recovered task is not traced
+	tracer.Reset()
+	task = newTask("task-03")
+	task.sm.SetState(events.States().Task.Scheduling)
+	assert.NilError(t, task.handle(NewAllocateTaskEvent(app.applicationID, task.taskID, "uuid-03", "node-1")))
+	assert.NilError(t, task.handle(NewBindTaskEvent(app.applicationID, task.taskID)))
+	assert.Equal(t, getSpans()[trace.SchedulePod], (*mocktracer.MockSpan)(nil))
+}
+
+func TestTaskGroupRequestedIndex(t *testing.T) {
+	mockedContext := initContextForTest()
+	recorder := record.NewFakeRecorder(1024)
+	events.SetRecorderForTest(recorder)
+	app := NewApplication("app01", "root.default",
+		"bob", map[string]string{}, newMockSchedulerAPI())
+	app.setTaskGroups([]v1alpha1.TaskGroup{
+		{
+			Name:      "test-group",
+			MinMember: 3,
+		},
+	})
+	newMember := func(uid string, index string) *Task {
+		annotations := map[string]string{constants.AnnotationTaskGroupName: "test-group"}
+		if index != "" {
+			annotations[constants.AnnotationTaskGroupIndex] = index
+		}
+		task := NewTask(uid, app, mockedContext, &v1.Pod{
+			ObjectMeta: apis.ObjectMeta{
+				Name:        "pod-" + uid,
+				UID:         types.UID(uid),
+				Annotations: annotations,
+			},
+		})
+		app.addTask(task)
+		return task
+	}
+	placeholder := NewFromTaskMeta("ph-01", app, mockedContext, interfaces.TaskMetadata{
+		ApplicationID: app.applicationID,
+		TaskID:        "ph-01",
+		Pod: &v1.Pod{
+			ObjectMeta: apis.ObjectMeta{
+				Name: "ph-01",
+				UID:  "ph-01",
+				Annotations: map[string]string{
+					constants.AnnotationTaskGroupName:  "test-group",
+					constants.AnnotationTaskGroupIndex: "1",
+				},
+			},
+		},
+		Placeholder:   true,
+		TaskGroupName: "test-group",
+	})
+	app.addTask(placeholder)
+	placeholder.nodeName = "node-1"
+
+	// the requested index is kept, a taken index is replaced by the lowest free index
+	requested := newMember("task-01", "1")
+	duplicate := newMember("task-02", "1")
+	unset := newMember("task-03", "")
+	assert.Equal(t, requested.getTaskGroupIndex(), int32(1))
+	assert.Assert(t, requested.indexRequested)
+	assert.Equal(t, duplicate.getTaskGroupIndex(), int32(0))
+	assert.Assert(t, !duplicate.indexRequested)
+	assert.Equal(t, unset.getTaskGroupIndex(), int32(2))
+	assert.Equal(t, app.getTaskGroupMember("test-group", 1, false), requested)
+
+	// the member is expected on the node of the placeholder with the same index
+	requested.checkTaskGroupPlacement("node-1")
+	assert.Equal(t, len(recorder.Events), 0)
+	requested.checkTaskGroupPlacement("node-2")
+	assert.Equal(t, len(recorder.Events), 1)
+	event := <-recorder.Events
+	assert.Assert(t, strings.Contains(event, "TaskGroupPlacementMismatch"), event)
+	// members without a requested index are not checked
+	duplicate.checkTaskGroupPlacement("node-2")
+	assert.Equal(t, len(recorder.Events), 0)
+}
+
+func TestReleaseAskOfDeletedTask(t *testing.T) {
+	states := events.States().Task
+	testCases := []struct {
+		state       string
+		moves       []events.TaskEventType
+		askReleased bool
+	}{
+		{states.New, nil, true},
+		{states.Gated, []events.TaskEventType{events.GateTask}, false},
+		{states.Pending, []events.TaskEventType{events.InitTask}, true},
+		{states.Scheduling, []events.TaskEventType{events.InitTask, events.SubmitTask}, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.state, func(t *testing.T) {
+			mockedContext := initContextForTest()
+			mockedApiProvider, ok := mockedContext.apiProvider.(*client.MockedAPIProvider)
+			assert.Assert(t, ok, "expecting MockedAPIProvider")
+			app := NewApplication("app01", "root.default",
+				"bob", map[string]string{}, newMockSchedulerAPI())
+			task := NewTask("task01", app, mockedContext, &v1.Pod{
+				ObjectMeta: apis.ObjectMeta{
+					Name: "pod-01",
+					UID:  "UID-00001",
+				},
+			})
+			for _, move := range tc.moves {
+				assert.NilError(t, task.handle(NewSimpleTaskEvent(app.applicationID, task.taskID, move)))
+			}
+			assert.Equal(t, task.GetTaskState(), tc.state)
+
+			var released []*si.AllocationAskRelease
+			mockedApiProvider.MockSchedulerApiUpdateFn(func(request *si.UpdateRequest) error {
+				assert.Assert(t, request.Releases != nil)
+				assert.Assert(t, request.Releases.AllocationsToRelease == nil)
+				released = append(released, request.Releases.AllocationAsksToRelease...)
+				return nil
+			})
+			// the pod is deleted
+			assert.NilError(t, task.handle(NewSimpleTaskEvent(app.applicationID, task.taskID, events.CompleteTask)))
+			assert.Equal(t, task.GetTaskState(), states.Completed)
+			if !tc.askReleased {
+				assert.Equal(t, len(released), 0)
+				return
+			}
+			assert.Equal(t, len(released), 1)
+			assert.Equal(t, released[0].ApplicationID, app.applicationID)
+			assert.Equal(t, released[0].Allocationkey, task.taskID)
+			assert.Assert(t, task.schedulingTimer == nil)
+		})
+	}
+}
+
+func TestReleaseLateAllocation(t *testing.T) {
+	mockedContext := initContextForTest()
+	mockedApiProvider, ok := mockedContext.apiProvider.(*client.MockedAPIProvider)
+	assert.Assert(t, ok, "expecting MockedAPIProvider")
+	mockedContext.AddApplication(&interfaces.AddApplicationRequest{
+		Metadata: interfaces.ApplicationMetadata{
+			ApplicationID: "app01",
+			QueueName:     "root.default",
+			User:          "bob",
+		},
+	})
+	task := mockedContext.AddTask(&interfaces.AddTaskRequest{
+		Metadata: interfaces.TaskMetadata{
+			ApplicationID: "app01",
+			TaskID:        "task01",
+			Pod: &v1.Pod{
+				ObjectMeta: apis.ObjectMeta{
+					Name: "pod-01",
+					UID:  "task01",
+				},
+			},
+		},
+	})
+	assert.Assert(t, task != nil)
+	handler := mockedContext.TaskEventHandler()
+	handler(NewSimpleTaskEvent("app01", "task01", events.InitTask))
+	handler(NewSubmitTaskEvent("app01", "task01"))
+	assert.Equal(t, task.GetTaskState(), events.States().Task.Scheduling)
+	// the pod is deleted while the core allocates the ask
+	handler(NewSimpleTaskEvent("app01", "task01", events.CompleteTask))
+	assert.Equal(t, task.GetTaskState(), events.States().Task.Completed)
+
+	var released []*si.AllocationRelease
+	mockedApiProvider.MockSchedulerApiUpdateFn(func(request *si.UpdateRequest) error {
+		assert.Assert(t, request.Releases != nil)
+		released = append(released, request.Releases.AllocationsToRelease...)
+		return nil
+	})
+	handler(NewAllocateTaskEvent("app01", "task01", "uuid-01", "node-1"))
+	assert.Equal(t, task.GetTaskState(), events.States().Task.Completed)
+	assert.Equal(t, len(released), 1)
+	assert.Equal(t, released[0].UUID, "uuid-01")
+	assert.Equal(t, released[0].ApplicationID, "app01")
+	assert.Equal(t, released[0].TerminationType, si.TerminationType_STOPPED_BY_RM)
+}
@@ -0,0 +1,85 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+)
+
+// throughputBurst tracks the window after the recovery the scheduling limits are raised in. The recovered
+// apps have all their tasks pending at once, more tasks are submitted per cycle and more allocations are bound
+// in parallel until the window ends, the steady state limits are then restored.
+type throughputBurst struct {
+	timer *time.Timer
+	lock  sync.Mutex
+}
+
+// StartThroughputBurst raises the scheduling limits by the burst factor for the burst window,
+// this is a noop if the burst is disabled
+func (ctx *Context) StartThroughputBurst() {
+	configs := ctx.apiProvider.GetAPIs().Conf
+	window := configs.GetBurstWindow()
+	if window == 0 {
+		return
+	}
+	factor := configs.GetBurstFactor()
+	ctx.burst.lock.Lock()
+	defer ctx.burst.lock.Unlock()
+	if ctx.burst.timer != nil {
+		return
+	}
+	ctx.setSchedulingLimits(factor)
+	log.Logger().Info("throughput burst started",
+		zap.Duration("window", window),
+		zap.Int("factor", factor))
+	ctx.burst.timer = time.AfterFunc(window, ctx.StopThroughputBurst)
+}
+
+// StopThroughputBurst restores the steady state scheduling limits, this is a noop if no burst is in progress
+func (ctx *Context) StopThroughputBurst() {
+	ctx.burst.lock.Lock()
+	defer ctx.burst.lock.Unlock()
+	if ctx.burst.timer == nil {
+		return
+	}
+	ctx.burst.timer.Stop()
+	ctx.burst.timer = nil
+	ctx.setSchedulingLimits(1)
+	log.Logger().Info("throughput burst ended, the steady state scheduling limits are restored")
+}
+
+// isThroughputBurst returns true while the scheduling limits are raised
+func (ctx *Context) isThroughputBurst() bool {
+	ctx.burst.lock.Lock()
+	defer ctx.burst.lock.Unlock()
+	return ctx.burst.timer != nil
+}
+
+// setSchedulingLimits multiplies the configured scheduling limits by the factor, the limits are read again
+// from the configuration as they may have changed since the burst started
+func (ctx *Context) setSchedulingLimits(factor int) {
+	configs := ctx.apiProvider.GetAPIs().Conf
+	ctx.appScheduler.setLimits(configs.GetScheduleWorkers()*factor, configs.GetScheduleTaskBudget()*factor)
+	ctx.bindQueue.setMaxWorkers(configs.GetBindWorkers() * factor)
+}
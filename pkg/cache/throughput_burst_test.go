@@ -0,0 +1,84 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
+)
+
+func TestThroughputBurst(t *testing.T) {
+	apiProvider := client.NewMockedAPIProvider()
+	configs := apiProvider.GetAPIs().Conf
+	configs.ScheduleWorkers = 2
+	configs.ScheduleTaskBudget = 10
+	configs.BindWorkers = 4
+	configs.BurstFactor = 3
+	context := NewContext(apiProvider)
+	getLimits := func() (int, int, int) {
+		context.appScheduler.lock.Lock()
+		workers, budget := context.appScheduler.maxWorkers, context.appScheduler.budget
+		context.appScheduler.lock.Unlock()
+		context.bindQueue.lock.Lock()
+		defer context.bindQueue.lock.Unlock()
+		return workers, budget, context.bindQueue.maxWorkers
+	}
+
+	// no burst without a window
+	context.StartThroughputBurst()
+	assert.Assert(t, !context.isThroughputBurst())
+	workers, budget, bindWorkers := getLimits()
+	assert.Equal(t, workers, 2)
+	assert.Equal(t, budget, 10)
+	assert.Equal(t, bindWorkers, 4)
+
+	// the limits are raised for the window
+	configs.BurstWindow = 100 * time.Millisecond
+	context.StartThroughputBurst()
+	assert.Assert(t, context.isThroughputBurst())
+	workers, budget, bindWorkers = getLimits()
+	assert.Equal(t, workers, 6)
+	assert.Equal(t, budget, 30)
+	assert.Equal(t, bindWorkers, 12)
+
+	// the steady state limits are restored once the window is over
+	err := utils.WaitForCondition(func() bool {
+		return !context.isThroughputBurst()
+	}, 10*time.Millisecond, 5*time.Second)
+	assert.NilError(t, err)
+	workers, budget, bindWorkers = getLimits()
+	assert.Equal(t, workers, 2)
+	assert.Equal(t, budget, 10)
+	assert.Equal(t, bindWorkers, 4)
+
+	// the burst can be ended before the window is over
+	configs.BurstWindow = time.Hour
+	context.StartThroughputBurst()
+	assert.Assert(t, context.isThroughputBurst())
+	context.StopThroughputBurst()
+	assert.Assert(t, !context.isThroughputBurst())
+	workers, _, bindWorkers = getLimits()
+	assert.Equal(t, workers, 2)
+	assert.Equal(t, bindWorkers, 4)
+}
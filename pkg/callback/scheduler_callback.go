@@ -25,6 +25,7 @@ import (
 
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/cache"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/dispatcher"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
@@ -81,10 +82,11 @@ func (callback *AsyncRMCallback) RecvUpdateResponse(response *si.UpdateResponse)
 	for _, app := range response.RejectedApplications {
 		// update context
 		log.Logger().Debug("callback: response to rejected application",
-			zap.String("appID", app.ApplicationID))
+			zap.String("appID", app.ApplicationID),
+			zap.String("reason", app.Reason))
 
-		if app := callback.context.GetApplication(app.ApplicationID); app != nil {
-			ev := cache.NewSimpleApplicationEvent(app.GetApplicationID(), events.RejectApplication)
+		if managedApp := callback.context.GetApplication(app.ApplicationID); managedApp != nil {
+			ev := cache.NewRejectApplicationEvent(managedApp.GetApplicationID(), app.Reason)
 			dispatcher.Dispatch(ev)
 		}
 	}
@@ -116,24 +118,41 @@ func (callback *AsyncRMCallback) RecvUpdateResponse(response *si.UpdateResponse)
 		}
 	}
 
+	// the pods deleted for the preempted allocations are selected before the releases are handled
+	callback.context.SelectReleaseVictims(response.ReleasedAllocations)
 	for _, release := range response.ReleasedAllocations {
 		log.Logger().Debug("callback: response to released allocations",
 			zap.String("UUID", release.UUID))
 
-		// TerminationType 0 mean STOPPED_BY_RM
-		if release.TerminationType != si.TerminationType_STOPPED_BY_RM {
+		// TerminationType 0 mean STOPPED_BY_RM, the allocations of a removed app are released by the core
+		if release.TerminationType == si.TerminationType_STOPPED_BY_RM {
+			callback.context.NotifyAllocationReleased(release.ApplicationID, release.UUID)
+		} else {
+			// the allocation may not be known yet when its allocate event is still queued,
+			// the release is dispatched anyway and handled in order
+			if task, err := callback.context.GetTaskByAllocation(release.ApplicationID, release.UUID); err == nil {
+				log.Logger().Info("allocation released by the scheduler",
+					zap.String("appID", release.ApplicationID),
+					zap.String("taskID", task.GetTaskID()),
+					zap.String("podName", task.GetTaskPod().Name),
+					zap.String("terminationType", release.TerminationType.String()))
+			}
 			// send release app allocation to application states machine
 			ev := cache.NewReleaseAppAllocationEvent(release.ApplicationID, release.TerminationType, release.UUID)
 			dispatcher.Dispatch(ev)
 		}
 	}
 
+	// the asks of the timed out placeholders are always released,
+	// the asks released for any other reason only when a real task is failed or resubmitted
+	releasedAskPolicy := conf.GetSchedulerConf().GetReleasedAskPolicy()
 	for _, ask := range response.ReleasedAllocationAsks {
 		log.Logger().Debug("callback: response to released allocations",
 			zap.String("allocation key", ask.Allocationkey))
 
-		if ask.TerminationType == si.TerminationType_TIMEOUT {
-			ev := cache.NewReleaseAppAllocationAskEvent(ask.ApplicationID, ask.TerminationType, ask.Allocationkey)
+		if ask.TerminationType == si.TerminationType_TIMEOUT || releasedAskPolicy != conf.ReleasedAskSkip {
+			ev := cache.NewReleaseAppAllocationAskEvent(ask.ApplicationID, ask.TerminationType, ask.Allocationkey,
+				releasedAskPolicy)
 			dispatcher.Dispatch(ev)
 		}
 	}
@@ -143,20 +162,7 @@ func (callback *AsyncRMCallback) RecvUpdateResponse(response *si.UpdateResponse)
 		log.Logger().Debug("status update callback received",
 			zap.String("appId", updated.ApplicationID),
 			zap.String("new status", updated.State))
-		// delete application from context
-		if updated.State == events.States().Application.Completed {
-			err := callback.context.RemoveApplicationInternal(updated.ApplicationID)
-			if err != nil {
-				log.Logger().Error("failed to delete application", zap.Error(err))
-			}
-		} else {
-			if updated.State == "Failing" || updated.State == events.States().Application.Failed {
-				ev := cache.NewFailApplicationEvent(updated.ApplicationID, updated.Message)
-				dispatcher.Dispatch(ev)
-			}
-			// handle status update
-			dispatcher.Dispatch(cache.NewApplicationStatusChangeEvent(updated.ApplicationID, events.AppStateChange, updated.State))
-		}
+		callback.context.HandleApplicationStateUpdate(updated)
 	}
 
 	return nil
@@ -28,6 +28,9 @@ import (
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/client/informers/externalversions/yunikorn.apache.org/v1alpha1"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/kubernetes/pkg/scheduler/volumebinder"
@@ -49,6 +52,7 @@ const (
 	PVInformerHandlers
 	PVCInformerHandlers
 	ApplicationInformerHandlers
+	NamespaceInformerHandlers
 )
 
 type APIProvider interface {
@@ -57,6 +61,7 @@ type APIProvider interface {
 	Start()
 	Stop()
 	WaitForSync() error
+	HasSynced() bool
 	IsTestingMode() bool
 }
 
@@ -82,25 +87,48 @@ type APIFactory struct {
 func NewAPIFactory(scheduler api.SchedulerAPI, configs *conf.SchedulerConf, testMode bool) *APIFactory {
 	kubeClient := NewKubeClient(configs.KubeConfig)
 
-	// we have disabled re-sync to keep ourselves up-to-date
-	informerFactory := informers.NewSharedInformerFactory(kubeClient.GetClientSet(), 0)
+	// re-sync is disabled by default, the events keep ourselves up-to-date,
+	// the initial list of the informers is paged to reduce the load of the api-server on large clusters
+	pageList := func(options *metav1.ListOptions) {
+		if options.Limit == 0 {
+			options.Limit = configs.GetKubeListPageSize()
+		}
+	}
+	informerFactory := informers.NewSharedInformerFactoryWithOptions(kubeClient.GetClientSet(),
+		configs.GetInformerResyncPeriod(),
+		informers.WithTweakListOptions(pageList))
 
 	// init informers
 	// volume informers are also used to get the Listers for the predicates
 	nodeInformer := informerFactory.Core().V1().Nodes()
 	podInformer := informerFactory.Core().V1().Pods()
+	watchedNamespaces := getWatchedNamespaces(kubeClient, configs)
+	if watchedNamespaces != nil {
+		podInformer = newNamespacedPodInformer(kubeClient.GetClientSet(), watchedNamespaces,
+			configs.GetInformerResyncPeriod(), pageList)
+		log.Logger().Info("the pods are watched in the selected namespaces only",
+			zap.Strings("namespaces", watchedNamespaces))
+	}
 	configMapInformer := informerFactory.Core().V1().ConfigMaps()
 	storageInformer := informerFactory.Storage().V1().StorageClasses()
 	pvInformer := informerFactory.Core().V1().PersistentVolumes()
 	pvcInformer := informerFactory.Core().V1().PersistentVolumeClaims()
 	namespaceInformer := informerFactory.Core().V1().Namespaces()
+	runtimeClassInformer := informerFactory.Node().V1beta1().RuntimeClasses()
 
 	var appClient *appclient.Clientset = nil
 	var applicationInformer v1alpha1.ApplicationInformer = nil
 
 	if configs.IsOperatorPluginEnabled(constants.AppManagerHandlerName) {
 		appClient = applicationclient.NewForConfigOrDie(kubeClient.GetConfigs())
-		applicationInformer = appinformers.NewSharedInformerFactory(appClient, time.Minute*1).Apache().V1alpha1().Applications()
+		applicationInformer = appinformers.NewSharedInformerFactory(appClient,
+			configs.GetAppInformerResyncPeriod()).Apache().V1alpha1().Applications()
+	}
+
+	// the provisioning requests are not part of client-go, they are handled as unstructured objects
+	var dynamicClient dynamic.Interface = nil
+	if configs.EnableProvisioningRequest {
+		dynamicClient = dynamic.NewForConfigOrDie(kubeClient.GetConfigs())
 	}
 
 	// create a volume binder (needs the informers)
@@ -113,20 +141,23 @@ func NewAPIFactory(scheduler api.SchedulerAPI, configs *conf.SchedulerConf, test
 
 	return &APIFactory{
 		clients: &Clients{
-			Conf:              configs,
-			KubeClient:        kubeClient,
-			AppClient:         appClient,
-			SchedulerAPI:      scheduler,
-			InformerFactory:   informerFactory,
-			PodInformer:       podInformer,
-			NodeInformer:      nodeInformer,
-			ConfigMapInformer: configMapInformer,
-			PVInformer:        pvInformer,
-			PVCInformer:       pvcInformer,
-			NamespaceInformer: namespaceInformer,
-			StorageInformer:   storageInformer,
-			VolumeBinder:      volumeBinder,
-			AppInformer:       applicationInformer,
+			Conf:                 configs,
+			KubeClient:           kubeClient,
+			AppClient:            appClient,
+			DynamicClient:        dynamicClient,
+			SchedulerAPI:         scheduler,
+			InformerFactory:      informerFactory,
+			PodInformer:          podInformer,
+			NodeInformer:         nodeInformer,
+			ConfigMapInformer:    configMapInformer,
+			PVInformer:           pvInformer,
+			PVCInformer:          pvcInformer,
+			NamespaceInformer:    namespaceInformer,
+			StorageInformer:      storageInformer,
+			VolumeBinder:         volumeBinder,
+			AppInformer:          applicationInformer,
+			RuntimeClassInformer: runtimeClassInformer,
+			WatchedNamespaces:    watchedNamespaces,
 		},
 		testMode: testMode,
 		stopChan: make(chan struct{}),
@@ -134,6 +165,34 @@ func NewAPIFactory(scheduler api.SchedulerAPI, configs *conf.SchedulerConf, test
 	}
 }
 
+// getWatchedNamespaces returns the namespaces listed in the configuration and the namespaces matching the configured
+// selector when the scheduler starts, nil if the pods of all namespaces are watched
+func getWatchedNamespaces(kubeClient KubeClient, configs *conf.SchedulerConf) []string {
+	namespaces := configs.GetWatchNamespaces()
+	selector := configs.GetWatchNamespaceSelector()
+	if selector == "" {
+		if len(namespaces) == 0 {
+			return nil
+		}
+		return namespaces
+	}
+	if _, err := labels.Parse(selector); err != nil {
+		log.Logger().Fatal("invalid namespace selector",
+			zap.String("watchNamespaceSelector", selector),
+			zap.Error(err))
+	}
+	selected, err := kubeClient.GetClientSet().CoreV1().Namespaces().List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		log.Logger().Fatal("failed to list the namespaces matching the namespace selector",
+			zap.String("watchNamespaceSelector", selector),
+			zap.Error(err))
+	}
+	for _, namespace := range selected.Items {
+		namespaces = append(namespaces, namespace.Name)
+	}
+	return namespaces
+}
+
 func (s *APIFactory) GetAPIs() *Clients {
 	return s.clients
 }
@@ -164,7 +223,7 @@ func (s *APIFactory) AddEventHandler(handlers *ResourceEventHandlers) {
 		h = fns
 	}
 
-	s.addEventHandlers(handlers.Type, h, 0)
+	s.addEventHandlers(handlers.Type, h, s.clients.Conf.GetInformerResyncPeriod())
 }
 
 func (s *APIFactory) addEventHandlers(
@@ -191,6 +250,9 @@ func (s *APIFactory) addEventHandlers(
 	case ApplicationInformerHandlers:
 		s.GetAPIs().AppInformer.Informer().
 			AddEventHandlerWithResyncPeriod(handler, resyncPeriod)
+	case NamespaceInformerHandlers:
+		s.GetAPIs().NamespaceInformer.Informer().
+			AddEventHandlerWithResyncPeriod(handler, resyncPeriod)
 	}
 }
 
@@ -202,6 +264,13 @@ func (s *APIFactory) WaitForSync() error {
 	return s.clients.WaitForSync(time.Second, 30*time.Second)
 }
 
+func (s *APIFactory) HasSynced() bool {
+	if s.testMode {
+		return true
+	}
+	return s.clients.HasSynced()
+}
+
 func (s *APIFactory) Start() {
 	// launch clients
 	if !s.IsTestingMode() {
@@ -24,6 +24,8 @@ import (
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
 	corev1 "k8s.io/client-go/listers/core/v1"
 	storagev1 "k8s.io/client-go/listers/storage/v1"
 	"k8s.io/client-go/tools/cache"
@@ -58,6 +60,7 @@ func NewMockedAPIProvider() *MockedAPIProvider {
 			KubeClient:        NewKubeClientMock(),
 			SchedulerAPI:      test.NewSchedulerAPIMock(),
 			AppClient:         fake.NewSimpleClientset(),
+			DynamicClient:     dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()),
 			PodInformer:       test.NewMockedPodInformer(),
 			NodeInformer:      test.NewMockedNodeInformer(),
 			ConfigMapInformer: test.NewMockedConfigMapInformer(),
@@ -103,6 +106,12 @@ func (m *MockedAPIProvider) MockDeleteFn(dfn func(pod *v1.Pod) error) {
 	}
 }
 
+func (m *MockedAPIProvider) MockEvictFn(efn func(pod *v1.Pod) error) {
+	if mock, ok := m.clients.KubeClient.(*KubeClientMock); ok {
+		mock.evictFn = efn
+	}
+}
+
 func (m *MockedAPIProvider) MockCreateFn(cfn func(pod *v1.Pod) (*v1.Pod, error)) {
 	if mock, ok := m.clients.KubeClient.(*KubeClientMock); ok {
 		mock.createFn = cfn
@@ -147,6 +156,10 @@ func (m *MockedAPIProvider) WaitForSync() error {
 	return nil
 }
 
+func (m *MockedAPIProvider) HasSynced() bool {
+	return true
+}
+
 // MockedPersistentVolumeInformer implements PersistentVolumeInformer interface
 type MockedPersistentVolumeInformer struct{}
 
@@ -23,8 +23,10 @@ import (
 
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/client/informers/externalversions/yunikorn.apache.org/v1alpha1"
 
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
 	coreInformerV1 "k8s.io/client-go/informers/core/v1"
+	nodeInformerV1beta1 "k8s.io/client-go/informers/node/v1beta1"
 	storageInformerV1 "k8s.io/client-go/informers/storage/v1"
 	"k8s.io/kubernetes/pkg/scheduler/volumebinder"
 
@@ -45,6 +47,8 @@ type Clients struct {
 	KubeClient   KubeClient
 	SchedulerAPI api.SchedulerAPI
 	AppClient    appclient.Interface
+	// the client of the ProvisioningRequests, nil if they are disabled
+	DynamicClient dynamic.Interface
 
 	// informer factory
 	InformerFactory informers.SharedInformerFactory
@@ -58,23 +62,44 @@ type Clients struct {
 	StorageInformer   storageInformerV1.StorageClassInformer
 	NamespaceInformer coreInformerV1.NamespaceInformer
 	AppInformer       v1alpha1.ApplicationInformer
+	// the runtime classes give the overhead of the pods admitted without it, nil if not watched
+	RuntimeClassInformer nodeInformerV1beta1.RuntimeClassInformer
 
 	// volume binder handles PV/PVC related operations
 	VolumeBinder *volumebinder.VolumeBinder
+
+	// the namespaces the pods are watched in, nil if all namespaces are watched
+	WatchedNamespaces []string
+}
+
+// IsNamespaceWatched returns true if the pods of the namespace are watched
+func (c *Clients) IsNamespaceWatched(namespace string) bool {
+	if c.WatchedNamespaces == nil {
+		return true
+	}
+	for _, watched := range c.WatchedNamespaces {
+		if watched == namespace {
+			return true
+		}
+	}
+	return false
 }
 
 func (c *Clients) WaitForSync(interval time.Duration, timeout time.Duration) error {
-	return utils.WaitForCondition(func() bool {
-		// cache is re-sync'd when all informers are sync'd
-		return c.NodeInformer.Informer().HasSynced() &&
-			c.PodInformer.Informer().HasSynced() &&
-			c.PVCInformer.Informer().HasSynced() &&
-			c.PVInformer.Informer().HasSynced() &&
-			c.StorageInformer.Informer().HasSynced() &&
-			c.ConfigMapInformer.Informer().HasSynced() &&
-			c.NamespaceInformer.Informer().HasSynced() &&
-			(c.AppInformer == nil || c.AppInformer.Informer().HasSynced())
-	}, interval, timeout)
+	return utils.WaitForCondition(c.HasSynced, interval, timeout)
+}
+
+// HasSynced returns true when the caches of all informers are synced
+func (c *Clients) HasSynced() bool {
+	return c.NodeInformer.Informer().HasSynced() &&
+		c.PodInformer.Informer().HasSynced() &&
+		c.PVCInformer.Informer().HasSynced() &&
+		c.PVInformer.Informer().HasSynced() &&
+		c.StorageInformer.Informer().HasSynced() &&
+		c.ConfigMapInformer.Informer().HasSynced() &&
+		c.NamespaceInformer.Informer().HasSynced() &&
+		(c.AppInformer == nil || c.AppInformer.Informer().HasSynced()) &&
+		(c.RuntimeClassInformer == nil || c.RuntimeClassInformer.Informer().HasSynced())
 }
 
 func (c *Clients) Run(stopCh <-chan struct{}) {
@@ -88,4 +113,7 @@ func (c *Clients) Run(stopCh <-chan struct{}) {
 	if c.AppInformer != nil {
 		go c.AppInformer.Informer().Run(stopCh)
 	}
+	if c.RuntimeClassInformer != nil {
+		go c.RuntimeClassInformer.Informer().Run(stopCh)
+	}
 }
@@ -0,0 +1,136 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+)
+
+// dryRunKubeClient logs the changes the scheduler decides to make to the pods instead of making them,
+// the pods are reported as bound, created, deleted or evicted.
+type dryRunKubeClient struct {
+	KubeClient
+}
+
+func newDryRunKubeClient(kubeClient KubeClient) KubeClient {
+	log.Logger().Info("dry-run mode: the scheduler observes the cluster, it does not change it")
+	return dryRunKubeClient{KubeClient: kubeClient}
+}
+
+func (c dryRunKubeClient) Bind(pod *v1.Pod, hostID string) error {
+	log.Logger().Info("dry-run: skipped binding pod to node",
+		zap.String("namespace", pod.Namespace),
+		zap.String("podName", pod.Name),
+		zap.String("nodeID", hostID))
+	return nil
+}
+
+func (c dryRunKubeClient) Create(pod *v1.Pod) (*v1.Pod, error) {
+	log.Logger().Info("dry-run: skipped creating pod",
+		zap.String("namespace", pod.Namespace),
+		zap.String("podName", pod.Name))
+	return pod, nil
+}
+
+func (c dryRunKubeClient) Delete(pod *v1.Pod) error {
+	log.Logger().Info("dry-run: skipped deleting pod",
+		zap.String("namespace", pod.Namespace),
+		zap.String("podName", pod.Name))
+	return nil
+}
+
+func (c dryRunKubeClient) DeleteWithGracePeriod(pod *v1.Pod, gracePeriod time.Duration) error {
+	log.Logger().Info("dry-run: skipped deleting pod",
+		zap.String("namespace", pod.Namespace),
+		zap.String("podName", pod.Name),
+		zap.Duration("gracePeriod", gracePeriod))
+	return nil
+}
+
+func (c dryRunKubeClient) Evict(pod *v1.Pod) error {
+	log.Logger().Info("dry-run: skipped evicting pod",
+		zap.String("namespace", pod.Namespace),
+		zap.String("podName", pod.Name))
+	return nil
+}
+
+func (c dryRunKubeClient) GetClientSet() kubernetes.Interface {
+	return c.KubeClient.GetClientSet()
+}
+
+func (c dryRunKubeClient) GetConfigs() *rest.Config {
+	return c.KubeClient.GetConfigs()
+}
+
+// dryRunTransport answers the requests that change the cluster without sending them to the api-server,
+// e.g. the pod status updates, the events or the application CRD updates, the requests that read are sent.
+// The response echoes the request body, the clients decode it as the object written by the server.
+type dryRunTransport struct {
+	delegate http.RoundTripper
+}
+
+func wrapDryRunTransport(rt http.RoundTripper) http.RoundTripper {
+	return &dryRunTransport{delegate: rt}
+}
+
+func (t *dryRunTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return t.delegate.RoundTrip(req)
+	}
+	body := []byte("{}")
+	if req.Body != nil {
+		data, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > 0 && req.Method != http.MethodDelete && req.Method != http.MethodPatch {
+			body = data
+		}
+	}
+	log.Logger().Debug("dry-run: skipped request to the api-server",
+		zap.String("method", req.Method),
+		zap.String("path", req.URL.Path))
+	contentType := req.Header.Get("Content-Type")
+	if contentType == "" || req.Method == http.MethodDelete || req.Method == http.MethodPatch {
+		contentType = "application/json"
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         req.Proto,
+		ProtoMajor:    req.ProtoMajor,
+		ProtoMinor:    req.ProtoMinor,
+		Header:        http.Header{"Content-Type": []string{contentType}},
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
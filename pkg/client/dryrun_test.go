@@ -0,0 +1,103 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestDryRunKubeClient(t *testing.T) {
+	mock := NewKubeClientMock()
+	calls := 0
+	mock.MockBindFn(func(pod *v1.Pod, hostID string) error {
+		calls++
+		return nil
+	})
+	mock.MockDeleteFn(func(pod *v1.Pod) error {
+		calls++
+		return nil
+	})
+	mock.MockCreateFn(func(pod *v1.Pod) (*v1.Pod, error) {
+		calls++
+		return pod, nil
+	})
+	kubeClient := newDryRunKubeClient(mock)
+	pod := &v1.Pod{ObjectMeta: apis.ObjectMeta{Name: "pod-1", Namespace: "default"}}
+
+	assert.NilError(t, kubeClient.Bind(pod, "node-1"))
+	created, err := kubeClient.Create(pod)
+	assert.NilError(t, err)
+	assert.Equal(t, created, pod)
+	assert.NilError(t, kubeClient.Delete(pod))
+	assert.NilError(t, kubeClient.DeleteWithGracePeriod(pod, 0))
+	assert.NilError(t, kubeClient.Evict(pod))
+	assert.Equal(t, calls, 0, "the pods should not be changed in the dry-run mode")
+	assert.Equal(t, kubeClient.GetClientSet(), mock.GetClientSet())
+}
+
+func TestDryRunTransport(t *testing.T) {
+	var lock sync.Mutex
+	methods := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		methods = append(methods, r.Method)
+		lock.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"kind":"Pod","apiVersion":"v1","metadata":{"name":"pod-1","namespace":"default"}}`)
+	}))
+	defer server.Close()
+	clientSet, err := kubernetes.NewForConfig(&rest.Config{
+		Host:          server.URL,
+		WrapTransport: wrapDryRunTransport,
+	})
+	assert.NilError(t, err)
+	pods := clientSet.CoreV1().Pods("default")
+
+	// the reads reach the api-server
+	pod, err := pods.Get("pod-1", apis.GetOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, pod.Name, "pod-1")
+
+	// the writes are answered with the object sent
+	pod.Labels = map[string]string{"label": "value"}
+	updated, err := pods.UpdateStatus(pod)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, updated.Labels, pod.Labels)
+	created, err := pods.Create(&v1.Pod{ObjectMeta: apis.ObjectMeta{Name: "pod-2", Namespace: "default"}})
+	assert.NilError(t, err)
+	assert.Equal(t, created.Name, "pod-2")
+	assert.NilError(t, pods.Delete("pod-1", &apis.DeleteOptions{}))
+	_, err = pods.Patch("pod-1", types.MergePatchType, []byte(`{"metadata":{"labels":null}}`))
+	assert.NilError(t, err)
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.DeepEqual(t, methods, []string{http.MethodGet})
+}
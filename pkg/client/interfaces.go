@@ -19,9 +19,13 @@
 package client
 
 import (
+	"time"
+
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
 )
 
 type KubeClient interface {
@@ -34,6 +38,12 @@ type KubeClient interface {
 	// Delete a pod from a host
 	Delete(pod *v1.Pod) error
 
+	// Delete a pod with the grace period, a negative grace period uses the termination grace period of the pod
+	DeleteWithGracePeriod(pod *v1.Pod, gracePeriod time.Duration) error
+
+	// Evict a pod through the eviction API, the eviction is refused when it violates a pod disruption budget
+	Evict(pod *v1.Pod) error
+
 	// minimal expose this, only informers factory needs it
 	GetClientSet() kubernetes.Interface
 
@@ -41,5 +51,8 @@ type KubeClient interface {
 }
 
 func NewKubeClient(kc string) KubeClient {
+	if conf.GetSchedulerConf().DryRun {
+		return newDryRunKubeClient(newSchedulerKubeClient(kc))
+	}
 	return newSchedulerKubeClient(kc)
 }
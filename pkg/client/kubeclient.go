@@ -19,8 +19,11 @@
 package client
 
 import (
+	"time"
+
 	"go.uber.org/zap"
 	v1 "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1beta1"
 	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -36,15 +39,14 @@ type SchedulerKubeClient struct {
 }
 
 func newSchedulerKubeClient(kc string) SchedulerKubeClient {
-	schedulerConf := conf.GetSchedulerConf()
 	// using kube config
 	if kc != "" {
 		config, err := clientcmd.BuildConfigFromFlags("", kc)
 		if err != nil {
 			log.Logger().Fatal("failed to create kubeClient configs", zap.Error(err))
 		}
-		config.QPS = float32(schedulerConf.KubeQPS)
-		config.Burst = schedulerConf.KubeBurst
+		setRateLimiter(config)
+		setDryRun(config)
 		configuredClient := kubernetes.NewForConfigOrDie(config)
 		return SchedulerKubeClient{
 			clientSet: configuredClient,
@@ -57,8 +59,8 @@ func newSchedulerKubeClient(kc string) SchedulerKubeClient {
 	if err != nil {
 		log.Logger().Fatal("failed to get InClusterConfig", zap.Error(err))
 	}
-	config.QPS = float32(schedulerConf.KubeQPS)
-	config.Burst = schedulerConf.KubeBurst
+	setRateLimiter(config)
+	setDryRun(config)
 	configuredClient, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		log.Logger().Fatal("failed to get Clientset", zap.Error(err))
@@ -69,6 +71,44 @@ func newSchedulerKubeClient(kc string) SchedulerKubeClient {
 	}
 }
 
+// setRateLimiter makes the client use the shared rate limiter, which is created with the configured QPS and burst.
+// The clients created from the same configs share the limits, e.g the application CRD client.
+func setRateLimiter(config *rest.Config) {
+	qps, burst := conf.GetSchedulerConf().GetKubeClientLimits()
+	config.QPS = qps
+	config.Burst = burst
+	if kubeRateLimiter == nil {
+		kubeRateLimiter = newTunableRateLimiter(qps, burst)
+	}
+	config.RateLimiter = kubeRateLimiter
+}
+
+// setDryRun keeps the clients created from the configs from changing the cluster in the dry-run mode,
+// e.g. the application CRD client.
+func setDryRun(config *rest.Config) {
+	if conf.GetSchedulerConf().DryRun {
+		config.WrapTransport = wrapDryRunTransport
+	}
+}
+
+// ListPods lists the pods in the given namespace, all namespaces if empty, in pages of at most pageSize pods.
+// Paging keeps the responses of the api-server small on large clusters, a pageSize of 0 lists all the pods at once.
+func ListPods(clientSet kubernetes.Interface, namespace string, pageSize int64) ([]v1.Pod, error) {
+	pods := make([]v1.Pod, 0)
+	options := apis.ListOptions{Limit: pageSize}
+	for {
+		podList, err := clientSet.CoreV1().Pods(namespace).List(options)
+		if err != nil {
+			return nil, err
+		}
+		pods = append(pods, podList.Items...)
+		if podList.Continue == "" {
+			return pods, nil
+		}
+		options.Continue = podList.Continue
+	}
+}
+
 func (nc SchedulerKubeClient) GetClientSet() kubernetes.Interface {
 	return nc.clientSet
 }
@@ -118,3 +158,40 @@ func (nc SchedulerKubeClient) Delete(pod *v1.Pod) error {
 	}
 	return nil
 }
+
+func (nc SchedulerKubeClient) DeleteWithGracePeriod(pod *v1.Pod, gracePeriod time.Duration) error {
+	options := &apis.DeleteOptions{}
+	if gracePeriod >= 0 {
+		gracefulSeconds := int64(gracePeriod.Seconds())
+		options.GracePeriodSeconds = &gracefulSeconds
+	}
+	if err := nc.clientSet.CoreV1().Pods(pod.Namespace).Delete(pod.Name, options); err != nil {
+		log.Logger().Warn("failed to delete pod",
+			zap.String("namespace", pod.Namespace),
+			zap.String("podName", pod.Name),
+			zap.Duration("gracePeriod", gracePeriod),
+			zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (nc SchedulerKubeClient) Evict(pod *v1.Pod) error {
+	gracefulSeconds := int64(3)
+	if err := nc.clientSet.CoreV1().Pods(pod.Namespace).Evict(&policy.Eviction{
+		ObjectMeta: apis.ObjectMeta{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+		},
+		DeleteOptions: &apis.DeleteOptions{
+			GracePeriodSeconds: &gracefulSeconds,
+		},
+	}); err != nil {
+		log.Logger().Warn("failed to evict pod",
+			zap.String("namespace", pod.Namespace),
+			zap.String("podName", pod.Name),
+			zap.Error(err))
+		return err
+	}
+	return nil
+}
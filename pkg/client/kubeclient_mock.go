@@ -19,6 +19,9 @@
 package client
 
 import (
+	"sync"
+	"time"
+
 	"go.uber.org/zap"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes"
@@ -32,8 +35,11 @@ import (
 type KubeClientMock struct {
 	bindFn    func(pod *v1.Pod, hostID string) error
 	deleteFn  func(pod *v1.Pod) error
+	evictFn   func(pod *v1.Pod) error
 	createFn  func(pod *v1.Pod) (*v1.Pod, error)
 	clientSet kubernetes.Interface
+	// the mocked functions are not thread safe, calls are serialized
+	lock sync.Mutex
 }
 
 func NewKubeClientMock() *KubeClientMock {
@@ -65,19 +71,46 @@ func (c *KubeClientMock) MockDeleteFn(dfn func(pod *v1.Pod) error) {
 	c.deleteFn = dfn
 }
 
+// MockEvictFn sets the evict function, the pods are evicted with the delete function if it is not set
+func (c *KubeClientMock) MockEvictFn(efn func(pod *v1.Pod) error) {
+	c.evictFn = efn
+}
+
 func (c *KubeClientMock) MockCreateFn(cfn func(pod *v1.Pod) (*v1.Pod, error)) {
 	c.createFn = cfn
 }
 
 func (c *KubeClientMock) Bind(pod *v1.Pod, hostID string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	return c.bindFn(pod, hostID)
 }
 
 func (c *KubeClientMock) Create(pod *v1.Pod) (*v1.Pod, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	return c.createFn(pod)
 }
 
 func (c *KubeClientMock) Delete(pod *v1.Pod) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.deleteFn(pod)
+}
+
+// DeleteWithGracePeriod deletes the pod with the delete function, the grace period is ignored
+func (c *KubeClientMock) DeleteWithGracePeriod(pod *v1.Pod, gracePeriod time.Duration) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.deleteFn(pod)
+}
+
+func (c *KubeClientMock) Evict(pod *v1.Pod) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.evictFn != nil {
+		return c.evictFn(pod)
+	}
 	return c.deleteFn(pod)
 }
 
@@ -0,0 +1,67 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"testing"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestListPods(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	for i := 0; i < 3; i++ {
+		pod := &v1.Pod{ObjectMeta: apis.ObjectMeta{
+			Name:      fmt.Sprintf("pod-%d", i),
+			Namespace: "default",
+		}}
+		_, err := clientSet.CoreV1().Pods(pod.Namespace).Create(pod)
+		assert.NilError(t, err)
+	}
+
+	pods, err := ListPods(clientSet, "", 2)
+	assert.NilError(t, err)
+	assert.Equal(t, len(pods), 3)
+	pods, err = ListPods(clientSet, "other", 0)
+	assert.NilError(t, err)
+	assert.Equal(t, len(pods), 0)
+}
+
+func TestTunableRateLimiter(t *testing.T) {
+	limiter := newTunableRateLimiter(10, 1)
+	assert.Equal(t, limiter.QPS(), float32(10))
+	assert.Assert(t, limiter.TryAccept())
+	assert.Assert(t, !limiter.TryAccept(), "the burst of 1 should be used up")
+
+	// the new limits come with a new bucket
+	limiter.setLimits(20, 2)
+	assert.Equal(t, limiter.QPS(), float32(20))
+	assert.Equal(t, limiter.getBurst(), 2)
+	assert.Assert(t, limiter.TryAccept())
+	assert.Assert(t, limiter.TryAccept())
+	assert.Assert(t, !limiter.TryAccept())
+
+	// same limits keep the bucket
+	limiter.setLimits(20, 2)
+	assert.Assert(t, !limiter.TryAccept())
+}
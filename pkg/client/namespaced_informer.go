@@ -0,0 +1,257 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	coreInformerV1 "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	listerV1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// namespacedPodInformer watches the pods of a list of namespaces only, the pods of the other namespaces
+// are not cached. Each namespace has its own informer, the informers are seen as a single pod informer:
+// the event handlers are added to all of them and the pods are listed from all of them.
+type namespacedPodInformer struct {
+	informer *namespacedInformer
+}
+
+func newNamespacedPodInformer(client kubernetes.Interface, namespaces []string, resync time.Duration,
+	tweak func(options *metav1.ListOptions)) coreInformerV1.PodInformer {
+	informer := &namespacedInformer{
+		informers: make(map[string]cache.SharedIndexInformer, len(namespaces)),
+	}
+	for _, namespace := range namespaces {
+		if _, ok := informer.informers[namespace]; ok {
+			continue
+		}
+		factory := informers.NewSharedInformerFactoryWithOptions(client, resync,
+			informers.WithNamespace(namespace), informers.WithTweakListOptions(tweak))
+		informer.informers[namespace] = factory.Core().V1().Pods().Informer()
+	}
+	return &namespacedPodInformer{informer: informer}
+}
+
+func (n *namespacedPodInformer) Informer() cache.SharedIndexInformer {
+	return n.informer
+}
+
+func (n *namespacedPodInformer) Lister() listerV1.PodLister {
+	return listerV1.NewPodLister(n.informer.GetIndexer())
+}
+
+// namespacedInformer is a shared informer made of the informers of several namespaces
+type namespacedInformer struct {
+	informers map[string]cache.SharedIndexInformer
+}
+
+func (n *namespacedInformer) AddEventHandler(handler cache.ResourceEventHandler) {
+	for _, informer := range n.informers {
+		informer.AddEventHandler(handler)
+	}
+}
+
+func (n *namespacedInformer) AddEventHandlerWithResyncPeriod(handler cache.ResourceEventHandler, resyncPeriod time.Duration) {
+	for _, informer := range n.informers {
+		informer.AddEventHandlerWithResyncPeriod(handler, resyncPeriod)
+	}
+}
+
+func (n *namespacedInformer) GetStore() cache.Store {
+	return n.GetIndexer()
+}
+
+func (n *namespacedInformer) GetController() cache.Controller {
+	return n
+}
+
+// Run runs the informers of all namespaces until the stop channel is closed
+func (n *namespacedInformer) Run(stopCh <-chan struct{}) {
+	for _, informer := range n.informers {
+		go informer.Run(stopCh)
+	}
+	<-stopCh
+}
+
+func (n *namespacedInformer) HasSynced() bool {
+	for _, informer := range n.informers {
+		if !informer.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+// LastSyncResourceVersion returns an empty version, the namespaces are not synced at the same version
+func (n *namespacedInformer) LastSyncResourceVersion() string {
+	return ""
+}
+
+func (n *namespacedInformer) AddIndexers(indexers cache.Indexers) error {
+	for _, informer := range n.informers {
+		if err := informer.AddIndexers(indexers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *namespacedInformer) GetIndexer() cache.Indexer {
+	return &namespacedIndexer{informers: n.informers}
+}
+
+// namespacedIndexer is a read-only view of the stores of the informers of the namespaces,
+// the stores are only updated by their own informer.
+type namespacedIndexer struct {
+	informers map[string]cache.SharedIndexInformer
+}
+
+func (n *namespacedIndexer) Add(obj interface{}) error {
+	return fmt.Errorf("the store of the watched namespaces is read-only")
+}
+
+func (n *namespacedIndexer) Update(obj interface{}) error {
+	return fmt.Errorf("the store of the watched namespaces is read-only")
+}
+
+func (n *namespacedIndexer) Delete(obj interface{}) error {
+	return fmt.Errorf("the store of the watched namespaces is read-only")
+}
+
+func (n *namespacedIndexer) Replace(list []interface{}, resourceVersion string) error {
+	return fmt.Errorf("the store of the watched namespaces is read-only")
+}
+
+func (n *namespacedIndexer) Resync() error {
+	return nil
+}
+
+func (n *namespacedIndexer) AddIndexers(newIndexers cache.Indexers) error {
+	return fmt.Errorf("the store of the watched namespaces is read-only")
+}
+
+func (n *namespacedIndexer) List() []interface{} {
+	items := make([]interface{}, 0)
+	for _, informer := range n.informers {
+		items = append(items, informer.GetIndexer().List()...)
+	}
+	return items
+}
+
+func (n *namespacedIndexer) ListKeys() []string {
+	keys := make([]string, 0)
+	for _, informer := range n.informers {
+		keys = append(keys, informer.GetIndexer().ListKeys()...)
+	}
+	return keys
+}
+
+func (n *namespacedIndexer) Get(obj interface{}) (interface{}, bool, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, false, err
+	}
+	if informer, ok := n.informers[accessor.GetNamespace()]; ok {
+		return informer.GetIndexer().Get(obj)
+	}
+	return nil, false, nil
+}
+
+func (n *namespacedIndexer) GetByKey(key string) (interface{}, bool, error) {
+	namespace, _, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil, false, err
+	}
+	if informer, ok := n.informers[namespace]; ok {
+		return informer.GetIndexer().GetByKey(key)
+	}
+	return nil, false, nil
+}
+
+func (n *namespacedIndexer) Index(indexName string, obj interface{}) ([]interface{}, error) {
+	items := make([]interface{}, 0)
+	for _, informer := range n.informers {
+		found, err := informer.GetIndexer().Index(indexName, obj)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, found...)
+	}
+	return items, nil
+}
+
+func (n *namespacedIndexer) IndexKeys(indexName, indexedValue string) ([]string, error) {
+	keys := make([]string, 0)
+	for _, informer := range n.informers {
+		found, err := informer.GetIndexer().IndexKeys(indexName, indexedValue)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, found...)
+	}
+	return keys, nil
+}
+
+func (n *namespacedIndexer) ListIndexFuncValues(indexName string) []string {
+	values := make(map[string]bool)
+	for _, informer := range n.informers {
+		for _, value := range informer.GetIndexer().ListIndexFuncValues(indexName) {
+			values[value] = true
+		}
+	}
+	result := make([]string, 0, len(values))
+	for value := range values {
+		result = append(result, value)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func (n *namespacedIndexer) ByIndex(indexName, indexedValue string) ([]interface{}, error) {
+	// the namespace index only needs the informer of the namespace
+	if indexName == cache.NamespaceIndex {
+		if informer, ok := n.informers[indexedValue]; ok {
+			return informer.GetIndexer().ByIndex(indexName, indexedValue)
+		}
+		return []interface{}{}, nil
+	}
+	items := make([]interface{}, 0)
+	for _, informer := range n.informers {
+		found, err := informer.GetIndexer().ByIndex(indexName, indexedValue)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, found...)
+	}
+	return items, nil
+}
+
+func (n *namespacedIndexer) GetIndexers() cache.Indexers {
+	for _, informer := range n.informers {
+		return informer.GetIndexer().GetIndexers()
+	}
+	return cache.Indexers{}
+}
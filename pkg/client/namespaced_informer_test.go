@@ -0,0 +1,112 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+)
+
+func TestNamespacedPodInformer(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	for _, namespace := range []string{"tenant-a", "tenant-b", "other"} {
+		pod := &v1.Pod{ObjectMeta: apis.ObjectMeta{Name: "pod-01", Namespace: namespace}}
+		_, err := clientSet.CoreV1().Pods(namespace).Create(pod)
+		assert.NilError(t, err)
+	}
+
+	informer := newNamespacedPodInformer(clientSet, []string{"tenant-a", "tenant-b", "tenant-a"}, 0,
+		func(options *apis.ListOptions) {})
+	added := make(chan string, 10)
+	informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			added <- obj.(*v1.Pod).Namespace
+		},
+	})
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go informer.Informer().Run(stopCh)
+	assert.NilError(t, utils.WaitForCondition(informer.Informer().HasSynced, 10*time.Millisecond, time.Second))
+
+	// only the pods of the watched namespaces are cached
+	pods, err := informer.Lister().List(labels.Everything())
+	assert.NilError(t, err)
+	assert.Equal(t, len(pods), 2)
+	pod, err := informer.Lister().Pods("tenant-b").Get("pod-01")
+	assert.NilError(t, err)
+	assert.Equal(t, pod.Namespace, "tenant-b")
+	_, err = informer.Lister().Pods("other").Get("pod-01")
+	assert.Assert(t, err != nil)
+	pods, err = informer.Lister().Pods("other").List(labels.Everything())
+	assert.NilError(t, err)
+	assert.Equal(t, len(pods), 0)
+
+	// the handlers get the events of all watched namespaces
+	namespaces := map[string]bool{<-added: true, <-added: true}
+	assert.DeepEqual(t, namespaces, map[string]bool{"tenant-a": true, "tenant-b": true})
+
+	// the store is read-only
+	assert.Assert(t, informer.Informer().GetStore().Add(pod) != nil)
+}
+
+func TestGetWatchedNamespaces(t *testing.T) {
+	kubeClient := NewKubeClientMock()
+	for name, value := range map[string]string{"tenant-a": "enabled", "tenant-b": "disabled", "tenant-c": "enabled"} {
+		namespace := &v1.Namespace{ObjectMeta: apis.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{"yunikorn": value},
+		}}
+		_, err := kubeClient.GetClientSet().CoreV1().Namespaces().Create(namespace)
+		assert.NilError(t, err)
+	}
+
+	// all namespaces are watched by default
+	configs := &conf.SchedulerConf{}
+	assert.Assert(t, getWatchedNamespaces(kubeClient, configs) == nil)
+
+	configs.WatchNamespaces = "tenant-d"
+	assert.DeepEqual(t, getWatchedNamespaces(kubeClient, configs), []string{"tenant-d"})
+
+	configs.WatchNamespaceSelector = "yunikorn=enabled"
+	namespaces := getWatchedNamespaces(kubeClient, configs)
+	assert.Equal(t, len(namespaces), 3)
+	clients := &Clients{WatchedNamespaces: namespaces}
+	assert.Assert(t, clients.IsNamespaceWatched("tenant-a"))
+	assert.Assert(t, !clients.IsNamespaceWatched("tenant-b"))
+	assert.Assert(t, clients.IsNamespaceWatched("tenant-c"))
+	assert.Assert(t, clients.IsNamespaceWatched("tenant-d"))
+
+	// no namespace matches the selector: no pod is watched
+	configs.WatchNamespaces = ""
+	configs.WatchNamespaceSelector = "yunikorn=unknown"
+	namespaces = getWatchedNamespaces(kubeClient, configs)
+	assert.Assert(t, namespaces != nil)
+	assert.Assert(t, !(&Clients{WatchedNamespaces: namespaces}).IsNamespaceWatched("tenant-a"))
+	assert.Assert(t, (&Clients{}).IsNamespaceWatched("tenant-a"))
+}
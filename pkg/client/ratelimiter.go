@@ -0,0 +1,96 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"sync"
+
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// the rate limiter shared by all the clients talking to the kubernetes master,
+// its limits can be changed at runtime with SetKubeClientRateLimit.
+var kubeRateLimiter *tunableRateLimiter
+
+// tunableRateLimiter is a token bucket rate limiter whose QPS and burst can be updated,
+// the callers waiting for a token when the limits change keep waiting on the old bucket.
+type tunableRateLimiter struct {
+	limiter flowcontrol.RateLimiter
+	burst   int
+	lock    sync.RWMutex
+}
+
+func newTunableRateLimiter(qps float32, burst int) *tunableRateLimiter {
+	return &tunableRateLimiter{
+		limiter: flowcontrol.NewTokenBucketRateLimiter(qps, burst),
+		burst:   burst,
+	}
+}
+
+func (r *tunableRateLimiter) current() flowcontrol.RateLimiter {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.limiter
+}
+
+func (r *tunableRateLimiter) setLimits(qps float32, burst int) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.limiter.QPS() == qps && r.burst == burst {
+		return
+	}
+	r.limiter.Stop()
+	r.limiter = flowcontrol.NewTokenBucketRateLimiter(qps, burst)
+	r.burst = burst
+}
+
+func (r *tunableRateLimiter) getBurst() int {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.burst
+}
+
+func (r *tunableRateLimiter) TryAccept() bool {
+	return r.current().TryAccept()
+}
+
+func (r *tunableRateLimiter) Accept() {
+	r.current().Accept()
+}
+
+func (r *tunableRateLimiter) Stop() {
+	r.current().Stop()
+}
+
+func (r *tunableRateLimiter) QPS() float32 {
+	return r.current().QPS()
+}
+
+func (r *tunableRateLimiter) Wait(ctx context.Context) error {
+	return r.current().Wait(ctx)
+}
+
+// SetKubeClientRateLimit updates the QPS and burst of the clients talking to the kubernetes master,
+// this is a no-op when the kube client is not created yet.
+func SetKubeClientRateLimit(qps float32, burst int) {
+	if kubeRateLimiter != nil {
+		kubeRateLimiter.setLimits(qps, burst)
+	}
+}
@@ -0,0 +1,295 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+const (
+	registerTimeout    = time.Minute
+	streamRetryInitial = 100 * time.Millisecond
+	streamRetryMax     = 10 * time.Second
+)
+
+var errStreamStopped = fmt.Errorf("the update stream to the core is stopped")
+
+// StreamingSchedulerAPI talks to a core running outside of the shim over the grpc scheduler interface.
+// The updates are not sent with one call each, they are split into messages of at most batchSize nodes,
+// apps and asks and sent over a single update stream that also carries the responses of the core back.
+// The messages wait in a bounded queue, Update returns once its messages are sent or failed to be sent.
+// A broken stream is re-opened with a backoff, the message that failed to be sent is not sent again,
+// its error is returned by Update instead. When a reconnect handler is set the core is assumed to have lost
+// the state of the shim, e.g. because it restarted: the queued messages are failed and the handler is called
+// before the re-opened stream is used, the state they carry is replayed by the handler.
+type StreamingSchedulerAPI struct {
+	conn        *grpc.ClientConn
+	client      si.SchedulerClient
+	callback    api.ResourceManagerCallback
+	reconnected func()
+	batchSize   int
+	queue       chan *streamMessage
+	streams     int32
+	ctx         context.Context
+	cancel      context.CancelFunc
+	stopOnce    sync.Once
+	lock        sync.Mutex
+}
+
+// streamMessage is a message queued on the update stream, the result of the send is returned on the channel
+type streamMessage struct {
+	request *si.UpdateRequest
+	result  chan error
+}
+
+// NewStreamingSchedulerAPI connects to the core at the given address, the connection is made lazily
+// and re-established by grpc when it is lost.
+func NewStreamingSchedulerAPI(address string, batchSize int, queueSize int, opts ...grpc.DialOption) (*StreamingSchedulerAPI, error) {
+	conn, err := grpc.Dial(address, append([]grpc.DialOption{grpc.WithInsecure()}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &StreamingSchedulerAPI{
+		conn:      conn,
+		client:    si.NewSchedulerClient(conn),
+		batchSize: batchSize,
+		queue:     make(chan *streamMessage, queueSize),
+		ctx:       ctx,
+		cancel:    cancel,
+	}, nil
+}
+
+// RegisterResourceManager registers the shim with the core and opens the update stream once registered
+func (s *StreamingSchedulerAPI) RegisterResourceManager(request *si.RegisterResourceManagerRequest,
+	callback api.ResourceManagerCallback) (*si.RegisterResourceManagerResponse, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, registerTimeout)
+	defer cancel()
+	response, err := s.client.RegisterResourceManager(ctx, request, grpc.WaitForReady(true))
+	if err != nil {
+		return nil, err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.callback == nil {
+		s.callback = callback
+		go s.run()
+	}
+	return response, nil
+}
+
+// SetReconnectHandler sets the handler called when the update stream is re-opened after it broke,
+// the handler registers the shim again and replays its state to the core.
+func (s *StreamingSchedulerAPI) SetReconnectHandler(handler func()) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.reconnected = handler
+}
+
+// Update queues the request to be sent to the core in messages of at most batchSize objects and waits
+// until they are sent. The first message that failed to be sent, e.g. because the stream broke, fails the update.
+func (s *StreamingSchedulerAPI) Update(request *si.UpdateRequest) error {
+	s.lock.Lock()
+	registered := s.callback != nil
+	s.lock.Unlock()
+	if !registered {
+		return fmt.Errorf("resource manager %s is not registered with the core", request.RmID)
+	}
+	msgs := splitUpdateRequest(request, s.batchSize)
+	results := make([]chan error, 0, len(msgs))
+	for _, msg := range msgs {
+		result := make(chan error, 1)
+		select {
+		case s.queue <- &streamMessage{request: msg, result: result}:
+			results = append(results, result)
+		case <-s.ctx.Done():
+			return errStreamStopped
+		}
+	}
+	for _, result := range results {
+		select {
+		case err := <-result:
+			if err != nil {
+				return err
+			}
+		case <-s.ctx.Done():
+			return errStreamStopped
+		}
+	}
+	return nil
+}
+
+// ReloadConfiguration does nothing, the remote core watches its own configuration and reloads it
+func (s *StreamingSchedulerAPI) ReloadConfiguration(clusterID string) error {
+	log.Logger().Debug("the configuration is reloaded by the remote core itself",
+		zap.String("clusterID", clusterID))
+	return nil
+}
+
+// Stop closes the update stream and the connection to the core, the updates waiting to be sent fail
+func (s *StreamingSchedulerAPI) Stop() {
+	s.stopOnce.Do(func() {
+		s.cancel()
+		if err := s.conn.Close(); err != nil {
+			log.Logger().Warn("failed to close the connection to the core", zap.Error(err))
+		}
+	})
+}
+
+// run keeps an update stream open until the api is stopped
+func (s *StreamingSchedulerAPI) run() {
+	retry := streamRetryInitial
+	for {
+		stream, err := s.client.Update(s.ctx, grpc.WaitForReady(true))
+		if err == nil {
+			if atomic.AddInt32(&s.streams, 1) > 1 {
+				s.reconnect()
+			}
+			var sent bool
+			sent, err = s.serve(stream)
+			if sent {
+				retry = streamRetryInitial
+			}
+		}
+		if s.ctx.Err() != nil {
+			return
+		}
+		log.Logger().Warn("the update stream to the core is broken, re-opening it",
+			zap.Duration("retryIn", retry),
+			zap.Error(err))
+		select {
+		case <-time.After(retry):
+		case <-s.ctx.Done():
+			return
+		}
+		if retry *= 2; retry > streamRetryMax {
+			retry = streamRetryMax
+		}
+	}
+}
+
+// reconnect fails the queued messages and calls the reconnect handler when it is set, the state they carry
+// is replayed by the handler. Without a handler the queued messages are sent on the re-opened stream.
+func (s *StreamingSchedulerAPI) reconnect() {
+	s.lock.Lock()
+	handler := s.reconnected
+	s.lock.Unlock()
+	if handler == nil {
+		return
+	}
+	dropped := 0
+	for drained := false; !drained; {
+		select {
+		case msg := <-s.queue:
+			msg.result <- fmt.Errorf("the update stream to the core was re-opened, the state of the shim is replayed")
+			dropped++
+		default:
+			drained = true
+		}
+	}
+	log.Logger().Info("the update stream to the core is re-opened, registering again",
+		zap.Int("droppedMessages", dropped))
+	handler()
+}
+
+// serve sends the queued messages on the stream and hands the responses to the callback until the stream breaks,
+// it returns whether anything was sent at all. The message that failed to be sent gets the error of the stream.
+func (s *StreamingSchedulerAPI) serve(stream si.Scheduler_UpdateClient) (bool, error) {
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			response, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			if err = s.callback.RecvUpdateResponse(response); err != nil {
+				log.Logger().Error("failed to handle the update response of the core", zap.Error(err))
+			}
+		}
+	}()
+	sent := false
+	for {
+		var msg *streamMessage
+		select {
+		case msg = <-s.queue:
+		case err := <-recvErr:
+			return sent, err
+		case <-s.ctx.Done():
+			return sent, s.ctx.Err()
+		}
+		if err := stream.Send(msg.request); err != nil {
+			msg.result <- fmt.Errorf("the update stream to the core is broken: %v", err)
+			return sent, err
+		}
+		msg.result <- nil
+		sent = true
+	}
+}
+
+// splitUpdateRequest splits an update into messages of at most batchSize nodes, apps and asks.
+// The nodes go before the apps and the apps before the asks, so that the core knows the app of an ask,
+// the releases, the removed apps and the utilization reports follow everything else in the last message.
+func splitUpdateRequest(request *si.UpdateRequest, batchSize int) []*si.UpdateRequest {
+	if batchSize <= 0 {
+		return []*si.UpdateRequest{request}
+	}
+	msgs := make([]*si.UpdateRequest, 0)
+	current := &si.UpdateRequest{RmID: request.RmID}
+	count := 0
+	reserve := func() {
+		if count == batchSize {
+			msgs = append(msgs, current)
+			current = &si.UpdateRequest{RmID: request.RmID}
+			count = 0
+		}
+		count++
+	}
+	for _, node := range request.NewSchedulableNodes {
+		reserve()
+		current.NewSchedulableNodes = append(current.NewSchedulableNodes, node)
+	}
+	for _, node := range request.UpdatedNodes {
+		reserve()
+		current.UpdatedNodes = append(current.UpdatedNodes, node)
+	}
+	for _, app := range request.NewApplications {
+		reserve()
+		current.NewApplications = append(current.NewApplications, app)
+	}
+	for _, ask := range request.Asks {
+		reserve()
+		current.Asks = append(current.Asks, ask)
+	}
+	current.Releases = request.Releases
+	current.RemoveApplications = request.RemoveApplications
+	current.UtilizationReports = request.UtilizationReports
+	return append(msgs, current)
+}
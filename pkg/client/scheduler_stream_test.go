@@ -0,0 +1,258 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+// fakeCore records the messages received on the update stream and answers each of them,
+// it breaks the first stream after the given number of messages.
+type fakeCore struct {
+	received      []*si.UpdateRequest
+	registrations int32
+	breakAfter    int
+	broken        bool
+	lock          sync.Mutex
+}
+
+func (f *fakeCore) RegisterResourceManager(ctx context.Context,
+	request *si.RegisterResourceManagerRequest) (*si.RegisterResourceManagerResponse, error) {
+	atomic.AddInt32(&f.registrations, 1)
+	return &si.RegisterResourceManagerResponse{}, nil
+}
+
+func (f *fakeCore) Update(stream si.Scheduler_UpdateServer) error {
+	for {
+		request, err := stream.Recv()
+		if err != nil {
+			return nil
+		}
+		f.lock.Lock()
+		f.received = append(f.received, request)
+		breakStream := !f.broken && f.breakAfter > 0 && len(f.received) == f.breakAfter
+		if breakStream {
+			f.broken = true
+		}
+		f.lock.Unlock()
+		if err = stream.Send(&si.UpdateResponse{}); err != nil {
+			return err
+		}
+		if breakStream {
+			return fmt.Errorf("stream broken by the test")
+		}
+	}
+}
+
+func (f *fakeCore) getReceived() []*si.UpdateRequest {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.received
+}
+
+type countingCallback struct {
+	responses int32
+}
+
+func (c *countingCallback) RecvUpdateResponse(response *si.UpdateResponse) error {
+	atomic.AddInt32(&c.responses, 1)
+	return nil
+}
+
+func (c *countingCallback) getResponses() int32 {
+	return atomic.LoadInt32(&c.responses)
+}
+
+func startFakeCore(t *testing.T, core *fakeCore, batchSize int) (*StreamingSchedulerAPI, *countingCallback, func()) {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	si.RegisterSchedulerServer(server, core)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	dialer := grpc.WithContextDialer(func(ctx context.Context, address string) (net.Conn, error) {
+		return listener.Dial()
+	})
+	sa, err := NewStreamingSchedulerAPI("bufnet", batchSize, 2, dialer)
+	assert.NilError(t, err)
+	callback := &countingCallback{}
+	_, err = sa.RegisterResourceManager(&si.RegisterResourceManagerRequest{RmID: "rm-1"}, callback)
+	assert.NilError(t, err)
+	return sa, callback, func() {
+		sa.Stop()
+		server.Stop()
+	}
+}
+
+func newAsks(count int) []*si.AllocationAsk {
+	asks := make([]*si.AllocationAsk, count)
+	for i := range asks {
+		asks[i] = &si.AllocationAsk{AllocationKey: fmt.Sprintf("ask-%d", i), ApplicationID: "app-1"}
+	}
+	return asks
+}
+
+func TestSplitUpdateRequest(t *testing.T) {
+	request := &si.UpdateRequest{
+		RmID:                "rm-1",
+		NewSchedulableNodes: []*si.NewNodeInfo{{NodeID: "node-1"}, {NodeID: "node-2"}, {NodeID: "node-3"}},
+		NewApplications:     []*si.AddApplicationRequest{{ApplicationID: "app-1"}, {ApplicationID: "app-2"}},
+		Asks:                newAsks(4),
+		Releases:            &si.AllocationReleasesRequest{},
+		RemoveApplications:  []*si.RemoveApplicationRequest{{ApplicationID: "app-0"}},
+	}
+	msgs := splitUpdateRequest(request, 4)
+	assert.Equal(t, len(msgs), 3)
+	assert.Equal(t, len(msgs[0].NewSchedulableNodes), 3)
+	assert.Equal(t, len(msgs[0].NewApplications), 1)
+	assert.Equal(t, len(msgs[1].NewApplications), 1)
+	assert.Equal(t, len(msgs[1].Asks), 3)
+	assert.Equal(t, len(msgs[2].Asks), 1)
+	for i, msg := range msgs {
+		assert.Equal(t, msg.RmID, "rm-1")
+		// the releases and the removed apps only go with the last message
+		assert.Equal(t, msg.Releases != nil, i == 2)
+		assert.Equal(t, len(msg.RemoveApplications) == 1, i == 2)
+	}
+
+	// no batch size sends the request as is
+	msgs = splitUpdateRequest(request, 0)
+	assert.Equal(t, len(msgs), 1)
+	assert.Equal(t, msgs[0], request)
+}
+
+func TestStreamingUpdate(t *testing.T) {
+	core := &fakeCore{}
+	sa, callback, stop := startFakeCore(t, core, 2)
+	defer stop()
+
+	// more messages than the queue holds, the update waits for the stream to drain it
+	err := sa.Update(&si.UpdateRequest{RmID: "rm-1", Asks: newAsks(9)})
+	assert.NilError(t, err)
+	err = utils.WaitForCondition(func() bool {
+		return callback.getResponses() == 5
+	}, 10*time.Millisecond, 5*time.Second)
+	assert.NilError(t, err)
+	asks := 0
+	for _, msg := range core.getReceived() {
+		assert.Assert(t, len(msg.Asks) <= 2)
+		asks += len(msg.Asks)
+	}
+	assert.Equal(t, asks, 9)
+}
+
+func TestStreamingReconnect(t *testing.T) {
+	core := &fakeCore{breakAfter: 1}
+	sa, callback, stop := startFakeCore(t, core, 0)
+	defer stop()
+
+	err := sa.Update(&si.UpdateRequest{RmID: "rm-1", Asks: newAsks(1)})
+	assert.NilError(t, err)
+	// the core broke the first stream, the shim opens a new one
+	err = utils.WaitForCondition(func() bool {
+		return atomic.LoadInt32(&sa.streams) == 2
+	}, 10*time.Millisecond, 5*time.Second)
+	assert.NilError(t, err)
+
+	err = sa.Update(&si.UpdateRequest{RmID: "rm-1", Asks: newAsks(1)})
+	assert.NilError(t, err)
+	err = utils.WaitForCondition(func() bool {
+		return len(core.getReceived()) == 2 && callback.getResponses() == 2
+	}, 10*time.Millisecond, 5*time.Second)
+	assert.NilError(t, err)
+}
+
+func TestStreamingReconnectHandler(t *testing.T) {
+	core := &fakeCore{breakAfter: 1}
+	sa, callback, stop := startFakeCore(t, core, 0)
+	defer stop()
+	sa.SetReconnectHandler(func() {
+		_, err := sa.RegisterResourceManager(&si.RegisterResourceManagerRequest{RmID: "rm-1"}, callback)
+		assert.NilError(t, err)
+		go func() {
+			assert.NilError(t, sa.Update(&si.UpdateRequest{RmID: "rm-1", NewApplications: []*si.AddApplicationRequest{{ApplicationID: "app-1"}}}))
+		}()
+	})
+
+	// the core broke the first stream, the shim registers again and replays its state on the new stream
+	err := sa.Update(&si.UpdateRequest{RmID: "rm-1", Asks: newAsks(1)})
+	assert.NilError(t, err)
+	err = utils.WaitForCondition(func() bool {
+		return len(core.getReceived()) == 2
+	}, 10*time.Millisecond, 5*time.Second)
+	assert.NilError(t, err)
+	assert.Equal(t, atomic.LoadInt32(&core.registrations), int32(2))
+	assert.Equal(t, core.getReceived()[1].NewApplications[0].ApplicationID, "app-1")
+}
+
+func TestReconnectFailsQueue(t *testing.T) {
+	sa, err := NewStreamingSchedulerAPI("bufnet", 1, 2)
+	assert.NilError(t, err)
+	defer sa.Stop()
+	queued := []*streamMessage{
+		{request: &si.UpdateRequest{RmID: "rm-1"}, result: make(chan error, 1)},
+		{request: &si.UpdateRequest{RmID: "rm-1"}, result: make(chan error, 1)},
+	}
+
+	// without a handler the queued messages are sent on the re-opened stream
+	sa.queue <- queued[0]
+	sa.reconnect()
+	assert.Equal(t, len(sa.queue), 1)
+
+	called := false
+	sa.SetReconnectHandler(func() {
+		called = true
+	})
+	sa.queue <- queued[1]
+	sa.reconnect()
+	assert.Assert(t, called)
+	assert.Equal(t, len(sa.queue), 0)
+	for _, msg := range queued {
+		assert.ErrorContains(t, <-msg.result, "re-opened")
+	}
+}
+
+func TestReloadConfiguration(t *testing.T) {
+	sa, err := NewStreamingSchedulerAPI("bufnet", 1, 1)
+	assert.NilError(t, err)
+	defer sa.Stop()
+	// the remote core reloads its own configuration
+	assert.NilError(t, sa.ReloadConfiguration("cluster-1"))
+}
+
+func TestUpdateNotRegistered(t *testing.T) {
+	sa, err := NewStreamingSchedulerAPI("bufnet", 1, 1)
+	assert.NilError(t, err)
+	defer sa.Stop()
+	err = sa.Update(&si.UpdateRequest{RmID: "rm-1"})
+	assert.ErrorContains(t, err, "not registered")
+}
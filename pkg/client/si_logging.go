@@ -0,0 +1,188 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/webservice/dao"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+// value of the redacted fields in the logged messages
+const redacted = "<redacted>"
+
+// the json fields of the scheduler interface messages holding the user names, groups and tags
+var redactedFields = map[string]bool{
+	"user":           true,
+	"groups":         true,
+	"tags":           true,
+	"allocationTags": true,
+}
+
+// siLogger logs the scheduler interface messages, the settings can be changed at runtime.
+// A message is logged each time the sampled share of the messages adds up to a whole message,
+// e.g. every fourth message with a sample rate of 0.25.
+type siLogger struct {
+	settings dao.SILogging
+	credit   float64
+	sync.Mutex
+}
+
+var wireLogger = &siLogger{
+	settings: dao.SILogging{SampleRate: 1, Redact: true},
+}
+
+// GetSILogging returns the current settings of the scheduler interface logging
+func GetSILogging() dao.SILogging {
+	wireLogger.Lock()
+	defer wireLogger.Unlock()
+	return wireLogger.settings
+}
+
+// SetSILogging changes the settings of the scheduler interface logging, they apply to the next message
+func SetSILogging(settings dao.SILogging) error {
+	if settings.SampleRate <= 0 || settings.SampleRate > 1 {
+		return fmt.Errorf("sample rate %v is not in (0, 1]", settings.SampleRate)
+	}
+	wireLogger.Lock()
+	defer wireLogger.Unlock()
+	wireLogger.settings = settings
+	wireLogger.credit = 0
+	log.Logger().Info("scheduler interface logging updated",
+		zap.Bool("enabled", settings.Enabled),
+		zap.Float64("sampleRate", settings.SampleRate),
+		zap.Bool("redact", settings.Redact))
+	return nil
+}
+
+// sample returns true if the next message is logged and if its user names, groups and tags are redacted
+func (l *siLogger) sample() (bool, bool) {
+	l.Lock()
+	defer l.Unlock()
+	if !l.settings.Enabled {
+		return false, false
+	}
+	l.credit += l.settings.SampleRate
+	if l.credit < 1 {
+		return false, false
+	}
+	l.credit--
+	return true, l.settings.Redact
+}
+
+// log logs a message in json when it is sampled
+func (l *siLogger) log(direction, rmID string, message interface{}) {
+	logged, redact := l.sample()
+	if !logged {
+		return
+	}
+	data, err := encodeSIMessage(message, redact)
+	if err != nil {
+		log.Logger().Warn("failed to encode scheduler interface message", zap.Error(err))
+		return
+	}
+	log.Logger().Info("scheduler interface message",
+		zap.String("direction", direction),
+		zap.String("rmID", rmID),
+		zap.String("message", data))
+}
+
+// encodeSIMessage encodes a message in json, the user names, groups and tags are replaced when redacted
+func encodeSIMessage(message interface{}, redact bool) (string, error) {
+	data, err := json.Marshal(message)
+	if err != nil || !redact {
+		return string(data), err
+	}
+	var fields interface{}
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return "", err
+	}
+	// the marker of the redacted fields is not escaped
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err = encoder.Encode(redactFields(fields)); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// redactFields replaces the values of the redacted fields at any depth of a decoded json message
+func redactFields(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if redactedFields[key] {
+				v[key] = redacted
+				continue
+			}
+			v[key] = redactFields(field)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactFields(item)
+		}
+	}
+	return value
+}
+
+// LoggingSchedulerAPI logs the requests sent to the core and the responses of the core,
+// following the settings of the scheduler interface logging. Nothing is logged while it is disabled.
+type LoggingSchedulerAPI struct {
+	api.SchedulerAPI
+}
+
+// NewLoggingSchedulerAPI wraps the api of the core, the logging starts with the given settings
+func NewLoggingSchedulerAPI(scheduler api.SchedulerAPI, settings dao.SILogging) *LoggingSchedulerAPI {
+	if err := SetSILogging(settings); err != nil {
+		log.Logger().Warn("invalid scheduler interface logging settings", zap.Error(err))
+	}
+	return &LoggingSchedulerAPI{SchedulerAPI: scheduler}
+}
+
+func (l *LoggingSchedulerAPI) RegisterResourceManager(request *si.RegisterResourceManagerRequest,
+	callback api.ResourceManagerCallback) (*si.RegisterResourceManagerResponse, error) {
+	wireLogger.log("request", request.RmID, request)
+	return l.SchedulerAPI.RegisterResourceManager(request, &loggingCallback{callback: callback, rmID: request.RmID})
+}
+
+func (l *LoggingSchedulerAPI) Update(request *si.UpdateRequest) error {
+	wireLogger.log("request", request.RmID, request)
+	return l.SchedulerAPI.Update(request)
+}
+
+// loggingCallback logs the responses of the core before they are handled by the shim
+type loggingCallback struct {
+	callback api.ResourceManagerCallback
+	rmID     string
+}
+
+func (c *loggingCallback) RecvUpdateResponse(response *si.UpdateResponse) error {
+	wireLogger.log("response", c.rmID, response)
+	return c.callback.RecvUpdateResponse(response)
+}
@@ -0,0 +1,120 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/test"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/webservice/dao"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+type recordingCallback struct {
+	responses int
+}
+
+func (c *recordingCallback) RecvUpdateResponse(response *si.UpdateResponse) error {
+	c.responses++
+	return nil
+}
+
+func TestSILoggingSettings(t *testing.T) {
+	defer func() {
+		assert.NilError(t, SetSILogging(dao.SILogging{SampleRate: 1, Redact: true}))
+	}()
+	assert.DeepEqual(t, GetSILogging(), dao.SILogging{SampleRate: 1, Redact: true})
+	assert.ErrorContains(t, SetSILogging(dao.SILogging{Enabled: true}), "sample rate")
+	assert.ErrorContains(t, SetSILogging(dao.SILogging{Enabled: true, SampleRate: 1.5}), "sample rate")
+	assert.NilError(t, SetSILogging(dao.SILogging{Enabled: true, SampleRate: 0.25}))
+	assert.DeepEqual(t, GetSILogging(), dao.SILogging{Enabled: true, SampleRate: 0.25})
+
+	// every fourth message is logged
+	logged := 0
+	for i := 0; i < 12; i++ {
+		if ok, redact := wireLogger.sample(); ok {
+			assert.Assert(t, !redact)
+			logged++
+		}
+	}
+	assert.Equal(t, logged, 3)
+
+	// nothing is logged once disabled
+	assert.NilError(t, SetSILogging(dao.SILogging{SampleRate: 1}))
+	ok, _ := wireLogger.sample()
+	assert.Assert(t, !ok)
+}
+
+func TestEncodeSIMessage(t *testing.T) {
+	request := &si.UpdateRequest{
+		NewApplications: []*si.AddApplicationRequest{{
+			ApplicationID: "app-1",
+			QueueName:     "root.a",
+			Ugi:           &si.UserGroupInformation{User: "alice", Groups: []string{"dev"}},
+			Tags:          map[string]string{"namespace": "secret-ns"},
+		}},
+		Asks: []*si.AllocationAsk{{
+			AllocationKey: "ask-1",
+			Tags:          map[string]string{"kubernetes.io/meta/podName": "pod-1"},
+		}},
+		RmID: "rm-1",
+	}
+	plain, err := encodeSIMessage(request, false)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(plain, "alice"), plain)
+	assert.Assert(t, strings.Contains(plain, "secret-ns"), plain)
+
+	redactedMessage, err := encodeSIMessage(request, true)
+	assert.NilError(t, err)
+	for _, hidden := range []string{"alice", "dev", "secret-ns", "pod-1"} {
+		assert.Assert(t, !strings.Contains(redactedMessage, hidden), redactedMessage)
+	}
+	for _, kept := range []string{"app-1", "root.a", "ask-1", "rm-1", redacted} {
+		assert.Assert(t, strings.Contains(redactedMessage, kept), redactedMessage)
+	}
+}
+
+func TestLoggingSchedulerAPI(t *testing.T) {
+	defer func() {
+		assert.NilError(t, SetSILogging(dao.SILogging{SampleRate: 1, Redact: true}))
+	}()
+	mock := test.NewSchedulerAPIMock()
+	var registered api.ResourceManagerCallback
+	mock.RegisterFunction(func(request *si.RegisterResourceManagerRequest,
+		callback api.ResourceManagerCallback) (*si.RegisterResourceManagerResponse, error) {
+		registered = callback
+		return &si.RegisterResourceManagerResponse{}, nil
+	})
+	logging := NewLoggingSchedulerAPI(mock, dao.SILogging{Enabled: true, SampleRate: 1, Redact: true})
+	assert.Assert(t, GetSILogging().Enabled)
+
+	// the requests and the responses are passed through
+	callback := &recordingCallback{}
+	_, err := logging.RegisterResourceManager(&si.RegisterResourceManagerRequest{RmID: "rm-1"}, callback)
+	assert.NilError(t, err)
+	assert.NilError(t, logging.Update(&si.UpdateRequest{RmID: "rm-1"}))
+	assert.Equal(t, mock.GetRegisterCount(), int32(1))
+	assert.Equal(t, mock.GetUpdateCount(), int32(1))
+	assert.NilError(t, registered.RecvUpdateResponse(&si.UpdateResponse{}))
+	assert.Equal(t, callback.responses, 1)
+}
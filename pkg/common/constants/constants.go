@@ -33,10 +33,30 @@ const DefaultPartition = "default"
 const AppTagNamespace = "namespace"
 const AppTagNamespaceResourceQuota = "namespace.resourcequota"
 const AppTagNamespaceParentQueue = "namespace.parentqueue"
+const AppTagNamespaceStrictFIFO = "namespace.strictfifo"
+const AppTagNamespacePriorityAging = "namespace.priorityaging"
+const AppTagNamespaceLabelPrefix = "namespace.label."
+const AppTagNamespaceAnnotationPrefix = "namespace.annotation."
+
+// the queue of an app resubmitted after its queue was not found, read by a tag placement rule creating the queue
+const AppTagRequestedQueue = "requestedQueue"
 const DefaultAppNamespace = "default"
 const DefaultUserLabel = "yunikorn.apache.org/username"
 const DefaultUser = "nobody"
 
+// the user and the groups of the requester of a pod, injected by the admission controller
+// from the authenticated request, the value is a JSON encoded {"user": "", "groups": []}
+const AnnotationUserInfo = "yunikorn.apache.org/user.info"
+const AnnotationParentQueue = "yunikorn.apache.org/parentqueue"
+const AnnotationStrictFIFO = "yunikorn.apache.org/strict-fifo"
+
+// Priority aging, the priority of the asks of an app waiting for an allocation is raised by the step every interval
+// up to the max, e.g. "interval=10m step=10 max=100", the parameters are separated by SchedulingPolicyParamDelimiter
+const AnnotationPriorityAging = "yunikorn.apache.org/priority-aging"
+const PriorityAgingIntervalParam = "interval"
+const PriorityAgingStepParam = "step"
+const PriorityAgingMaxParam = "max"
+
 // Resource
 const Memory = "memory"
 const CPU = "vcore"
@@ -48,6 +68,7 @@ const SparkLabelRoleDriver = "driver"
 
 // Configuration
 const DefaultConfigMapName = "yunikorn-configs"
+const AppCheckpointConfigMapName = "yunikorn-app-checkpoint"
 const SchedulerName = "yunikorn"
 
 // Application crd
@@ -58,9 +79,63 @@ const PlaceholderContainerImage = "k8s.gcr.io/pause"
 const PlaceholderContainerName = "pause"
 const PlaceholderPodRestartPolicy = "Never"
 const LabelPlaceholderFlag = "placeholder"
+const LabelTaskGroupName = "yunikorn.apache.org/task-group-name"
+const LabelTaskGroupHash = "yunikorn.apache.org/task-group-hash"
 const AnnotationPlaceholderFlag = "yunikorn.apache.org/placeholder"
 const AnnotationTaskGroupName = "yunikorn.apache.org/task-group-name"
+const AnnotationTaskGroupIndex = "yunikorn.apache.org/task-group-index"
 const AnnotationTaskGroups = "yunikorn.apache.org/task-groups"
+const AnnotationNonGang = "yunikorn.apache.org/non-gang"
+
+// the slot of a placeholder of a task group with a max per node, placeholders of the same slot never share a node
+const LabelPlaceholderSlot = "yunikorn.apache.org/placeholder-slot"
+
+// the names of the init containers of a pod that keep running alongside its containers, separated by commas
+const AnnotationSidecarContainers = "yunikorn.apache.org/sidecar-init-containers"
 const AnnotationSchedulingPolicyParam = "yunikorn.apache.org/schedulingPolicyParameters"
 const SchedulingPolicyTimeoutParam = "placeholderTimeoutInSeconds"
+const SchedulingPolicyTaskOrderingParam = "taskOrderingPolicy"
+const SchedulingPolicyProgressTimeoutParam = "placeholderProgressTimeoutInSeconds"
+const SchedulingPolicyStyleParam = "gangSchedulingStyle"
+const SchedulingPolicyStyleSoft = "Soft"
+const SchedulingPolicyStyleHard = "Hard"
 const SchedulingPolicyParamDelimiter = " "
+const TagKeyTaskGroupName = "taskGroupName"
+const TagKeyTaskGroupIndex = "taskGroupIndex"
+const TagKeyTaskGroupTotal = "taskGroupTotal"
+
+// Scheduling gates
+const AnnotationSchedulingGates = "yunikorn.apache.org/scheduling-gates"
+const SchedulingGatesDelimiter = ","
+const SchedulingGateQueueAdmission = "yunikorn.apache.org/queue-admission"
+
+// Execution hints
+const AnnotationPreferredNodes = "yunikorn.apache.org/preferred-nodes"
+const PreferredNodesDelimiter = ","
+const TagKeyPreferredNodes = "preferredNodes"
+
+// Multi-queue apps, a task scheduled in another queue than the queue of its app
+const AnnotationTaskQueue = "yunikorn.apache.org/task-queue"
+const TagKeyTaskQueue = "queue"
+
+// Throttling, the maximum number of tasks of the app being scheduled at a time
+const AnnotationMaxParallelTasks = "yunikorn.apache.org/max-parallel-tasks"
+
+// Gang throttling, the maximum number of apps of the queue reserving at the same time, a configured limit wins
+const AnnotationMaxReservingApps = "yunikorn.apache.org/max-reserving-apps"
+
+// Downscaling, the cost of deleting a pod set by its controller, the cheapest pods are deleted first
+const AnnotationPodDeletionCost = "controller.kubernetes.io/pod-deletion-cost"
+
+// Job tracking, the finalizer the Job controller removes once it has accounted for the terminated pod
+const JobTrackingFinalizer = "batch.kubernetes.io/job-tracking"
+
+// Cluster autoscaler, the annotations tying a pod to the ProvisioningRequest whose capacity it consumes,
+// the legacy annotation is read by the autoscaler versions before the v1 API
+const AnnotationConsumeProvisioningRequest = "autoscaling.x-k8s.io/consume-provisioning-request"
+const AnnotationLegacyConsumeProvisioningRequest = "cluster-autoscaler.kubernetes.io/consume-provisioning-request"
+
+// Federation
+const AnnotationClusterID = "yunikorn.apache.org/cluster-id"
+const AnnotationPartition = "yunikorn.apache.org/partition"
+const FederatedClusterIDsDelimiter = ","
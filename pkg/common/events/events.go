@@ -19,6 +19,7 @@
 package events
 
 const EnterState = "enter_state"
+const LeaveState = "leave_state"
 
 //----------------------------------------------
 // General event interface
@@ -46,7 +47,11 @@ const (
 	KilledApplication    ApplicationEventType = "KilledApplication"
 	ReleaseAppAllocation ApplicationEventType = "ReleaseAppAllocation"
 	ReleaseAppAllocationAsk ApplicationEventType = "ReleaseAppAllocationAsk"
+	ReleaseTaskGroup        ApplicationEventType = "ReleaseTaskGroup"
+	ResumeApplication       ApplicationEventType = "ResumeApplication"
+	ReclaimApplication      ApplicationEventType = "ReclaimApplication"
 	AppStateChange       ApplicationEventType = "ApplicationStateChange"
+	AppTaskGroupsChange  ApplicationEventType = "ApplicationTaskGroupsChange"
 )
 
 type ApplicationEvent interface {
@@ -76,15 +81,23 @@ type ApplicationStatusEvent interface {
 type TaskEventType string
 
 const (
-	InitTask      TaskEventType = "InitTask"
-	SubmitTask    TaskEventType = "SubmitTask"
-	TaskAllocated TaskEventType = "TaskAllocated"
-	TaskRejected  TaskEventType = "TaskRejected"
-	TaskBound     TaskEventType = "TaskBound"
-	CompleteTask  TaskEventType = "CompleteTask"
-	TaskFail      TaskEventType = "TaskFail"
-	KillTask      TaskEventType = "KillTask"
-	TaskKilled    TaskEventType = "TaskKilled"
+	InitTask              TaskEventType = "InitTask"
+	SubmitTask            TaskEventType = "SubmitTask"
+	TaskAllocated         TaskEventType = "TaskAllocated"
+	TaskRejected          TaskEventType = "TaskRejected"
+	TaskBound             TaskEventType = "TaskBound"
+	TaskRunning           TaskEventType = "TaskRunning"
+	TaskSucceeded         TaskEventType = "TaskSucceeded"
+	CompleteTask          TaskEventType = "CompleteTask"
+	TaskFail              TaskEventType = "TaskFail"
+	KillTask              TaskEventType = "KillTask"
+	TaskKilled            TaskEventType = "TaskKilled"
+	TaskSchedulingTimeout TaskEventType = "TaskSchedulingTimeout"
+	GateTask              TaskEventType = "GateTask"
+	UngateTask            TaskEventType = "UngateTask"
+	QuarantineTask        TaskEventType = "QuarantineTask"
+	ResetTask             TaskEventType = "ResetTask"
+	TaskAskReleased       TaskEventType = "TaskAskReleased"
 )
 
 type TaskEvent interface {
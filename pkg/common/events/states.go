@@ -63,18 +63,22 @@ type NodeStates struct {
 }
 
 type TaskStates struct {
-	New        string
-	Pending    string
-	Scheduling string
-	Allocated  string
-	Rejected   string
-	Bound      string
-	Killing    string
-	Killed     string
-	Failed     string
-	Completed  string
-	Any        []string // Any refers to all possible states
-	Terminated []string // Rejected, Killed, Failed, Completed
+	New         string
+	Gated       string
+	Quarantined string
+	Pending     string
+	Scheduling  string
+	Allocated   string
+	Rejected    string
+	Bound       string
+	Running     string
+	Killing     string
+	Killed      string
+	Failed      string
+	Succeeded   string
+	Completed   string
+	Any         []string // Any refers to all possible states
+	Terminated  []string // Rejected, Killed, Failed, Succeeded, Completed
 }
 
 func States() *AllStates {
@@ -94,25 +98,29 @@ func States() *AllStates {
 				Failed:     "Failed",
 			},
 			Task: &TaskStates{
-				New:        "New",
-				Pending:    "Pending",
-				Scheduling: "Scheduling",
-				Allocated:  "TaskAllocated",
-				Rejected:   "Rejected",
-				Bound:      "Bound",
-				Killing:    "Killing",
-				Killed:     "Killed",
-				Failed:     "Failed",
-				Completed:  "Completed",
+				New:         "New",
+				Gated:       "Gated",
+				Quarantined: "Quarantined",
+				Pending:     "Pending",
+				Scheduling:  "Scheduling",
+				Allocated:   "TaskAllocated",
+				Rejected:    "Rejected",
+				Bound:       "Bound",
+				Running:     "Running",
+				Killing:     "Killing",
+				Killed:      "Killed",
+				Failed:      "Failed",
+				Succeeded:   "Succeeded",
+				Completed:   "Completed",
 				Any: []string{
-					"New", "Pending", "Scheduling",
+					"New", "Gated", "Quarantined", "Pending", "Scheduling",
 					"TaskAllocated", "Rejected",
-					"Bound", "Killing", "Killed",
-					"Failed", "Completed",
+					"Bound", "Running", "Killing", "Killed",
+					"Failed", "Succeeded", "Completed",
 				},
 				Terminated: []string{
 					"Rejected", "Killed", "Failed",
-					"Completed",
+					"Succeeded", "Completed",
 				},
 			},
 			Scheduler: &SchedulerStates{
@@ -0,0 +1,61 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package common
+
+import (
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+var overheadResolver struct {
+	resolve func(runtimeClassName string) v1.ResourceList
+	sync.RWMutex
+}
+
+// SetRuntimeClassOverheadResolver sets how the fixed pod overhead of a runtime class is found. The overhead of the
+// runtime class is added to the requests of a pod that was admitted without its overhead, e.g. the PodOverhead
+// admission is not enabled on the cluster. A nil resolver leaves the requests of such a pod as they are.
+func SetRuntimeClassOverheadResolver(resolve func(runtimeClassName string) v1.ResourceList) {
+	overheadResolver.Lock()
+	defer overheadResolver.Unlock()
+	overheadResolver.resolve = resolve
+}
+
+// GetRuntimeClassOverhead returns the fixed pod overhead of the runtime class,
+// nil if the runtime class is not known or has no overhead
+func GetRuntimeClassOverhead(runtimeClassName string) v1.ResourceList {
+	if runtimeClassName == "" {
+		return nil
+	}
+	overheadResolver.RLock()
+	defer overheadResolver.RUnlock()
+	if overheadResolver.resolve == nil {
+		return nil
+	}
+	return overheadResolver.resolve(runtimeClassName)
+}
+
+// getPodOverhead returns the overhead of the pod, the overhead of its runtime class if it was admitted without one
+func getPodOverhead(pod *v1.Pod) v1.ResourceList {
+	if pod.Spec.Overhead != nil || pod.Spec.RuntimeClassName == nil {
+		return pod.Spec.Overhead
+	}
+	return GetRuntimeClassOverhead(*pod.Spec.RuntimeClassName)
+}
@@ -0,0 +1,80 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+)
+
+func TestRuntimeClassOverhead(t *testing.T) {
+	SetRuntimeClassOverheadResolver(func(runtimeClassName string) v1.ResourceList {
+		if runtimeClassName != "kata" {
+			return nil
+		}
+		return v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("250m"),
+			v1.ResourceMemory: resource.MustParse("120M"),
+		}
+	})
+	defer SetRuntimeClassOverheadResolver(nil)
+
+	kata, other := "kata", "other"
+	newPod := func(runtimeClassName *string, overhead v1.ResourceList, requests v1.ResourceList) *v1.Pod {
+		return &v1.Pod{
+			Spec: v1.PodSpec{
+				RuntimeClassName: runtimeClassName,
+				Overhead:         overhead,
+				Containers: []v1.Container{
+					{Name: "container-01", Resources: v1.ResourceRequirements{Requests: requests}},
+				},
+			},
+		}
+	}
+	requests := v1.ResourceList{
+		v1.ResourceCPU:    resource.MustParse("1"),
+		v1.ResourceMemory: resource.MustParse("500M"),
+	}
+
+	// the overhead of the runtime class is added to a pod admitted without it
+	res := GetPodResource(newPod(&kata, nil, requests))
+	assert.Equal(t, res.Resources[constants.CPU].GetValue(), int64(1250))
+	assert.Equal(t, res.Resources[constants.Memory].GetValue(), int64(620))
+
+	// the overhead set on the pod wins
+	res = GetPodResource(newPod(&kata, v1.ResourceList{v1.ResourceCPU: resource.MustParse("100m")}, requests))
+	assert.Equal(t, res.Resources[constants.CPU].GetValue(), int64(1100))
+	assert.Equal(t, res.Resources[constants.Memory].GetValue(), int64(500))
+
+	// unknown runtime class or default runtime
+	res = GetPodResource(newPod(&other, nil, requests))
+	assert.Equal(t, res.Resources[constants.CPU].GetValue(), int64(1000))
+	res = GetPodResource(newPod(nil, nil, requests))
+	assert.Equal(t, res.Resources[constants.CPU].GetValue(), int64(1000))
+
+	// a best effort pod still has the overhead
+	res = GetPodResource(newPod(&kata, nil, nil))
+	assert.Equal(t, res.Resources[constants.CPU].GetValue(), int64(250))
+	assert.Equal(t, res.Resources[constants.Memory].GetValue(), int64(121))
+}
@@ -19,12 +19,16 @@
 package common
 
 import (
+	"strings"
+	"sync"
+
 	"go.uber.org/zap"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/kubernetes/pkg/apis/core/v1/helper/qos"
 
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
@@ -55,24 +59,88 @@ func (w *ResourceBuilder) Build() *si.Resource {
 // https://kubernetes.io/docs/tasks/configure-pod-container/quality-service-pod/
 // QOS class Guaranteed and Burstable are supported. However Burstable is scheduled based on the request
 // values, limits are ignored in the current setup.
-// BestEffort pods are scheduled using a minimum resource of 1MB only.
+// BestEffort pods are scheduled using a minimum memory of 1 (1MB by default) and the overhead of the pod only.
+// The requests of the pod are the effective requests the kubelet admits, see GetPodRequests.
 func GetPodResource(pod *v1.Pod) (resource *si.Resource) {
-	//var memory, vcore = int64(0), int64(0)
-	var podResource *si.Resource
 	// A QosBestEffort pod does not request any resources and thus cannot be
 	// scheduled. Handle a QosBestEffort pod by setting a tiny memory value.
 	if qos.GetPodQOS(pod) == v1.PodQOSBestEffort {
 		resources := NewResourceBuilder()
 		resources.AddResource(constants.Memory, 1)
-		return resources.Build()
+		return Add(resources.Build(), getResource(getPodOverhead(pod)))
 	}
+	return getResource(GetPodRequests(pod))
+}
 
+// GetPodRequests returns the effective requests of a pod: max(max(init containers), sum(containers)) + overhead.
+// The init containers run one after the other before the containers are started, a pod needs the most
+// of its largest init container and the sum of its containers. A sidecar init container keeps running
+// once it is started: it adds up to the init containers started after it and to the containers.
+// The init containers are left out when configured, the sidecars are still counted in that case.
+func GetPodRequests(pod *v1.Pod) v1.ResourceList {
+	sidecars := getSidecarContainers(pod)
+	ignoreInit := conf.GetSchedulerConf().IsInitContainersIgnored()
+	initRequests := v1.ResourceList{}
+	sidecarRequests := v1.ResourceList{}
+	for _, c := range pod.Spec.InitContainers {
+		if sidecars[c.Name] {
+			addResourceList(sidecarRequests, c.Resources.Requests)
+			maxResourceList(initRequests, sidecarRequests)
+			continue
+		}
+		if ignoreInit {
+			continue
+		}
+		// the init container runs with the sidecars started before it
+		running := v1.ResourceList{}
+		addResourceList(running, sidecarRequests)
+		addResourceList(running, c.Resources.Requests)
+		maxResourceList(initRequests, running)
+	}
+	requests := v1.ResourceList{}
 	for _, c := range pod.Spec.Containers {
-		resourceList := c.Resources.Requests
-		containerResource := getResource(resourceList)
-		podResource = Add(podResource, containerResource)
+		addResourceList(requests, c.Resources.Requests)
+	}
+	addResourceList(requests, sidecarRequests)
+	maxResourceList(requests, initRequests)
+	addResourceList(requests, getPodOverhead(pod))
+	return requests
+}
+
+// returns the names of the init containers of the pod that keep running alongside its containers
+func getSidecarContainers(pod *v1.Pod) map[string]bool {
+	value, ok := pod.Annotations[constants.AnnotationSidecarContainers]
+	if !ok {
+		return nil
+	}
+	sidecars := make(map[string]bool)
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			sidecars[name] = true
+		}
+	}
+	return sidecars
+}
+
+// adds the quantities of the second list to the first one
+func addResourceList(list, toAdd v1.ResourceList) {
+	for name, quantity := range toAdd {
+		if value, ok := list[name]; ok {
+			value.Add(quantity)
+			list[name] = value
+		} else {
+			list[name] = quantity.DeepCopy()
+		}
+	}
+}
+
+// sets the quantities of the first list to the max of both lists
+func maxResourceList(list, other v1.ResourceList) {
+	for name, quantity := range other {
+		if value, ok := list[name]; !ok || quantity.Cmp(value) > 0 {
+			list[name] = quantity.DeepCopy()
+		}
 	}
-	return podResource
 }
 
 func GetNodeResource(nodeStatus *v1.NodeStatus) *si.Resource {
@@ -105,7 +173,7 @@ func ParseResource(cpuStr, memStr string) *si.Resource {
 
 	if memStr != "" {
 		if mem, err := resource.ParseQuantity(memStr); err == nil {
-			result.AddResource(constants.Memory, mem.ScaledValue(resource.Mega))
+			result.AddResource(constants.Memory, getMemoryConverter()(mem))
 		} else {
 			log.Logger().Error("failed to parse memory resource",
 				zap.String("memStr", memStr),
@@ -117,38 +185,78 @@ func ParseResource(cpuStr, memStr string) *si.Resource {
 	return result.Build()
 }
 
+// GetTGResource returns the resource of the given number of task group members,
+// the task group resources are converted the same way as the pod resources, this ensures
+// the placeholder asks match the asks of the real pods replacing them.
 func GetTGResource(resMap map[string]resource.Quantity, members int64) *si.Resource {
 	result := NewResourceBuilder()
 	for resName, resValue := range resMap {
-		switch resName {
-		case v1.ResourceCPU.String():
-			result.AddResource(constants.CPU, members*resValue.MilliValue())
-		case v1.ResourceMemory.String():
-			result.AddResource(constants.Memory, members*resValue.ScaledValue(resource.Mega))
-		default:
-			result.AddResource(resName, members*resValue.Value())
-		}
+		name, value := convertResource(v1.ResourceName(resName), resValue)
+		result.AddResource(name, members*value)
 	}
 	return result.Build()
 }
 
 func getResource(resourceList v1.ResourceList) *si.Resource {
 	resources := NewResourceBuilder()
-	for name, value := range resourceList {
-		switch name {
-		case v1.ResourceMemory:
-			memory := value.ScaledValue(resource.Mega)
-			resources.AddResource(constants.Memory, memory)
-		case v1.ResourceCPU:
-			vcore := value.MilliValue()
-			resources.AddResource(constants.CPU, vcore)
-		default:
-			resources.AddResource(string(name), value.Value())
-		}
+	for resName, resValue := range resourceList {
+		name, value := convertResource(resName, resValue)
+		resources.AddResource(name, value)
 	}
 	return resources.Build()
 }
 
+// MemoryConverter converts the memory quantity of a pod to the value sent to the scheduler
+type MemoryConverter func(value resource.Quantity) int64
+
+var memoryConverters = struct {
+	converters map[string]MemoryConverter
+	sync.RWMutex
+}{
+	converters: map[string]MemoryConverter{
+		// rounded up to MB, a pod never asks less than it requests
+		conf.MemoryConversionMegabytes: func(value resource.Quantity) int64 {
+			return value.ScaledValue(resource.Mega)
+		},
+		conf.MemoryConversionBytes: func(value resource.Quantity) int64 {
+			return value.Value()
+		},
+	},
+}
+
+// RegisterMemoryConverter registers a memory conversion under the name it is selected with in the configuration,
+// a conversion registered with the name of an existing conversion replaces it.
+func RegisterMemoryConverter(name string, converter MemoryConverter) {
+	memoryConverters.Lock()
+	defer memoryConverters.Unlock()
+	memoryConverters.converters[name] = converter
+}
+
+// getMemoryConverter returns the configured memory conversion, the conversion to MB if it is not known
+func getMemoryConverter() MemoryConverter {
+	name := conf.GetSchedulerConf().GetMemoryConversion()
+	memoryConverters.RLock()
+	defer memoryConverters.RUnlock()
+	if converter, ok := memoryConverters.converters[name]; ok {
+		return converter
+	}
+	return memoryConverters.converters[conf.MemoryConversionMegabytes]
+}
+
+// convert a K8s resource to the name and value of a si resource:
+// cpu is converted to milli cores, memory following the configured memory conversion (MB by default),
+// ephemeral-storage and hugepages are kept in bytes, extended resources (e.g nvidia.com/gpu) are kept as their count.
+func convertResource(name v1.ResourceName, value resource.Quantity) (string, int64) {
+	switch name {
+	case v1.ResourceCPU:
+		return constants.CPU, value.MilliValue()
+	case v1.ResourceMemory:
+		return constants.Memory, getMemoryConverter()(value)
+	default:
+		return string(name), value.Value()
+	}
+}
+
 func Equals(left *si.Resource, right *si.Resource) bool {
 	if left == right {
 		return true
@@ -199,6 +307,18 @@ func Add(left *si.Resource, right *si.Resource) *si.Resource {
 	return result
 }
 
+// Multiply returns the resource multiplied by the given factor
+func Multiply(r *si.Resource, factor int64) *si.Resource {
+	result := NewResourceBuilder()
+	if r == nil {
+		return result.Build()
+	}
+	for k, v := range r.Resources {
+		result.AddResource(k, v.Value*factor)
+	}
+	return result.Build()
+}
+
 func Sub(left *si.Resource, right *si.Resource) *si.Resource {
 	if left == nil {
 		left = &si.Resource{}
@@ -239,3 +359,23 @@ func IsZero(r *si.Resource) bool {
 	}
 	return true
 }
+
+// FitIn checks if the smaller resource fits in the larger resource,
+// a resource type that is not defined in the larger resource is treated as zero.
+func FitIn(larger *si.Resource, smaller *si.Resource) bool {
+	if smaller == nil {
+		return true
+	}
+	for k, v := range smaller.Resources {
+		var available int64
+		if larger != nil {
+			if q, ok := larger.Resources[k]; ok {
+				available = q.Value
+			}
+		}
+		if v.Value > available {
+			return false
+		}
+	}
+	return true
+}
@@ -27,6 +27,7 @@ import (
 	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
 
@@ -192,6 +193,147 @@ func TestParsePodResource(t *testing.T) {
 	assert.Equal(t, res.Resources["nvidia.com/gpu"].GetValue(), int64(5))
 }
 
+func TestGetPodRequests(t *testing.T) {
+	requests := func(cpu, memory string) v1.ResourceRequirements {
+		return v1.ResourceRequirements{
+			Requests: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse(cpu),
+				v1.ResourceMemory: resource.MustParse(memory),
+			},
+		}
+	}
+	containers := []v1.Container{
+		{Name: "container-01", Resources: requests("1", "500M")},
+		{Name: "container-02", Resources: requests("2", "1000M")},
+	}
+	testCases := []struct {
+		name           string
+		initContainers []v1.Container
+		sidecars       string
+		overhead       v1.ResourceList
+		ignoreInit     bool
+		cpu            int64
+		memory         int64
+	}{
+		{"containers only", nil, "", nil, false, 3000, 1500},
+		{"small init containers", []v1.Container{
+			{Name: "init-01", Resources: requests("1", "1000M")},
+			{Name: "init-02", Resources: requests("2", "200M")},
+		}, "", nil, false, 3000, 1500},
+		// the max of the init containers and the containers is taken per resource
+		{"large init container", []v1.Container{
+			{Name: "init-01", Resources: requests("4", "100M")},
+		}, "", nil, false, 4000, 1500},
+		{"large init container ignored", []v1.Container{
+			{Name: "init-01", Resources: requests("4", "100M")},
+		}, "", nil, true, 3000, 1500},
+		// the sidecar runs with the containers and the init containers started after it
+		{"sidecar", []v1.Container{
+			{Name: "init-01", Resources: requests("4", "100M")},
+			{Name: "sidecar-01", Resources: requests("1", "100M")},
+			{Name: "init-02", Resources: requests("3", "2000M")},
+		}, "sidecar-01", nil, false, 4000, 2100},
+		{"sidecar with init containers ignored", []v1.Container{
+			{Name: "init-01", Resources: requests("4", "100M")},
+			{Name: "sidecar-01", Resources: requests("1", "100M")},
+		}, " sidecar-01, other", nil, true, 4000, 1600},
+		{"overhead", []v1.Container{
+			{Name: "init-01", Resources: requests("4", "100M")},
+		}, "", v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("250m"),
+			v1.ResourceMemory: resource.MustParse("120M"),
+		}, false, 4250, 1620},
+	}
+	defer func() { conf.GetSchedulerConf().IgnoreInitContainers = false }()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conf.GetSchedulerConf().IgnoreInitContainers = tc.ignoreInit
+			pod := &v1.Pod{
+				ObjectMeta: apis.ObjectMeta{
+					Name:        "pod-resource-test-00001",
+					UID:         "UID-00001",
+					Annotations: map[string]string{},
+				},
+				Spec: v1.PodSpec{
+					InitContainers: tc.initContainers,
+					Containers:     containers,
+					Overhead:       tc.overhead,
+				},
+			}
+			if tc.sidecars != "" {
+				pod.Annotations[constants.AnnotationSidecarContainers] = tc.sidecars
+			}
+			res := GetPodResource(pod)
+			assert.Equal(t, res.Resources[constants.CPU].GetValue(), tc.cpu)
+			assert.Equal(t, res.Resources[constants.Memory].GetValue(), tc.memory)
+		})
+	}
+}
+
+func TestGetTGResource(t *testing.T) {
+	minResource := map[string]resource.Quantity{
+		"cpu":               resource.MustParse("500m"),
+		"memory":            resource.MustParse("1024M"),
+		"ephemeral-storage": resource.MustParse("1Gi"),
+		"hugepages-2Mi":     resource.MustParse("4Mi"),
+		"nvidia.com/gpu":    resource.MustParse("1"),
+	}
+	res := GetTGResource(minResource, 3)
+	assert.Equal(t, res.Resources[constants.CPU].GetValue(), int64(1500))
+	assert.Equal(t, res.Resources[constants.Memory].GetValue(), int64(3072))
+	assert.Equal(t, res.Resources["ephemeral-storage"].GetValue(), int64(3*1024*1024*1024))
+	assert.Equal(t, res.Resources["hugepages-2Mi"].GetValue(), int64(3*4*1024*1024))
+	assert.Equal(t, res.Resources["nvidia.com/gpu"].GetValue(), int64(3))
+
+	// a single member has the same resource as a pod requesting the same resources
+	requests := v1.ResourceList{}
+	for name, value := range minResource {
+		requests[v1.ResourceName(name)] = value
+	}
+	pod := &v1.Pod{
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{
+					Name: "container-01",
+					Resources: v1.ResourceRequirements{
+						Requests: requests,
+					},
+				},
+			},
+		},
+	}
+	assert.Assert(t, Equals(GetTGResource(minResource, 1), GetPodResource(pod)))
+}
+
+func TestMemoryConversion(t *testing.T) {
+	defer func() { conf.GetSchedulerConf().MemoryConversion = "" }()
+	requests := v1.ResourceList{v1.ResourceMemory: resource.MustParse("500Mi")}
+
+	// rounded up to MB by default
+	assert.Equal(t, getResource(requests).Resources[constants.Memory].GetValue(), int64(525))
+	assert.Equal(t, ParseResource("", "500Mi").Resources[constants.Memory].GetValue(), int64(525))
+
+	conf.GetSchedulerConf().MemoryConversion = conf.MemoryConversionBytes
+	assert.Equal(t, getResource(requests).Resources[constants.Memory].GetValue(), int64(500*1024*1024))
+	assert.Equal(t, ParseResource("", "500Mi").Resources[constants.Memory].GetValue(), int64(500*1024*1024))
+
+	// a registered conversion can be selected
+	RegisterMemoryConverter("mebibytes", func(value resource.Quantity) int64 {
+		return value.ScaledValue(resource.Mega) * 1000 * 1000 / (1024 * 1024)
+	})
+	defer func() {
+		memoryConverters.Lock()
+		delete(memoryConverters.converters, "mebibytes")
+		memoryConverters.Unlock()
+	}()
+	conf.GetSchedulerConf().MemoryConversion = "mebibytes"
+	assert.Equal(t, getResource(requests).Resources[constants.Memory].GetValue(), int64(500))
+
+	// an unknown conversion falls back to MB
+	conf.GetSchedulerConf().MemoryConversion = "unknown"
+	assert.Equal(t, getResource(requests).Resources[constants.Memory].GetValue(), int64(525))
+}
+
 func TestBestEffortPod(t *testing.T) {
 	resources := make(map[v1.ResourceName]resource.Quantity)
 	containers := make([]v1.Container, 0)
@@ -379,3 +521,24 @@ func TestParseResourceString(t *testing.T) {
 		})
 	}
 }
+
+func TestFitIn(t *testing.T) {
+	// nil checks
+	assert.Assert(t, FitIn(nil, nil))
+	assert.Assert(t, FitIn(NewResourceBuilder().AddResource("a", 1).Build(), nil))
+	assert.Assert(t, !FitIn(nil, NewResourceBuilder().AddResource("a", 1).Build()))
+
+	larger := NewResourceBuilder().
+		AddResource("a", 5).
+		AddResource("b", 10).
+		Build()
+
+	// equal and smaller values fit
+	assert.Assert(t, FitIn(larger, larger))
+	assert.Assert(t, FitIn(larger, NewResourceBuilder().AddResource("a", 1).Build()))
+	assert.Assert(t, FitIn(larger, NewResourceBuilder().AddResource("c", 0).Build()))
+
+	// larger values or undefined resources do not fit
+	assert.Assert(t, !FitIn(larger, NewResourceBuilder().AddResource("a", 6).Build()))
+	assert.Assert(t, !FitIn(larger, NewResourceBuilder().AddResource("c", 1).Build()))
+}
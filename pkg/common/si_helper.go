@@ -19,14 +19,31 @@
 package common
 
 import (
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
 	v1 "k8s.io/api/core/v1"
 
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/sirequest"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/trace"
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/common"
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
 
+// buildRequest returns the built request, an invalid request is logged and returned as is,
+// the core rejects it the same way it did before the shim validated the requests
+func buildRequest(builder *sirequest.Builder) si.UpdateRequest {
+	request, err := builder.Build()
+	if err != nil {
+		log.Logger().Warn("invalid update request", zap.Error(err))
+	}
+	return *request
+}
+
 func createTagsForTask(pod *v1.Pod) map[string]string {
 	metaPrefix := common.DomainK8s + common.GroupMeta
 	tags := map[string]string{
@@ -54,35 +71,69 @@ func CreateUpdateRequestForTask(appID, taskID string, resource *si.Resource, pla
 		TaskGroupName:  taskGroupName,
 	}
 
-	result := si.UpdateRequest{
-		Asks:                []*si.AllocationAsk{&ask},
-		NewSchedulableNodes: nil,
-		UpdatedNodes:        nil,
-		RmID:                conf.GetSchedulerConf().ClusterID,
-	}
+	return buildRequest(sirequest.New(conf.GetSchedulerConf().ClusterID).AddAsk(&ask))
+}
 
-	return result
+// AddTaskGroupTags tags the ask with the gang topology: the task group name, the index of the member
+// in the task group and the total members of the task group. This allows the core to do gang aware
+// node selection, a real member and the placeholder it replaces share the same index.
+func AddTaskGroupTags(ask *si.AllocationAsk, taskGroupName string, index, total int32) {
+	if ask.Tags == nil {
+		ask.Tags = make(map[string]string)
+	}
+	metaPrefix := common.DomainYuniKorn + common.GroupMeta
+	ask.Tags[metaPrefix+constants.TagKeyTaskGroupName] = taskGroupName
+	ask.Tags[metaPrefix+constants.TagKeyTaskGroupIndex] = strconv.Itoa(int(index))
+	ask.Tags[metaPrefix+constants.TagKeyTaskGroupTotal] = strconv.Itoa(int(total))
 }
 
-func CreateReleaseAskRequestForTask(appID, taskId, partition string) si.UpdateRequest {
-	toReleases := make([]*si.AllocationAskRelease, 0)
-	toReleases = append(toReleases, &si.AllocationAskRelease{
-		ApplicationID: appID,
-		Allocationkey: taskId,
-		PartitionName: partition,
-		Message:       "task request is canceled",
-	})
+// AddPreferredNodesTag tags the ask with the nodes the task prefers to run on. This is a hint only,
+// the core tries these nodes first and falls back to the other nodes of the partition.
+func AddPreferredNodesTag(ask *si.AllocationAsk, nodes []string) {
+	if len(nodes) == 0 {
+		return
+	}
+	if ask.Tags == nil {
+		ask.Tags = make(map[string]string)
+	}
+	ask.Tags[common.DomainYuniKorn+common.GroupMeta+constants.TagKeyPreferredNodes] =
+		strings.Join(nodes, constants.PreferredNodesDelimiter)
+}
+
+// AddTaskQueueTag tags the ask with the queue the task is scheduled in when it overrides the queue of its app,
+// the app keeps its own queue and the other tasks of the app are not affected.
+func AddTaskQueueTag(ask *si.AllocationAsk, queue string) {
+	if queue == "" {
+		return
+	}
+	if ask.Tags == nil {
+		ask.Tags = make(map[string]string)
+	}
+	ask.Tags[common.DomainYuniKorn+common.GroupMeta+constants.TagKeyTaskQueue] = queue
+}
 
-	releaseRequest := si.AllocationReleasesRequest{
-		AllocationAsksToRelease: toReleases,
+// SetAskPriority sets the priority of the ask, the core schedules the asks of an app with a higher priority first
+func SetAskPriority(ask *si.AllocationAsk, priority int32) {
+	ask.Priority = &si.Priority{
+		Priority: &si.Priority_PriorityValue{PriorityValue: priority},
 	}
+}
 
-	result := si.UpdateRequest{
-		Releases: &releaseRequest,
-		RmID:     conf.GetSchedulerConf().ClusterID,
+// AddTraceTags tags the ask with the span context of the trace of its task,
+// the ask is not tagged if the task is not traced.
+func AddTraceTags(ask *si.AllocationAsk, taskID string) {
+	if !trace.IsEnabled() {
+		return
+	}
+	if ask.Tags == nil {
+		ask.Tags = make(map[string]string)
 	}
+	trace.InjectTaskTrace(taskID, ask.Tags)
+}
 
-	return result
+func CreateReleaseAskRequestForTask(appID, taskId, partition string) si.UpdateRequest {
+	return buildRequest(sirequest.New(conf.GetSchedulerConf().ClusterID).
+		ReleaseAsk(appID, taskId, partition, "task request is canceled"))
 }
 
 func GetTerminationTypeFromString(terminationTypeStr string) si.TerminationType {
@@ -93,25 +144,8 @@ func GetTerminationTypeFromString(terminationTypeStr string) si.TerminationType
 }
 
 func CreateReleaseAllocationRequestForTask(appID, allocUUID, partition, terminationType string) si.UpdateRequest {
-	toReleases := make([]*si.AllocationRelease, 0)
-	toReleases = append(toReleases, &si.AllocationRelease{
-		ApplicationID:   appID,
-		UUID:            allocUUID,
-		PartitionName:   partition,
-		TerminationType: GetTerminationTypeFromString(terminationType),
-		Message:         "task completed",
-	})
-
-	releaseRequest := si.AllocationReleasesRequest{
-		AllocationsToRelease: toReleases,
-	}
-
-	result := si.UpdateRequest{
-		Releases: &releaseRequest,
-		RmID:     conf.GetSchedulerConf().ClusterID,
-	}
-
-	return result
+	return buildRequest(sirequest.New(conf.GetSchedulerConf().ClusterID).
+		ReleaseAlloc(appID, allocUUID, partition, GetTerminationTypeFromString(terminationType), "task completed"))
 }
 
 func CreateUpdateRequestForNewNode(node Node) si.UpdateRequest {
@@ -127,13 +161,7 @@ func CreateUpdateRequestForNewNode(node Node) si.UpdateRequest {
 		},
 	}
 
-	nodes := make([]*si.NewNodeInfo, 1)
-	nodes[0] = nodeInfo
-	request := si.UpdateRequest{
-		NewSchedulableNodes: nodes,
-		RmID:                conf.GetSchedulerConf().ClusterID,
-	}
-	return request
+	return buildRequest(sirequest.New(conf.GetSchedulerConf().ClusterID).AddNode(nodeInfo))
 }
 
 func CreateUpdateRequestForUpdatedNode(node Node) si.UpdateRequest {
@@ -146,17 +174,10 @@ func CreateUpdateRequestForUpdatedNode(node Node) si.UpdateRequest {
 		Action:              si.UpdateNodeInfo_UPDATE,
 	}
 
-	nodes := make([]*si.UpdateNodeInfo, 1)
-	nodes[0] = nodeInfo
-	request := si.UpdateRequest{
-		UpdatedNodes: nodes,
-		RmID:         conf.GetSchedulerConf().ClusterID,
-	}
-	return request
+	return buildRequest(sirequest.New(conf.GetSchedulerConf().ClusterID).UpdateNode(nodeInfo))
 }
 
 func CreateUpdateRequestForDeleteNode(node Node) si.UpdateRequest {
-	deletedNodes := make([]*si.UpdateNodeInfo, 1)
 	nodeInfo := &si.UpdateNodeInfo{
 		NodeID:              node.name,
 		SchedulableResource: node.capacity,
@@ -165,24 +186,9 @@ func CreateUpdateRequestForDeleteNode(node Node) si.UpdateRequest {
 		Action:              si.UpdateNodeInfo_DECOMISSION,
 	}
 
-	deletedNodes[0] = nodeInfo
-	request := si.UpdateRequest{
-		UpdatedNodes: deletedNodes,
-		RmID:         conf.GetSchedulerConf().ClusterID,
-	}
-	return request
+	return buildRequest(sirequest.New(conf.GetSchedulerConf().ClusterID).UpdateNode(nodeInfo))
 }
 
 func CreateUpdateRequestForRemoveApplication(appID, partition string) si.UpdateRequest {
-	removeApp := make([]*si.RemoveApplicationRequest, 0)
-	removeApp = append(removeApp, &si.RemoveApplicationRequest{
-		ApplicationID: appID,
-		PartitionName: partition,
-	})
-	request := si.UpdateRequest{
-		RemoveApplications: removeApp,
-		RmID:               conf.GetSchedulerConf().ClusterID,
-	}
-
-	return request
+	return buildRequest(sirequest.New(conf.GetSchedulerConf().ClusterID).RemoveApp(appID, partition))
 }
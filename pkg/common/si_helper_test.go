@@ -21,6 +21,7 @@ import (
 	"testing"
 
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/common"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 	"gotest.tools/assert"
 	v1 "k8s.io/api/core/v1"
 	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -96,3 +97,40 @@ func TestCreateUpdateRequestForTask(t *testing.T) {
 	assert.Equal(t, tags[common.DomainK8s+common.GroupLabel+"label1"], "val1")
 	assert.Equal(t, tags[common.DomainK8s+common.GroupLabel+"label2"], "val2")
 }
+
+func TestAddTaskGroupTags(t *testing.T) {
+	ask := &si.AllocationAsk{}
+	AddTaskGroupTags(ask, "test-group", 2, 10)
+	prefix := common.DomainYuniKorn + common.GroupMeta
+	assert.Equal(t, len(ask.Tags), 3)
+	assert.Equal(t, ask.Tags[prefix+"taskGroupName"], "test-group")
+	assert.Equal(t, ask.Tags[prefix+"taskGroupIndex"], "2")
+	assert.Equal(t, ask.Tags[prefix+"taskGroupTotal"], "10")
+}
+
+func TestAddPreferredNodesTag(t *testing.T) {
+	ask := &si.AllocationAsk{}
+	AddPreferredNodesTag(ask, nil)
+	assert.Assert(t, ask.Tags == nil)
+
+	AddPreferredNodesTag(ask, []string{"node-1", "node-2"})
+	assert.Equal(t, len(ask.Tags), 1)
+	assert.Equal(t, ask.Tags[common.DomainYuniKorn+common.GroupMeta+"preferredNodes"], "node-1,node-2")
+}
+
+func TestAddTaskQueueTag(t *testing.T) {
+	ask := &si.AllocationAsk{}
+	AddTaskQueueTag(ask, "")
+	assert.Assert(t, ask.Tags == nil)
+
+	AddTaskQueueTag(ask, "root.batch")
+	assert.Equal(t, len(ask.Tags), 1)
+	assert.Equal(t, ask.Tags[common.DomainYuniKorn+common.GroupMeta+"queue"], "root.batch")
+}
+
+func TestSetAskPriority(t *testing.T) {
+	ask := &si.AllocationAsk{}
+	assert.Equal(t, ask.GetPriority().GetPriorityValue(), int32(0))
+	SetAskPriority(ask, 20)
+	assert.Equal(t, ask.GetPriority().GetPriorityValue(), int32(20))
+}
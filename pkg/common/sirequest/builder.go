@@ -0,0 +1,181 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// Package sirequest builds the update requests the shim sends to the scheduler core. The builder validates
+// every object it adds, a request that would be rejected by the core is detected before it is sent.
+package sirequest
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+// Builder collects the content of an UpdateRequest, the first validation error is kept and returned by Build.
+// An invalid object is still added to the request, only nil objects are skipped. The fields of the request
+// are only set when something is added to them.
+type Builder struct {
+	request *si.UpdateRequest
+	err     error
+}
+
+// New returns a builder of a request sent to the core on behalf of the given RM
+func New(rmID string) *Builder {
+	return &Builder{
+		request: &si.UpdateRequest{RmID: rmID},
+	}
+}
+
+func (b *Builder) fail(format string, args ...interface{}) {
+	if b.err == nil {
+		b.err = fmt.Errorf(format, args...)
+	}
+}
+
+// AddApp adds a new application to the request
+func (b *Builder) AddApp(app *si.AddApplicationRequest) *Builder {
+	switch {
+	case app == nil:
+		b.fail("application must not be nil")
+		return b
+	case app.ApplicationID == "":
+		b.fail("application ID must not be empty")
+	case app.Ugi == nil:
+		b.fail("application %s has no user group information", app.ApplicationID)
+	}
+	b.request.NewApplications = append(b.request.NewApplications, app)
+	return b
+}
+
+// RemoveApp adds the removal of an application to the request
+func (b *Builder) RemoveApp(appID, partition string) *Builder {
+	if appID == "" {
+		b.fail("application ID of the removed application must not be empty")
+	}
+	b.request.RemoveApplications = append(b.request.RemoveApplications, &si.RemoveApplicationRequest{
+		ApplicationID: appID,
+		PartitionName: partition,
+	})
+	return b
+}
+
+// AddAsk adds an allocation ask to the request
+func (b *Builder) AddAsk(ask *si.AllocationAsk) *Builder {
+	switch {
+	case ask == nil:
+		b.fail("ask must not be nil")
+		return b
+	case ask.AllocationKey == "":
+		b.fail("allocation key of the ask must not be empty")
+	case ask.ApplicationID == "":
+		b.fail("ask %s has no application ID", ask.AllocationKey)
+	case ask.MaxAllocations < 1:
+		b.fail("ask %s must request at least one allocation", ask.AllocationKey)
+	}
+	b.request.Asks = append(b.request.Asks, ask)
+	return b
+}
+
+// ReleaseAsk adds the release of a pending allocation ask to the request
+func (b *Builder) ReleaseAsk(appID, allocationKey, partition, message string) *Builder {
+	if appID == "" || allocationKey == "" {
+		b.fail("released ask %s of application %s must have an application ID and an allocation key",
+			allocationKey, appID)
+	}
+	b.releases().AllocationAsksToRelease = append(b.releases().AllocationAsksToRelease, &si.AllocationAskRelease{
+		ApplicationID: appID,
+		Allocationkey: allocationKey,
+		PartitionName: partition,
+		Message:       message,
+	})
+	return b
+}
+
+// ReleaseAlloc adds the release of an allocation to the request
+func (b *Builder) ReleaseAlloc(appID, uuid, partition string, terminationType si.TerminationType, message string) *Builder {
+	if appID == "" || uuid == "" {
+		b.fail("released allocation %s of application %s must have an application ID and a UUID", uuid, appID)
+	}
+	b.releases().AllocationsToRelease = append(b.releases().AllocationsToRelease, &si.AllocationRelease{
+		ApplicationID:   appID,
+		UUID:            uuid,
+		PartitionName:   partition,
+		TerminationType: terminationType,
+		Message:         message,
+	})
+	return b
+}
+
+func (b *Builder) releases() *si.AllocationReleasesRequest {
+	if b.request.Releases == nil {
+		b.request.Releases = &si.AllocationReleasesRequest{}
+	}
+	return b.request.Releases
+}
+
+// AddNode adds a new schedulable node to the request
+func (b *Builder) AddNode(node *si.NewNodeInfo) *Builder {
+	switch {
+	case node == nil:
+		b.fail("node must not be nil")
+		return b
+	case node.NodeID == "":
+		b.fail("node ID must not be empty")
+	}
+	b.request.NewSchedulableNodes = append(b.request.NewSchedulableNodes, node)
+	return b
+}
+
+// UpdateNode adds an update of a node to the request, e.g. a new capacity or a change of its schedulable state
+func (b *Builder) UpdateNode(node *si.UpdateNodeInfo) *Builder {
+	switch {
+	case node == nil:
+		b.fail("node update must not be nil")
+		return b
+	case node.NodeID == "":
+		b.fail("node ID of the node update must not be empty")
+	}
+	b.request.UpdatedNodes = append(b.request.UpdatedNodes, node)
+	return b
+}
+
+// NodeAction returns the update of a node that only changes its schedulable state, e.g. draining the node
+func NodeAction(nodeID string, action si.UpdateNodeInfo_ActionFromRM) *si.UpdateNodeInfo {
+	return &si.UpdateNodeInfo{
+		NodeID: nodeID,
+		Action: action,
+		Attributes: map[string]string{
+			constants.DefaultNodeAttributeHostNameKey: nodeID,
+			constants.DefaultNodeAttributeRackNameKey: constants.DefaultRackName,
+		},
+	}
+}
+
+// Build returns the request with the first validation error, the request is returned even if it is invalid
+// and the caller decides to drop it or to send it anyway
+func (b *Builder) Build() (*si.UpdateRequest, error) {
+	if b.err != nil {
+		return b.request, b.err
+	}
+	if b.request.RmID == "" {
+		return b.request, errors.New("RM ID of the request must not be empty")
+	}
+	return b.request, nil
+}
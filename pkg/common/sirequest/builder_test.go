@@ -0,0 +1,141 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package sirequest
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+func TestBuildEmpty(t *testing.T) {
+	request, err := New("cluster-1").Build()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, request, &si.UpdateRequest{RmID: "cluster-1"})
+
+	_, err = New("").Build()
+	assert.ErrorContains(t, err, "RM ID of the request must not be empty")
+}
+
+func TestBuildApps(t *testing.T) {
+	app := &si.AddApplicationRequest{
+		ApplicationID: "app-1",
+		QueueName:     "root.a",
+		PartitionName: "default",
+		Ugi:           &si.UserGroupInformation{User: "user-1"},
+	}
+	request, err := New("cluster-1").
+		AddApp(app).
+		RemoveApp("app-2", "default").
+		Build()
+	assert.NilError(t, err)
+	assert.Equal(t, len(request.NewApplications), 1)
+	assert.Equal(t, request.NewApplications[0], app)
+	assert.DeepEqual(t, request.RemoveApplications, []*si.RemoveApplicationRequest{
+		{ApplicationID: "app-2", PartitionName: "default"},
+	})
+	assert.Assert(t, request.Asks == nil)
+	assert.Assert(t, request.Releases == nil)
+
+	_, err = New("cluster-1").AddApp(nil).Build()
+	assert.ErrorContains(t, err, "application must not be nil")
+	_, err = New("cluster-1").AddApp(&si.AddApplicationRequest{Ugi: app.Ugi}).Build()
+	assert.ErrorContains(t, err, "application ID must not be empty")
+	_, err = New("cluster-1").AddApp(&si.AddApplicationRequest{ApplicationID: "app-1"}).Build()
+	assert.ErrorContains(t, err, "application app-1 has no user group information")
+	_, err = New("cluster-1").RemoveApp("", "default").Build()
+	assert.ErrorContains(t, err, "application ID of the removed application must not be empty")
+}
+
+func TestBuildAsks(t *testing.T) {
+	ask := &si.AllocationAsk{
+		AllocationKey:  "task-1",
+		ApplicationID:  "app-1",
+		MaxAllocations: 1,
+	}
+	request, err := New("cluster-1").
+		AddAsk(ask).
+		ReleaseAsk("app-1", "task-2", "default", "canceled").
+		ReleaseAlloc("app-1", "uuid-1", "default", si.TerminationType_TIMEOUT, "timed out").
+		Build()
+	assert.NilError(t, err)
+	assert.Equal(t, len(request.Asks), 1)
+	assert.Equal(t, request.Asks[0], ask)
+	assert.DeepEqual(t, request.Releases, &si.AllocationReleasesRequest{
+		AllocationAsksToRelease: []*si.AllocationAskRelease{
+			{ApplicationID: "app-1", Allocationkey: "task-2", PartitionName: "default", Message: "canceled"},
+		},
+		AllocationsToRelease: []*si.AllocationRelease{
+			{ApplicationID: "app-1", UUID: "uuid-1", PartitionName: "default",
+				TerminationType: si.TerminationType_TIMEOUT, Message: "timed out"},
+		},
+	})
+
+	// the first error is returned, the invalid objects are still added
+	request, err = New("cluster-1").
+		AddAsk(&si.AllocationAsk{ApplicationID: "app-1", MaxAllocations: 1}).
+		AddAsk(&si.AllocationAsk{AllocationKey: "task-1", MaxAllocations: 1}).
+		Build()
+	assert.ErrorContains(t, err, "allocation key of the ask must not be empty")
+	assert.Equal(t, len(request.Asks), 2)
+	_, err = New("cluster-1").AddAsk(&si.AllocationAsk{AllocationKey: "task-1", MaxAllocations: 1}).Build()
+	assert.ErrorContains(t, err, "ask task-1 has no application ID")
+	_, err = New("cluster-1").AddAsk(&si.AllocationAsk{AllocationKey: "task-1", ApplicationID: "app-1"}).Build()
+	assert.ErrorContains(t, err, "ask task-1 must request at least one allocation")
+	request, err = New("cluster-1").AddAsk(nil).Build()
+	assert.ErrorContains(t, err, "ask must not be nil")
+	assert.Assert(t, request.Asks == nil)
+	_, err = New("cluster-1").ReleaseAsk("app-1", "", "default", "").Build()
+	assert.ErrorContains(t, err, "must have an application ID and an allocation key")
+	_, err = New("cluster-1").ReleaseAlloc("", "uuid-1", "default", si.TerminationType_STOPPED_BY_RM, "").Build()
+	assert.ErrorContains(t, err, "must have an application ID and a UUID")
+}
+
+func TestBuildNodes(t *testing.T) {
+	node := &si.NewNodeInfo{NodeID: "node-1"}
+	request, err := New("cluster-1").
+		AddNode(node).
+		UpdateNode(NodeAction("node-2", si.UpdateNodeInfo_DRAIN_NODE)).
+		Build()
+	assert.NilError(t, err)
+	assert.Equal(t, len(request.NewSchedulableNodes), 1)
+	assert.Equal(t, request.NewSchedulableNodes[0], node)
+	assert.DeepEqual(t, request.UpdatedNodes, []*si.UpdateNodeInfo{
+		{
+			NodeID: "node-2",
+			Action: si.UpdateNodeInfo_DRAIN_NODE,
+			Attributes: map[string]string{
+				constants.DefaultNodeAttributeHostNameKey: "node-2",
+				constants.DefaultNodeAttributeRackNameKey: constants.DefaultRackName,
+			},
+		},
+	})
+
+	_, err = New("cluster-1").AddNode(nil).Build()
+	assert.ErrorContains(t, err, "node must not be nil")
+	_, err = New("cluster-1").AddNode(&si.NewNodeInfo{}).Build()
+	assert.ErrorContains(t, err, "node ID must not be empty")
+	_, err = New("cluster-1").UpdateNode(nil).Build()
+	assert.ErrorContains(t, err, "node update must not be nil")
+	_, err = New("cluster-1").UpdateNode(&si.UpdateNodeInfo{}).Build()
+	assert.ErrorContains(t, err, "node ID of the node update must not be empty")
+}
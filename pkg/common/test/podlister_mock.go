@@ -60,5 +60,32 @@ func (n *PodListerMock) Get(name string) (*v1.Pod, error) {
 }
 
 func (n *PodListerMock) Pods(namespace string) clientv1.PodNamespaceLister {
-	panic("implement me")
+	return &podNamespaceListerMock{
+		lister:    n,
+		namespace: namespace,
+	}
+}
+
+type podNamespaceListerMock struct {
+	lister    *PodListerMock
+	namespace string
+}
+
+func (n *podNamespaceListerMock) List(selector labels.Selector) (ret []*v1.Pod, err error) {
+	result := make([]*v1.Pod, 0)
+	for _, pod := range n.lister.allPods {
+		if pod.Namespace == n.namespace && selector.Matches(labels.Set(pod.Labels)) {
+			result = append(result, pod)
+		}
+	}
+	return result, nil
+}
+
+func (n *podNamespaceListerMock) Get(name string) (*v1.Pod, error) {
+	for _, pod := range n.lister.allPods {
+		if pod.Namespace == n.namespace && pod.Name == name {
+			return pod, nil
+		}
+	}
+	return nil, fmt.Errorf("pod %s/%s is not found", n.namespace, name)
 }
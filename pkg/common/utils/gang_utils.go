@@ -21,15 +21,21 @@ package utils
 import (
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"strconv"
 	"strings"
 	"sync"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/kubernetes/pkg/apis/core/v1/helper"
 
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/apis/yunikorn.apache.org/v1alpha1"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
 
 func FindAppTaskGroup(appTaskGroups []*v1alpha1.TaskGroup, groupName string) (*v1alpha1.TaskGroup, error) {
@@ -55,15 +61,122 @@ func FindAppTaskGroup(appTaskGroups []*v1alpha1.TaskGroup, groupName string) (*v
 }
 
 // the placeholder name is the pod name, pod name can not be longer than 63 chars,
-// taskGroup name and appID will be truncated if they go over 20/28 chars respectively,
+// taskGroup name and appID will be truncated if they go over 16/20 chars respectively,
 // each taskGroup is assigned with an incremental index starting from 0.
+// The name only depends on its inputs, a placeholder keeps its name across restarts of the shim.
+// The hash of the full taskGroup name and appID keeps the names of different apps apart when
+// their truncated names are the same.
 func GeneratePlaceholderName(taskGroupName, appID string, index int32) string {
-	// taskGroup name no longer than 20 chars
-	// appID no longer than 28 chars
-	// total length no longer than 20 + 28 + 5 + 10 = 63
-	shortTaskGroupName := fmt.Sprintf("%.20s", taskGroupName)
-	shortAppID := fmt.Sprintf("%.28s", appID)
-	return "tg-" + shortTaskGroupName + "-" + shortAppID + fmt.Sprintf("-%d", index)
+	// taskGroup name no longer than 16 chars
+	// appID no longer than 20 chars
+	// total length no longer than 3 + 16 + 1 + 20 + 1 + 8 + 1 + 10 = 60
+	shortTaskGroupName := toPodNamePart(fmt.Sprintf("%.16s", taskGroupName))
+	shortAppID := toPodNamePart(fmt.Sprintf("%.20s", appID))
+	return "tg-" + shortTaskGroupName + "-" + shortAppID + "-" +
+		GetTaskGroupHash(taskGroupName, appID) + fmt.Sprintf("-%d", index)
+}
+
+// GenerateProvisioningRequestName returns the name of the ProvisioningRequest of a taskGroup, it is also
+// the name of the PodTemplate the request refers to. Like the placeholder names it only depends on its inputs.
+func GenerateProvisioningRequestName(taskGroupName, appID string) string {
+	shortTaskGroupName := toPodNamePart(fmt.Sprintf("%.16s", taskGroupName))
+	shortAppID := toPodNamePart(fmt.Sprintf("%.20s", appID))
+	return "pr-" + shortTaskGroupName + "-" + shortAppID + "-" + GetTaskGroupHash(taskGroupName, appID)
+}
+
+// GetTaskGroupHash returns a short hash identifying the taskGroup of an app,
+// it is a valid part of a pod name and a valid label value.
+func GetTaskGroupHash(taskGroupName, appID string) string {
+	h := fnv.New32a()
+	// the separator cannot be part of a pod name, app "a-b" group "c" and app "a" group "b-c" differ
+	_, _ = h.Write([]byte(appID + "/" + taskGroupName))
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// toPodNamePart lower cases the value and replaces the chars that are not allowed in a pod name
+func toPodNamePart(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		default:
+			return '-'
+		}
+	}, value)
+}
+
+// GetPlaceholderLabels returns the labels reserved by the scheduler on the placeholders of a taskGroup,
+// they override the labels defined in the taskGroup. The taskGroup name is only added if it is a valid
+// label value, the hash label always identifies the taskGroup of the app.
+func GetPlaceholderLabels(appID, queue, taskGroupName string) map[string]string {
+	labels := map[string]string{
+		constants.LabelApplicationID:   appID,
+		constants.LabelQueueName:       queue,
+		constants.LabelPlaceholderFlag: "true",
+		constants.LabelTaskGroupHash:   GetTaskGroupHash(taskGroupName, appID),
+	}
+	if len(validation.IsValidLabelValue(taskGroupName)) == 0 {
+		labels[constants.LabelTaskGroupName] = taskGroupName
+	}
+	return labels
+}
+
+// GetPlaceholderSpread returns the slot label and the pod anti-affinity spreading the placeholders of a taskGroup
+// with a max per node across the nodes. The placeholders are divided round-robin over maxPerNode slots and a
+// placeholder repels the placeholders of the same slot of the taskGroup, so a node holds at most one placeholder
+// per slot. Nil is returned if the taskGroup has no max per node.
+func GetPlaceholderSpread(appID, taskGroupName string, maxPerNode, index int32) (map[string]string, *v1.Affinity) {
+	if maxPerNode <= 0 {
+		return nil, nil
+	}
+	if index < 0 {
+		index = 0
+	}
+	slot := map[string]string{
+		constants.LabelPlaceholderSlot: strconv.Itoa(int(index % maxPerNode)),
+	}
+	return slot, &v1.Affinity{
+		PodAntiAffinity: &v1.PodAntiAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []v1.PodAffinityTerm{{
+				LabelSelector: &metav1.LabelSelector{
+					MatchLabels: MergeMaps(slot, map[string]string{
+						constants.LabelTaskGroupHash: GetTaskGroupHash(taskGroupName, appID),
+					}),
+				},
+				TopologyKey: v1.LabelHostname,
+			}},
+		},
+	}
+}
+
+// GetPlaceholderOwner returns the app, the taskGroup and the index of a placeholder pod.
+// The annotations hold the exact values, the labels are used for the pods that lost them.
+// False is returned if the pod is not a placeholder or cannot be mapped back to its app and taskGroup.
+func GetPlaceholderOwner(pod *v1.Pod) (appID string, taskGroupName string, index int32, ok bool) {
+	if pod == nil || !GetPlaceholderFlagFromPodSpec(pod) {
+		return "", "", -1, false
+	}
+	appID = pod.Labels[constants.LabelApplicationID]
+	if appID == "" {
+		appID = pod.Annotations[constants.AnnotationApplicationID]
+	}
+	taskGroupName = pod.Annotations[constants.AnnotationTaskGroupName]
+	if taskGroupName == "" {
+		taskGroupName = pod.Labels[constants.LabelTaskGroupName]
+	}
+	index, _ = GetTaskGroupIndexFromPodSpec(pod)
+	if appID == "" || taskGroupName == "" {
+		return appID, taskGroupName, index, false
+	}
+	return appID, taskGroupName, index, true
+}
+
+// IsPlaceholderOf returns true if the pod is a placeholder of the taskGroup of the app
+func IsPlaceholderOf(pod *v1.Pod, appID, taskGroupName string) bool {
+	podAppID, podTaskGroupName, _, ok := GetPlaceholderOwner(pod)
+	return ok && podAppID == appID && podTaskGroupName == taskGroupName
 }
 
 func GetPlaceholderResourceRequest(resources map[string]resource.Quantity) v1.ResourceList {
@@ -74,6 +187,44 @@ func GetPlaceholderResourceRequest(resources map[string]resource.Quantity) v1.Re
 	return resourceReq
 }
 
+// returns the limits of the placeholder, resources that cannot be overcommitted,
+// e.g hugepages and extended resources like nvidia.com/gpu, must have limits equal to
+// the requests, otherwise the placeholder pod is rejected by the api-server.
+func GetPlaceholderResourceLimits(resources map[string]resource.Quantity) v1.ResourceList {
+	var resourceLimits v1.ResourceList
+	for k, v := range resources {
+		if !helper.IsOvercommitAllowed(v1.ResourceName(k)) {
+			if resourceLimits == nil {
+				resourceLimits = v1.ResourceList{}
+			}
+			resourceLimits[v1.ResourceName(k)] = v
+		}
+	}
+	return resourceLimits
+}
+
+// GetPlaceholderResource returns the resources asked by the given number of placeholders of a task group,
+// the resources are derived from a placeholder pod the same way as the asks of the placeholders are: the overhead
+// of the runtime class and the minimum memory of a best effort pod are included and rounded the same way.
+func GetPlaceholderResource(resources map[string]resource.Quantity, runtimeClassName string, members int64) *si.Resource {
+	pod := &v1.Pod{
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{
+					Resources: v1.ResourceRequirements{
+						Requests: GetPlaceholderResourceRequest(resources),
+						Limits:   GetPlaceholderResourceLimits(resources),
+					},
+				},
+			},
+		},
+	}
+	if runtimeClassName != "" {
+		pod.Spec.RuntimeClassName = &runtimeClassName
+	}
+	return common.Multiply(common.GetPodResource(pod), members)
+}
+
 func GetPlaceholderFlagFromPodSpec(pod *v1.Pod) bool {
 	if value, ok := pod.Annotations[constants.AnnotationPlaceholderFlag]; ok {
 		if v, err := strconv.ParseBool(value); err == nil {
@@ -89,13 +240,48 @@ func GetPlaceholderFlagFromPodSpec(pod *v1.Pod) bool {
 	return false
 }
 
+// GetTaskGroupFromPodSpec returns the task group of the pod,
+// a pod that opted out of the gang reservation does not belong to any task group.
+// The task group of a placeholder that lost its annotations is recovered from its labels.
 func GetTaskGroupFromPodSpec(pod *v1.Pod) string {
+	if IsNonGangPod(pod) {
+		return ""
+	}
 	if value, ok := pod.Annotations[constants.AnnotationTaskGroupName]; ok {
 		return value
 	}
+	if GetPlaceholderFlagFromPodSpec(pod) {
+		return pod.Labels[constants.LabelTaskGroupName]
+	}
 	return ""
 }
 
+// IsNonGangPod returns true if the pod opted out of the gang reservation of its app,
+// the pod is scheduled while the app is reserving, without waiting for the min members.
+// A placeholder is always part of the gang.
+func IsNonGangPod(pod *v1.Pod) bool {
+	if GetPlaceholderFlagFromPodSpec(pod) {
+		return false
+	}
+	if value, ok := pod.Annotations[constants.AnnotationNonGang]; ok {
+		if v, err := strconv.ParseBool(value); err == nil {
+			return v
+		}
+	}
+	return false
+}
+
+// GetTaskGroupIndexFromPodSpec returns the index of a placeholder in its task group,
+// false is returned if the pod does not have a valid index.
+func GetTaskGroupIndexFromPodSpec(pod *v1.Pod) (int32, bool) {
+	if value, ok := pod.Annotations[constants.AnnotationTaskGroupIndex]; ok {
+		if index, err := strconv.ParseInt(value, 10, 32); err == nil && index >= 0 {
+			return int32(index), true
+		}
+	}
+	return -1, false
+}
+
 func GetTaskGroupsFromAnnotation(pod *v1.Pod) ([]v1alpha1.TaskGroup, error) {
 	taskGroupInfo, ok := pod.Annotations[constants.AnnotationTaskGroups]
 	if !ok {
@@ -107,23 +293,137 @@ func GetTaskGroupsFromAnnotation(pod *v1.Pod) ([]v1alpha1.TaskGroup, error) {
 		return nil, err
 	}
 	// json.Unmarchal won't return error if name or MinMember is empty, but will return error if MinResource is empty or error format.
+	// A task group without MinMember reserves its MinResource in total.
 	for _, taskGroup := range taskGroups {
 		if taskGroup.Name == "" {
 			return nil, fmt.Errorf("can't get taskGroup Name from pod annotation, %s",
 				pod.Annotations[constants.AnnotationTaskGroups])
 		}
-		if taskGroup.MinMember == int32(0) {
-			return nil, fmt.Errorf("can't get taskGroup MinMember from pod annotation, %s",
+		if taskGroup.MinMember == int32(0) && len(taskGroup.MinResource) == 0 {
+			return nil, fmt.Errorf("can't get taskGroup MinMember or MinResource from pod annotation, %s",
 				pod.Annotations[constants.AnnotationTaskGroups])
 		}
 		if taskGroup.MinMember < int32(0) {
 			return nil, fmt.Errorf("minMember cannot be negative, %s",
 				pod.Annotations[constants.AnnotationTaskGroups])
 		}
+		if taskGroup.MaxPerNode < int32(0) {
+			return nil, fmt.Errorf("maxPerNode cannot be negative, %s",
+				pod.Annotations[constants.AnnotationTaskGroups])
+		}
+		if IsResourceGang(taskGroup) {
+			for name, quantity := range taskGroup.MinResource {
+				if quantity.Sign() <= 0 {
+					return nil, fmt.Errorf("minResource %s of a taskGroup without minMember must be positive, %s",
+						name, pod.Annotations[constants.AnnotationTaskGroups])
+				}
+			}
+		}
 	}
 	return taskGroups, nil
 }
 
+// IsResourceGang returns true if the task group reserves an amount of resources rather than a number of members:
+// it has a min resource but no min member.
+func IsResourceGang(taskGroup v1alpha1.TaskGroup) bool {
+	return taskGroup.MinMember == 0 && len(taskGroup.MinResource) > 0
+}
+
+// GetPlaceholderTaskGroup returns the task group the placeholders of the given task group are created from.
+// The min resource of a task group without min member is split over the fewest placeholders that are not larger
+// than its max placeholder resource, all of the same size, the rounded up placeholders reserve at least the
+// min resource. The other task groups are returned as they are.
+func GetPlaceholderTaskGroup(taskGroup v1alpha1.TaskGroup) v1alpha1.TaskGroup {
+	if !IsResourceGang(taskGroup) {
+		return taskGroup
+	}
+	members := int64(1)
+	for name, total := range taskGroup.MinResource {
+		limit, ok := taskGroup.MaxPlaceholderResource[name]
+		if !ok || limit.Sign() <= 0 {
+			continue
+		}
+		if needed := ceilDiv(quantityValue(name, total), quantityValue(name, limit)); needed > members {
+			members = needed
+		}
+	}
+	result := *taskGroup.DeepCopy()
+	result.MinMember = int32(members)
+	for name, total := range taskGroup.MinResource {
+		value := ceilDiv(quantityValue(name, total), members)
+		if name == v1.ResourceCPU.String() {
+			result.MinResource[name] = *resource.NewMilliQuantity(value, total.Format)
+		} else {
+			result.MinResource[name] = *resource.NewQuantity(value, total.Format)
+		}
+	}
+	return result
+}
+
+// quantityValue returns the cpu in milli cores and the other resources in units
+func quantityValue(name string, quantity resource.Quantity) int64 {
+	if name == v1.ResourceCPU.String() {
+		return quantity.MilliValue()
+	}
+	return quantity.Value()
+}
+
+func ceilDiv(value, divisor int64) int64 {
+	if divisor <= 0 {
+		return value
+	}
+	return (value + divisor - 1) / divisor
+}
+
+// ValidateTaskGroupDependencies checks that the task groups a task group depends on are defined
+// in the app, a task group cannot depend on itself and the dependencies cannot form a cycle.
+func ValidateTaskGroupDependencies(taskGroups []v1alpha1.TaskGroup) error {
+	dependencies := make(map[string][]string, len(taskGroups))
+	for _, tg := range taskGroups {
+		dependencies[tg.Name] = tg.DependsOn
+	}
+	for _, tg := range taskGroups {
+		for _, dep := range tg.DependsOn {
+			if dep == tg.Name {
+				return fmt.Errorf("taskGroup %s cannot depend on itself", tg.Name)
+			}
+			if _, ok := dependencies[dep]; !ok {
+				return fmt.Errorf("taskGroup %s depends on an undefined taskGroup %s", tg.Name, dep)
+			}
+		}
+	}
+
+	// depth first search, a task group that is reached again while it is visited is part of a cycle
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	marks := make(map[string]int, len(taskGroups))
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch marks[name] {
+		case visiting:
+			return fmt.Errorf("the dependencies of taskGroup %s form a cycle", name)
+		case visited:
+			return nil
+		}
+		marks[name] = visiting
+		for _, dep := range dependencies[name] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		marks[name] = visited
+		return nil
+	}
+	for _, tg := range taskGroups {
+		if err := visit(tg.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func GetPlaceholderTimeoutParam(pod *v1.Pod) (int64, error) {
 	param, ok := pod.Annotations[constants.AnnotationSchedulingPolicyParam]
 	if !ok {
@@ -146,6 +446,99 @@ func GetPlaceholderTimeoutParam(pod *v1.Pod) (int64, error) {
 	return 0, fmt.Errorf("no timeout parameter found")
 }
 
+// returns the task ordering policy defined in the scheduling policy parameters,
+// an empty string is returned if the policy is not defined
+func GetTaskOrderingPolicyParam(pod *v1.Pod) string {
+	param, ok := pod.Annotations[constants.AnnotationSchedulingPolicyParam]
+	if !ok {
+		return ""
+	}
+	params := strings.Split(param, constants.SchedulingPolicyParamDelimiter)
+	for _, p := range params {
+		orderingParam := strings.Split(p, "=")
+		if orderingParam[0] == constants.SchedulingPolicyTaskOrderingParam && len(orderingParam) == 2 {
+			return orderingParam[1]
+		}
+	}
+	return ""
+}
+
+// returns the maximum time the reservation of the app may go without a new placeholder bound,
+// 0 is returned if the timeout is not defined or invalid
+func GetPlaceholderProgressTimeoutParam(pod *v1.Pod) int64 {
+	value, ok := getSchedulingPolicyParam(pod, constants.SchedulingPolicyProgressTimeoutParam)
+	if !ok {
+		return 0
+	}
+	timeout, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || timeout < 0 {
+		return 0
+	}
+	return timeout
+}
+
+// returns the gang scheduling style defined in the scheduling policy parameters: a Soft gang falls back
+// to the normal scheduling when its reservation stalls, a Hard gang fails. The default style is Soft.
+func GetGangSchedulingStyleParam(pod *v1.Pod) string {
+	if value, ok := getSchedulingPolicyParam(pod, constants.SchedulingPolicyStyleParam); ok &&
+		value == constants.SchedulingPolicyStyleHard {
+		return constants.SchedulingPolicyStyleHard
+	}
+	return constants.SchedulingPolicyStyleSoft
+}
+
+// NamespaceGangDefaults are the task groups and the gang policy of the apps of a namespace
+// that do not define task groups of their own
+type NamespaceGangDefaults struct {
+	TaskGroups              []v1alpha1.TaskGroup
+	PlaceholderTimeoutInSec int64
+	ProgressTimeoutInSec    int64
+	GangSchedulingStyle     string // empty when the namespace does not set the style
+}
+
+// GetNamespaceGangDefaults returns the gang defaults of the namespace, set with the same task groups and
+// scheduling policy parameters annotations as a pod. Nil is returned if the namespace has no task groups.
+func GetNamespaceGangDefaults(namespace *v1.Namespace) (*NamespaceGangDefaults, error) {
+	if _, ok := namespace.Annotations[constants.AnnotationTaskGroups]; !ok {
+		return nil, nil
+	}
+	// the annotations are parsed the same way as the annotations of a pod
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: namespace.Annotations,
+		},
+	}
+	taskGroups, err := GetTaskGroupsFromAnnotation(pod)
+	if err != nil || len(taskGroups) == 0 {
+		return nil, err
+	}
+	defaults := &NamespaceGangDefaults{
+		TaskGroups:           taskGroups,
+		ProgressTimeoutInSec: GetPlaceholderProgressTimeoutParam(pod),
+	}
+	if timeout, err := GetPlaceholderTimeoutParam(pod); err == nil && timeout > 0 {
+		defaults.PlaceholderTimeoutInSec = timeout
+	}
+	if _, ok := getSchedulingPolicyParam(pod, constants.SchedulingPolicyStyleParam); ok {
+		defaults.GangSchedulingStyle = GetGangSchedulingStyleParam(pod)
+	}
+	return defaults, nil
+}
+
+func getSchedulingPolicyParam(pod *v1.Pod, name string) (string, bool) {
+	param, ok := pod.Annotations[constants.AnnotationSchedulingPolicyParam]
+	if !ok {
+		return "", false
+	}
+	for _, p := range strings.Split(param, constants.SchedulingPolicyParamDelimiter) {
+		kv := strings.Split(p, "=")
+		if kv[0] == name && len(kv) == 2 {
+			return kv[1], true
+		}
+	}
+	return "", false
+}
+
 type TaskGroupInstanceCountMap struct {
 	counts map[string]int32
 	sync.RWMutex
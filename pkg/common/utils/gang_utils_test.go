@@ -28,6 +28,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/apis/yunikorn.apache.org/v1alpha1"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
 )
 
@@ -73,22 +74,118 @@ func TestFindAppTaskGroup(t *testing.T) {
 
 func TestGeneratePlaceholderName(t *testing.T) {
 	name := GeneratePlaceholderName("my-group", "app0001", 100)
-	assert.Equal(t, name, "tg-my-group-app0001-100")
+	assert.Equal(t, name, "tg-my-group-app0001-148bb9bc-100")
+	// the name does not change between calls
+	assert.Equal(t, GeneratePlaceholderName("my-group", "app0001", 100), name)
 
+	// apps with the same truncated ID do not share the names of their placeholders
 	name = GeneratePlaceholderName("my-group",
 		"app00000000000000000000000000000000000000000001", 100)
-	assert.Equal(t, name, "tg-my-group-app0000000000000000000000000-100")
+	assert.Equal(t, name, "tg-my-group-app00000000000000000-5873cfbc-100")
 	assert.Assert(t, len(name) < 63)
+	name = GeneratePlaceholderName("my-group",
+		"app00000000000000000000000000000000000000000002", 100)
+	assert.Equal(t, name, "tg-my-group-app00000000000000000-933fe4fb-100")
 
 	name = GeneratePlaceholderName("a-very-long-task-group-name------------------------------------------",
-		"a-very-long-app-ID-----------------------------------------------------------------", 100)
-	assert.Equal(t, name, "tg-a-very-long-task-gro-a-very-long-app-ID-----------100")
+		"a-very-long-app-ID-----------------------------------------------------------------", math.MaxInt32)
+	assert.Equal(t, name, "tg-a-very-long-task-a-very-long-app-id---78ee9c75-2147483647")
 	assert.Assert(t, len(name) < 63)
 
-	name = GeneratePlaceholderName("a-very-long-task-group-name------------------------------------------",
-		"a-very-long-app-ID-----------------------------------------------------------------", math.MaxInt32)
-	assert.Equal(t, name, "tg-a-very-long-task-gro-a-very-long-app-ID-----------2147483647")
-	assert.Assert(t, len(name) == 63)
+	// chars not allowed in a pod name are replaced
+	name = GeneratePlaceholderName("Group_1", "spark.App_01", 0)
+	assert.Equal(t, name, "tg-group-1-spark-app-01-5d48f0eb-0")
+
+	// the separator keeps the app and the group apart
+	assert.Assert(t, GetTaskGroupHash("c", "a-b") != GetTaskGroupHash("b-c", "a"))
+}
+
+func TestGenerateProvisioningRequestName(t *testing.T) {
+	name := GenerateProvisioningRequestName("my-group", "app0001")
+	assert.Equal(t, name, "pr-my-group-app0001-148bb9bc")
+	// chars not allowed in a name are replaced, the hash keeps apps with the same truncated ID apart
+	assert.Equal(t, GenerateProvisioningRequestName("Group_1", "spark.App_01"), "pr-group-1-spark-app-01-5d48f0eb")
+	assert.Assert(t, GenerateProvisioningRequestName("my-group", "app00000000000000000000000000000000000000000001") !=
+		GenerateProvisioningRequestName("my-group", "app00000000000000000000000000000000000000000002"))
+}
+
+func TestGetPlaceholderLabels(t *testing.T) {
+	labels := GetPlaceholderLabels("app-1", "root.a", "group-1")
+	assert.DeepEqual(t, labels, map[string]string{
+		constants.LabelApplicationID:   "app-1",
+		constants.LabelQueueName:       "root.a",
+		constants.LabelPlaceholderFlag: "true",
+		constants.LabelTaskGroupName:   "group-1",
+		constants.LabelTaskGroupHash:   GetTaskGroupHash("group-1", "app-1"),
+	})
+
+	// a group name that is not a valid label value is only identified by its hash
+	labels = GetPlaceholderLabels("app-1", "root.a", "group 1")
+	_, ok := labels[constants.LabelTaskGroupName]
+	assert.Assert(t, !ok)
+	assert.Equal(t, labels[constants.LabelTaskGroupHash], GetTaskGroupHash("group 1", "app-1"))
+}
+
+func TestGetPlaceholderSpread(t *testing.T) {
+	// no max per node, no spread
+	slot, affinity := GetPlaceholderSpread("app-1", "group-1", 0, 3)
+	assert.Assert(t, slot == nil)
+	assert.Assert(t, affinity == nil)
+
+	slot, affinity = GetPlaceholderSpread("app-1", "group-1", 2, 3)
+	assert.DeepEqual(t, slot, map[string]string{constants.LabelPlaceholderSlot: "1"})
+	assert.Assert(t, affinity != nil && affinity.PodAntiAffinity != nil)
+	terms := affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	assert.Equal(t, len(terms), 1)
+	assert.Equal(t, terms[0].TopologyKey, v1.LabelHostname)
+	assert.DeepEqual(t, terms[0].LabelSelector.MatchLabels, map[string]string{
+		constants.LabelPlaceholderSlot: "1",
+		constants.LabelTaskGroupHash:   GetTaskGroupHash("group-1", "app-1"),
+	})
+
+	// the placeholders are divided round-robin over the slots
+	slot, _ = GetPlaceholderSpread("app-1", "group-1", 2, 4)
+	assert.Equal(t, slot[constants.LabelPlaceholderSlot], "0")
+}
+
+func TestGetPlaceholderOwner(t *testing.T) {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   GeneratePlaceholderName("group-1", "app-1", 2),
+			Labels: GetPlaceholderLabels("app-1", "root.a", "group-1"),
+			Annotations: map[string]string{
+				constants.AnnotationPlaceholderFlag: "true",
+				constants.AnnotationTaskGroupName:   "group-1",
+				constants.AnnotationTaskGroupIndex:  "2",
+			},
+		},
+	}
+	appID, taskGroupName, index, ok := GetPlaceholderOwner(pod)
+	assert.Assert(t, ok)
+	assert.Equal(t, appID, "app-1")
+	assert.Equal(t, taskGroupName, "group-1")
+	assert.Equal(t, index, int32(2))
+	assert.Assert(t, IsPlaceholderOf(pod, "app-1", "group-1"))
+	assert.Assert(t, !IsPlaceholderOf(pod, "app-1", "group-2"))
+
+	// the annotations are lost, the labels are used
+	pod.Annotations = nil
+	appID, taskGroupName, index, ok = GetPlaceholderOwner(pod)
+	assert.Assert(t, ok)
+	assert.Equal(t, appID, "app-1")
+	assert.Equal(t, taskGroupName, "group-1")
+	assert.Equal(t, index, int32(-1))
+
+	// the group cannot be found
+	delete(pod.Labels, constants.LabelTaskGroupName)
+	_, _, _, ok = GetPlaceholderOwner(pod)
+	assert.Assert(t, !ok)
+
+	// not a placeholder
+	_, _, _, ok = GetPlaceholderOwner(&v1.Pod{})
+	assert.Assert(t, !ok)
+	_, _, _, ok = GetPlaceholderOwner(nil)
+	assert.Assert(t, !ok)
 }
 
 func TestGetTaskGroupFromPodSpec(t *testing.T) {
@@ -120,6 +217,74 @@ func TestGetTaskGroupFromPodSpec(t *testing.T) {
 	}
 
 	assert.Equal(t, GetTaskGroupFromPodSpec(pod), "")
+
+	// only the label of a placeholder is used
+	pod.Labels = map[string]string{constants.LabelTaskGroupName: "test-group-01"}
+	assert.Equal(t, GetTaskGroupFromPodSpec(pod), "")
+	pod.Labels[constants.LabelPlaceholderFlag] = "true"
+	assert.Equal(t, GetTaskGroupFromPodSpec(pod), "test-group-01")
+}
+
+func TestIsNonGangPod(t *testing.T) {
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		nonGang     bool
+	}{
+		{"no annotation", map[string]string{}, false},
+		{"non-gang", map[string]string{constants.AnnotationNonGang: "true"}, true},
+		{"gang", map[string]string{constants.AnnotationNonGang: "false"}, false},
+		{"invalid value", map[string]string{constants.AnnotationNonGang: "yes"}, false},
+		{"placeholder", map[string]string{constants.AnnotationNonGang: "true", constants.AnnotationPlaceholderFlag: "true"}, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.annotations[constants.AnnotationTaskGroupName] = "test-task-group"
+			pod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "pod-01",
+					Annotations: tc.annotations,
+				},
+			}
+			assert.Equal(t, IsNonGangPod(pod), tc.nonGang)
+			// a non-gang pod does not belong to its task group
+			if tc.nonGang {
+				assert.Equal(t, GetTaskGroupFromPodSpec(pod), "")
+			} else {
+				assert.Equal(t, GetTaskGroupFromPodSpec(pod), "test-task-group")
+			}
+		})
+	}
+}
+
+func TestGetTaskGroupIndexFromPodSpec(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    string
+		index    int32
+		hasIndex bool
+	}{
+		{"valid index", "3", 3, true},
+		{"negative index", "-1", -1, false},
+		{"invalid index", "abc", -1, false},
+		{"no index", "", -1, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "pod-01",
+					Annotations: map[string]string{},
+				},
+			}
+			if tc.value != "" {
+				pod.Annotations[constants.AnnotationTaskGroupIndex] = tc.value
+			}
+			index, ok := GetTaskGroupIndexFromPodSpec(pod)
+			assert.Equal(t, index, tc.index)
+			assert.Equal(t, ok, tc.hasIndex)
+		})
+	}
 }
 
 func TestTaskGroupInstanceCountMap(t *testing.T) {
@@ -258,15 +423,11 @@ func TestGetTaskGroupFromAnnotation(t *testing.T) {
 			}
 		}
 	]`
-	// without minMember
+	// without minMember nor minResource
 	testGroupErr3 := `
 	[
 		{
-			"name": "test-group-err-2",
-			"minResource": {
-				"cpu": 2,
-				"memory": "1Gi"
-			}
+			"name": "test-group-err-2"
 		}
 	]`
 	// withot minResource
@@ -285,6 +446,38 @@ func TestGetTaskGroupFromAnnotation(t *testing.T) {
 			"minMember": -100,
 		}
 	]`
+	testGroupErr6 := `
+	[
+		{
+			"name": "test-group-err-6",
+			"minMember": 2,
+			"maxPerNode": -1
+		}
+	]`
+	// without minMember and with an empty minResource
+	testGroupErr7 := `
+	[
+		{
+			"name": "test-group-err-7",
+			"minResource": {
+				"cpu": 0
+			}
+		}
+	]`
+	// without minMember, the min resource is reserved in total
+	testGroup3 := `
+	[
+		{
+			"name": "test-group-4",
+			"minResource": {
+				"cpu": 8,
+				"memory": "16Gi"
+			},
+			"maxPlaceholderResource": {
+				"cpu": 2
+			}
+		}
+	]`
 	// Insert task group info to pod annotation
 	pod := &v1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
@@ -322,6 +515,14 @@ func TestGetTaskGroupFromAnnotation(t *testing.T) {
 	taskGroupErr5, err := GetTaskGroupsFromAnnotation(pod)
 	assert.Assert(t, taskGroupErr5 == nil)
 	assert.Assert(t, err != nil)
+	pod.Annotations = map[string]string{constants.AnnotationTaskGroups: testGroupErr6}
+	taskGroupErr6, err := GetTaskGroupsFromAnnotation(pod)
+	assert.Assert(t, taskGroupErr6 == nil)
+	assert.ErrorContains(t, err, "maxPerNode cannot be negative")
+	pod.Annotations = map[string]string{constants.AnnotationTaskGroups: testGroupErr7}
+	taskGroupErr7, err := GetTaskGroupsFromAnnotation(pod)
+	assert.Assert(t, taskGroupErr7 == nil)
+	assert.ErrorContains(t, err, "must be positive")
 	// Correct case
 	pod.Annotations = map[string]string{constants.AnnotationTaskGroups: testGroup}
 	taskGroups, err := GetTaskGroupsFromAnnotation(pod)
@@ -356,4 +557,193 @@ func TestGetTaskGroupFromAnnotation(t *testing.T) {
 	assert.Equal(t, taskGroups2[0].MinMember, int32(3))
 	assert.Equal(t, taskGroups2[0].MinResource["cpu"], resource.MustParse("2"))
 	assert.Equal(t, taskGroups2[0].MinResource["memory"], resource.MustParse("1Gi"))
+
+	pod.Annotations = map[string]string{constants.AnnotationTaskGroups: testGroup3}
+	taskGroups3, err := GetTaskGroupsFromAnnotation(pod)
+	assert.NilError(t, err)
+	assert.Equal(t, taskGroups3[0].MinMember, int32(0))
+	assert.Assert(t, IsResourceGang(taskGroups3[0]))
+	assert.Equal(t, taskGroups3[0].MaxPlaceholderResource["cpu"], resource.MustParse("2"))
+}
+
+func TestGetPlaceholderTaskGroup(t *testing.T) {
+	// the task groups with min member are unchanged
+	taskGroup := v1alpha1.TaskGroup{
+		Name:        "tg-members",
+		MinMember:   3,
+		MinResource: map[string]resource.Quantity{"cpu": resource.MustParse("1")},
+	}
+	assert.Assert(t, !IsResourceGang(taskGroup))
+	assert.DeepEqual(t, GetPlaceholderTaskGroup(taskGroup), taskGroup)
+
+	// a single placeholder reserves the min resource without a max placeholder resource
+	taskGroup = v1alpha1.TaskGroup{
+		Name: "tg-resources",
+		MinResource: map[string]resource.Quantity{
+			"cpu":    resource.MustParse("5"),
+			"memory": resource.MustParse("10Gi"),
+		},
+	}
+	assert.Assert(t, IsResourceGang(taskGroup))
+	placeholders := GetPlaceholderTaskGroup(taskGroup)
+	assert.Equal(t, placeholders.MinMember, int32(1))
+	assert.Equal(t, quantityValue("cpu", placeholders.MinResource["cpu"]), int64(5000))
+	assert.Equal(t, quantityValue("memory", placeholders.MinResource["memory"]), int64(10*1024*1024*1024))
+
+	// the placeholders are not larger than the max, the sizes are rounded up
+	taskGroup.MaxPlaceholderResource = map[string]resource.Quantity{
+		"cpu":    resource.MustParse("2"),
+		"memory": resource.MustParse("8Gi"),
+	}
+	placeholders = GetPlaceholderTaskGroup(taskGroup)
+	assert.Equal(t, placeholders.MinMember, int32(3))
+	assert.Equal(t, quantityValue("cpu", placeholders.MinResource["cpu"]), int64(1667))
+	assert.Equal(t, quantityValue("memory", placeholders.MinResource["memory"]), int64((10*1024*1024*1024+2)/3))
+	// the task group is not changed
+	assert.Equal(t, taskGroup.MinMember, int32(0))
+	assert.Equal(t, quantityValue("cpu", taskGroup.MinResource["cpu"]), int64(5000))
+}
+
+func TestGetReservationProgressParams(t *testing.T) {
+	pod := &v1.Pod{}
+	assert.Equal(t, GetPlaceholderProgressTimeoutParam(pod), int64(0))
+	assert.Equal(t, GetGangSchedulingStyleParam(pod), constants.SchedulingPolicyStyleSoft)
+
+	pod.Annotations = map[string]string{
+		constants.AnnotationSchedulingPolicyParam: "placeholderTimeoutInSeconds=300 placeholderProgressTimeoutInSeconds=60 gangSchedulingStyle=Hard",
+	}
+	assert.Equal(t, GetPlaceholderProgressTimeoutParam(pod), int64(60))
+	assert.Equal(t, GetGangSchedulingStyleParam(pod), constants.SchedulingPolicyStyleHard)
+
+	// invalid values fall back to the defaults
+	pod.Annotations = map[string]string{
+		constants.AnnotationSchedulingPolicyParam: "placeholderProgressTimeoutInSeconds=-1 gangSchedulingStyle=Strict",
+	}
+	assert.Equal(t, GetPlaceholderProgressTimeoutParam(pod), int64(0))
+	assert.Equal(t, GetGangSchedulingStyleParam(pod), constants.SchedulingPolicyStyleSoft)
+	pod.Annotations[constants.AnnotationSchedulingPolicyParam] = "placeholderProgressTimeoutInSeconds=abc"
+	assert.Equal(t, GetPlaceholderProgressTimeoutParam(pod), int64(0))
+}
+
+func TestGetTaskOrderingPolicyParam(t *testing.T) {
+	pod := &v1.Pod{}
+	assert.Equal(t, GetTaskOrderingPolicyParam(pod), "")
+
+	pod.Annotations = map[string]string{
+		constants.AnnotationSchedulingPolicyParam: "placeholderTimeoutInSeconds=30 taskOrderingPolicy=Priority",
+	}
+	assert.Equal(t, GetTaskOrderingPolicyParam(pod), "Priority")
+
+	pod.Annotations = map[string]string{
+		constants.AnnotationSchedulingPolicyParam: "placeholderTimeoutInSeconds=30",
+	}
+	assert.Equal(t, GetTaskOrderingPolicyParam(pod), "")
+
+	pod.Annotations = map[string]string{
+		constants.AnnotationSchedulingPolicyParam: "taskOrderingPolicy",
+	}
+	assert.Equal(t, GetTaskOrderingPolicyParam(pod), "")
+}
+
+func TestValidateTaskGroupDependencies(t *testing.T) {
+	// no dependencies
+	taskGroups := []v1alpha1.TaskGroup{
+		{Name: "a", MinMember: 1},
+		{Name: "b", MinMember: 1},
+	}
+	assert.NilError(t, ValidateTaskGroupDependencies(taskGroups))
+
+	// c depends on b, b depends on a
+	taskGroups = []v1alpha1.TaskGroup{
+		{Name: "c", MinMember: 1, DependsOn: []string{"b"}},
+		{Name: "a", MinMember: 1},
+		{Name: "b", MinMember: 1, DependsOn: []string{"a"}},
+	}
+	assert.NilError(t, ValidateTaskGroupDependencies(taskGroups))
+
+	// undefined task group
+	taskGroups = []v1alpha1.TaskGroup{
+		{Name: "a", MinMember: 1, DependsOn: []string{"x"}},
+	}
+	assert.ErrorContains(t, ValidateTaskGroupDependencies(taskGroups), "undefined taskGroup x")
+
+	// self dependency
+	taskGroups = []v1alpha1.TaskGroup{
+		{Name: "a", MinMember: 1, DependsOn: []string{"a"}},
+	}
+	assert.ErrorContains(t, ValidateTaskGroupDependencies(taskGroups), "cannot depend on itself")
+
+	// cycle
+	taskGroups = []v1alpha1.TaskGroup{
+		{Name: "a", MinMember: 1, DependsOn: []string{"c"}},
+		{Name: "b", MinMember: 1, DependsOn: []string{"a"}},
+		{Name: "c", MinMember: 1, DependsOn: []string{"b"}},
+	}
+	assert.ErrorContains(t, ValidateTaskGroupDependencies(taskGroups), "form a cycle")
+}
+
+func TestGetNamespaceGangDefaults(t *testing.T) {
+	namespace := &v1.Namespace{}
+	defaults, err := GetNamespaceGangDefaults(namespace)
+	assert.NilError(t, err)
+	assert.Assert(t, defaults == nil)
+
+	namespace.Annotations = map[string]string{
+		constants.AnnotationTaskGroups: `[{"name": "tg", "minMember": 0}]`,
+	}
+	_, err = GetNamespaceGangDefaults(namespace)
+	assert.ErrorContains(t, err, "MinMember")
+
+	namespace.Annotations = map[string]string{
+		constants.AnnotationTaskGroups:            `[{"name": "tg", "minMember": 3}]`,
+		constants.AnnotationSchedulingPolicyParam: "placeholderTimeoutInSeconds=60 placeholderProgressTimeoutInSeconds=20",
+	}
+	defaults, err = GetNamespaceGangDefaults(namespace)
+	assert.NilError(t, err)
+	assert.Equal(t, len(defaults.TaskGroups), 1)
+	assert.Equal(t, defaults.PlaceholderTimeoutInSec, int64(60))
+	assert.Equal(t, defaults.ProgressTimeoutInSec, int64(20))
+	assert.Equal(t, defaults.GangSchedulingStyle, "")
+
+	namespace.Annotations[constants.AnnotationSchedulingPolicyParam] = "gangSchedulingStyle=Hard"
+	defaults, err = GetNamespaceGangDefaults(namespace)
+	assert.NilError(t, err)
+	assert.Equal(t, defaults.PlaceholderTimeoutInSec, int64(0))
+	assert.Equal(t, defaults.GangSchedulingStyle, constants.SchedulingPolicyStyleHard)
+}
+
+func TestGetPlaceholderResource(t *testing.T) {
+	common.SetRuntimeClassOverheadResolver(func(runtimeClassName string) v1.ResourceList {
+		if runtimeClassName != "kata" {
+			return nil
+		}
+		return v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("250m"),
+			v1.ResourceMemory: resource.MustParse("1Mi"),
+		}
+	})
+	defer common.SetRuntimeClassOverheadResolver(nil)
+
+	minResource := map[string]resource.Quantity{
+		"cpu":            resource.MustParse("500m"),
+		"memory":         resource.MustParse("500Mi"),
+		"nvidia.com/gpu": resource.MustParse("1"),
+	}
+	res := GetPlaceholderResource(minResource, "", 10)
+	assert.Equal(t, res.Resources[constants.CPU].GetValue(), int64(5000))
+	assert.Equal(t, res.Resources[constants.Memory].GetValue(), int64(5250))
+	assert.Equal(t, res.Resources["nvidia.com/gpu"].GetValue(), int64(10))
+
+	// the overhead is rounded together with the requests, the same as for the placeholder pod:
+	// 501Mi is 526M while 500Mi and 1Mi rounded apart are 525M and 2M
+	res = GetPlaceholderResource(minResource, "kata", 10)
+	assert.Equal(t, res.Resources[constants.CPU].GetValue(), int64(7500))
+	assert.Equal(t, res.Resources[constants.Memory].GetValue(), int64(5260))
+
+	// a best effort placeholder asks for the minimum memory of 1 on top of the overhead
+	res = GetPlaceholderResource(nil, "", 3)
+	assert.Equal(t, res.Resources[constants.Memory].GetValue(), int64(3))
+	res = GetPlaceholderResource(nil, "kata", 3)
+	assert.Equal(t, res.Resources[constants.CPU].GetValue(), int64(750))
+	assert.Equal(t, res.Resources[constants.Memory].GetValue(), int64(9))
 }
@@ -0,0 +1,124 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package utils
+
+import (
+	"encoding/json"
+	"sync"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+)
+
+// UserGroup is the user and the groups an application is submitted as
+type UserGroup struct {
+	User   string   `json:"user"`
+	Groups []string `json:"groups,omitempty"`
+}
+
+// UserResolver resolves the user and the groups from the pod of an application,
+// the scheduler core enforces the queue ACLs and quotas against them.
+type UserResolver interface {
+	Resolve(pod *v1.Pod) UserGroup
+}
+
+var userResolvers = map[string]UserResolver{
+	conf.UserResolverLabel:      labelUserResolver{},
+	conf.UserResolverAnnotation: annotationUserResolver{},
+}
+var userResolverLock sync.RWMutex
+
+// RegisterUserResolver registers a user resolver with the given name, the resolver
+// can then be selected with the userResolver option. a registered resolver with the same name is replaced.
+func RegisterUserResolver(name string, resolver UserResolver) {
+	userResolverLock.Lock()
+	defer userResolverLock.Unlock()
+	userResolvers[name] = resolver
+}
+
+func getUserResolver(name string) (UserResolver, bool) {
+	userResolverLock.RLock()
+	defer userResolverLock.RUnlock()
+	resolver, ok := userResolvers[name]
+	return resolver, ok
+}
+
+// GetUserGroupFromPod resolves the user and the groups of the pod with the configured resolver,
+// the label resolver is used if the configured one is unknown.
+func GetUserGroupFromPod(pod *v1.Pod) UserGroup {
+	name := conf.GetSchedulerConf().UserResolver
+	resolver, ok := getUserResolver(name)
+	if !ok {
+		if name != "" {
+			log.Logger().Warn("unknown user resolver, the user is read from the pod labels",
+				zap.String("userResolver", name))
+		}
+		resolver = labelUserResolver{}
+	}
+	return resolver.Resolve(pod)
+}
+
+// labelUserResolver reads the user from a pod label, the label is set by the submitter and can be spoofed
+type labelUserResolver struct{}
+
+func (labelUserResolver) Resolve(pod *v1.Pod) UserGroup {
+	userLabelKey := conf.GetSchedulerConf().UserLabelKey
+	// User name to be defined in labels
+	for name, value := range pod.Labels {
+		if name == userLabelKey {
+			log.Logger().Info("Found user name from pod labels.",
+				zap.String("userLabel", userLabelKey), zap.String("user", value))
+			return UserGroup{User: value}
+		}
+	}
+
+	log.Logger().Info("Unable to retrieve user name from pod labels. Empty user label",
+		zap.String("userLabel", constants.DefaultUserLabel))
+
+	return UserGroup{User: constants.DefaultUser}
+}
+
+// annotationUserResolver reads the user and the groups from the annotation injected by the admission controller,
+// the annotation holds the authenticated user of the request that created the pod. The user label is not used
+// as a fallback, a pod without a valid annotation is submitted as the default user.
+type annotationUserResolver struct{}
+
+func (annotationUserResolver) Resolve(pod *v1.Pod) UserGroup {
+	value, ok := pod.Annotations[constants.AnnotationUserInfo]
+	if !ok {
+		log.Logger().Warn("pod has no user info annotation, the default user is used",
+			zap.String("namespace", pod.Namespace),
+			zap.String("name", pod.Name))
+		return UserGroup{User: constants.DefaultUser}
+	}
+	var userGroup UserGroup
+	if err := json.Unmarshal([]byte(value), &userGroup); err != nil || userGroup.User == "" {
+		log.Logger().Warn("invalid user info annotation, the default user is used",
+			zap.String("namespace", pod.Namespace),
+			zap.String("name", pod.Name),
+			zap.String("userInfo", value),
+			zap.Error(err))
+		return UserGroup{User: constants.DefaultUser}
+	}
+	return userGroup
+}
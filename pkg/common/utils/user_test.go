@@ -0,0 +1,77 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package utils
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+)
+
+type fixedUserResolver struct{}
+
+func (fixedUserResolver) Resolve(pod *v1.Pod) UserGroup {
+	return UserGroup{User: "fixed", Groups: []string{"fixed-group"}}
+}
+
+func TestGetUserGroupFromPod(t *testing.T) {
+	schedulerConf := conf.GetSchedulerConf()
+	resolver := schedulerConf.UserResolver
+	defer func() {
+		schedulerConf.UserResolver = resolver
+	}()
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{constants.DefaultUserLabel: "label-user"},
+			Annotations: map[string]string{
+				constants.AnnotationUserInfo: `{"user":"alice","groups":["dev","system:authenticated"]}`,
+			},
+		},
+	}
+
+	// the label resolver ignores the annotation
+	schedulerConf.UserResolver = conf.UserResolverLabel
+	assert.DeepEqual(t, GetUserGroupFromPod(pod), UserGroup{User: "label-user"})
+
+	// the annotation resolver ignores the label
+	schedulerConf.UserResolver = conf.UserResolverAnnotation
+	assert.DeepEqual(t, GetUserGroupFromPod(pod), UserGroup{User: "alice", Groups: []string{"dev", "system:authenticated"}})
+	assert.Equal(t, GetUserFromPod(pod), "alice")
+
+	// an invalid or missing annotation falls back to the default user, not to the label
+	pod.Annotations[constants.AnnotationUserInfo] = `{"groups":["dev"]}`
+	assert.DeepEqual(t, GetUserGroupFromPod(pod), UserGroup{User: constants.DefaultUser})
+	pod.Annotations[constants.AnnotationUserInfo] = "alice"
+	assert.DeepEqual(t, GetUserGroupFromPod(pod), UserGroup{User: constants.DefaultUser})
+	delete(pod.Annotations, constants.AnnotationUserInfo)
+	assert.DeepEqual(t, GetUserGroupFromPod(pod), UserGroup{User: constants.DefaultUser})
+
+	// an unknown resolver falls back to the label resolver
+	schedulerConf.UserResolver = "fixed"
+	assert.DeepEqual(t, GetUserGroupFromPod(pod), UserGroup{User: "label-user"})
+
+	// a registered resolver is used once configured
+	RegisterUserResolver("fixed", fixedUserResolver{})
+	assert.DeepEqual(t, GetUserGroupFromPod(pod), UserGroup{User: "fixed", Groups: []string{"fixed-group"}})
+}
@@ -19,12 +19,12 @@
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
-	"go.uber.org/zap"
-
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -33,7 +33,6 @@ import (
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
-	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
 
@@ -69,13 +68,30 @@ func IsPodTerminated(pod *v1.Pod) bool {
 	return pod.Status.Phase == v1.PodFailed || pod.Status.Phase == v1.PodSucceeded
 }
 
+// IsTrackedByJob returns true if the Job controller accounts for the termination of the pod with its finalizer
+func IsTrackedByJob(pod *v1.Pod) bool {
+	for _, finalizer := range pod.Finalizers {
+		if finalizer == constants.JobTrackingFinalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTerminatingTrackedPod returns true if the pod is deleted and waits for the Job controller to account for it
+func IsTerminatingTrackedPod(pod *v1.Pod) bool {
+	return pod.DeletionTimestamp != nil && IsTrackedByJob(pod)
+}
+
 // assignedPod selects pods that are assigned (scheduled and running).
 func IsAssignedPod(pod *v1.Pod) bool {
 	return len(pod.Spec.NodeName) != 0
 }
 
+// GeneralPodFilter selects the pods scheduled by the scheduler: the pods of yunikorn and of the other
+// scheduler names it is configured to take over
 func GeneralPodFilter(pod *v1.Pod) bool {
-	return strings.Compare(pod.Spec.SchedulerName, constants.SchedulerName) == 0
+	return conf.GetSchedulerConf().IsSchedulerName(pod.Spec.SchedulerName)
 }
 
 func GetQueueNameFromPod(pod *v1.Pod) string {
@@ -86,6 +102,18 @@ func GetQueueNameFromPod(pod *v1.Pod) string {
 	return queueName
 }
 
+// GetClusterIDFromPod returns the cluster the app of the pod is routed to in federation mode,
+// an empty string means the app is scheduled by the cluster the shim is deployed in.
+func GetClusterIDFromPod(pod *v1.Pod) string {
+	return pod.Annotations[constants.AnnotationClusterID]
+}
+
+// GetPartitionFromPod returns the partition the app of the pod is submitted to,
+// an empty string means the default partition.
+func GetPartitionFromPod(pod *v1.Pod) string {
+	return pod.Annotations[constants.AnnotationPartition]
+}
+
 func GetApplicationIDFromPod(pod *v1.Pod) (string, error) {
 	// application ID can be defined in annotations
 	for name, value := range pod.Annotations {
@@ -111,6 +139,42 @@ func GetApplicationIDFromPod(pod *v1.Pod) (string, error) {
 		pod.Spec.String())
 }
 
+// GetApplicationIDConflicts returns the application IDs of the pod that name another app than the one it is
+// added to. The app-id annotation wins over the applicationId label, which wins over the spark-app-selector label.
+func GetApplicationIDConflicts(pod *v1.Pod) []string {
+	sources := []struct {
+		kind  string
+		name  string
+		value string
+		ok    bool
+	}{
+		{kind: "annotation", name: constants.AnnotationApplicationID},
+		{kind: "label", name: constants.LabelApplicationID},
+		{kind: "label", name: constants.SparkLabelAppID},
+	}
+	sources[0].value, sources[0].ok = pod.Annotations[constants.AnnotationApplicationID]
+	sources[1].value, sources[1].ok = pod.Labels[constants.LabelApplicationID]
+	sources[2].value, sources[2].ok = pod.Labels[constants.SparkLabelAppID]
+
+	conflicts := make([]string, 0)
+	winner := -1
+	for i, source := range sources {
+		if !source.ok {
+			continue
+		}
+		if winner == -1 {
+			winner = i
+			continue
+		}
+		if source.value != sources[winner].value {
+			conflicts = append(conflicts, fmt.Sprintf("%s %s=%s conflicts with %s %s=%s",
+				source.kind, source.name, source.value,
+				sources[winner].kind, sources[winner].name, sources[winner].value))
+		}
+	}
+	return conflicts
+}
+
 // compare the existing pod condition with the given one, return true if the pod condition remains not changed.
 // return false if pod has no condition set yet, or condition has changed.
 func PodUnderCondition(pod *v1.Pod, condition *v1.PodCondition) bool {
@@ -223,21 +287,134 @@ func MergeMaps(first, second map[string]string) map[string]string {
 	return result
 }
 
-// find user name from pod label
+// find user name from pod, the user is resolved by the configured user resolver
 func GetUserFromPod(pod *v1.Pod) string {
-	userLabelKey := conf.GetSchedulerConf().UserLabelKey
-	// User name to be defined in labels
-	for name, value := range pod.Labels {
-		if name == userLabelKey {
-			log.Logger().Info("Found user name from pod labels.",
-				zap.String("userLabel", userLabelKey), zap.String("user", value))
-			return value
+	return GetUserGroupFromPod(pod).User
+}
+
+// GetSchedulingGates returns the scheduling gates of the pod, the shim holds a pod
+// until all its gates are removed. The K8s API version in use does not expose
+// spec.schedulingGates, the gates are carried by an annotation instead.
+func GetSchedulingGates(pod *v1.Pod) []string {
+	gates := make([]string, 0)
+	value, ok := pod.Annotations[constants.AnnotationSchedulingGates]
+	if !ok {
+		return gates
+	}
+	for _, gate := range strings.Split(value, constants.SchedulingGatesDelimiter) {
+		if gate = strings.TrimSpace(gate); gate != "" {
+			gates = append(gates, gate)
 		}
 	}
-	value := constants.DefaultUser
+	return gates
+}
 
-	log.Logger().Info("Unable to retrieve user name from pod labels. Empty user label",
-		zap.String("userLabel", constants.DefaultUserLabel))
+// SetSchedulingGates sets the scheduling gates of the pod,
+// the annotation is removed when there is no gate left.
+func SetSchedulingGates(pod *v1.Pod, gates []string) {
+	if len(gates) == 0 {
+		delete(pod.Annotations, constants.AnnotationSchedulingGates)
+		return
+	}
+	if pod.Annotations == nil {
+		pod.Annotations = make(map[string]string)
+	}
+	pod.Annotations[constants.AnnotationSchedulingGates] = strings.Join(gates, constants.SchedulingGatesDelimiter)
+}
 
-	return value
+// GetConsumeProvisioningRequestPatch returns the merge patch tying a pod to the ProvisioningRequest whose capacity
+// it consumes, the annotations are removed when the name is empty.
+func GetConsumeProvisioningRequestPatch(name string) ([]byte, error) {
+	var value interface{}
+	if name != "" {
+		value = name
+	}
+	return json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				constants.AnnotationConsumeProvisioningRequest:       value,
+				constants.AnnotationLegacyConsumeProvisioningRequest: value,
+			},
+		},
+	})
+}
+
+// RemoveSchedulingGate returns the gates without the given gate
+func RemoveSchedulingGate(gates []string, gate string) []string {
+	result := make([]string, 0, len(gates))
+	for _, g := range gates {
+		if g != gate {
+			result = append(result, g)
+		}
+	}
+	return result
+}
+
+// GetPreferredNodes returns the nodes the pod prefers to run on, e.g. the nodes a previous run of a
+// recurring job used. Empty entries and duplicates are ignored, the order of the annotation is kept.
+func GetPreferredNodes(pod *v1.Pod) []string {
+	nodes := make([]string, 0)
+	value, ok := pod.Annotations[constants.AnnotationPreferredNodes]
+	if !ok {
+		return nodes
+	}
+	seen := make(map[string]bool)
+	for _, node := range strings.Split(value, constants.PreferredNodesDelimiter) {
+		if node = strings.TrimSpace(node); node != "" && !seen[node] {
+			seen[node] = true
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// GetMaxParallelTasks returns the maximum number of tasks of the app of the pod being scheduled at a time,
+// 0 means no limit. A missing, invalid or negative annotation value is treated as no limit.
+func GetMaxParallelTasks(pod *v1.Pod) int {
+	value, ok := pod.Annotations[constants.AnnotationMaxParallelTasks]
+	if !ok {
+		return 0
+	}
+	maxTasks, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || maxTasks < 0 {
+		return 0
+	}
+	return maxTasks
+}
+
+// GetMaxReservingApps returns the maximum number of apps of the queue of the pod reserving at the same time,
+// 0 means no limit. A missing, invalid or negative annotation value is treated as no limit.
+func GetMaxReservingApps(pod *v1.Pod) int {
+	value, ok := pod.Annotations[constants.AnnotationMaxReservingApps]
+	if !ok {
+		return 0
+	}
+	maxApps, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || maxApps < 0 {
+		return 0
+	}
+	return maxApps
+}
+
+// GetRuntimeClassName returns the runtime class of the pod, empty if the pod runs with the default runtime
+func GetRuntimeClassName(pod *v1.Pod) string {
+	if pod.Spec.RuntimeClassName == nil {
+		return ""
+	}
+	return *pod.Spec.RuntimeClassName
+}
+
+// GetTaskQueue returns the queue the task of the pod is scheduled in when it is not the queue of its app,
+// e.g. the driver of a job in a high priority queue and its workers in a batch queue. Empty means the app queue.
+func GetTaskQueue(pod *v1.Pod) string {
+	return strings.TrimSpace(pod.Annotations[constants.AnnotationTaskQueue])
+}
+
+// JoinWithLimit joins the first limit items, the number of items left out is appended,
+// this keeps the diagnostics about large clusters readable in the logs.
+func JoinWithLimit(items []string, limit int) string {
+	if len(items) <= limit {
+		return strings.Join(items, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(items[:limit], ", "), len(items)-limit)
 }
@@ -29,6 +29,7 @@ import (
 
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
 
@@ -332,6 +333,44 @@ func TestGetApplicationIDFromPod(t *testing.T) {
 	}
 }
 
+func TestGetApplicationIDConflicts(t *testing.T) {
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		labels      map[string]string
+		expected    []string
+	}{
+		{"no app ID", nil, nil, []string{}},
+		{"single app ID", nil, map[string]string{constants.LabelApplicationID: "app-1"}, []string{}},
+		{"same app ID", map[string]string{constants.AnnotationApplicationID: "app-1"},
+			map[string]string{constants.LabelApplicationID: "app-1", constants.SparkLabelAppID: "app-1"}, []string{}},
+		{"annotation and label", map[string]string{constants.AnnotationApplicationID: "app-1"},
+			map[string]string{constants.LabelApplicationID: "app-2"},
+			[]string{"label applicationId=app-2 conflicts with annotation yunikorn.apache.org/app-id=app-1"}},
+		{"label and spark selector", nil,
+			map[string]string{constants.LabelApplicationID: "app-1", constants.SparkLabelAppID: "spark-1"},
+			[]string{"label spark-app-selector=spark-1 conflicts with label applicationId=app-1"}},
+		{"all different", map[string]string{constants.AnnotationApplicationID: "app-1"},
+			map[string]string{constants.LabelApplicationID: "app-2", constants.SparkLabelAppID: "spark-1"},
+			[]string{
+				"label applicationId=app-2 conflicts with annotation yunikorn.apache.org/app-id=app-1",
+				"label spark-app-selector=spark-1 conflicts with annotation yunikorn.apache.org/app-id=app-1",
+			}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: tc.annotations,
+					Labels:      tc.labels,
+				},
+			}
+			assert.DeepEqual(t, GetApplicationIDConflicts(pod), tc.expected)
+		})
+	}
+}
+
 func TestMergeMaps(t *testing.T) {
 	result := MergeMaps(nil, nil)
 	assert.Assert(t, result == nil)
@@ -391,3 +430,151 @@ func TestGetUserFromPod(t *testing.T) {
 		})
 	}
 }
+
+func TestSchedulingGates(t *testing.T) {
+	pod := &v1.Pod{}
+	assert.Equal(t, len(GetSchedulingGates(pod)), 0)
+
+	SetSchedulingGates(pod, []string{"gate-1", "gate-2"})
+	assert.Equal(t, pod.Annotations[constants.AnnotationSchedulingGates], "gate-1,gate-2")
+	assert.DeepEqual(t, GetSchedulingGates(pod), []string{"gate-1", "gate-2"})
+
+	// empty entries and spaces are ignored
+	pod.Annotations[constants.AnnotationSchedulingGates] = " gate-1, ,gate-2,"
+	gates := GetSchedulingGates(pod)
+	assert.DeepEqual(t, gates, []string{"gate-1", "gate-2"})
+
+	gates = RemoveSchedulingGate(gates, "gate-1")
+	assert.DeepEqual(t, gates, []string{"gate-2"})
+	gates = RemoveSchedulingGate(gates, "gate-3")
+	assert.DeepEqual(t, gates, []string{"gate-2"})
+
+	// the annotation is removed when no gate is left
+	SetSchedulingGates(pod, RemoveSchedulingGate(gates, "gate-2"))
+	_, ok := pod.Annotations[constants.AnnotationSchedulingGates]
+	assert.Assert(t, !ok)
+}
+
+func TestGetConsumeProvisioningRequestPatch(t *testing.T) {
+	patch, err := GetConsumeProvisioningRequestPatch("pr-1")
+	assert.NilError(t, err)
+	assert.Equal(t, string(patch), `{"metadata":{"annotations":{"`+
+		constants.AnnotationConsumeProvisioningRequest+`":"pr-1","`+
+		constants.AnnotationLegacyConsumeProvisioningRequest+`":"pr-1"}}}`)
+
+	// the annotations are removed without a request
+	patch, err = GetConsumeProvisioningRequestPatch("")
+	assert.NilError(t, err)
+	assert.Equal(t, string(patch), `{"metadata":{"annotations":{"`+
+		constants.AnnotationConsumeProvisioningRequest+`":null,"`+
+		constants.AnnotationLegacyConsumeProvisioningRequest+`":null}}}`)
+}
+
+func TestGeneralPodFilter(t *testing.T) {
+	newPod := func(schedulerName string) *v1.Pod {
+		return &v1.Pod{Spec: v1.PodSpec{SchedulerName: schedulerName}}
+	}
+	assert.Assert(t, GeneralPodFilter(newPod(constants.SchedulerName)))
+	assert.Assert(t, !GeneralPodFilter(newPod("default-scheduler")))
+
+	// take over the pods of the default scheduler
+	conf.GetSchedulerConf().SchedulerNames = "default-scheduler"
+	defer func() { conf.GetSchedulerConf().SchedulerNames = "" }()
+	assert.Assert(t, GeneralPodFilter(newPod(constants.SchedulerName)))
+	assert.Assert(t, GeneralPodFilter(newPod("default-scheduler")))
+	assert.Assert(t, !GeneralPodFilter(newPod("other-scheduler")))
+}
+
+func TestIsTrackedByJob(t *testing.T) {
+	pod := &v1.Pod{}
+	assert.Assert(t, !IsTrackedByJob(pod))
+	assert.Assert(t, !IsTerminatingTrackedPod(pod))
+
+	pod.Finalizers = []string{"example.com/other", constants.JobTrackingFinalizer}
+	assert.Assert(t, IsTrackedByJob(pod))
+	assert.Assert(t, !IsTerminatingTrackedPod(pod))
+
+	now := metav1.Now()
+	pod.DeletionTimestamp = &now
+	assert.Assert(t, IsTerminatingTrackedPod(pod))
+	pod.Finalizers = nil
+	assert.Assert(t, !IsTerminatingTrackedPod(pod))
+}
+
+func TestGetPreferredNodes(t *testing.T) {
+	pod := &v1.Pod{}
+	assert.Equal(t, len(GetPreferredNodes(pod)), 0)
+
+	// empty entries, spaces and duplicates are ignored
+	pod.Annotations = map[string]string{
+		constants.AnnotationPreferredNodes: " node-2, ,node-1,node-2,",
+	}
+	assert.DeepEqual(t, GetPreferredNodes(pod), []string{"node-2", "node-1"})
+}
+
+func TestGetTaskQueue(t *testing.T) {
+	pod := &v1.Pod{}
+	assert.Equal(t, GetTaskQueue(pod), "")
+	pod.Annotations = map[string]string{
+		constants.AnnotationTaskQueue: " root.batch ",
+	}
+	assert.Equal(t, GetTaskQueue(pod), "root.batch")
+}
+
+func TestGetMaxParallelTasks(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    string
+		expected int
+	}{
+		{"not set", "", 0},
+		{"valid", " 5 ", 5},
+		{"invalid", "five", 0},
+		{"negative", "-1", 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := &v1.Pod{}
+			if tc.value != "" {
+				pod.Annotations = map[string]string{constants.AnnotationMaxParallelTasks: tc.value}
+			}
+			assert.Equal(t, GetMaxParallelTasks(pod), tc.expected)
+		})
+	}
+}
+
+func TestGetMaxReservingApps(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    string
+		expected int
+	}{
+		{"not set", "", 0},
+		{"valid", " 3 ", 3},
+		{"invalid", "three", 0},
+		{"negative", "-1", 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := &v1.Pod{}
+			if tc.value != "" {
+				pod.Annotations = map[string]string{constants.AnnotationMaxReservingApps: tc.value}
+			}
+			assert.Equal(t, GetMaxReservingApps(pod), tc.expected)
+		})
+	}
+}
+
+func TestGetRuntimeClassName(t *testing.T) {
+	pod := &v1.Pod{}
+	assert.Equal(t, GetRuntimeClassName(pod), "")
+	kata := "kata"
+	pod.Spec.RuntimeClassName = &kata
+	assert.Equal(t, GetRuntimeClassName(pod), "kata")
+}
+
+func TestJoinWithLimit(t *testing.T) {
+	assert.Equal(t, JoinWithLimit(nil, 2), "")
+	assert.Equal(t, JoinWithLimit([]string{"a", "b"}, 2), "a, b")
+	assert.Equal(t, JoinWithLimit([]string{"a", "b", "c", "d"}, 2), "a, b and 2 more")
+}
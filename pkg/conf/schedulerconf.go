@@ -19,8 +19,10 @@
 package conf
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -45,30 +47,180 @@ const (
 	DefaultDispatchTimeout      = 300 * time.Second
 	DefaultKubeQPS              = 1000
 	DefaultKubeBurst            = 1000
+	DefaultWebServicePort       = 9089
+	DefaultPlaceholderWorkers   = 10
+	DefaultPlaceholderJanitor   = 5 * time.Minute
+	DefaultAppInformerResync    = time.Minute
+	DefaultKubeListPageSize     = 500
+	DefaultKillDeletionWorkers  = 10
+	DefaultKillDeletionQPS      = 100
+	DefaultKillDeletionOrder    = KillOrderPlaceholder + "," + KillOrderWorker + "," + KillOrderDriver
+	DefaultKillGracePeriod      = 30 * time.Second
+	DefaultKillTimeout          = 5 * time.Minute
+	DefaultBindWorkers          = 16
+	DefaultRecoveryTimeout      = 6 * time.Minute
+	DefaultAppTombstone         = 5 * time.Minute
+	DefaultFallbackQueue        = "root.default"
+	DefaultUpdateBatchSize      = 500
+	DefaultUpdateQueueSize      = 64
+	DefaultCoreWebAddress       = "localhost:9080"
+	DefaultPlaceholderTimeout   = 15 * time.Minute
+	DefaultReservationGrace     = time.Minute
+	DefaultRecoveryBatchSize    = 5000
+	DefaultEventAuditSize       = 1000
+	DefaultGangStatusInterval   = 30 * time.Second
+	DefaultScheduleWorkers      = 8
+	DefaultScheduleTaskBudget   = 1000
+	DefaultProvisioningClass    = "best-effort-atomic-scale-up.autoscaling.x-k8s.io"
+	DefaultBurstFactor          = 4
+)
+
+// policies applied to a task that is not scheduled within the task scheduling timeout
+const (
+	TaskSchedulingTimeoutRetry = "Retry"
+	TaskSchedulingTimeoutFail  = "Fail"
+)
+
+// resolvers of the user and the groups an app is submitted as
+const (
+	UserResolverLabel      = "label"
+	UserResolverAnnotation = "annotation"
+)
+
+// policies applied to the placeholders of an app when some of them failed to be created
+const (
+	PlaceholderRollbackAll       = "All"
+	PlaceholderRollbackTaskGroup = "TaskGroup"
+)
+
+// policies deciding when an app moves to Completed
+const (
+	AppCompletionManual             = "manual"
+	AppCompletionAllTasksTerminated = "allTasksTerminated"
+)
+
+// policies applied to an app rejected by the core because its queue does not exist
+const (
+	UnknownQueueFail     = "Fail"
+	UnknownQueueFallback = "Fallback"
+	UnknownQueueCreate   = "Create"
+)
+
+// kinds of pods deleted in stages when an app is killed
+const (
+	KillOrderPlaceholder = "placeholder"
+	KillOrderWorker      = "worker"
+	KillOrderDriver      = "driver"
+)
+
+// policies selecting the pods deleted for the allocations preempted by the core
+const (
+	VictimSelectionCore         = "core"
+	VictimSelectionDeletionCost = "deletionCost"
+)
+
+// policies applied to a real task whose pending ask is released by the scheduler
+const (
+	ReleasedAskSkip    = "Skip"
+	ReleasedAskFail    = "Fail"
+	ReleasedAskRequeue = "Requeue"
+)
+
+// conversions of the memory quantities of the pods to the values sent to the scheduler
+const (
+	MemoryConversionMegabytes = "megabytes"
+	MemoryConversionBytes     = "bytes"
 )
 
 var once sync.Once
 var configuration *SchedulerConf
 
 type SchedulerConf struct {
-	ClusterID              string        `json:"clusterId"`
-	ClusterVersion         string        `json:"clusterVersion"`
-	PolicyGroup            string        `json:"policyGroup"`
-	Interval               time.Duration `json:"schedulingIntervalSecond"`
-	KubeConfig             string        `json:"absoluteKubeConfigFilePath"`
-	LoggingLevel           int           `json:"loggingLevel"`
-	LogEncoding            string        `json:"logEncoding"`
-	LogFile                string        `json:"logFilePath"`
-	VolumeBindTimeout      time.Duration `json:"volumeBindTimeout"`
-	TestMode               bool          `json:"testMode"`
-	EventChannelCapacity   int           `json:"eventChannelCapacity"`
-	DispatchTimeout        time.Duration `json:"dispatchTimeout"`
-	KubeQPS                int           `json:"kubeQPS"`
-	KubeBurst              int           `json:"kubeBurst"`
-	Predicates             string        `json:"predicates"`
-	OperatorPlugins        string        `json:"operatorPlugins"`
-	EnableConfigHotRefresh bool          `json:"enableConfigHotRefresh"`
-	UserLabelKey           string        `json:"userLabelKey"`
+	ClusterID                   string        `json:"clusterId"`
+	ClusterVersion              string        `json:"clusterVersion"`
+	PolicyGroup                 string        `json:"policyGroup"`
+	Interval                    time.Duration `json:"schedulingIntervalSecond"`
+	KubeConfig                  string        `json:"absoluteKubeConfigFilePath"`
+	LoggingLevel                int           `json:"loggingLevel"`
+	LogEncoding                 string        `json:"logEncoding"`
+	LogFile                     string        `json:"logFilePath"`
+	VolumeBindTimeout           time.Duration `json:"volumeBindTimeout"`
+	TestMode                    bool          `json:"testMode"`
+	EventChannelCapacity        int           `json:"eventChannelCapacity"`
+	DispatchTimeout             time.Duration `json:"dispatchTimeout"`
+	KubeQPS                     int           `json:"kubeQPS"`
+	KubeBurst                   int           `json:"kubeBurst"`
+	Predicates                  string        `json:"predicates"`
+	OperatorPlugins             string        `json:"operatorPlugins"`
+	EnableConfigHotRefresh      bool          `json:"enableConfigHotRefresh"`
+	UserLabelKey                string        `json:"userLabelKey"`
+	EnableGangFeasibilityCheck  bool          `json:"enableGangFeasibilityCheck"`
+	TaskSchedulingTimeout       time.Duration `json:"taskSchedulingTimeout"`
+	TaskSchedulingTimeoutPolicy string        `json:"taskSchedulingTimeoutPolicy"`
+	WebServicePort              int           `json:"webServicePort"`
+	PlaceholderWorkers          int           `json:"placeholderWorkers"`
+	PlaceholderRollbackPolicy   string        `json:"placeholderRollbackPolicy"`
+	FederatedClusterIDs         string        `json:"federatedClusterIds"`
+	PlaceholderJanitorInterval  time.Duration `json:"placeholderJanitorInterval"`
+	PlaceholderJanitorDryRun    bool          `json:"placeholderJanitorDryRun"`
+	NamespaceLabelTags          string        `json:"namespaceLabelTags"`
+	NamespaceAnnotationTags     string        `json:"namespaceAnnotationTags"`
+	EnableAppCheckpoint         bool          `json:"enableAppCheckpoint"`
+	AppCheckpointNamespace      string        `json:"appCheckpointNamespace"`
+	InformerResyncPeriod        time.Duration `json:"informerResyncPeriod"`
+	AppInformerResyncPeriod     time.Duration `json:"appInformerResyncPeriod"`
+	KubeListPageSize            int64         `json:"kubeListPageSize"`
+	RecoveryKubeQPS             int           `json:"recoveryKubeQPS"`
+	RecoveryKubeBurst           int           `json:"recoveryKubeBurst"`
+	NodeUpdateCoalescePeriod    time.Duration `json:"nodeUpdateCoalescePeriod"`
+	KillDeletionWorkers         int           `json:"killDeletionWorkers"`
+	KillDeletionQPS             int           `json:"killDeletionQPS"`
+	KillDeletionOrder           string        `json:"killDeletionOrder"`
+	KillGracePeriod             time.Duration `json:"killGracePeriod"`
+	KillTimeout                 time.Duration `json:"killTimeout"`
+	BindWorkers                 int           `json:"bindWorkers"`
+	RecoveryTimeout             time.Duration `json:"recoveryTimeout"`
+	UserResolver                string        `json:"userResolver"`
+	PodEventCoalescePeriod      time.Duration `json:"podEventCoalescePeriod"`
+	AppCompletionPolicies       string        `json:"appCompletionPolicies"`
+	CompletedAppTombstone       time.Duration `json:"completedAppTombstone"`
+	UnknownQueuePolicy          string        `json:"unknownQueuePolicy"`
+	UnknownQueueFallback        string        `json:"unknownQueueFallback"`
+	CoreAddress                 string        `json:"coreAddress"`
+	UpdateBatchSize             int           `json:"updateBatchSize"`
+	UpdateQueueSize             int           `json:"updateQueueSize"`
+	CoreWebAddress              string        `json:"coreWebAddress"`
+	ReconcileInterval           time.Duration `json:"reconcileInterval"`
+	ReconcileAutoHeal           bool          `json:"reconcileAutoHeal"`
+	PlaceholderRestoreWindow    time.Duration `json:"placeholderRestoreWindow"`
+	QueueCapacityRefresh        time.Duration `json:"queueCapacityRefresh"`
+	EnableTracing               bool          `json:"enableTracing"`
+	IgnoreInitContainers        bool          `json:"ignoreInitContainers"`
+	VictimSelectionPolicy       string        `json:"victimSelectionPolicy"`
+	ReservationWatchdogGrace    time.Duration `json:"reservationWatchdogGrace"`
+	RecoveryBatchSize           int           `json:"recoveryBatchSize"`
+	PlaceholderLingerWindow     time.Duration `json:"placeholderLingerWindow"`
+	EnableSILogging             bool          `json:"enableSILogging"`
+	SILogSampleRate             float64       `json:"siLogSampleRate"`
+	SILogRedact                 bool          `json:"siLogRedact"`
+	EventAuditSize              int           `json:"eventAuditSize"`
+	MaxReservingApps            string        `json:"maxReservingApps"`
+	ReleaseEviction             bool          `json:"releaseEviction"`
+	HardPreemption              bool          `json:"hardPreemption"`
+	JobTrackingCompatibility    bool          `json:"jobTrackingCompatibility"`
+	DryRun                      bool          `json:"dryRun"`
+	MemoryConversion            string        `json:"memoryConversion"`
+	WatchNamespaces             string        `json:"watchNamespaces"`
+	SchedulerNames              string        `json:"schedulerNames"`
+	WatchNamespaceSelector      string        `json:"watchNamespaceSelector"`
+	GangStatusInterval          time.Duration `json:"gangStatusInterval"`
+	ReleasedAskPolicy           string        `json:"releasedAskPolicy"`
+	ScheduleWorkers             int           `json:"scheduleWorkers"`
+	ScheduleTaskBudget          int           `json:"scheduleTaskBudget"`
+	EnableProvisioningRequest   bool          `json:"enableProvisioningRequest"`
+	ProvisioningClassName       string        `json:"provisioningClassName"`
+	BurstWindow                 time.Duration `json:"burstWindow"`
+	BurstFactor                 int           `json:"burstFactor"`
 	sync.RWMutex
 }
 
@@ -77,6 +229,20 @@ func GetSchedulerConf() *SchedulerConf {
 	return configuration
 }
 
+// Clone returns a copy of the configuration taken under its lock, the copy is not updated by a hot refresh
+func (conf *SchedulerConf) Clone() *SchedulerConf {
+	conf.RLock()
+	defer conf.RUnlock()
+	clone := &SchedulerConf{}
+	// the fields are copied through their json form: the struct embeds its lock and cannot be copied as a value
+	if data, err := json.Marshal(conf); err == nil {
+		if err = json.Unmarshal(data, clone); err != nil {
+			return &SchedulerConf{}
+		}
+	}
+	return clone
+}
+
 func (conf *SchedulerConf) SetTestMode(testMode bool) {
 	conf.Lock()
 	defer conf.Unlock()
@@ -112,6 +278,549 @@ func (conf *SchedulerConf) IsOperatorPluginEnabled(name string) bool {
 	return false
 }
 
+// GetKubeClientLimits returns the QPS and burst of the kube client
+func (conf *SchedulerConf) GetKubeClientLimits() (float32, int) {
+	conf.RLock()
+	defer conf.RUnlock()
+	return float32(conf.KubeQPS), conf.KubeBurst
+}
+
+// SetKubeClientLimits updates the QPS and burst of the kube client, this only changes the configuration,
+// the limits of a running client are updated by client.SetKubeClientRateLimit.
+func (conf *SchedulerConf) SetKubeClientLimits(qps int, burst int) {
+	conf.Lock()
+	defer conf.Unlock()
+	conf.KubeQPS = qps
+	conf.KubeBurst = burst
+}
+
+// GetRecoveryKubeClientLimits returns the QPS and burst of the kube client while the shim is recovering,
+// the regular limits are returned when no recovery limits are configured.
+func (conf *SchedulerConf) GetRecoveryKubeClientLimits() (float32, int) {
+	conf.RLock()
+	defer conf.RUnlock()
+	qps, burst := conf.KubeQPS, conf.KubeBurst
+	if conf.RecoveryKubeQPS > 0 {
+		qps = conf.RecoveryKubeQPS
+	}
+	if conf.RecoveryKubeBurst > 0 {
+		burst = conf.RecoveryKubeBurst
+	}
+	return float32(qps), burst
+}
+
+// GetInformerResyncPeriod returns the resync period of the shared informers, 0 disables the resync
+func (conf *SchedulerConf) GetInformerResyncPeriod() time.Duration {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.InformerResyncPeriod < 0 {
+		return 0
+	}
+	return conf.InformerResyncPeriod
+}
+
+// GetAppInformerResyncPeriod returns the resync period of the application CRD informer, 0 disables the resync
+func (conf *SchedulerConf) GetAppInformerResyncPeriod() time.Duration {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.AppInformerResyncPeriod < 0 {
+		return 0
+	}
+	return conf.AppInformerResyncPeriod
+}
+
+// GetKubeListPageSize returns the max number of objects returned by a single list call, 0 disables the paging
+func (conf *SchedulerConf) GetKubeListPageSize() int64 {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.KubeListPageSize < 0 {
+		return 0
+	}
+	return conf.KubeListPageSize
+}
+
+// SetKubeListPageSize updates the page size of the list calls, it takes effect on the next list
+func (conf *SchedulerConf) SetKubeListPageSize(pageSize int64) {
+	conf.Lock()
+	defer conf.Unlock()
+	conf.KubeListPageSize = pageSize
+}
+
+// GetNodeUpdateCoalescePeriod returns how long the node resource updates are collected before they are
+// sent to the scheduler-core, 0 sends every update immediately
+func (conf *SchedulerConf) GetNodeUpdateCoalescePeriod() time.Duration {
+	conf.RLock()
+	defer conf.RUnlock()
+	return conf.NodeUpdateCoalescePeriod
+}
+
+// GetPodEventCoalescePeriod returns how long the pod adds and updates of an app are collected before
+// the tasks are added or completed in one batch, 0 handles every pod event immediately
+func (conf *SchedulerConf) GetPodEventCoalescePeriod() time.Duration {
+	conf.RLock()
+	defer conf.RUnlock()
+	return conf.PodEventCoalescePeriod
+}
+
+// GetAppCompletionPolicy returns the completion policy of the apps of an app manager. The policies are
+// configured as a comma-separated list of manager=policy pairs, an app manager without a valid policy
+// uses the manual policy: the app stays Running until its owner completes it.
+func (conf *SchedulerConf) GetAppCompletionPolicy(appManager string) string {
+	conf.RLock()
+	defer conf.RUnlock()
+	for _, entry := range splitList(conf.AppCompletionPolicies) {
+		pair := strings.SplitN(entry, "=", 2)
+		if len(pair) != 2 || strings.TrimSpace(pair[0]) != appManager {
+			continue
+		}
+		if policy := strings.TrimSpace(pair[1]); policy == AppCompletionAllTasksTerminated {
+			return policy
+		}
+		return AppCompletionManual
+	}
+	return AppCompletionManual
+}
+
+// GetCompletedAppTombstone returns how long an app completed by the shim is kept before it is removed,
+// the state of the app stays visible and the late events of its pods are still handled in that period
+func (conf *SchedulerConf) GetCompletedAppTombstone() time.Duration {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.CompletedAppTombstone <= 0 {
+		return DefaultAppTombstone
+	}
+	return conf.CompletedAppTombstone
+}
+
+// GetUnknownQueuePolicy returns what happens to an app rejected because its queue does not exist,
+// an invalid policy fails the app
+func (conf *SchedulerConf) GetUnknownQueuePolicy() string {
+	conf.RLock()
+	defer conf.RUnlock()
+	switch conf.UnknownQueuePolicy {
+	case UnknownQueueFallback, UnknownQueueCreate:
+		return conf.UnknownQueuePolicy
+	default:
+		return UnknownQueueFail
+	}
+}
+
+// GetUnknownQueueFallback returns the queue an app is resubmitted to when its queue does not exist
+func (conf *SchedulerConf) GetUnknownQueueFallback() string {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.UnknownQueueFallback == "" {
+		return DefaultFallbackQueue
+	}
+	return conf.UnknownQueueFallback
+}
+
+// GetCoreAddress returns the address of the remote core the shim streams its updates to,
+// empty when the shim runs with the core embedded
+func (conf *SchedulerConf) GetCoreAddress() string {
+	conf.RLock()
+	defer conf.RUnlock()
+	return conf.CoreAddress
+}
+
+// GetUpdateStreamLimits returns the maximum number of objects sent in a single message on the update stream,
+// and the number of messages queued before the updates are held back until the core catches up
+func (conf *SchedulerConf) GetUpdateStreamLimits() (int, int) {
+	conf.RLock()
+	defer conf.RUnlock()
+	batchSize := conf.UpdateBatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultUpdateBatchSize
+	}
+	queueSize := conf.UpdateQueueSize
+	if queueSize <= 0 {
+		queueSize = DefaultUpdateQueueSize
+	}
+	return batchSize, queueSize
+}
+
+// GetCoreWebAddress returns the address of the web service of the core, the allocations of the core are read from it
+func (conf *SchedulerConf) GetCoreWebAddress() string {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.CoreWebAddress == "" {
+		return DefaultCoreWebAddress
+	}
+	return conf.CoreWebAddress
+}
+
+// GetReconcileInterval returns how often the allocations of the shim are compared with the allocations of the core,
+// 0 disables the comparison
+func (conf *SchedulerConf) GetReconcileInterval() time.Duration {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.ReconcileInterval < 0 {
+		return 0
+	}
+	return conf.ReconcileInterval
+}
+
+// GetPlaceholderRestoreWindow returns the period after being bound in which a failed real member of a task group
+// gets back the placeholder it replaced, 0 disables re-creating the placeholders
+func (conf *SchedulerConf) GetPlaceholderRestoreWindow() time.Duration {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.PlaceholderRestoreWindow < 0 {
+		return 0
+	}
+	return conf.PlaceholderRestoreWindow
+}
+
+// IsInitContainersIgnored returns true if the init containers are left out of the resource requests of the pods,
+// the sidecar init containers are still counted as they run alongside the app containers
+func (conf *SchedulerConf) IsInitContainersIgnored() bool {
+	conf.RLock()
+	defer conf.RUnlock()
+	return conf.IgnoreInitContainers
+}
+
+// GetVictimSelectionPolicy returns how the pods deleted for the allocations preempted by the core are selected,
+// an invalid policy deletes the pods of the allocations selected by the core
+func (conf *SchedulerConf) GetVictimSelectionPolicy() string {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.VictimSelectionPolicy == VictimSelectionDeletionCost {
+		return conf.VictimSelectionPolicy
+	}
+	return VictimSelectionCore
+}
+
+// GetReleasedAskPolicy returns the policy applied to a real task whose pending ask is released by the scheduler,
+// the task is left untouched unless configured otherwise
+func (conf *SchedulerConf) GetReleasedAskPolicy() string {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.ReleasedAskPolicy == ReleasedAskFail || conf.ReleasedAskPolicy == ReleasedAskRequeue {
+		return conf.ReleasedAskPolicy
+	}
+	return ReleasedAskSkip
+}
+
+// GetMemoryConversion returns the name of the conversion of the memory quantities sent to the scheduler,
+// the memory is rounded up to megabytes unless configured otherwise
+func (conf *SchedulerConf) GetMemoryConversion() string {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.MemoryConversion == "" {
+		return MemoryConversionMegabytes
+	}
+	return conf.MemoryConversion
+}
+
+// GetReservationWatchdogGrace returns how long the shim waits past the placeholder timeout of an app
+// before it ends the reservation itself, false when the watchdog is disabled
+func (conf *SchedulerConf) GetReservationWatchdogGrace() (time.Duration, bool) {
+	conf.RLock()
+	defer conf.RUnlock()
+	return conf.ReservationWatchdogGrace, conf.ReservationWatchdogGrace >= 0
+}
+
+// GetRecoveryBatchSize returns the max number of existing allocations reported to the core in a single request
+// when the nodes are recovered in bulk, 0 reports every node in its own request
+func (conf *SchedulerConf) GetRecoveryBatchSize() int {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.RecoveryBatchSize < 0 {
+		return 0
+	}
+	return conf.RecoveryBatchSize
+}
+
+// GetEventAuditSize returns the number of the last dispatched events kept for the audit, 0 disables the audit
+func (conf *SchedulerConf) GetEventAuditSize() int {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.EventAuditSize < 0 {
+		return 0
+	}
+	return conf.EventAuditSize
+}
+
+// GetMaxReservingApps returns how many apps of the queue may be reserving at the same time, 0 for no limit.
+// The limits are configured as a comma-separated list of queue=limit pairs, an invalid or negative limit is ignored.
+func (conf *SchedulerConf) GetMaxReservingApps(queue string) int {
+	conf.RLock()
+	defer conf.RUnlock()
+	for _, entry := range splitList(conf.MaxReservingApps) {
+		pair := strings.SplitN(entry, "=", 2)
+		if len(pair) != 2 || strings.TrimSpace(pair[0]) != queue {
+			continue
+		}
+		if limit, err := strconv.Atoi(strings.TrimSpace(pair[1])); err == nil && limit > 0 {
+			return limit
+		}
+		return 0
+	}
+	return 0
+}
+
+// GetGangStatusInterval returns how often the placeholder statistics of a reserving gang are published,
+// 0 if they are only published when the state of the app changes
+func (conf *SchedulerConf) GetGangStatusInterval() time.Duration {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.GangStatusInterval < 0 {
+		return 0
+	}
+	return conf.GangStatusInterval
+}
+
+// GetPlaceholderLingerWindow returns how long the bound placeholders of an app completed by the shim are kept
+// for a retry of the app with the same task groups to reclaim them, 0 cleans up the placeholders on completion
+func (conf *SchedulerConf) GetPlaceholderLingerWindow() time.Duration {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.PlaceholderLingerWindow < 0 {
+		return 0
+	}
+	return conf.PlaceholderLingerWindow
+}
+
+// GetSILogging returns if the scheduler interface messages are logged at startup, the share of the messages
+// logged between 0 and 1, and if the user names, groups and tags are redacted. An invalid rate logs all messages.
+func (conf *SchedulerConf) GetSILogging() (bool, float64, bool) {
+	conf.RLock()
+	defer conf.RUnlock()
+	rate := conf.SILogSampleRate
+	if rate <= 0 || rate > 1 {
+		rate = 1
+	}
+	return conf.EnableSILogging, rate, conf.SILogRedact
+}
+
+// GetQueueCapacityRefresh returns how often the max capacities of the queues are read from the core,
+// the apps whose placeholders exceed the max capacity of their queue are failed at submission, 0 disables the check
+func (conf *SchedulerConf) GetQueueCapacityRefresh() time.Duration {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.QueueCapacityRefresh < 0 {
+		return 0
+	}
+	return conf.QueueCapacityRefresh
+}
+
+// GetFederatedClusterIDs returns the secondary clusters the shim registers with in federation mode,
+// the local cluster is never part of the result.
+// GetKillDeletionLimits returns the number of workers deleting the pods of a killed app in parallel,
+// and the maximum number of pods deleted per second, 0 means the deletion is not throttled.
+func (conf *SchedulerConf) GetKillDeletionLimits() (int, int) {
+	conf.RLock()
+	defer conf.RUnlock()
+	workers := conf.KillDeletionWorkers
+	if workers <= 0 {
+		workers = DefaultKillDeletionWorkers
+	}
+	return workers, conf.KillDeletionQPS
+}
+
+// GetKillTimeouts returns the grace period of the pods deleted when an app is killed, negative for the
+// termination grace period of the pods, and how long a killed app waits for its pods to terminate and for the
+// core to release its allocations before it is reported as killed anyway
+func (conf *SchedulerConf) GetKillTimeouts() (time.Duration, time.Duration) {
+	conf.RLock()
+	defer conf.RUnlock()
+	timeout := conf.KillTimeout
+	if timeout <= 0 {
+		timeout = DefaultKillTimeout
+	}
+	return conf.KillGracePeriod, timeout
+}
+
+// GetKillDeletionOrder returns the kinds of pods in the order they are deleted when an app is killed,
+// the default order is returned if the configured order is not valid.
+func (conf *SchedulerConf) GetKillDeletionOrder() []string {
+	conf.RLock()
+	defer conf.RUnlock()
+	order := splitList(conf.KillDeletionOrder)
+	seen := make(map[string]bool, len(order))
+	for _, kind := range order {
+		if kind != KillOrderPlaceholder && kind != KillOrderWorker && kind != KillOrderDriver || seen[kind] {
+			return splitList(DefaultKillDeletionOrder)
+		}
+		seen[kind] = true
+	}
+	if len(order) == 0 {
+		return splitList(DefaultKillDeletionOrder)
+	}
+	return order
+}
+
+// GetBindWorkers returns the maximum number of allocations bound in parallel
+func (conf *SchedulerConf) GetBindWorkers() int {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.BindWorkers <= 0 {
+		return DefaultBindWorkers
+	}
+	return conf.BindWorkers
+}
+
+// GetScheduleWorkers returns the maximum number of apps scheduled in parallel in a scheduling cycle
+func (conf *SchedulerConf) GetScheduleWorkers() int {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.ScheduleWorkers <= 0 {
+		return DefaultScheduleWorkers
+	}
+	return conf.ScheduleWorkers
+}
+
+// GetScheduleTaskBudget returns the maximum number of new tasks of an app submitted in a scheduling cycle,
+// the remaining tasks wait for the next cycle. 0 submits all new tasks of the app.
+func (conf *SchedulerConf) GetScheduleTaskBudget() int {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.ScheduleTaskBudget < 0 {
+		return 0
+	}
+	return conf.ScheduleTaskBudget
+}
+
+// GetBurstWindow returns the period after the recovery the scheduling limits are raised by the burst factor,
+// 0 disables the burst
+func (conf *SchedulerConf) GetBurstWindow() time.Duration {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.BurstWindow < 0 {
+		return 0
+	}
+	return conf.BurstWindow
+}
+
+// GetBurstFactor returns the factor the scheduling limits are multiplied by during the burst window
+func (conf *SchedulerConf) GetBurstFactor() int {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.BurstFactor <= 0 {
+		return DefaultBurstFactor
+	}
+	return conf.BurstFactor
+}
+
+// GetProvisioningClassName returns the class of the provisioning requests created for the unschedulable task groups
+func (conf *SchedulerConf) GetProvisioningClassName() string {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.ProvisioningClassName == "" {
+		return DefaultProvisioningClass
+	}
+	return conf.ProvisioningClassName
+}
+
+// GetRecoveryTimeout returns the deadline of the whole recovery, the recovery fails once it is passed
+func (conf *SchedulerConf) GetRecoveryTimeout() time.Duration {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.RecoveryTimeout <= 0 {
+		return DefaultRecoveryTimeout
+	}
+	return conf.RecoveryTimeout
+}
+
+func (conf *SchedulerConf) GetFederatedClusterIDs() []string {
+	conf.RLock()
+	defer conf.RUnlock()
+	clusterIDs := make([]string, 0)
+	if conf.FederatedClusterIDs == "" {
+		return clusterIDs
+	}
+	for _, id := range strings.Split(conf.FederatedClusterIDs, constants.FederatedClusterIDsDelimiter) {
+		id = strings.TrimSpace(id)
+		if id == "" || id == conf.ClusterID {
+			continue
+		}
+		clusterIDs = append(clusterIDs, id)
+	}
+	return clusterIDs
+}
+
+// GetNamespaceLabelTags returns the keys of the namespace labels copied into the app tags
+func (conf *SchedulerConf) GetNamespaceLabelTags() []string {
+	conf.RLock()
+	defer conf.RUnlock()
+	return splitList(conf.NamespaceLabelTags)
+}
+
+// GetNamespaceAnnotationTags returns the keys of the namespace annotations copied into the app tags
+func (conf *SchedulerConf) GetNamespaceAnnotationTags() []string {
+	conf.RLock()
+	defer conf.RUnlock()
+	return splitList(conf.NamespaceAnnotationTags)
+}
+
+// splitList splits a comma-separated list, the entries are trimmed and the empty ones are skipped
+// GetWatchNamespaces returns the namespaces the pods are watched in, all namespaces when empty
+func (conf *SchedulerConf) GetWatchNamespaces() []string {
+	conf.RLock()
+	defer conf.RUnlock()
+	return splitList(conf.WatchNamespaces)
+}
+
+// GetWatchNamespaceSelector returns the label selector of the namespaces the pods are watched in,
+// all namespaces when empty
+func (conf *SchedulerConf) GetWatchNamespaceSelector() string {
+	conf.RLock()
+	defer conf.RUnlock()
+	return strings.TrimSpace(conf.WatchNamespaceSelector)
+}
+
+// GetSchedulerNames returns the scheduler names of the pods the scheduler schedules, yunikorn and the configured names
+func (conf *SchedulerConf) GetSchedulerNames() []string {
+	conf.RLock()
+	defer conf.RUnlock()
+	names := []string{constants.SchedulerName}
+	for _, name := range splitList(conf.SchedulerNames) {
+		if name != constants.SchedulerName {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// IsSchedulerName returns true if the pods with the scheduler name are scheduled by the scheduler
+func (conf *SchedulerConf) IsSchedulerName(name string) bool {
+	if name == constants.SchedulerName {
+		return true
+	}
+	conf.RLock()
+	defer conf.RUnlock()
+	for _, entry := range splitList(conf.SchedulerNames) {
+		if entry == name {
+			return true
+		}
+	}
+	return false
+}
+
+func splitList(list string) []string {
+	entries := make([]string, 0)
+	for _, entry := range strings.Split(list, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// IsRegisteredCluster returns true if the shim registers with the given cluster,
+// either the local cluster or one of the federated clusters.
+func (conf *SchedulerConf) IsRegisteredCluster(clusterID string) bool {
+	if clusterID == conf.ClusterID {
+		return true
+	}
+	for _, id := range conf.GetFederatedClusterIDs() {
+		if id == clusterID {
+			return true
+		}
+	}
+	return false
+}
+
 func initConfigs() {
 	// scheduler options
 	kubeConfig := flag.String("kubeConfig", "",
@@ -138,8 +847,11 @@ func initConfigs() {
 		fmt.Sprintf("comma-separated list of predicates, valid predicates are: %s, "+
 			"the program will exit if any invalid predicates exist.", predicates.Ordering()))
 	operatorPluginList := flag.String("operatorPlugins", "general,"+constants.AppManagerHandlerName,
-		"comma-separated list of operator plugin names, currently, only \"spark-k8s-operator\""+
-			"and"+constants.AppManagerHandlerName+"is supported.")
+		"comma-separated list of operator plugin names, currently, only \"spark-k8s-operator\", "+
+			"\"owner-reference\" and "+constants.AppManagerHandlerName+" is supported.")
+
+	webServicePort := flag.Int("webServicePort", DefaultWebServicePort,
+		"port of the shim web service")
 
 	// logging options
 	logLevel := flag.Int("logLevel", DefaultLoggingLevel,
@@ -153,6 +865,196 @@ func initConfigs() {
 		"automatically reloaded without restarting the scheduler.")
 	userLabelKey := flag.String("userLabelKey", constants.DefaultUserLabel,
 		"provide pod label key to be used to identify an user")
+	enableGangFeasibilityCheck := flag.Bool("enableGangFeasibilityCheck", false, "Flag for enabling "+
+		"the gang feasibility check. If this value is set to true, an application with task groups fails immediately "+
+		"when its gang can never be satisfied by the cluster, instead of waiting for the placeholder timeout.")
+	taskSchedulingTimeout := flag.Duration("taskSchedulingTimeout", 0,
+		"timeout of a task waiting for an allocation from the scheduler core, 0 means no timeout")
+	taskSchedulingTimeoutPolicy := flag.String("taskSchedulingTimeoutPolicy", TaskSchedulingTimeoutRetry,
+		"policy applied to a task when the task scheduling timeout is reached, "+
+			"\""+TaskSchedulingTimeoutRetry+"\" to resubmit the task, or \""+TaskSchedulingTimeoutFail+"\" to fail the pod")
+	placeholderWorkers := flag.Int("placeholderWorkers", DefaultPlaceholderWorkers,
+		"number of workers creating the placeholders of an app in parallel")
+	placeholderRollbackPolicy := flag.String("placeholderRollbackPolicy", PlaceholderRollbackAll,
+		"policy applied when some placeholders of an app failed to be created, "+
+			"\""+PlaceholderRollbackAll+"\" to delete all the placeholders of the app, or \""+PlaceholderRollbackTaskGroup+
+			"\" to only release the task groups with failed placeholders")
+	federatedClusterIDs := flag.String("federatedClusterIds", "",
+		"comma-separated list of the secondary cluster ids the shim registers with, "+
+			"an app is routed to one of these clusters with the "+constants.AnnotationClusterID+" annotation")
+	placeholderJanitorInterval := flag.Duration("placeholderJanitorInterval", DefaultPlaceholderJanitor,
+		"interval of the janitor deleting the placeholder pods whose app is not known by the shim, 0 disables the janitor")
+	placeholderJanitorDryRun := flag.Bool("placeholderJanitorDryRun", false,
+		"only log the placeholder pods the janitor would delete")
+	namespaceLabelTags := flag.String("namespaceLabelTags", "",
+		"comma-separated list of the namespace labels copied into the tags of the apps in the namespace, "+
+			"the tag of the label \"team\" is \""+constants.AppTagNamespaceLabelPrefix+"team\"")
+	namespaceAnnotationTags := flag.String("namespaceAnnotationTags", "",
+		"comma-separated list of the namespace annotations copied into the tags of the apps in the namespace, "+
+			"the tag of the annotation \"team\" is \""+constants.AppTagNamespaceAnnotationPrefix+"team\"")
+
+	enableAppCheckpoint := flag.Bool("enableAppCheckpoint", false, "Flag for enabling "+
+		"the app checkpoint. If this value is set to true, the state of the apps is saved in the "+
+		constants.AppCheckpointConfigMapName+" configmap and used to speed up the recovery after a restart.")
+	appCheckpointNamespace := flag.String("appCheckpointNamespace", constants.DefaultAppNamespace,
+		"namespace of the app checkpoint configmap")
+	informerResyncPeriod := flag.Duration("informerResyncPeriod", 0,
+		"resync period of the shared informers watching the kubernetes objects, 0 disables the resync")
+	appInformerResyncPeriod := flag.Duration("appInformerResyncPeriod", DefaultAppInformerResync,
+		"resync period of the application CRD informer, 0 disables the resync")
+	kubeListPageSize := flag.Int64("kubeListPageSize", DefaultKubeListPageSize,
+		"max number of objects returned by a single list call to kubernetes master, 0 disables the paging")
+	recoveryKubeQPS := flag.Int("recoveryKubeQPS", 0,
+		"the maximum QPS to kubernetes master from this client while the scheduler is recovering, 0 uses kubeQPS")
+	recoveryKubeBurst := flag.Int("recoveryKubeBurst", 0,
+		"the maximum burst to kubernetes master from this client while the scheduler is recovering, 0 uses kubeBurst")
+	nodeUpdateCoalescePeriod := flag.Duration("nodeUpdateCoalescePeriod", 0,
+		"period the capacity and occupied resource updates of a node are collected before being sent to the scheduler, "+
+			"updates of flapping nodes within the period are merged, 0 sends every update immediately")
+	killDeletionWorkers := flag.Int("killDeletionWorkers", DefaultKillDeletionWorkers,
+		"number of workers deleting the pods of a killed app in parallel")
+	killDeletionQPS := flag.Int("killDeletionQPS", DefaultKillDeletionQPS,
+		"maximum number of pods of a killed app deleted per second, 0 disables the throttling")
+	killGracePeriod := flag.Duration("killGracePeriod", DefaultKillGracePeriod,
+		"grace period of the pods deleted when an app is killed, a negative value uses the termination grace "+
+			"period of the pods")
+	killTimeout := flag.Duration("killTimeout", DefaultKillTimeout,
+		"maximum time a killed app waits for its pods to terminate and for the scheduler to release its "+
+			"allocations, the app is reported as partially killed once it is passed")
+	killDeletionOrder := flag.String("killDeletionOrder", DefaultKillDeletionOrder,
+		"comma-separated list of the kinds of pods in the order they are deleted when an app is killed, "+
+			"pods not in the list are deleted last, the kinds are \""+KillOrderPlaceholder+"\", \""+KillOrderWorker+
+			"\" and \""+KillOrderDriver+"\"")
+	bindWorkers := flag.Int("bindWorkers", DefaultBindWorkers,
+		"maximum number of allocations bound in parallel, the binds on the same node are done one at a time")
+	recoveryTimeout := flag.Duration("recoveryTimeout", DefaultRecoveryTimeout,
+		"maximum time the recovery of the apps, the nodes and the tasks can take after a restart, "+
+			"the scheduler fails with the recovery progress once it is passed")
+	userResolver := flag.String("userResolver", UserResolverLabel,
+		"resolver of the user and the groups an app is submitted as, \""+UserResolverLabel+"\" reads the user from the "+
+			"userLabelKey label of the pod, \""+UserResolverAnnotation+"\" reads the user and the groups from the "+
+			constants.AnnotationUserInfo+" annotation injected by the admission controller")
+	appCompletionPolicies := flag.String("appCompletionPolicies", "",
+		"comma-separated list of manager=policy pairs setting how the apps of an app manager are completed, "+
+			"\""+AppCompletionAllTasksTerminated+"\" completes an app once all its tasks are terminated, "+
+			"\""+AppCompletionManual+"\" (default) waits for the owner of the app to complete it")
+	completedAppTombstone := flag.Duration("completedAppTombstone", DefaultAppTombstone,
+		"period an app completed by the scheduler is kept before it is removed")
+	unknownQueuePolicy := flag.String("unknownQueuePolicy", UnknownQueueFail,
+		"policy applied to an app rejected because its queue does not exist, \""+UnknownQueueFail+"\" fails the app and "+
+			"its pods, \""+UnknownQueueFallback+"\" resubmits the app to the unknownQueueFallback queue, \""+
+			UnknownQueueCreate+"\" resubmits the app with its queue in the \""+constants.AppTagRequestedQueue+
+			"\" tag, for a tag placement rule with create set to create the queue")
+	unknownQueueFallback := flag.String("unknownQueueFallback", DefaultFallbackQueue,
+		"queue an app is resubmitted to when its queue does not exist and the unknownQueuePolicy is \""+
+			UnknownQueueFallback+"\"")
+	coreAddress := flag.String("coreAddress", "",
+		"address of a remote core the updates are streamed to over grpc, empty runs the core embedded in the shim")
+	updateBatchSize := flag.Int("updateBatchSize", DefaultUpdateBatchSize,
+		"maximum number of nodes, apps and asks sent in a single message on the update stream to the remote core")
+	updateQueueSize := flag.Int("updateQueueSize", DefaultUpdateQueueSize,
+		"number of messages queued for the remote core before the updates are held back until it catches up")
+	coreWebAddress := flag.String("coreWebAddress", DefaultCoreWebAddress,
+		"address of the web service of the core, the allocations of the core are read from it to detect divergences")
+	reconcileInterval := flag.Duration("reconcileInterval", 0,
+		"period the bound tasks of the shim are compared with the allocations of the core, 0 disables the comparison")
+	reconcileAutoHeal := flag.Bool("reconcileAutoHeal", false,
+		"heal the divergences found by the comparison with the core, the resources of a bound task unknown to the core "+
+			"are occupied on its node and an allocation unknown to the shim is released")
+	placeholderRestoreWindow := flag.Duration("placeholderRestoreWindow", 0,
+		"period after being bound in which a real member of a task group that fails gets back the placeholder it "+
+			"replaced, this keeps the reservation of the gang for the restarted member, 0 disables it")
+	queueCapacityRefresh := flag.Duration("queueCapacityRefresh", 0,
+		"period the max capacities of the queues are read from the web service of the core, an app whose placeholders "+
+			"can never fit in the max capacity of its queue is failed at submission, 0 disables the check")
+	enableTracing := flag.Bool("enableTracing", false,
+		"trace the scheduling of the pods from their add to their bind, the spans are reported to Jaeger "+
+			"configured with the JAEGER_* environment variables")
+	ignoreInitContainers := flag.Bool("ignoreInitContainers", false,
+		"leave the init containers out of the resource requests of the pods, by default a pod requests the max "+
+			"of its init containers and the sum of its containers, plus its overhead, the same as the kubelet admits")
+	victimSelectionPolicy := flag.String("victimSelectionPolicy", VictimSelectionCore,
+		"policy selecting the pods deleted for the allocations preempted by the core, \""+VictimSelectionCore+
+			"\" deletes the pods of the preempted allocations, \""+VictimSelectionDeletionCost+"\" deletes the pods "+
+			"with the lowest "+constants.AnnotationPodDeletionCost+" instead, among the pods of the same app "+
+			"running on the same node in the same queue with the same resources")
+	reservationWatchdogGrace := flag.Duration("reservationWatchdogGrace", DefaultReservationGrace,
+		"period past the placeholder timeout of an app after which the shim ends a reservation the core did not time out, "+
+			"the placeholders are cleaned up and the app falls back or fails following its gang scheduling style, "+
+			"a negative value disables the watchdog")
+	recoveryBatchSize := flag.Int("recoveryBatchSize", DefaultRecoveryBatchSize,
+		"max number of existing allocations reported to the scheduler in a single request when the nodes are recovered "+
+			"after a restart, the nodes are reported together with their allocations in as few requests as possible, "+
+			"0 reports every node in its own request")
+	placeholderLingerWindow := flag.Duration("placeholderLingerWindow", 0,
+		"period the bound placeholders of an app completed by the shim are kept, a retry of the app submitted in "+
+			"that period with the same task groups reclaims the reservation instead of reserving again, "+
+			"0 cleans up the placeholders as soon as the app is completed")
+	enableSILogging := flag.Bool("enableSILogging", false,
+		"log the requests sent to the scheduler and the responses received from it, "+
+			"the logging can be changed at runtime through the web service")
+	siLogSampleRate := flag.Float64("siLogSampleRate", 1,
+		"share of the scheduler interface messages logged, between 0 and 1")
+	siLogRedact := flag.Bool("siLogRedact", true,
+		"redact the user names, groups and tags in the logged scheduler interface messages")
+	eventAuditSize := flag.Int("eventAuditSize", DefaultEventAuditSize,
+		"number of the last dispatched events kept in memory and exposed through /debug/events, 0 disables the audit")
+	releaseEviction := flag.Bool("releaseEviction", false,
+		"evict the pods released by the scheduler through the eviction API instead of deleting them, "+
+			"the pod disruption budgets are honored and an eviction they block is retried")
+	hardPreemption := flag.Bool("hardPreemption", false,
+		"delete the pods preempted by the scheduler even if the release eviction is enabled, "+
+			"the preemption is not blocked by the pod disruption budgets")
+	jobTrackingCompatibility := flag.Bool("jobTrackingCompatibility", false,
+		"leave the terminating pods tracked by the Job controller to the Job controller, they are not deleted "+
+			"again, and remove a killed app from the scheduler once its tracked pods are accounted for")
+	dryRun := flag.Bool("dryRun", false,
+		"observe-only mode, the scheduling decisions are made and logged but the cluster is never changed: "+
+			"the pods are not bound, deleted, evicted or created and the objects are not updated")
+	memoryConversion := flag.String("memoryConversion", MemoryConversionMegabytes,
+		"conversion of the memory of the pods sent to the scheduler, \""+MemoryConversionMegabytes+"\" rounds up "+
+			"to megabytes, \""+MemoryConversionBytes+"\" sends the exact bytes, the memory of the queue and node "+
+			"resources configured in the scheduler must use the same unit")
+	watchNamespaces := flag.String("watchNamespaces", "",
+		"comma-separated list of the namespaces the pods are watched and scheduled in, the pods of the other "+
+			"namespaces are not cached, their resources on the nodes are not seen by the scheduler, empty watches "+
+			"all namespaces")
+	watchNamespaceSelector := flag.String("watchNamespaceSelector", "",
+		"label selector of the namespaces the pods are watched and scheduled in, the namespaces are selected when "+
+			"the scheduler starts and added to the watched namespaces")
+	schedulerNames := flag.String("schedulerNames", "",
+		"comma-separated list of the scheduler names of the pods scheduled in addition to \""+constants.SchedulerName+
+			"\", e.g. \"default-scheduler\" takes over the pods of the default scheduler, which must not run then")
+	releasedAskPolicy := flag.String("releasedAskPolicy", ReleasedAskSkip,
+		"policy applied to a pending task whose request is released by the scheduler, e.g. its queue shrinks, "+
+			"\""+ReleasedAskSkip+"\" leaves the task waiting, \""+ReleasedAskFail+"\" fails the pod, \""+
+			ReleasedAskRequeue+"\" resubmits the task, the placeholders of a timed out reservation are always deleted")
+	scheduleWorkers := flag.Int("scheduleWorkers", DefaultScheduleWorkers,
+		"number of apps scheduled in parallel in a scheduling cycle")
+	scheduleTaskBudget := flag.Int("scheduleTaskBudget", DefaultScheduleTaskBudget,
+		"maximum number of new tasks of an app submitted in a scheduling cycle, the remaining tasks wait for the "+
+			"next cycle so an app with many pending pods does not delay the other apps, 0 submits all new tasks")
+	burstWindow := flag.Duration("burstWindow", 0,
+		"period after the recovery the scheduling limits are raised, the recovered tasks are submitted and bound "+
+			"faster: the scheduleWorkers, the scheduleTaskBudget and the bindWorkers are multiplied by the burstFactor, "+
+			"0 disables the burst")
+	burstFactor := flag.Int("burstFactor", DefaultBurstFactor,
+		"factor the scheduling limits are multiplied by during the burstWindow")
+	enableProvisioningRequest := flag.Bool("enableProvisioningRequest", false, "Flag for enabling "+
+		"the provisioning requests. If this value is set to true, a ProvisioningRequest of the cluster autoscaler is "+
+		"created for the placeholders of a task group that cannot be scheduled, the capacity of the whole task group "+
+		"is provisioned at once")
+	provisioningClassName := flag.String("provisioningClassName", DefaultProvisioningClass,
+		"class of the provisioning requests created for the task groups that cannot be scheduled")
+	gangStatusInterval := flag.Duration("gangStatusInterval", DefaultGangStatusInterval,
+		"period the placeholder statistics of the task groups of a reserving gang are published as an event "+
+			"and in the status of the application CRD, 0 only publishes them when the state of the app changes")
+	maxReservingApps := flag.String("maxReservingApps", "",
+		"comma-separated list of queue=limit pairs, the maximum number of apps of a queue reserving resources for their "+
+			"gang at the same time, the other apps wait in the Accepted state")
+	podEventCoalescePeriod := flag.Duration("podEventCoalescePeriod", 0,
+		"period the pod adds and updates of an app are collected before its tasks are added or completed in one batch, "+
+			"this reduces the pressure on the dispatcher during mass pod creation, 0 handles every pod event immediately")
 
 	flag.Parse()
 
@@ -167,22 +1069,89 @@ func initConfigs() {
 	}
 
 	configuration = &SchedulerConf{
-		ClusterID:              *clusterID,
-		ClusterVersion:         *clusterVersion,
-		PolicyGroup:            *policyGroup,
-		Interval:               *schedulingInterval,
-		KubeConfig:             *kubeConfig,
-		LoggingLevel:           *logLevel,
-		LogEncoding:            *encode,
-		LogFile:                *logFile,
-		VolumeBindTimeout:      *volumeBindTimeout,
-		EventChannelCapacity:   *eventChannelCapacity,
-		DispatchTimeout:        *dispatchTimeout,
-		KubeQPS:                *kubeQPS,
-		KubeBurst:              *kubeBurst,
-		Predicates:             *predicateList,
-		OperatorPlugins:        *operatorPluginList,
-		EnableConfigHotRefresh: *enableConfigHotRefresh,
-		UserLabelKey:           *userLabelKey,
+		ClusterID:                   *clusterID,
+		ClusterVersion:              *clusterVersion,
+		PolicyGroup:                 *policyGroup,
+		Interval:                    *schedulingInterval,
+		KubeConfig:                  *kubeConfig,
+		LoggingLevel:                *logLevel,
+		LogEncoding:                 *encode,
+		LogFile:                     *logFile,
+		VolumeBindTimeout:           *volumeBindTimeout,
+		EventChannelCapacity:        *eventChannelCapacity,
+		DispatchTimeout:             *dispatchTimeout,
+		KubeQPS:                     *kubeQPS,
+		KubeBurst:                   *kubeBurst,
+		Predicates:                  *predicateList,
+		OperatorPlugins:             *operatorPluginList,
+		EnableConfigHotRefresh:      *enableConfigHotRefresh,
+		UserLabelKey:                *userLabelKey,
+		EnableGangFeasibilityCheck:  *enableGangFeasibilityCheck,
+		TaskSchedulingTimeout:       *taskSchedulingTimeout,
+		TaskSchedulingTimeoutPolicy: *taskSchedulingTimeoutPolicy,
+		WebServicePort:              *webServicePort,
+		PlaceholderWorkers:          *placeholderWorkers,
+		PlaceholderRollbackPolicy:   *placeholderRollbackPolicy,
+		FederatedClusterIDs:         *federatedClusterIDs,
+		PlaceholderJanitorInterval:  *placeholderJanitorInterval,
+		PlaceholderJanitorDryRun:    *placeholderJanitorDryRun,
+		NamespaceLabelTags:          *namespaceLabelTags,
+		NamespaceAnnotationTags:     *namespaceAnnotationTags,
+		EnableAppCheckpoint:         *enableAppCheckpoint,
+		AppCheckpointNamespace:      *appCheckpointNamespace,
+		InformerResyncPeriod:        *informerResyncPeriod,
+		AppInformerResyncPeriod:     *appInformerResyncPeriod,
+		KubeListPageSize:            *kubeListPageSize,
+		RecoveryKubeQPS:             *recoveryKubeQPS,
+		RecoveryKubeBurst:           *recoveryKubeBurst,
+		NodeUpdateCoalescePeriod:    *nodeUpdateCoalescePeriod,
+		KillDeletionWorkers:         *killDeletionWorkers,
+		KillDeletionQPS:             *killDeletionQPS,
+		KillDeletionOrder:           *killDeletionOrder,
+		KillGracePeriod:             *killGracePeriod,
+		KillTimeout:                 *killTimeout,
+		BindWorkers:                 *bindWorkers,
+		RecoveryTimeout:             *recoveryTimeout,
+		UserResolver:                *userResolver,
+		PodEventCoalescePeriod:      *podEventCoalescePeriod,
+		AppCompletionPolicies:       *appCompletionPolicies,
+		CompletedAppTombstone:       *completedAppTombstone,
+		UnknownQueuePolicy:          *unknownQueuePolicy,
+		UnknownQueueFallback:        *unknownQueueFallback,
+		CoreAddress:                 *coreAddress,
+		UpdateBatchSize:             *updateBatchSize,
+		UpdateQueueSize:             *updateQueueSize,
+		CoreWebAddress:              *coreWebAddress,
+		ReconcileInterval:           *reconcileInterval,
+		ReconcileAutoHeal:           *reconcileAutoHeal,
+		PlaceholderRestoreWindow:    *placeholderRestoreWindow,
+		QueueCapacityRefresh:        *queueCapacityRefresh,
+		EnableTracing:               *enableTracing,
+		IgnoreInitContainers:        *ignoreInitContainers,
+		VictimSelectionPolicy:       *victimSelectionPolicy,
+		ReservationWatchdogGrace:    *reservationWatchdogGrace,
+		RecoveryBatchSize:           *recoveryBatchSize,
+		PlaceholderLingerWindow:     *placeholderLingerWindow,
+		EnableSILogging:             *enableSILogging,
+		SILogSampleRate:             *siLogSampleRate,
+		SILogRedact:                 *siLogRedact,
+		EventAuditSize:              *eventAuditSize,
+		MaxReservingApps:            *maxReservingApps,
+		ReleaseEviction:             *releaseEviction,
+		HardPreemption:              *hardPreemption,
+		JobTrackingCompatibility:    *jobTrackingCompatibility,
+		DryRun:                      *dryRun,
+		MemoryConversion:            *memoryConversion,
+		WatchNamespaces:             *watchNamespaces,
+		SchedulerNames:              *schedulerNames,
+		WatchNamespaceSelector:      *watchNamespaceSelector,
+		GangStatusInterval:          *gangStatusInterval,
+		ReleasedAskPolicy:           *releasedAskPolicy,
+		ScheduleWorkers:             *scheduleWorkers,
+		ScheduleTaskBudget:          *scheduleTaskBudget,
+		EnableProvisioningRequest:   *enableProvisioningRequest,
+		ProvisioningClassName:       *provisioningClassName,
+		BurstWindow:                 *burstWindow,
+		BurstFactor:                 *burstFactor,
 	}
 }
@@ -19,6 +19,7 @@ package conf
 
 import (
 	"testing"
+	"time"
 
 	"gotest.tools/assert"
 
@@ -38,4 +39,373 @@ func TestDefaultValues(t *testing.T) {
 	assert.Equal(t, conf.KubeBurst, DefaultKubeBurst)
 	assert.Equal(t, conf.Predicates, "")
 	assert.Equal(t, conf.UserLabelKey, constants.DefaultUserLabel)
+	assert.Equal(t, conf.GetInformerResyncPeriod(), time.Duration(0))
+	assert.Equal(t, conf.GetAppInformerResyncPeriod(), DefaultAppInformerResync)
+	assert.Equal(t, conf.GetKubeListPageSize(), int64(DefaultKubeListPageSize))
+}
+
+func TestKubeClientLimits(t *testing.T) {
+	conf := &SchedulerConf{KubeQPS: 100, KubeBurst: 200}
+	qps, burst := conf.GetKubeClientLimits()
+	assert.Equal(t, qps, float32(100))
+	assert.Equal(t, burst, 200)
+
+	// the regular limits are used during the recovery when no recovery limits are set
+	qps, burst = conf.GetRecoveryKubeClientLimits()
+	assert.Equal(t, qps, float32(100))
+	assert.Equal(t, burst, 200)
+	conf.RecoveryKubeQPS = 20
+	qps, burst = conf.GetRecoveryKubeClientLimits()
+	assert.Equal(t, qps, float32(20))
+	assert.Equal(t, burst, 200)
+
+	conf.SetKubeClientLimits(50, 60)
+	qps, burst = conf.GetKubeClientLimits()
+	assert.Equal(t, qps, float32(50))
+	assert.Equal(t, burst, 60)
+
+	// negative values disable the paging and the resync
+	conf.SetKubeListPageSize(-1)
+	conf.InformerResyncPeriod = -time.Second
+	assert.Equal(t, conf.GetKubeListPageSize(), int64(0))
+	assert.Equal(t, conf.GetInformerResyncPeriod(), time.Duration(0))
+}
+
+func TestGetFederatedClusterIDs(t *testing.T) {
+	conf := &SchedulerConf{ClusterID: "cluster-a"}
+	assert.Equal(t, len(conf.GetFederatedClusterIDs()), 0)
+	assert.Assert(t, conf.IsRegisteredCluster("cluster-a"))
+	assert.Assert(t, !conf.IsRegisteredCluster("cluster-b"))
+
+	// the local cluster and empty ids are skipped
+	conf.FederatedClusterIDs = "cluster-b, cluster-a,,cluster-c"
+	assert.DeepEqual(t, conf.GetFederatedClusterIDs(), []string{"cluster-b", "cluster-c"})
+	assert.Assert(t, conf.IsRegisteredCluster("cluster-a"))
+	assert.Assert(t, conf.IsRegisteredCluster("cluster-c"))
+	assert.Assert(t, !conf.IsRegisteredCluster("cluster-d"))
+}
+
+func TestGetNamespaceTags(t *testing.T) {
+	conf := &SchedulerConf{}
+	assert.Equal(t, len(conf.GetNamespaceLabelTags()), 0)
+	assert.Equal(t, len(conf.GetNamespaceAnnotationTags()), 0)
+
+	conf.NamespaceLabelTags = "team, environment,,"
+	conf.NamespaceAnnotationTags = " cost-center "
+	assert.DeepEqual(t, conf.GetNamespaceLabelTags(), []string{"team", "environment"})
+	assert.DeepEqual(t, conf.GetNamespaceAnnotationTags(), []string{"cost-center"})
+}
+
+func TestKillDeletion(t *testing.T) {
+	conf := &SchedulerConf{}
+	workers, qps := conf.GetKillDeletionLimits()
+	assert.Equal(t, workers, DefaultKillDeletionWorkers)
+	assert.Equal(t, qps, 0)
+	assert.DeepEqual(t, conf.GetKillDeletionOrder(), []string{KillOrderPlaceholder, KillOrderWorker, KillOrderDriver})
+
+	conf.KillDeletionWorkers = 5
+	conf.KillDeletionQPS = 20
+	workers, qps = conf.GetKillDeletionLimits()
+	assert.Equal(t, workers, 5)
+	assert.Equal(t, qps, 20)
+
+	// the kinds missing from the order are deleted last
+	conf.KillDeletionOrder = "driver, placeholder"
+	assert.DeepEqual(t, conf.GetKillDeletionOrder(), []string{KillOrderDriver, KillOrderPlaceholder})
+
+	// unknown and duplicated kinds fall back to the default order
+	conf.KillDeletionOrder = "driver,executor"
+	assert.DeepEqual(t, conf.GetKillDeletionOrder(), []string{KillOrderPlaceholder, KillOrderWorker, KillOrderDriver})
+	conf.KillDeletionOrder = "driver,driver"
+	assert.DeepEqual(t, conf.GetKillDeletionOrder(), []string{KillOrderPlaceholder, KillOrderWorker, KillOrderDriver})
+}
+
+func TestGetKillTimeouts(t *testing.T) {
+	conf := &SchedulerConf{}
+	grace, timeout := conf.GetKillTimeouts()
+	assert.Equal(t, grace, time.Duration(0))
+	assert.Equal(t, timeout, DefaultKillTimeout)
+
+	conf.KillGracePeriod = -time.Second
+	conf.KillTimeout = time.Minute
+	grace, timeout = conf.GetKillTimeouts()
+	assert.Equal(t, grace, -time.Second)
+	assert.Equal(t, timeout, time.Minute)
+}
+
+func TestGetBindWorkers(t *testing.T) {
+	conf := &SchedulerConf{}
+	assert.Equal(t, conf.GetBindWorkers(), DefaultBindWorkers)
+	conf.BindWorkers = -1
+	assert.Equal(t, conf.GetBindWorkers(), DefaultBindWorkers)
+	conf.BindWorkers = 4
+	assert.Equal(t, conf.GetBindWorkers(), 4)
+}
+
+func TestGetRecoveryTimeout(t *testing.T) {
+	conf := &SchedulerConf{}
+	assert.Equal(t, conf.GetRecoveryTimeout(), DefaultRecoveryTimeout)
+	conf.RecoveryTimeout = 10 * time.Minute
+	assert.Equal(t, conf.GetRecoveryTimeout(), 10*time.Minute)
+}
+
+func TestGetAppCompletionPolicy(t *testing.T) {
+	conf := &SchedulerConf{}
+	assert.Equal(t, conf.GetAppCompletionPolicy("general"), AppCompletionManual)
+	conf.AppCompletionPolicies = "general=allTasksTerminated, owner-reference = manual,spark=unknown,invalid"
+	assert.Equal(t, conf.GetAppCompletionPolicy("general"), AppCompletionAllTasksTerminated)
+	assert.Equal(t, conf.GetAppCompletionPolicy("owner-reference"), AppCompletionManual)
+	assert.Equal(t, conf.GetAppCompletionPolicy("spark"), AppCompletionManual)
+	assert.Equal(t, conf.GetAppCompletionPolicy("other"), AppCompletionManual)
+}
+
+func TestGetMaxReservingApps(t *testing.T) {
+	conf := &SchedulerConf{}
+	assert.Equal(t, conf.GetMaxReservingApps("root.a"), 0)
+	conf.MaxReservingApps = "root.a=2, root.b = 5,root.c=-1,root.d=none,invalid"
+	assert.Equal(t, conf.GetMaxReservingApps("root.a"), 2)
+	assert.Equal(t, conf.GetMaxReservingApps("root.b"), 5)
+	assert.Equal(t, conf.GetMaxReservingApps("root.c"), 0)
+	assert.Equal(t, conf.GetMaxReservingApps("root.d"), 0)
+	assert.Equal(t, conf.GetMaxReservingApps("root.other"), 0)
+}
+
+func TestGetCompletedAppTombstone(t *testing.T) {
+	conf := &SchedulerConf{}
+	assert.Equal(t, conf.GetCompletedAppTombstone(), DefaultAppTombstone)
+	conf.CompletedAppTombstone = time.Minute
+	assert.Equal(t, conf.GetCompletedAppTombstone(), time.Minute)
+}
+
+func TestGetUnknownQueuePolicy(t *testing.T) {
+	conf := &SchedulerConf{}
+	assert.Equal(t, conf.GetUnknownQueuePolicy(), UnknownQueueFail)
+	assert.Equal(t, conf.GetUnknownQueueFallback(), DefaultFallbackQueue)
+	conf.UnknownQueuePolicy = "invalid"
+	assert.Equal(t, conf.GetUnknownQueuePolicy(), UnknownQueueFail)
+	conf.UnknownQueuePolicy = UnknownQueueCreate
+	assert.Equal(t, conf.GetUnknownQueuePolicy(), UnknownQueueCreate)
+	conf.UnknownQueuePolicy = UnknownQueueFallback
+	conf.UnknownQueueFallback = "root.sandbox"
+	assert.Equal(t, conf.GetUnknownQueuePolicy(), UnknownQueueFallback)
+	assert.Equal(t, conf.GetUnknownQueueFallback(), "root.sandbox")
+}
+
+func TestGetUpdateStreamLimits(t *testing.T) {
+	conf := &SchedulerConf{}
+	batchSize, queueSize := conf.GetUpdateStreamLimits()
+	assert.Equal(t, batchSize, DefaultUpdateBatchSize)
+	assert.Equal(t, queueSize, DefaultUpdateQueueSize)
+	conf.UpdateBatchSize = 10
+	conf.UpdateQueueSize = 2
+	batchSize, queueSize = conf.GetUpdateStreamLimits()
+	assert.Equal(t, batchSize, 10)
+	assert.Equal(t, queueSize, 2)
+}
+
+func TestGetReconcileInterval(t *testing.T) {
+	conf := &SchedulerConf{}
+	assert.Equal(t, conf.GetReconcileInterval(), time.Duration(0))
+	assert.Equal(t, conf.GetCoreWebAddress(), DefaultCoreWebAddress)
+	conf.ReconcileInterval = -time.Minute
+	assert.Equal(t, conf.GetReconcileInterval(), time.Duration(0))
+	conf.ReconcileInterval = time.Minute
+	conf.CoreWebAddress = "core:9080"
+	assert.Equal(t, conf.GetReconcileInterval(), time.Minute)
+	assert.Equal(t, conf.GetCoreWebAddress(), "core:9080")
+}
+
+func TestGetQueueCapacityRefresh(t *testing.T) {
+	conf := &SchedulerConf{}
+	assert.Equal(t, conf.GetQueueCapacityRefresh(), time.Duration(0))
+	conf.QueueCapacityRefresh = -time.Minute
+	assert.Equal(t, conf.GetQueueCapacityRefresh(), time.Duration(0))
+	conf.QueueCapacityRefresh = time.Minute
+	assert.Equal(t, conf.GetQueueCapacityRefresh(), time.Minute)
+}
+
+func TestIsInitContainersIgnored(t *testing.T) {
+	conf := &SchedulerConf{}
+	assert.Assert(t, !conf.IsInitContainersIgnored())
+	conf.IgnoreInitContainers = true
+	assert.Assert(t, conf.IsInitContainersIgnored())
+}
+
+func TestGetVictimSelectionPolicy(t *testing.T) {
+	conf := &SchedulerConf{}
+	assert.Equal(t, conf.GetVictimSelectionPolicy(), VictimSelectionCore)
+	conf.VictimSelectionPolicy = "cheapest"
+	assert.Equal(t, conf.GetVictimSelectionPolicy(), VictimSelectionCore)
+	conf.VictimSelectionPolicy = VictimSelectionDeletionCost
+	assert.Equal(t, conf.GetVictimSelectionPolicy(), VictimSelectionDeletionCost)
+}
+
+func TestGetMemoryConversion(t *testing.T) {
+	conf := &SchedulerConf{}
+	assert.Equal(t, conf.GetMemoryConversion(), MemoryConversionMegabytes)
+	conf.MemoryConversion = MemoryConversionBytes
+	assert.Equal(t, conf.GetMemoryConversion(), MemoryConversionBytes)
+}
+
+func TestGetWatchNamespaces(t *testing.T) {
+	conf := &SchedulerConf{}
+	assert.Equal(t, len(conf.GetWatchNamespaces()), 0)
+	assert.Equal(t, conf.GetWatchNamespaceSelector(), "")
+	conf.WatchNamespaces = "tenant-a, tenant-b,"
+	assert.DeepEqual(t, conf.GetWatchNamespaces(), []string{"tenant-a", "tenant-b"})
+	conf.WatchNamespaceSelector = " yunikorn=enabled "
+	assert.Equal(t, conf.GetWatchNamespaceSelector(), "yunikorn=enabled")
+}
+
+func TestGetSchedulerNames(t *testing.T) {
+	conf := &SchedulerConf{}
+	assert.DeepEqual(t, conf.GetSchedulerNames(), []string{constants.SchedulerName})
+	assert.Assert(t, conf.IsSchedulerName(constants.SchedulerName))
+	assert.Assert(t, !conf.IsSchedulerName("default-scheduler"))
+	conf.SchedulerNames = "default-scheduler, yunikorn,"
+	assert.DeepEqual(t, conf.GetSchedulerNames(), []string{constants.SchedulerName, "default-scheduler"})
+	assert.Assert(t, conf.IsSchedulerName("default-scheduler"))
+	assert.Assert(t, !conf.IsSchedulerName("other-scheduler"))
+	assert.Assert(t, !conf.IsSchedulerName(""))
+}
+
+func TestGetReleasedAskPolicy(t *testing.T) {
+	conf := &SchedulerConf{}
+	assert.Equal(t, conf.GetReleasedAskPolicy(), ReleasedAskSkip)
+	conf.ReleasedAskPolicy = "Retry"
+	assert.Equal(t, conf.GetReleasedAskPolicy(), ReleasedAskSkip)
+	conf.ReleasedAskPolicy = ReleasedAskFail
+	assert.Equal(t, conf.GetReleasedAskPolicy(), ReleasedAskFail)
+	conf.ReleasedAskPolicy = ReleasedAskRequeue
+	assert.Equal(t, conf.GetReleasedAskPolicy(), ReleasedAskRequeue)
+}
+
+func TestGetScheduleLimits(t *testing.T) {
+	conf := &SchedulerConf{}
+	assert.Equal(t, conf.GetScheduleWorkers(), DefaultScheduleWorkers)
+	assert.Equal(t, conf.GetScheduleTaskBudget(), 0)
+	conf.ScheduleWorkers = 2
+	conf.ScheduleTaskBudget = 100
+	assert.Equal(t, conf.GetScheduleWorkers(), 2)
+	assert.Equal(t, conf.GetScheduleTaskBudget(), 100)
+	conf.ScheduleWorkers = -1
+	conf.ScheduleTaskBudget = -1
+	assert.Equal(t, conf.GetScheduleWorkers(), DefaultScheduleWorkers)
+	assert.Equal(t, conf.GetScheduleTaskBudget(), 0)
+}
+
+func TestGetBurstLimits(t *testing.T) {
+	conf := &SchedulerConf{}
+	assert.Equal(t, conf.GetBurstWindow(), time.Duration(0))
+	assert.Equal(t, conf.GetBurstFactor(), DefaultBurstFactor)
+	conf.BurstWindow = time.Minute
+	conf.BurstFactor = 8
+	assert.Equal(t, conf.GetBurstWindow(), time.Minute)
+	assert.Equal(t, conf.GetBurstFactor(), 8)
+	conf.BurstWindow = -1
+	conf.BurstFactor = -1
+	assert.Equal(t, conf.GetBurstWindow(), time.Duration(0))
+	assert.Equal(t, conf.GetBurstFactor(), DefaultBurstFactor)
+}
+
+func TestGetProvisioningClassName(t *testing.T) {
+	conf := &SchedulerConf{}
+	assert.Equal(t, conf.GetProvisioningClassName(), DefaultProvisioningClass)
+	conf.ProvisioningClassName = "check-capacity.autoscaling.x-k8s.io"
+	assert.Equal(t, conf.GetProvisioningClassName(), "check-capacity.autoscaling.x-k8s.io")
+}
+
+func TestGetGangStatusInterval(t *testing.T) {
+	conf := &SchedulerConf{}
+	assert.Equal(t, conf.GetGangStatusInterval(), time.Duration(0))
+	conf.GangStatusInterval = 10 * time.Second
+	assert.Equal(t, conf.GetGangStatusInterval(), 10*time.Second)
+	conf.GangStatusInterval = -time.Second
+	assert.Equal(t, conf.GetGangStatusInterval(), time.Duration(0))
+}
+
+func TestGetReservationWatchdogGrace(t *testing.T) {
+	conf := &SchedulerConf{}
+	grace, enabled := conf.GetReservationWatchdogGrace()
+	assert.Equal(t, grace, time.Duration(0))
+	assert.Assert(t, enabled)
+	conf.ReservationWatchdogGrace = time.Minute
+	grace, enabled = conf.GetReservationWatchdogGrace()
+	assert.Equal(t, grace, time.Minute)
+	assert.Assert(t, enabled)
+	conf.ReservationWatchdogGrace = -time.Second
+	_, enabled = conf.GetReservationWatchdogGrace()
+	assert.Assert(t, !enabled)
+}
+
+func TestGetRecoveryBatchSize(t *testing.T) {
+	conf := &SchedulerConf{}
+	assert.Equal(t, conf.GetRecoveryBatchSize(), 0)
+	conf.RecoveryBatchSize = -1
+	assert.Equal(t, conf.GetRecoveryBatchSize(), 0)
+	conf.RecoveryBatchSize = 1000
+	assert.Equal(t, conf.GetRecoveryBatchSize(), 1000)
+}
+
+func TestGetEventAuditSize(t *testing.T) {
+	conf := &SchedulerConf{}
+	assert.Equal(t, conf.GetEventAuditSize(), 0)
+	conf.EventAuditSize = -1
+	assert.Equal(t, conf.GetEventAuditSize(), 0)
+	conf.EventAuditSize = 100
+	assert.Equal(t, conf.GetEventAuditSize(), 100)
+}
+
+func TestGetPlaceholderLingerWindow(t *testing.T) {
+	conf := &SchedulerConf{}
+	assert.Equal(t, conf.GetPlaceholderLingerWindow(), time.Duration(0))
+	conf.PlaceholderLingerWindow = -time.Minute
+	assert.Equal(t, conf.GetPlaceholderLingerWindow(), time.Duration(0))
+	conf.PlaceholderLingerWindow = 2 * time.Minute
+	assert.Equal(t, conf.GetPlaceholderLingerWindow(), 2*time.Minute)
+}
+
+func TestGetSILogging(t *testing.T) {
+	conf := &SchedulerConf{}
+	enabled, rate, redact := conf.GetSILogging()
+	assert.Assert(t, !enabled)
+	assert.Equal(t, rate, float64(1))
+	assert.Assert(t, !redact)
+	conf.EnableSILogging = true
+	conf.SILogSampleRate = 0.25
+	conf.SILogRedact = true
+	enabled, rate, redact = conf.GetSILogging()
+	assert.Assert(t, enabled)
+	assert.Equal(t, rate, 0.25)
+	assert.Assert(t, redact)
+	conf.SILogSampleRate = 2
+	_, rate, _ = conf.GetSILogging()
+	assert.Equal(t, rate, float64(1))
+}
+
+func TestGetPlaceholderRestoreWindow(t *testing.T) {
+	conf := &SchedulerConf{}
+	assert.Equal(t, conf.GetPlaceholderRestoreWindow(), time.Duration(0))
+	conf.PlaceholderRestoreWindow = -time.Minute
+	assert.Equal(t, conf.GetPlaceholderRestoreWindow(), time.Duration(0))
+	conf.PlaceholderRestoreWindow = 5 * time.Minute
+	assert.Equal(t, conf.GetPlaceholderRestoreWindow(), 5*time.Minute)
+}
+
+func TestGetPodEventCoalescePeriod(t *testing.T) {
+	conf := &SchedulerConf{}
+	assert.Equal(t, conf.GetPodEventCoalescePeriod(), time.Duration(0))
+	conf.PodEventCoalescePeriod = 100 * time.Millisecond
+	assert.Equal(t, conf.GetPodEventCoalescePeriod(), 100*time.Millisecond)
+}
+
+func TestClone(t *testing.T) {
+	conf := &SchedulerConf{ClusterID: "cluster", KubeQPS: 10, KillTimeout: time.Minute}
+	clone := conf.Clone()
+	assert.Equal(t, clone.ClusterID, "cluster")
+	assert.Equal(t, clone.KubeQPS, 10)
+	assert.Equal(t, clone.KillTimeout, time.Minute)
+	// the clone is not updated with the configuration
+	conf.SetKubeClientLimits(20, 30)
+	assert.Equal(t, clone.KubeQPS, 10)
 }
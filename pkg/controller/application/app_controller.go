@@ -65,6 +65,7 @@ func (appMgr *AppManager) ServiceInit() error {
 func (appMgr *AppManager) Start() error {
 	appMgr.apiProvider.AddEventHandler(&client.ResourceEventHandlers{
 		Type:     client.ApplicationInformerHandlers,
+		FilterFn: appMgr.filterApps,
 		AddFn:    appMgr.addApp,
 		DeleteFn: appMgr.deleteApp,
 	})
@@ -80,41 +81,79 @@ func (appMgr *AppManager) Stop() {
 func (appMgr *AppManager) HandleApplicationStateUpdate() func(obj interface{}) {
 	return func(obj interface{}) {
 		if event, ok := obj.(events.ApplicationEvent); ok {
-			if events.AppStateChange == event.GetEvent() {
+			switch event.GetEvent() {
+			case events.AppStateChange:
 				if shimEvent, ok := event.(shimcache.ApplicationStatusChangeEvent); ok {
-					appID := event.GetApplicationID()
-					log.Logger().Info("Status Change callback received",
-						zap.String("app id", appID),
-						zap.String("new status", shimEvent.GetState()))
-					var app = appMgr.amProtocol.GetApplication(appID).(*shimcache.Application)
-					appName, err := getNameFromAppID(appID)
-					if err != nil {
-						log.Logger().Warn("Failed to handle status update",
-							zap.String("application ID", appID),
-							zap.Error(err))
-					}
-					appCRD, err := appMgr.apiProvider.GetAPIs().AppInformer.Lister().Applications(app.GetTags()[constants.AppTagNamespace]).Get(appName)
-					if err != nil {
-						log.Logger().Warn("Failed to query app CRD for status update",
-							zap.String("Application ID", appID),
-							zap.Error(err))
-					}
-					crdState := convertShimAppStateToAppCRDState(shimEvent.GetState())
-					if crdState != "Undefined" {
-						appMgr.updateAppCRDStatus(appCRD, crdState)
-					} else {
-						log.Logger().Error("Invalid status, skip saving it",
-							zap.String("App id", appID))
-						//create some error
-					}
-					log.Logger().Debug("Application status changed",
-						zap.String("AppID", appID))
+					appMgr.handleAppStateChange(shimEvent)
 				}
+			case events.AppTaskGroupsChange:
+				appMgr.handleAppTaskGroupsChange(event.GetApplicationID())
 			}
 		}
 	}
 }
 
+func (appMgr *AppManager) handleAppStateChange(shimEvent shimcache.ApplicationStatusChangeEvent) {
+	appID := shimEvent.GetApplicationID()
+	log.Logger().Info("Status Change callback received",
+		zap.String("app id", appID),
+		zap.String("new status", shimEvent.GetState()))
+	var app = appMgr.amProtocol.GetApplication(appID).(*shimcache.Application)
+	appName, err := getNameFromAppID(appID)
+	if err != nil {
+		log.Logger().Warn("Failed to handle status update",
+			zap.String("application ID", appID),
+			zap.Error(err))
+	}
+	appCRD, err := appMgr.apiProvider.GetAPIs().AppInformer.Lister().Applications(app.GetTags()[constants.AppTagNamespace]).Get(appName)
+	if err != nil {
+		log.Logger().Warn("Failed to query app CRD for status update",
+			zap.String("Application ID", appID),
+			zap.Error(err))
+	}
+	crdState := convertShimAppStateToAppCRDState(shimEvent.GetState())
+	if crdState != "Undefined" {
+		appMgr.updateAppCRDStatus(appCRD, crdState, app.GetTaskGroupStatuses())
+	} else {
+		log.Logger().Error("Invalid status, skip saving it",
+			zap.String("App id", appID))
+		//create some error
+	}
+	log.Logger().Debug("Application status changed",
+		zap.String("AppID", appID))
+}
+
+// handleAppTaskGroupsChange saves the placeholder statistics of the task groups in the CRD of the app,
+// the apps without a CRD are skipped
+func (appMgr *AppManager) handleAppTaskGroupsChange(appID string) {
+	managed := appMgr.amProtocol.GetApplication(appID)
+	if managed == nil {
+		return
+	}
+	app, ok := managed.(*shimcache.Application)
+	if !ok {
+		return
+	}
+	appName, err := getNameFromAppID(appID)
+	if err != nil {
+		return
+	}
+	appCRD, err := appMgr.apiProvider.GetAPIs().AppInformer.Lister().Applications(app.GetTags()[constants.AppTagNamespace]).Get(appName)
+	if err != nil || appCRD == nil {
+		log.Logger().Debug("no app CRD to save the task group statistics in",
+			zap.String("Application ID", appID))
+		return
+	}
+	appCopy := appCRD.DeepCopy()
+	appCopy.Status.TaskGroups = app.GetTaskGroupStatuses()
+	appCopy.Status.LastUpdate = v1.NewTime(time.Now())
+	if _, err = appMgr.apiProvider.GetAPIs().AppClient.ApacheV1alpha1().Applications(appCRD.Namespace).UpdateStatus(appCopy); err != nil {
+		log.Logger().Error("Failed to update the task groups of the application CRD",
+			zap.String("AppId", appCopy.Name),
+			zap.Error(err))
+	}
+}
+
 /*
 Remove the application from the scheduler as well
 */
@@ -139,6 +178,15 @@ func (appMgr *AppManager) deleteApp(obj interface{}) {
 /*
 Add application to scheduler
 */
+// filterApps skips the apps of the namespaces the pods are not watched in
+func (appMgr *AppManager) filterApps(obj interface{}) bool {
+	appCRD, ok := obj.(*appv1.Application)
+	if !ok {
+		return false
+	}
+	return appMgr.apiProvider.GetAPIs().IsNamespaceWatched(appCRD.Namespace)
+}
+
 func (appMgr *AppManager) addApp(obj interface{}) {
 	appCRD, ok := obj.(*appv1.Application)
 	if !ok {
@@ -153,13 +201,56 @@ func (appMgr *AppManager) addApp(obj interface{}) {
 			})
 			// set and save status = New in case it is not set. In case of recovery don't overwrite it
 			if len(appCRD.Status.AppStatus) == 0 {
-				appMgr.updateAppCRDStatus(appCRD, appv1.NewApplicationState)
+				appMgr.updateAppCRDStatus(appCRD, appv1.NewApplicationState, nil)
 			}
+			appMgr.adoptPods(appCRD, appMeta.ApplicationID)
 		}
 	}
 }
 
-func (appMgr *AppManager) updateAppCRDStatus(appCRD *appv1.Application, status appv1.ApplicationStateType) {
+// adoptPods adds the pods matching the selector of the app to the app, the pods of other schedulers already
+// bound to a node are adopted, e.g. the pods created before yunikorn was rolled out. Their resources are
+// accounted for the app from the start. Returns the number of pods adopted.
+func (appMgr *AppManager) adoptPods(appCRD *appv1.Application, appID string) int {
+	if appCRD.Spec.Selector == nil {
+		return 0
+	}
+	selector, err := v1.LabelSelectorAsSelector(appCRD.Spec.Selector)
+	if err != nil {
+		log.Logger().Warn("invalid selector of the app, no pods are adopted",
+			zap.String("appID", appID),
+			zap.Error(err))
+		return 0
+	}
+	// an empty selector matches all the pods of the namespace, this is most likely a mistake
+	if selector.Empty() {
+		log.Logger().Warn("empty selector of the app, no pods are adopted",
+			zap.String("appID", appID))
+		return 0
+	}
+	pods, err := appMgr.apiProvider.GetAPIs().PodInformer.Lister().Pods(appCRD.Namespace).List(selector)
+	if err != nil {
+		log.Logger().Warn("failed to list the pods to adopt",
+			zap.String("appID", appID),
+			zap.Error(err))
+		return 0
+	}
+	adopted := 0
+	for _, pod := range pods {
+		if appMgr.amProtocol.AdoptPod(appID, pod) {
+			adopted++
+		}
+	}
+	if adopted > 0 {
+		log.Logger().Info("app adopted existing pods",
+			zap.String("appID", appID),
+			zap.Int("adoptedPods", adopted))
+	}
+	return adopted
+}
+
+func (appMgr *AppManager) updateAppCRDStatus(appCRD *appv1.Application, status appv1.ApplicationStateType,
+	taskGroups []appv1.TaskGroupStatus) {
 	if appCRD == nil {
 		log.Logger().Error("AppCRD is nil, there is nothing to update")
 		return
@@ -169,6 +260,7 @@ func (appMgr *AppManager) updateAppCRDStatus(appCRD *appv1.Application, status a
 		AppStatus:  status,
 		Message:    "app CRD status change",
 		LastUpdate: v1.NewTime(time.Now()),
+		TaskGroups: taskGroups,
 	}
 	_, err := appMgr.apiProvider.GetAPIs().AppClient.ApacheV1alpha1().Applications(appCRD.Namespace).UpdateStatus(appCopy)
 	if err != nil {
@@ -22,12 +22,15 @@ import (
 	"testing"
 
 	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
 	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 
 	appv1 "github.com/apache/incubator-yunikorn-k8shim/pkg/apis/yunikorn.apache.org/v1alpha1"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/cache"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/test"
 )
 
 const defaultName = "example"
@@ -63,6 +66,72 @@ func TestAddApp(t *testing.T) {
 	assert.Equal(t, managedApp.GetApplicationID(), appID)
 }
 
+func TestFilterApps(t *testing.T) {
+	apiProvider := client.NewMockedAPIProvider()
+	am := NewAppManager(cache.NewMockedAMProtocol(), apiProvider)
+	app := createApp(defaultName, defaultNamespace, defaultQueue)
+	assert.Assert(t, am.filterApps(&app))
+	assert.Assert(t, !am.filterApps(&v1.Pod{}))
+
+	apiProvider.GetAPIs().WatchedNamespaces = []string{"tenant-a"}
+	assert.Assert(t, !am.filterApps(&app))
+	app.Namespace = "tenant-a"
+	assert.Assert(t, am.filterApps(&app))
+}
+
+func TestAdoptPods(t *testing.T) {
+	apiProvider := client.NewMockedAPIProvider()
+	podLister := test.NewPodListerMock()
+	apiProvider.SetPodLister(podLister)
+	am := NewAppManager(cache.NewMockedAMProtocol(), apiProvider)
+	newPod := func(name, namespace string, labels map[string]string) *v1.Pod {
+		pod := &v1.Pod{
+			ObjectMeta: apis.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				UID:       types.UID("uid-" + name),
+				Labels:    labels,
+			},
+			Spec: v1.PodSpec{NodeName: "node-1"},
+		}
+		podLister.AddPod(pod)
+		return pod
+	}
+	newPod("pod-1", defaultNamespace, map[string]string{"app": "legacy"})
+	newPod("pod-2", defaultNamespace, map[string]string{"app": "legacy", "role": "worker"})
+	newPod("pod-3", "other", map[string]string{"app": "legacy"})
+	newPod("pod-4", defaultNamespace, map[string]string{"app": "other"})
+
+	app := createApp(defaultName, defaultNamespace, defaultQueue)
+	app.Spec.Selector = &apis.LabelSelector{MatchLabels: map[string]string{"app": "legacy"}}
+	am.addApp(&app)
+	appID := constructAppID(defaultName, defaultNamespace)
+	managedApp := am.amProtocol.GetApplication(appID)
+	assert.Assert(t, managedApp != nil)
+	// only the matching pods of the namespace of the app are adopted
+	for _, uid := range []string{"uid-pod-1", "uid-pod-2"} {
+		task, err := managedApp.GetTask(uid)
+		assert.NilError(t, err)
+		assert.Equal(t, task.GetTaskState(), events.States().Task.Bound)
+	}
+	for _, uid := range []string{"uid-pod-3", "uid-pod-4"} {
+		_, err := managedApp.GetTask(uid)
+		assert.Assert(t, err != nil)
+	}
+	// the pods are adopted once
+	assert.Equal(t, am.adoptPods(&app, appID), 0)
+
+	// no selector, an empty selector or an invalid selector does not adopt any pod
+	app.Spec.Selector = nil
+	assert.Equal(t, am.adoptPods(&app, appID), 0)
+	app.Spec.Selector = &apis.LabelSelector{}
+	assert.Equal(t, am.adoptPods(&app, appID), 0)
+	app.Spec.Selector = &apis.LabelSelector{MatchExpressions: []apis.LabelSelectorRequirement{
+		{Key: "app", Operator: "Unknown"},
+	}}
+	assert.Equal(t, am.adoptPods(&app, appID), 0)
+}
+
 func TestGetAppMetadata(t *testing.T) {
 	am := NewAppManager(cache.NewMockedAMProtocol(), client.NewMockedAPIProvider())
 	app := createApp(defaultName, defaultNamespace, defaultQueue)
@@ -144,6 +213,8 @@ func TestHandleApplicationStateUpdate(t *testing.T) {
 		{"Not application event", cache.NewBindTaskEvent(appID, "taskID")},
 		{"Not AppStateChange event", cache.NewSimpleApplicationEvent(appID, events.AcceptApplication)},
 		{"AppStateChange event", cache.NewApplicationStatusChangeEvent(appID, events.AppStateChange, "New")},
+		{"AppTaskGroupsChange event", cache.NewApplicationTaskGroupsChangeEvent(appID)},
+		{"AppTaskGroupsChange event of unknown app", cache.NewApplicationTaskGroupsChangeEvent("unknown-app")},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -0,0 +1,157 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package dispatcher
+
+import (
+	"sync"
+	"time"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/webservice/dao"
+)
+
+// eventAudit keeps the last dispatched events in a ring buffer, the oldest event is overwritten
+// when the buffer is full. Errors reported by a handler are attached to the event being handled.
+type eventAudit struct {
+	records  []dao.DispatchedEvent
+	next     int
+	full     bool
+	handling bool
+	errors   []string
+	lock     sync.Mutex
+}
+
+// newEventAudit returns an audit keeping the given number of events, nil is returned if the size is 0
+func newEventAudit(size int) *eventAudit {
+	if size <= 0 {
+		return nil
+	}
+	return &eventAudit{
+		records: make([]dao.DispatchedEvent, size),
+	}
+}
+
+// start marks the beginning of the handling of an event
+func (a *eventAudit) start() {
+	if a == nil {
+		return
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.handling = true
+	a.errors = nil
+}
+
+// addError attaches an error to the event being handled, nothing is recorded outside of the handling of an event
+func (a *eventAudit) addError(err error) {
+	if a == nil || err == nil {
+		return
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.handling {
+		a.errors = append(a.errors, err.Error())
+	}
+}
+
+// finish records the event handled since the last start
+func (a *eventAudit) finish(eventType EventType, event events.SchedulingEvent, begin time.Time, duration time.Duration) {
+	if a == nil {
+		return
+	}
+	record := describeEvent(eventType, event)
+	record.Timestamp = begin
+	record.Duration = duration.String()
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.handling = false
+	for i, err := range a.errors {
+		if i > 0 {
+			record.Error += "; "
+		}
+		record.Error += err
+	}
+	a.errors = nil
+	a.records[a.next] = record
+	a.next = (a.next + 1) % len(a.records)
+	if a.next == 0 {
+		a.full = true
+	}
+}
+
+// list returns the recorded events of the app, or of all apps if appID is empty, the oldest event first
+func (a *eventAudit) list(appID string) []dao.DispatchedEvent {
+	result := make([]dao.DispatchedEvent, 0)
+	if a == nil {
+		return result
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	first, count := 0, a.next
+	if a.full {
+		first, count = a.next, len(a.records)
+	}
+	for i := 0; i < count; i++ {
+		record := a.records[(first+i)%len(a.records)]
+		if appID == "" || record.ApplicationID == appID {
+			result = append(result, record)
+		}
+	}
+	return result
+}
+
+// describeEvent returns the audit record of an event without the handling details
+func describeEvent(eventType EventType, event events.SchedulingEvent) dao.DispatchedEvent {
+	record := dao.DispatchedEvent{
+		Type: eventType.String(),
+	}
+	switch v := event.(type) {
+	case events.ApplicationStatusEvent:
+		record.Event = v.GetState()
+		if app, ok := event.(events.ApplicationEvent); ok {
+			record.ApplicationID = app.GetApplicationID()
+		}
+	case events.ApplicationEvent:
+		record.Event = string(v.GetEvent())
+		record.ApplicationID = v.GetApplicationID()
+	case events.TaskEvent:
+		record.Event = string(v.GetEvent())
+		record.ApplicationID = v.GetApplicationID()
+		record.TaskID = v.GetTaskID()
+	case events.SchedulerEvent:
+		record.Event = string(v.GetEvent())
+	case events.SchedulerNodeEvent:
+		record.Event = string(v.GetEvent())
+		record.NodeID = v.GetNodeID()
+	}
+	return record
+}
+
+// GetDispatchedEvents returns the last events handled by the dispatcher, the oldest event first.
+// The events can be limited to a single app, an empty appID returns the events of all apps.
+func GetDispatchedEvents(appID string) []dao.DispatchedEvent {
+	return getDispatcher().audit.list(appID)
+}
+
+// RecordEventError attaches an error to the event that is being handled, it must be called by the event handler
+// while it handles the event. The error is kept with the event in the audit of the dispatched events.
+func RecordEventError(err error) {
+	getDispatcher().audit.addError(err)
+}
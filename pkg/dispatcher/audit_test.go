@@ -0,0 +1,99 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package dispatcher
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
+)
+
+func TestEventAudit(t *testing.T) {
+	// a disabled audit records nothing
+	audit := newEventAudit(0)
+	audit.start()
+	audit.addError(fmt.Errorf("ignored"))
+	audit.finish(EventTypeApp, TestAppEvent{appID: "app-1"}, time.Now(), time.Millisecond)
+	assert.Equal(t, len(audit.list("")), 0)
+
+	audit = newEventAudit(3)
+	// errors outside of the handling of an event are dropped
+	audit.addError(fmt.Errorf("ignored"))
+	for i := 0; i < 4; i++ {
+		audit.start()
+		if i == 3 {
+			audit.addError(fmt.Errorf("first"))
+			audit.addError(fmt.Errorf("second"))
+		}
+		audit.finish(EventTypeApp, TestAppEvent{
+			appID:     fmt.Sprintf("app-%d", i%2),
+			eventType: events.RunApplication,
+		}, time.Now(), time.Millisecond)
+	}
+
+	// the oldest event is overwritten
+	records := audit.list("")
+	assert.Equal(t, len(records), 3)
+	assert.Equal(t, records[0].ApplicationID, "app-1")
+	assert.Equal(t, records[1].ApplicationID, "app-0")
+	assert.Equal(t, records[2].ApplicationID, "app-1")
+	assert.Equal(t, records[0].Type, "Application")
+	assert.Equal(t, records[0].Event, string(events.RunApplication))
+	assert.Equal(t, records[0].Duration, "1ms")
+	assert.Equal(t, records[0].Error, "")
+	assert.Equal(t, records[2].Error, "first; second")
+
+	records = audit.list("app-0")
+	assert.Equal(t, len(records), 1)
+	assert.Equal(t, records[0].ApplicationID, "app-0")
+}
+
+func TestDispatchedEvents(t *testing.T) {
+	RegisterEventHandler(EventTypeApp, func(obj interface{}) {
+		if event, ok := obj.(events.ApplicationEvent); ok && event.GetEvent() == events.FailApplication {
+			RecordEventError(fmt.Errorf("app cannot fail"))
+		}
+	})
+	Start()
+	defer Stop()
+
+	Dispatch(TestAppEvent{
+		appID:     "test-audit-app",
+		eventType: events.RunApplication,
+	})
+	Dispatch(TestAppEvent{
+		appID:     "test-audit-app",
+		eventType: events.FailApplication,
+	})
+	err := utils.WaitForCondition(func() bool {
+		return len(GetDispatchedEvents("test-audit-app")) == 2
+	}, 10*time.Millisecond, 3*time.Second)
+	assert.NilError(t, err)
+
+	records := GetDispatchedEvents("test-audit-app")
+	assert.Equal(t, records[0].Event, string(events.RunApplication))
+	assert.Equal(t, records[0].Error, "")
+	assert.Equal(t, records[1].Event, string(events.FailApplication))
+	assert.Equal(t, records[1].Error, "app cannot fail")
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/trace"
 )
 
 var dispatcher *Dispatcher
@@ -44,6 +45,23 @@ const (
 	EventTypeAppStatus
 )
 
+func (t EventType) String() string {
+	switch t {
+	case EventTypeApp:
+		return "Application"
+	case EventTypeTask:
+		return "Task"
+	case EventTypeNode:
+		return "Node"
+	case EventTypeScheduler:
+		return "Scheduler"
+	case EventTypeAppStatus:
+		return "ApplicationStatus"
+	default:
+		return fmt.Sprintf("EventType(%d)", int8(t))
+	}
+}
+
 var (
 	AsyncDispatchLimit         int32
 	AsyncDispatchCheckInterval = 3 * time.Second
@@ -56,6 +74,7 @@ type Dispatcher struct {
 	eventChan chan events.SchedulingEvent
 	stopChan  chan struct{}
 	handlers  map[EventType]func(interface{})
+	audit     *eventAudit
 	running   atomic.Value
 	lock      sync.RWMutex
 }
@@ -66,6 +85,7 @@ func initDispatcher() {
 		dispatcher = &Dispatcher{
 			eventChan: make(chan events.SchedulingEvent, eventChannelCapacity),
 			handlers:  make(map[EventType]func(interface{})),
+			audit:     newEventAudit(conf.GetSchedulerConf().GetEventAuditSize()),
 			stopChan:  make(chan struct{}),
 			running:   atomic.Value{},
 			lock:      sync.RWMutex{},
@@ -118,6 +138,16 @@ func Dispatch(event events.SchedulingEvent) {
 	}
 }
 
+// returns the number of events waiting in the event channel
+func GetEventQueueLength() int {
+	return len(getDispatcher().eventChan)
+}
+
+// returns the number of events waiting to be enqueued in async-dispatch mode
+func GetAsyncDispatchCount() int32 {
+	return atomic.LoadInt32(&asyncDispatchCount)
+}
+
 func (p *Dispatcher) isRunning() bool {
 	return p.running.Load().(bool)
 }
@@ -170,6 +200,44 @@ func (p *Dispatcher) asyncDispatch(event events.SchedulingEvent) {
 	}(time.Now(), p.stopChan)
 }
 
+// handle passes an event to the handler of its type and records it in the audit
+func (p *Dispatcher) handle(event events.SchedulingEvent) {
+	var eventType EventType
+	switch event.(type) {
+	case events.ApplicationStatusEvent:
+		eventType = EventTypeAppStatus
+	case events.ApplicationEvent:
+		eventType = EventTypeApp
+	case events.TaskEvent:
+		eventType = EventTypeTask
+	case events.SchedulerEvent:
+		eventType = EventTypeScheduler
+	case events.SchedulerNodeEvent:
+		eventType = EventTypeNode
+	default:
+		log.Logger().Fatal("unsupported event",
+			zap.Any("event", event))
+		return
+	}
+
+	p.audit.start()
+	begin := time.Now()
+	handler := getEventHandler(eventType)
+	if handler == nil {
+		err := fmt.Errorf("no handler registered for %s events", eventType)
+		log.Logger().Error("failed to handle SchedulingEvent", zap.Error(err))
+		p.audit.addError(err)
+	} else if taskEvent, ok := event.(events.TaskEvent); ok {
+		span := trace.StartTaskSpan(taskEvent.GetTaskID(), "HandleTaskEvent")
+		span.SetTag(trace.TagEvent, string(taskEvent.GetEvent()))
+		handler(event)
+		span.Finish()
+	} else {
+		handler(event)
+	}
+	p.audit.finish(eventType, event, begin, time.Since(begin))
+}
+
 func (p *Dispatcher) drain() {
 	for len(p.eventChan) > 0 {
 		log.Logger().Info("wait dispatcher to drain",
@@ -185,21 +253,7 @@ func Start() {
 		for {
 			select {
 			case event := <-getDispatcher().eventChan:
-				switch v := event.(type) {
-				case events.ApplicationStatusEvent:
-					getEventHandler(EventTypeAppStatus)(v)
-				case events.ApplicationEvent:
-					getEventHandler(EventTypeApp)(v)
-				case events.TaskEvent:
-					getEventHandler(EventTypeTask)(v)
-				case events.SchedulerEvent:
-					getEventHandler(EventTypeScheduler)(v)
-				case events.SchedulerNodeEvent:
-					getEventHandler(EventTypeNode)(v)
-				default:
-					log.Logger().Fatal("unsupported event",
-						zap.Any("event", v))
-				}
+				getDispatcher().handle(event)
 			case <-getDispatcher().stopChan:
 				log.Logger().Info("shutting down event channel")
 				getDispatcher().setRunning(false)
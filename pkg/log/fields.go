@@ -0,0 +1,62 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package log
+
+import (
+	"go.uber.org/zap"
+)
+
+// common field keys used to tag log lines related to an application or a task,
+// these keys are shared across modules, so an operator can filter the entire
+// lifecycle of a single application by one key/value pair.
+const (
+	FieldAppID     = "appID"
+	FieldQueue     = "queue"
+	FieldUser      = "user"
+	FieldTaskID    = "taskID"
+	FieldTaskAlias = "taskAlias"
+	FieldTaskGroup = "taskGroup"
+)
+
+// AppFields returns the structured context of an application
+func AppFields(appID, queue, user string) []zap.Field {
+	return []zap.Field{
+		zap.String(FieldAppID, appID),
+		zap.String(FieldQueue, queue),
+		zap.String(FieldUser, user),
+	}
+}
+
+// TaskFields returns the structured context of a task,
+// the task group field is only added when the task belongs to a task group.
+func TaskFields(taskID, taskAlias, taskGroup string) []zap.Field {
+	fields := []zap.Field{
+		zap.String(FieldTaskID, taskID),
+		zap.String(FieldTaskAlias, taskAlias),
+	}
+	if taskGroup != "" {
+		fields = append(fields, zap.String(FieldTaskGroup, taskGroup))
+	}
+	return fields
+}
+
+// ForApplication returns a logger that tags every log line with the application context
+func ForApplication(appID, queue, user string) *zap.Logger {
+	return Logger().With(AppFields(appID, queue, user)...)
+}
@@ -0,0 +1,52 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package log
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestAppFields(t *testing.T) {
+	fields := AppFields("app-1", "root.a", "bob")
+	assert.Equal(t, len(fields), 3)
+	assert.Equal(t, fields[0].Key, FieldAppID)
+	assert.Equal(t, fields[0].String, "app-1")
+	assert.Equal(t, fields[1].Key, FieldQueue)
+	assert.Equal(t, fields[1].String, "root.a")
+	assert.Equal(t, fields[2].Key, FieldUser)
+	assert.Equal(t, fields[2].String, "bob")
+}
+
+func TestTaskFields(t *testing.T) {
+	// task without a task group
+	fields := TaskFields("task-1", "default/pod-1", "")
+	assert.Equal(t, len(fields), 2)
+	assert.Equal(t, fields[0].Key, FieldTaskID)
+	assert.Equal(t, fields[0].String, "task-1")
+	assert.Equal(t, fields[1].Key, FieldTaskAlias)
+	assert.Equal(t, fields[1].String, "default/pod-1")
+
+	// task belongs to a task group
+	fields = TaskFields("task-1", "default/pod-1", "tg-1")
+	assert.Equal(t, len(fields), 3)
+	assert.Equal(t, fields[2].Key, FieldTaskGroup)
+	assert.Equal(t, fields[2].String, "tg-1")
+}
@@ -0,0 +1,114 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	BindSucceeded = "success"
+	BindFailed    = "failure"
+)
+
+// BindMetrics tracks the pod binds of the shim, the wait latency is the time an allocation spends in
+// the bind queue and the bind latency is the time of the volume and pod binding calls to the API server.
+type BindMetrics struct {
+	waitLatency prometheus.Histogram
+	bindLatency *prometheus.HistogramVec
+	pending     prometheus.Gauge
+}
+
+var bindMetrics = newBindMetrics()
+
+func newBindMetrics() *BindMetrics {
+	return &BindMetrics{
+		waitLatency: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Namespace: Namespace,
+				Subsystem: ShimSubsystem,
+				Name:      "bind_wait_latency_seconds",
+				Help:      "Time between an allocation being queued for binding and a bind worker picking it up.",
+				Buckets:   prometheus.ExponentialBuckets(0.001, 2, 15),
+			}),
+		bindLatency: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: Namespace,
+				Subsystem: ShimSubsystem,
+				Name:      "bind_latency_seconds",
+				Help:      "Time taken to bind the volumes and the pod of an allocation.",
+				Buckets:   prometheus.ExponentialBuckets(0.001, 2, 15),
+			}, []string{"result"}),
+		pending: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: ShimSubsystem,
+				Name:      "bind_pending",
+				Help:      "Number of allocations queued or being bound.",
+			}),
+	}
+}
+
+// GetBindMetrics returns the bind metrics of the shim, these can be updated before they are registered.
+func GetBindMetrics() *BindMetrics {
+	return bindMetrics
+}
+
+func (m *BindMetrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.waitLatency, m.bindLatency, m.pending}
+}
+
+func (m *BindMetrics) ObserveBindWait(latency time.Duration) {
+	m.waitLatency.Observe(latency.Seconds())
+}
+
+func (m *BindMetrics) ObserveBind(result string, latency time.Duration) {
+	m.bindLatency.WithLabelValues(result).Observe(latency.Seconds())
+}
+
+func (m *BindMetrics) IncPending() {
+	m.pending.Inc()
+}
+
+func (m *BindMetrics) DecPending() {
+	m.pending.Dec()
+}
+
+func (m *BindMetrics) GetPending() int {
+	metric := &dto.Metric{}
+	if err := m.pending.Write(metric); err != nil {
+		return 0
+	}
+	return int(metric.GetGauge().GetValue())
+}
+
+func (m *BindMetrics) GetBindCount(result string) int {
+	metric := &dto.Metric{}
+	observer, err := m.bindLatency.GetMetricWithLabelValues(result)
+	if err != nil {
+		return 0
+	}
+	if err = observer.(prometheus.Metric).Write(metric); err != nil {
+		return 0
+	}
+	return int(metric.GetHistogram().GetSampleCount())
+}
@@ -0,0 +1,57 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gotest.tools/assert"
+)
+
+func TestBindMetrics(t *testing.T) {
+	m := newBindMetrics()
+	registry := prometheus.NewRegistry()
+	for _, collector := range m.collectors() {
+		assert.NilError(t, registry.Register(collector))
+	}
+
+	m.IncPending()
+	m.IncPending()
+	m.DecPending()
+	assert.Equal(t, m.GetPending(), 1)
+
+	m.ObserveBindWait(10 * time.Millisecond)
+	m.ObserveBind(BindSucceeded, 20*time.Millisecond)
+	m.ObserveBind(BindSucceeded, 30*time.Millisecond)
+	m.ObserveBind(BindFailed, time.Second)
+	assert.Equal(t, m.GetBindCount(BindSucceeded), 2)
+	assert.Equal(t, m.GetBindCount(BindFailed), 1)
+
+	families, err := registry.Gather()
+	assert.NilError(t, err)
+	names := make(map[string]bool)
+	for _, family := range families {
+		names[family.GetName()] = true
+	}
+	assert.Assert(t, names["yunikorn_k8shim_bind_wait_latency_seconds"])
+	assert.Assert(t, names["yunikorn_k8shim_bind_latency_seconds"])
+	assert.Assert(t, names["yunikorn_k8shim_bind_pending"])
+}
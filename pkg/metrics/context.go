@@ -0,0 +1,78 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Context is the structured context of the application a task or placeholder belongs to, it is added as
+// labels to the series of the task and placeholder metrics, the same way it is added as fields to the logs.
+// The application ID is left out on purpose: these series are never deleted, so only bounded values are used.
+type Context struct {
+	Queue     string
+	User      string
+	TaskGroup string
+}
+
+// contextLabels are the label names of the context, in the order of the values returned by labelValues
+var contextLabels = []string{"queue", "user", "task_group"}
+
+// withContextLabels returns the given label names followed by the context label names
+func withContextLabels(labels ...string) []string {
+	return append(labels, contextLabels...)
+}
+
+// labelValues returns the given label values followed by the context label values
+func (c Context) labelValues(values ...string) []string {
+	return append(values, c.Queue, c.User, c.TaskGroup)
+}
+
+// sumCounter returns the sum of the series of the counter that match all the given labels,
+// the labels not given are aggregated.
+func sumCounter(counter *prometheus.CounterVec, labels prometheus.Labels) int {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		counter.Collect(ch)
+		close(ch)
+	}()
+	total := 0.0
+	for m := range ch {
+		metric := &dto.Metric{}
+		if err := m.Write(metric); err != nil || !matchLabels(metric, labels) {
+			continue
+		}
+		total += metric.GetCounter().GetValue()
+	}
+	return int(total)
+}
+
+func matchLabels(metric *dto.Metric, labels prometheus.Labels) bool {
+	matched := 0
+	for _, pair := range metric.GetLabel() {
+		if value, ok := labels[pair.GetName()]; ok {
+			if value != pair.GetValue() {
+				return false
+			}
+			matched++
+		}
+	}
+	return matched == len(labels)
+}
@@ -0,0 +1,69 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/webservice/dao"
+)
+
+// collectorsProvider is implemented by the metrics of the shim, its collectors are registered by Register
+type collectorsProvider interface {
+	collectors() []prometheus.Collector
+}
+
+// providers returns the metrics of the shim, these can be updated before they are registered
+func providers() []collectorsProvider {
+	return []collectorsProvider{
+		placeholderMetrics,
+		bindMetrics,
+		recoveryMetrics,
+		reconcileMetrics,
+		taskMetrics,
+		reservationMetrics,
+		schedulingMetrics,
+		stateHookMetrics,
+	}
+}
+
+// Register registers the metrics of the shim in the default registry, these are served together with the
+// scheduler core metrics. The resource usage and the pending resources are computed when the metrics are scraped.
+// A collector that fails to register does not stop the others, the first failure is returned.
+func Register(usageFn func() *dao.ResourceUsage, pendingFn func() []*dao.ApplicationPendingResource) error {
+	return register(prometheus.DefaultRegisterer, usageFn, pendingFn)
+}
+
+func register(registerer prometheus.Registerer, usageFn func() *dao.ResourceUsage,
+	pendingFn func() []*dao.ApplicationPendingResource) error {
+	collectors := []prometheus.Collector{
+		newResourceUsageCollector(usageFn),
+		newPendingResourceCollector(pendingFn),
+	}
+	for _, provider := range providers() {
+		collectors = append(collectors, provider.collectors()...)
+	}
+	var registerErr error
+	for _, collector := range collectors {
+		if err := registerer.Register(collector); err != nil && registerErr == nil {
+			registerErr = err
+		}
+	}
+	return registerErr
+}
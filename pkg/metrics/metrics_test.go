@@ -0,0 +1,67 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/webservice/dao"
+)
+
+func TestRegister(t *testing.T) {
+	usageFn := func() *dao.ResourceUsage {
+		return &dao.ResourceUsage{}
+	}
+	pendingFn := func() []*dao.ApplicationPendingResource {
+		return nil
+	}
+	registry := prometheus.NewRegistry()
+	assert.NilError(t, register(registry, usageFn, pendingFn))
+
+	// registering twice fails
+	err := register(registry, usageFn, pendingFn)
+	_, ok := err.(prometheus.AlreadyRegisteredError)
+	assert.Assert(t, ok, "unexpected error %v", err)
+
+	// all the collectors of the shim are registered
+	for _, provider := range providers() {
+		for _, collector := range provider.collectors() {
+			assert.Assert(t, registry.Unregister(collector))
+		}
+	}
+}
+
+func TestRegisterContinuesOnError(t *testing.T) {
+	usageFn := func() *dao.ResourceUsage {
+		return &dao.ResourceUsage{}
+	}
+	pendingFn := func() []*dao.ApplicationPendingResource {
+		return nil
+	}
+	// the resource usage collector is already registered, the other collectors are still registered
+	registry := prometheus.NewRegistry()
+	assert.NilError(t, registry.Register(newResourceUsageCollector(usageFn)))
+	err := register(registry, usageFn, pendingFn)
+	_, ok := err.(prometheus.AlreadyRegisteredError)
+	assert.Assert(t, ok, "unexpected error %v", err)
+	assert.Assert(t, registry.Unregister(bindMetrics.collectors()[0]))
+}
@@ -0,0 +1,149 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// PlaceholderMetrics tracks how the placeholders of the gang apps end up, the success rate of a task group is
+// the number of replaced placeholders divided by the total of replaced, timed out and orphaned placeholders.
+type PlaceholderMetrics struct {
+	replacementLatency *prometheus.HistogramVec
+	replaced           *prometheus.CounterVec
+	timedOut           *prometheus.CounterVec
+	orphaned           *prometheus.CounterVec
+	preempted          *prometheus.CounterVec
+	restored           *prometheus.CounterVec
+}
+
+var placeholderMetrics = newPlaceholderMetrics()
+
+func newPlaceholderMetrics() *PlaceholderMetrics {
+	return &PlaceholderMetrics{
+		replacementLatency: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: Namespace,
+				Subsystem: ShimSubsystem,
+				Name:      "placeholder_replacement_latency_seconds",
+				Help:      "Time between a placeholder being bound and the real member replacing it being bound.",
+				Buckets:   prometheus.ExponentialBuckets(0.1, 2, 15),
+			}, contextLabels),
+		replaced: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: Namespace,
+				Subsystem: ShimSubsystem,
+				Name:      "placeholder_replaced_total",
+				Help:      "Number of placeholders replaced by a real member of the task group.",
+			}, contextLabels),
+		timedOut: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: Namespace,
+				Subsystem: ShimSubsystem,
+				Name:      "placeholder_timeout_total",
+				Help:      "Number of placeholders released by the scheduler core because they timed out.",
+			}, contextLabels),
+		orphaned: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: Namespace,
+				Subsystem: ShimSubsystem,
+				Name:      "placeholder_orphaned_total",
+				Help:      "Number of placeholder pods that could not be deleted and are left for a retry.",
+			}, contextLabels),
+		preempted: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: Namespace,
+				Subsystem: ShimSubsystem,
+				Name:      "placeholder_preempted_total",
+				Help:      "Number of placeholders removed from their node without being released by the scheduler.",
+			}, contextLabels),
+		restored: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: Namespace,
+				Subsystem: ShimSubsystem,
+				Name:      "placeholder_restored_total",
+				Help:      "Number of placeholders re-created for a real member that failed shortly after replacing it.",
+			}, contextLabels),
+	}
+}
+
+// GetPlaceholderMetrics returns the placeholder metrics of the shim, these can be updated
+// before they are registered.
+func GetPlaceholderMetrics() *PlaceholderMetrics {
+	return placeholderMetrics
+}
+
+func (m *PlaceholderMetrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.replacementLatency, m.replaced, m.timedOut, m.orphaned, m.preempted, m.restored}
+}
+
+// ObservePlaceholderReplaced records a placeholder replaced by a real member, the latency is
+// the time between the placeholder and the real member being bound.
+func (m *PlaceholderMetrics) ObservePlaceholderReplaced(ctx Context, latency time.Duration) {
+	m.replaced.WithLabelValues(ctx.labelValues()...).Inc()
+	m.replacementLatency.WithLabelValues(ctx.labelValues()...).Observe(latency.Seconds())
+}
+
+func (m *PlaceholderMetrics) IncPlaceholderTimedOut(ctx Context) {
+	m.timedOut.WithLabelValues(ctx.labelValues()...).Inc()
+}
+
+func (m *PlaceholderMetrics) IncPlaceholderOrphaned(ctx Context) {
+	m.orphaned.WithLabelValues(ctx.labelValues()...).Inc()
+}
+
+func (m *PlaceholderMetrics) IncPlaceholderPreempted(ctx Context) {
+	m.preempted.WithLabelValues(ctx.labelValues()...).Inc()
+}
+
+func (m *PlaceholderMetrics) IncPlaceholderRestored(ctx Context) {
+	m.restored.WithLabelValues(ctx.labelValues()...).Inc()
+}
+
+// PlaceholderCounts is the outcome of the placeholders of a task group
+type PlaceholderCounts struct {
+	Replaced  int
+	TimedOut  int
+	Orphaned  int
+	Preempted int
+	Restored  int
+}
+
+// GetPlaceholderCounts returns the outcome of the placeholders of a task group summed over the queues and users
+func (m *PlaceholderMetrics) GetPlaceholderCounts(taskGroup string) PlaceholderCounts {
+	labels := prometheus.Labels{"task_group": taskGroup}
+	return PlaceholderCounts{
+		Replaced:  sumCounter(m.replaced, labels),
+		TimedOut:  sumCounter(m.timedOut, labels),
+		Orphaned:  sumCounter(m.orphaned, labels),
+		Preempted: sumCounter(m.preempted, labels),
+		Restored:  sumCounter(m.restored, labels),
+	}
+}
+
+func counterValue(counter *prometheus.CounterVec, label string) int {
+	metric := &dto.Metric{}
+	if err := counter.WithLabelValues(label).Write(metric); err != nil {
+		return 0
+	}
+	return int(metric.GetCounter().GetValue())
+}
@@ -0,0 +1,66 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gotest.tools/assert"
+)
+
+func TestPlaceholderMetrics(t *testing.T) {
+	m := newPlaceholderMetrics()
+	registry := prometheus.NewRegistry()
+	for _, collector := range m.collectors() {
+		assert.NilError(t, registry.Register(collector))
+	}
+
+	tg1 := Context{Queue: "root.a", User: "alice", TaskGroup: "tg-1"}
+	tg2 := Context{Queue: "root.b", User: "bob", TaskGroup: "tg-2"}
+	m.ObservePlaceholderReplaced(tg1, 2*time.Second)
+	m.ObservePlaceholderReplaced(tg1, 4*time.Second)
+	m.IncPlaceholderTimedOut(tg1)
+	// the same task group in another queue is summed in the counts of the task group
+	m.IncPlaceholderTimedOut(Context{Queue: "root.b", User: "alice", TaskGroup: "tg-1"})
+	m.IncPlaceholderOrphaned(tg2)
+	m.IncPlaceholderPreempted(tg2)
+	m.IncPlaceholderRestored(tg2)
+	assert.Equal(t, m.GetPlaceholderCounts("tg-1"), PlaceholderCounts{Replaced: 2, TimedOut: 2})
+	assert.Equal(t, m.GetPlaceholderCounts("tg-2"), PlaceholderCounts{Orphaned: 1, Preempted: 1, Restored: 1})
+
+	families, err := registry.Gather()
+	assert.NilError(t, err)
+	for _, family := range families {
+		if family.GetName() != "yunikorn_k8shim_placeholder_replacement_latency_seconds" {
+			continue
+		}
+		labels := make(map[string]string)
+		for _, pair := range family.GetMetric()[0].GetLabel() {
+			labels[pair.GetName()] = pair.GetValue()
+		}
+		assert.DeepEqual(t, labels, map[string]string{"queue": "root.a", "user": "alice", "task_group": "tg-1"})
+		histogram := family.GetMetric()[0].GetHistogram()
+		assert.Equal(t, histogram.GetSampleCount(), uint64(2))
+		assert.Equal(t, histogram.GetSampleSum(), float64(6))
+		return
+	}
+	t.Fatal("replacement latency histogram is not registered")
+}
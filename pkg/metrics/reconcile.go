@@ -0,0 +1,78 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ReconcileMetrics tracks the divergences between the allocations of the shim and the allocations of the core,
+// the divergences found by the last comparison and the divergences healed so far are reported per kind.
+type ReconcileMetrics struct {
+	divergences *prometheus.GaugeVec
+	healed      *prometheus.CounterVec
+}
+
+var reconcileMetrics = newReconcileMetrics()
+
+func newReconcileMetrics() *ReconcileMetrics {
+	return &ReconcileMetrics{
+		divergences: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: ShimSubsystem,
+				Name:      "state_divergences",
+				Help:      "Number of allocations known to only one of the shim and the core per kind of divergence.",
+			}, []string{"kind"}),
+		healed: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: Namespace,
+				Subsystem: ShimSubsystem,
+				Name:      "state_divergences_healed_total",
+				Help:      "Number of divergences between the shim and the core healed per kind of divergence.",
+			}, []string{"kind"}),
+	}
+}
+
+// GetReconcileMetrics returns the reconcile metrics of the shim, these can be updated before they are registered.
+func GetReconcileMetrics() *ReconcileMetrics {
+	return reconcileMetrics
+}
+
+func (m *ReconcileMetrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.divergences, m.healed}
+}
+
+func (m *ReconcileMetrics) SetDivergences(kind string, count int) {
+	m.divergences.WithLabelValues(kind).Set(float64(count))
+}
+
+func (m *ReconcileMetrics) AddHealed(kind string, count int) {
+	m.healed.WithLabelValues(kind).Add(float64(count))
+}
+
+// GetDivergences returns the number of divergences of a kind found by the last comparison
+func (m *ReconcileMetrics) GetDivergences(kind string) int {
+	return gaugeValue(m.divergences, kind)
+}
+
+// GetHealed returns the number of divergences of a kind healed so far
+func (m *ReconcileMetrics) GetHealed(kind string) int {
+	return counterValue(m.healed, kind)
+}
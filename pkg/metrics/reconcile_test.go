@@ -0,0 +1,46 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gotest.tools/assert"
+)
+
+func TestReconcileMetrics(t *testing.T) {
+	m := newReconcileMetrics()
+	registry := prometheus.NewRegistry()
+	for _, collector := range m.collectors() {
+		assert.NilError(t, registry.Register(collector))
+	}
+
+	assert.Equal(t, m.GetDivergences("missing"), 0)
+	m.SetDivergences("missing", 3)
+	m.SetDivergences("missing", 2)
+	m.SetDivergences("ghost", 1)
+	assert.Equal(t, m.GetDivergences("missing"), 2)
+	assert.Equal(t, m.GetDivergences("ghost"), 1)
+
+	m.AddHealed("ghost", 1)
+	m.AddHealed("ghost", 2)
+	assert.Equal(t, m.GetHealed("ghost"), 3)
+	assert.Equal(t, m.GetHealed("missing"), 0)
+}
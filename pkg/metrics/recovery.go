@@ -0,0 +1,132 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// RecoveryMetrics tracks the recovery of the shim after a restart, the objects to recover and the objects
+// recovered are reported per phase while the recovery is running.
+type RecoveryMetrics struct {
+	total     *prometheus.GaugeVec
+	recovered *prometheus.GaugeVec
+	duration  prometheus.Gauge
+	requests  prometheus.Gauge
+	reported  prometheus.Gauge
+}
+
+var recoveryMetrics = newRecoveryMetrics()
+
+func newRecoveryMetrics() *RecoveryMetrics {
+	return &RecoveryMetrics{
+		total: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: ShimSubsystem,
+				Name:      "recovery_total",
+				Help:      "Number of objects to recover per recovery phase.",
+			}, []string{"phase"}),
+		recovered: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: ShimSubsystem,
+				Name:      "recovery_recovered",
+				Help:      "Number of objects recovered per recovery phase.",
+			}, []string{"phase"}),
+		duration: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: ShimSubsystem,
+				Name:      "recovery_duration_seconds",
+				Help:      "Time taken by the recovery, set once the recovery succeeded or failed.",
+			}),
+		requests: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: ShimSubsystem,
+				Name:      "recovery_bulk_requests",
+				Help:      "Number of requests sent to the scheduler to recover the nodes in bulk.",
+			}),
+		reported: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: ShimSubsystem,
+				Name:      "recovery_bulk_allocations",
+				Help:      "Number of existing allocations reported to the scheduler by the bulk recovery requests.",
+			}),
+	}
+}
+
+// GetRecoveryMetrics returns the recovery metrics of the shim, these can be updated before they are registered.
+func GetRecoveryMetrics() *RecoveryMetrics {
+	return recoveryMetrics
+}
+
+func (m *RecoveryMetrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.total, m.recovered, m.duration, m.requests, m.reported}
+}
+
+func (m *RecoveryMetrics) SetProgress(phase string, total, recovered int) {
+	m.total.WithLabelValues(phase).Set(float64(total))
+	m.recovered.WithLabelValues(phase).Set(float64(recovered))
+}
+
+func (m *RecoveryMetrics) SetDuration(duration time.Duration) {
+	m.duration.Set(duration.Seconds())
+}
+
+// SetBulkProgress sets the number of bulk recovery requests sent and the existing allocations they reported
+func (m *RecoveryMetrics) SetBulkProgress(requests, allocations int) {
+	m.requests.Set(float64(requests))
+	m.reported.Set(float64(allocations))
+}
+
+// GetBulkProgress returns the number of bulk recovery requests sent and the existing allocations they reported
+func (m *RecoveryMetrics) GetBulkProgress() (int, int) {
+	return gaugeInt(m.requests), gaugeInt(m.reported)
+}
+
+func gaugeInt(gauge prometheus.Gauge) int {
+	metric := &dto.Metric{}
+	if err := gauge.Write(metric); err != nil {
+		return 0
+	}
+	return int(metric.GetGauge().GetValue())
+}
+
+// GetProgress returns the number of objects to recover and recovered of a phase
+func (m *RecoveryMetrics) GetProgress(phase string) (int, int) {
+	return gaugeValue(m.total, phase), gaugeValue(m.recovered, phase)
+}
+
+func gaugeValue(vec *prometheus.GaugeVec, phase string) int {
+	metric := &dto.Metric{}
+	gauge, err := vec.GetMetricWithLabelValues(phase)
+	if err != nil {
+		return 0
+	}
+	if err = gauge.Write(metric); err != nil {
+		return 0
+	}
+	return int(metric.GetGauge().GetValue())
+}
@@ -0,0 +1,68 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gotest.tools/assert"
+)
+
+func TestRecoveryMetrics(t *testing.T) {
+	m := newRecoveryMetrics()
+	registry := prometheus.NewRegistry()
+	for _, collector := range m.collectors() {
+		assert.NilError(t, registry.Register(collector))
+	}
+
+	total, recovered := m.GetProgress("Nodes")
+	assert.Equal(t, total, 0)
+	assert.Equal(t, recovered, 0)
+	m.SetProgress("Nodes", 10, 4)
+	m.SetProgress("Nodes", 10, 7)
+	m.SetProgress("Applications", 3, 3)
+	total, recovered = m.GetProgress("Nodes")
+	assert.Equal(t, total, 10)
+	assert.Equal(t, recovered, 7)
+	total, recovered = m.GetProgress("Applications")
+	assert.Equal(t, total, 3)
+	assert.Equal(t, recovered, 3)
+
+	requests, reported := m.GetBulkProgress()
+	assert.Equal(t, requests, 0)
+	assert.Equal(t, reported, 0)
+	m.SetBulkProgress(2, 8000)
+	requests, reported = m.GetBulkProgress()
+	assert.Equal(t, requests, 2)
+	assert.Equal(t, reported, 8000)
+
+	m.SetDuration(90 * time.Second)
+	families, err := registry.Gather()
+	assert.NilError(t, err)
+	for _, family := range families {
+		if family.GetName() != "yunikorn_k8shim_recovery_duration_seconds" {
+			continue
+		}
+		assert.Equal(t, family.GetMetric()[0].GetGauge().GetValue(), float64(90))
+		return
+	}
+	t.Fatal("recovery duration gauge is not registered")
+}
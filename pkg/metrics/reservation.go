@@ -0,0 +1,87 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ReservationMetrics tracks the apps reserving resources for their gang per queue, and the apps waiting in
+// the Accepted state because their queue has reached the maximum number of reserving apps.
+type ReservationMetrics struct {
+	reserving   *prometheus.GaugeVec
+	waiting     *prometheus.GaugeVec
+	waitLatency prometheus.Histogram
+}
+
+var reservationMetrics = newReservationMetrics()
+
+func newReservationMetrics() *ReservationMetrics {
+	return &ReservationMetrics{
+		reserving: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: ShimSubsystem,
+				Name:      "reservation_apps_reserving",
+				Help:      "Number of apps reserving resources for their gang per queue.",
+			}, []string{"queue"}),
+		waiting: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: ShimSubsystem,
+				Name:      "reservation_apps_waiting",
+				Help:      "Number of apps waiting for their queue to allow another reserving app.",
+			}, []string{"queue"}),
+		waitLatency: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Namespace: Namespace,
+				Subsystem: ShimSubsystem,
+				Name:      "reservation_wait_seconds",
+				Help:      "Time an app waited for its queue to allow another reserving app.",
+				Buckets:   prometheus.ExponentialBuckets(1, 2, 15),
+			}),
+	}
+}
+
+// GetReservationMetrics returns the reservation metrics of the shim, these can be updated before they are registered.
+func GetReservationMetrics() *ReservationMetrics {
+	return reservationMetrics
+}
+
+func (m *ReservationMetrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.reserving, m.waiting, m.waitLatency}
+}
+
+// SetQueue sets the number of reserving and waiting apps of a queue
+func (m *ReservationMetrics) SetQueue(queue string, reserving, waiting int) {
+	m.reserving.WithLabelValues(queue).Set(float64(reserving))
+	m.waiting.WithLabelValues(queue).Set(float64(waiting))
+}
+
+// GetQueue returns the number of reserving and waiting apps of a queue
+func (m *ReservationMetrics) GetQueue(queue string) (int, int) {
+	return gaugeValue(m.reserving, queue), gaugeValue(m.waiting, queue)
+}
+
+// ObserveWait records the time an app waited before it was allowed to reserve
+func (m *ReservationMetrics) ObserveWait(wait time.Duration) {
+	m.waitLatency.Observe(wait.Seconds())
+}
@@ -0,0 +1,54 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"gotest.tools/assert"
+)
+
+func TestReservationMetrics(t *testing.T) {
+	m := newReservationMetrics()
+	registry := prometheus.NewRegistry()
+	for _, collector := range m.collectors() {
+		assert.NilError(t, registry.Register(collector))
+	}
+
+	reserving, waiting := m.GetQueue("root.a")
+	assert.Equal(t, reserving, 0)
+	assert.Equal(t, waiting, 0)
+	m.SetQueue("root.a", 2, 3)
+	m.SetQueue("root.b", 1, 0)
+	reserving, waiting = m.GetQueue("root.a")
+	assert.Equal(t, reserving, 2)
+	assert.Equal(t, waiting, 3)
+	reserving, waiting = m.GetQueue("root.b")
+	assert.Equal(t, reserving, 1)
+	assert.Equal(t, waiting, 0)
+
+	m.ObserveWait(10 * time.Second)
+	metric := &dto.Metric{}
+	assert.NilError(t, m.waitLatency.Write(metric))
+	assert.Equal(t, metric.GetHistogram().GetSampleCount(), uint64(1))
+	assert.Equal(t, metric.GetHistogram().GetSampleSum(), float64(10))
+}
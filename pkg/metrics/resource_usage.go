@@ -0,0 +1,108 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/webservice/dao"
+)
+
+const (
+	// all metrics should be declared under this namespace
+	Namespace = "yunikorn"
+	// ShimSubsystem - subsystem name used by the k8shim
+	ShimSubsystem = "k8shim"
+)
+
+// resourceUsageCollector reports the resources allocated to the bound tasks per queue and per user,
+// the usage is computed when the metrics are scraped so it is always in line with the shim cache.
+type resourceUsageCollector struct {
+	usageFn   func() *dao.ResourceUsage
+	queueDesc *prometheus.Desc
+	userDesc  *prometheus.Desc
+}
+
+func newResourceUsageCollector(usageFn func() *dao.ResourceUsage) *resourceUsageCollector {
+	return &resourceUsageCollector{
+		usageFn: usageFn,
+		queueDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, ShimSubsystem, "queue_allocated_resource"),
+			"Resources allocated to the bound tasks of a queue, as seen by the shim.",
+			[]string{"queue", "resource"}, nil),
+		userDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, ShimSubsystem, "user_allocated_resource"),
+			"Resources allocated to the bound tasks of a user, as seen by the shim.",
+			[]string{"user", "resource"}, nil),
+	}
+}
+
+// pendingResourceCollector reports the resources the apps are still waiting for,
+// it is computed when the metrics are scraped like the resource usage.
+type pendingResourceCollector struct {
+	pendingFn func() []*dao.ApplicationPendingResource
+	appDesc   *prometheus.Desc
+}
+
+func newPendingResourceCollector(pendingFn func() []*dao.ApplicationPendingResource) *pendingResourceCollector {
+	return &pendingResourceCollector{
+		pendingFn: pendingFn,
+		appDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, ShimSubsystem, "app_pending_resource"),
+			"Resources asked by the tasks of an application that are not allocated yet.",
+			[]string{"application_id", "queue", "resource"}, nil),
+	}
+}
+
+func (c *pendingResourceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.appDesc
+}
+
+func (c *pendingResourceCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, app := range c.pendingFn() {
+		for name, value := range app.Pending {
+			ch <- prometheus.MustNewConstMetric(c.appDesc, prometheus.GaugeValue,
+				float64(value), app.ApplicationID, app.Queue, name)
+		}
+	}
+}
+
+func (c *resourceUsageCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.queueDesc
+	ch <- c.userDesc
+}
+
+func (c *resourceUsageCollector) Collect(ch chan<- prometheus.Metric) {
+	usage := c.usageFn()
+	if usage == nil {
+		return
+	}
+	for _, queue := range usage.Queues {
+		for name, value := range queue.Allocated {
+			ch <- prometheus.MustNewConstMetric(c.queueDesc, prometheus.GaugeValue,
+				float64(value), queue.QueueName, name)
+		}
+	}
+	for _, user := range usage.Users {
+		for name, value := range user.Allocated {
+			ch <- prometheus.MustNewConstMetric(c.userDesc, prometheus.GaugeValue,
+				float64(value), user.User, name)
+		}
+	}
+}
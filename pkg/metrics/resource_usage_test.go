@@ -0,0 +1,89 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/webservice/dao"
+)
+
+func TestResourceUsageCollector(t *testing.T) {
+	usage := &dao.ResourceUsage{
+		Queues: []dao.QueueResourceUsage{
+			{QueueName: "root.a", Allocated: map[string]int64{"vcore": 1000, "memory": 1024}, Tasks: 1},
+		},
+		Users: []dao.UserResourceUsage{
+			{User: "alice", Allocated: map[string]int64{"vcore": 1000}, Tasks: 1},
+		},
+	}
+	registry := prometheus.NewRegistry()
+	err := registry.Register(newResourceUsageCollector(func() *dao.ResourceUsage {
+		return usage
+	}))
+	assert.NilError(t, err)
+
+	families, err := registry.Gather()
+	assert.NilError(t, err)
+	values := make(map[string]float64)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			key := family.GetName()
+			for _, label := range metric.GetLabel() {
+				key += "/" + label.GetValue()
+			}
+			values[key] = metric.GetGauge().GetValue()
+		}
+	}
+	assert.Equal(t, len(values), 3)
+	assert.Equal(t, values["yunikorn_k8shim_queue_allocated_resource/root.a/vcore"], float64(1000))
+	assert.Equal(t, values["yunikorn_k8shim_queue_allocated_resource/root.a/memory"], float64(1024))
+	// labels are sorted by name
+	assert.Equal(t, values["yunikorn_k8shim_user_allocated_resource/vcore/alice"], float64(1000))
+}
+
+func TestPendingResourceCollector(t *testing.T) {
+	pending := []*dao.ApplicationPendingResource{
+		{ApplicationID: "app-1", Queue: "root.a", Pending: map[string]int64{"vcore": 2000, "memory": 512}, Tasks: 2},
+	}
+	registry := prometheus.NewRegistry()
+	err := registry.Register(newPendingResourceCollector(func() []*dao.ApplicationPendingResource {
+		return pending
+	}))
+	assert.NilError(t, err)
+
+	families, err := registry.Gather()
+	assert.NilError(t, err)
+	values := make(map[string]float64)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			key := family.GetName()
+			for _, label := range metric.GetLabel() {
+				key += "/" + label.GetValue()
+			}
+			values[key] = metric.GetGauge().GetValue()
+		}
+	}
+	assert.Equal(t, len(values), 2)
+	assert.Equal(t, values["yunikorn_k8shim_app_pending_resource/app-1/root.a/vcore"], float64(2000))
+	assert.Equal(t, values["yunikorn_k8shim_app_pending_resource/app-1/root.a/memory"], float64(512))
+}
@@ -0,0 +1,94 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// SchedulingMetrics tracks the scheduling cycles of the shim: the time taken by a cycle, the share of the time
+// of a cycle spent on each app, and the new tasks left for the next cycle because the budget of their app is used up.
+type SchedulingMetrics struct {
+	cycleDuration prometheus.Histogram
+	appTimeShare  prometheus.Histogram
+	deferred      prometheus.Counter
+}
+
+var schedulingMetrics = newSchedulingMetrics()
+
+func newSchedulingMetrics() *SchedulingMetrics {
+	return &SchedulingMetrics{
+		cycleDuration: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Namespace: Namespace,
+				Subsystem: ShimSubsystem,
+				Name:      "schedule_cycle_duration_seconds",
+				Help:      "Time taken to schedule all apps in a scheduling cycle.",
+				Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 16),
+			}),
+		appTimeShare: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Namespace: Namespace,
+				Subsystem: ShimSubsystem,
+				Name:      "schedule_app_time_share",
+				Help:      "Share of the time spent on an app in a scheduling cycle, observed per app and cycle.",
+				Buckets:   prometheus.LinearBuckets(0.1, 0.1, 10),
+			}),
+		deferred: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace: Namespace,
+				Subsystem: ShimSubsystem,
+				Name:      "schedule_deferred_tasks_total",
+				Help:      "Number of new tasks left for the next scheduling cycle because the budget of their app is used up.",
+			}),
+	}
+}
+
+// GetSchedulingMetrics returns the scheduling metrics of the shim, these can be updated before they are registered.
+func GetSchedulingMetrics() *SchedulingMetrics {
+	return schedulingMetrics
+}
+
+func (m *SchedulingMetrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.cycleDuration, m.appTimeShare, m.deferred}
+}
+
+func (m *SchedulingMetrics) ObserveCycle(duration time.Duration) {
+	m.cycleDuration.Observe(duration.Seconds())
+}
+
+// ObserveAppTimeShare observes the time spent on an app as a share of the time spent on all apps in a cycle
+func (m *SchedulingMetrics) ObserveAppTimeShare(share float64) {
+	m.appTimeShare.Observe(share)
+}
+
+func (m *SchedulingMetrics) AddDeferredTasks(count int) {
+	m.deferred.Add(float64(count))
+}
+
+func (m *SchedulingMetrics) GetDeferredTasks() int {
+	metric := &dto.Metric{}
+	if err := m.deferred.Write(metric); err != nil {
+		return 0
+	}
+	return int(metric.GetCounter().GetValue())
+}
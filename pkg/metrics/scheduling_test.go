@@ -0,0 +1,49 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"gotest.tools/assert"
+)
+
+func TestSchedulingMetrics(t *testing.T) {
+	m := newSchedulingMetrics()
+	registry := prometheus.NewRegistry()
+	for _, collector := range m.collectors() {
+		assert.NilError(t, registry.Register(collector))
+	}
+
+	m.ObserveCycle(10 * time.Millisecond)
+	m.ObserveAppTimeShare(0.75)
+	m.ObserveAppTimeShare(0.25)
+	metric := &dto.Metric{}
+	assert.NilError(t, m.appTimeShare.Write(metric))
+	assert.Equal(t, metric.GetHistogram().GetSampleCount(), uint64(2))
+	assert.Equal(t, metric.GetHistogram().GetSampleSum(), float64(1))
+
+	assert.Equal(t, m.GetDeferredTasks(), 0)
+	m.AddDeferredTasks(3)
+	m.AddDeferredTasks(0)
+	assert.Equal(t, m.GetDeferredTasks(), 3)
+}
@@ -0,0 +1,96 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// StateHookMetrics tracks the hooks run on the state transitions of the apps and the tasks,
+// the latency and the panics are reported per hook.
+type StateHookMetrics struct {
+	latency *prometheus.HistogramVec
+	panics  *prometheus.CounterVec
+}
+
+var stateHookMetrics = newStateHookMetrics()
+
+func newStateHookMetrics() *StateHookMetrics {
+	return &StateHookMetrics{
+		latency: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: Namespace,
+				Subsystem: ShimSubsystem,
+				Name:      "state_hook_latency_seconds",
+				Help:      "Time taken by a hook run on a state transition of an app or a task.",
+				Buckets:   prometheus.ExponentialBuckets(0.00001, 2, 16),
+			}, []string{"hook"}),
+		panics: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: Namespace,
+				Subsystem: ShimSubsystem,
+				Name:      "state_hook_panics_total",
+				Help:      "Number of panics recovered from a hook run on a state transition of an app or a task.",
+			}, []string{"hook"}),
+	}
+}
+
+// GetStateHookMetrics returns the state hook metrics of the shim, these can be updated before they are registered.
+func GetStateHookMetrics() *StateHookMetrics {
+	return stateHookMetrics
+}
+
+func (m *StateHookMetrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.latency, m.panics}
+}
+
+func (m *StateHookMetrics) ObserveHook(hook string, latency time.Duration) {
+	m.latency.WithLabelValues(hook).Observe(latency.Seconds())
+}
+
+func (m *StateHookMetrics) IncHookPanic(hook string) {
+	m.panics.WithLabelValues(hook).Inc()
+}
+
+func (m *StateHookMetrics) GetHookCount(hook string) int {
+	metric := &dto.Metric{}
+	observer, err := m.latency.GetMetricWithLabelValues(hook)
+	if err != nil {
+		return 0
+	}
+	if err = observer.(prometheus.Metric).Write(metric); err != nil {
+		return 0
+	}
+	return int(metric.GetHistogram().GetSampleCount())
+}
+
+func (m *StateHookMetrics) GetHookPanics(hook string) int {
+	metric := &dto.Metric{}
+	counter, err := m.panics.GetMetricWithLabelValues(hook)
+	if err != nil {
+		return 0
+	}
+	if err = counter.Write(metric); err != nil {
+		return 0
+	}
+	return int(metric.GetCounter().GetValue())
+}
@@ -0,0 +1,53 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gotest.tools/assert"
+)
+
+func TestStateHookMetrics(t *testing.T) {
+	m := newStateHookMetrics()
+	registry := prometheus.NewRegistry()
+	for _, collector := range m.collectors() {
+		assert.NilError(t, registry.Register(collector))
+	}
+
+	m.ObserveHook("notify", time.Millisecond)
+	m.ObserveHook("notify", 2*time.Millisecond)
+	m.ObserveHook("audit", time.Millisecond)
+	m.IncHookPanic("audit")
+	assert.Equal(t, m.GetHookCount("notify"), 2)
+	assert.Equal(t, m.GetHookCount("audit"), 1)
+	assert.Equal(t, m.GetHookPanics("notify"), 0)
+	assert.Equal(t, m.GetHookPanics("audit"), 1)
+
+	families, err := registry.Gather()
+	assert.NilError(t, err)
+	names := make(map[string]bool)
+	for _, family := range families {
+		names[family.GetName()] = true
+	}
+	assert.Assert(t, names["yunikorn_k8shim_state_hook_latency_seconds"])
+	assert.Assert(t, names["yunikorn_k8shim_state_hook_panics_total"])
+}
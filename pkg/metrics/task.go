@@ -0,0 +1,129 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	TaskSucceeded = "succeeded"
+	TaskFailed    = "failed"
+
+	EvictionSucceeded = "evicted"
+	EvictionBlocked   = "blocked"
+	EvictionFailed    = "failed"
+)
+
+// TaskMetrics tracks the execution of the pods of the tasks once they are bound: the tasks whose pod is running,
+// the tasks terminated by the phase of their pod per result, and the time the pods were running.
+// The evictions of the pods released by the scheduler are counted per result.
+type TaskMetrics struct {
+	running     prometheus.Gauge
+	terminated  *prometheus.CounterVec
+	runDuration *prometheus.HistogramVec
+	evictions   *prometheus.CounterVec
+}
+
+var taskMetrics = newTaskMetrics()
+
+func newTaskMetrics() *TaskMetrics {
+	return &TaskMetrics{
+		running: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: ShimSubsystem,
+				Name:      "tasks_running",
+				Help:      "Number of tasks whose pod is running.",
+			}),
+		terminated: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: Namespace,
+				Subsystem: ShimSubsystem,
+				Name:      "tasks_terminated_total",
+				Help:      "Number of bound tasks terminated by the phase of their pod per result.",
+			}, withContextLabels("result")),
+		runDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: Namespace,
+				Subsystem: ShimSubsystem,
+				Name:      "task_run_duration_seconds",
+				Help:      "Time between the pod of a task being reported running and its termination.",
+				Buckets:   prometheus.ExponentialBuckets(1, 2, 16),
+			}, withContextLabels("result")),
+		evictions: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: Namespace,
+				Subsystem: ShimSubsystem,
+				Name:      "release_evictions_total",
+				Help:      "Number of evictions of the pods released by the scheduler per result, blocked by a disruption budget or not.",
+			}, []string{"result"}),
+	}
+}
+
+// GetTaskMetrics returns the task metrics of the shim, these can be updated before they are registered.
+func GetTaskMetrics() *TaskMetrics {
+	return taskMetrics
+}
+
+func (m *TaskMetrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.running, m.terminated, m.runDuration, m.evictions}
+}
+
+func (m *TaskMetrics) IncRunning() {
+	m.running.Inc()
+}
+
+func (m *TaskMetrics) DecRunning() {
+	m.running.Dec()
+}
+
+// IncTerminated counts a task terminated by the phase of its pod,
+// the run duration is only observed for a task whose pod was reported running.
+func (m *TaskMetrics) IncTerminated(result string, runningTime time.Time, ctx Context) {
+	m.terminated.WithLabelValues(ctx.labelValues(result)...).Inc()
+	if !runningTime.IsZero() {
+		m.runDuration.WithLabelValues(ctx.labelValues(result)...).Observe(time.Since(runningTime).Seconds())
+	}
+}
+
+func (m *TaskMetrics) GetRunning() int {
+	metric := &dto.Metric{}
+	if err := m.running.Write(metric); err != nil {
+		return 0
+	}
+	return int(metric.GetGauge().GetValue())
+}
+
+// GetTerminated returns the tasks terminated with the result summed over the queues, users and task groups
+func (m *TaskMetrics) GetTerminated(result string) int {
+	return sumCounter(m.terminated, prometheus.Labels{"result": result})
+}
+
+// IncEviction counts an eviction of a pod released by the scheduler
+func (m *TaskMetrics) IncEviction(result string) {
+	m.evictions.WithLabelValues(result).Inc()
+}
+
+func (m *TaskMetrics) GetEvictions(result string) int {
+	return counterValue(m.evictions, result)
+}
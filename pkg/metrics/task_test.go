@@ -0,0 +1,66 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"gotest.tools/assert"
+)
+
+func TestTaskMetrics(t *testing.T) {
+	m := newTaskMetrics()
+	registry := prometheus.NewRegistry()
+	for _, collector := range m.collectors() {
+		assert.NilError(t, registry.Register(collector))
+	}
+
+	m.IncRunning()
+	m.IncRunning()
+	m.DecRunning()
+	assert.Equal(t, m.GetRunning(), 1)
+
+	ctx := Context{Queue: "root.a", User: "alice"}
+	m.IncTerminated(TaskSucceeded, time.Now().Add(-time.Minute), ctx)
+	m.IncTerminated(TaskFailed, time.Time{}, ctx)
+	m.IncTerminated(TaskFailed, time.Now(), Context{Queue: "root.b", User: "bob", TaskGroup: "tg-1"})
+	assert.Equal(t, m.GetTerminated(TaskSucceeded), 1)
+	assert.Equal(t, m.GetTerminated(TaskFailed), 2)
+
+	// the run duration is only observed for the tasks reported running
+	metric := &dto.Metric{}
+	observer, err := m.runDuration.GetMetricWithLabelValues("failed", "root.b", "bob", "tg-1")
+	assert.NilError(t, err)
+	assert.NilError(t, observer.(prometheus.Metric).Write(metric))
+	assert.Equal(t, metric.GetHistogram().GetSampleCount(), uint64(1))
+}
+
+func TestEvictionMetrics(t *testing.T) {
+	m := newTaskMetrics()
+	assert.Equal(t, m.GetEvictions(EvictionBlocked), 0)
+	m.IncEviction(EvictionBlocked)
+	m.IncEviction(EvictionBlocked)
+	m.IncEviction(EvictionSucceeded)
+	assert.Equal(t, m.GetEvictions(EvictionBlocked), 2)
+	assert.Equal(t, m.GetEvictions(EvictionSucceeded), 1)
+	assert.Equal(t, m.GetEvictions(EvictionFailed), 0)
+}
@@ -30,12 +30,16 @@ import (
 
 	"go.uber.org/zap"
 	"k8s.io/api/admission/v1beta1"
+	authenticationv1 "k8s.io/api/authentication/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
 )
 
@@ -43,6 +47,20 @@ const (
 	autoGenAppPrefix             = "yunikorn"
 	autoGenAppSuffix             = "autogen"
 	enableConfigHotRefreshEnvVar = "ENABLE_CONFIG_HOT_REFRESH"
+	enableSchedulingGateEnvVar   = "ENABLE_SCHEDULING_GATE"
+	enableUserInfoEnvVar         = "ENABLE_USER_INFO"
+	defaultQueueEnvVar           = "DEFAULT_QUEUE"
+	rejectUnmappedPodsEnvVar     = "REJECT_UNMAPPED_PODS"
+	namespaceDefaultQueuesEnvVar = "NAMESPACE_DEFAULT_QUEUES"
+	rejectUnmappedNsEnvVar       = "REJECT_UNMAPPED_NAMESPACES"
+	processNamespacesEnvVar      = "PROCESS_NAMESPACES"
+	bypassNamespacesEnvVar       = "BYPASS_NAMESPACES"
+	labelSelectorEnvVar          = "LABEL_SELECTOR"
+	processWorkloadKindsEnvVar   = "PROCESS_WORKLOAD_KINDS"
+	defaultRequestsEnvVar        = "DEFAULT_CONTAINER_REQUESTS"
+	rejectNoRequestsEnvVar       = "REJECT_PODS_WITHOUT_REQUESTS"
+	bareWorkloadKind             = "Pod"
+	defaultQueue                 = "root.default"
 )
 
 var (
@@ -54,6 +72,248 @@ var (
 type admissionController struct {
 	configName               string
 	schedulerValidateConfURL string
+	unmappedPods             *unmappedPodPolicy
+	selector                 *podSelector
+	requests                 *requestPolicy
+}
+
+// unmappedPodPolicy decides what happens to the pods without a queue label:
+// the queue mapped to the namespace of the pod is injected if there is one,
+// otherwise the pod is rejected or the default queue is injected.
+type unmappedPodPolicy struct {
+	defaultQueue       string
+	rejectAll          bool
+	namespaceQueues    map[string]string
+	rejectedNamespaces map[string]bool
+}
+
+// newUnmappedPodPolicy creates the policy from the environment variables of the admission controller
+func newUnmappedPodPolicy() *unmappedPodPolicy {
+	policy := &unmappedPodPolicy{
+		defaultQueue:       defaultQueue,
+		namespaceQueues:    make(map[string]string),
+		rejectedNamespaces: parseSet(os.Getenv(rejectUnmappedNsEnvVar)),
+	}
+	if queue := strings.TrimSpace(os.Getenv(defaultQueueEnvVar)); queue != "" {
+		policy.defaultQueue = queue
+	}
+	if reject := os.Getenv(rejectUnmappedPodsEnvVar); reject != "" {
+		rejectAll, err := strconv.ParseBool(reject)
+		if err != nil {
+			log.Logger().Error("Failed to parse REJECT_UNMAPPED_PODS value",
+				zap.String("REJECT_UNMAPPED_PODS", reject))
+		}
+		policy.rejectAll = rejectAll
+	}
+	for _, entry := range strings.Split(os.Getenv(namespaceDefaultQueuesEnvVar), ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		mapping := strings.SplitN(entry, "=", 2)
+		if len(mapping) != 2 || strings.TrimSpace(mapping[0]) == "" || strings.TrimSpace(mapping[1]) == "" {
+			log.Logger().Error("Failed to parse NAMESPACE_DEFAULT_QUEUES entry, expecting namespace=queue",
+				zap.String("entry", entry))
+			continue
+		}
+		policy.namespaceQueues[strings.TrimSpace(mapping[0])] = strings.TrimSpace(mapping[1])
+	}
+	return policy
+}
+
+// getQueue returns the queue injected into a pod of the namespace without a queue label,
+// an error is returned when the pod must be rejected.
+func (p *unmappedPodPolicy) getQueue(namespace string) (string, error) {
+	if queue, ok := p.namespaceQueues[namespace]; ok {
+		return queue, nil
+	}
+	if p.rejectAll || p.rejectedNamespaces[namespace] {
+		return "", fmt.Errorf("pod has no %s label and namespace %s has no default queue, "+
+			"set the %s label of the pod to the queue the pod should run in",
+			constants.LabelQueueName, namespace, constants.LabelQueueName)
+	}
+	return p.defaultQueue, nil
+}
+
+// podSelector decides which pods are handed to yunikorn, the pods that are not selected
+// are admitted untouched and keep the scheduler they ask for. This allows clusters to move
+// their workloads to yunikorn one namespace, label or workload kind at a time.
+// An empty rule places no restriction on the pods.
+type podSelector struct {
+	processNamespaces map[string]bool
+	bypassNamespaces  map[string]bool
+	labelSelector     labels.Selector
+	workloadKinds     map[string]bool
+}
+
+// newPodSelector creates the selector from the environment variables of the admission controller
+func newPodSelector() *podSelector {
+	selector := &podSelector{
+		processNamespaces: parseSet(os.Getenv(processNamespacesEnvVar)),
+		bypassNamespaces:  parseSet(os.Getenv(bypassNamespacesEnvVar)),
+		workloadKinds:     parseSet(os.Getenv(processWorkloadKindsEnvVar)),
+	}
+	if expr := strings.TrimSpace(os.Getenv(labelSelectorEnvVar)); expr != "" {
+		parsed, err := labels.Parse(expr)
+		if err != nil {
+			log.Logger().Error("Failed to parse LABEL_SELECTOR value, the label rule is ignored",
+				zap.String("LABEL_SELECTOR", expr),
+				zap.Error(err))
+		} else {
+			selector.labelSelector = parsed
+		}
+	}
+	return selector
+}
+
+// parseSet splits a comma-separated list into a set, blank entries are skipped
+func parseSet(value string) map[string]bool {
+	set := make(map[string]bool)
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			set[entry] = true
+		}
+	}
+	return set
+}
+
+// isSelected returns true if the pod must be scheduled by yunikorn,
+// a pod that asks for yunikorn explicitly is always selected.
+func (s *podSelector) isSelected(pod *v1.Pod) bool {
+	if s == nil || pod.Spec.SchedulerName == constants.SchedulerName {
+		return true
+	}
+	if s.bypassNamespaces[pod.Namespace] {
+		return false
+	}
+	if len(s.processNamespaces) > 0 && !s.processNamespaces[pod.Namespace] {
+		return false
+	}
+	if s.labelSelector != nil && !s.labelSelector.Matches(labels.Set(pod.Labels)) {
+		return false
+	}
+	if len(s.workloadKinds) > 0 && !s.workloadKinds[getWorkloadKind(pod)] {
+		return false
+	}
+	return true
+}
+
+// getWorkloadKind returns the kind of the controller owning the pod, or Pod for a bare pod
+func getWorkloadKind(pod *v1.Pod) string {
+	if owner := metav1.GetControllerOf(pod); owner != nil {
+		return owner.Kind
+	}
+	return bareWorkloadKind
+}
+
+// requestPolicy makes sure the containers of the pods handed to yunikorn carry resource requests,
+// a container without requests results in an ask without resources which is not counted against
+// the queue and skews the fairness between the queues. Like a LimitRange a missing request is set
+// to the limit of the container, or else to the configured default request.
+// The requests are rounded up to the units the scheduler works with: millicores for the cpu and
+// whole units for all other resources.
+type requestPolicy struct {
+	defaults    v1.ResourceList
+	rejectEmpty bool
+}
+
+// newRequestPolicy creates the policy from the environment variables of the admission controller,
+// the default requests are set as a comma-separated list of resource=quantity entries.
+func newRequestPolicy() *requestPolicy {
+	policy := &requestPolicy{
+		defaults:    make(v1.ResourceList),
+		rejectEmpty: isEnvEnabled(rejectNoRequestsEnvVar),
+	}
+	for _, entry := range strings.Split(os.Getenv(defaultRequestsEnvVar), ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		request := strings.SplitN(entry, "=", 2)
+		if len(request) != 2 || strings.TrimSpace(request[0]) == "" {
+			log.Logger().Error("Failed to parse DEFAULT_CONTAINER_REQUESTS entry, expecting resource=quantity",
+				zap.String("entry", entry))
+			continue
+		}
+		quantity, err := resource.ParseQuantity(strings.TrimSpace(request[1]))
+		if err != nil || quantity.Sign() < 0 {
+			log.Logger().Error("Failed to parse DEFAULT_CONTAINER_REQUESTS quantity, the entry is ignored",
+				zap.String("entry", entry))
+			continue
+		}
+		name := v1.ResourceName(strings.TrimSpace(request[0]))
+		policy.defaults[name] = normalizeQuantity(name, quantity)
+	}
+	return policy
+}
+
+// updateRequests sets the missing requests and normalizes the requests of all containers of the pod,
+// an error is returned when the pod must be rejected because a container has no requests at all.
+func (p *requestPolicy) updateRequests(pod *v1.Pod, patch []patchOperation) ([]patchOperation, error) {
+	if p == nil {
+		return patch, nil
+	}
+	var err error
+	for i := range pod.Spec.InitContainers {
+		path := fmt.Sprintf("/spec/initContainers/%d/resources", i)
+		if patch, err = p.updateContainer(pod, &pod.Spec.InitContainers[i], path, patch); err != nil {
+			return patch, err
+		}
+	}
+	for i := range pod.Spec.Containers {
+		path := fmt.Sprintf("/spec/containers/%d/resources", i)
+		if patch, err = p.updateContainer(pod, &pod.Spec.Containers[i], path, patch); err != nil {
+			return patch, err
+		}
+	}
+	return patch, nil
+}
+
+func (p *requestPolicy) updateContainer(pod *v1.Pod, container *v1.Container, path string, patch []patchOperation) ([]patchOperation, error) {
+	requests := make(v1.ResourceList, len(container.Resources.Requests))
+	changed := false
+	for name, quantity := range container.Resources.Requests {
+		normalized := normalizeQuantity(name, quantity)
+		changed = changed || normalized.Cmp(quantity) != 0
+		requests[name] = normalized
+	}
+	for name, limit := range container.Resources.Limits {
+		if _, ok := requests[name]; !ok {
+			requests[name] = normalizeQuantity(name, limit)
+			changed = true
+		}
+	}
+	for name, quantity := range p.defaults {
+		if _, ok := requests[name]; !ok {
+			requests[name] = quantity.DeepCopy()
+			changed = true
+		}
+	}
+	if len(requests) == 0 && p.rejectEmpty {
+		return patch, fmt.Errorf("container %s has no resource requests, "+
+			"set the requests of all containers of the pod", container.Name)
+	}
+	if !changed {
+		return patch, nil
+	}
+	log.Logger().Info("updating container resource requests",
+		zap.String("podName", pod.Name),
+		zap.String("generateName", pod.GenerateName),
+		zap.String("container", container.Name),
+		zap.Any("requests", requests))
+	container.Resources.Requests = requests
+	return append(patch, patchOperation{
+		Op:    "add",
+		Path:  path,
+		Value: container.Resources,
+	}), nil
+}
+
+// normalizeQuantity rounds the quantity up to millicores for the cpu and to whole units for all
+// other resources, e.g. a memory request in millibytes, the rounded quantity is in canonical form.
+func normalizeQuantity(name v1.ResourceName, quantity resource.Quantity) resource.Quantity {
+	if name == v1.ResourceCPU {
+		return *resource.NewMilliQuantity(quantity.MilliValue(), quantity.Format)
+	}
+	return *resource.NewQuantity(quantity.Value(), quantity.Format)
 }
 
 type patchOperation struct {
@@ -99,8 +359,55 @@ func (c *admissionController) mutate(ar *v1beta1.AdmissionReview) *v1beta1.Admis
 			}
 		}
 
+		// the pod object of a create request may not have the namespace set yet
+		if pod.Namespace == "" {
+			pod.Namespace = namespace
+		}
+		if !c.selector.isSelected(&pod) {
+			log.Logger().Info("ignore pod not selected for yunikorn",
+				zap.String("podName", pod.Name),
+				zap.String("generateName", pod.GenerateName),
+				zap.String("namespace", namespace))
+			return &v1beta1.AdmissionResponse{
+				Allowed: true,
+			}
+		}
+
+		if err := c.updateQueue(namespace, &pod); err != nil {
+			log.Logger().Info("rejecting pod without queue",
+				zap.String("podName", pod.Name),
+				zap.String("generateName", pod.GenerateName),
+				zap.String("namespace", namespace))
+			return &v1beta1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Message: err.Error(),
+				},
+			}
+		}
+
+		var err error
+		if patch, err = c.requests.updateRequests(&pod, patch); err != nil {
+			log.Logger().Info("rejecting pod without resource requests",
+				zap.String("podName", pod.Name),
+				zap.String("generateName", pod.GenerateName),
+				zap.String("namespace", namespace))
+			return &v1beta1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Message: err.Error(),
+				},
+			}
+		}
+
 		patch = updateSchedulerName(patch)
 		patch = updateLabels(namespace, &pod, patch)
+		if isUserInfoEnabled() {
+			patch = updateUserInfo(&pod, req.UserInfo, patch)
+		}
+		if isSchedulingGateEnabled() {
+			patch = updateSchedulingGates(&pod, patch)
+		}
 	}
 
 	patchBytes, err := json.Marshal(patch)
@@ -170,8 +477,8 @@ func updateLabels(namespace string, pod *v1.Pod, patch []patchOperation) []patch
 
 	if _, ok := existingLabels[constants.LabelQueueName]; !ok {
 		log.Logger().Debug("adding queue name",
-			zap.String("defaultQueue", "root.default"))
-		result[constants.LabelQueueName] = "root.default"
+			zap.String("defaultQueue", defaultQueue))
+		result[constants.LabelQueueName] = defaultQueue
 	}
 
 	patch = append(patch, patchOperation{
@@ -183,6 +490,112 @@ func updateLabels(namespace string, pod *v1.Pod, patch []patchOperation) []patch
 	return patch
 }
 
+// updateQueue sets the queue label of a pod without one according to the unmapped pod policy,
+// the label is then patched by updateLabels along with the other labels.
+func (c *admissionController) updateQueue(namespace string, pod *v1.Pod) error {
+	if _, ok := pod.Labels[constants.LabelQueueName]; ok || c.unmappedPods == nil {
+		return nil
+	}
+	queue, err := c.unmappedPods.getQueue(namespace)
+	if err != nil {
+		return err
+	}
+	log.Logger().Debug("adding queue name",
+		zap.String("namespace", namespace),
+		zap.String("queue", queue))
+	if pod.Labels == nil {
+		pod.Labels = make(map[string]string)
+	}
+	pod.Labels[constants.LabelQueueName] = queue
+	return nil
+}
+
+// the queue admission gate keeps the pod gated until its app is accepted by the queue,
+// the scheduler removes the gate once the app is accepted. Only the gates annotation is patched,
+// the other annotations are left as they are.
+func updateSchedulingGates(pod *v1.Pod, patch []patchOperation) []patchOperation {
+	gates := utils.GetSchedulingGates(pod)
+	for _, gate := range gates {
+		if gate == constants.SchedulingGateQueueAdmission {
+			return patch
+		}
+	}
+	log.Logger().Info("adding queue admission scheduling gate",
+		zap.String("podName", pod.Name),
+		zap.String("generateName", pod.GenerateName))
+	// the annotations map must exist before a key can be added to it
+	if pod.Annotations == nil {
+		utils.SetSchedulingGates(pod, []string{constants.SchedulingGateQueueAdmission})
+		return append(patch, patchOperation{
+			Op:    "add",
+			Path:  "/metadata/annotations",
+			Value: pod.Annotations,
+		})
+	}
+	utils.SetSchedulingGates(pod, append(gates, constants.SchedulingGateQueueAdmission))
+	return append(patch, patchOperation{
+		Op:    "add",
+		Path:  "/metadata/annotations/" + jsonPointerEscaper.Replace(constants.AnnotationSchedulingGates),
+		Value: pod.Annotations[constants.AnnotationSchedulingGates],
+	})
+}
+
+// escapes a key used as a JSON pointer reference token, see RFC 6901
+var jsonPointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// the user info annotation holds the authenticated user and groups of the request creating the pod,
+// a value set by the submitter is overwritten. The scheduler resolves the user of the app from this
+// annotation when it is configured with the annotation user resolver. A pod created by a controller,
+// e.g. the pods of a Deployment, carries the identity of the controller.
+func updateUserInfo(pod *v1.Pod, userInfo authenticationv1.UserInfo, patch []patchOperation) []patchOperation {
+	value, err := json.Marshal(utils.UserGroup{
+		User:   userInfo.Username,
+		Groups: userInfo.Groups,
+	})
+	if err != nil {
+		log.Logger().Error("failed to encode the user info", zap.Error(err))
+		return patch
+	}
+	if existing, ok := pod.Annotations[constants.AnnotationUserInfo]; ok && existing != string(value) {
+		log.Logger().Warn("overwriting the user info set on the pod",
+			zap.String("podName", pod.Name),
+			zap.String("generateName", pod.GenerateName),
+			zap.String("userInfo", existing))
+	}
+	if pod.Annotations == nil {
+		pod.Annotations = make(map[string]string)
+	}
+	pod.Annotations[constants.AnnotationUserInfo] = string(value)
+	return append(patch, patchOperation{
+		Op:    "add",
+		Path:  "/metadata/annotations",
+		Value: pod.Annotations,
+	})
+}
+
+func isSchedulingGateEnabled() bool {
+	return isEnvEnabled(enableSchedulingGateEnvVar)
+}
+
+func isUserInfoEnabled() bool {
+	return isEnvEnabled(enableUserInfoEnvVar)
+}
+
+// isEnvEnabled parses the boolean environment variable, an unset or invalid value is false
+func isEnvEnabled(envVar string) bool {
+	value := os.Getenv(envVar)
+	if value == "" {
+		return false
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Logger().Error("Failed to parse "+envVar+" value",
+			zap.String(envVar, value))
+		return false
+	}
+	return enabled
+}
+
 func isConfigMapUpdateAllowed(userInfo string) bool {
 	hotRefreshEnabled := os.Getenv(enableConfigHotRefreshEnvVar)
 	allowed, err := strconv.ParseBool(hotRefreshEnabled)
@@ -19,6 +19,7 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -28,10 +29,15 @@ import (
 	"testing"
 
 	"gotest.tools/assert"
+	"k8s.io/api/admission/v1beta1"
+	authenticationv1 "k8s.io/api/authentication/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
 )
 
@@ -244,6 +250,125 @@ func TestUpdateSchedulerName(t *testing.T) {
 	}
 }
 
+func TestUpdateSchedulingGates(t *testing.T) {
+	var patch []patchOperation
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "a-test-pod",
+			Namespace: "default",
+			Annotations: map[string]string{
+				"random":                            "random",
+				constants.AnnotationSchedulingGates: "example.com/gate",
+			},
+		},
+	}
+	patch = updateSchedulingGates(pod, patch)
+	assert.Equal(t, len(patch), 1)
+	assert.Equal(t, patch[0].Op, "add")
+	// only the gates annotation is patched
+	assert.Equal(t, patch[0].Path, "/metadata/annotations/yunikorn.apache.org~1scheduling-gates")
+	assert.Equal(t, patch[0].Value, "example.com/gate,"+constants.SchedulingGateQueueAdmission)
+
+	// the gate is only added once
+	patch = updateSchedulingGates(pod, make([]patchOperation, 0))
+	assert.Equal(t, len(patch), 0)
+
+	// the annotations are added when the pod has none
+	pod = &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "a-test-pod",
+			Namespace: "default",
+		},
+	}
+	patch = updateSchedulingGates(pod, make([]patchOperation, 0))
+	assert.Equal(t, len(patch), 1)
+	assert.Equal(t, patch[0].Path, "/metadata/annotations")
+	assert.DeepEqual(t, patch[0].Value, map[string]string{
+		constants.AnnotationSchedulingGates: constants.SchedulingGateQueueAdmission,
+	})
+}
+
+func TestUpdateUserInfo(t *testing.T) {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "a-test-pod",
+			Namespace: "default",
+			Annotations: map[string]string{
+				"random":                     "random",
+				constants.AnnotationUserInfo: `{"user":"admin"}`,
+			},
+		},
+	}
+	userInfo := authenticationv1.UserInfo{
+		Username: "alice",
+		Groups:   []string{"dev", "system:authenticated"},
+	}
+
+	// the user info set by the submitter is overwritten with the authenticated one
+	patch := updateUserInfo(pod, userInfo, make([]patchOperation, 0))
+	assert.Equal(t, len(patch), 1)
+	assert.Equal(t, patch[0].Op, "add")
+	assert.Equal(t, patch[0].Path, "/metadata/annotations")
+	updatedMap, ok := patch[0].Value.(map[string]string)
+	assert.Assert(t, ok, "patch info content is not as expected")
+	assert.Equal(t, len(updatedMap), 2)
+	assert.Equal(t, updatedMap["random"], "random")
+	var userGroup utils.UserGroup
+	assert.NilError(t, json.Unmarshal([]byte(updatedMap[constants.AnnotationUserInfo]), &userGroup))
+	assert.DeepEqual(t, userGroup, utils.UserGroup{User: "alice", Groups: []string{"dev", "system:authenticated"}})
+
+	// the annotations are created for a pod without any
+	pod = &v1.Pod{}
+	patch = updateUserInfo(pod, authenticationv1.UserInfo{Username: "bob"}, make([]patchOperation, 0))
+	assert.Equal(t, len(patch), 1)
+	assert.Equal(t, pod.Annotations[constants.AnnotationUserInfo], `{"user":"bob"}`)
+}
+
+func TestMutateUserInfo(t *testing.T) {
+	controller := &admissionController{}
+	pod := v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:      "a-test-pod",
+		Namespace: "default",
+		Labels:    map[string]string{constants.LabelQueueName: "root.a"},
+	}}
+	raw, err := json.Marshal(pod)
+	assert.NilError(t, err)
+	review := &v1beta1.AdmissionReview{Request: &v1beta1.AdmissionRequest{
+		Kind:      metav1.GroupVersionKind{Kind: "Pod"},
+		Namespace: "default",
+		Object:    runtime.RawExtension{Raw: raw},
+		UserInfo:  authenticationv1.UserInfo{Username: "alice", Groups: []string{"dev"}},
+	}}
+	getUserInfo := func(response *v1beta1.AdmissionResponse) (string, bool) {
+		var patch []patchOperation
+		assert.NilError(t, json.Unmarshal(response.Patch, &patch))
+		for _, op := range patch {
+			if op.Path == "/metadata/annotations" {
+				value, ok := op.Value.(map[string]interface{})[constants.AnnotationUserInfo]
+				if ok {
+					return value.(string), true
+				}
+			}
+		}
+		return "", false
+	}
+
+	// the user info is only injected when enabled
+	os.Unsetenv(enableUserInfoEnvVar)
+	response := controller.mutate(review)
+	assert.Assert(t, response.Allowed)
+	_, ok := getUserInfo(response)
+	assert.Assert(t, !ok, "user info injected while disabled")
+
+	os.Setenv(enableUserInfoEnvVar, "true")
+	defer os.Unsetenv(enableUserInfoEnvVar)
+	response = controller.mutate(review)
+	assert.Assert(t, response.Allowed)
+	value, ok := getUserInfo(response)
+	assert.Assert(t, ok, "user info not injected")
+	assert.Equal(t, value, `{"user":"alice","groups":["dev"]}`)
+}
+
 func TestValidateConfigMap(t *testing.T) {
 	configName := fmt.Sprintf("%s.yaml", conf.DefaultPolicyGroup)
 	controller := &admissionController{
@@ -400,3 +525,335 @@ func TestIsConfigMapUpdateAllowed(t *testing.T) {
 		})
 	}
 }
+
+func TestUnmappedPodPolicy(t *testing.T) {
+	defer func() {
+		os.Unsetenv(defaultQueueEnvVar)
+		os.Unsetenv(rejectUnmappedPodsEnvVar)
+		os.Unsetenv(namespaceDefaultQueuesEnvVar)
+		os.Unsetenv(rejectUnmappedNsEnvVar)
+	}()
+
+	// by default every unmapped pod goes to the default queue
+	policy := newUnmappedPodPolicy()
+	queue, err := policy.getQueue("dev")
+	assert.NilError(t, err)
+	assert.Equal(t, queue, defaultQueue)
+
+	os.Setenv(defaultQueueEnvVar, "root.sandbox")
+	os.Setenv(namespaceDefaultQueuesEnvVar, "dev=root.dev, prod = root.prod,invalid")
+	os.Setenv(rejectUnmappedNsEnvVar, "prod,finance")
+	policy = newUnmappedPodPolicy()
+	testCases := []struct {
+		namespace string
+		queue     string
+		rejected  bool
+	}{
+		{"dev", "root.dev", false},
+		{"prod", "root.prod", false},
+		{"finance", "", true},
+		{"test", "root.sandbox", false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.namespace, func(t *testing.T) {
+			queue, err := policy.getQueue(tc.namespace)
+			assert.Equal(t, queue, tc.queue)
+			assert.Equal(t, err != nil, tc.rejected)
+		})
+	}
+
+	// rejecting all unmapped pods keeps the namespace default queues
+	os.Setenv(rejectUnmappedPodsEnvVar, "true")
+	policy = newUnmappedPodPolicy()
+	_, err = policy.getQueue("test")
+	assert.ErrorContains(t, err, "namespace test has no default queue")
+	queue, err = policy.getQueue("dev")
+	assert.NilError(t, err)
+	assert.Equal(t, queue, "root.dev")
+}
+
+func TestMutateUnmappedPod(t *testing.T) {
+	controller := &admissionController{
+		unmappedPods: &unmappedPodPolicy{
+			defaultQueue:       defaultQueue,
+			namespaceQueues:    map[string]string{"dev": "root.dev"},
+			rejectedNamespaces: map[string]bool{"prod": true},
+		},
+	}
+	newReview := func(namespace string, labels map[string]string) *v1beta1.AdmissionReview {
+		pod := v1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      "a-test-pod",
+			Namespace: namespace,
+			Labels:    labels,
+		}}
+		raw, err := json.Marshal(pod)
+		assert.NilError(t, err)
+		return &v1beta1.AdmissionReview{Request: &v1beta1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Kind: "Pod"},
+			Namespace: namespace,
+			Object:    runtime.RawExtension{Raw: raw},
+		}}
+	}
+	getQueue := func(response *v1beta1.AdmissionResponse) string {
+		var patch []patchOperation
+		assert.NilError(t, json.Unmarshal(response.Patch, &patch))
+		for _, op := range patch {
+			if op.Path == "/metadata/labels" {
+				return op.Value.(map[string]interface{})[constants.LabelQueueName].(string)
+			}
+		}
+		return ""
+	}
+
+	// the namespace default queue is injected
+	response := controller.mutate(newReview("dev", nil))
+	assert.Assert(t, response.Allowed)
+	assert.Equal(t, getQueue(response), "root.dev")
+
+	// the pods without queue are rejected in the namespace
+	response = controller.mutate(newReview("prod", nil))
+	assert.Assert(t, !response.Allowed)
+	assert.Assert(t, strings.Contains(response.Result.Message, "namespace prod has no default queue"))
+
+	// a pod with a queue label is never rejected
+	response = controller.mutate(newReview("prod", map[string]string{constants.LabelQueueName: "root.a"}))
+	assert.Assert(t, response.Allowed)
+	assert.Equal(t, getQueue(response), "root.a")
+}
+
+func TestPodSelector(t *testing.T) {
+	defer func() {
+		os.Unsetenv(processNamespacesEnvVar)
+		os.Unsetenv(bypassNamespacesEnvVar)
+		os.Unsetenv(labelSelectorEnvVar)
+		os.Unsetenv(processWorkloadKindsEnvVar)
+	}()
+	newPod := func(namespace string, labels map[string]string, ownerKind string) *v1.Pod {
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      "a-test-pod",
+			Namespace: namespace,
+			Labels:    labels,
+		}}
+		if ownerKind != "" {
+			controller := true
+			pod.OwnerReferences = []metav1.OwnerReference{{Kind: ownerKind, Name: "owner", Controller: &controller}}
+		}
+		return pod
+	}
+
+	// without rules every pod is selected
+	selector := newPodSelector()
+	assert.Assert(t, selector.isSelected(newPod("dev", nil, "")))
+	var nilSelector *podSelector
+	assert.Assert(t, nilSelector.isSelected(newPod("dev", nil, "")))
+
+	os.Setenv(processNamespacesEnvVar, "dev, prod")
+	os.Setenv(bypassNamespacesEnvVar, "prod")
+	os.Setenv(labelSelectorEnvVar, "team in (a,b)")
+	os.Setenv(processWorkloadKindsEnvVar, "Job,Pod")
+	selector = newPodSelector()
+	testCases := []struct {
+		name     string
+		pod      *v1.Pod
+		selected bool
+	}{
+		{"selected bare pod", newPod("dev", map[string]string{"team": "a"}, ""), true},
+		{"selected job pod", newPod("dev", map[string]string{"team": "b"}, "Job"), true},
+		{"namespace not processed", newPod("test", map[string]string{"team": "a"}, ""), false},
+		{"namespace bypassed", newPod("prod", map[string]string{"team": "a"}, ""), false},
+		{"label not matching", newPod("dev", map[string]string{"team": "c"}, ""), false},
+		{"no labels", newPod("dev", nil, ""), false},
+		{"kind not processed", newPod("dev", map[string]string{"team": "a"}, "ReplicaSet"), false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, selector.isSelected(tc.pod), tc.selected)
+		})
+	}
+
+	// a pod asking for yunikorn is always selected
+	pod := newPod("test", nil, "ReplicaSet")
+	pod.Spec.SchedulerName = constants.SchedulerName
+	assert.Assert(t, selector.isSelected(pod))
+
+	// an invalid label selector is ignored
+	os.Unsetenv(processNamespacesEnvVar)
+	os.Unsetenv(bypassNamespacesEnvVar)
+	os.Unsetenv(processWorkloadKindsEnvVar)
+	os.Setenv(labelSelectorEnvVar, "team in (a")
+	selector = newPodSelector()
+	assert.Assert(t, selector.labelSelector == nil)
+	assert.Assert(t, selector.isSelected(newPod("dev", nil, "")))
+}
+
+func TestMutateUnselectedPod(t *testing.T) {
+	controller := &admissionController{
+		unmappedPods: &unmappedPodPolicy{
+			defaultQueue:       defaultQueue,
+			namespaceQueues:    map[string]string{},
+			rejectedNamespaces: map[string]bool{"legacy": true},
+		},
+		selector: &podSelector{
+			processNamespaces: map[string]bool{"dev": true},
+			bypassNamespaces:  map[string]bool{},
+			workloadKinds:     map[string]bool{},
+		},
+	}
+	newReview := func(pod v1.Pod) *v1beta1.AdmissionReview {
+		raw, err := json.Marshal(pod)
+		assert.NilError(t, err)
+		return &v1beta1.AdmissionReview{Request: &v1beta1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Kind: "Pod"},
+			Namespace: pod.Namespace,
+			Object:    runtime.RawExtension{Raw: raw},
+		}}
+	}
+
+	// a pod outside of the processed namespaces is admitted without a patch, even without a queue
+	pod := v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "a-test-pod", Namespace: "legacy"}}
+	response := controller.mutate(newReview(pod))
+	assert.Assert(t, response.Allowed)
+	assert.Assert(t, response.Patch == nil)
+
+	// a pod asking for yunikorn is handled as usual
+	pod.Spec.SchedulerName = constants.SchedulerName
+	response = controller.mutate(newReview(pod))
+	assert.Assert(t, !response.Allowed)
+
+	// a pod of a processed namespace gets the scheduler name
+	pod = v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "a-test-pod", Namespace: "dev"}}
+	response = controller.mutate(newReview(pod))
+	assert.Assert(t, response.Allowed)
+	assert.Assert(t, strings.Contains(string(response.Patch), "/spec/schedulerName"))
+}
+
+func TestRequestPolicy(t *testing.T) {
+	defer func() {
+		os.Unsetenv(defaultRequestsEnvVar)
+		os.Unsetenv(rejectNoRequestsEnvVar)
+	}()
+
+	// without configuration nothing is defaulted or rejected
+	policy := newRequestPolicy()
+	assert.Equal(t, len(policy.defaults), 0)
+	assert.Assert(t, !policy.rejectEmpty)
+
+	os.Setenv(defaultRequestsEnvVar, "cpu=0.1, memory = 128Mi,invalid,gpu=abc,storage=-1")
+	os.Setenv(rejectNoRequestsEnvVar, "true")
+	policy = newRequestPolicy()
+	assert.Equal(t, len(policy.defaults), 2)
+	assert.Equal(t, policy.defaults.Cpu().MilliValue(), int64(100))
+	assert.Equal(t, policy.defaults.Memory().Value(), int64(128*1024*1024))
+	assert.Assert(t, policy.rejectEmpty)
+}
+
+func TestUpdateRequests(t *testing.T) {
+	policy := &requestPolicy{
+		defaults: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("100m"),
+			v1.ResourceMemory: resource.MustParse("128Mi"),
+		},
+	}
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "a-test-pod"},
+		Spec: v1.PodSpec{
+			InitContainers: []v1.Container{{
+				Name: "init",
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{
+						v1.ResourceCPU:    resource.MustParse("1"),
+						v1.ResourceMemory: resource.MustParse("1Gi"),
+					},
+				},
+			}},
+			Containers: []v1.Container{{
+				Name: "limits",
+				Resources: v1.ResourceRequirements{
+					Limits: v1.ResourceList{v1.ResourceCPU: resource.MustParse("2")},
+				},
+			}, {
+				Name: "fraction",
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{
+						v1.ResourceCPU:    resource.MustParse("0.0005"),
+						v1.ResourceMemory: resource.MustParse("1500m"),
+					},
+				},
+			}},
+		},
+	}
+	patch, err := policy.updateRequests(pod, nil)
+	assert.NilError(t, err)
+
+	// the init container has all requests and is left untouched
+	assert.Equal(t, len(patch), 2)
+	assert.Equal(t, patch[0].Path, "/spec/containers/0/resources")
+	assert.Equal(t, patch[1].Path, "/spec/containers/1/resources")
+
+	// the limit is used before the default
+	requests := pod.Spec.Containers[0].Resources.Requests
+	assert.Equal(t, requests.Cpu().MilliValue(), int64(2000))
+	assert.Equal(t, requests.Memory().Value(), int64(128*1024*1024))
+	assert.Equal(t, pod.Spec.Containers[0].Resources.Limits.Cpu().MilliValue(), int64(2000))
+
+	// the requests are rounded up to millicores and bytes
+	requests = pod.Spec.Containers[1].Resources.Requests
+	assert.Equal(t, requests.Cpu().String(), "1m")
+	assert.Equal(t, requests.Memory().String(), "2")
+
+	// a container without requests is rejected only when configured
+	pod = &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{Name: "empty"}}}}
+	patch, err = (&requestPolicy{}).updateRequests(pod, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, len(patch), 0)
+	_, err = (&requestPolicy{rejectEmpty: true}).updateRequests(pod, nil)
+	assert.ErrorContains(t, err, "container empty has no resource requests")
+
+	// a nil policy does nothing
+	var nilPolicy *requestPolicy
+	patch, err = nilPolicy.updateRequests(pod, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, len(patch), 0)
+}
+
+func TestMutateRequests(t *testing.T) {
+	controller := &admissionController{
+		unmappedPods: newUnmappedPodPolicy(),
+		requests:     &requestPolicy{rejectEmpty: true},
+	}
+	newReview := func(pod v1.Pod) *v1beta1.AdmissionReview {
+		raw, err := json.Marshal(pod)
+		assert.NilError(t, err)
+		return &v1beta1.AdmissionReview{Request: &v1beta1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Kind: "Pod"},
+			Namespace: "dev",
+			Object:    runtime.RawExtension{Raw: raw},
+		}}
+	}
+
+	// a pod without requests is rejected
+	pod := v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "a-test-pod", Namespace: "dev"},
+		Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "main"}}},
+	}
+	response := controller.mutate(newReview(pod))
+	assert.Assert(t, !response.Allowed)
+	assert.Assert(t, strings.Contains(response.Result.Message, "container main has no resource requests"))
+
+	// the requests are defaulted from the limits and patched
+	pod.Spec.Containers[0].Resources.Limits = v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")}
+	response = controller.mutate(newReview(pod))
+	assert.Assert(t, response.Allowed)
+	var patch []patchOperation
+	assert.NilError(t, json.Unmarshal(response.Patch, &patch))
+	found := false
+	for _, op := range patch {
+		if op.Path == "/spec/containers/0/resources" {
+			found = true
+			value := op.Value.(map[string]interface{})
+			assert.Equal(t, value["requests"].(map[string]interface{})["memory"], "1Gi")
+			assert.Equal(t, value["limits"].(map[string]interface{})["memory"], "1Gi")
+		}
+	}
+	assert.Assert(t, found)
+}
@@ -64,6 +64,9 @@ func main() {
 	webHook := admissionController{
 		configName:               fmt.Sprintf("%s.yaml", policyGroup),
 		schedulerValidateConfURL: fmt.Sprintf(schedulerValidateConfURLPattern, schedulerServiceAddress),
+		unmappedPods:             newUnmappedPodPolicy(),
+		selector:                 newPodSelector(),
+		requests:                 newRequestPolicy(),
 	}
 	mux := http.NewServeMux()
 	mux.HandleFunc(mutateURL, webHook.serve)
@@ -0,0 +1,190 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package predicates
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/kubernetes/pkg/scheduler/algorithm/predicates"
+	schedulerapi "k8s.io/kubernetes/pkg/scheduler/api"
+	"k8s.io/kubernetes/pkg/scheduler/factory"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+)
+
+// keys of the predicate configuration in the scheduler configmap
+const (
+	// comma-separated list of the enabled predicates, overrides the predicates set at startup
+	ConfigKeyEnabledPredicates = "predicates.enabled"
+	// comma-separated list of the predicates removed from the enabled predicates
+	ConfigKeyDisabledPredicates = "predicates.disabled"
+	// prefix of the label selectors of the pods that skip a predicate, e.g.
+	// "predicates.skip.PodToleratesNodeTaints: app=node-exporter"
+	ConfigKeySkipPredicatePrefix = "predicates.skip."
+)
+
+var customPredicates = make(map[string]factory.FitPredicateFactory)
+var customPredicatesLock sync.RWMutex
+
+// RegisterCustomPredicate registers a custom predicate plugin. A custom predicate is only evaluated
+// when it is enabled by the predicates flag or the configmap, always after the built-in predicates.
+func RegisterCustomPredicate(name string, predicateFactory factory.FitPredicateFactory) {
+	customPredicatesLock.Lock()
+	defer customPredicatesLock.Unlock()
+	customPredicates[name] = predicateFactory
+}
+
+func getCustomPredicate(name string) (factory.FitPredicateFactory, bool) {
+	customPredicatesLock.RLock()
+	defer customPredicatesLock.RUnlock()
+	predicateFactory, ok := customPredicates[name]
+	return predicateFactory, ok
+}
+
+func isValidPredicate(name string) bool {
+	for _, validPredicate := range predicates.Ordering() {
+		if validPredicate == name {
+			return true
+		}
+	}
+	_, ok := getCustomPredicate(name)
+	return ok
+}
+
+// PredicatesConfig is the predicate configuration loaded from the scheduler configmap
+type PredicatesConfig struct {
+	Enabled  []string
+	Disabled []string
+	Skip     map[string]string // predicate name to the label selector of the pods that skip it
+}
+
+// ParsePredicatesConfig reads the predicate configuration from the configmap data,
+// nil is returned when the configmap has no predicate configuration.
+func ParsePredicatesConfig(data map[string]string) *PredicatesConfig {
+	config := &PredicatesConfig{
+		Enabled:  splitPredicates(data[ConfigKeyEnabledPredicates]),
+		Disabled: splitPredicates(data[ConfigKeyDisabledPredicates]),
+		Skip:     make(map[string]string),
+	}
+	for key, value := range data {
+		if strings.HasPrefix(key, ConfigKeySkipPredicatePrefix) {
+			config.Skip[strings.TrimPrefix(key, ConfigKeySkipPredicatePrefix)] = value
+		}
+	}
+	if len(config.Enabled) == 0 && len(config.Disabled) == 0 && len(config.Skip) == 0 {
+		return nil
+	}
+	return config
+}
+
+func splitPredicates(value string) []string {
+	names := make([]string, 0)
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// UpdatePredicates applies the predicate configuration, the predicates set at startup are restored
+// when the config is nil. An invalid configuration is rejected and the current predicates are kept.
+func (p *Predictor) UpdatePredicates(config *PredicatesConfig) error {
+	policy := p.basePolicy
+	skipSelectors := make(map[string]labels.Selector)
+	if config != nil {
+		var err error
+		if policy, err = config.toPolicy(p.basePolicy); err != nil {
+			return err
+		}
+		for name, selector := range config.Skip {
+			if !isValidPredicate(name) {
+				return fmt.Errorf("predicate '%s' to skip is invalid", name)
+			}
+			parsed, err := labels.Parse(selector)
+			if err != nil {
+				return fmt.Errorf("invalid selector of the pods skipping predicate '%s': %v", name, err)
+			}
+			skipSelectors[name] = parsed
+		}
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	p.schedulerPolicy = policy
+	p.skipSelectors = skipSelectors
+	p.fitPredicateFunctions = make(map[string]predicates.FitPredicate)
+	p.populatePredicateFunc(p.args)
+	log.Logger().Info("predicates updated",
+		zap.Any("predicates", policy.Predicates),
+		zap.Any("skip", config))
+	return nil
+}
+
+func (config *PredicatesConfig) toPolicy(basePolicy schedulerapi.Policy) (schedulerapi.Policy, error) {
+	enabled := config.Enabled
+	if len(enabled) == 0 {
+		for _, predicate := range basePolicy.Predicates {
+			enabled = append(enabled, predicate.Name)
+		}
+	}
+	disabled := make(map[string]bool)
+	for _, name := range config.Disabled {
+		if !isValidPredicate(name) {
+			return schedulerapi.Policy{}, fmt.Errorf("disabled predicate '%s' is invalid", name)
+		}
+		disabled[name] = true
+	}
+	policy := schedulerapi.Policy{Predicates: make([]schedulerapi.PredicatePolicy, 0)}
+	for _, name := range enabled {
+		if !isValidPredicate(name) {
+			return schedulerapi.Policy{}, fmt.Errorf("enabled predicate '%s' is invalid", name)
+		}
+		if !disabled[name] {
+			policy.Predicates = append(policy.Predicates, schedulerapi.PredicatePolicy{Name: name})
+		}
+	}
+	return policy, nil
+}
+
+// isSkipped returns true if the pod is exempted from the predicate, the caller must hold the lock
+func (p *Predictor) isSkipped(name string, pod *v1.Pod) bool {
+	if selector, ok := p.skipSelectors[name]; ok {
+		return selector.Matches(labels.Set(pod.Labels))
+	}
+	return false
+}
+
+// GetEnabledPredicates returns the names of the enabled predicates, sorted by name
+func (p *Predictor) GetEnabledPredicates() []string {
+	p.RLock()
+	defer p.RUnlock()
+	names := make([]string, 0, len(p.schedulerPolicy.Predicates))
+	for _, predicate := range p.schedulerPolicy.Predicates {
+		names = append(names, predicate.Name)
+	}
+	sort.Strings(names)
+	return names
+}
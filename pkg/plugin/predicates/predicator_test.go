@@ -2133,3 +2133,122 @@ func TestReserveNodeSelector(t *testing.T) {
 		})
 	}
 }
+
+func TestParsePredicatesConfig(t *testing.T) {
+	assert.Assert(t, ParsePredicatesConfig(map[string]string{"queues.yaml": "partitions:"}) == nil)
+
+	config := ParsePredicatesConfig(map[string]string{
+		ConfigKeyEnabledPredicates:  "HostName, PodToleratesNodeTaints",
+		ConfigKeyDisabledPredicates: "HostName",
+		ConfigKeySkipPredicatePrefix + predicates.PodToleratesNodeTaintsPred: "app=daemon",
+	})
+	assert.DeepEqual(t, config.Enabled, []string{predicates.HostNamePred, predicates.PodToleratesNodeTaintsPred})
+	assert.DeepEqual(t, config.Disabled, []string{predicates.HostNamePred})
+	assert.DeepEqual(t, config.Skip, map[string]string{predicates.PodToleratesNodeTaintsPred: "app=daemon"})
+}
+
+func TestUpdatePredicates(t *testing.T) {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{"app": "web"},
+		},
+		Spec: v1.PodSpec{
+			NodeName: "bar",
+		},
+	}
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "foo",
+		},
+		Spec: v1.NodeSpec{
+			Taints: []v1.Taint{{Key: "dedicated", Value: "infra", Effect: v1.TaintEffectNoSchedule}},
+		},
+	}
+	nodeInfo := deschedulernode.NewNodeInfo()
+	err := nodeInfo.SetNode(node)
+	assert.NilError(t, err, "No error expected")
+
+	predictor := newPredictorInternal(&factory.PluginFactoryArgs{}, schedulerapi.Policy{
+		Predicates: []schedulerapi.PredicatePolicy{
+			{Name: predicates.HostNamePred},
+		}})
+	err = predictor.Predicates(pod, nil, nodeInfo, true)
+	assert.ErrorContains(t, err, predicates.HostNamePred)
+
+	// disable the host name predicate and enable the taints predicate
+	err = predictor.UpdatePredicates(&PredicatesConfig{
+		Enabled:  []string{predicates.HostNamePred, predicates.PodToleratesNodeTaintsPred},
+		Disabled: []string{predicates.HostNamePred},
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, predictor.GetEnabledPredicates(), []string{predicates.PodToleratesNodeTaintsPred})
+	err = predictor.Predicates(pod, nil, nodeInfo, true)
+	assert.ErrorContains(t, err, predicates.PodToleratesNodeTaintsPred)
+
+	// pods matching the selector skip the taints predicate
+	err = predictor.UpdatePredicates(&PredicatesConfig{
+		Enabled: []string{predicates.PodToleratesNodeTaintsPred},
+		Skip:    map[string]string{predicates.PodToleratesNodeTaintsPred: "app=daemon"},
+	})
+	assert.NilError(t, err)
+	err = predictor.Predicates(pod, nil, nodeInfo, true)
+	assert.ErrorContains(t, err, predicates.PodToleratesNodeTaintsPred)
+	pod.Labels["app"] = "daemon"
+	err = predictor.Predicates(pod, nil, nodeInfo, true)
+	assert.NilError(t, err, "pod should skip the taints predicate")
+	err = predictor.Predicates(pod, nil, nodeInfo, false)
+	assert.NilError(t, err, "pod should skip the taints predicate on reservation")
+
+	// invalid configurations are rejected, the current predicates are kept
+	err = predictor.UpdatePredicates(&PredicatesConfig{Enabled: []string{"xxx"}})
+	assert.Error(t, err, "enabled predicate 'xxx' is invalid")
+	err = predictor.UpdatePredicates(&PredicatesConfig{Skip: map[string]string{predicates.HostNamePred: "app in ("}})
+	assert.ErrorContains(t, err, "invalid selector")
+	assert.DeepEqual(t, predictor.GetEnabledPredicates(), []string{predicates.PodToleratesNodeTaintsPred})
+
+	// the predicates at startup are restored when the configuration is removed
+	err = predictor.UpdatePredicates(nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, predictor.GetEnabledPredicates(), []string{predicates.HostNamePred})
+	err = predictor.Predicates(pod, nil, nodeInfo, true)
+	assert.ErrorContains(t, err, predicates.HostNamePred)
+}
+
+func TestCustomPredicates(t *testing.T) {
+	RegisterCustomPredicate("NoDaemon", func(factory.PluginFactoryArgs) predicates.FitPredicate {
+		return func(pod *v1.Pod, meta predicates.PredicateMetadata,
+			nodeInfo *deschedulernode.NodeInfo) (bool, []predicates.PredicateFailureReason, error) {
+			if pod.Labels["app"] == "daemon" {
+				return false, []predicates.PredicateFailureReason{predicates.ErrFakePredicate}, nil
+			}
+			return true, nil, nil
+		}
+	})
+	assert.Assert(t, isValidPredicate("NoDaemon"))
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{"app": "daemon"},
+		},
+	}
+	nodeInfo := deschedulernode.NewNodeInfo()
+	err := nodeInfo.SetNode(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "foo"}})
+	assert.NilError(t, err, "No error expected")
+
+	predictor := newPredictorInternal(&factory.PluginFactoryArgs{}, schedulerapi.Policy{
+		Predicates: []schedulerapi.PredicatePolicy{
+			{Name: predicates.HostNamePred},
+		}})
+	err = predictor.Predicates(pod, nil, nodeInfo, true)
+	assert.NilError(t, err, "custom predicate is not enabled")
+
+	err = predictor.UpdatePredicates(&PredicatesConfig{
+		Enabled: []string{predicates.HostNamePred, "NoDaemon"},
+	})
+	assert.NilError(t, err)
+	err = predictor.Predicates(pod, nil, nodeInfo, true)
+	assert.ErrorContains(t, err, "NoDaemon")
+	pod.Labels["app"] = "web"
+	err = predictor.Predicates(pod, nil, nodeInfo, true)
+	assert.NilError(t, err)
+}
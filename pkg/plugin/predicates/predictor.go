@@ -27,6 +27,7 @@ import (
 	"go.uber.org/zap"
 	"golang.org/x/time/rate"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/kubernetes/pkg/scheduler/algorithm/predicates"
 	schedulerapi "k8s.io/kubernetes/pkg/scheduler/api"
@@ -83,6 +84,10 @@ type Predictor struct {
 	predicateMetaProducer        predicates.PredicateMetadataProducer
 	mandatoryFitPredicates       sets.String
 	schedulerPolicy              schedulerapi.Policy
+	basePolicy                   schedulerapi.Policy        // the policy before the configmap overrides
+	predicateOrdering            []string                   // the built-in predicates, then the enabled custom ones
+	skipSelectors                map[string]labels.Selector // pods matching the selector skip the predicate
+	args                         factory.PluginFactoryArgs
 
 	sync.RWMutex
 }
@@ -110,6 +115,9 @@ func newPredictorInternal(args *factory.PluginFactoryArgs, schedulerPolicy sched
 		fitPredicateFunctions:  make(map[string]predicates.FitPredicate),
 		mandatoryFitPredicates: sets.NewString(),
 		schedulerPolicy:        schedulerPolicy,
+		basePolicy:             schedulerPolicy,
+		skipSelectors:          make(map[string]labels.Selector),
+		args:                   *args,
 	}
 	// init all predicates
 	p.init()
@@ -227,9 +235,13 @@ func (p *Predictor) registerPredicateMetadataProducerFactory(factory factory.Pre
 }
 
 func (p *Predictor) populatePredicateFunc(args factory.PluginFactoryArgs) {
+	p.predicateOrdering = append(make([]string, 0), predicates.Ordering()...)
 	for _, predicate := range p.schedulerPolicy.Predicates {
 		if preFactory, ok := p.fitPredicateMap[predicate.Name]; ok {
 			p.fitPredicateFunctions[predicate.Name] = preFactory(args)
+		} else if preFactory, ok := getCustomPredicate(predicate.Name); ok {
+			p.fitPredicateFunctions[predicate.Name] = preFactory(args)
+			p.predicateOrdering = append(p.predicateOrdering, predicate.Name)
 		}
 	}
 }
@@ -246,10 +258,14 @@ func (p *Predictor) GetPredicateMeta(pod *v1.Pod,
 }
 
 func (p *Predictor) Enabled() bool {
+	p.RLock()
+	defer p.RUnlock()
 	return len(p.schedulerPolicy.Predicates) > 0
 }
 
 func (p *Predictor) Predicates(pod *v1.Pod, meta predicates.PredicateMetadata, node *deschedulernode.NodeInfo, allocate bool) error {
+	p.RLock()
+	defer p.RUnlock()
 	if allocate {
 		return p.predicatesAllocate(pod, meta, node)
 	}
@@ -258,6 +274,9 @@ func (p *Predictor) Predicates(pod *v1.Pod, meta predicates.PredicateMetadata, n
 
 func (p *Predictor) predicatesReserve(pod *v1.Pod, meta predicates.PredicateMetadata, node *deschedulernode.NodeInfo) error {
 	for _, predicateKey := range reservationPredicates {
+		if p.isSkipped(predicateKey, pod) {
+			continue
+		}
 		if predicateFn, exist := p.fitPredicateFunctions[predicateKey]; exist {
 			fit, reasons, err := predicateFn(pod, meta, node)
 			if !fit {
@@ -272,8 +291,11 @@ func (p *Predictor) predicatesReserve(pod *v1.Pod, meta predicates.PredicateMeta
 }
 
 func (p *Predictor) predicatesAllocate(pod *v1.Pod, meta predicates.PredicateMetadata, node *deschedulernode.NodeInfo) error {
-	// honor the ordering...
-	for _, predicateKey := range predicates.Ordering() {
+	// honor the ordering, the custom predicates are always evaluated after the built-in ones
+	for _, predicateKey := range p.predicateOrdering {
+		if p.isSkipped(predicateKey, pod) {
+			continue
+		}
 		if predicateFn, exist := p.fitPredicateFunctions[predicateKey]; exist {
 			fit, reasons, err := predicateFn(pod, meta, node)
 			if err != nil {
@@ -298,15 +320,11 @@ func (p *Predictor) predicatesAllocate(pod *v1.Pod, meta predicates.PredicateMet
 func parseConfiguredSchedulerPolicy() (*schedulerapi.Policy, error) {
 	configuredPredicates := conf.GetSchedulerConf().Predicates
 	if configuredPredicates != "" {
-		validPredicates := make(map[string]bool)
-		for _, validPredicate := range predicates.Ordering() {
-			validPredicates[validPredicate] = true
-		}
 		parsedPredicates := strings.Split(configuredPredicates, ",")
 		predicatePolicies := make([]schedulerapi.PredicatePolicy, len(parsedPredicates))
 		// validate parsed predicates and update predicate policies
 		for i, parsedPredicate := range parsedPredicates {
-			if _, ok := validPredicates[parsedPredicate]; ok {
+			if isValidPredicate(parsedPredicate) {
 				predicatePolicies[i] = schedulerapi.PredicatePolicy{Name: parsedPredicate}
 			} else {
 				// return error if there's invalid predicate
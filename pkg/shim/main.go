@@ -19,17 +19,24 @@
 package main
 
 import (
+	"encoding/json"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"go.uber.org/zap"
 
 	"github.com/apache/incubator-yunikorn-core/pkg/api"
 	"github.com/apache/incubator-yunikorn-core/pkg/entrypoint"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/metrics"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/trace"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/webservice"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/webservice/dao"
 )
 
 var (
@@ -37,23 +44,83 @@ var (
 	date    string
 )
 
+const registrationTimeout = 5 * time.Minute
+
 func main() {
 	log.Logger().Info("Build info", zap.String("version", version), zap.String("date", date))
+	setBuildInfo(version, date)
 	log.Logger().Info("starting scheduler",
-		zap.String("name", constants.SchedulerName))
-
-	serviceContext := entrypoint.StartAllServices()
+		zap.String("name", constants.SchedulerName),
+		zap.Strings("schedulerNames", conf.GetSchedulerConf().GetSchedulerNames()))
 
-	if sa, ok := serviceContext.RMProxy.(api.SchedulerAPI); ok {
-		ss := newShimScheduler(sa, conf.GetSchedulerConf())
+	if conf.GetSchedulerConf().EnableTracing {
+		if err := trace.Init(); err != nil {
+			log.Logger().Error("failed to start the tracing", zap.Error(err))
+		}
+	}
+	if sa, ok := startSchedulerAPI(conf.GetSchedulerConf()); ok {
+		enabled, sampleRate, redact := conf.GetSchedulerConf().GetSILogging()
+		ss := newShimScheduler(client.NewLoggingSchedulerAPI(sa, dao.SILogging{
+			Enabled:    enabled,
+			SampleRate: sampleRate,
+			Redact:     redact,
+		}), conf.GetSchedulerConf())
+		// a remote core that restarted gets the state of the shim replayed instead of restarting the shim
+		if remote, ok := sa.(*client.StreamingSchedulerAPI); ok {
+			remote.SetReconnectHandler(ss.reregister)
+		}
 		ss.run()
+		// do not serve anything if the core refused the shim, e.g. a protocol version mismatch
+		if err := ss.waitForRegistration(registrationTimeout); err != nil {
+			log.Logger().Fatal("failed to start the scheduler", zap.Error(err))
+		}
+
+		webapp := webservice.NewWebApp(ss.context, ss, conf.GetSchedulerConf().WebServicePort)
+		webapp.StartWebApp()
+
+		if err := metrics.Register(ss.context.GetResourceUsage, ss.context.GetPendingResources); err != nil {
+			log.Logger().Error("failed to register the shim metrics", zap.Error(err))
+		}
 
 		signalChan := make(chan os.Signal, 1)
-		signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
-		for range signalChan {
+		signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGUSR1)
+		for sig := range signalChan {
+			// SIGUSR1 dumps the state of the shim into the log, the same as /debug/fullstatedump
+			if sig == syscall.SIGUSR1 {
+				if dump, err := json.Marshal(ss.context.GetStateDump()); err == nil {
+					log.Logger().Info("shim state dump", zap.String("dump", string(dump)))
+				} else {
+					log.Logger().Error("failed to dump the shim state", zap.Error(err))
+				}
+				continue
+			}
 			log.Logger().Info("Shutdown signal received, exiting...")
+			if err := webapp.StopWebApp(); err != nil {
+				log.Logger().Error("failed to stop the web-app", zap.Error(err))
+			}
 			ss.stop()
+			trace.Close()
+			if remote, ok := sa.(*client.StreamingSchedulerAPI); ok {
+				remote.Stop()
+			}
 			os.Exit(0)
 		}
 	}
 }
+
+// startSchedulerAPI returns the api of the core the shim registers with, the updates are streamed to a remote core
+// when its address is configured, otherwise the core is started embedded in the shim.
+func startSchedulerAPI(configs *conf.SchedulerConf) (api.SchedulerAPI, bool) {
+	if address := configs.GetCoreAddress(); address != "" {
+		log.Logger().Info("connecting to the remote core", zap.String("address", address))
+		batchSize, queueSize := configs.GetUpdateStreamLimits()
+		remote, err := client.NewStreamingSchedulerAPI(address, batchSize, queueSize)
+		if err != nil {
+			log.Logger().Fatal("failed to connect to the remote core", zap.String("address", address), zap.Error(err))
+		}
+		return remote, true
+	}
+	serviceContext := entrypoint.StartAllServices()
+	sa, ok := serviceContext.RMProxy.(api.SchedulerAPI)
+	return sa, ok
+}
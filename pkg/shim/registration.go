@@ -0,0 +1,129 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+)
+
+const (
+	metadataShimVersion       = "shimVersion"
+	metadataBuildDate         = "buildDate"
+	metadataKubernetesVersion = "k8sVersion"
+	metadataFeatures          = "features"
+	unknownVersion            = "unknown"
+)
+
+// the build info of the shim, set by main from the link time variables
+var buildInfo = map[string]string{
+	metadataShimVersion: unknownVersion,
+	metadataBuildDate:   unknownVersion,
+}
+
+func setBuildInfo(version, date string) {
+	if version != "" {
+		buildInfo[metadataShimVersion] = version
+	}
+	if date != "" {
+		buildInfo[metadataBuildDate] = date
+	}
+}
+
+// getEnabledFeatures returns the sorted names of the optional features turned on in the configuration
+func getEnabledFeatures(configuration *conf.SchedulerConf) []string {
+	features := make([]string, 0)
+	flags := map[string]bool{
+		"configHotRefresh":      configuration.EnableConfigHotRefresh,
+		"gangFeasibilityCheck":  configuration.EnableGangFeasibilityCheck,
+		"appCheckpoint":         configuration.EnableAppCheckpoint,
+		"placeholderJanitorDry": configuration.PlaceholderJanitorDryRun,
+		"federation":            configuration.FederatedClusterIDs != "",
+		"queueCapacityCheck":    configuration.QueueCapacityRefresh > 0,
+	}
+	for name, enabled := range flags {
+		if enabled {
+			features = append(features, name)
+		}
+	}
+	for _, plugin := range strings.Split(configuration.OperatorPlugins, ",") {
+		if plugin = strings.TrimSpace(plugin); plugin != "" {
+			features = append(features, "plugin:"+plugin)
+		}
+	}
+	sort.Strings(features)
+	return features
+}
+
+// getKubernetesVersion returns the git version of the API server, or unknown if it cannot be retrieved
+func getKubernetesVersion(clientSet kubernetes.Interface) string {
+	if clientSet == nil {
+		return unknownVersion
+	}
+	info, err := clientSet.Discovery().ServerVersion()
+	if err != nil || info == nil {
+		log.Logger().Warn("failed to retrieve the kubernetes version", zap.Error(err))
+		return unknownVersion
+	}
+	return info.GitVersion
+}
+
+// getRegistrationMetadata collects the metadata the shim reports to the core when it registers
+func getRegistrationMetadata(configuration *conf.SchedulerConf, clientSet kubernetes.Interface) map[string]string {
+	metadata := map[string]string{
+		metadataKubernetesVersion: getKubernetesVersion(clientSet),
+		metadataFeatures:          strings.Join(getEnabledFeatures(configuration), "|"),
+	}
+	for k, v := range buildInfo {
+		metadata[k] = v
+	}
+	return metadata
+}
+
+// formatRegistrationVersion appends the metadata to the cluster version as semver build metadata,
+// e.g. "0.1+buildDate=...;features=...;k8sVersion=v1.16.3;shimVersion=0.10.0".
+// the scheduler interface has no generic metadata on the registration, the version is the only free form field.
+func formatRegistrationVersion(clusterVersion string, metadata map[string]string) string {
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, metadata[k]))
+	}
+	return clusterVersion + "+" + strings.Join(pairs, ";")
+}
+
+// isIncompatibleVersion checks if the core rejected the registration because of a protocol version mismatch
+func isIncompatibleVersion(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "incompatible") && strings.Contains(msg, "version")
+}
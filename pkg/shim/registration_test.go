@@ -0,0 +1,73 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"gotest.tools/assert"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+)
+
+func TestGetEnabledFeatures(t *testing.T) {
+	configuration := &conf.SchedulerConf{}
+	assert.Equal(t, len(getEnabledFeatures(configuration)), 0)
+
+	configuration.EnableGangFeasibilityCheck = true
+	configuration.EnableAppCheckpoint = true
+	configuration.OperatorPlugins = "general, spark-k8s-operator"
+	assert.DeepEqual(t, getEnabledFeatures(configuration),
+		[]string{"appCheckpoint", "gangFeasibilityCheck", "plugin:general", "plugin:spark-k8s-operator"})
+}
+
+func TestRegistrationMetadata(t *testing.T) {
+	setBuildInfo("0.10.0", "")
+	defer setBuildInfo(unknownVersion, unknownVersion)
+
+	configuration := &conf.SchedulerConf{EnableConfigHotRefresh: true}
+	metadata := getRegistrationMetadata(configuration, fake.NewSimpleClientset())
+	assert.Equal(t, metadata[metadataShimVersion], "0.10.0")
+	assert.Equal(t, metadata[metadataBuildDate], unknownVersion)
+	assert.Equal(t, metadata[metadataFeatures], "configHotRefresh")
+	_, ok := metadata[metadataKubernetesVersion]
+	assert.Assert(t, ok)
+
+	// no client available
+	metadata = getRegistrationMetadata(configuration, nil)
+	assert.Equal(t, metadata[metadataKubernetesVersion], unknownVersion)
+}
+
+func TestFormatRegistrationVersion(t *testing.T) {
+	version := formatRegistrationVersion("0.1", map[string]string{
+		metadataShimVersion:       "0.10.0",
+		metadataKubernetesVersion: "v1.16.3",
+		metadataFeatures:          "appCheckpoint|federation",
+	})
+	assert.Equal(t, version, "0.1+features=appCheckpoint|federation;k8sVersion=v1.16.3;shimVersion=0.10.0")
+}
+
+func TestIsIncompatibleVersion(t *testing.T) {
+	assert.Assert(t, !isIncompatibleVersion(nil))
+	assert.Assert(t, !isIncompatibleVersion(fmt.Errorf("some error")))
+	assert.Assert(t, !isIncompatibleVersion(fmt.Errorf("unknown version of the config")))
+	assert.Assert(t, isIncompatibleVersion(fmt.Errorf("registration of RM failed: Incompatible RM version 0.1")))
+}
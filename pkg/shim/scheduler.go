@@ -19,6 +19,7 @@
 package main
 
 import (
+	"fmt"
 	"sync"
 	"time"
 
@@ -33,6 +34,7 @@ import (
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/callback"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/dispatcher"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
@@ -48,7 +50,9 @@ type KubernetesShim struct {
 	callback     api.ResourceManagerCallback
 	stateMachine *fsm.FSM
 	stopChan     chan struct{}
-	lock         *sync.RWMutex
+	// the reason of the last failed registration with the core
+	registrationErr error
+	lock            *sync.RWMutex
 }
 
 func newShimScheduler(scheduler api.SchedulerAPI, configs *conf.SchedulerConf) *KubernetesShim {
@@ -132,7 +136,15 @@ func (ss *KubernetesShim) SchedulerEventHandler() func(obj interface{}) {
 }
 
 func (ss *KubernetesShim) register(e *fsm.Event) {
-	if err := ss.registerShimLayer(); err != nil {
+	// called from the state machine, the shim lock is held
+	ss.registrationErr = ss.registerShimLayer()
+	if err := ss.registrationErr; err != nil {
+		if isIncompatibleVersion(err) {
+			log.Logger().Error("the scheduler core does not support this shim version, refusing to start",
+				zap.Error(err))
+		} else {
+			log.Logger().Error("failed to register with the scheduler core", zap.Error(err))
+		}
 		dispatcher.Dispatch(ShimSchedulerEvent{
 			event: events.RegisterSchedulerFailed,
 		})
@@ -158,16 +170,19 @@ func (ss *KubernetesShim) recoverSchedulerState(e *fsm.Event) {
 	// do not block main thread
 	go func() {
 		log.Logger().Info("recovering scheduler states")
+		// the recovery lists everything from the api-server at once,
+		// use the recovery limits of the kube client until it is done
+		client.SetKubeClientRateLimit(conf.GetSchedulerConf().GetRecoveryKubeClientLimits())
+		defer client.SetKubeClientRateLimit(conf.GetSchedulerConf().GetKubeClientLimits())
+		// all the steps share one deadline, the recovery fails fast once it is passed
+		deadline := time.Now().Add(conf.GetSchedulerConf().GetRecoveryTimeout())
+		ss.context.StartRecovery(deadline)
 		// step 1: recover all applications
 		// this step, we collect all the existing allocated pods from api-server,
 		// identify the scheduling identity (aka applicationInfo) from the pod,
 		// and then add these applications to the scheduler.
-		if err := ss.appManager.WaitForRecovery(3 * time.Minute); err != nil {
-			// failed
-			log.Logger().Error("scheduler recovery failed", zap.Error(err))
-			dispatcher.Dispatch(ShimSchedulerEvent{
-				event: events.RecoverSchedulerFailed,
-			})
+		if err := ss.appManager.WaitForRecovery(time.Until(deadline)); err != nil {
+			ss.recoveryFailed(err)
 			return
 		}
 
@@ -181,16 +196,13 @@ func (ss *KubernetesShim) recoverSchedulerState(e *fsm.Event) {
 				recoverableAppManagers = append(recoverableAppManagers, m)
 			}
 		}
-		if err := ss.context.WaitForRecovery(recoverableAppManagers, 3*time.Minute); err != nil {
-			// failed
-			log.Logger().Error("scheduler recovery failed", zap.Error(err))
-			dispatcher.Dispatch(ShimSchedulerEvent{
-				event: events.RecoverSchedulerFailed,
-			})
+		if err := ss.context.WaitForRecovery(recoverableAppManagers, time.Until(deadline)); err != nil {
+			ss.recoveryFailed(err)
 			return
 		}
 
 		// success
+		ss.context.FinishRecovery(nil)
 		log.Logger().Info("scheduler recovery succeed")
 		dispatcher.Dispatch(ShimSchedulerEvent{
 			event: events.RecoverSchedulerSucceed,
@@ -198,31 +210,119 @@ func (ss *KubernetesShim) recoverSchedulerState(e *fsm.Event) {
 	}()
 }
 
+// recoveryFailed records the recovery error in the progress and fails the scheduler
+func (ss *KubernetesShim) recoveryFailed(err error) {
+	log.Logger().Error("scheduler recovery failed", zap.Error(err))
+	ss.context.FinishRecovery(err)
+	dispatcher.Dispatch(ShimSchedulerEvent{
+		event: events.RecoverSchedulerFailed,
+	})
+}
+
 func (ss *KubernetesShim) doScheduling(e *fsm.Event) {
 	// add event handlers to the context
 	ss.context.AddSchedulingEventHandlers()
 
+	// the apps are recovered, start saving their state
+	ss.context.StartAppCheckpoint()
+
+	// the apps are recovered, the janitor can tell the leaked placeholders apart
+	ss.phManager.SetApplicationLookup(func(appID string) bool {
+		return ss.context.GetApplication(appID) != nil
+	})
+
+	// the recovered tasks are submitted and bound with raised limits for a while
+	ss.context.StartThroughputBurst()
+
+	// the allocations are recovered, the shim and the core can be compared
+	ss.context.StartStateReconciler()
+
+	// the apps exceeding the max capacity of their queue are failed at submission
+	ss.context.StartQueueCapacityRefresh()
+
+	// the capacity of the unschedulable task groups is provisioned by the autoscaler
+	ss.context.StartProvisioningRequests()
+
 	// run main scheduling loop
 	go wait.Until(ss.schedule, conf.GetSchedulerConf().GetSchedulingInterval(), ss.stopChan)
 }
 
 func (ss *KubernetesShim) registerShimLayer() error {
 	configuration := conf.GetSchedulerConf()
+	metadata := getRegistrationMetadata(configuration, ss.apiFactory.GetAPIs().KubeClient.GetClientSet())
+	version := formatRegistrationVersion(configuration.ClusterVersion, metadata)
 	registerMessage := si.RegisterResourceManagerRequest{
 		RmID:        configuration.ClusterID,
-		Version:     configuration.ClusterVersion,
+		Version:     version,
 		PolicyGroup: configuration.PolicyGroup,
 	}
 
 	log.Logger().Info("register RM to the scheduler",
 		zap.String("clusterID", configuration.ClusterID),
 		zap.String("clusterVersion", configuration.ClusterVersion),
-		zap.String("policyGroup", configuration.PolicyGroup))
+		zap.String("policyGroup", configuration.PolicyGroup),
+		zap.Any("metadata", metadata))
 	if _, err := ss.apiFactory.GetAPIs().SchedulerAPI.
 		RegisterResourceManager(&registerMessage, ss.callback); err != nil {
 		return err
 	}
 
+	// in federation mode, the shim registers with the secondary clusters too,
+	// apps with the cluster-id annotation are submitted to these clusters.
+	// the nodes of a secondary cluster are registered by the shim deployed in that cluster.
+	for _, clusterID := range configuration.GetFederatedClusterIDs() {
+		federatedMessage := si.RegisterResourceManagerRequest{
+			RmID:        clusterID,
+			Version:     version,
+			PolicyGroup: configuration.PolicyGroup,
+		}
+		log.Logger().Info("register federated RM to the scheduler",
+			zap.String("clusterID", clusterID))
+		if _, err := ss.apiFactory.GetAPIs().SchedulerAPI.
+			RegisterResourceManager(&federatedMessage, ss.callback); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reregister registers the shim again with a core that lost the state of the shim, e.g. after the core restarted,
+// and replays the apps, nodes, allocations and pending asks cached by the shim to the core. The shim keeps running,
+// nothing is recovered from the api-server again.
+func (ss *KubernetesShim) reregister() {
+	if state := ss.GetSchedulerState(); state != events.States().Scheduler.Running {
+		log.Logger().Warn("the scheduler is not running, the shim is not registered again",
+			zap.String("state", state))
+		return
+	}
+	if err := ss.registerShimLayer(); err != nil {
+		log.Logger().Error("failed to register with the scheduler core again", zap.Error(err))
+		return
+	}
+	log.Logger().Info("registered with the scheduler core again, replaying the shim cache")
+	// the replay is queued on the update stream, it must not block the stream from being served
+	go ss.context.ReplayToCore()
+}
+
+// waitForRegistration blocks until the shim is registered with the core or has stopped,
+// it returns the registration failure if the shim stopped before it was registered.
+func (ss *KubernetesShim) waitForRegistration(timeout time.Duration) error {
+	states := events.States().Scheduler
+	if err := utils.WaitForCondition(func() bool {
+		state := ss.GetSchedulerState()
+		return state != states.New && state != states.Registering
+	}, 100*time.Millisecond, timeout); err != nil {
+		return fmt.Errorf("shim was not registered with the scheduler core within %s", timeout)
+	}
+	ss.lock.RLock()
+	defer ss.lock.RUnlock()
+	if ss.stateMachine.Current() == states.Stopped {
+		if ss.registrationErr != nil {
+			return ss.registrationErr
+		}
+		return fmt.Errorf("shim stopped before it was registered with the scheduler core")
+	}
 	return nil
 }
 
@@ -249,10 +349,7 @@ func (ss *KubernetesShim) canHandle(se events.SchedulerEvent) bool {
 
 // each schedule iteration, we scan all apps and triggers app state transition
 func (ss *KubernetesShim) schedule() {
-	apps := ss.context.SelectApplications(nil)
-	for _, app := range apps {
-		app.Schedule()
-	}
+	ss.context.ScheduleApplications()
 }
 
 func (ss *KubernetesShim) run() {
@@ -303,6 +400,16 @@ func (ss *KubernetesShim) stop() {
 		ss.appManager.Stop()
 		// stop the placeholder manager
 		ss.phManager.Stop()
+		// write the last app checkpoints
+		ss.context.StopAppCheckpoint()
+		// stop comparing the allocations with the core
+		ss.context.StopStateReconciler()
+		// stop reading the queue capacities from the core
+		ss.context.StopQueueCapacityRefresh()
+		// stop checking the provisioning requests
+		ss.context.StopProvisioningRequests()
+		// restore the steady state scheduling limits
+		ss.context.StopThroughputBurst()
 	default:
 		log.Logger().Info("scheduler is already stopped")
 	}
@@ -20,6 +20,8 @@ package main
 
 import (
 	"fmt"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -35,6 +37,7 @@ import (
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/test"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
 	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
@@ -169,6 +172,87 @@ func TestSchedulerRegistrationFailed(t *testing.T) {
 	assert.NilError(t, err)
 }
 
+func TestSchedulerRegistrationIncompatible(t *testing.T) {
+	var callback api.ResourceManagerCallback
+	var registeredVersion string
+
+	mockedAMProtocol := cache.NewMockedAMProtocol()
+	mockedAPIProvider := client.NewMockedAPIProvider()
+	mockedAPIProvider.GetAPIs().SchedulerAPI = test.NewSchedulerAPIMock().RegisterFunction(
+		func(request *si.RegisterResourceManagerRequest,
+			callback api.ResourceManagerCallback) (response *si.RegisterResourceManagerResponse, e error) {
+			registeredVersion = request.Version
+			return nil, fmt.Errorf("incompatible RM version %s", request.Version)
+		})
+
+	ctx := cache.NewContext(mockedAPIProvider)
+	shim := newShimSchedulerInternal(ctx, mockedAPIProvider,
+		appmgmt.NewAMService(mockedAMProtocol, mockedAPIProvider), callback)
+	shim.run()
+	defer shim.stop()
+
+	// the shim refuses to start and reports why
+	err := shim.waitForRegistration(5 * time.Second)
+	assert.ErrorContains(t, err, "incompatible RM version")
+	assert.Equal(t, shim.GetSchedulerState(), events.States().Scheduler.Stopped)
+	assert.Assert(t, strings.HasPrefix(registeredVersion, conf.GetSchedulerConf().ClusterVersion+"+"), registeredVersion)
+	assert.Assert(t, strings.Contains(registeredVersion, metadataShimVersion+"="), registeredVersion)
+	assert.Assert(t, strings.Contains(registeredVersion, metadataKubernetesVersion+"="), registeredVersion)
+}
+
+func TestFederatedRegistration(t *testing.T) {
+	schedulerConf := conf.GetSchedulerConf()
+	federated := schedulerConf.FederatedClusterIDs
+	schedulerConf.FederatedClusterIDs = "cluster-b,cluster-c"
+	defer func() {
+		schedulerConf.FederatedClusterIDs = federated
+	}()
+
+	var callback api.ResourceManagerCallback
+	registered := make([]string, 0)
+	mockedAMProtocol := cache.NewMockedAMProtocol()
+	mockedAPIProvider := client.NewMockedAPIProvider()
+	mockedAPIProvider.GetAPIs().SchedulerAPI = test.NewSchedulerAPIMock().RegisterFunction(
+		func(request *si.RegisterResourceManagerRequest,
+			callback api.ResourceManagerCallback) (response *si.RegisterResourceManagerResponse, e error) {
+			registered = append(registered, request.RmID)
+			return nil, nil
+		})
+
+	ctx := cache.NewContext(mockedAPIProvider)
+	shim := newShimSchedulerInternal(ctx, mockedAPIProvider,
+		appmgmt.NewAMService(mockedAMProtocol, mockedAPIProvider), callback)
+	err := shim.registerShimLayer()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, registered, []string{schedulerConf.ClusterID, "cluster-b", "cluster-c"})
+}
+
+func TestReregister(t *testing.T) {
+	var callback api.ResourceManagerCallback
+	var registrations int32
+	mockedAMProtocol := cache.NewMockedAMProtocol()
+	mockedAPIProvider := client.NewMockedAPIProvider()
+	mockedAPIProvider.GetAPIs().SchedulerAPI = test.NewSchedulerAPIMock().RegisterFunction(
+		func(request *si.RegisterResourceManagerRequest,
+			callback api.ResourceManagerCallback) (response *si.RegisterResourceManagerResponse, e error) {
+			atomic.AddInt32(&registrations, 1)
+			return nil, nil
+		})
+
+	ctx := cache.NewContext(mockedAPIProvider)
+	shim := newShimSchedulerInternal(ctx, mockedAPIProvider,
+		appmgmt.NewAMService(mockedAMProtocol, mockedAPIProvider), callback)
+
+	// the shim is not registered again before it is running
+	shim.reregister()
+	assert.Equal(t, atomic.LoadInt32(&registrations), int32(0))
+
+	shim.stateMachine.SetState(events.States().Scheduler.Running)
+	shim.reregister()
+	assert.Equal(t, atomic.LoadInt32(&registrations), int32(1))
+	assert.Equal(t, shim.GetSchedulerState(), events.States().Scheduler.Running)
+}
+
 func TestTaskFailures(t *testing.T) {
 	configData := `
 partitions:
@@ -0,0 +1,212 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package testutils
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/apis/yunikorn.apache.org/v1alpha1"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/appmgmt/interfaces"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+)
+
+// PodBuilder builds a pod scheduled by yunikorn with a single container, the UID of the pod is its name
+type PodBuilder struct {
+	pod *v1.Pod
+}
+
+func NewPodBuilder(name, namespace string) *PodBuilder {
+	return &PodBuilder{
+		pod: &v1.Pod{
+			TypeMeta: apis.TypeMeta{
+				Kind:       "Pod",
+				APIVersion: "v1",
+			},
+			ObjectMeta: apis.ObjectMeta{
+				Name:        name,
+				Namespace:   namespace,
+				UID:         types.UID(name),
+				Labels:      make(map[string]string),
+				Annotations: make(map[string]string),
+			},
+			Spec: v1.PodSpec{
+				SchedulerName: constants.SchedulerName,
+				Containers: []v1.Container{{
+					Name: "container",
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{},
+					},
+				}},
+			},
+			Status: v1.PodStatus{
+				Phase: v1.PodPending,
+			},
+		},
+	}
+}
+
+// AppID sets the application ID label of the pod
+func (b *PodBuilder) AppID(appID string) *PodBuilder {
+	return b.Label(constants.LabelApplicationID, appID)
+}
+
+// Queue sets the queue label of the pod
+func (b *PodBuilder) Queue(queue string) *PodBuilder {
+	return b.Label(constants.LabelQueueName, queue)
+}
+
+// TaskGroup makes the pod a member of the task group of its app
+func (b *PodBuilder) TaskGroup(taskGroupName string) *PodBuilder {
+	return b.Annotation(constants.AnnotationTaskGroupName, taskGroupName)
+}
+
+// CPU sets the cpu request of the container, e.g. "500m"
+func (b *PodBuilder) CPU(quantity string) *PodBuilder {
+	b.pod.Spec.Containers[0].Resources.Requests[v1.ResourceCPU] = resource.MustParse(quantity)
+	return b
+}
+
+// Memory sets the memory request of the container, e.g. "1Gi"
+func (b *PodBuilder) Memory(quantity string) *PodBuilder {
+	b.pod.Spec.Containers[0].Resources.Requests[v1.ResourceMemory] = resource.MustParse(quantity)
+	return b
+}
+
+func (b *PodBuilder) Label(key, value string) *PodBuilder {
+	b.pod.Labels[key] = value
+	return b
+}
+
+func (b *PodBuilder) Annotation(key, value string) *PodBuilder {
+	b.pod.Annotations[key] = value
+	return b
+}
+
+// NodeName binds the pod to the node before it is scheduled
+func (b *PodBuilder) NodeName(nodeName string) *PodBuilder {
+	b.pod.Spec.NodeName = nodeName
+	return b
+}
+
+func (b *PodBuilder) Phase(phase v1.PodPhase) *PodBuilder {
+	b.pod.Status.Phase = phase
+	return b
+}
+
+func (b *PodBuilder) Build() *v1.Pod {
+	return b.pod.DeepCopy()
+}
+
+// AppBuilder builds the request adding an app to the cache, the app is in the default queue of the
+// admission controller and submitted by the default user unless they are set
+type AppBuilder struct {
+	metadata interfaces.ApplicationMetadata
+}
+
+func NewAppBuilder(appID string) *AppBuilder {
+	return &AppBuilder{
+		metadata: interfaces.ApplicationMetadata{
+			ApplicationID: appID,
+			QueueName:     constants.ApplicationDefaultQueue,
+			User:          constants.DefaultUser,
+			Tags:          make(map[string]string),
+		},
+	}
+}
+
+func (b *AppBuilder) Queue(queue string) *AppBuilder {
+	b.metadata.QueueName = queue
+	return b
+}
+
+func (b *AppBuilder) User(user string, groups ...string) *AppBuilder {
+	b.metadata.User = user
+	b.metadata.Groups = groups
+	return b
+}
+
+func (b *AppBuilder) Tag(key, value string) *AppBuilder {
+	b.metadata.Tags[key] = value
+	return b
+}
+
+// TaskGroups sets the task groups reserved by the placeholders of the app
+func (b *AppBuilder) TaskGroups(taskGroups ...v1alpha1.TaskGroup) *AppBuilder {
+	b.metadata.TaskGroups = taskGroups
+	return b
+}
+
+func (b *AppBuilder) PlaceholderTimeout(timeoutInSec int64) *AppBuilder {
+	b.metadata.PlaceholderTimeoutInSec = timeoutInSec
+	return b
+}
+
+func (b *AppBuilder) Build() *interfaces.AddApplicationRequest {
+	return &interfaces.AddApplicationRequest{
+		Metadata: b.metadata,
+	}
+}
+
+// TaskGroupBuilder builds a task group, every member asks for the min resource of the task group
+type TaskGroupBuilder struct {
+	taskGroup v1alpha1.TaskGroup
+}
+
+func NewTaskGroupBuilder(name string, minMember int32) *TaskGroupBuilder {
+	return &TaskGroupBuilder{
+		taskGroup: v1alpha1.TaskGroup{
+			Name:        name,
+			MinMember:   minMember,
+			MinResource: make(map[string]resource.Quantity),
+		},
+	}
+}
+
+// CPU sets the cpu of a member, e.g. "500m"
+func (b *TaskGroupBuilder) CPU(quantity string) *TaskGroupBuilder {
+	b.taskGroup.MinResource[v1.ResourceCPU.String()] = resource.MustParse(quantity)
+	return b
+}
+
+// Memory sets the memory of a member, e.g. "1Gi"
+func (b *TaskGroupBuilder) Memory(quantity string) *TaskGroupBuilder {
+	b.taskGroup.MinResource[v1.ResourceMemory.String()] = resource.MustParse(quantity)
+	return b
+}
+
+func (b *TaskGroupBuilder) NodeSelector(key, value string) *TaskGroupBuilder {
+	if b.taskGroup.NodeSelector == nil {
+		b.taskGroup.NodeSelector = make(map[string]string)
+	}
+	b.taskGroup.NodeSelector[key] = value
+	return b
+}
+
+// DependsOn sets the task groups that must be bound before this task group is reserved
+func (b *TaskGroupBuilder) DependsOn(taskGroupNames ...string) *TaskGroupBuilder {
+	b.taskGroup.DependsOn = taskGroupNames
+	return b
+}
+
+func (b *TaskGroupBuilder) Build() v1alpha1.TaskGroup {
+	return *b.taskGroup.DeepCopy()
+}
@@ -0,0 +1,36 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// Package testutils helps to test code built on top of the shim without a cluster or a core.
+//
+// The Harness runs the cache, the dispatcher and the scheduling loop of the shim with the mocked
+// API provider of the client package and a FakeCore in place of the scheduler core. The FakeCore
+// accepts every node and app it receives and allocates every ask on one of its nodes, the pods are
+// bound by the mocked kube client. The builders create the pods, apps and task groups the tests
+// add to the harness:
+//
+//	h := testutils.NewHarness()
+//	h.Start()
+//	defer h.Stop()
+//	h.AddApplication(testutils.NewAppBuilder("app-1").Queue("root.a").Build())
+//	h.AddPod(testutils.NewPodBuilder("pod-1", "default").AppID("app-1").CPU("100m").Build())
+//	err := h.WaitForBound("pod-1", 5*time.Second)
+//
+// An error the shim returns for a response of the FakeCore fails the waits of the harness.
+// The dispatcher of the shim is global, only one harness can run at a time.
+package testutils
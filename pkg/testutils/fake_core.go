@@ -0,0 +1,183 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package testutils
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+// FakeCore replaces the scheduler core: the nodes and the apps it receives are accepted and the asks
+// are allocated on its nodes in turn. The asks received while the core has no node stay pending until
+// a node is added. The responses are sent to the callback from another routine, as the core does.
+// An error returned by the callback is kept, the Harness fails its waits with it.
+type FakeCore struct {
+	callback    api.ResourceManagerCallback
+	nodes       []string
+	nextNode    int
+	allocID     int64
+	pending     []*si.AllocationAsk
+	allocations map[string]*si.Allocation
+	updateCount int
+	err         error // first error returned by the callback
+	sync.Mutex
+}
+
+// NewFakeCore creates a core allocating on the given nodes, the nodes registered by the shim are added to them
+func NewFakeCore(nodes ...string) *FakeCore {
+	return &FakeCore{
+		nodes:       append([]string{}, nodes...),
+		pending:     make([]*si.AllocationAsk, 0),
+		allocations: make(map[string]*si.Allocation),
+	}
+}
+
+// SetCallback sets the callback the responses are sent to when the shim does not register with the core
+func (c *FakeCore) SetCallback(callback api.ResourceManagerCallback) {
+	c.Lock()
+	defer c.Unlock()
+	c.callback = callback
+}
+
+func (c *FakeCore) RegisterResourceManager(request *si.RegisterResourceManagerRequest,
+	callback api.ResourceManagerCallback) (*si.RegisterResourceManagerResponse, error) {
+	c.SetCallback(callback)
+	return &si.RegisterResourceManagerResponse{}, nil
+}
+
+func (c *FakeCore) Update(request *si.UpdateRequest) error {
+	c.Lock()
+	defer c.Unlock()
+	c.updateCount++
+	response := &si.UpdateResponse{}
+	for _, node := range request.NewSchedulableNodes {
+		c.nodes = append(c.nodes, node.NodeID)
+		response.AcceptedNodes = append(response.AcceptedNodes, &si.AcceptedNode{NodeID: node.NodeID})
+	}
+	for _, app := range request.NewApplications {
+		response.AcceptedApplications = append(response.AcceptedApplications,
+			&si.AcceptedApplication{ApplicationID: app.ApplicationID})
+	}
+	if request.Releases != nil {
+		c.release(request.Releases)
+	}
+	c.pending = append(c.pending, request.Asks...)
+	response.NewAllocations = c.allocate()
+
+	if c.callback != nil && (len(response.AcceptedNodes) > 0 || len(response.AcceptedApplications) > 0 ||
+		len(response.NewAllocations) > 0) {
+		callback := c.callback
+		go func() {
+			if err := callback.RecvUpdateResponse(response); err != nil {
+				c.setError(err)
+			}
+		}()
+	}
+	return nil
+}
+
+func (c *FakeCore) ReloadConfiguration(clusterID string) error {
+	return nil
+}
+
+func (c *FakeCore) setError(err error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+// GetError returns the first error the callback returned for a response of the core, nil if there was none
+func (c *FakeCore) GetError() error {
+	c.Lock()
+	defer c.Unlock()
+	return c.err
+}
+
+// release drops the released asks and allocations, the caller holds the lock
+func (c *FakeCore) release(releases *si.AllocationReleasesRequest) {
+	for _, ask := range releases.AllocationAsksToRelease {
+		pending := c.pending[:0]
+		for _, p := range c.pending {
+			if p.AllocationKey != ask.Allocationkey {
+				pending = append(pending, p)
+			}
+		}
+		c.pending = pending
+	}
+	for _, alloc := range releases.AllocationsToRelease {
+		delete(c.allocations, alloc.UUID)
+	}
+}
+
+// allocate allocates the pending asks on the nodes in turn, the caller holds the lock
+func (c *FakeCore) allocate() []*si.Allocation {
+	if len(c.nodes) == 0 {
+		return nil
+	}
+	allocations := make([]*si.Allocation, 0, len(c.pending))
+	for _, ask := range c.pending {
+		c.allocID++
+		alloc := &si.Allocation{
+			AllocationKey:    ask.AllocationKey,
+			AllocationTags:   ask.Tags,
+			UUID:             fmt.Sprintf("alloc-%d", c.allocID),
+			ResourcePerAlloc: ask.ResourceAsk,
+			NodeID:           c.nodes[c.nextNode%len(c.nodes)],
+			ApplicationID:    ask.ApplicationID,
+			PartitionName:    ask.PartitionName,
+			TaskGroupName:    ask.TaskGroupName,
+			Placeholder:      ask.Placeholder,
+		}
+		c.nextNode++
+		c.allocations[alloc.UUID] = alloc
+		allocations = append(allocations, alloc)
+	}
+	c.pending = c.pending[:0]
+	return allocations
+}
+
+// GetAllocations returns the allocations the core made that are not released
+func (c *FakeCore) GetAllocations() []*si.Allocation {
+	c.Lock()
+	defer c.Unlock()
+	allocations := make([]*si.Allocation, 0, len(c.allocations))
+	for _, alloc := range c.allocations {
+		allocations = append(allocations, alloc)
+	}
+	return allocations
+}
+
+// GetPendingAsks returns the number of asks waiting for a node
+func (c *FakeCore) GetPendingAsks() int {
+	c.Lock()
+	defer c.Unlock()
+	return len(c.pending)
+}
+
+// GetUpdateCount returns the number of update requests the core received
+func (c *FakeCore) GetUpdateCount() int {
+	c.Lock()
+	defer c.Unlock()
+	return c.updateCount
+}
@@ -0,0 +1,241 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package testutils
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/appmgmt/interfaces"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/cache"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/callback"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/dispatcher"
+)
+
+const (
+	// DefaultNode is the node of the fake core when a harness is created without nodes
+	DefaultNode = "test-node"
+	// the interval of the scheduling loop of the harness
+	harnessSchedulingInterval = 10 * time.Millisecond
+	harnessPollInterval       = 10 * time.Millisecond
+)
+
+// Harness runs the cache of the shim against a FakeCore, it replaces the KubernetesShim: it runs the
+// scheduling loop and passes the responses of the core to the callback. The mocked kube client records
+// the pods it binds, the placeholders it creates are added to their app like the informers would.
+type Harness struct {
+	Context     *cache.Context
+	Core        *FakeCore
+	APIProvider *client.MockedAPIProvider
+	callback    *callback.AsyncRMCallback
+	placeholder *cache.PlaceholderManager
+	bound       map[string]string
+	deleted     map[string]bool
+	stopChan    chan struct{}
+	sync.RWMutex
+}
+
+// NewHarness creates a harness whose fake core allocates on the given nodes, or on the DefaultNode
+func NewHarness(nodes ...string) *Harness {
+	if len(nodes) == 0 {
+		nodes = []string{DefaultNode}
+	}
+	apiProvider := client.NewMockedAPIProvider()
+	core := NewFakeCore(nodes...)
+	apiProvider.GetAPIs().SchedulerAPI = core
+	h := &Harness{
+		Context:     cache.NewContext(apiProvider),
+		Core:        core,
+		APIProvider: apiProvider,
+		bound:       make(map[string]string),
+		deleted:     make(map[string]bool),
+	}
+	h.callback = callback.NewAsyncRMCallback(h.Context)
+	core.SetCallback(h.callback)
+	h.placeholder = cache.NewPlaceholderManager(apiProvider.GetAPIs())
+	apiProvider.MockBindFn(h.bind)
+	apiProvider.MockCreateFn(h.create)
+	apiProvider.MockDeleteFn(h.delete)
+	return h
+}
+
+// Start starts the dispatcher, the placeholder manager and the scheduling loop
+func (h *Harness) Start() {
+	conf.GetSchedulerConf().SetTestMode(true)
+	// the fake recorder of the test mode blocks once its buffer is full
+	events.SetRecorderForTest(events.NewMockedRecorder())
+	dispatcher.RegisterEventHandler(dispatcher.EventTypeApp, h.Context.ApplicationEventHandler())
+	dispatcher.RegisterEventHandler(dispatcher.EventTypeTask, h.Context.TaskEventHandler())
+	dispatcher.RegisterEventHandler(dispatcher.EventTypeNode, h.Context.SchedulerNodeEventHandler())
+	dispatcher.Start()
+	h.placeholder.Start()
+	h.stopChan = make(chan struct{})
+	go h.run()
+}
+
+// Stop stops the scheduling loop, the placeholder manager and the dispatcher
+func (h *Harness) Stop() {
+	close(h.stopChan)
+	h.placeholder.Stop()
+	dispatcher.Stop()
+}
+
+func (h *Harness) run() {
+	for {
+		select {
+		case <-h.stopChan:
+			return
+		case <-time.After(harnessSchedulingInterval):
+			h.Context.ScheduleApplications()
+		}
+	}
+}
+
+func (h *Harness) AddApplication(request *interfaces.AddApplicationRequest) interfaces.ManagedApp {
+	return h.Context.AddApplication(request)
+}
+
+// AddPod adds the pod as a task of the app of its applicationId label, the task ID is the UID of the pod
+func (h *Harness) AddPod(pod *v1.Pod) interfaces.ManagedTask {
+	appID, err := utils.GetApplicationIDFromPod(pod)
+	if err != nil {
+		return nil
+	}
+	return h.Context.AddTask(&interfaces.AddTaskRequest{
+		Metadata: interfaces.TaskMetadata{
+			ApplicationID: appID,
+			TaskID:        string(pod.UID),
+			Pod:           pod,
+			Placeholder:   utils.GetPlaceholderFlagFromPodSpec(pod),
+			TaskGroupName: utils.GetTaskGroupFromPodSpec(pod),
+		},
+	})
+}
+
+// CompletePod completes the task of the pod, like the pod being deleted or terminated
+func (h *Harness) CompletePod(pod *v1.Pod) {
+	if appID, err := utils.GetApplicationIDFromPod(pod); err == nil {
+		h.Context.NotifyTaskComplete(appID, string(pod.UID))
+	}
+}
+
+// GetBoundNode returns the node the pod is bound to, empty if it is not bound
+func (h *Harness) GetBoundNode(podName string) string {
+	h.RLock()
+	defer h.RUnlock()
+	return h.bound[podName]
+}
+
+// IsDeleted returns true if the shim deleted the pod
+func (h *Harness) IsDeleted(podName string) bool {
+	h.RLock()
+	defer h.RUnlock()
+	return h.deleted[podName]
+}
+
+// WaitForBound waits until the pod is bound to a node
+func (h *Harness) WaitForBound(podName string, timeout time.Duration) error {
+	if err := h.waitFor(func() bool {
+		return h.GetBoundNode(podName) != ""
+	}, timeout); err != nil {
+		return fmt.Errorf("pod %s is not bound: %v", podName, err)
+	}
+	return nil
+}
+
+// WaitForAppState waits until the app is in the given state
+func (h *Harness) WaitForAppState(appID, state string, timeout time.Duration) error {
+	var current string
+	if err := h.waitFor(func() bool {
+		if app := h.Context.GetApplication(appID); app != nil {
+			current = app.GetApplicationState()
+		}
+		return current == state
+	}, timeout); err != nil {
+		return fmt.Errorf("app %s is in state %q, expected %q: %v", appID, current, state, err)
+	}
+	return nil
+}
+
+// WaitForTaskState waits until the task of the pod is in the given state
+func (h *Harness) WaitForTaskState(appID, taskID, state string, timeout time.Duration) error {
+	var current string
+	if err := h.waitFor(func() bool {
+		if app := h.Context.GetApplication(appID); app != nil {
+			if task, err := app.GetTask(taskID); err == nil {
+				current = task.GetTaskState()
+			}
+		}
+		return current == state
+	}, timeout); err != nil {
+		return fmt.Errorf("task %s is in state %q, expected %q: %v", taskID, current, state, err)
+	}
+	return nil
+}
+
+// waitFor waits until the condition is met, it fails right away once the shim failed to process
+// a response of the fake core: the condition may then never be met.
+func (h *Harness) waitFor(condition func() bool, timeout time.Duration) error {
+	var coreErr error
+	err := utils.WaitForCondition(func() bool {
+		if coreErr = h.Core.GetError(); coreErr != nil {
+			return true
+		}
+		return condition()
+	}, harnessPollInterval, timeout)
+	if coreErr != nil {
+		return fmt.Errorf("the shim failed to process a response of the core: %v", coreErr)
+	}
+	return err
+}
+
+func (h *Harness) bind(pod *v1.Pod, hostID string) error {
+	h.Lock()
+	defer h.Unlock()
+	h.bound[pod.Name] = hostID
+	return nil
+}
+
+// create adds the created placeholders to their app, like the informers would,
+// the API server assigns the UID of the pod
+func (h *Harness) create(pod *v1.Pod) (*v1.Pod, error) {
+	created := pod.DeepCopy()
+	if created.UID == "" {
+		created.UID = types.UID(created.Name)
+	}
+	go h.AddPod(created)
+	return created, nil
+}
+
+// delete completes the task of the deleted pod, like the informers would
+func (h *Harness) delete(pod *v1.Pod) error {
+	h.Lock()
+	h.deleted[pod.Name] = true
+	h.Unlock()
+	go h.CompletePod(pod)
+	return nil
+}
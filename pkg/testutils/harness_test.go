@@ -0,0 +1,117 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package testutils
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+func TestBuilders(t *testing.T) {
+	pod := NewPodBuilder("pod-1", "ns").AppID("app-1").Queue("root.a").TaskGroup("tg").CPU("500m").Memory("1Gi").Build()
+	assert.Equal(t, string(pod.UID), "pod-1")
+	assert.Equal(t, pod.Labels[constants.LabelApplicationID], "app-1")
+	assert.Equal(t, pod.Labels[constants.LabelQueueName], "root.a")
+	assert.Equal(t, pod.Annotations[constants.AnnotationTaskGroupName], "tg")
+	assert.Equal(t, pod.Spec.Containers[0].Resources.Requests.Cpu().MilliValue(), int64(500))
+	assert.Equal(t, pod.Spec.SchedulerName, constants.SchedulerName)
+
+	tg := NewTaskGroupBuilder("tg", 3).CPU("1").DependsOn("driver").Build()
+	assert.Equal(t, tg.MinMember, int32(3))
+	assert.Equal(t, len(tg.DependsOn), 1)
+	app := NewAppBuilder("app-1").Queue("root.a").User("alice", "dev").TaskGroups(tg).Build()
+	assert.Equal(t, app.Metadata.QueueName, "root.a")
+	assert.Equal(t, app.Metadata.User, "alice")
+	assert.Equal(t, len(app.Metadata.TaskGroups), 1)
+}
+
+func TestFakeCorePendingAsks(t *testing.T) {
+	core := NewFakeCore()
+	ask := &si.AllocationAsk{AllocationKey: "ask-1", ApplicationID: "app-1"}
+	assert.NilError(t, core.Update(&si.UpdateRequest{Asks: []*si.AllocationAsk{ask}}))
+	assert.Equal(t, core.GetPendingAsks(), 1)
+
+	// the ask is allocated on the first node added
+	assert.NilError(t, core.Update(&si.UpdateRequest{
+		NewSchedulableNodes: []*si.NewNodeInfo{{NodeID: "node-1"}},
+	}))
+	assert.Equal(t, core.GetPendingAsks(), 0)
+	allocations := core.GetAllocations()
+	assert.Equal(t, len(allocations), 1)
+	assert.Equal(t, allocations[0].NodeID, "node-1")
+
+	assert.NilError(t, core.Update(&si.UpdateRequest{
+		Releases: &si.AllocationReleasesRequest{
+			AllocationsToRelease: []*si.AllocationRelease{{UUID: allocations[0].UUID}},
+		},
+	}))
+	assert.Equal(t, len(core.GetAllocations()), 0)
+	assert.Equal(t, core.GetUpdateCount(), 3)
+}
+
+type failingCallback struct{}
+
+func (f failingCallback) RecvUpdateResponse(response *si.UpdateResponse) error {
+	return fmt.Errorf("response rejected")
+}
+
+func TestFakeCoreCallbackError(t *testing.T) {
+	core := NewFakeCore("node-1")
+	core.SetCallback(failingCallback{})
+	assert.NilError(t, core.Update(&si.UpdateRequest{
+		NewApplications: []*si.AddApplicationRequest{{ApplicationID: "app-1"}},
+	}))
+	// the error of the callback is kept instead of crashing the test binary
+	assert.NilError(t, utils.WaitForCondition(func() bool {
+		return core.GetError() != nil
+	}, harnessPollInterval, 5*time.Second))
+	assert.ErrorContains(t, core.GetError(), "response rejected")
+}
+
+func TestHarness(t *testing.T) {
+	h := NewHarness("node-1", "node-2")
+	h.Start()
+	defer h.Stop()
+
+	// the pods are allocated on the nodes in turn
+	h.AddApplication(NewAppBuilder("app-1").Build())
+	h.AddPod(NewPodBuilder("pod-1", "default").AppID("app-1").CPU("100m").Build())
+	h.AddPod(NewPodBuilder("pod-2", "default").AppID("app-1").CPU("100m").Build())
+	assert.NilError(t, h.WaitForBound("pod-1", 5*time.Second))
+	assert.NilError(t, h.WaitForBound("pod-2", 5*time.Second))
+	assert.Assert(t, h.GetBoundNode("pod-1") != h.GetBoundNode("pod-2"))
+	assert.NilError(t, h.WaitForAppState("app-1", events.States().Application.Running, 5*time.Second))
+	assert.NilError(t, h.WaitForTaskState("app-1", "pod-1", events.States().Task.Bound, 5*time.Second))
+
+	// the placeholders of a gang are created and bound before the app runs
+	h.AddApplication(NewAppBuilder("app-2").TaskGroups(NewTaskGroupBuilder("tg", 2).CPU("100m").Build()).Build())
+	h.AddPod(NewPodBuilder("pod-3", "default").AppID("app-2").TaskGroup("tg").CPU("100m").Build())
+	assert.NilError(t, h.WaitForAppState("app-2", events.States().Application.Running, 5*time.Second))
+	// the fake core does not replace the placeholders, the member gets its own allocation
+	assert.NilError(t, h.WaitForBound("pod-3", 5*time.Second))
+	assert.Equal(t, len(h.Core.GetAllocations()), 5)
+}
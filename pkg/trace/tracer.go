@@ -0,0 +1,215 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// Package trace traces the scheduling of the pods, a trace covers a pod from the time it is added
+// to the shim until it is bound to a node: the time it is pending in the shim, the time its ask waits
+// for an allocation of the core and the time of the binding. The spans are reported to Jaeger.
+package trace
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"go.uber.org/zap"
+
+	coretrace "github.com/apache/incubator-yunikorn-core/pkg/trace"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/common"
+)
+
+const (
+	ServiceName = "yunikorn-k8shim"
+
+	// the root span of the trace of a pod
+	SchedulePod = "SchedulePod"
+
+	TagAppID  = "appID"
+	TagTaskID = "taskID"
+	TagEvent  = "event"
+	TagState  = "state"
+
+	// the span context of a task is passed to the core in the tags of its ask with this prefix
+	AskTagPrefix = common.DomainYuniKorn + "trace/"
+)
+
+var tracer = &schedulerTracer{
+	traces: make(map[string]*taskTrace),
+}
+
+type schedulerTracer struct {
+	tracer opentracing.Tracer // nil when tracing is disabled
+	closer func() error
+	traces map[string]*taskTrace // the traces of the pods being scheduled, keyed by task ID
+	sync.RWMutex
+}
+
+// the root span of a pod and the span of its current scheduling stage
+type taskTrace struct {
+	root  opentracing.Span
+	stage opentracing.Span
+}
+
+// Init enables the tracing, the Jaeger tracer is configured from the JAEGER_* environment variables.
+func Init() error {
+	t, closer, err := coretrace.NewTracerFromEnv(ServiceName)
+	if err != nil {
+		return err
+	}
+	SetTracer(t, closer.Close)
+	log.Logger().Info("tracing is enabled", zap.String("serviceName", ServiceName))
+	return nil
+}
+
+// SetTracer sets the tracer the spans are reported to, a nil tracer disables the tracing.
+func SetTracer(t opentracing.Tracer, closer func() error) {
+	tracer.Lock()
+	defer tracer.Unlock()
+	tracer.tracer = t
+	tracer.closer = closer
+	tracer.traces = make(map[string]*taskTrace)
+}
+
+// Close finishes the open traces, flushes the spans and disables the tracing.
+func Close() {
+	tracer.Lock()
+	defer tracer.Unlock()
+	for taskID, t := range tracer.traces {
+		t.finish("shutdown")
+		delete(tracer.traces, taskID)
+	}
+	if tracer.closer != nil {
+		if err := tracer.closer(); err != nil {
+			log.Logger().Warn("failed to close the tracer", zap.Error(err))
+		}
+	}
+	tracer.tracer = nil
+	tracer.closer = nil
+}
+
+func IsEnabled() bool {
+	tracer.RLock()
+	defer tracer.RUnlock()
+	return tracer.tracer != nil
+}
+
+// StartTaskTrace starts the trace of a pod, the trace starts at the time the pod was added.
+// Nothing is done if the task is already traced or the tracing is disabled.
+func StartTaskTrace(appID, taskID string, startTime time.Time) {
+	tracer.Lock()
+	defer tracer.Unlock()
+	if tracer.tracer == nil {
+		return
+	}
+	if _, ok := tracer.traces[taskID]; ok {
+		return
+	}
+	root := tracer.tracer.StartSpan(SchedulePod,
+		opentracing.StartTime(startTime),
+		opentracing.Tag{Key: TagAppID, Value: appID},
+		opentracing.Tag{Key: TagTaskID, Value: taskID})
+	tracer.traces[taskID] = &taskTrace{root: root}
+}
+
+// SetTaskStage finishes the current stage of the pod and starts the next one,
+// nothing is done if the task is not traced.
+func SetTaskStage(taskID, stage string) {
+	tracer.Lock()
+	defer tracer.Unlock()
+	t, ok := tracer.traces[taskID]
+	if !ok {
+		return
+	}
+	if t.stage != nil {
+		t.stage.Finish()
+	}
+	t.stage = tracer.tracer.StartSpan(stage, opentracing.ChildOf(t.root.Context()))
+}
+
+// StartTaskSpan starts a span in the current stage of the pod, the caller must finish the span.
+// A noop span is returned if the task is not traced.
+func StartTaskSpan(taskID, operation string) opentracing.Span {
+	tracer.RLock()
+	defer tracer.RUnlock()
+	t, ok := tracer.traces[taskID]
+	if !ok {
+		return opentracing.NoopTracer{}.StartSpan(operation)
+	}
+	parent := t.root
+	if t.stage != nil {
+		parent = t.stage
+	}
+	return tracer.tracer.StartSpan(operation, opentracing.ChildOf(parent.Context()))
+}
+
+// FinishTaskTrace finishes the trace of a pod, a pod that is not bound is marked as failed
+// with the state it ended in. Nothing is done if the task is not traced.
+func FinishTaskTrace(taskID, failedState string) {
+	tracer.Lock()
+	defer tracer.Unlock()
+	t, ok := tracer.traces[taskID]
+	if !ok {
+		return
+	}
+	t.finish(failedState)
+	delete(tracer.traces, taskID)
+}
+
+func (t *taskTrace) finish(failedState string) {
+	if t.stage != nil {
+		t.stage.Finish()
+	}
+	if failedState != "" {
+		ext.Error.Set(t.root, true)
+		t.root.SetTag(TagState, failedState)
+	}
+	t.root.Finish()
+}
+
+// InjectTaskTrace adds the span context of the pod to the tags of its ask,
+// the trace can be continued from the ask by the core. Nothing is done if the task is not traced.
+func InjectTaskTrace(taskID string, tags map[string]string) {
+	tracer.RLock()
+	defer tracer.RUnlock()
+	t, ok := tracer.traces[taskID]
+	if !ok {
+		return
+	}
+	carrier := opentracing.TextMapCarrier{}
+	if err := tracer.tracer.Inject(t.root.Context(), opentracing.TextMap, carrier); err != nil {
+		log.Logger().Debug("failed to inject the span context", zap.String(TagTaskID, taskID), zap.Error(err))
+		return
+	}
+	for k, v := range carrier {
+		tags[AskTagPrefix+k] = v
+	}
+}
+
+// ExtractTaskTrace returns the span context of a pod from the tags of its ask,
+// it returns opentracing.ErrSpanContextNotFound if the ask does not carry a span context.
+func ExtractTaskTrace(t opentracing.Tracer, tags map[string]string) (opentracing.SpanContext, error) {
+	carrier := opentracing.TextMapCarrier{}
+	for k, v := range tags {
+		if strings.HasPrefix(k, AskTagPrefix) {
+			carrier[strings.TrimPrefix(k, AskTagPrefix)] = v
+		}
+	}
+	return t.Extract(opentracing.TextMap, carrier)
+}
@@ -0,0 +1,156 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package trace
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"gotest.tools/assert"
+)
+
+func initTracerForTest() *mocktracer.MockTracer {
+	t := mocktracer.New()
+	SetTracer(t, nil)
+	return t
+}
+
+func getFinishedSpans(t *mocktracer.MockTracer) map[string]*mocktracer.MockSpan {
+	spans := make(map[string]*mocktracer.MockSpan)
+	for _, span := range t.FinishedSpans() {
+		spans[span.OperationName] = span
+	}
+	return spans
+}
+
+func TestTaskTrace(t *testing.T) {
+	mockTracer := initTracerForTest()
+	defer Close()
+	assert.Assert(t, IsEnabled())
+
+	createTime := time.Now().Add(-time.Second)
+	StartTaskTrace("app-1", "task-1", createTime)
+	// a task is traced once
+	StartTaskTrace("app-1", "task-1", time.Now())
+	SetTaskStage("task-1", "Pending")
+	SetTaskStage("task-1", "Scheduling")
+	span := StartTaskSpan("task-1", "SendAsk")
+	span.Finish()
+	SetTaskStage("task-1", "Binding")
+	assert.Equal(t, len(mockTracer.FinishedSpans()), 3)
+	FinishTaskTrace("task-1", "")
+	// the trace is finished once
+	FinishTaskTrace("task-1", "")
+
+	spans := getFinishedSpans(mockTracer)
+	assert.Equal(t, len(mockTracer.FinishedSpans()), 5)
+	root := spans[SchedulePod]
+	assert.Assert(t, root != nil)
+	assert.Equal(t, root.StartTime, createTime)
+	assert.Equal(t, root.Tag(TagAppID), "app-1")
+	assert.Equal(t, root.Tag(TagTaskID), "task-1")
+	assert.Equal(t, root.Tag("error"), nil)
+	rootID := root.SpanContext.SpanID
+	assert.Equal(t, spans["Pending"].ParentID, rootID)
+	assert.Equal(t, spans["Scheduling"].ParentID, rootID)
+	assert.Equal(t, spans["Binding"].ParentID, rootID)
+	// a span is started in the current stage
+	assert.Equal(t, spans["SendAsk"].ParentID, spans["Scheduling"].SpanContext.SpanID)
+	for _, span := range mockTracer.FinishedSpans() {
+		assert.Equal(t, span.SpanContext.TraceID, root.SpanContext.TraceID)
+	}
+}
+
+func TestFailedTaskTrace(t *testing.T) {
+	mockTracer := initTracerForTest()
+	defer Close()
+
+	StartTaskTrace("app-1", "task-1", time.Now())
+	SetTaskStage("task-1", "Scheduling")
+	FinishTaskTrace("task-1", "Failed")
+	spans := getFinishedSpans(mockTracer)
+	assert.Equal(t, len(spans), 2)
+	assert.Equal(t, spans[SchedulePod].Tag("error"), true)
+	assert.Equal(t, spans[SchedulePod].Tag(TagState), "Failed")
+}
+
+func TestTaskNotTraced(t *testing.T) {
+	// the tracing is disabled
+	SetTracer(nil, nil)
+	assert.Assert(t, !IsEnabled())
+	StartTaskTrace("app-1", "task-1", time.Now())
+	SetTaskStage("task-1", "Pending")
+	span := StartTaskSpan("task-1", "SendAsk")
+	assert.Equal(t, span.Tracer(), opentracing.NoopTracer{})
+	span.Finish()
+	tags := map[string]string{}
+	InjectTaskTrace("task-1", tags)
+	assert.Equal(t, len(tags), 0)
+	FinishTaskTrace("task-1", "")
+
+	// the task trace is not started
+	mockTracer := initTracerForTest()
+	defer Close()
+	SetTaskStage("task-1", "Pending")
+	StartTaskSpan("task-1", "SendAsk").Finish()
+	FinishTaskTrace("task-1", "")
+	assert.Equal(t, len(mockTracer.FinishedSpans()), 0)
+}
+
+func TestInjectTaskTrace(t *testing.T) {
+	mockTracer := initTracerForTest()
+	defer Close()
+
+	StartTaskTrace("app-1", "task-1", time.Now())
+	tags := map[string]string{"other": "value"}
+	InjectTaskTrace("task-1", tags)
+	assert.Assert(t, len(tags) > 1)
+	spanContext, err := ExtractTaskTrace(mockTracer, tags)
+	assert.NilError(t, err)
+	FinishTaskTrace("task-1", "")
+	root := mockTracer.FinishedSpans()[0]
+	mockContext, ok := spanContext.(mocktracer.MockSpanContext)
+	assert.Assert(t, ok)
+	assert.Equal(t, mockContext.TraceID, root.SpanContext.TraceID)
+	assert.Equal(t, mockContext.SpanID, root.SpanContext.SpanID)
+
+	// the ask does not carry a span context
+	_, err = ExtractTaskTrace(mockTracer, map[string]string{"other": "value"})
+	assert.Equal(t, err, opentracing.ErrSpanContextNotFound)
+}
+
+func TestCloseTracer(t *testing.T) {
+	mockTracer := initTracerForTest()
+	closed := false
+	SetTracer(mockTracer, func() error {
+		closed = true
+		return nil
+	})
+	StartTaskTrace("app-1", "task-1", time.Now())
+	SetTaskStage("task-1", "Pending")
+	Close()
+	assert.Assert(t, closed)
+	assert.Assert(t, !IsEnabled())
+	// the open traces are finished
+	spans := getFinishedSpans(mockTracer)
+	assert.Equal(t, len(spans), 2)
+	assert.Equal(t, spans[SchedulePod].Tag(TagState), "shutdown")
+}
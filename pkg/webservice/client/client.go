@@ -0,0 +1,336 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// Package client is a typed client of the REST API of the shim. It only depends on the request and
+// response types of the dao package and on the standard library, tools importing it do not pull the shim.
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/webservice/dao"
+)
+
+// Client calls the REST API of a shim, it is safe for concurrent use
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// Error is returned when the shim answers a request with an error status,
+// the message is the error reported by the shim
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("request failed with status %d: %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound returns true if the error is returned for an app or a node unknown to the shim
+func IsNotFound(err error) bool {
+	e, ok := err.(*Error)
+	return ok && e.StatusCode == http.StatusNotFound
+}
+
+// New returns a client of the shim at the address, e.g. "http://yunikorn-service:9089" or "localhost:9089".
+// The http client is used for all the requests, http.DefaultClient is used when it is nil.
+func New(address string, httpClient *http.Client) *Client {
+	if !strings.HasPrefix(address, "http://") && !strings.HasPrefix(address, "https://") {
+		address = "http://" + address
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		baseURL:    strings.TrimSuffix(address, "/"),
+		httpClient: httpClient,
+	}
+}
+
+// GetStateDump returns the full state of the shim
+func (c *Client) GetStateDump(ctx context.Context) (*dao.StateDump, error) {
+	dump := &dao.StateDump{}
+	if err := c.get(ctx, "/debug/fullstatedump", nil, dump); err != nil {
+		return nil, err
+	}
+	return dump, nil
+}
+
+// GetSILogging returns the logging settings of the scheduler interface messages
+func (c *Client) GetSILogging(ctx context.Context) (*dao.SILogging, error) {
+	settings := &dao.SILogging{}
+	if err := c.get(ctx, "/debug/silogging", nil, settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// UpdateSILogging changes the logging settings of the scheduler interface messages, the settings in effect are returned
+func (c *Client) UpdateSILogging(ctx context.Context, settings dao.SILogging) (*dao.SILogging, error) {
+	body, err := json.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+	updated := &dao.SILogging{}
+	if err = c.do(ctx, http.MethodPost, "/debug/silogging", nil, body, updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// GetDispatchedEvents returns the last events handled by the dispatcher, the events of all the apps
+// are returned when the appID is empty
+func (c *Client) GetDispatchedEvents(ctx context.Context, appID string) ([]dao.DispatchedEvent, error) {
+	dispatched := make([]dao.DispatchedEvent, 0)
+	if err := c.get(ctx, "/debug/events", appQuery(appID), &dispatched); err != nil {
+		return nil, err
+	}
+	return dispatched, nil
+}
+
+// GetResourceUsage returns the resources used by the apps of the shim
+func (c *Client) GetResourceUsage(ctx context.Context) (*dao.ResourceUsage, error) {
+	usage := &dao.ResourceUsage{}
+	if err := c.get(ctx, "/ws/v1/resourceusage", nil, usage); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// GetPendingResources returns the resources of the tasks that are not allocated yet, per app
+func (c *Client) GetPendingResources(ctx context.Context) ([]*dao.ApplicationPendingResource, error) {
+	pending := make([]*dao.ApplicationPendingResource, 0)
+	if err := c.get(ctx, "/ws/v1/pendingresources", nil, &pending); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// GetQuarantinedTasks returns the tasks that are not scheduled because the scheduling directives of their pods conflict
+func (c *Client) GetQuarantinedTasks(ctx context.Context) ([]*dao.QuarantinedTask, error) {
+	tasks := make([]*dao.QuarantinedTask, 0)
+	if err := c.get(ctx, "/ws/v1/tasks/quarantined", nil, &tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// GetApplicationPendingResource returns the resources of the tasks of the app that are not allocated yet
+func (c *Client) GetApplicationPendingResource(ctx context.Context, appID string) (*dao.ApplicationPendingResource, error) {
+	pending := &dao.ApplicationPendingResource{}
+	if err := c.get(ctx, "/ws/v1/apps/"+url.PathEscape(appID)+"/pendingresource", nil, pending); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// GetApplicationsByQueue returns the apps of the queue and of its child queues
+func (c *Client) GetApplicationsByQueue(ctx context.Context, queue string) (*dao.ApplicationsUsage, error) {
+	apps := &dao.ApplicationsUsage{}
+	if err := c.get(ctx, "/ws/v1/queues/"+url.PathEscape(queue)+"/apps", nil, apps); err != nil {
+		return nil, err
+	}
+	return apps, nil
+}
+
+// GetApplicationsByUser returns the apps submitted by the user
+func (c *Client) GetApplicationsByUser(ctx context.Context, user string) (*dao.ApplicationsUsage, error) {
+	apps := &dao.ApplicationsUsage{}
+	if err := c.get(ctx, "/ws/v1/users/"+url.PathEscape(user)+"/apps", nil, apps); err != nil {
+		return nil, err
+	}
+	return apps, nil
+}
+
+// ResumeApplication retries a failed app
+func (c *Client) ResumeApplication(ctx context.Context, appID string) error {
+	return c.do(ctx, http.MethodPost, "/ws/v1/apps/"+url.PathEscape(appID)+"/resume", nil, nil, nil)
+}
+
+// KillApplication kills an app and deletes its pods
+func (c *Client) KillApplication(ctx context.Context, appID string) error {
+	return c.do(ctx, http.MethodPost, "/ws/v1/apps/"+url.PathEscape(appID)+"/kill", nil, nil, nil)
+}
+
+// ReleaseTaskGroup cancels the reservation of one task group of an app,
+// the members of the task group are scheduled without placeholders
+func (c *Client) ReleaseTaskGroup(ctx context.Context, appID, taskGroup string) error {
+	return c.do(ctx, http.MethodPost,
+		"/ws/v1/apps/"+url.PathEscape(appID)+"/taskgroups/"+url.PathEscape(taskGroup)+"/release", nil, nil, nil)
+}
+
+// ReplacePlaceholder deletes a placeholder of a reserving app and creates it again,
+// the placeholder is kept off its current node when excludeNode is true
+func (c *Client) ReplacePlaceholder(ctx context.Context, appID, placeholder string, excludeNode bool) error {
+	var query url.Values
+	if excludeNode {
+		query = url.Values{"excludeNode": []string{"true"}}
+	}
+	return c.do(ctx, http.MethodPost,
+		"/ws/v1/apps/"+url.PathEscape(appID)+"/placeholders/"+url.PathEscape(placeholder)+"/replace", query, nil, nil)
+}
+
+// GetNodeViews returns the nodes known to the shim
+func (c *Client) GetNodeViews(ctx context.Context) ([]*dao.NodeView, error) {
+	nodes := make([]*dao.NodeView, 0)
+	if err := c.get(ctx, "/ws/v1/nodes", nil, &nodes); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// GetNodeView returns the node with the given name
+func (c *Client) GetNodeView(ctx context.Context, nodeName string) (*dao.NodeView, error) {
+	node := &dao.NodeView{}
+	if err := c.get(ctx, "/ws/v1/nodes/"+url.PathEscape(nodeName), nil, node); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// GetLiveness returns the result of the liveness probe, a failed probe is not an error:
+// the shim is alive if the scheduler state of the result is not Stopped
+func (c *Client) GetLiveness(ctx context.Context) (*dao.HealthCheckInfo, error) {
+	return c.getHealth(ctx, "/ws/v1/health/live")
+}
+
+// GetReadiness returns the result of the readiness probe, a failed probe is not an error:
+// the shim is ready if the result is healthy
+func (c *Client) GetReadiness(ctx context.Context) (*dao.HealthCheckInfo, error) {
+	return c.getHealth(ctx, "/ws/v1/health/ready")
+}
+
+// StreamSchedulingDecisions calls the handler with the scheduling decisions of the shim as they are made,
+// the decisions of all the apps are streamed when the appID is empty. The call blocks until the context
+// is done, nil is returned, or the stream fails.
+func (c *Client) StreamSchedulingDecisions(ctx context.Context, appID string, handler func(decision dao.SchedulingDecision)) error {
+	resp, err := c.send(ctx, http.MethodGet, "/ws/v1/stream/decisions", appQuery(appID), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return readError(resp)
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		// the events and the keep alive comments are skipped, the decision holds its type
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		decision := dao.SchedulingDecision{}
+		if err = json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &decision); err != nil {
+			return fmt.Errorf("failed to decode the scheduling decision: %v", err)
+		}
+		handler(decision)
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+	return io.ErrUnexpectedEOF
+}
+
+func (c *Client) getHealth(ctx context.Context, path string) (*dao.HealthCheckInfo, error) {
+	resp, err := c.send(ctx, http.MethodGet, path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+		return nil, readError(resp)
+	}
+	info := &dao.HealthCheckInfo{}
+	if err = json.NewDecoder(resp.Body).Decode(info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, query url.Values, result interface{}) error {
+	return c.do(ctx, http.MethodGet, path, query, nil, result)
+}
+
+// do sends the request and decodes the response into the result, the response is ignored without a result
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body []byte, result interface{}) error {
+	resp, err := c.send(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return readError(resp)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body []byte) (*http.Response, error) {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.httpClient.Do(req.WithContext(ctx))
+}
+
+// readError reads the message of an error response, the shim writes the error as plain text
+func readError(resp *http.Response) error {
+	message, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		message = []byte(resp.Status)
+	}
+	return &Error{
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(string(message)),
+	}
+}
+
+func appQuery(appID string) url.Values {
+	if appID == "" {
+		return nil
+	}
+	return url.Values{"appID": []string{appID}}
+}
@@ -0,0 +1,124 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/webservice/dao"
+)
+
+func TestNew(t *testing.T) {
+	assert.Equal(t, New("localhost:9089", nil).baseURL, "http://localhost:9089")
+	assert.Equal(t, New("https://yunikorn:9089/", nil).baseURL, "https://yunikorn:9089")
+	assert.Equal(t, New("localhost:9089", nil).httpClient, http.DefaultClient)
+}
+
+func TestClientRequests(t *testing.T) {
+	var method, uri string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, uri = r.Method, r.URL.RequestURI()
+		switch r.URL.Path {
+		case "/ws/v1/apps/app-1/kill":
+			w.WriteHeader(http.StatusOK)
+		case "/ws/v1/apps/app-2/kill":
+			http.Error(w, "application app-2 is not running", http.StatusBadRequest)
+		case "/ws/v1/apps/app-1/placeholders/ph-1/replace":
+			w.WriteHeader(http.StatusOK)
+		case "/ws/v1/apps/app-1/taskgroups/tg-1/release":
+			w.WriteHeader(http.StatusOK)
+		case "/ws/v1/queues/root.a/apps":
+			fmt.Fprint(w, `{"applications":[{"applicationID":"app-1"}]}`)
+		case "/debug/events":
+			fmt.Fprint(w, `[]`)
+		case "/ws/v1/tasks/quarantined":
+			fmt.Fprint(w, `[{"applicationID":"app-1","taskID":"task-1","conflicts":"label queue=root.b conflicts with queue root.a of application app-1"}]`)
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	c := New(server.URL, nil)
+	ctx := context.Background()
+
+	assert.NilError(t, c.KillApplication(ctx, "app-1"))
+	assert.Equal(t, method, http.MethodPost)
+	err := c.KillApplication(ctx, "app-2")
+	assert.Error(t, err, "request failed with status 400: application app-2 is not running")
+	assert.Assert(t, !IsNotFound(err))
+
+	assert.NilError(t, c.ReplacePlaceholder(ctx, "app-1", "ph-1", true))
+	assert.Equal(t, uri, "/ws/v1/apps/app-1/placeholders/ph-1/replace?excludeNode=true")
+	assert.NilError(t, c.ReplacePlaceholder(ctx, "app-1", "ph-1", false))
+	assert.Equal(t, uri, "/ws/v1/apps/app-1/placeholders/ph-1/replace")
+
+	assert.NilError(t, c.ReleaseTaskGroup(ctx, "app-1", "tg-1"))
+	assert.Equal(t, method, http.MethodPost)
+	assert.Equal(t, uri, "/ws/v1/apps/app-1/taskgroups/tg-1/release")
+
+	apps, err := c.GetApplicationsByQueue(ctx, "root.a")
+	assert.NilError(t, err)
+	assert.Equal(t, len(apps.Applications), 1)
+	assert.Equal(t, apps.Applications[0].ApplicationID, "app-1")
+
+	_, err = c.GetDispatchedEvents(ctx, "app-1")
+	assert.NilError(t, err)
+	assert.Equal(t, uri, "/debug/events?appID=app-1")
+
+	quarantined, err := c.GetQuarantinedTasks(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, len(quarantined), 1)
+	assert.Equal(t, quarantined[0].TaskID, "task-1")
+
+	_, err = c.GetNodeView(ctx, "node-1")
+	assert.Assert(t, IsNotFound(err))
+}
+
+func TestStreamSchedulingDecisions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.URL.Query().Get("appID"), "app-1")
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": keep-alive\n\n")
+		fmt.Fprintf(w, "event: %s\ndata: {\"type\":\"%s\",\"applicationID\":\"app-1\",\"toState\":\"Running\"}\n\n",
+			dao.DecisionAppStateChange, dao.DecisionAppStateChange)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	decisions := make([]dao.SchedulingDecision, 0)
+	err := New(server.URL, nil).StreamSchedulingDecisions(ctx, "app-1", func(decision dao.SchedulingDecision) {
+		decisions = append(decisions, decision)
+		// the stream ends once the context is done
+		cancel()
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(decisions), 1)
+	assert.Equal(t, decisions[0].ApplicationID, "app-1")
+	assert.Equal(t, decisions[0].ToState, "Running")
+}
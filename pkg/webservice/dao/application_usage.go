@@ -0,0 +1,42 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package dao
+
+// ApplicationsUsage is the applications of a queue or a user with the resources of their tasks,
+// the totals are aggregated over the listed applications.
+type ApplicationsUsage struct {
+	Applications []ApplicationUsage `json:"applications"`
+	Running      map[string]int64   `json:"running"`
+	RunningTasks int                `json:"runningTasks"`
+	Pending      map[string]int64   `json:"pending"`
+	PendingTasks int                `json:"pendingTasks"`
+}
+
+// ApplicationUsage is the resources allocated to the bound tasks of an application
+// and the resources asked by its tasks that are not allocated yet.
+type ApplicationUsage struct {
+	ApplicationID string           `json:"applicationID"`
+	Queue         string           `json:"queue"`
+	User          string           `json:"user"`
+	State         string           `json:"state"`
+	Running       map[string]int64 `json:"running"`
+	RunningTasks  int              `json:"runningTasks"`
+	Pending       map[string]int64 `json:"pending"`
+	PendingTasks  int              `json:"pendingTasks"`
+}
@@ -0,0 +1,44 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package dao
+
+import "time"
+
+// types of the scheduling decisions streamed by the shim
+const (
+	DecisionTaskScheduled       = "TaskScheduled"
+	DecisionPlaceholderReplaced = "PlaceholderReplaced"
+	DecisionAppStateChange      = "ApplicationStateChange"
+)
+
+// SchedulingDecision is a scheduling decision taken by the shim, the fields that do not apply to the type are empty.
+type SchedulingDecision struct {
+	Type          string    `json:"type"`
+	Timestamp     time.Time `json:"timestamp"`
+	ApplicationID string    `json:"applicationID"`
+	Queue         string    `json:"queue,omitempty"`
+	TaskID        string    `json:"taskID,omitempty"`
+	Pod           string    `json:"pod,omitempty"`
+	NodeID        string    `json:"nodeID,omitempty"`
+	Placeholder   bool      `json:"placeholder,omitempty"`
+	TaskGroup     string    `json:"taskGroup,omitempty"`
+	ReplacedBy    string    `json:"replacedBy,omitempty"`
+	FromState     string    `json:"fromState,omitempty"`
+	ToState       string    `json:"toState,omitempty"`
+}
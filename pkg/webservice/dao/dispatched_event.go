@@ -0,0 +1,34 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package dao
+
+import "time"
+
+// DispatchedEvent is an event handled by the dispatcher of the shim, the last dispatched events are kept
+// in memory in the order they were handled. The fields that do not apply to the event type are empty.
+type DispatchedEvent struct {
+	Type          string    `json:"type"`
+	Event         string    `json:"event"`
+	ApplicationID string    `json:"applicationID,omitempty"`
+	TaskID        string    `json:"taskID,omitempty"`
+	NodeID        string    `json:"nodeID,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+	Duration      string    `json:"duration"`
+	Error         string    `json:"error,omitempty"`
+}
@@ -0,0 +1,53 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package dao
+
+import "time"
+
+// HealthCheckInfo is the bootstrap health of the shim, the shim is ready when all the checks succeeded.
+type HealthCheckInfo struct {
+	Healthy        bool              `json:"healthy"`
+	SchedulerState string            `json:"schedulerState"`
+	HealthChecks   []HealthCheck     `json:"healthChecks"`
+	Recovery       *RecoveryProgress `json:"recovery,omitempty"`
+}
+
+type HealthCheck struct {
+	Name        string `json:"name"`
+	Succeeded   bool   `json:"succeeded"`
+	Description string `json:"description"`
+}
+
+// RecoveryProgress is the progress of the recovery after a restart, the phases are listed in the order they run.
+// The error is set when the recovery failed, e.g. the deadline passed.
+type RecoveryProgress struct {
+	Phase     string                  `json:"phase"`
+	StartTime time.Time               `json:"startTime"`
+	Deadline  time.Time               `json:"deadline"`
+	Elapsed   string                  `json:"elapsed"`
+	Phases    []RecoveryPhaseProgress `json:"phases"`
+	Error     string                  `json:"error,omitempty"`
+}
+
+type RecoveryPhaseProgress struct {
+	Phase     string `json:"phase"`
+	Total     int    `json:"total"`
+	Recovered int    `json:"recovered"`
+	Failed    int    `json:"failed"`
+}
@@ -0,0 +1,52 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package dao
+
+// NodeView is what the shim believes is running on a node: the allocations of the real tasks and
+// the placeholders, and the pods of other schedulers that occupy the node. It is used to debug
+// the cases where the core and the node itself do not agree on the free resources.
+type NodeView struct {
+	NodeName     string           `json:"nodeName"`
+	State        string           `json:"state"`
+	Schedulable  bool             `json:"schedulable"`
+	Capacity     map[string]int64 `json:"capacity"`
+	Occupied     map[string]int64 `json:"occupied"`
+	Allocated    map[string]int64 `json:"allocated"`
+	Tasks        []NodeTaskInfo   `json:"tasks"`
+	Placeholders []NodeTaskInfo   `json:"placeholders"`
+	ForeignPods  []ForeignPodInfo `json:"foreignPods"`
+}
+
+type NodeTaskInfo struct {
+	ApplicationID  string           `json:"applicationID"`
+	TaskID         string           `json:"taskID"`
+	TaskAlias      string           `json:"taskAlias"`
+	State          string           `json:"state"`
+	AllocationUUID string           `json:"allocationUUID"`
+	TaskGroupName  string           `json:"taskGroupName,omitempty"`
+	Resource       map[string]int64 `json:"resource"`
+}
+
+type ForeignPodInfo struct {
+	Name          string           `json:"name"`
+	Namespace     string           `json:"namespace"`
+	SchedulerName string           `json:"schedulerName"`
+	Phase         string           `json:"phase"`
+	Resource      map[string]int64 `json:"resource"`
+}
@@ -0,0 +1,42 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package dao
+
+// ApplicationPendingResource is the resources an application is still waiting for, aggregated from
+// the asks of its tasks that are not allocated yet. The total is broken down per task state and per task group.
+type ApplicationPendingResource struct {
+	ApplicationID string                     `json:"applicationID"`
+	Queue         string                     `json:"queue"`
+	Pending       map[string]int64           `json:"pending"`
+	Tasks         int                        `json:"tasks"`
+	States        []StatePendingResource     `json:"states"`
+	TaskGroups    []TaskGroupPendingResource `json:"taskGroups,omitempty"`
+}
+
+type StatePendingResource struct {
+	State   string           `json:"state"`
+	Pending map[string]int64 `json:"pending"`
+	Tasks   int              `json:"tasks"`
+}
+
+type TaskGroupPendingResource struct {
+	TaskGroup string           `json:"taskGroup"`
+	Pending   map[string]int64 `json:"pending"`
+	Tasks     int              `json:"tasks"`
+}
@@ -0,0 +1,32 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package dao
+
+import "time"
+
+// QuarantinedTask is a task held by the shim because the scheduling directives of its pod conflict,
+// e.g. the app ID annotation and label name different apps. The task is never scheduled.
+type QuarantinedTask struct {
+	ApplicationID string    `json:"applicationID"`
+	TaskID        string    `json:"taskID"`
+	TaskAlias     string    `json:"taskAlias"`
+	TaskGroupName string    `json:"taskGroupName,omitempty"`
+	Conflicts     string    `json:"conflicts"`
+	CreateTime    time.Time `json:"createTime"`
+}
@@ -0,0 +1,38 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package dao
+
+// ResourceUsage is the resources allocated to the bound tasks from the shim point of view,
+// aggregated per queue and per user. It is compared against the core accounting to detect drift.
+type ResourceUsage struct {
+	Queues []QueueResourceUsage `json:"queues"`
+	Users  []UserResourceUsage  `json:"users"`
+}
+
+type QueueResourceUsage struct {
+	QueueName string           `json:"queueName"`
+	Allocated map[string]int64 `json:"allocated"`
+	Tasks     int              `json:"tasks"`
+}
+
+type UserResourceUsage struct {
+	User      string           `json:"user"`
+	Allocated map[string]int64 `json:"allocated"`
+	Tasks     int              `json:"tasks"`
+}
@@ -0,0 +1,28 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package dao
+
+// SILogging are the settings of the wire logger of the scheduler interface, the requests sent to the core
+// and the responses received from it are logged when enabled. Only a sample of the messages is logged with
+// a sample rate below 1, the user names, groups and tags are redacted with redact set.
+type SILogging struct {
+	Enabled    bool    `json:"enabled"`
+	SampleRate float64 `json:"sampleRate"`
+	Redact     bool    `json:"redact"`
+}
@@ -0,0 +1,101 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package dao
+
+import (
+	"time"
+)
+
+// StateDump is a snapshot of the entire shim cache,
+// it is serialized into JSON and attached to bug reports.
+type StateDump struct {
+	Timestamp    time.Time         `json:"timestamp"`
+	Applications []ApplicationInfo `json:"applications"`
+	Nodes        []NodeInfo        `json:"nodes"`
+	Placeholders PlaceholderInfo   `json:"placeholders"`
+	Dispatcher   DispatcherInfo    `json:"dispatcher"`
+	Config       interface{}       `json:"config"`
+	Predicates   []string          `json:"predicates"`
+}
+
+type ApplicationInfo struct {
+	ApplicationID          string              `json:"applicationID"`
+	QueueName              string              `json:"queueName"`
+	Partition              string              `json:"partition"`
+	User                   string              `json:"user"`
+	Groups                 []string            `json:"groups,omitempty"`
+	State                  string              `json:"state"`
+	Tags                   map[string]string   `json:"tags,omitempty"`
+	TaskGroups             []TaskGroupInfo     `json:"taskGroups,omitempty"`
+	PlaceholderAsk         map[string]int64    `json:"placeholderAsk,omitempty"`
+	PlaceholderTimeoutSecs int64               `json:"placeholderTimeoutInSec"`
+	Tasks                  []TaskInfo          `json:"tasks"`
+	QueueAsks              map[string][]string `json:"queueAsks,omitempty"`
+}
+
+type TaskGroupInfo struct {
+	Name        string                   `json:"name"`
+	MinMember   int32                    `json:"minMember"`
+	MinResource map[string]int64         `json:"minResource"`
+	Progress    *PlaceholderProgressInfo `json:"placeholderProgress,omitempty"`
+}
+
+// PlaceholderProgressInfo is the placeholder creation progress of a task group,
+// it is only set once the app started to create its placeholders.
+type PlaceholderProgressInfo struct {
+	Desired int32 `json:"desired"`
+	Created int32 `json:"created"`
+	Failed  int32 `json:"failed"`
+}
+
+type TaskInfo struct {
+	TaskID         string           `json:"taskID"`
+	TaskAlias      string           `json:"taskAlias"`
+	State          string           `json:"state"`
+	Resource       map[string]int64 `json:"resource"`
+	AllocationUUID string           `json:"allocationUUID,omitempty"`
+	NodeName       string           `json:"nodeName,omitempty"`
+	Placeholder    bool             `json:"placeholder"`
+	TaskGroupName  string           `json:"taskGroupName,omitempty"`
+	TaskGroupIndex int32            `json:"taskGroupIndex"`
+	QueueName      string           `json:"queueName,omitempty"`
+	Quarantined    string           `json:"quarantined,omitempty"`
+	CreateTime     time.Time        `json:"createTime"`
+}
+
+type NodeInfo struct {
+	NodeName    string           `json:"nodeName"`
+	NodeUID     string           `json:"nodeUID"`
+	State       string           `json:"state"`
+	Schedulable bool             `json:"schedulable"`
+	Capacity    map[string]int64 `json:"capacity"`
+	Occupied    map[string]int64 `json:"occupied"`
+}
+
+// PlaceholderInfo is the placeholder inventory,
+// the orphan pods are placeholders that failed to be deleted and are being retried.
+type PlaceholderInfo struct {
+	Total      int      `json:"total"`
+	OrphanPods []string `json:"orphanPods"`
+}
+
+type DispatcherInfo struct {
+	EventQueueLength   int   `json:"eventQueueLength"`
+	AsyncDispatchCount int32 `json:"asyncDispatchCount"`
+}
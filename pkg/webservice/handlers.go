@@ -0,0 +1,341 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package webservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/dispatcher"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/log"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/webservice/dao"
+)
+
+func writeHeaders(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Credentials", "true")
+	w.Header().Set("Access-Control-Allow-Methods", "GET,POST,HEAD,OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "X-Requested-With,Content-Type,Accept,Origin")
+}
+
+func getFullStateDump(w http.ResponseWriter, r *http.Request) {
+	writeHeaders(w)
+	if err := json.NewEncoder(w).Encode(schedulerContext.GetStateDump()); err != nil {
+		log.Logger().Error("failed to encode the state dump", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func getSILogging(w http.ResponseWriter, r *http.Request) {
+	writeHeaders(w)
+	if err := json.NewEncoder(w).Encode(client.GetSILogging()); err != nil {
+		log.Logger().Error("failed to encode the scheduler interface logging", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// updateSILogging changes the logging of the scheduler interface messages at runtime,
+// the fields missing in the request keep their current value
+func updateSILogging(w http.ResponseWriter, r *http.Request) {
+	writeHeaders(w)
+	settings := client.GetSILogging()
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := client.SetSILogging(settings); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(settings); err != nil {
+		log.Logger().Error("failed to encode the scheduler interface logging", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// getDispatchedEvents returns the last events handled by the dispatcher in the order they were handled,
+// the events can be limited to a single app with the appID query parameter.
+func getDispatchedEvents(w http.ResponseWriter, r *http.Request) {
+	writeHeaders(w)
+	dispatched := dispatcher.GetDispatchedEvents(r.URL.Query().Get("appID"))
+	if err := json.NewEncoder(w).Encode(dispatched); err != nil {
+		log.Logger().Error("failed to encode the dispatched events", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func getResourceUsage(w http.ResponseWriter, r *http.Request) {
+	writeHeaders(w)
+	if err := json.NewEncoder(w).Encode(schedulerContext.GetResourceUsage()); err != nil {
+		log.Logger().Error("failed to encode the resource usage", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func getPendingResources(w http.ResponseWriter, r *http.Request) {
+	writeHeaders(w)
+	if err := json.NewEncoder(w).Encode(schedulerContext.GetPendingResources()); err != nil {
+		log.Logger().Error("failed to encode the pending resources", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func getQuarantinedTasks(w http.ResponseWriter, r *http.Request) {
+	writeHeaders(w)
+	if err := json.NewEncoder(w).Encode(schedulerContext.GetQuarantinedTasks()); err != nil {
+		log.Logger().Error("failed to encode the quarantined tasks", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func getApplicationPendingResource(w http.ResponseWriter, r *http.Request) {
+	writeHeaders(w)
+	appID := mux.Vars(r)["appID"]
+	pending, err := schedulerContext.GetApplicationPendingResource(appID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err = json.NewEncoder(w).Encode(pending); err != nil {
+		log.Logger().Error("failed to encode the pending resource", zap.String("appID", appID), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func getApplicationsByQueue(w http.ResponseWriter, r *http.Request) {
+	writeHeaders(w)
+	queue := mux.Vars(r)["queue"]
+	if err := json.NewEncoder(w).Encode(schedulerContext.GetApplicationsByQueue(queue)); err != nil {
+		log.Logger().Error("failed to encode the applications of the queue", zap.String("queue", queue), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func getApplicationsByUser(w http.ResponseWriter, r *http.Request) {
+	writeHeaders(w)
+	user := mux.Vars(r)["user"]
+	if err := json.NewEncoder(w).Encode(schedulerContext.GetApplicationsByUser(user)); err != nil {
+		log.Logger().Error("failed to encode the applications of the user", zap.String("user", user), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func getNodeViews(w http.ResponseWriter, r *http.Request) {
+	writeHeaders(w)
+	if err := json.NewEncoder(w).Encode(schedulerContext.GetNodeViews()); err != nil {
+		log.Logger().Error("failed to encode the nodes", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func getNodeView(w http.ResponseWriter, r *http.Request) {
+	writeHeaders(w)
+	nodeName := mux.Vars(r)["nodeName"]
+	view, err := schedulerContext.GetNodeView(nodeName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err = json.NewEncoder(w).Encode(view); err != nil {
+		log.Logger().Error("failed to encode the node", zap.String("nodeName", nodeName), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// resumeApplication retries a failed application
+func resumeApplication(w http.ResponseWriter, r *http.Request) {
+	writeHeaders(w)
+	appID := mux.Vars(r)["appID"]
+	if err := schedulerContext.ResumeApplication(appID); err != nil {
+		log.Logger().Info("failed to resume application", zap.String("appID", appID), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// killApplication kills a running application and deletes its pods
+func killApplication(w http.ResponseWriter, r *http.Request) {
+	writeHeaders(w)
+	appID := mux.Vars(r)["appID"]
+	if err := schedulerContext.KillApplication(appID); err != nil {
+		log.Logger().Info("failed to kill application", zap.String("appID", appID), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// replacePlaceholder deletes a placeholder of a reserving application and creates it again,
+// the placeholder is kept off its current node with the excludeNode=true query parameter
+func replacePlaceholder(w http.ResponseWriter, r *http.Request) {
+	writeHeaders(w)
+	vars := mux.Vars(r)
+	appID, placeholder := vars["appID"], vars["placeholder"]
+	excludeNode := r.URL.Query().Get("excludeNode") == "true"
+	if err := schedulerContext.ReplacePlaceholder(appID, placeholder, excludeNode); err != nil {
+		log.Logger().Info("failed to replace placeholder",
+			zap.String("appID", appID),
+			zap.String("placeholder", placeholder),
+			zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// releaseTaskGroup cancels the reservation of one task group of an application,
+// the members of the task group are scheduled without placeholders
+func releaseTaskGroup(w http.ResponseWriter, r *http.Request) {
+	writeHeaders(w)
+	vars := mux.Vars(r)
+	appID, taskGroup := vars["appID"], vars["taskGroup"]
+	if err := schedulerContext.ReleaseTaskGroup(appID, taskGroup); err != nil {
+		log.Logger().Info("failed to release task group",
+			zap.String("appID", appID),
+			zap.String("taskGroup", taskGroup),
+			zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// buffered decisions per stream client, decisions are dropped for a client that falls behind
+const decisionStreamBuffer = 1024
+
+// keep alive comments stop proxies from closing an idle stream
+var decisionStreamKeepAlive = 30 * time.Second
+
+// streamSchedulingDecisions streams the scheduling decisions of the shim as server-sent events,
+// the stream can be limited to a single app with the appID query parameter.
+func streamSchedulingDecisions(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	appID := r.URL.Query().Get("appID")
+	decisions, unsubscribe := schedulerContext.SubscribeSchedulingDecisions(decisionStreamBuffer)
+	defer unsubscribe()
+
+	writeHeaders(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(decisionStreamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case decision := <-decisions:
+			if appID != "" && decision.ApplicationID != appID {
+				continue
+			}
+			data, err := json.Marshal(decision)
+			if err != nil {
+				log.Logger().Error("failed to encode the scheduling decision", zap.Error(err))
+				continue
+			}
+			if _, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", decision.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// getLiveness fails only when the shim is stopped, a stopped shim never schedules again
+// and must be restarted. A shim that is still registering or recovering is alive.
+func getLiveness(w http.ResponseWriter, r *http.Request) {
+	info := checkHealth()
+	writeHealthCheckInfo(w, info, info.SchedulerState != events.States().Scheduler.Stopped)
+}
+
+// getReadiness succeeds when the shim is registered with the core, the recovery
+// is completed and the informer caches are synced. The progress of the recovery is
+// reported per phase once the recovery started.
+func getReadiness(w http.ResponseWriter, r *http.Request) {
+	info := checkHealth()
+	writeHealthCheckInfo(w, info, info.Healthy)
+}
+
+func checkHealth() *dao.HealthCheckInfo {
+	states := events.States().Scheduler
+	state := states.New
+	if healthChecker != nil {
+		state = healthChecker.GetSchedulerState()
+	}
+	registered := state == states.Registered || state == states.Recovering ||
+		state == states.Running || state == states.Draining
+	recovered := state == states.Running || state == states.Draining
+	info := &dao.HealthCheckInfo{
+		SchedulerState: state,
+		HealthChecks: []dao.HealthCheck{
+			{
+				Name:        "Registration",
+				Succeeded:   registered,
+				Description: "the shim is registered with the scheduler core",
+			},
+			{
+				Name:        "Recovery",
+				Succeeded:   recovered,
+				Description: "the nodes and the applications are recovered",
+			},
+			{
+				Name:        "InformerSync",
+				Succeeded:   schedulerContext.HasInformersSynced(),
+				Description: "the informer caches are synced",
+			},
+		},
+		Recovery: schedulerContext.GetRecoveryProgress(),
+	}
+	info.Healthy = true
+	for _, check := range info.HealthChecks {
+		info.Healthy = info.Healthy && check.Succeeded
+	}
+	return info
+}
+
+func writeHealthCheckInfo(w http.ResponseWriter, info *dao.HealthCheckInfo, succeeded bool) {
+	writeHeaders(w)
+	if !succeeded {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(info); err != nil {
+		log.Logger().Error("failed to encode the health check info", zap.Error(err))
+	}
+}
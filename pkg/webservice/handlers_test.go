@@ -0,0 +1,591 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package webservice
+
+import (
+	"bufio"
+	gocontext "context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/apis/yunikorn.apache.org/v1alpha1"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/appmgmt/interfaces"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/cache"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/client"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/conf"
+	wsclient "github.com/apache/incubator-yunikorn-k8shim/pkg/webservice/client"
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/webservice/dao"
+)
+
+func TestGetFullStateDump(t *testing.T) {
+	conf.GetSchedulerConf().SetTestMode(true)
+	context := cache.NewContext(client.NewMockedAPIProvider())
+	context.AddApplication(&interfaces.AddApplicationRequest{
+		Metadata: interfaces.ApplicationMetadata{
+			ApplicationID: "app00001",
+			QueueName:     "root.a",
+			User:          "test-user",
+			Tags:          map[string]string{"namespace": "default"},
+		},
+	})
+	NewWebApp(context, nil, conf.DefaultWebServicePort)
+
+	req, err := http.NewRequest("GET", "/debug/fullstatedump", nil)
+	assert.NilError(t, err)
+	resp := httptest.NewRecorder()
+	getFullStateDump(resp, req)
+	assert.Equal(t, resp.Code, http.StatusOK)
+
+	var dump dao.StateDump
+	err = json.Unmarshal(resp.Body.Bytes(), &dump)
+	assert.NilError(t, err, "failed to unmarshal the state dump")
+	assert.Equal(t, len(dump.Applications), 1)
+	assert.Equal(t, dump.Applications[0].ApplicationID, "app00001")
+	assert.Equal(t, dump.Applications[0].QueueName, "root.a")
+	assert.Equal(t, dump.Applications[0].State, "New")
+	assert.Equal(t, len(dump.Nodes), 0)
+	assert.Assert(t, dump.Config != nil)
+}
+
+func TestGetResourceUsage(t *testing.T) {
+	conf.GetSchedulerConf().SetTestMode(true)
+	NewWebApp(cache.NewContext(client.NewMockedAPIProvider()), nil, conf.DefaultWebServicePort)
+
+	req, err := http.NewRequest("GET", "/ws/v1/resourceusage", nil)
+	assert.NilError(t, err)
+	resp := httptest.NewRecorder()
+	getResourceUsage(resp, req)
+	assert.Equal(t, resp.Code, http.StatusOK)
+
+	var usage dao.ResourceUsage
+	err = json.Unmarshal(resp.Body.Bytes(), &usage)
+	assert.NilError(t, err, "failed to unmarshal the resource usage")
+	assert.Equal(t, len(usage.Queues), 0)
+	assert.Equal(t, len(usage.Users), 0)
+}
+
+type fakeHealthChecker struct {
+	state string
+}
+
+func (f *fakeHealthChecker) GetSchedulerState() string {
+	return f.state
+}
+
+func TestHealthProbes(t *testing.T) {
+	conf.GetSchedulerConf().SetTestMode(true)
+	checker := &fakeHealthChecker{state: events.States().Scheduler.Recovering}
+	NewWebApp(cache.NewContext(client.NewMockedAPIProvider()), checker, conf.DefaultWebServicePort)
+
+	probe := func(handler http.HandlerFunc) (int, dao.HealthCheckInfo) {
+		req, err := http.NewRequest("GET", "/ws/v1/health", nil)
+		assert.NilError(t, err)
+		resp := httptest.NewRecorder()
+		handler(resp, req)
+		var info dao.HealthCheckInfo
+		err = json.Unmarshal(resp.Body.Bytes(), &info)
+		assert.NilError(t, err, "failed to unmarshal the health check info")
+		return resp.Code, info
+	}
+
+	// recovering: alive but not ready
+	code, _ := probe(getLiveness)
+	assert.Equal(t, code, http.StatusOK)
+	code, info := probe(getReadiness)
+	assert.Equal(t, code, http.StatusServiceUnavailable)
+	assert.Equal(t, info.Healthy, false)
+	assert.Equal(t, info.SchedulerState, events.States().Scheduler.Recovering)
+	assert.Equal(t, len(info.HealthChecks), 3)
+	assert.Equal(t, info.HealthChecks[0].Succeeded, true)
+	assert.Equal(t, info.HealthChecks[1].Succeeded, false)
+	assert.Equal(t, info.HealthChecks[2].Succeeded, true)
+
+	// the progress of the recovery is reported once it started
+	schedulerContext.StartRecovery(time.Now().Add(time.Minute))
+	_, info = probe(getReadiness)
+	assert.Assert(t, info.Recovery != nil)
+	assert.Equal(t, info.Recovery.Phase, cache.RecoveryPhaseApplications)
+	assert.Equal(t, len(info.Recovery.Phases), 3)
+	schedulerContext.FinishRecovery(nil)
+	_, info = probe(getReadiness)
+	assert.Equal(t, info.Recovery.Phase, cache.RecoveryPhaseCompleted)
+	assert.Equal(t, info.Recovery.Error, "")
+
+	// running: alive and ready
+	checker.state = events.States().Scheduler.Running
+	code, _ = probe(getLiveness)
+	assert.Equal(t, code, http.StatusOK)
+	code, info = probe(getReadiness)
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, info.Healthy, true)
+
+	// stopped: neither alive nor ready
+	checker.state = events.States().Scheduler.Stopped
+	code, _ = probe(getLiveness)
+	assert.Equal(t, code, http.StatusServiceUnavailable)
+	code, _ = probe(getReadiness)
+	assert.Equal(t, code, http.StatusServiceUnavailable)
+}
+
+func TestResumeApplication(t *testing.T) {
+	conf.GetSchedulerConf().SetTestMode(true)
+	context := cache.NewContext(client.NewMockedAPIProvider())
+	context.AddApplication(&interfaces.AddApplicationRequest{
+		Metadata: interfaces.ApplicationMetadata{
+			ApplicationID: "app00001",
+			QueueName:     "root.a",
+			User:          "test-user",
+			Tags:          map[string]string{"namespace": "default"},
+		},
+	})
+	NewWebApp(context, nil, conf.DefaultWebServicePort)
+
+	// the path variable is only resolved by the router
+	router := newRouter()
+	req, err := http.NewRequest("POST", "/ws/v1/apps/app00002/resume", nil)
+	assert.NilError(t, err)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, resp.Code, http.StatusBadRequest)
+	assert.Assert(t, strings.Contains(resp.Body.String(), "application app00002 is not found"))
+
+	// the app is not failed
+	req, err = http.NewRequest("POST", "/ws/v1/apps/app00001/resume", nil)
+	assert.NilError(t, err)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, resp.Code, http.StatusBadRequest)
+	assert.Assert(t, strings.Contains(resp.Body.String(), "cannot be resumed in state New"))
+}
+
+func TestKillApplication(t *testing.T) {
+	conf.GetSchedulerConf().SetTestMode(true)
+	context := cache.NewContext(client.NewMockedAPIProvider())
+	context.AddApplication(&interfaces.AddApplicationRequest{
+		Metadata: interfaces.ApplicationMetadata{
+			ApplicationID: "app00001",
+			QueueName:     "root.a",
+			User:          "test-user",
+			Tags:          map[string]string{"namespace": "default"},
+		},
+	})
+	NewWebApp(context, nil, conf.DefaultWebServicePort)
+
+	router := newRouter()
+	req, err := http.NewRequest("POST", "/ws/v1/apps/app00002/kill", nil)
+	assert.NilError(t, err)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, resp.Code, http.StatusBadRequest)
+	assert.Assert(t, strings.Contains(resp.Body.String(), "application app00002 is not found"))
+
+	// the app is not accepted by the core yet
+	req, err = http.NewRequest("POST", "/ws/v1/apps/app00001/kill", nil)
+	assert.NilError(t, err)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, resp.Code, http.StatusBadRequest)
+	assert.Assert(t, strings.Contains(resp.Body.String(), "cannot be killed in state New"))
+}
+
+func TestReplacePlaceholder(t *testing.T) {
+	conf.GetSchedulerConf().SetTestMode(true)
+	context := cache.NewContext(client.NewMockedAPIProvider())
+	context.AddApplication(&interfaces.AddApplicationRequest{
+		Metadata: interfaces.ApplicationMetadata{
+			ApplicationID: "app00001",
+			QueueName:     "root.a",
+			User:          "test-user",
+			Tags:          map[string]string{"namespace": "default"},
+		},
+	})
+	NewWebApp(context, nil, conf.DefaultWebServicePort)
+
+	router := newRouter()
+	req, err := http.NewRequest("POST", "/ws/v1/apps/app00002/placeholders/tg-app00002-0/replace", nil)
+	assert.NilError(t, err)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, resp.Code, http.StatusBadRequest)
+	assert.Assert(t, strings.Contains(resp.Body.String(), "application app00002 is not found"))
+
+	// the app is not reserving
+	req, err = http.NewRequest("POST", "/ws/v1/apps/app00001/placeholders/tg-app00001-0/replace?excludeNode=true", nil)
+	assert.NilError(t, err)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, resp.Code, http.StatusBadRequest)
+	assert.Assert(t, strings.Contains(resp.Body.String(), "can only be replaced while reserving"))
+}
+
+func TestReleaseTaskGroup(t *testing.T) {
+	conf.GetSchedulerConf().SetTestMode(true)
+	context := cache.NewContext(client.NewMockedAPIProvider())
+	context.AddApplication(&interfaces.AddApplicationRequest{
+		Metadata: interfaces.ApplicationMetadata{
+			ApplicationID: "app00001",
+			QueueName:     "root.a",
+			User:          "test-user",
+			Tags:          map[string]string{"namespace": "default"},
+			TaskGroups:    []v1alpha1.TaskGroup{{Name: "tg-1", MinMember: 1}},
+		},
+	})
+	NewWebApp(context, nil, conf.DefaultWebServicePort)
+
+	router := newRouter()
+	req, err := http.NewRequest("POST", "/ws/v1/apps/app00002/taskgroups/tg-1/release", nil)
+	assert.NilError(t, err)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, resp.Code, http.StatusBadRequest)
+	assert.Assert(t, strings.Contains(resp.Body.String(), "application app00002 is not found"))
+
+	// the app is not accepted by the core yet
+	req, err = http.NewRequest("POST", "/ws/v1/apps/app00001/taskgroups/tg-1/release", nil)
+	assert.NilError(t, err)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, resp.Code, http.StatusBadRequest)
+	assert.Assert(t, strings.Contains(resp.Body.String(), "cannot be released in state New"))
+
+	app, ok := context.GetApplication("app00001").(*cache.Application)
+	assert.Assert(t, ok)
+	app.SetState(events.States().Application.Reserving)
+	req, err = http.NewRequest("POST", "/ws/v1/apps/app00001/taskgroups/tg-2/release", nil)
+	assert.NilError(t, err)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, resp.Code, http.StatusBadRequest)
+	assert.Assert(t, strings.Contains(resp.Body.String(), "task group tg-2 of application app00001 is not found"))
+
+	req, err = http.NewRequest("POST", "/ws/v1/apps/app00001/taskgroups/tg-1/release", nil)
+	assert.NilError(t, err)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, resp.Code, http.StatusOK)
+}
+
+func TestGetPendingResources(t *testing.T) {
+	conf.GetSchedulerConf().SetTestMode(true)
+	context := cache.NewContext(client.NewMockedAPIProvider())
+	context.AddApplication(&interfaces.AddApplicationRequest{
+		Metadata: interfaces.ApplicationMetadata{
+			ApplicationID: "app00001",
+			QueueName:     "root.a",
+			User:          "test-user",
+			Tags:          map[string]string{"namespace": "default"},
+		},
+	})
+	NewWebApp(context, nil, conf.DefaultWebServicePort)
+
+	router := newRouter()
+	req, err := http.NewRequest("GET", "/ws/v1/apps/app00002/pendingresource", nil)
+	assert.NilError(t, err)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, resp.Code, http.StatusNotFound)
+
+	req, err = http.NewRequest("GET", "/ws/v1/apps/app00001/pendingresource", nil)
+	assert.NilError(t, err)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, resp.Code, http.StatusOK)
+	var pending dao.ApplicationPendingResource
+	err = json.Unmarshal(resp.Body.Bytes(), &pending)
+	assert.NilError(t, err, "failed to unmarshal the pending resource")
+	assert.Equal(t, pending.ApplicationID, "app00001")
+	assert.Equal(t, pending.Queue, "root.a")
+	assert.Equal(t, pending.Tasks, 0)
+
+	// apps without pending tasks are not listed
+	req, err = http.NewRequest("GET", "/ws/v1/pendingresources", nil)
+	assert.NilError(t, err)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, resp.Code, http.StatusOK)
+	var all []dao.ApplicationPendingResource
+	err = json.Unmarshal(resp.Body.Bytes(), &all)
+	assert.NilError(t, err, "failed to unmarshal the pending resources")
+	assert.Equal(t, len(all), 0)
+}
+
+func TestGetQuarantinedTasks(t *testing.T) {
+	conf.GetSchedulerConf().SetTestMode(true)
+	context := cache.NewContext(client.NewMockedAPIProvider())
+	context.AddApplication(&interfaces.AddApplicationRequest{
+		Metadata: interfaces.ApplicationMetadata{
+			ApplicationID: "app00001",
+			QueueName:     "root.a",
+			User:          "test-user",
+			Tags:          map[string]string{"namespace": "default"},
+		},
+	})
+	context.AddTask(&interfaces.AddTaskRequest{
+		Metadata: interfaces.TaskMetadata{
+			ApplicationID: "app00001",
+			TaskID:        "task00001",
+			Pod: &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod00001",
+					Namespace: "default",
+					UID:       "task00001",
+					Labels: map[string]string{
+						constants.LabelApplicationID: "app00001",
+						constants.LabelQueueName:     "root.b",
+					},
+				},
+			},
+		},
+	})
+	NewWebApp(context, nil, conf.DefaultWebServicePort)
+
+	router := newRouter()
+	req, err := http.NewRequest("GET", "/ws/v1/tasks/quarantined", nil)
+	assert.NilError(t, err)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, resp.Code, http.StatusOK)
+	var tasks []dao.QuarantinedTask
+	err = json.Unmarshal(resp.Body.Bytes(), &tasks)
+	assert.NilError(t, err, "failed to unmarshal the quarantined tasks")
+	assert.Equal(t, len(tasks), 1)
+	assert.Equal(t, tasks[0].ApplicationID, "app00001")
+	assert.Equal(t, tasks[0].TaskID, "task00001")
+	assert.Equal(t, tasks[0].TaskAlias, "default/pod00001")
+	assert.Equal(t, tasks[0].Conflicts, "label queue=root.b conflicts with queue root.a of application app00001")
+}
+
+func TestGetApplicationsByQueueAndUser(t *testing.T) {
+	conf.GetSchedulerConf().SetTestMode(true)
+	context := cache.NewContext(client.NewMockedAPIProvider())
+	context.AddApplication(&interfaces.AddApplicationRequest{
+		Metadata: interfaces.ApplicationMetadata{
+			ApplicationID: "app00001",
+			QueueName:     "root.a",
+			User:          "test-user",
+			Tags:          map[string]string{"namespace": "default"},
+		},
+	})
+	NewWebApp(context, nil, conf.DefaultWebServicePort)
+
+	router := newRouter()
+	list := func(url string) dao.ApplicationsUsage {
+		req, err := http.NewRequest("GET", url, nil)
+		assert.NilError(t, err)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, resp.Code, http.StatusOK)
+		var apps dao.ApplicationsUsage
+		err = json.Unmarshal(resp.Body.Bytes(), &apps)
+		assert.NilError(t, err, "failed to unmarshal the applications")
+		return apps
+	}
+	apps := list("/ws/v1/queues/root/apps")
+	assert.Equal(t, len(apps.Applications), 1)
+	assert.Equal(t, apps.Applications[0].ApplicationID, "app00001")
+	assert.Equal(t, apps.Applications[0].Queue, "root.a")
+	assert.Equal(t, len(list("/ws/v1/queues/root.b/apps").Applications), 0)
+	apps = list("/ws/v1/users/test-user/apps")
+	assert.Equal(t, len(apps.Applications), 1)
+	assert.Equal(t, apps.Applications[0].User, "test-user")
+	assert.Equal(t, len(list("/ws/v1/users/other-user/apps").Applications), 0)
+}
+
+func TestGetNodeViews(t *testing.T) {
+	conf.GetSchedulerConf().SetTestMode(true)
+	context := cache.NewContext(client.NewMockedAPIProvider())
+	context.AddApplication(&interfaces.AddApplicationRequest{
+		Metadata: interfaces.ApplicationMetadata{
+			ApplicationID: "app00001",
+			QueueName:     "root.a",
+			User:          "test-user",
+			Tags:          map[string]string{"namespace": "default"},
+		},
+	})
+	NewWebApp(context, nil, conf.DefaultWebServicePort)
+
+	router := newRouter()
+	req, err := http.NewRequest("GET", "/ws/v1/nodes", nil)
+	assert.NilError(t, err)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, resp.Code, http.StatusOK)
+	var views []dao.NodeView
+	err = json.Unmarshal(resp.Body.Bytes(), &views)
+	assert.NilError(t, err, "failed to unmarshal the nodes")
+	assert.Equal(t, len(views), 0)
+
+	req, err = http.NewRequest("GET", "/ws/v1/nodes/host0001", nil)
+	assert.NilError(t, err)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, resp.Code, http.StatusNotFound)
+	assert.Assert(t, strings.Contains(resp.Body.String(), "node host0001 is not found"))
+}
+
+func TestStreamSchedulingDecisions(t *testing.T) {
+	conf.GetSchedulerConf().SetTestMode(true)
+	context := cache.NewContext(client.NewMockedAPIProvider())
+	newApp := func(appID string) *cache.Application {
+		app, ok := context.AddApplication(&interfaces.AddApplicationRequest{
+			Metadata: interfaces.ApplicationMetadata{
+				ApplicationID: appID,
+				QueueName:     "root.a",
+				User:          "test-user",
+				Tags:          map[string]string{"namespace": "default"},
+			},
+		}).(*cache.Application)
+		assert.Assert(t, ok)
+		return app
+	}
+	app1 := newApp("app00001")
+	app2 := newApp("app00002")
+	NewWebApp(context, nil, conf.DefaultWebServicePort)
+	server := httptest.NewServer(newRouter())
+	defer server.Close()
+
+	// only the decisions of the app in the query are streamed
+	resp, err := http.Get(server.URL + "/ws/v1/stream/decisions?appID=app00002")
+	assert.NilError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	assert.Equal(t, resp.Header.Get("Content-Type"), "text/event-stream")
+
+	// submitting the apps moves them from New to Submitted
+	app1.Schedule()
+	app2.Schedule()
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	assert.NilError(t, err)
+	assert.Equal(t, line, "event: "+dao.DecisionAppStateChange+"\n")
+	line, err = reader.ReadString('\n')
+	assert.NilError(t, err)
+	var decision dao.SchedulingDecision
+	err = json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &decision)
+	assert.NilError(t, err, "failed to unmarshal the scheduling decision")
+	assert.Equal(t, decision.ApplicationID, "app00002")
+	assert.Equal(t, decision.Queue, "root.a")
+	assert.Equal(t, decision.FromState, events.States().Application.New)
+	assert.Equal(t, decision.ToState, events.States().Application.Submitted)
+}
+
+func TestSILogging(t *testing.T) {
+	defer func() {
+		assert.NilError(t, client.SetSILogging(dao.SILogging{SampleRate: 1, Redact: true}))
+	}()
+	router := newRouter()
+	req, err := http.NewRequest("POST", "/debug/silogging", strings.NewReader(`{"enabled": true, "sampleRate": 0.1}`))
+	assert.NilError(t, err)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, resp.Code, http.StatusOK)
+
+	// the fields missing in the request are kept
+	req, err = http.NewRequest("GET", "/debug/silogging", nil)
+	assert.NilError(t, err)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, resp.Code, http.StatusOK)
+	var settings dao.SILogging
+	assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &settings))
+	assert.DeepEqual(t, settings, dao.SILogging{Enabled: true, SampleRate: 0.1, Redact: true})
+
+	req, err = http.NewRequest("POST", "/debug/silogging", strings.NewReader(`{"sampleRate": 0}`))
+	assert.NilError(t, err)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, resp.Code, http.StatusBadRequest)
+	assert.DeepEqual(t, client.GetSILogging(), dao.SILogging{Enabled: true, SampleRate: 0.1, Redact: true})
+}
+
+func TestGetDispatchedEvents(t *testing.T) {
+	router := newRouter()
+	req, err := http.NewRequest("GET", "/debug/events?appID=app-unknown", nil)
+	assert.NilError(t, err)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, resp.Code, http.StatusOK)
+
+	// no event of the app was dispatched, the response is an empty list
+	var dispatched []dao.DispatchedEvent
+	assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &dispatched))
+	assert.Assert(t, dispatched != nil)
+	assert.Equal(t, len(dispatched), 0)
+}
+
+func TestWebServiceClient(t *testing.T) {
+	conf.GetSchedulerConf().SetTestMode(true)
+	context := cache.NewContext(client.NewMockedAPIProvider())
+	context.AddApplication(&interfaces.AddApplicationRequest{
+		Metadata: interfaces.ApplicationMetadata{
+			ApplicationID: "app00001",
+			QueueName:     "root.a",
+			User:          "test-user",
+			Tags:          map[string]string{"namespace": "default"},
+		},
+	})
+	NewWebApp(context, nil, conf.DefaultWebServicePort)
+	server := httptest.NewServer(newRouter())
+	defer server.Close()
+	c := wsclient.New(server.URL, nil)
+	ctx := gocontext.Background()
+
+	// the client decodes the responses of the handlers
+	dump, err := c.GetStateDump(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, len(dump.Applications), 1)
+	apps, err := c.GetApplicationsByQueue(ctx, "root")
+	assert.NilError(t, err)
+	assert.Equal(t, len(apps.Applications), 1)
+	apps, err = c.GetApplicationsByUser(ctx, "test-user")
+	assert.NilError(t, err)
+	assert.Equal(t, apps.Applications[0].ApplicationID, "app00001")
+	_, err = c.GetResourceUsage(ctx)
+	assert.NilError(t, err)
+	nodes, err := c.GetNodeViews(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, len(nodes), 0)
+	quarantined, err := c.GetQuarantinedTasks(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, len(quarantined), 0)
+
+	// the errors of the handlers are returned
+	_, err = c.GetNodeView(ctx, "host0001")
+	assert.Assert(t, wsclient.IsNotFound(err))
+	_, err = c.GetApplicationPendingResource(ctx, "app-unknown")
+	assert.Assert(t, wsclient.IsNotFound(err))
+	err = c.KillApplication(ctx, "app-unknown")
+	assert.Assert(t, err != nil && !wsclient.IsNotFound(err))
+
+	// a failed probe is not an error
+	info, err := c.GetReadiness(ctx)
+	assert.NilError(t, err)
+	assert.Assert(t, !info.Healthy)
+	assert.Equal(t, info.SchedulerState, events.States().Scheduler.New)
+}
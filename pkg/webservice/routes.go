@@ -0,0 +1,149 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package webservice
+
+import (
+	"net/http"
+)
+
+type route struct {
+	Name        string
+	Method      string
+	Pattern     string
+	HandlerFunc http.HandlerFunc
+}
+
+type routes []route
+
+var webRoutes = routes{
+	route{
+		"Debug",
+		"GET",
+		"/debug/fullstatedump",
+		getFullStateDump,
+	},
+	route{
+		"Debug",
+		"GET",
+		"/debug/silogging",
+		getSILogging,
+	},
+	route{
+		"Debug",
+		"POST",
+		"/debug/silogging",
+		updateSILogging,
+	},
+	route{
+		"Debug",
+		"GET",
+		"/debug/events",
+		getDispatchedEvents,
+	},
+	route{
+		"Scheduler",
+		"GET",
+		"/ws/v1/resourceusage",
+		getResourceUsage,
+	},
+	route{
+		"Scheduler",
+		"GET",
+		"/ws/v1/pendingresources",
+		getPendingResources,
+	},
+	route{
+		"Scheduler",
+		"GET",
+		"/ws/v1/tasks/quarantined",
+		getQuarantinedTasks,
+	},
+	route{
+		"Scheduler",
+		"GET",
+		"/ws/v1/queues/{queue}/apps",
+		getApplicationsByQueue,
+	},
+	route{
+		"Scheduler",
+		"GET",
+		"/ws/v1/users/{user}/apps",
+		getApplicationsByUser,
+	},
+	route{
+		"Scheduler",
+		"GET",
+		"/ws/v1/apps/{appID}/pendingresource",
+		getApplicationPendingResource,
+	},
+	route{
+		"Scheduler",
+		"POST",
+		"/ws/v1/apps/{appID}/resume",
+		resumeApplication,
+	},
+	route{
+		"Scheduler",
+		"POST",
+		"/ws/v1/apps/{appID}/kill",
+		killApplication,
+	},
+	route{
+		"Scheduler",
+		"POST",
+		"/ws/v1/apps/{appID}/placeholders/{placeholder}/replace",
+		replacePlaceholder,
+	},
+	route{
+		"Scheduler",
+		"POST",
+		"/ws/v1/apps/{appID}/taskgroups/{taskGroup}/release",
+		releaseTaskGroup,
+	},
+	route{
+		"Scheduler",
+		"GET",
+		"/ws/v1/nodes",
+		getNodeViews,
+	},
+	route{
+		"Scheduler",
+		"GET",
+		"/ws/v1/nodes/{nodeName}",
+		getNodeView,
+	},
+	route{
+		"Scheduler",
+		"GET",
+		"/ws/v1/stream/decisions",
+		streamSchedulingDecisions,
+	},
+	route{
+		"Health",
+		"GET",
+		"/ws/v1/health/live",
+		getLiveness,
+	},
+	route{
+		"Health",
+		"GET",
+		"/ws/v1/health/ready",
+		getReadiness,
+	},
+}
@@ -31,6 +31,7 @@ import (
 
 	"k8s.io/apimachinery/pkg/util/wait"
 
+	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
 	authv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -225,6 +226,33 @@ func (k *KubeCtl) DeletePod(podName string, namespace string) error {
 	return err
 }
 
+func (k *KubeCtl) CreateJob(job *batchv1.Job, namespace string) (*batchv1.Job, error) {
+	return k.clientSet.BatchV1().Jobs(namespace).Create(job)
+}
+
+func (k *KubeCtl) GetJob(jobName string, namespace string) (*batchv1.Job, error) {
+	return k.clientSet.BatchV1().Jobs(namespace).Get(jobName, metav1.GetOptions{})
+}
+
+// DeleteJob deletes the job and lets the garbage collector delete its pods
+func (k *KubeCtl) DeleteJob(jobName string, namespace string) error {
+	policy := metav1.DeletePropagationBackground
+	return k.clientSet.BatchV1().Jobs(namespace).Delete(jobName, &metav1.DeleteOptions{
+		PropagationPolicy: &policy,
+	})
+}
+
+// Poll up to timeout for the job to count the given number of failed pods.
+func (k *KubeCtl) WaitForJobFailedPods(namespace string, jobName string, failed int32, timeout time.Duration) error {
+	return wait.PollImmediate(time.Second, timeout, func() (bool, error) {
+		job, err := k.GetJob(jobName, namespace)
+		if err != nil {
+			return false, err
+		}
+		return job.Status.Failed >= failed, nil
+	})
+}
+
 // return a condition function that indicates whether the given pod is
 // currently in desired state
 func (k *KubeCtl) isPodInDesiredState(podName string, namespace string, state v1.PodPhase) wait.ConditionFunc {
@@ -0,0 +1,176 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package jobtracking_test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/apache/incubator-yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/incubator-yunikorn-k8shim/test/e2e/framework/helpers/common"
+	"github.com/apache/incubator-yunikorn-k8shim/test/e2e/framework/helpers/k8s"
+)
+
+// The specs run against a scheduler started with -jobTrackingCompatibility, on a cluster
+// where the Job controller tracks the pods with the batch.kubernetes.io/job-tracking finalizer.
+var _ = ginkgo.Describe("JobTracking", func() {
+	var kClient k8s.KubeCtl
+	var ns = "job-tracking-" + common.RandSeq(5)
+	var jobName = "sleep-job-" + common.RandSeq(5)
+	var parallelism = int32(2)
+	var selector = fmt.Sprintf("job-name=%s", jobName)
+
+	ginkgo.BeforeSuite(func() {
+		kClient = k8s.KubeCtl{}
+		Ω(kClient.SetClient()).To(gomega.BeNil())
+
+		By("create the namespace " + ns)
+		namespace, err := kClient.CreateNamespace(ns, nil)
+		Ω(err).NotTo(HaveOccurred())
+		Ω(namespace.Status.Phase).To(gomega.Equal(v1.NamespaceActive))
+
+		By("deploy the sleep job " + jobName)
+		pod := common.InitSleepPod(common.SleepPodConfig{NS: ns, Time: 600})
+		backoffLimit := int32(10)
+		job := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      jobName,
+				Namespace: ns,
+			},
+			Spec: batchv1.JobSpec{
+				Parallelism:  &parallelism,
+				Completions:  &parallelism,
+				BackoffLimit: &backoffLimit,
+				Template: v1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: pod.Labels,
+					},
+					Spec: pod.Spec,
+				},
+			},
+		}
+		_, err = kClient.CreateJob(job, ns)
+		Ω(err).NotTo(HaveOccurred())
+		Ω(waitForJobPods(kClient, ns, selector, int(parallelism))).NotTo(HaveOccurred())
+		Ω(kClient.WaitForPodBySelectorRunning(ns, selector, 60)).NotTo(HaveOccurred())
+	})
+
+	ginkgo.It("Verify_Job_Pods_Scheduled", func() {
+		pods, err := kClient.ListPods(ns, selector)
+		Ω(err).NotTo(HaveOccurred())
+		for _, pod := range pods.Items {
+			Ω(pod.Spec.SchedulerName).To(gomega.Equal("yunikorn"))
+			Ω(pod.Spec.NodeName).NotTo(gomega.BeEmpty())
+		}
+	})
+
+	ginkgo.It("Verify_Finalizer_Not_Stripped", func() {
+		pods, err := kClient.ListPods(ns, selector)
+		Ω(err).NotTo(HaveOccurred())
+		if !hasTrackingFinalizer(pods.Items[0]) {
+			ginkgo.Skip("the Job controller does not track the pods with finalizers")
+		}
+		By("the running pods keep the finalizer")
+		for _, pod := range pods.Items {
+			Ω(hasTrackingFinalizer(pod)).To(gomega.BeTrue())
+		}
+	})
+
+	ginkgo.It("Verify_Deleted_Pod_Counted_Once", func() {
+		pods, err := kClient.ListPods(ns, selector)
+		Ω(err).NotTo(HaveOccurred())
+		if !hasTrackingFinalizer(pods.Items[0]) {
+			ginkgo.Skip("the Job controller does not track the pods with finalizers")
+		}
+		deleted := pods.Items[0].Name
+
+		By("delete the pod " + deleted)
+		err = kClient.GetClient().CoreV1().Pods(ns).Delete(deleted, &metav1.DeleteOptions{})
+		Ω(err).NotTo(HaveOccurred())
+
+		By("the Job controller accounts for the pod and removes its finalizer")
+		Ω(kClient.WaitForJobFailedPods(ns, jobName, 1, 60*time.Second)).NotTo(HaveOccurred())
+		err = wait.PollImmediate(time.Second, 60*time.Second, func() (bool, error) {
+			_, err := kClient.GetPod(deleted, ns)
+			return err != nil, nil
+		})
+		Ω(err).NotTo(HaveOccurred())
+
+		By("the pod is counted once")
+		// the status has settled once the Job controller also accounts for the replacement pod
+		err = wait.PollImmediate(time.Second, 60*time.Second, func() (bool, error) {
+			job, err := kClient.GetJob(jobName, ns)
+			if err != nil {
+				return false, err
+			}
+			if job.Status.Failed > 1 {
+				return false, fmt.Errorf("the deleted pod is counted %d times", job.Status.Failed)
+			}
+			return job.Status.Active == parallelism, nil
+		})
+		Ω(err).NotTo(HaveOccurred())
+		job, err := kClient.GetJob(jobName, ns)
+		Ω(err).NotTo(HaveOccurred())
+		Ω(job.Status.Failed).To(gomega.Equal(int32(1)))
+
+		By("the replacement pod is scheduled")
+		Ω(waitForJobPods(kClient, ns, selector, int(parallelism))).NotTo(HaveOccurred())
+		Ω(kClient.WaitForPodBySelectorRunning(ns, selector, 60)).NotTo(HaveOccurred())
+	})
+
+	ginkgo.AfterSuite(func() {
+		By("delete the job " + jobName)
+		Ω(kClient.DeleteJob(jobName, ns)).NotTo(HaveOccurred())
+		By("tear down the namespace " + ns)
+		Ω(kClient.TearDownNamespace(ns)).NotTo(HaveOccurred())
+	})
+})
+
+func hasTrackingFinalizer(pod v1.Pod) bool {
+	for _, finalizer := range pod.Finalizers {
+		if finalizer == constants.JobTrackingFinalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// waitForJobPods waits for the job to have the given number of pods that are not terminating
+func waitForJobPods(kClient k8s.KubeCtl, ns, selector string, count int) error {
+	return wait.PollImmediate(time.Second, 60*time.Second, func() (bool, error) {
+		pods, err := kClient.ListPods(ns, selector)
+		if err != nil {
+			return false, err
+		}
+		active := 0
+		for _, pod := range pods.Items {
+			if pod.DeletionTimestamp == nil {
+				active++
+			}
+		}
+		return active == count, nil
+	})
+}